@@ -0,0 +1,158 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultDirectory holds one JSONL file of entries per day
+const DefaultDirectory = "~/.openfaas/audit"
+
+// Actions recorded in the audit log
+const (
+	Deploy = "deploy"
+	Remove = "remove"
+	Invoke = "invoke"
+)
+
+const fileDateLayout = "2006-01-02"
+
+// Entry is a change or invocation issued by the CLI
+type Entry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Action   string    `json:"action"`
+	Gateway  string    `json:"gateway"`
+	Function string    `json:"function"`
+	Image    string    `json:"image,omitempty"`
+	Digest   string    `json:"digest,omitempty"`
+
+	// StatusCode is the gateway's response, 0 when no response was received
+	StatusCode int    `json:"status_code,omitempty"`
+	Success    bool   `json:"success"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Log appends entries to the files in a directory
+type Log struct {
+	Directory string
+}
+
+func (l *Log) fileFor(day time.Time) string {
+	return filepath.Join(l.Directory, day.UTC().Format(fileDateLayout)+".jsonl")
+}
+
+// Record appends an entry to the file for the day it happened
+func (l *Log) Record(entry Entry) error {
+	if err := os.MkdirAll(l.Directory, 0700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(l.fileFor(entry.Time), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Read gives the entries recorded since the given time, oldest first
+func (l *Log) Read(since time.Time) ([]Entry, error) {
+	files, err := ioutil.ReadDir(l.Directory)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	firstDay := since.UTC().Format(fileDateLayout)
+
+	var entries []Entry
+	for _, file := range files {
+		day := strings.TrimSuffix(file.Name(), ".jsonl")
+		if file.IsDir() || day == file.Name() || day < firstDay {
+			continue
+		}
+
+		dayEntries, err := readFile(filepath.Join(l.Directory, file.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range dayEntries {
+			if !entry.Time.Before(since) {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+func readFile(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// ParseSince reads a time such as 7d, 12h or 2018-06-01 as the start of a
+// period ending now
+func ParseSince(value string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err == nil && days >= 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, fileDateLayout} {
+		if parsed, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration such as 7d or 12h, or a date such as 2018-06-01", value)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func Test_RecordAndRead(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	log := &Log{Directory: dir}
+	now := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Time: now.AddDate(0, 0, -9), Action: Deploy, Function: "old", Success: true},
+		{Time: now.Add(-2 * time.Hour), Action: Deploy, Function: "api", Digest: "sha256:abc", StatusCode: 202, Success: true},
+		{Time: now.Add(-time.Hour), Action: Invoke, Function: "api", Error: "timeout"},
+		{Time: now.AddDate(0, 0, -1), Action: Remove, Function: "cron", Success: true},
+	}
+	for _, entry := range entries {
+		if err := log.Record(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 3 {
+		t.Errorf("want one file per day, got %d", len(files))
+	}
+
+	got, err := log.Read(now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var functions []string
+	for _, entry := range got {
+		functions = append(functions, entry.Action+" "+entry.Function)
+	}
+	want := []string{"remove cron", "deploy api", "invoke api"}
+	if len(functions) != len(want) {
+		t.Fatalf("want %v, got %v", want, functions)
+	}
+	for i := range want {
+		if functions[i] != want[i] {
+			t.Errorf("want %v, got %v", want, functions)
+		}
+	}
+	if got[1].Digest != "sha256:abc" || got[1].StatusCode != 202 || got[2].Error != "timeout" {
+		t.Errorf("fields were not kept: %+v", got)
+	}
+}
+
+func Test_Read_MissingDirectory(t *testing.T) {
+	log := &Log{Directory: "/tmp/faas-cli-audit-does-not-exist"}
+	entries, err := log.Read(time.Time{})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("want no entries and no error, got %v %v", entries, err)
+	}
+}
+
+func Test_ParseSince(t *testing.T) {
+	now := time.Date(2018, 6, 10, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"7d":                   now.AddDate(0, 0, -7),
+		"90m":                  now.Add(-90 * time.Minute),
+		"2018-06-01T08:00:00Z": time.Date(2018, 6, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for value, want := range cases {
+		got, err := ParseSince(value, now)
+		if err != nil {
+			t.Errorf("%s: %s", value, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: want %s, got %s", value, want, got)
+		}
+	}
+
+	if _, err := ParseSince("last week", now); err == nil {
+		t.Errorf("want an error for an unknown format")
+	}
+}
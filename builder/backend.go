@@ -0,0 +1,173 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultBackend builds with the local Docker daemon
+const DefaultBackend = "docker"
+
+// BuildOptions are the inputs of an image build
+type BuildOptions struct {
+	Image        string
+	Handler      string
+	FunctionName string
+	Language     string
+
+	NoCache    bool
+	Squash     bool
+	Shrinkwrap bool
+
+	BuildArgs map[string]string
+
+	// Platform is passed to the backend when set, i.e. linux/arm/v7
+	Platform string
+
+	// Secrets maps the id of each BuildKit secret to the file holding it
+	Secrets map[string]string
+
+	// Dockerfile is relative to the build context, defaults to Dockerfile
+	Dockerfile string
+
+	// Labels are written to the image, backends which build from source
+	// without a Dockerfile leave them out
+	Labels map[string]string
+
+	// SSH forwards agent sockets or keys to RUN --mount=type=ssh, each is
+	// default or ID=PATH
+	SSH []string
+
+	// InlineCache writes the build cache into the image and reuses the cache
+	// of the image last pushed with the same name
+	InlineCache bool
+
+	// CacheFrom are images whose layers are used as a build cache
+	CacheFrom []string
+}
+
+// Capabilities reports what a backend can do with a build
+type Capabilities struct {
+	BuildArgs bool
+	Secrets   bool
+	Squash    bool
+	Platforms bool
+	SSH       bool
+
+	// MultiPlatform is true when several platforms, separated by commas, are
+	// built into one multi-arch image
+	MultiPlatform bool
+
+	// InlineCache is true when the cache can be exported with the image
+	InlineCache bool
+
+	// CacheFrom is true when the layers of other images can be used as a cache
+	CacheFrom bool
+
+	// Dockerfile is false for backends which build the handler from source
+	// without a template or Dockerfile
+	Dockerfile bool
+
+	// Pushes is true when the image is pushed to its registry as it is built
+	Pushes bool
+
+	// Daemon is true when a local Docker daemon is needed
+	Daemon bool
+}
+
+// Unsupported lists the options of a build which the backend cannot honour
+func (c Capabilities) Unsupported(options BuildOptions) []string {
+	var unsupported []string
+	if len(options.BuildArgs) > 0 && !c.BuildArgs {
+		unsupported = append(unsupported, "build-args")
+	}
+	if len(options.Secrets) > 0 && !c.Secrets {
+		unsupported = append(unsupported, "build secrets")
+	}
+	if options.Squash && !c.Squash {
+		unsupported = append(unsupported, "--squash")
+	}
+	if len(options.Platform) > 0 && !c.Platforms {
+		unsupported = append(unsupported, "platforms")
+	}
+	if strings.Contains(options.Platform, ",") && !c.MultiPlatform {
+		unsupported = append(unsupported, "multi-arch builds")
+	}
+	if len(options.SSH) > 0 && !c.SSH {
+		unsupported = append(unsupported, "--ssh")
+	}
+	if options.InlineCache && !c.InlineCache {
+		unsupported = append(unsupported, "--inline-cache")
+	}
+	if len(options.CacheFrom) > 0 && !c.CacheFrom {
+		unsupported = append(unsupported, "--cache-from")
+	}
+	return unsupported
+}
+
+// Builder builds an image from a context prepared by BuildImage
+type Builder interface {
+	// Name is how the backend is chosen with build.builder in the config file
+	Name() string
+
+	// Command is the executable the backend runs
+	Command() string
+
+	Capabilities() Capabilities
+
+	// Build builds the image in options from the files at contextPath
+	Build(contextPath string, options BuildOptions) error
+}
+
+// backends creates each builder, host is the Docker daemon of the remote builder
+var backends = map[string]func(host string) Builder{
+	"docker":   func(string) Builder { return DockerBuilder{} },
+	"buildkit": func(string) Builder { return BuildKitBuilder{} },
+	"buildx":   func(string) Builder { return BuildxBuilder{} },
+	"podman":   func(string) Builder { return PodmanBuilder{} },
+	"ko":       func(string) Builder { return KoBuilder{} },
+	"remote":   func(host string) Builder { return RemoteBuilder{Host: host} },
+}
+
+// BackendNames lists the builders which can be chosen, sorted
+func BackendNames() []string {
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewBackend gives the named builder, an empty name gives DefaultBackend
+func NewBackend(name string, remoteHost string) (Builder, error) {
+	if len(name) == 0 {
+		name = DefaultBackend
+	}
+
+	newBackend, ok := backends[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown builder %s, choose from: %s", name, strings.Join(BackendNames(), ", "))
+	}
+	return newBackend(remoteHost), nil
+}
+
+var activeBackend Builder = DockerBuilder{}
+
+// SetBackend chooses the builder used by BuildImage and BuildBaseImages, nil
+// restores DefaultBackend
+func SetBackend(backend Builder) {
+	if backend == nil {
+		backend = DockerBuilder{}
+	}
+	activeBackend = backend
+}
+
+// Backend is the builder used by BuildImage and BuildBaseImages
+func Backend() Builder {
+	return activeBackend
+}
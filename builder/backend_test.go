@@ -0,0 +1,177 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_NewBackend(t *testing.T) {
+	backend, err := NewBackend("", "")
+	if err != nil || backend.Name() != DefaultBackend {
+		t.Errorf("want the docker builder by default, got %v %v", backend, err)
+	}
+
+	backend, err = NewBackend("remote", "ssh://builder")
+	if err != nil || backend.(RemoteBuilder).Host != "ssh://builder" {
+		t.Errorf("want the remote builder with its host, got %v %v", backend, err)
+	}
+
+	_, err = NewBackend("kaniko", "")
+	if err == nil || !strings.Contains(err.Error(), "buildkit, buildx, docker, ko, podman, remote") {
+		t.Errorf("want an error listing the builders, got %v", err)
+	}
+}
+
+func Test_Capabilities_Unsupported(t *testing.T) {
+	options := BuildOptions{
+		BuildArgs: map[string]string{"GO111MODULE": "on"},
+		Secrets:   map[string]string{"npmrc": "/tmp/npmrc"},
+		Squash:    true,
+		Platform:  "linux/arm64",
+	}
+
+	if got := (DockerBuilder{}).Capabilities().Unsupported(options); len(got) != 0 {
+		t.Errorf("want docker to support every option, got %v", got)
+	}
+
+	multiArch := BuildOptions{Platform: "linux/amd64,linux/arm64"}
+	if got := (DockerBuilder{}).Capabilities().Unsupported(multiArch); !reflect.DeepEqual(got, []string{"multi-arch builds"}) {
+		t.Errorf("want docker to not build several platforms at once, got %v", got)
+	}
+	if got := (BuildxBuilder{}).Capabilities().Unsupported(multiArch); len(got) != 0 {
+		t.Errorf("want buildx to build several platforms at once, got %v", got)
+	}
+
+	buildKitOptions := BuildOptions{SSH: []string{"default"}, InlineCache: true}
+	if got := (BuildKitBuilder{}).Capabilities().Unsupported(buildKitOptions); len(got) != 0 {
+		t.Errorf("want buildkit to support --ssh and --inline-cache, got %v", got)
+	}
+	if got := (DockerBuilder{}).Capabilities().Unsupported(buildKitOptions); !reflect.DeepEqual(got, []string{"--ssh", "--inline-cache"}) {
+		t.Errorf("want docker to not support --ssh and --inline-cache, got %v", got)
+	}
+	if got := (BuildKitBuilder{}).Capabilities().Unsupported(options); !reflect.DeepEqual(got, []string{"--squash"}) {
+		t.Errorf("want buildkit to not support --squash, got %v", got)
+	}
+	if got := (KoBuilder{}).Capabilities().Unsupported(options); !reflect.DeepEqual(got, []string{"build-args", "build secrets", "--squash"}) {
+		t.Errorf("unexpected options unsupported by ko: %v", got)
+	}
+
+	cacheFrom := BuildOptions{CacheFrom: []string{"alexellis/fn:latest"}}
+	if got := (KoBuilder{}).Capabilities().Unsupported(cacheFrom); !reflect.DeepEqual(got, []string{"--cache-from"}) {
+		t.Errorf("want ko to not support --cache-from, got %v", got)
+	}
+}
+
+func Test_dockerfileBuildCommand(t *testing.T) {
+	os.Unsetenv("http_proxy")
+	os.Unsetenv("https_proxy")
+
+	options := BuildOptions{
+		Image:      "alexellis/base:0.1",
+		NoCache:    true,
+		BuildArgs:  map[string]string{"NODE_BASE": "node:10", "ADDITIONAL_PACKAGE": "make gcc"},
+		Platform:   "linux/arm64",
+		Secrets:    map[string]string{"npmrc": "/tmp/npmrc"},
+		Dockerfile: "Dockerfile.base",
+		Labels:     map[string]string{"com.openfaas.watchdog": "of-watchdog"},
+		CacheFrom:  []string{"alexellis/base:latest", "alexellis/base:0.0"},
+	}
+
+	got := dockerfileBuildCommand("docker --host ssh://builder", options)
+	want := []string{"docker", "--host", "ssh://builder", "build", "--no-cache", "--build-arg", "ADDITIONAL_PACKAGE=make gcc", "--build-arg", "NODE_BASE=node:10",
+		"--platform", "linux/arm64", "--secret", "id=npmrc,src=/tmp/npmrc", "--label", "com.openfaas.watchdog=of-watchdog", "-f", "Dockerfile.base",
+		"--cache-from", "alexellis/base:latest", "--cache-from", "alexellis/base:0.0", "-t", "alexellis/base:0.1", "."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want:\n%q\ngot:\n%q", want, got)
+	}
+}
+
+func Test_buildxCommand(t *testing.T) {
+	os.Unsetenv("http_proxy")
+	os.Unsetenv("https_proxy")
+
+	got := strings.Join(buildxCommand(BuildOptions{Image: "alexellis/fn:0.1", Platform: "linux/amd64,linux/arm64"}), " ")
+	want := "docker buildx build --platform linux/amd64,linux/arm64 -t alexellis/fn:0.1 --push ."
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func Test_buildctlCommand(t *testing.T) {
+	os.Unsetenv("http_proxy")
+	os.Setenv("https_proxy", "http://proxy:3128")
+	defer os.Unsetenv("https_proxy")
+
+	got := strings.Join(buildctlCommand(BuildOptions{
+		Image:     "registry:5000/fn:0.2",
+		NoCache:   true,
+		BuildArgs: map[string]string{"ADDITIONAL_PACKAGE": "git"},
+		Secrets:   map[string]string{"npmrc": "/tmp/npmrc"},
+		Labels:    map[string]string{"com.openfaas.template": "node"},
+	}), " ")
+	want := "buildctl build --frontend dockerfile.v0 --local context=. --local dockerfile=. --no-cache " +
+		"--opt build-arg:ADDITIONAL_PACKAGE=git --opt build-arg:https_proxy=http://proxy:3128 --opt label:com.openfaas.template=node " +
+		"--secret id=npmrc,src=/tmp/npmrc --output type=image,name=registry:5000/fn:0.2,push=true"
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func Test_buildctlCommand_SSHAndInlineCache(t *testing.T) {
+	os.Unsetenv("http_proxy")
+	os.Unsetenv("https_proxy")
+
+	options := BuildOptions{
+		Image:       "registry:5000/fn:0.2",
+		SSH:         []string{"default", "github=/home/app/.ssh/id_rsa"},
+		InlineCache: true,
+		CacheFrom:   []string{"registry:5000/fn:latest"},
+	}
+	got := strings.Join(buildctlCommand(options), " ")
+	want := "buildctl build --frontend dockerfile.v0 --local context=. --local dockerfile=. " +
+		"--ssh default --ssh github=/home/app/.ssh/id_rsa --import-cache type=registry,ref=registry:5000/fn:latest --export-cache type=inline --import-cache type=registry,ref=registry:5000/fn:0.2 " +
+		"--output type=image,name=registry:5000/fn:0.2,push=true"
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+
+	options.SSH = nil
+	options.NoCache = true
+	got = strings.Join(buildctlCommand(options), " ")
+	if strings.Contains(got, "--import-cache") || !strings.Contains(got, "--export-cache type=inline") {
+		t.Errorf("want the cache exported but not imported with --no-cache, got: %s", got)
+	}
+}
+
+func Test_SplitImageTag(t *testing.T) {
+	cases := map[string][2]string{
+		"alexellis/fn:0.1":     {"alexellis/fn", "0.1"},
+		"registry:5000/fn":     {"registry:5000/fn", "latest"},
+		"registry:5000/fn:dev": {"registry:5000/fn", "dev"},
+	}
+	for image, want := range cases {
+		repository, tag := SplitImageTag(image)
+		if repository != want[0] || tag != want[1] {
+			t.Errorf("%s: want %v, got %s %s", image, want, repository, tag)
+		}
+	}
+}
+
+func Test_BuildKitBuilder_NeedsBuildctl(t *testing.T) {
+	originalLookPath := lookPath
+	defer func() { lookPath = originalLookPath }()
+	lookPath = func(file string) (string, error) {
+		return "", fmt.Errorf("%s: not found", file)
+	}
+
+	err := (BuildKitBuilder{}).Build(".", BuildOptions{Image: "alexellis/fn:0.1"})
+	if err == nil || !strings.Contains(err.Error(), "buildctl is needed by the buildkit builder") {
+		t.Fatalf("want an error asking for buildctl, got %v", err)
+	}
+}
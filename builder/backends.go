@@ -0,0 +1,255 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+)
+
+// DockerBuilder runs docker build against the local Docker daemon
+type DockerBuilder struct{}
+
+// Name of the builder
+func (DockerBuilder) Name() string { return "docker" }
+
+// Command run by the builder
+func (DockerBuilder) Command() string { return "docker" }
+
+// Capabilities of the builder
+func (DockerBuilder) Capabilities() Capabilities {
+	return Capabilities{BuildArgs: true, Secrets: true, Squash: true, Platforms: true, CacheFrom: true, Dockerfile: true, Daemon: true}
+}
+
+// Build runs docker build in the context
+func (DockerBuilder) Build(contextPath string, options BuildOptions) error {
+	ExecCommand(contextPath, dockerfileBuildCommand("docker", options))
+	return nil
+}
+
+// PodmanBuilder runs podman build, which needs no daemon
+type PodmanBuilder struct{}
+
+// Name of the builder
+func (PodmanBuilder) Name() string { return "podman" }
+
+// Command run by the builder
+func (PodmanBuilder) Command() string { return "podman" }
+
+// Capabilities of the builder
+func (PodmanBuilder) Capabilities() Capabilities {
+	return Capabilities{BuildArgs: true, Secrets: true, Squash: true, Platforms: true, CacheFrom: true, Dockerfile: true}
+}
+
+// Build runs podman build in the context
+func (PodmanBuilder) Build(contextPath string, options BuildOptions) error {
+	ExecCommand(contextPath, dockerfileBuildCommand("podman", options))
+	return nil
+}
+
+// RemoteBuilder runs docker build against the Docker daemon at Host, such as
+// tcp://builder:2376 or ssh://user@builder
+type RemoteBuilder struct {
+	Host string
+}
+
+// Name of the builder
+func (RemoteBuilder) Name() string { return "remote" }
+
+// Command run by the builder
+func (RemoteBuilder) Command() string { return "docker" }
+
+// Capabilities of the builder
+func (RemoteBuilder) Capabilities() Capabilities {
+	return Capabilities{BuildArgs: true, Secrets: true, Squash: true, Platforms: true, CacheFrom: true, Dockerfile: true}
+}
+
+// Build sends the context to the remote daemon
+func (r RemoteBuilder) Build(contextPath string, options BuildOptions) error {
+	if len(r.Host) == 0 {
+		return fmt.Errorf("give the address of the Docker daemon in build.remote_host")
+	}
+	ExecCommand(contextPath, dockerfileBuildCommand("docker --host "+r.Host, options))
+	return nil
+}
+
+// BuildxBuilder runs docker buildx build, which builds several platforms into
+// one multi-arch image and pushes it to its registry
+type BuildxBuilder struct{}
+
+// Name of the builder
+func (BuildxBuilder) Name() string { return "buildx" }
+
+// Command run by the builder
+func (BuildxBuilder) Command() string { return "docker" }
+
+// Capabilities of the builder
+func (BuildxBuilder) Capabilities() Capabilities {
+	return Capabilities{BuildArgs: true, Secrets: true, Platforms: true, MultiPlatform: true, CacheFrom: true, Dockerfile: true, Pushes: true, Daemon: true}
+}
+
+// Build runs docker buildx build in the context and pushes the image
+func (BuildxBuilder) Build(contextPath string, options BuildOptions) error {
+	ExecCommand(contextPath, buildxCommand(options))
+	return nil
+}
+
+// buildxCommand is docker build's command with --push, a multi-arch image
+// can't be loaded into the local image cache
+func buildxCommand(options BuildOptions) []string {
+	command := dockerfileBuildCommand("docker buildx", options)
+	return append(command[:len(command)-1], "--push", ".")
+}
+
+// BuildKitBuilder runs buildctl against a buildkitd, the image is pushed to
+// its registry as it is built. buildctl must be on the PATH, it is released
+// with BuildKit at https://github.com/moby/buildkit/releases
+type BuildKitBuilder struct{}
+
+// lookPath is swapped out in tests
+var lookPath = exec.LookPath
+
+// Name of the builder
+func (BuildKitBuilder) Name() string { return "buildkit" }
+
+// Command run by the builder
+func (BuildKitBuilder) Command() string { return "buildctl" }
+
+// Capabilities of the builder
+func (BuildKitBuilder) Capabilities() Capabilities {
+	return Capabilities{BuildArgs: true, Secrets: true, Platforms: true, MultiPlatform: true, SSH: true, InlineCache: true, CacheFrom: true, Dockerfile: true, Pushes: true}
+}
+
+// Build runs buildctl build with the Dockerfile frontend
+func (BuildKitBuilder) Build(contextPath string, options BuildOptions) error {
+	if _, err := lookPath("buildctl"); err != nil {
+		return fmt.Errorf("buildctl is needed by the buildkit builder but was not found in your PATH, install it from https://github.com/moby/buildkit/releases")
+	}
+	ExecCommand(contextPath, buildctlCommand(options))
+	return nil
+}
+
+func buildctlCommand(options BuildOptions) []string {
+	command := []string{"buildctl", "build", "--frontend", "dockerfile.v0", "--local", "context=.", "--local", "dockerfile=."}
+	if len(options.Dockerfile) > 0 {
+		command = append(command, "--opt", "filename="+options.Dockerfile)
+	}
+	if options.NoCache {
+		command = append(command, "--no-cache")
+	}
+	if len(options.Platform) > 0 {
+		command = append(command, "--opt", "platform="+options.Platform)
+	}
+
+	buildArgs := proxyBuildArgs()
+	for name, value := range options.BuildArgs {
+		buildArgs[name] = value
+	}
+	for _, name := range sortedKeys(buildArgs) {
+		command = append(command, "--opt", fmt.Sprintf("build-arg:%s=%s", name, buildArgs[name]))
+	}
+
+	for _, name := range sortedKeys(options.Labels) {
+		command = append(command, "--opt", fmt.Sprintf("label:%s=%s", name, options.Labels[name]))
+	}
+
+	ids := make([]string, 0, len(options.Secrets))
+	for id := range options.Secrets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		command = append(command, "--secret", fmt.Sprintf("id=%s,src=%s", id, options.Secrets[id]))
+	}
+
+	for _, ssh := range options.SSH {
+		command = append(command, "--ssh", ssh)
+	}
+
+	if !options.NoCache {
+		for _, image := range options.CacheFrom {
+			command = append(command, "--import-cache", "type=registry,ref="+image)
+		}
+	}
+	if options.InlineCache {
+		command = append(command, "--export-cache", "type=inline")
+		if !options.NoCache {
+			command = append(command, "--import-cache", "type=registry,ref="+options.Image)
+		}
+	}
+
+	return append(command, "--output", fmt.Sprintf("type=image,name=%s,push=true", options.Image))
+}
+
+// KoBuilder builds a Go handler from source with ko, without a template or
+// Dockerfile, and pushes the image as it is built
+type KoBuilder struct{}
+
+// Name of the builder
+func (KoBuilder) Name() string { return "ko" }
+
+// Command run by the builder
+func (KoBuilder) Command() string { return "ko" }
+
+// Capabilities of the builder
+func (KoBuilder) Capabilities() Capabilities {
+	return Capabilities{Platforms: true, Pushes: true}
+}
+
+// Build runs ko build in the handler, which must be a Go main package
+func (KoBuilder) Build(contextPath string, options BuildOptions) error {
+	repository, tag := SplitImageTag(options.Image)
+
+	command := []string{"ko", "build", "--bare", "--tags", tag}
+	if len(options.Platform) > 0 {
+		command = append(command, "--platform", options.Platform)
+	}
+	command = append(command, ".")
+
+	execCommand(contextPath, command, []string{"KO_DOCKER_REPO=" + repository}, nil)
+	return nil
+}
+
+// SplitImageTag splits the tag from an image, which defaults to latest
+func SplitImageTag(image string) (string, string) {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[:i], image[i+1:]
+	}
+	return image, "latest"
+}
+
+// dockerfileBuildCommand gives the build command of the docker CLI or a CLI
+// which accepts the same flags, such as podman. Each flag value is its own
+// argument so values containing spaces reach the CLI whole.
+func dockerfileBuildCommand(command string, options BuildOptions) []string {
+	args := append(strings.Fields(command), "build")
+	args = append(args, buildFlagSlice(options.NoCache, options.Squash, os.Getenv("http_proxy"), os.Getenv("https_proxy"), options.BuildArgs)...)
+	if len(options.Platform) > 0 {
+		args = append(args, "--platform", options.Platform)
+	}
+	args = append(args, buildSecretFlagSlice(options.Secrets)...)
+	for _, name := range sortedKeys(options.Labels) {
+		args = append(args, "--label", fmt.Sprintf("%s=%s", name, options.Labels[name]))
+	}
+	if len(options.Dockerfile) > 0 {
+		args = append(args, "-f", options.Dockerfile)
+	}
+	for _, image := range options.CacheFrom {
+		args = append(args, "--cache-from", image)
+	}
+	return append(args, "-t", options.Image, ".")
+}
+
+func proxyBuildArgs() map[string]string {
+	args := map[string]string{}
+	for _, name := range []string{"http_proxy", "https_proxy"} {
+		if value := os.Getenv(name); len(value) > 0 {
+			args[name] = value
+		}
+	}
+	return args
+}
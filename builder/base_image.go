@@ -0,0 +1,87 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// BaseImageBuildArg gives the build-arg name a base image is exposed as
+// i.e. python-base becomes PYTHON_BASE
+func BaseImageBuildArg(name string) string {
+	arg := strings.ToUpper(name)
+	arg = strings.Replace(arg, "-", "_", -1)
+	arg = strings.Replace(arg, ".", "_", -1)
+
+	return arg
+}
+
+// BaseImageBuildArgs gives the build-args which BuildBaseImages returns,
+// without building the base images
+func BaseImageBuildArgs(baseImages []stack.BaseImage) map[string]string {
+	buildArgMap := make(map[string]string)
+	for _, baseImage := range baseImages {
+		image := baseImage.Image
+		if len(image) == 0 {
+			image = baseImage.Name
+		}
+		buildArgMap[BaseImageBuildArg(baseImage.Name)] = image
+	}
+	return buildArgMap
+}
+
+// BuildBaseImages builds the stack's base images in the order they were
+// declared and returns the build-args which reference them. Each base image
+// can reference those declared before it.
+func BuildBaseImages(baseImages []stack.BaseImage, nocache bool, squash bool) (map[string]string, error) {
+	buildArgMap := make(map[string]string)
+
+	if capabilities := activeBackend.Capabilities(); !capabilities.Dockerfile && len(baseImages) > 0 {
+		return nil, fmt.Errorf("the %s builder cannot build base images from a Dockerfile", activeBackend.Name())
+	}
+
+	for _, baseImage := range baseImages {
+		image := baseImage.Image
+		if len(image) == 0 {
+			image = baseImage.Name
+		}
+
+		context := baseImage.Context
+		if len(context) == 0 {
+			context = "."
+		}
+
+		dockerfile := baseImage.Dockerfile
+		if len(dockerfile) == 0 {
+			dockerfile = "Dockerfile"
+		}
+
+		if _, err := os.Stat(filepath.Join(context, dockerfile)); err != nil {
+			return nil, fmt.Errorf("unable to build base image %s, %s was not found in %s", baseImage.Name, dockerfile, context)
+		}
+
+		fmt.Printf("Building base image: %s as %s. Please wait..\n", baseImage.Name, image)
+
+		options := BuildOptions{
+			Image:      image,
+			NoCache:    nocache,
+			Squash:     squash,
+			BuildArgs:  buildArgMap,
+			Dockerfile: dockerfile,
+		}
+		if err := activeBackend.Build(context, options); err != nil {
+			return nil, fmt.Errorf("unable to build base image %s: %s", baseImage.Name, err)
+		}
+		fmt.Printf("Base image: %s built.\n", image)
+
+		buildArgMap[BaseImageBuildArg(baseImage.Name)] = image
+	}
+
+	return buildArgMap, nil
+}
@@ -7,65 +7,134 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/openfaas/faas-cli/stack"
 )
 
-// BuildImage construct Docker image from function parameters
-func BuildImage(image string, handler string, functionName string, language string, nocache bool, squash bool, shrinkwrap bool) {
+// BuildDirectory is where the build context of each function built from a
+// template is prepared, the CLI moves it into its state directory
+var BuildDirectory = "./build"
 
-	if stack.IsValidTemplate(language) {
+// ShrinkwrapDirectory is where shrink-wrapped build contexts are written, to
+// be built elsewhere
+const ShrinkwrapDirectory = "./build"
 
-		var tempPath string
-		if strings.ToLower(language) == "dockerfile" {
+// BuildImage construct Docker image from function parameters with the
+// builder chosen by SetBackend
+func BuildImage(options BuildOptions) {
+	image, handler, functionName, language := options.Image, options.Handler, options.FunctionName, options.Language
 
-			if shrinkwrap {
-				fmt.Printf("Nothing to do for: %s.\n", functionName)
+	if !stack.IsValidTemplate(language) {
+		errorEvent(functionName, image, fmt.Sprintf("language template %s not supported", language))
+		log.Fatalf("Language template: %s not supported. Build a custom Dockerfile instead.", language)
+	}
 
-				return
-			}
+	backend := activeBackend
+	capabilities := backend.Capabilities()
 
-			tempPath = handler
-			if err := ensureHandlerPath(handler); err != nil {
-				fmt.Printf("Unable to build %s, %s is an invalid path\n", image, handler)
-				fmt.Printf("Image: %s not built.\n", image)
+	var tempPath string
+	if strings.ToLower(language) == "dockerfile" || !capabilities.Dockerfile {
 
-				return
-			}
-			fmt.Printf("Building: %s with Dockerfile. Please wait..\n", image)
+		if options.Shrinkwrap {
+			fmt.Printf("Nothing to do for: %s.\n", functionName)
 
-		} else {
+			return
+		}
+
+		tempPath = handler
+		if err := ensureHandlerPath(handler); err != nil {
+			errorEvent(functionName, image, fmt.Sprintf("%s is an invalid path", handler))
+			fmt.Printf("Unable to build %s, %s is an invalid path\n", image, handler)
+			fmt.Printf("Image: %s not built.\n", image)
 
-			if err := ensureHandlerPath(handler); err != nil {
-				fmt.Printf("Unable to build %s, %s is an invalid path\n", image, handler)
-				fmt.Printf("Image: %s not built.\n", image)
+			return
+		}
+		if capabilities.Dockerfile {
+			fmt.Printf("Building: %s with Dockerfile. Please wait..\n", image)
+		} else {
+			fmt.Printf("Building: %s from source with %s. Please wait..\n", image, backend.Name())
+		}
 
-				return
+		if buildInfoEnabled {
+			// The handler is the user's own folder so the file is not left behind
+			infoFile, err := writeBuildInfo(tempPath, language, newBuildInfo(functionName, image, handler, time.Now()))
+			if err != nil {
+				log.Fatalf("Unable to write build info for %s: %s", functionName, err)
 			}
-			tempPath = createBuildTemplate(functionName, handler, language)
-			fmt.Printf("Building: %s with %s template. Please wait..\n", image, language)
+			defer os.Remove(infoFile)
+		}
+
+	} else {
 
-			if shrinkwrap {
-				fmt.Printf("%s shrink-wrapped to %s\n", functionName, tempPath)
+		if err := ensureHandlerPath(handler); err != nil {
+			errorEvent(functionName, image, fmt.Sprintf("%s is an invalid path", handler))
+			fmt.Printf("Unable to build %s, %s is an invalid path\n", image, handler)
+			fmt.Printf("Image: %s not built.\n", image)
 
-				return
+			return
+		}
+		buildDirectory := BuildDirectory
+		if options.Shrinkwrap {
+			buildDirectory = ShrinkwrapDirectory
+		}
+		tempPath = createBuildTemplate(buildDirectory, functionName, handler, language)
+		fmt.Printf("Building: %s with %s template. Please wait..\n", image, language)
+
+		// The watchdog is recorded so deploy can check it against the gateway
+		if watchdog, err := stack.TemplateWatchdog(language); err == nil {
+			labels := watchdog.Labels(language)
+			for name, value := range options.Labels {
+				labels[name] = value
 			}
+			options.Labels = labels
 		}
 
-		flagStr := buildFlagString(nocache, squash, os.Getenv("http_proxy"), os.Getenv("https_proxy"))
-		builder := strings.Split(fmt.Sprintf("docker build %s-t %s .", flagStr, image), " ")
-		ExecCommand(tempPath, builder)
-		fmt.Printf("Image: %s built.\n", image)
+		if buildInfoEnabled {
+			infoFile, err := writeBuildInfo(tempPath, language, newBuildInfo(functionName, image, handler, time.Now()))
+			if err != nil {
+				log.Fatalf("Unable to write build info for %s: %s", functionName, err)
+			}
+			fmt.Printf("Build info written to %s\n", infoFile)
+		}
 
+		if options.Shrinkwrap {
+			fmt.Printf("%s shrink-wrapped to %s\n", functionName, tempPath)
+
+			return
+		}
+	}
+
+	startEvents(tempPath, functionName, image)
+
+	if unsupported := capabilities.Unsupported(options); len(unsupported) > 0 {
+		err := fmt.Errorf("the %s builder does not support %s", backend.Name(), strings.Join(unsupported, ", "))
+		finishEvents(tempPath, err, false)
+		runExitHook()
+		log.Fatalf("The %s builder does not support %s, which the build of %s uses.", backend.Name(), strings.Join(unsupported, ", "), functionName)
+	}
+
+	if err := backend.Build(tempPath, options); err != nil {
+		finishEvents(tempPath, err, false)
+		runExitHook()
+		log.Fatalf("Unable to build %s with the %s builder: %s", image, backend.Name(), err)
+	}
+	// An image which was pushed as it was built is not in the daemon to inspect
+	finishEvents(tempPath, nil, capabilities.Daemon && !capabilities.Pushes)
+
+	if capabilities.Pushes {
+		fmt.Printf("Image: %s built and pushed.\n", image)
 	} else {
-		log.Fatalf("Language template: %s not supported. Build a custom Dockerfile instead.", language)
+		fmt.Printf("Image: %s built.\n", image)
 	}
 }
 
 // createBuildTemplate creates temporary build folder to perform a Docker build with language template
-func createBuildTemplate(functionName string, handler string, language string) string {
-	tempPath := fmt.Sprintf("./build/%s/", functionName)
+func createBuildTemplate(buildDirectory string, functionName string, handler string, language string) string {
+	tempPath := fmt.Sprintf("%s/%s/", strings.TrimRight(buildDirectory, "/"), functionName)
 	fmt.Printf("Clearing temporary build folder: %s\n", tempPath)
 
 	clearErr := os.RemoveAll(tempPath)
@@ -85,7 +154,7 @@ func createBuildTemplate(functionName string, handler string, language string) s
 	if language == "Dockerfile" {
 		language = "dockerfile"
 	}
-	CopyFiles("./template/"+language, tempPath)
+	CopyFiles(filepath.Join(stack.TemplateDirectory, language), tempPath)
 
 	// Overlay in user-function
 	CopyFiles(handler, functionPath)
@@ -93,28 +162,55 @@ func createBuildTemplate(functionName string, handler string, language string) s
 	return tempPath
 }
 
-func buildFlagString(nocache bool, squash bool, httpProxy string, httpsProxy string) string {
+// buildFlagSlice gives the build flags as separate arguments so that a
+// build-arg value containing spaces is passed whole
+func buildFlagSlice(nocache bool, squash bool, httpProxy string, httpsProxy string, buildArgMap map[string]string) []string {
 
-	buildFlags := ""
+	var buildFlags []string
 
 	if nocache {
-		buildFlags += "--no-cache "
+		buildFlags = append(buildFlags, "--no-cache")
 	}
 	if squash {
-		buildFlags += "--squash "
+		buildFlags = append(buildFlags, "--squash")
 	}
 
 	if len(httpProxy) > 0 {
-		buildFlags += fmt.Sprintf("--build-arg http_proxy=%s ", httpProxy)
+		buildFlags = append(buildFlags, "--build-arg", fmt.Sprintf("http_proxy=%s", httpProxy))
 	}
 
 	if len(httpsProxy) > 0 {
-		buildFlags += fmt.Sprintf("--build-arg https_proxy=%s ", httpsProxy)
+		buildFlags = append(buildFlags, "--build-arg", fmt.Sprintf("https_proxy=%s", httpsProxy))
+	}
+
+	buildArgNames := make([]string, 0, len(buildArgMap))
+	for name := range buildArgMap {
+		buildArgNames = append(buildArgNames, name)
+	}
+	sort.Strings(buildArgNames)
+
+	for _, name := range buildArgNames {
+		buildFlags = append(buildFlags, "--build-arg", fmt.Sprintf("%s=%s", name, buildArgMap[name]))
 	}
 
 	return buildFlags
 }
 
+// buildSecretFlagSlice gives a sorted --secret flag for each BuildKit secret
+func buildSecretFlagSlice(buildSecrets map[string]string) []string {
+	ids := make([]string, 0, len(buildSecrets))
+	for id := range buildSecrets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var flags []string
+	for _, id := range ids {
+		flags = append(flags, "--secret", fmt.Sprintf("id=%s,src=%s", id, buildSecrets[id]))
+	}
+	return flags
+}
+
 func ensureHandlerPath(handler string) error {
 	if _, err := os.Stat(handler); err != nil {
 		return err
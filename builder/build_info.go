@@ -0,0 +1,114 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/version"
+)
+
+// DefaultBuildInfoPath is where build info is written in the build context of
+// templates which do not declare build_info in their template.yml
+const DefaultBuildInfoPath = "function/version.json"
+
+// dockerfileBuildInfoPath is used for functions built from their own Dockerfile
+const dockerfileBuildInfoPath = "version.json"
+
+// BuildInfo is written into the build context so a function can report the
+// version it was built from
+type BuildInfo struct {
+	Function   string `json:"function"`
+	Image      string `json:"image"`
+	ImageTag   string `json:"image_tag"`
+	GitSHA     string `json:"git_sha,omitempty"`
+	BuildTime  string `json:"build_time"`
+	CLIVersion string `json:"cli_version"`
+}
+
+// buildInfoEnabled is set with SetBuildInfo
+var buildInfoEnabled bool
+
+// SetBuildInfo turns writing build info into each build context on or off
+func SetBuildInfo(enabled bool) {
+	buildInfoEnabled = enabled
+}
+
+// gitSHA gives the commit of the repository the handler is in, or nothing
+var gitSHA = func(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func newBuildInfo(functionName string, image string, handler string, now time.Time) BuildInfo {
+	tag := "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		tag = image[i+1:]
+	}
+
+	return BuildInfo{
+		Function:   functionName,
+		Image:      image,
+		ImageTag:   tag,
+		GitSHA:     gitSHA(handler),
+		BuildTime:  now.UTC().Format(time.RFC3339),
+		CLIVersion: version.BuildVersion(),
+	}
+}
+
+// buildInfoPath gives the path declared by the template, relative to the
+// build context
+func buildInfoPath(language string) (string, error) {
+	if strings.ToLower(language) == "dockerfile" {
+		return dockerfileBuildInfoPath, nil
+	}
+
+	langTemplate, err := stack.ParseYAMLForLanguageTemplate(filepath.Join(stack.TemplateDirectory, language, "template.yml"))
+	if err != nil {
+		return "", err
+	}
+	if len(langTemplate.BuildInfo) == 0 {
+		return DefaultBuildInfoPath, nil
+	}
+
+	path := filepath.Clean(langTemplate.BuildInfo)
+	if filepath.IsAbs(path) || path == ".." || strings.HasPrefix(path, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("build_info %s of template %s must be inside the build context", langTemplate.BuildInfo, language)
+	}
+	return path, nil
+}
+
+// writeBuildInfo writes the build info into the build context and returns
+// the file written
+func writeBuildInfo(contextPath string, language string, info BuildInfo) (string, error) {
+	path, err := buildInfoPath(language)
+	if err != nil {
+		return "", err
+	}
+
+	target := filepath.Join(contextPath, path)
+	if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+		return "", err
+	}
+
+	out, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(target, append(out, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return target, nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_newBuildInfo(t *testing.T) {
+	defer func(sha func(string) string) { gitSHA = sha }(gitSHA)
+	gitSHA = func(dir string) string { return "4c2b1a0" }
+
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+	cases := map[string]string{
+		"alexellis/url-ping:0.2":       "0.2",
+		"alexellis/url-ping":           "latest",
+		"registry:5000/team/url-ping":  "latest",
+		"registry:5000/url-ping:1.0.1": "1.0.1",
+	}
+	for image, wantTag := range cases {
+		info := newBuildInfo("url-ping", image, "./url-ping", now)
+		if info.ImageTag != wantTag {
+			t.Errorf("%s: want tag %s, got %s", image, wantTag, info.ImageTag)
+		}
+		if info.BuildTime != "2018-03-01T11:00:00Z" || info.GitSHA != "4c2b1a0" || info.Function != "url-ping" {
+			t.Errorf("%s: unexpected build info %+v", image, info)
+		}
+	}
+}
+
+func Test_writeBuildInfo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-build-info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(templateDirectory string) { stack.TemplateDirectory = templateDirectory }(stack.TemplateDirectory)
+	stack.TemplateDirectory = filepath.Join(dir, "template")
+
+	templates := map[string]string{
+		"python":  "language: python\nfprocess: python index.py\n",
+		"node-v2": "language: node-v2\nbuild_info: function/static/build.json\n",
+		"escapes": "language: escapes\nbuild_info: ../../version.json\n",
+	}
+	for name, templateYAML := range templates {
+		os.MkdirAll(filepath.Join(stack.TemplateDirectory, name), 0700)
+		ioutil.WriteFile(filepath.Join(stack.TemplateDirectory, name, "template.yml"), []byte(templateYAML), 0600)
+	}
+
+	info := BuildInfo{Function: "url-ping", Image: "alexellis/url-ping:0.2", ImageTag: "0.2"}
+	cases := []struct {
+		language string
+		want     string
+		wantErr  bool
+	}{
+		{"python", filepath.Join(dir, "build", "function", "version.json"), false},
+		{"node-v2", filepath.Join(dir, "build", "function", "static", "build.json"), false},
+		{"dockerfile", filepath.Join(dir, "build", "version.json"), false},
+		{"escapes", "", true},
+	}
+
+	for _, c := range cases {
+		written, err := writeBuildInfo(filepath.Join(dir, "build"), c.language, info)
+		if c.wantErr {
+			if err == nil {
+				t.Errorf("%s: want an error for a path outside the build context", c.language)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.language, err)
+		}
+		if written != c.want {
+			t.Errorf("%s: want %s, got %s", c.language, c.want, written)
+		}
+
+		var read BuildInfo
+		data, _ := ioutil.ReadFile(written)
+		if err := json.Unmarshal(data, &read); err != nil || read != info {
+			t.Errorf("%s: want %+v, got %+v (%v)", c.language, info, read, err)
+		}
+	}
+}
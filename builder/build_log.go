@@ -0,0 +1,34 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// logDir is where the output of each function's build is written, it goes
+// to stdout when empty
+var logDir string
+
+// SetLogDir writes the output of the commands which build each function to
+// LogFile instead of stdout, an empty dir goes back to stdout
+func SetLogDir(dir string) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	logDir = dir
+}
+
+// LogFile is the file a function's build output is written to in dir
+func LogFile(dir string, function string) string {
+	return filepath.Join(dir, function+".log")
+}
+
+func openBuildLog(dir string, function string) (*os.File, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return os.Create(LogFile(dir, function))
+}
@@ -0,0 +1,48 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_SetLogDir_WritesCommandOutput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build-logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	SetLogDir(dir)
+	defer SetLogDir("")
+
+	var events []Event
+	SetEventListener(func(event Event) { events = append(events, event) })
+	defer SetEventListener(nil)
+
+	context, err := ioutil.TempDir("", "build-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(context)
+
+	startEvents(context, "fn", "fn:latest")
+	ExecCommand(context, []string{"echo", "Step 1/1 : FROM alpine"})
+	finishEvents(context, fmt.Errorf("push denied"), false)
+
+	data, err := ioutil.ReadFile(LogFile(dir, "fn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Step 1/1 : FROM alpine\npush denied\n"; string(data) != want {
+		t.Errorf("want log %q, got %q", want, string(data))
+	}
+
+	if len(events) != 2 || events[0].Type != EventStart || events[1].Type != EventError {
+		t.Errorf("want start and error events given to the listener, got %+v", events)
+	}
+}
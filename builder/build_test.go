@@ -0,0 +1,83 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_buildFlagSlice(t *testing.T) {
+	testCases := []struct {
+		title       string
+		nocache     bool
+		squash      bool
+		httpProxy   string
+		buildArgMap map[string]string
+		expected    []string
+	}{
+		{
+			title: "No flags",
+		},
+		{
+			title:    "No cache and squash",
+			nocache:  true,
+			squash:   true,
+			expected: []string{"--no-cache", "--squash"},
+		},
+		{
+			title:     "Proxy and build-args are sorted",
+			httpProxy: "http://proxy:3128",
+			buildArgMap: map[string]string{
+				"PYTHON_BASE": "acme/python-base:1.0",
+				"NODE_BASE":   "acme/node-base:1.0",
+			},
+			expected: []string{"--build-arg", "http_proxy=http://proxy:3128", "--build-arg", "NODE_BASE=acme/node-base:1.0", "--build-arg", "PYTHON_BASE=acme/python-base:1.0"},
+		},
+		{
+			title:       "Build-arg values with spaces are kept whole",
+			buildArgMap: map[string]string{"ADDITIONAL_PACKAGE": "make automake gcc"},
+			expected:    []string{"--build-arg", "ADDITIONAL_PACKAGE=make automake gcc"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.title, func(t *testing.T) {
+			flags := buildFlagSlice(testCase.nocache, testCase.squash, testCase.httpProxy, "", testCase.buildArgMap)
+			if !reflect.DeepEqual(flags, testCase.expected) {
+				t.Errorf("want: %q, got: %q", testCase.expected, flags)
+			}
+		})
+	}
+}
+
+func Test_BaseImageBuildArg(t *testing.T) {
+	testCases := map[string]string{
+		"python-base": "PYTHON_BASE",
+		"base":        "BASE",
+		"node.8-base": "NODE_8_BASE",
+	}
+
+	for name, expected := range testCases {
+		if arg := BaseImageBuildArg(name); arg != expected {
+			t.Errorf("base image %s, want: %s, got: %s", name, expected, arg)
+		}
+	}
+}
+
+func Test_buildSecretFlagSlice(t *testing.T) {
+	flags := buildSecretFlagSlice(map[string]string{
+		"npmrc":  "/tmp/secrets/npmrc",
+		"gitcfg": "/tmp/secrets/gitcfg",
+	})
+
+	expected := []string{"--secret", "id=gitcfg,src=/tmp/secrets/gitcfg", "--secret", "id=npmrc,src=/tmp/secrets/npmrc"}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("want: %q, got: %q", expected, flags)
+	}
+
+	if flags := buildSecretFlagSlice(nil); len(flags) != 0 {
+		t.Errorf("want no flags, got: %q", flags)
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Statuses of a dispatched build job
+const (
+	DispatchQueued    = "queued"
+	DispatchRunning   = "running"
+	DispatchSucceeded = "succeeded"
+	DispatchFailed    = "failed"
+)
+
+// dispatchPollInterval is how often the status of a job is read, it is
+// shortened in tests
+var dispatchPollInterval = 2 * time.Second
+
+// DispatchJob is the build job posted to the webhook of a build service
+type DispatchJob struct {
+	Function string `json:"function"`
+	Image    string `json:"image"`
+	Language string `json:"language,omitempty"`
+
+	Context DispatchContext `json:"context"`
+
+	Dockerfile string            `json:"dockerfile,omitempty"`
+	BuildArgs  map[string]string `json:"build_args,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Platform   string            `json:"platform,omitempty"`
+	NoCache    bool              `json:"no_cache,omitempty"`
+	CacheFrom  []string          `json:"cache_from,omitempty"`
+}
+
+// DispatchContext is the shrink-wrapped build context, a .tar.gz which is
+// base64 encoded in JSON, and its digest
+type DispatchContext struct {
+	Digest  string `json:"digest"`
+	Archive []byte `json:"archive"`
+}
+
+// DispatchStatus is returned by the build service when a job is accepted
+// and each time its status is read
+type DispatchStatus struct {
+	ID string `json:"id,omitempty"`
+
+	// StatusURL is read until the job finishes, it may be relative to the
+	// webhook and defaults to the webhook's URL followed by /ID
+	StatusURL string `json:"status_url,omitempty"`
+
+	Status  string `json:"status,omitempty"`
+	Message string `json:"message,omitempty"`
+	Digest  string `json:"digest,omitempty"`
+	LogsURL string `json:"logs_url,omitempty"`
+}
+
+// DispatchBuilder posts each build to the webhook of an external build
+// service, which builds and pushes the image, and polls until it is done
+type DispatchBuilder struct {
+	URL string
+
+	// Token is sent as a bearer token when it is set
+	Token string
+
+	// Timeout is how long a job may take once it is accepted
+	Timeout time.Duration
+}
+
+// Name of the builder
+func (DispatchBuilder) Name() string { return "dispatch" }
+
+// Command is empty as nothing is run locally
+func (DispatchBuilder) Command() string { return "" }
+
+// Capabilities of the builder, secrets are not sent to the build service
+func (DispatchBuilder) Capabilities() Capabilities {
+	return Capabilities{BuildArgs: true, Platforms: true, MultiPlatform: true, CacheFrom: true, Dockerfile: true, Pushes: true}
+}
+
+// Build posts the context as a job and waits for it to finish
+func (d DispatchBuilder) Build(contextPath string, options BuildOptions) error {
+	archive, err := archiveContext(contextPath)
+	if err != nil {
+		return fmt.Errorf("unable to archive the build context %s: %s", contextPath, err)
+	}
+
+	job := DispatchJob{
+		Function:   options.FunctionName,
+		Image:      options.Image,
+		Language:   options.Language,
+		Context:    DispatchContext{Digest: fmt.Sprintf("sha256:%x", sha256.Sum256(archive)), Archive: archive},
+		Dockerfile: options.Dockerfile,
+		BuildArgs:  options.BuildArgs,
+		Labels:     options.Labels,
+		Platform:   options.Platform,
+		NoCache:    options.NoCache,
+		CacheFrom:  options.CacheFrom,
+	}
+
+	accepted, err := d.submit(job)
+	if err != nil {
+		return err
+	}
+	statusURL, err := d.statusURL(accepted)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Dispatched %s to %s as job %s.\n", options.Image, d.URL, accepted.ID)
+
+	status, err := d.wait(accepted, statusURL)
+	if err != nil {
+		return err
+	}
+	if status.Status != DispatchSucceeded {
+		message := fmt.Sprintf("job %s %s", accepted.ID, status.Status)
+		if len(status.Message) > 0 {
+			message += ": " + status.Message
+		}
+		if len(status.LogsURL) > 0 {
+			message += ", see " + status.LogsURL
+		}
+		return fmt.Errorf("%s", message)
+	}
+
+	if len(status.Digest) > 0 {
+		fmt.Printf("Job %s pushed %s@%s.\n", accepted.ID, options.Image, status.Digest)
+	}
+	return nil
+}
+
+func (d DispatchBuilder) submit(job DispatchJob) (DispatchStatus, error) {
+	body, err := json.Marshal(job)
+	if err != nil {
+		return DispatchStatus{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return DispatchStatus{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	status, location, err := d.do(req)
+	if err != nil {
+		return DispatchStatus{}, fmt.Errorf("unable to dispatch the build of %s: %s", job.Image, err)
+	}
+	if len(status.StatusURL) == 0 {
+		status.StatusURL = location
+	}
+	return status, nil
+}
+
+// statusURL resolves where the status of an accepted job is read
+func (d DispatchBuilder) statusURL(accepted DispatchStatus) (string, error) {
+	if len(accepted.StatusURL) == 0 && len(accepted.ID) == 0 {
+		return "", fmt.Errorf("the build service at %s gave neither a job id nor a status_url", d.URL)
+	}
+
+	base, err := url.Parse(d.URL)
+	if err != nil {
+		return "", err
+	}
+	if len(accepted.StatusURL) == 0 {
+		return strings.TrimSuffix(d.URL, "/") + "/" + url.PathEscape(accepted.ID), nil
+	}
+	statusURL, err := base.Parse(accepted.StatusURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid status_url %q: %s", accepted.StatusURL, err)
+	}
+	return statusURL.String(), nil
+}
+
+// wait polls the status of a job until it succeeds, fails or times out
+func (d DispatchBuilder) wait(accepted DispatchStatus, statusURL string) (DispatchStatus, error) {
+	timeout := d.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+
+	last := accepted.Status
+	for {
+		req, err := http.NewRequest(http.MethodGet, statusURL, nil)
+		if err != nil {
+			return DispatchStatus{}, err
+		}
+		status, _, err := d.do(req)
+		if err != nil {
+			return DispatchStatus{}, fmt.Errorf("unable to read the status of job %s: %s", accepted.ID, err)
+		}
+
+		switch status.Status {
+		case DispatchSucceeded, DispatchFailed:
+			return status, nil
+		}
+		if status.Status != last {
+			fmt.Printf("Job %s is %s.\n", accepted.ID, status.Status)
+			last = status.Status
+		}
+
+		if time.Now().After(deadline) {
+			return DispatchStatus{}, fmt.Errorf("job %s did not finish within %s", accepted.ID, timeout)
+		}
+		time.Sleep(dispatchPollInterval)
+	}
+}
+
+// do sends a request to the build service and decodes the status it gives
+func (d DispatchBuilder) do(req *http.Request) (DispatchStatus, string, error) {
+	if len(d.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+d.Token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := http.Client{Timeout: 60 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return DispatchStatus{}, "", err
+	}
+	defer res.Body.Close()
+
+	body, _ := ioutil.ReadAll(res.Body)
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+	default:
+		return DispatchStatus{}, "", fmt.Errorf("unexpected status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var status DispatchStatus
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &status); err != nil {
+			return DispatchStatus{}, "", fmt.Errorf("unable to parse the response: %s", err)
+		}
+	}
+	return status, res.Header.Get("Location"), nil
+}
+
+// archiveContext writes the files of a build context to a .tar.gz with
+// their paths relative to the context
+func archiveContext(contextPath string) ([]byte, error) {
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	if err := addContextFiles(tarWriter, contextPath, ""); err != nil {
+		return nil, err
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
+
+// addContextFiles writes the files of a build context to tarWriter with their
+// paths relative to the context under prefix, i.e. context/
+func addContextFiles(tarWriter *tar.Writer, contextPath string, prefix string) error {
+	return filepath.Walk(contextPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(contextPath, path)
+		if err != nil || relative == "." {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = prefix + filepath.ToSlash(relative)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		_, err = io.Copy(tarWriter, file)
+		return err
+	})
+}
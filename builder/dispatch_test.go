@@ -0,0 +1,150 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dispatchContext(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "dispatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(dir, "function"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "function", "handler.py"), []byte("def handle(req):\n"), 0600)
+	return dir
+}
+
+func archiveNames(t *testing.T, archive []byte) []string {
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+
+	var names []string
+	for {
+		header, err := tarReader.Next()
+		if err != nil {
+			break
+		}
+		names = append(names, header.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Test_DispatchBuilder_PollsUntilSucceeded(t *testing.T) {
+	dir := dispatchContext(t)
+	defer os.RemoveAll(dir)
+
+	defer func(original time.Duration) { dispatchPollInterval = original }(dispatchPollInterval)
+	dispatchPollInterval = time.Millisecond
+
+	var job DispatchJob
+	var auth string
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/jobs":
+			auth = r.Header.Get("Authorization")
+			json.NewDecoder(r.Body).Decode(&job)
+			w.WriteHeader(http.StatusAccepted)
+			w.Write([]byte(`{"id": "42", "status": "queued", "status_url": "/jobs/42/status"}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/jobs/42/status":
+			polls++
+			if polls < 3 {
+				w.Write([]byte(`{"status": "running"}`))
+				return
+			}
+			w.Write([]byte(`{"status": "succeeded", "digest": "sha256:abc"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	backend := DispatchBuilder{URL: server.URL + "/jobs", Token: "s3cret", Timeout: time.Minute}
+	err := backend.Build(dir, BuildOptions{
+		Image:        "alexellis/fn:0.1",
+		FunctionName: "fn",
+		Language:     "python3",
+		BuildArgs:    map[string]string{"ADDITIONAL_PACKAGE": "git"},
+		Platform:     "linux/arm64",
+	})
+	if err != nil {
+		t.Fatalf("want the build to succeed, got %s", err)
+	}
+
+	if auth != "Bearer s3cret" {
+		t.Errorf("want the token sent, got %q", auth)
+	}
+	if polls != 3 {
+		t.Errorf("want 3 polls, got %d", polls)
+	}
+	if job.Image != "alexellis/fn:0.1" || job.Function != "fn" || job.Platform != "linux/arm64" || job.BuildArgs["ADDITIONAL_PACKAGE"] != "git" {
+		t.Errorf("unexpected job %+v", job)
+	}
+	if !strings.HasPrefix(job.Context.Digest, "sha256:") {
+		t.Errorf("want the digest of the context, got %q", job.Context.Digest)
+	}
+	if got := strings.Join(archiveNames(t, job.Context.Archive), " "); got != "Dockerfile function/ function/handler.py" {
+		t.Errorf("unexpected context archive: %s", got)
+	}
+}
+
+func Test_DispatchBuilder_Failed(t *testing.T) {
+	dir := dispatchContext(t)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"id": "7"}`))
+			return
+		}
+		if r.URL.Path != "/jobs/7" {
+			t.Errorf("want the status read from the webhook followed by the id, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"status": "failed", "message": "npm install failed", "logs_url": "https://builds/7/logs"}`))
+	}))
+	defer server.Close()
+
+	err := DispatchBuilder{URL: server.URL + "/jobs"}.Build(dir, BuildOptions{Image: "fn:0.1"})
+	if err == nil {
+		t.Fatalf("want the failed job to be an error")
+	}
+	if want := "job 7 failed: npm install failed, see https://builds/7/logs"; err.Error() != want {
+		t.Errorf("want %q, got %q", want, err.Error())
+	}
+}
+
+func Test_DispatchBuilder_Rejected(t *testing.T) {
+	dir := dispatchContext(t)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("bad token"))
+	}))
+	defer server.Close()
+
+	err := DispatchBuilder{URL: server.URL}.Build(dir, BuildOptions{Image: "fn:0.1"})
+	if err == nil || !strings.Contains(err.Error(), "unexpected status 401: bad token") {
+		t.Errorf("want the rejection given, got %v", err)
+	}
+}
@@ -0,0 +1,288 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Types of the build events written by SetEventWriter
+const (
+	EventStart    = "start"
+	EventProgress = "progress"
+	EventComplete = "complete"
+	EventError    = "error"
+)
+
+// Event is one line of the machine-readable build output
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Function string    `json:"function,omitempty"`
+	Image    string    `json:"image,omitempty"`
+	Message  string    `json:"message,omitempty"`
+
+	// Step and Steps are set for progress lines which give the layer being
+	// built, i.e. "Step 3/9" or BuildKit's "[3/9]"
+	Step  int `json:"step,omitempty"`
+	Steps int `json:"steps,omitempty"`
+
+	// Duration is in seconds, given when the build completes or fails
+	Duration float64 `json:"duration,omitempty"`
+	Digest   string  `json:"digest,omitempty"`
+}
+
+// eventBuild is the function built in a context folder
+type eventBuild struct {
+	function string
+	image    string
+	started  time.Time
+
+	// log is given the output of the build when SetLogDir was called
+	log io.WriteCloser
+}
+
+var (
+	eventWriter   io.Writer
+	eventListener func(Event)
+	eventLock     sync.Mutex
+
+	// exitHook runs before a failed build exits
+	exitHook func()
+
+	// eventBuilds is keyed by the build context so that the output of the
+	// commands run there, which may be in parallel, is given to its function
+	eventBuilds = map[string]eventBuild{}
+
+	// imageDigest gives the ID of an image in the local daemon, it is swapped
+	// out in tests
+	imageDigest = func(image string) string {
+		out, err := exec.Command("docker", "image", "inspect", "--format", "{{.Id}}", image).Output()
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+)
+
+var (
+	dockerStep   = regexp.MustCompile(`^Step (\d+)/(\d+) :`)
+	buildKitStep = regexp.MustCompile(`^#\d+ \[[^\]]*?(\d+)/(\d+)\]`)
+)
+
+// SetEventWriter writes each build event to w as a line of JSON and the
+// output of the build commands as progress events, nil goes back to text
+func SetEventWriter(w io.Writer) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	eventWriter = w
+	eventBuilds = map[string]eventBuild{}
+}
+
+// SetEventListener calls listener with each build event, nil removes it. It
+// must not emit events itself.
+func SetEventListener(listener func(Event)) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	eventListener = listener
+}
+
+// SetExitHook runs hook before a failed build exits the process, so that what
+// was built until then can be reported, nil removes it
+func SetExitHook(hook func()) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	exitHook = hook
+}
+
+func runExitHook() {
+	eventLock.Lock()
+	hook := exitHook
+	eventLock.Unlock()
+
+	if hook != nil {
+		hook()
+	}
+}
+
+func eventsWritten() bool {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	return eventWriter != nil
+}
+
+// tracking is true when builds are followed by their context folder, the
+// caller holds eventLock
+func tracking() bool {
+	return eventWriter != nil || eventListener != nil || len(logDir) > 0
+}
+
+// EmitEvent writes an event when SetEventWriter has been given a writer and
+// gives it to the listener of SetEventListener
+func EmitEvent(event Event) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	if eventWriter == nil && eventListener == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	if eventListener != nil {
+		eventListener(event)
+	}
+	if eventWriter == nil {
+		return
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	eventWriter.Write(append(data, '\n'))
+}
+
+// startEvents emits the start of a function's build in the context folder
+// and opens its log file
+func startEvents(contextPath string, function string, image string) {
+	eventLock.Lock()
+	if !tracking() {
+		eventLock.Unlock()
+		return
+	}
+	build := eventBuild{function: function, image: image, started: time.Now()}
+	dir := logDir
+	eventLock.Unlock()
+
+	if len(dir) > 0 {
+		logFile, err := openBuildLog(dir, function)
+		if err != nil {
+			log.Fatalf("Unable to write the build log of %s: %s", function, err)
+		}
+		build.log = logFile
+	}
+
+	eventLock.Lock()
+	eventBuilds[contextPath] = build
+	eventLock.Unlock()
+
+	EmitEvent(Event{Type: EventStart, Function: function, Image: image})
+}
+
+// finishEvents emits the completion of the build in the context folder, or
+// its failure when err is set
+func finishEvents(contextPath string, err error, daemon bool) {
+	build, ok := lookupEventBuild(contextPath)
+	if !ok {
+		return
+	}
+
+	eventLock.Lock()
+	delete(eventBuilds, contextPath)
+	eventLock.Unlock()
+
+	if build.log != nil {
+		if err != nil {
+			fmt.Fprintf(build.log, "%s\n", RedactOutput(err.Error()))
+		}
+		build.log.Close()
+	}
+
+	event := Event{
+		Type:     EventComplete,
+		Function: build.function,
+		Image:    build.image,
+		Duration: time.Since(build.started).Seconds(),
+	}
+	if err != nil {
+		event.Type = EventError
+		event.Message = RedactOutput(err.Error())
+	} else if daemon {
+		event.Digest = imageDigest(build.image)
+	}
+	EmitEvent(event)
+}
+
+// errorEvent emits a failure before a build has a context folder
+func errorEvent(function string, image string, message string) {
+	EmitEvent(Event{Type: EventError, Function: function, Image: image, Message: RedactOutput(message)})
+}
+
+func lookupEventBuild(contextPath string) (eventBuild, bool) {
+	eventLock.Lock()
+	defer eventLock.Unlock()
+
+	if !tracking() {
+		return eventBuild{}, false
+	}
+	build, ok := eventBuilds[contextPath]
+	return build, ok
+}
+
+// progressEvent gives the event for a line of build output
+func progressEvent(build eventBuild, line string) Event {
+	event := Event{Type: EventProgress, Function: build.function, Image: build.image, Message: line}
+	for _, pattern := range []*regexp.Regexp{dockerStep, buildKitStep} {
+		if match := pattern.FindStringSubmatch(line); match != nil {
+			event.Step, _ = strconv.Atoi(match[1])
+			event.Steps, _ = strconv.Atoi(match[2])
+			break
+		}
+	}
+	return event
+}
+
+// progressWriter emits each complete line written to it as a progress event,
+// stdout and stderr may write to it at the same time
+type progressWriter struct {
+	build   eventBuild
+	pending []byte
+	lock    sync.Mutex
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.pending = append(w.pending, p...)
+	for {
+		end := bytes.IndexAny(w.pending, "\r\n")
+		if end < 0 {
+			break
+		}
+		w.emit(w.pending[:end])
+		w.pending = w.pending[end+1:]
+	}
+	return len(p), nil
+}
+
+// Close emits what is left of the last line
+func (w *progressWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.emit(w.pending)
+	w.pending = nil
+	return nil
+}
+
+func (w *progressWriter) emit(line []byte) {
+	if text := strings.TrimSpace(string(line)); len(text) > 0 {
+		EmitEvent(progressEvent(w.build, text))
+	}
+}
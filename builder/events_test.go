@@ -0,0 +1,117 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func readEvents(t *testing.T, out *bytes.Buffer) []Event {
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("%q is not a JSON event: %s", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func Test_progressEvent_Steps(t *testing.T) {
+	build := eventBuild{function: "fn", image: "fn:latest"}
+
+	cases := []struct {
+		line  string
+		step  int
+		steps int
+	}{
+		{"Step 3/9 : RUN npm i", 3, 9},
+		{"#7 [build 2/5] COPY . .", 2, 5},
+		{"#7 [4/6] RUN go build", 4, 6},
+		{"Successfully built 1a2b3c", 0, 0},
+	}
+	for _, c := range cases {
+		event := progressEvent(build, c.line)
+		if event.Type != EventProgress || event.Function != "fn" || event.Message != c.line {
+			t.Errorf("%q: want a progress event for fn, got %+v", c.line, event)
+		}
+		if event.Step != c.step || event.Steps != c.steps {
+			t.Errorf("%q: want step %d/%d, got %d/%d", c.line, c.step, c.steps, event.Step, event.Steps)
+		}
+	}
+}
+
+func Test_progressWriter_EmitsLines(t *testing.T) {
+	var out bytes.Buffer
+	SetEventWriter(&out)
+	defer SetEventWriter(nil)
+
+	w := &progressWriter{build: eventBuild{function: "fn"}}
+	fmt.Fprint(w, "Step 1/2 : FROM alpine\nStep 2")
+	fmt.Fprint(w, "/2 : RUN true\r\n\nlast")
+	w.Close()
+
+	events := readEvents(t, &out)
+	var messages []string
+	for _, event := range events {
+		messages = append(messages, event.Message)
+	}
+	want := "Step 1/2 : FROM alpine|Step 2/2 : RUN true|last"
+	if got := strings.Join(messages, "|"); got != want {
+		t.Errorf("want messages %q, got %q", want, got)
+	}
+}
+
+func Test_finishEvents_CompleteWithDigest(t *testing.T) {
+	var out bytes.Buffer
+	SetEventWriter(&out)
+	defer SetEventWriter(nil)
+
+	defer func(original func(string) string) { imageDigest = original }(imageDigest)
+	imageDigest = func(image string) string { return "sha256:abc" }
+
+	startEvents("./build/fn", "fn", "fn:latest")
+	finishEvents("./build/fn", nil, true)
+
+	events := readEvents(t, &out)
+	if len(events) != 2 {
+		t.Fatalf("want start and complete events, got %+v", events)
+	}
+	if events[0].Type != EventStart || events[0].Image != "fn:latest" {
+		t.Errorf("want a start event for fn:latest, got %+v", events[0])
+	}
+	if events[1].Type != EventComplete || events[1].Digest != "sha256:abc" || events[1].Time.IsZero() {
+		t.Errorf("want a complete event with the digest, got %+v", events[1])
+	}
+	if _, ok := lookupEventBuild("./build/fn"); ok {
+		t.Errorf("want the build forgotten once finished")
+	}
+}
+
+func Test_finishEvents_Error(t *testing.T) {
+	var out bytes.Buffer
+	SetEventWriter(&out)
+	defer SetEventWriter(nil)
+
+	startEvents("./build/fn", "fn", "fn:latest")
+	finishEvents("./build/fn", fmt.Errorf("exit status 1"), true)
+
+	events := readEvents(t, &out)
+	last := events[len(events)-1]
+	if last.Type != EventError || last.Message != "exit status 1" || len(last.Digest) > 0 {
+		t.Errorf("want an error event without a digest, got %+v", last)
+	}
+}
+
+func Test_EmitEvent_DisabledByDefault(t *testing.T) {
+	startEvents("./build/fn", "fn", "fn:latest")
+	if _, ok := lookupEventBuild("./build/fn"); ok {
+		t.Errorf("want no builds tracked without an event writer")
+	}
+}
@@ -4,7 +4,9 @@
 package builder
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -14,14 +16,89 @@ import (
 
 // ExecCommand run a system command
 func ExecCommand(tempPath string, builder []string) {
+	execCommand(tempPath, builder, nil, nil)
+}
+
+// ExecCommandWithOutput runs a system command like ExecCommand and also
+// returns what it wrote to stdout so that it can be parsed
+func ExecCommandWithOutput(tempPath string, builder []string) string {
+	var output bytes.Buffer
+	execCommand(tempPath, builder, nil, &output)
+	return output.String()
+}
+
+// ExecCommandWithEnv runs a system command like ExecCommand with env added to
+// its environment, i.e. DOCKER_CONFIG
+func ExecCommandWithEnv(tempPath string, builder []string, env []string) {
+	execCommand(tempPath, builder, env, nil)
+}
+
+// ExecCommandWithEnvOutput runs a system command like ExecCommandWithEnv and
+// also returns what it wrote to stdout
+func ExecCommandWithEnvOutput(tempPath string, builder []string, env []string) string {
+	var output bytes.Buffer
+	execCommand(tempPath, builder, env, &output)
+	return output.String()
+}
+
+// execCommand adds env to the environment of the command when it is set
+func execCommand(tempPath string, builder []string, env []string, capture io.Writer) {
 	targetCmd := exec.Command(builder[0], builder[1:]...)
 	targetCmd.Dir = tempPath
-	targetCmd.Stdout = os.Stdout
-	targetCmd.Stderr = os.Stderr
+	if len(env) > 0 {
+		targetCmd.Env = append(os.Environ(), env...)
+	}
+	var outputs []io.Closer
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+
+	if build, ok := lookupEventBuild(tempPath); ok {
+		if build.log != nil {
+			stdout, stderr = build.log, build.log
+		}
+
+		// The output of a build becomes progress events with --output json
+		if eventsWritten() {
+			progress := &progressWriter{build: build}
+			outputs = append(outputs, progress)
+
+			if build.log != nil {
+				stdout = io.MultiWriter(build.log, progress)
+				stderr = stdout
+			} else {
+				stdout, stderr = progress, progress
+			}
+		}
+	}
+	targetCmd.Stdout = stdout
+	targetCmd.Stderr = stderr
+
+	// Output is only piped through when there are secrets to mask so that
+	// docker keeps its interactive progress otherwise
+	if activeRedactor != nil {
+		redactedStdout := activeRedactor.Writer(stdout)
+		redactedStderr := activeRedactor.Writer(stderr)
+		// The redacted writers are closed first to flush into the others
+		outputs = append([]io.Closer{redactedStdout, redactedStderr}, outputs...)
+
+		targetCmd.Stdout = redactedStdout
+		targetCmd.Stderr = redactedStderr
+	}
+
+	if capture != nil {
+		targetCmd.Stdout = io.MultiWriter(targetCmd.Stdout, capture)
+	}
+
 	targetCmd.Start()
 	err := targetCmd.Wait()
+
+	for _, output := range outputs {
+		output.Close()
+	}
+
 	if err != nil {
-		errString := fmt.Sprintf("ERROR - Could not execute command: %s", builder)
-		log.Fatalf(aec.RedF.Apply(errString))
+		errString := RedactOutput(fmt.Sprintf("ERROR - Could not execute command: %s", builder))
+		finishEvents(tempPath, fmt.Errorf("%s: %s", errString, err), false)
+		runExitHook()
+		log.Fatal(aec.RedF.Apply(errString))
 	}
 }
@@ -0,0 +1,286 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// FingerprintDirectory keeps the fingerprint of each function's last build
+var FingerprintDirectory = "./build/.fingerprints"
+
+// FileHash is a file in a build context and the SHA256 of its contents
+type FileHash struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Fingerprint lists the inputs of a function's build which decide whether
+// Docker can reuse the layers cached by a previous build
+type Fingerprint struct {
+	Function string `json:"function"`
+	Language string `json:"language"`
+	Platform string `json:"platform,omitempty"`
+
+	// TemplateDigest hashes every file of the language template
+	TemplateDigest string `json:"template_digest,omitempty"`
+
+	// BuildArgs holds a hash of each value, as build-args may be secrets
+	BuildArgs map[string]string `json:"build_args,omitempty"`
+
+	// Files is the build context, with the handler under function/ for templates
+	Files []FileHash `json:"files"`
+
+	// Digest hashes all of the inputs above
+	Digest string `json:"digest"`
+}
+
+// NewFingerprint hashes the inputs of a build without preparing its build context
+func NewFingerprint(handler string, functionName string, language string, buildArgMap map[string]string, platform string) (*Fingerprint, error) {
+	fingerprint := &Fingerprint{
+		Function:  functionName,
+		Language:  language,
+		Platform:  platform,
+		BuildArgs: map[string]string{},
+	}
+
+	files := map[string]FileHash{}
+	if strings.ToLower(language) == "dockerfile" {
+		if err := hashFiles(handler, "", files); err != nil {
+			return nil, err
+		}
+	} else {
+		templateFiles := map[string]FileHash{}
+		if err := hashFiles(filepath.Join(stack.TemplateDirectory, language), "", templateFiles); err != nil {
+			return nil, err
+		}
+		fingerprint.TemplateDigest = digestOf(sortedFileHashes(templateFiles))
+
+		// The handler is overlaid on the template's function folder
+		for path, file := range templateFiles {
+			files[path] = file
+		}
+		if err := hashFiles(handler, "function", files); err != nil {
+			return nil, err
+		}
+	}
+	fingerprint.Files = sortedFileHashes(files)
+
+	args := proxyBuildArgs()
+	for name, value := range buildArgMap {
+		args[name] = value
+	}
+	for name, value := range args {
+		sum := sha256.Sum256([]byte(value))
+		fingerprint.BuildArgs[name] = "sha256:" + hex.EncodeToString(sum[:])[:12]
+	}
+
+	fingerprint.Digest = fingerprint.digest()
+	return fingerprint, nil
+}
+
+// hashFiles adds each file under root to files, keyed by its slash separated
+// path below prefix
+func hashFiles(root string, prefix string, files map[string]FileHash) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		key := filepath.ToSlash(filepath.Join(prefix, rel))
+		files[key] = FileHash{Path: key, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(data))}
+		return nil
+	})
+}
+
+func sortedFileHashes(files map[string]FileHash) []FileHash {
+	hashes := make([]FileHash, 0, len(files))
+	for _, file := range files {
+		hashes = append(hashes, file)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return hashes[i].Path < hashes[j].Path
+	})
+	return hashes
+}
+
+func digestOf(files []FileHash) string {
+	hash := sha256.New()
+	for _, file := range files {
+		fmt.Fprintf(hash, "%s %s\n", file.SHA256, file.Path)
+	}
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+func (f *Fingerprint) digest() string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "language %s\nplatform %s\ntemplate %s\n", f.Language, f.Platform, f.TemplateDigest)
+	for _, name := range sortedKeys(f.BuildArgs) {
+		fmt.Fprintf(hash, "arg %s %s\n", name, f.BuildArgs[name])
+	}
+	fmt.Fprintf(hash, "files %s\n", digestOf(f.Files))
+	return "sha256:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func fingerprintPath(functionName string) string {
+	return filepath.Join(FingerprintDirectory, functionName+".json")
+}
+
+// SaveFingerprint records the fingerprint of a function's latest build
+func SaveFingerprint(fingerprint *Fingerprint) error {
+	if err := os.MkdirAll(FingerprintDirectory, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(fingerprint, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fingerprintPath(fingerprint.Function), data, 0600)
+}
+
+// LastFingerprint reads the fingerprint saved by a function's last build, it
+// is nil when the function has not been built
+func LastFingerprint(functionName string) (*Fingerprint, error) {
+	data, err := ioutil.ReadFile(fingerprintPath(functionName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var fingerprint Fingerprint
+	if err := json.Unmarshal(data, &fingerprint); err != nil {
+		return nil, fmt.Errorf("unable to read the last fingerprint of %s: %s", functionName, err)
+	}
+	return &fingerprint, nil
+}
+
+// ContextSize is the total size of the files in the build context
+func (f *Fingerprint) ContextSize() int64 {
+	var size int64
+	for _, file := range f.Files {
+		size += file.Size
+	}
+	return size
+}
+
+// Diff lists the inputs which changed since the previous fingerprint
+func (f *Fingerprint) Diff(previous *Fingerprint) []string {
+	var changes []string
+	if f.Language != previous.Language {
+		changes = append(changes, fmt.Sprintf("language changed from %s to %s", previous.Language, f.Language))
+	}
+	if f.Platform != previous.Platform {
+		changes = append(changes, fmt.Sprintf("platform changed from %q to %q", previous.Platform, f.Platform))
+	}
+	if f.TemplateDigest != previous.TemplateDigest {
+		changes = append(changes, "template changed")
+	}
+
+	for _, name := range sortedKeys(mergeKeys(f.BuildArgs, previous.BuildArgs)) {
+		now, hasNow := f.BuildArgs[name]
+		before, hadBefore := previous.BuildArgs[name]
+		switch {
+		case !hadBefore:
+			changes = append(changes, "build-arg added: "+name)
+		case !hasNow:
+			changes = append(changes, "build-arg removed: "+name)
+		case now != before:
+			changes = append(changes, "build-arg changed: "+name)
+		}
+	}
+
+	files := map[string]string{}
+	for _, file := range f.Files {
+		files[file.Path] = file.SHA256
+	}
+	previousFiles := map[string]string{}
+	for _, file := range previous.Files {
+		previousFiles[file.Path] = file.SHA256
+	}
+	for _, path := range sortedKeys(mergeKeys(files, previousFiles)) {
+		now, hasNow := files[path]
+		before, hadBefore := previousFiles[path]
+		switch {
+		case !hadBefore:
+			changes = append(changes, "file added: "+path)
+		case !hasNow:
+			changes = append(changes, "file removed: "+path)
+		case now != before:
+			changes = append(changes, "file changed: "+path)
+		}
+	}
+	return changes
+}
+
+func mergeKeys(a map[string]string, b map[string]string) map[string]string {
+	keys := map[string]string{}
+	for key := range a {
+		keys[key] = ""
+	}
+	for key := range b {
+		keys[key] = ""
+	}
+	return keys
+}
+
+// Write prints every input of the fingerprint
+func (f *Fingerprint) Write(w io.Writer) {
+	fmt.Fprintf(w, "Function: %s\n", f.Function)
+	fmt.Fprintf(w, "Language: %s\n", f.Language)
+	if len(f.Platform) > 0 {
+		fmt.Fprintf(w, "Platform: %s\n", f.Platform)
+	}
+	if len(f.TemplateDigest) > 0 {
+		fmt.Fprintf(w, "Template digest: %s\n", f.TemplateDigest)
+	}
+
+	if len(f.BuildArgs) > 0 {
+		fmt.Fprintln(w, "Build args:")
+		for _, name := range sortedKeys(f.BuildArgs) {
+			fmt.Fprintf(w, "  %s=%s\n", name, f.BuildArgs[name])
+		}
+	}
+
+	fmt.Fprintf(w, "Files (%d):\n", len(f.Files))
+	for _, file := range f.Files {
+		fmt.Fprintf(w, "  %s  %s\n", file.SHA256[:12], file.Path)
+	}
+	fmt.Fprintf(w, "Digest: %s\n", f.Digest)
+}
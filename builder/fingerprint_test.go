@@ -0,0 +1,117 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func writeFingerprintFiles(t *testing.T, root string, files map[string]string) {
+	for path, contents := range files {
+		full := filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(full, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func Test_NewFingerprint_Diff(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-fingerprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(templateDirectory string) { stack.TemplateDirectory = templateDirectory }(stack.TemplateDirectory)
+	stack.TemplateDirectory = filepath.Join(dir, "template")
+	os.Unsetenv("http_proxy")
+	os.Unsetenv("https_proxy")
+
+	writeFingerprintFiles(t, filepath.Join(stack.TemplateDirectory, "python"), map[string]string{
+		"Dockerfile":            "FROM python:3",
+		"index.py":              "import handler",
+		"function/handler.py":   "def handle(req): pass",
+		"function/requirements": "",
+	})
+	handler := filepath.Join(dir, "url-ping")
+	writeFingerprintFiles(t, handler, map[string]string{
+		"handler.py": "def handle(req): return req",
+	})
+
+	before, err := NewFingerprint(handler, "url-ping", "python", map[string]string{"ADDITIONAL_PACKAGE": "curl"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, file := range before.Files {
+		paths = append(paths, file.Path)
+	}
+	wantPaths := []string{"Dockerfile", "function/handler.py", "function/requirements", "index.py"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("want files %v, got %v", wantPaths, paths)
+	}
+	if len(before.TemplateDigest) == 0 || before.BuildArgs["ADDITIONAL_PACKAGE"] == "curl" {
+		t.Errorf("want a template digest and hashed build-args, got %+v", before)
+	}
+
+	same, _ := NewFingerprint(handler, "url-ping", "python", map[string]string{"ADDITIONAL_PACKAGE": "curl"}, "")
+	if same.Digest != before.Digest {
+		t.Errorf("the digest should be stable")
+	}
+
+	writeFingerprintFiles(t, handler, map[string]string{
+		"handler.py": "def handle(req): return req.upper()",
+		"util.py":    "",
+	})
+	after, _ := NewFingerprint(handler, "url-ping", "python", map[string]string{"ADDITIONAL_PACKAGE": "git", "DEBUG": "1"}, "")
+
+	wantChanges := []string{
+		"build-arg changed: ADDITIONAL_PACKAGE",
+		"build-arg added: DEBUG",
+		"file changed: function/handler.py",
+		"file added: function/util.py",
+	}
+	if changes := after.Diff(before); !reflect.DeepEqual(changes, wantChanges) {
+		t.Errorf("want changes %v, got %v", wantChanges, changes)
+	}
+}
+
+func Test_SaveFingerprint_LastFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-fingerprint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(fingerprintDirectory string) { FingerprintDirectory = fingerprintDirectory }(FingerprintDirectory)
+	FingerprintDirectory = filepath.Join(dir, ".fingerprints")
+
+	if last, err := LastFingerprint("url-ping"); last != nil || err != nil {
+		t.Fatalf("want no fingerprint before a build, got %v, %v", last, err)
+	}
+
+	fingerprint := &Fingerprint{Function: "url-ping", Language: "dockerfile", Files: []FileHash{{Path: "Dockerfile", SHA256: "abc"}}}
+	fingerprint.Digest = fingerprint.digest()
+	if err := SaveFingerprint(fingerprint); err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := LastFingerprint("url-ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(last, fingerprint) {
+		t.Errorf("want %+v, got %+v", fingerprint, last)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// redactedText replaces secret values in build output
+const redactedText = "********"
+
+// minRedactedLength stops very short values such as "1" from masking
+// unrelated output like "Step 1/8"
+const minRedactedLength = 3
+
+// Redactor masks secret values and matches of patterns in build output
+type Redactor struct {
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor masks each of the values and every match of the patterns
+func NewRedactor(values []string, patterns []string) (*Redactor, error) {
+	redactor := &Redactor{}
+
+	for _, value := range values {
+		if len(value) >= minRedactedLength {
+			redactor.values = append(redactor.values, value)
+		}
+	}
+
+	// Longer values first so a value containing another is masked whole
+	sort.Slice(redactor.values, func(i, j int) bool {
+		return len(redactor.values[i]) > len(redactor.values[j])
+	})
+
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %s", pattern, err)
+		}
+		redactor.patterns = append(redactor.patterns, compiled)
+	}
+
+	return redactor, nil
+}
+
+// Redact masks the secrets in text
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+
+	for _, value := range r.values {
+		text = strings.Replace(text, value, redactedText, -1)
+	}
+	for _, pattern := range r.patterns {
+		text = pattern.ReplaceAllString(text, redactedText)
+	}
+
+	return text
+}
+
+// Writer masks secrets in everything written through to w. Output is held
+// until the end of each line, or the carriage return of a progress line, so a
+// secret split across writes is still masked, Close flushes any remaining
+// partial line.
+func (r *Redactor) Writer(w io.Writer) io.WriteCloser {
+	return &redactingWriter{redactor: r, out: w}
+}
+
+type redactingWriter struct {
+	redactor *Redactor
+	out      io.Writer
+	buffer   bytes.Buffer
+	lock     sync.Mutex
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	w.buffer.Write(p)
+
+	for {
+		end := bytes.IndexAny(w.buffer.Bytes(), "\r\n")
+		if end < 0 {
+			// No line ending yet, keep the partial line for the next write
+			break
+		}
+
+		line := string(w.buffer.Next(end + 1))
+		if _, err := io.WriteString(w.out, w.redactor.Redact(line)); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *redactingWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+
+	_, err := io.WriteString(w.out, w.redactor.Redact(w.buffer.String()))
+	w.buffer.Reset()
+	return err
+}
+
+// activeRedactor masks the output of every command run by ExecCommand
+var activeRedactor *Redactor
+
+// SetRedactor masks secrets in the output of the commands run from now on
+func SetRedactor(redactor *Redactor) {
+	activeRedactor = redactor
+}
+
+// RedactOutput masks secrets in text written to reports by the build
+func RedactOutput(text string) string {
+	return activeRedactor.Redact(text)
+}
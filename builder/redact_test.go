@@ -0,0 +1,78 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"bytes"
+	"testing"
+)
+
+func Test_Redactor_Redact(t *testing.T) {
+	redactor, err := NewRedactor([]string{"s3cr3t-token", "s3cr3t", "1"}, []string{`ghp_[A-Za-z0-9]+`})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	testCases := map[string]string{
+		"Step 1/8 : ARG TOKEN=s3cr3t-token": "Step 1/8 : ARG TOKEN=********",
+		"using s3cr3t and s3cr3t":           "using ******** and ********",
+		"cloning with ghp_abc123XYZ":        "cloning with ********",
+		"nothing to hide":                   "nothing to hide",
+	}
+
+	for input, expected := range testCases {
+		if got := redactor.Redact(input); got != expected {
+			t.Errorf("want: %q, got: %q", expected, got)
+		}
+	}
+}
+
+func Test_Redactor_InvalidPattern(t *testing.T) {
+	if _, err := NewRedactor(nil, []string{"("}); err == nil {
+		t.Fatalf("want error for an invalid pattern")
+	}
+}
+
+func Test_Redactor_Writer_SplitWrites(t *testing.T) {
+	redactor, _ := NewRedactor([]string{"s3cr3t-token"}, nil)
+
+	var out bytes.Buffer
+	w := redactor.Writer(&out)
+
+	w.Write([]byte("--build-arg TOKEN=s3cr"))
+	w.Write([]byte("3t-token\nnext line with s3cr3t-to"))
+	if out.String() != "--build-arg TOKEN=********\n" {
+		t.Fatalf("want only the complete line written, got: %q", out.String())
+	}
+
+	w.Write([]byte("ken"))
+	w.Close()
+
+	expected := "--build-arg TOKEN=********\nnext line with ********"
+	if out.String() != expected {
+		t.Errorf("want: %q, got: %q", expected, out.String())
+	}
+}
+
+func Test_Redactor_Writer_CarriageReturn(t *testing.T) {
+	redactor, _ := NewRedactor([]string{"s3cr3t-token"}, nil)
+
+	var out bytes.Buffer
+	w := redactor.Writer(&out)
+
+	// Progress output rewrites one line with \r and may never end it
+	w.Write([]byte("#5 fetching s3cr3t-token 10%\r#5 fetching s3cr3t-token 20%\r"))
+
+	expected := "#5 fetching ******** 10%\r#5 fetching ******** 20%\r"
+	if out.String() != expected {
+		t.Errorf("want progress written as each line is rewritten: %q, got: %q", expected, out.String())
+	}
+}
+
+func Test_Redactor_Nil(t *testing.T) {
+	var redactor *Redactor
+	if got := redactor.Redact("s3cr3t"); got != "s3cr3t" {
+		t.Errorf("want text unchanged without a redactor, got: %q", got)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Statuses streamed back by a remote builder
+const (
+	RemoteBuildInProgress = "in_progress"
+	RemoteBuildSuccess    = "success"
+	RemoteBuildFailure    = "failure"
+)
+
+// remoteBuildConfigFile is the file in the archive which holds the
+// RemoteBuildConfig, the build context is under context/
+const remoteBuildConfigFile = "com.openfaas.docker.config"
+
+// RemoteBuildConfig tells the remote builder what to build from the context
+type RemoteBuildConfig struct {
+	Image     string            `json:"image"`
+	BuildArgs map[string]string `json:"buildArgs,omitempty"`
+	Platforms []string          `json:"platforms,omitempty"`
+	NoCache   bool              `json:"noCache,omitempty"`
+}
+
+// RemoteBuildResult is streamed back as a line of JSON while the image is
+// built, the last one has the status of the build
+type RemoteBuildResult struct {
+	Log    []string `json:"log,omitempty"`
+	Image  string   `json:"image,omitempty"`
+	Status string   `json:"status,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// RemoteBuilderAPI streams each shrink-wrapped build context as a tar to the
+// /build endpoint of a builder API, such as the OpenFaaS Pro builder, which
+// builds and pushes the image and streams its logs and status back. No Docker
+// daemon is needed locally.
+type RemoteBuilderAPI struct {
+	URL string
+
+	// PayloadSecret signs each archive with HMAC-SHA256 in the
+	// X-Build-Signature header when it is set
+	PayloadSecret string
+
+	// Timeout is how long a build may take
+	Timeout time.Duration
+}
+
+// Name of the builder
+func (RemoteBuilderAPI) Name() string { return "remote-builder" }
+
+// Command is empty as nothing is run locally
+func (RemoteBuilderAPI) Command() string { return "" }
+
+// Capabilities of the builder, secrets are not sent to the builder
+func (RemoteBuilderAPI) Capabilities() Capabilities {
+	return Capabilities{BuildArgs: true, Platforms: true, MultiPlatform: true, Dockerfile: true, Pushes: true}
+}
+
+// Build sends the context and prints the logs streamed back until the build
+// succeeds or fails
+func (r RemoteBuilderAPI) Build(contextPath string, options BuildOptions) error {
+	config := RemoteBuildConfig{
+		Image:     options.Image,
+		BuildArgs: options.BuildArgs,
+		NoCache:   options.NoCache,
+	}
+	if len(options.Platform) > 0 {
+		config.Platforms = strings.Split(options.Platform, ",")
+	}
+
+	archive, err := remoteBuildArchive(contextPath, config)
+	if err != nil {
+		return fmt.Errorf("unable to archive the build context %s: %s", contextPath, err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(r.URL, "/")+"/build", bytes.NewReader(archive))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Accept", "application/x-ndjson")
+	if len(r.PayloadSecret) > 0 {
+		req.Header.Set("X-Build-Signature", "sha256="+signPayload(archive, r.PayloadSecret))
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Minute
+	}
+	client := http.Client{Timeout: timeout}
+	fmt.Printf("Sending %s to the builder at %s.\n", options.Image, r.URL)
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach the builder at %s: %s", r.URL, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusAccepted {
+		body, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("the builder returned unexpected status %d: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var last RemoteBuildResult
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		var result RemoteBuildResult
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			return fmt.Errorf("unable to parse the builder's response: %s", err)
+		}
+		for _, logLine := range result.Log {
+			fmt.Println(RedactOutput(logLine))
+		}
+		last = result
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("unable to read the builder's response: %s", err)
+	}
+
+	switch last.Status {
+	case RemoteBuildSuccess:
+		return nil
+	case RemoteBuildFailure:
+		if len(last.Error) > 0 {
+			return fmt.Errorf("the remote build failed: %s", RedactOutput(last.Error))
+		}
+		return fmt.Errorf("the remote build failed")
+	default:
+		return fmt.Errorf("the builder stopped before the build finished")
+	}
+}
+
+// remoteBuildArchive writes the config and the files of the build context
+// under context/ to a tar
+func remoteBuildArchive(contextPath string, config RemoteBuildConfig) ([]byte, error) {
+	configData, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+
+	header := &tar.Header{Name: remoteBuildConfigFile, Mode: 0600, Size: int64(len(configData)), ModTime: time.Now()}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	if _, err := tarWriter.Write(configData); err != nil {
+		return nil, err
+	}
+
+	if err := addContextFiles(tarWriter, contextPath, "context/"); err != nil {
+		return nil, err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return nil, err
+	}
+	return archive.Bytes(), nil
+}
+
+// signPayload is the hex HMAC-SHA256 of payload with secret
+func signPayload(payload []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
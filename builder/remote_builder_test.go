@@ -0,0 +1,97 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package builder
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func Test_RemoteBuilderAPI_StreamsLogs(t *testing.T) {
+	dir := dispatchContext(t)
+	defer os.RemoveAll(dir)
+
+	var names []string
+	var config RemoteBuildConfig
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/build" {
+			t.Errorf("want a POST to /build, got %s %s", r.Method, r.URL.Path)
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		if want := "sha256=" + signPayload(body, "s3cret"); r.Header.Get("X-Build-Signature") == want {
+			signature = want
+		}
+
+		tarReader := tar.NewReader(bytes.NewReader(body))
+		for {
+			header, err := tarReader.Next()
+			if err != nil {
+				break
+			}
+			names = append(names, header.Name)
+			if header.Name == remoteBuildConfigFile {
+				json.NewDecoder(tarReader).Decode(&config)
+			}
+		}
+
+		w.Write([]byte(`{"log": ["Step 1/8 : FROM alpine"], "status": "in_progress"}` + "\n"))
+		w.Write([]byte(`{"log": ["pushed fn:0.1"], "image": "fn:0.1", "status": "success"}` + "\n"))
+	}))
+	defer server.Close()
+
+	err := RemoteBuilderAPI{URL: server.URL, PayloadSecret: "s3cret"}.Build(dir, BuildOptions{
+		Image:     "fn:0.1",
+		BuildArgs: map[string]string{"ADDITIONAL_PACKAGE": "git"},
+		Platform:  "linux/amd64,linux/arm64",
+	})
+	if err != nil {
+		t.Fatalf("want the build to succeed, got %s", err)
+	}
+
+	if len(signature) == 0 {
+		t.Errorf("want the archive signed with the payload secret")
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, " "); got != "com.openfaas.docker.config context/Dockerfile context/function/ context/function/handler.py" {
+		t.Errorf("unexpected archive: %s", got)
+	}
+	if config.Image != "fn:0.1" || config.BuildArgs["ADDITIONAL_PACKAGE"] != "git" || len(config.Platforms) != 2 {
+		t.Errorf("unexpected config %+v", config)
+	}
+}
+
+func Test_RemoteBuilderAPI_Failure(t *testing.T) {
+	dir := dispatchContext(t)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"log": ["npm ERR!"], "status": "failure", "error": "exit code 1"}` + "\n"))
+	}))
+	defer server.Close()
+
+	err := RemoteBuilderAPI{URL: server.URL}.Build(dir, BuildOptions{Image: "fn:0.1"})
+	if err == nil || err.Error() != "the remote build failed: exit code 1" {
+		t.Errorf("want the failure returned, got %v", err)
+	}
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte("invalid signature"))
+	}))
+	defer unauthorized.Close()
+
+	err = RemoteBuilderAPI{URL: unauthorized.URL}.Build(dir, BuildOptions{Image: "fn:0.1"})
+	if err == nil || err.Error() != "the builder returned unexpected status 401: invalid signature" {
+		t.Errorf("want the rejection returned, got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// watchdogIndexEnv gives the index when --index is not set
+const watchdogIndexEnv = "FAAS_WATCHDOG_INDEX"
+
+var (
+	advisorIndex           string
+	advisorRebuildAffected bool
+)
+
+func init() {
+	advisorCmd.Flags().StringVar(&advisorIndex, "index", "", "File or http(s) URL of the watchdog index, "+watchdogIndexEnv+" is used when not given")
+	advisorCmd.Flags().BoolVar(&advisorRebuildAffected, "rebuild-affected", false, "Rebuild the functions affected by an advisory whose template has a fixed watchdog")
+	advisorCmd.Flags().IntVar(&parallel, "parallel", 1, "Rebuild in parallel to depth specified.")
+
+	faasCmd.AddCommand(advisorCmd)
+}
+
+var advisorCmd = &cobra.Command{
+	Use:   `advisor -f YAML_FILE [--index FILE_OR_URL] [--rebuild-affected]`,
+	Short: "Flag functions built with an outdated or vulnerable watchdog",
+	Long: `Compares the watchdog version recorded in each function's image, or pinned by
+its template when the image can't be read, with a watchdog index which lists
+the latest release of each watchdog and the advisories, such as CVEs, which
+affect older ones. The index is JSON:
+
+  {
+    "latest": {"of-watchdog": "0.9.11", "classic": "0.2.1"},
+    "advisories": [
+      {"id": "CVE-2021-12345", "watchdog": "of-watchdog",
+       "introduced": "0.7.0", "fixed": "0.8.4", "summary": "..."}
+    ]
+  }
+
+The command fails when a function is affected by an advisory. Functions whose
+template pins a fixed watchdog can be rebuilt with --rebuild-affected, others
+need their template updated first with "faas-cli template pull --overwrite".`,
+	Example: `  faas-cli advisor -f stack.yml --index https://example.com/watchdog-index.json
+  FAAS_WATCHDOG_INDEX=./watchdog-index.json faas-cli advisor --rebuild-affected`,
+	RunE: runAdvisor,
+}
+
+// watchdogIndex lists the latest release of each watchdog and the advisories
+// which affect its older releases
+type watchdogIndex struct {
+	Latest     map[string]string  `json:"latest"`
+	Advisories []watchdogAdvisory `json:"advisories"`
+}
+
+type watchdogAdvisory struct {
+	ID       string `json:"id"`
+	Watchdog string `json:"watchdog"`
+
+	// Introduced is the first affected version, empty when every version
+	// before Fixed is affected
+	Introduced string `json:"introduced,omitempty"`
+	Fixed      string `json:"fixed"`
+
+	Summary string `json:"summary,omitempty"`
+}
+
+// advice is what the advisor found for one function
+type advice struct {
+	Function string
+	Watchdog *stack.Watchdog
+
+	// Source is where the watchdog was read from, image or template
+	Source string
+
+	Latest     string
+	Outdated   bool
+	Advisories []string
+
+	// TemplateFixed is true when the function's template pins a watchdog
+	// which is not affected, so a rebuild fixes the function
+	TemplateFixed bool
+}
+
+func runAdvisor(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the functions to check with -f")
+	}
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+
+	location := advisorIndex
+	if len(location) == 0 {
+		location = os.Getenv(watchdogIndexEnv)
+	}
+	if len(location) == 0 {
+		return fmt.Errorf("give the watchdog index with --index or %s", watchdogIndexEnv)
+	}
+	index, err := readWatchdogIndex(location)
+	if err != nil {
+		return err
+	}
+
+	advices := adviseFunctions(services, index)
+	printAdvice(os.Stdout, advices)
+
+	var affected []string
+	var rebuild []string
+	for _, a := range advices {
+		if len(a.Advisories) == 0 {
+			continue
+		}
+		affected = append(affected, a.Function)
+		if a.TemplateFixed {
+			rebuild = append(rebuild, a.Function)
+		}
+	}
+	if len(affected) == 0 {
+		return nil
+	}
+
+	if advisorRebuildAffected && len(rebuild) > 0 {
+		if err := rebuildFunctions(services, rebuild); err != nil {
+			return err
+		}
+		if len(rebuild) == len(affected) {
+			fmt.Printf("Rebuilt %d affected function(s), push and deploy them to finish.\n", len(rebuild))
+			return nil
+		}
+	}
+	return fmt.Errorf("%d function(s) use a watchdog affected by an advisory: %s", len(affected), strings.Join(affected, ", "))
+}
+
+func readWatchdogIndex(location string) (*watchdogIndex, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		data, err = fetchWatchdogIndex(location)
+	} else {
+		data, err = ioutil.ReadFile(location)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the watchdog index: %s", err)
+	}
+
+	index := &watchdogIndex{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("unable to parse the watchdog index %s: %s", location, err)
+	}
+	return index, nil
+}
+
+func fetchWatchdogIndex(url string) ([]byte, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// adviseFunctions checks the watchdog of each function, sorted by name.
+// Functions whose watchdog can't be found, such as dockerfile functions
+// without labels, are left out.
+func adviseFunctions(services *stack.Services, index *watchdogIndex) []advice {
+	names := make([]string, 0, len(services.Functions))
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var advices []advice
+	for _, name := range names {
+		function := services.Functions[name]
+
+		templateWatchdog, _ := stack.TemplateWatchdog(function.Language)
+		watchdog, source := templateWatchdog, "template"
+		if labels, err := imageLabels(function.Image); err == nil {
+			if labelled := stack.LabelledWatchdog(labels); labelled != nil {
+				watchdog, source = labelled, "image"
+			}
+		}
+		if watchdog == nil {
+			continue
+		}
+
+		a := advice{
+			Function:   name,
+			Watchdog:   watchdog,
+			Source:     source,
+			Latest:     index.Latest[watchdog.Type],
+			Advisories: index.affecting(watchdog),
+		}
+		if older, ok := versionBefore(watchdog.Version, a.Latest); ok && older {
+			a.Outdated = true
+		}
+		a.TemplateFixed = len(a.Advisories) > 0 && templateWatchdog != nil && len(templateWatchdog.Version) > 0 &&
+			len(index.affecting(templateWatchdog)) == 0
+		advices = append(advices, a)
+	}
+	return advices
+}
+
+// affecting lists the advisories which affect the watchdog's version, an
+// unknown version is not matched
+func (index *watchdogIndex) affecting(watchdog *stack.Watchdog) []string {
+	var ids []string
+	for _, advisory := range index.Advisories {
+		if advisory.Watchdog != watchdog.Type {
+			continue
+		}
+		if older, ok := versionBefore(watchdog.Version, advisory.Fixed); !ok || !older {
+			continue
+		}
+		if len(advisory.Introduced) > 0 {
+			if older, ok := versionBefore(watchdog.Version, advisory.Introduced); !ok || older {
+				continue
+			}
+		}
+		ids = append(ids, advisory.ID)
+	}
+	return ids
+}
+
+func printAdvice(w io.Writer, advices []advice) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FUNCTION\tWATCHDOG\tVERSION\tFROM\tLATEST\tSTATUS")
+	for _, a := range advices {
+		version := a.Watchdog.Version
+		if len(version) == 0 {
+			version = "unknown"
+		}
+
+		status := "ok"
+		switch {
+		case len(a.Advisories) > 0 && a.TemplateFixed:
+			status = strings.Join(a.Advisories, ", ") + " - rebuild to fix"
+		case len(a.Advisories) > 0:
+			status = strings.Join(a.Advisories, ", ") + " - update the template"
+		case a.Outdated:
+			status = "outdated"
+		case len(a.Watchdog.Version) == 0:
+			status = "unknown version"
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\n", a.Function, a.Watchdog, version, a.Source, a.Latest, status)
+	}
+	table.Flush()
+}
+
+// rebuildFunctions builds the named functions of the stack with the builder
+// chosen in the config file
+func rebuildFunctions(services *stack.Services, names []string) error {
+	if err := useConfiguredBuilder(false); err != nil {
+		return err
+	}
+	if err := checkBuildTools(); err != nil {
+		return err
+	}
+
+	rebuild := *services
+	rebuild.Functions = map[string]stack.Function{}
+	for _, name := range names {
+		rebuild.Functions[name] = services.Functions[name]
+	}
+
+	fmt.Printf("Rebuilding %d affected function(s): %s.\n", len(names), strings.Join(names, ", "))
+	build(&rebuild, parallel, false, builder.BaseImageBuildArgs(services.BaseImages), nil, nil)
+	return nil
+}
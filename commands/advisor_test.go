@@ -0,0 +1,104 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_adviseFunctions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-advisor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(templateDirectory string) { stack.TemplateDirectory = templateDirectory }(stack.TemplateDirectory)
+	stack.TemplateDirectory = dir
+
+	templates := map[string]string{
+		"go-http": "FROM openfaas/of-watchdog:0.9.11 as watchdog\n",
+		"node":    "FROM openfaas/of-watchdog:0.8.0 as watchdog\n",
+	}
+	for name, dockerfile := range templates {
+		os.MkdirAll(filepath.Join(dir, name), 0700)
+		ioutil.WriteFile(filepath.Join(dir, name, "template.yml"), []byte("language: "+name+"\n"), 0600)
+		ioutil.WriteFile(filepath.Join(dir, name, "Dockerfile"), []byte(dockerfile), 0600)
+	}
+
+	defer stubImageLabels(map[string]map[string]string{
+		"api:0.1":  {stack.WatchdogLabel: stack.OfWatchdog, stack.WatchdogVersionLabel: "0.8.0"},
+		"cron:0.1": {stack.WatchdogLabel: stack.OfWatchdog, stack.WatchdogVersionLabel: "0.9.11"},
+	})()
+
+	services := &stack.Services{Functions: map[string]stack.Function{
+		"api":    {Image: "api:0.1", Language: "go-http"},
+		"cron":   {Image: "cron:0.1", Language: "go-http"},
+		"web":    {Image: "web:0.1", Language: "node"},
+		"worker": {Image: "worker:0.1", Language: "dockerfile"},
+	}}
+	index := &watchdogIndex{
+		Latest: map[string]string{stack.OfWatchdog: "0.9.11"},
+		Advisories: []watchdogAdvisory{
+			{ID: "CVE-2021-0001", Watchdog: stack.OfWatchdog, Introduced: "0.7.0", Fixed: "0.8.4"},
+			{ID: "CVE-2019-0002", Watchdog: stack.OfWatchdog, Fixed: "0.5.0"},
+		},
+	}
+
+	advices := adviseFunctions(services, index)
+	if len(advices) != 3 {
+		t.Fatalf("want advice for the 3 functions with a known watchdog, got %+v", advices)
+	}
+
+	api, cron, web := advices[0], advices[1], advices[2]
+	if api.Source != "image" || !reflect.DeepEqual(api.Advisories, []string{"CVE-2021-0001"}) || !api.TemplateFixed || !api.Outdated {
+		t.Errorf("want api affected and fixed by a rebuild, got %+v", api)
+	}
+	if len(cron.Advisories) != 0 || cron.Outdated {
+		t.Errorf("want cron up to date, got %+v", cron)
+	}
+	if web.Source != "template" || len(web.Advisories) != 1 || web.TemplateFixed {
+		t.Errorf("want web read from its template, which is affected too, got %+v", web)
+	}
+
+	var out bytes.Buffer
+	printAdvice(&out, advices)
+	want := `FUNCTION  WATCHDOG            VERSION  FROM      LATEST  STATUS
+api       of-watchdog         0.8.0    image     0.9.11  CVE-2021-0001 - rebuild to fix
+cron      of-watchdog         0.9.11   image     0.9.11  ok
+web       of-watchdog (http)  0.8.0    template  0.9.11  CVE-2021-0001 - update the template
+`
+	if out.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func Test_readWatchdogIndex(t *testing.T) {
+	file, err := ioutil.TempFile("", "watchdog-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString(`{"latest": {"classic": "0.2.1"}, "advisories": [{"id": "CVE-1", "watchdog": "classic", "fixed": "0.1.0"}]}`)
+	file.Close()
+
+	index, err := readWatchdogIndex(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Latest["classic"] != "0.2.1" || len(index.Advisories) != 1 || index.Advisories[0].ID != "CVE-1" {
+		t.Errorf("unexpected index: %+v", index)
+	}
+
+	if _, err := readWatchdogIndex(filepath.Join(os.TempDir(), "missing-index.json")); err == nil {
+		t.Errorf("want an error for a missing index")
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var analyzeTop int
+
+func init() {
+	analyzeCmd.Flags().IntVar(&analyzeTop, "top", 5, "Number of the largest files to list for each function")
+
+	faasCmd.AddCommand(analyzeCmd)
+}
+
+var analyzeCmd = &cobra.Command{
+	Use:   `analyze -f YAML_FILE [--regex "REGEX"] [--filter "WILDCARD"] [--top N]`,
+	Short: "Report the size and dependencies of each function before building",
+	Long: `Reports the size of each function's build context, its largest files and the
+number of dependencies declared by its handler, without building anything.
+
+The context size is compared with the last build of the function, and the size
+of the image from that build is used to estimate the size of the next image.`,
+	Example: `  faas-cli analyze -f ./stack.yml
+  faas-cli analyze -f ./stack.yml --filter "api-*" --top 10`,
+	RunE: runAnalyze,
+}
+
+// localImageSize gives the size of an image in the local Docker image cache
+var localImageSize = func(image string) (int64, error) {
+	out, err := exec.Command("docker", "image", "inspect", "--format", "{{.Size}}", image).Output()
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+}
+
+// functionAnalysis describes a function's build context before it is built
+type functionAnalysis struct {
+	Function     string
+	Language     string
+	ContextSize  int64
+	Files        int
+	Largest      []builder.FileHash
+	Dependencies map[string]int
+
+	// ContextDelta is the change since the last build, Built is false when
+	// there is no build to compare with
+	Built        bool
+	ContextDelta int64
+
+	// ImageSize is the image from the last build, or -1 when it is not known
+	ImageSize int64
+}
+
+func runAnalyze(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the stack with --yaml")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+
+	if pullErr := pullStackTemplates(*services, stackLanguages(*services, "")); pullErr != nil {
+		return fmt.Errorf("could not pull templates for OpenFaaS: %v", pullErr)
+	}
+
+	names := make([]string, 0, len(services.Functions))
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var analyses []functionAnalysis
+	for _, name := range names {
+		function := services.Functions[name]
+		if function.SkipBuild {
+			continue
+		}
+		function.Name = name
+
+		analysis, err := analyzeFunction(function, analyzeTop)
+		if err != nil {
+			return err
+		}
+		analyses = append(analyses, *analysis)
+	}
+
+	printAnalysis(os.Stdout, analyses)
+	return nil
+}
+
+func analyzeFunction(function stack.Function, top int) (*functionAnalysis, error) {
+	fingerprint, err := builder.NewFingerprint(function.Handler, function.Name, function.Language, function.BuildArgs, "")
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the build context of %s: %s", function.Name, err)
+	}
+
+	analysis := &functionAnalysis{
+		Function:    function.Name,
+		Language:    function.Language,
+		ContextSize: fingerprint.ContextSize(),
+		Files:       len(fingerprint.Files),
+		ImageSize:   -1,
+	}
+
+	largest := append([]builder.FileHash{}, fingerprint.Files...)
+	sort.SliceStable(largest, func(i, j int) bool {
+		return largest[i].Size > largest[j].Size
+	})
+	if len(largest) > top {
+		largest = largest[:top]
+	}
+	analysis.Largest = largest
+
+	dependencies, err := countDependencies(function.Handler)
+	if err != nil {
+		return nil, fmt.Errorf("unable to count the dependencies of %s: %s", function.Name, err)
+	}
+	analysis.Dependencies = dependencies
+
+	previous, err := builder.LastFingerprint(function.Name)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		analysis.Built = true
+		analysis.ContextDelta = analysis.ContextSize - previous.ContextSize()
+
+		if size, sizeErr := localImageSize(function.Image); sizeErr == nil {
+			analysis.ImageSize = size
+		}
+	}
+	return analysis, nil
+}
+
+var (
+	goModRequire    = regexp.MustCompile(`^\s*require\s+\S+\s+\S+`)
+	gemfileGem      = regexp.MustCompile(`^\s*gem\s+['"]`)
+	csprojReference = regexp.MustCompile(`<PackageReference\s`)
+	pomDependency   = regexp.MustCompile(`<dependency>`)
+)
+
+// dependencyCounters count the dependencies declared by each kind of manifest
+var dependencyCounters = map[string]func([]byte) (int, error){
+	"requirements.txt": func(data []byte) (int, error) {
+		return countLines(data, func(line string) bool {
+			return !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "-")
+		}), nil
+	},
+	"package.json": func(data []byte) (int, error) {
+		return countJSONKeys(data, "dependencies")
+	},
+	"composer.json": func(data []byte) (int, error) {
+		return countJSONKeys(data, "require")
+	},
+	"go.mod": func(data []byte) (int, error) {
+		inBlock := false
+		return countLines(data, func(line string) bool {
+			switch {
+			case strings.HasPrefix(line, "require ("):
+				inBlock = true
+				return false
+			case inBlock && line == ")":
+				inBlock = false
+				return false
+			case inBlock:
+				return !strings.HasPrefix(line, "//")
+			}
+			return goModRequire.MatchString(line)
+		}), nil
+	},
+	"Gemfile": func(data []byte) (int, error) {
+		return countLines(data, gemfileGem.MatchString), nil
+	},
+	"pom.xml": func(data []byte) (int, error) {
+		return len(pomDependency.FindAll(data, -1)), nil
+	},
+	".csproj": func(data []byte) (int, error) {
+		return len(csprojReference.FindAll(data, -1)), nil
+	},
+}
+
+// countDependencies counts the dependencies in each manifest at the top of
+// the handler, keyed by the manifest's file name
+func countDependencies(handler string) (map[string]int, error) {
+	entries, err := ioutil.ReadDir(handler)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := map[string]int{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		counter, ok := dependencyCounters[entry.Name()]
+		if !ok {
+			counter, ok = dependencyCounters[filepath.Ext(entry.Name())]
+		}
+		if !ok {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(handler, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		count, err := counter(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err)
+		}
+		dependencies[entry.Name()] = count
+	}
+	return dependencies, nil
+}
+
+func countLines(data []byte, matches func(string) bool) int {
+	count := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) > 0 && matches(line) {
+			count++
+		}
+	}
+	return count
+}
+
+func countJSONKeys(data []byte, field string) (int, error) {
+	var manifest map[string]json.RawMessage
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return 0, err
+	}
+	raw, ok := manifest[field]
+	if !ok {
+		return 0, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return 0, err
+	}
+	return len(values), nil
+}
+
+func printAnalysis(w io.Writer, analyses []functionAnalysis) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FUNCTION\tLANGUAGE\tCONTEXT\tFILES\tDEPENDENCIES\tSINCE LAST BUILD\tEST. IMAGE")
+	for _, analysis := range analyses {
+		delta, image := "not built", "unknown"
+		if analysis.Built {
+			delta = formatSizeDelta(analysis.ContextDelta)
+			if analysis.ImageSize >= 0 {
+				image = formatSize(analysis.ImageSize + analysis.ContextDelta)
+			}
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", analysis.Function, analysis.Language,
+			formatSize(analysis.ContextSize), analysis.Files, formatDependencies(analysis.Dependencies), delta, image)
+	}
+	table.Flush()
+
+	for _, analysis := range analyses {
+		if len(analysis.Largest) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\nLargest files in %s:\n", analysis.Function)
+		files := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+		for _, file := range analysis.Largest {
+			fmt.Fprintf(files, "  %s\t  %s\n", formatSize(file.Size), file.Path)
+		}
+		files.Flush()
+	}
+}
+
+func formatDependencies(dependencies map[string]int) string {
+	if len(dependencies) == 0 {
+		return "-"
+	}
+
+	manifests := make([]string, 0, len(dependencies))
+	for manifest := range dependencies {
+		manifests = append(manifests, manifest)
+	}
+	sort.Strings(manifests)
+
+	var counts []string
+	for _, manifest := range manifests {
+		counts = append(counts, fmt.Sprintf("%d (%s)", dependencies[manifest], manifest))
+	}
+	return strings.Join(counts, ", ")
+}
+
+func formatSizeDelta(delta int64) string {
+	switch {
+	case delta > 0:
+		return "+" + formatSize(delta)
+	case delta < 0:
+		return "-" + formatSize(-delta)
+	}
+	return "unchanged"
+}
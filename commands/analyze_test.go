@@ -0,0 +1,107 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_countDependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-analyze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"requirements.txt": "# pinned\nrequests==2.18\n-r base.txt\n\nflask\n",
+		"package.json":     `{"dependencies": {"express": "^4", "lodash": "^4"}, "devDependencies": {"mocha": "^5"}}`,
+		"go.mod":           "module fn\n\nrequire (\n\tgithub.com/a/b v1.0.0\n\t// indirect comment\n\tgithub.com/c/d v2.0.0\n)\nrequire github.com/e/f v1.0.0\n",
+		"Gemfile":          "source 'https://rubygems.org'\ngem 'rack'\ngem \"json\"\n",
+		"Function.csproj":  `<ItemGroup><PackageReference Include="A" /><PackageReference Include="B" /></ItemGroup>`,
+		"handler.py":       "import requests",
+	}
+	for name, contents := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600)
+	}
+
+	got, err := countDependencies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int{
+		"requirements.txt": 2,
+		"package.json":     2,
+		"go.mod":           3,
+		"Gemfile":          2,
+		"Function.csproj":  2,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func Test_analyzeFunction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-analyze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(fingerprintDirectory string) { builder.FingerprintDirectory = fingerprintDirectory }(builder.FingerprintDirectory)
+	builder.FingerprintDirectory = filepath.Join(dir, ".fingerprints")
+
+	defer func(size func(string) (int64, error)) { localImageSize = size }(localImageSize)
+	localImageSize = func(image string) (int64, error) { return 50000000, nil }
+
+	handler := filepath.Join(dir, "model")
+	os.MkdirAll(handler, 0700)
+	ioutil.WriteFile(filepath.Join(handler, "Dockerfile"), []byte("FROM alpine"), 0600)
+	ioutil.WriteFile(filepath.Join(handler, "model.bin"), make([]byte, 3000), 0600)
+
+	function := stack.Function{Name: "model", Language: "dockerfile", Handler: handler, Image: "model:0.1"}
+
+	analysis, err := analyzeFunction(function, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if analysis.Built || analysis.ContextSize != 3011 || analysis.Files != 2 {
+		t.Errorf("unexpected analysis before a build: %+v", analysis)
+	}
+	if len(analysis.Largest) != 1 || analysis.Largest[0].Path != "model.bin" {
+		t.Errorf("want model.bin as the largest file, got %+v", analysis.Largest)
+	}
+
+	recordFingerprint(handler, "model", "dockerfile", nil, "")
+	ioutil.WriteFile(filepath.Join(handler, "model.bin"), make([]byte, 5000), 0600)
+
+	analysis, err = analyzeFunction(function, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !analysis.Built || analysis.ContextDelta != 2000 || analysis.ImageSize != 50000000 {
+		t.Errorf("unexpected analysis after a build: %+v", analysis)
+	}
+
+	var out bytes.Buffer
+	printAnalysis(&out, []functionAnalysis{*analysis})
+	for _, want := range []string{
+		"model     dockerfile  5.0 kB   2      -             +2.0 kB           50.0 MB",
+		"Largest files in model:",
+		"    5.0 kB  model.bin\n      11 B  Dockerfile",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiData    string
+	apiHeaders []string
+	apiInclude bool
+	apiTimeout time.Duration
+)
+
+// apiInput is read when the request body is given as --data -
+var apiInput io.Reader = os.Stdin
+
+func init() {
+	apiCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	apiCmd.Flags().StringVarP(&apiData, "data", "d", "", "Request body, @FILE reads it from a file and - from stdin")
+	apiCmd.Flags().StringArrayVarP(&apiHeaders, "header", "H", []string{}, "Add a request header (NAME: VALUE)")
+	apiCmd.Flags().BoolVarP(&apiInclude, "include", "i", false, "Print the response's status line and headers before its body")
+	apiCmd.Flags().DurationVar(&apiTimeout, "timeout", 60*time.Second, "Timeout for the request")
+
+	faasCmd.AddCommand(apiCmd)
+}
+
+var apiCmd = &cobra.Command{
+	Use:   `api METHOD PATH [--data BODY] [--header "NAME: VALUE" ...] [--include]`,
+	Short: "Send a request to any path on the gateway",
+	Long: `Sends a request to a path on the gateway with the credentials saved by
+"faas-cli login" and prints the raw response, for scripting against provider
+endpoints which the CLI has no command for.
+
+The command fails when the gateway responds with a status of 400 or above,
+after printing the response.`,
+	Example: `  faas-cli api GET /system/functions
+  faas-cli api GET "/system/functions?namespace=staging" | jq '.[].name'
+  faas-cli api POST /system/scale-function/figlet -d '{"serviceName":"figlet","replicas":2}'
+  faas-cli api PUT /system/secrets -d @secret.json -H "Content-Type: application/json"`,
+	RunE: runAPI,
+}
+
+func runAPI(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("please provide the METHOD and PATH of the request, i.e. faas-cli api GET /system/functions")
+	}
+	method, path := args[0], args[1]
+
+	headers, err := parseHeaders(apiHeaders)
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader
+	switch {
+	case apiData == "-":
+		body = apiInput
+	case strings.HasPrefix(apiData, "@"):
+		file, err := os.Open(apiData[1:])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		body = file
+	case len(apiData) > 0:
+		body = strings.NewReader(apiData)
+	}
+	if body != nil && len(headers.Get("Content-Type")) == 0 {
+		headers.Set("Content-Type", "application/json")
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	res, err := proxy.CallAPI(gatewayAddress, method, path, body, headers, apiTimeout)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if apiInclude {
+		printResponseHeaders(os.Stdout, res)
+	}
+	if _, err := io.Copy(os.Stdout, res.Body); err != nil {
+		return err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest {
+		return fmt.Errorf("server returned status code: %d", res.StatusCode)
+	}
+	return nil
+}
+
+func parseHeaders(values []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) == 0 {
+			return nil, fmt.Errorf("header %q must be given as NAME: VALUE", value)
+		}
+		headers.Add(strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]))
+	}
+	return headers, nil
+}
+
+func printResponseHeaders(w io.Writer, res *http.Response) {
+	fmt.Fprintf(w, "%s %s\n", res.Proto, res.Status)
+
+	names := make([]string, 0, len(res.Header))
+	for name := range res.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range res.Header[name] {
+			fmt.Fprintf(w, "%s: %s\n", name, value)
+		}
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_runAPI(t *testing.T) {
+	var method, uri, contentType, trace, body string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, uri = r.Method, r.RequestURI
+		contentType, trace = r.Header.Get("Content-Type"), r.Header.Get("X-Trace")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+
+		w.Header().Set("X-Served-By", "gateway")
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer s.Close()
+
+	gateway = s.URL
+	apiData = "-"
+	apiInput = strings.NewReader(`{"serviceName":"figlet","replicas":2}`)
+	apiHeaders = []string{"X-Trace: abc"}
+	apiInclude = true
+	defer func() {
+		gateway, apiData, apiHeaders, apiInclude = defaultGateway, "", nil, false
+	}()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		err = runAPI(nil, []string{"post", "system/scale-function/figlet?namespace=staging"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPost || uri != "/system/scale-function/figlet?namespace=staging" {
+		t.Errorf("got %s %s", method, uri)
+	}
+	if contentType != "application/json" || trace != "abc" || body != `{"serviceName":"figlet","replicas":2}` {
+		t.Errorf("got content type %q, trace %q and body %q", contentType, trace, body)
+	}
+	if !strings.HasPrefix(stdOut, "HTTP/1.1 202 Accepted\n") || !strings.Contains(stdOut, "X-Served-By: gateway\n") || !strings.HasSuffix(stdOut, "\n\n{\"ok\":true}") {
+		t.Errorf("unexpected output:\n%s", stdOut)
+	}
+}
+
+func Test_runAPI_ErrorStatus(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusUnauthorized,
+			ResponseBody:       "unauthorized",
+		},
+	})
+	defer s.Close()
+
+	gateway = s.URL
+	defer func() { gateway = defaultGateway }()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		err = runAPI(nil, []string{"GET", "/system/functions"})
+	})
+	if err == nil || err.Error() != "server returned status code: 401" {
+		t.Errorf("got error %v", err)
+	}
+	if stdOut != `"unauthorized"` {
+		t.Errorf("want the body to be printed, got %q", stdOut)
+	}
+}
+
+func Test_parseHeaders(t *testing.T) {
+	headers, err := parseHeaders([]string{"Accept: text/plain", "X-Empty:"})
+	if err != nil || headers.Get("Accept") != "text/plain" || len(headers["X-Empty"]) != 1 {
+		t.Errorf("got %v, %v", headers, err)
+	}
+
+	if _, err := parseHeaders([]string{"no-colon"}); err == nil {
+		t.Errorf("want an error for a header without a colon")
+	}
+}
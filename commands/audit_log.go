@@ -0,0 +1,194 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/audit"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditSince    string
+	auditFunction string
+	auditAction   string
+)
+
+func init() {
+	auditLogShowCmd.Flags().StringVar(&auditSince, "since", "7d", "Show entries since a duration ago such as 7d or 12h, or since a date such as 2018-06-01")
+	auditLogShowCmd.Flags().StringVar(&auditFunction, "function", "", "Only show entries for this function")
+	auditLogShowCmd.Flags().StringVar(&auditAction, "action", "", "Only show deploy, remove or invoke entries")
+
+	auditLogCmd.AddCommand(auditLogShowCmd)
+	faasCmd.AddCommand(auditLogCmd)
+}
+
+var auditLogCmd = &cobra.Command{
+	Use:   `audit-log`,
+	Short: "Read the local audit log of deployments, removals and invocations",
+	Long: `The audit log records each deploy, remove and invoke issued by the CLI on
+this machine: who ran it, when, the gateway, the function, its image digest and
+whether it succeeded. It is off until it is turned on in the config file:
+
+  audit:
+    enabled: true
+
+Entries are kept as one JSONL file per day under ` + audit.DefaultDirectory + `.`,
+}
+
+var auditLogShowCmd = &cobra.Command{
+	Use:   `show [--since 7d] [--function NAME] [--action ACTION]`,
+	Short: "Print the entries of the audit log",
+	Example: `  faas-cli audit-log show
+  faas-cli audit-log show --since 24h --function api
+  faas-cli audit-log show --since 2018-06-01 --action deploy`,
+	RunE: runAuditLogShow,
+}
+
+// auditLog gives the log entries are recorded in, nil when it is turned off
+var auditLog = func() (*audit.Log, error) {
+	cfg, err := config.ReadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Audit == nil || !cfg.Audit.Enabled {
+		return nil, nil
+	}
+	return openAuditLog(cfg.Audit.Directory)
+}
+
+func openAuditLog(directory string) (*audit.Log, error) {
+	if len(directory) == 0 {
+		directory = audit.DefaultDirectory
+	}
+	expanded, err := homedir.Expand(directory)
+	if err != nil {
+		return nil, err
+	}
+	return &audit.Log{Directory: expanded}, nil
+}
+
+// recordAudit adds an entry when the audit log is turned on, a failure to
+// record is reported without failing the command
+func recordAudit(entry audit.Entry) {
+	log, err := auditLog()
+	if err == nil && log == nil {
+		return
+	}
+
+	if err == nil {
+		entry.Time = time.Now().UTC()
+		entry.User = auditUser()
+		err = log.Record(entry)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to record %s of %s in the audit log: %s\n", entry.Action, entry.Function, err)
+	}
+}
+
+func auditUser() string {
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
+
+// auditDigest is the digest pinned in an image or recorded when it was
+// deployed, the registry is not asked
+func auditDigest(image string, annotations map[string]string) string {
+	if i := strings.Index(image, "@"); i > -1 {
+		return image[i+1:]
+	}
+	return annotations[imageDigestAnnotation]
+}
+
+func auditError(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func runAuditLogShow(cmd *cobra.Command, args []string) error {
+	since, err := audit.ParseSince(auditSince, time.Now())
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.ReadConfigFile()
+	if err != nil {
+		return err
+	}
+	directory := ""
+	if cfg.Audit != nil {
+		directory = cfg.Audit.Directory
+	}
+	log, err := openAuditLog(directory)
+	if err != nil {
+		return err
+	}
+
+	entries, err := log.Read(since)
+	if err != nil {
+		return err
+	}
+
+	var shown []audit.Entry
+	for _, entry := range entries {
+		if len(auditFunction) > 0 && entry.Function != auditFunction {
+			continue
+		}
+		if len(auditAction) > 0 && entry.Action != auditAction {
+			continue
+		}
+		shown = append(shown, entry)
+	}
+
+	if len(shown) == 0 {
+		fmt.Printf("No entries in the audit log since %s.\n", since.Format(time.RFC3339))
+		if cfg.Audit == nil || !cfg.Audit.Enabled {
+			fmt.Println("The audit log is turned off, set audit.enabled to true in the config file to record entries.")
+		}
+		return nil
+	}
+
+	printAuditEntries(os.Stdout, shown)
+	return nil
+}
+
+func printAuditEntries(w io.Writer, entries []audit.Entry) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "TIME\tUSER\tACTION\tGATEWAY\tFUNCTION\tDIGEST\tSTATUS")
+	for _, entry := range entries {
+		digest := entry.Digest
+		if len(digest) == 0 {
+			digest = "-"
+		}
+
+		status := "ok"
+		if !entry.Success {
+			status = "failed"
+		}
+		if entry.StatusCode > 0 {
+			status += " (" + strconv.Itoa(entry.StatusCode) + ")"
+		}
+		if len(entry.Error) > 0 {
+			status += ": " + entry.Error
+		}
+
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", entry.Time.Local().Format(time.RFC3339), entry.User,
+			entry.Action, entry.Gateway, entry.Function, digest, status)
+	}
+	table.Flush()
+}
@@ -0,0 +1,81 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/audit"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_recordAudit_Deploy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	log := &audit.Log{Directory: dir}
+	defer func(original func() (*audit.Log, error)) {
+		auditLog = original
+	}(auditLog)
+	auditLog = func() (*audit.Log, error) { return log, nil }
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+	})
+	defer s.Close()
+
+	resetForTest()
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"deploy",
+			"--gateway=" + s.URL,
+			"--image=api@sha256:abc",
+			"--name=api",
+		})
+		faasCmd.Execute()
+	})
+
+	entries, err := log.Read(time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("want one entry, got %d", len(entries))
+	}
+	entry := entries[0]
+	if entry.Action != audit.Deploy || entry.Function != "api" || entry.Gateway != s.URL ||
+		entry.Digest != "sha256:abc" || entry.StatusCode != http.StatusAccepted || !entry.Success || len(entry.User) == 0 {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+}
+
+func Test_printAuditEntries(t *testing.T) {
+	when := time.Date(2018, 6, 10, 12, 0, 0, 0, time.Local)
+	var out bytes.Buffer
+	printAuditEntries(&out, []audit.Entry{
+		{Time: when, User: "alex", Action: audit.Deploy, Gateway: "http://gw", Function: "api", Digest: "sha256:abc", StatusCode: 202, Success: true},
+		{Time: when, User: "alex", Action: audit.Invoke, Gateway: "http://gw", Function: "api", Error: "timeout"},
+	})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want a header and two rows, got:\n%s", out.String())
+	}
+	if !strings.HasSuffix(lines[1], "sha256:abc  ok (202)") || !strings.HasSuffix(lines[2], "-           failed: timeout") {
+		t.Errorf("unexpected rows:\n%s", out.String())
+	}
+}
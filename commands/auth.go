@@ -0,0 +1,114 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/spf13/cobra"
+)
+
+var migrateStore string
+
+func init() {
+	authMigrateCmd.Flags().StringVar(&migrateStore, "store", "", "Move the credentials to the file, keychain or age store")
+
+	authCmd.AddCommand(authStatusCmd)
+	authCmd.AddCommand(authMigrateCmd)
+	faasCmd.AddCommand(authCmd)
+}
+
+var authCmd = &cobra.Command{
+	Use:   `auth`,
+	Short: "Manage the credentials saved by login",
+	Long: `Manage the credentials saved by login, or log in through an OAuth2 or OpenID
+Connect identity provider with "faas-cli auth login".
+
+Credentials are kept in ~/.openfaas/config.yml in plaintext unless
+"credentials.store" in the config file, or login --store, picks the OS keychain
+or an age identity:
+
+  credentials:
+    store: age
+    age_identity: ~/.openfaas/identity.txt`,
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:     `status`,
+	Short:   "Show where the credentials for each gateway are stored",
+	Example: `  faas-cli auth status`,
+	RunE:    runAuthStatus,
+}
+
+var authMigrateCmd = &cobra.Command{
+	Use:   `migrate --store STORE`,
+	Short: "Move saved credentials to another store",
+	Example: `  faas-cli auth migrate --store keychain
+  faas-cli auth migrate --store age`,
+	RunE: runAuthMigrate,
+}
+
+func runAuthStatus(cmd *cobra.Command, args []string) error {
+	statuses, err := config.AuthStatus()
+	if err != nil {
+		return err
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No credentials saved, use faas-cli login to add some.")
+		return nil
+	}
+
+	printAuthStatus(os.Stdout, statuses)
+	return nil
+}
+
+func printAuthStatus(out io.Writer, statuses []config.CredentialStatus) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GATEWAY\tUSER\tSTORE\tLOCATION\tSTATUS")
+
+	var plaintext int
+	for _, status := range statuses {
+		state := "ok"
+		if len(status.Error) > 0 {
+			state = "error: " + status.Error
+		}
+		if status.Store == config.FileStore {
+			plaintext++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status.Gateway, status.Username, status.Store, status.Location, state)
+	}
+	w.Flush()
+
+	if plaintext > 0 {
+		fmt.Fprintf(out, "\n%d credential(s) are stored in plaintext, encrypt them with: faas-cli auth migrate --store keychain|age\n", plaintext)
+	}
+}
+
+func runAuthMigrate(cmd *cobra.Command, args []string) error {
+	switch migrateStore {
+	case config.FileStore, config.KeychainStore, config.AgeStore:
+	case "":
+		return fmt.Errorf("give the store to move the credentials to with --store")
+	default:
+		return fmt.Errorf("--store must be file, keychain or age")
+	}
+
+	moved, err := config.MigrateAuthConfigs(migrateStore)
+	if err != nil {
+		return err
+	}
+
+	if len(moved) == 0 {
+		fmt.Printf("All credentials are already in the %s store.\n", migrateStore)
+		return nil
+	}
+	fmt.Printf("Moved the credentials for %s to the %s store.\n", strings.Join(moved, ", "), migrateStore)
+	return nil
+}
@@ -0,0 +1,308 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+// Grants given to auth login --grant
+const (
+	codeGrant              = "code"
+	clientCredentialsGrant = "client-credentials"
+)
+
+// oauthCallbackPath is where the identity provider redirects the browser to
+const oauthCallbackPath = "/oauth/callback"
+
+var (
+	authLoginIssuer            string
+	authLoginAuthURL           string
+	authLoginTokenURL          string
+	authLoginClientID          string
+	authLoginClientSecret      string
+	authLoginClientSecretStdin bool
+	authLoginGrant             string
+	authLoginScope             string
+	authLoginAudience          string
+	authLoginListenPort        int
+	authLoginNoBrowser         bool
+	authLoginTimeout           time.Duration
+	authLoginStore             string
+)
+
+// authLoginInput is read for --client-secret-stdin, it is swapped out in tests
+var authLoginInput io.Reader = os.Stdin
+
+// openBrowser opens the authorization URL for the user, it is swapped out in
+// tests
+var openBrowser = func(address string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", address).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", address).Start()
+	default:
+		return exec.Command("xdg-open", address).Start()
+	}
+}
+
+func init() {
+	authLoginCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	authLoginCmd.Flags().StringVar(&authLoginIssuer, "issuer", "", "OpenID Connect issuer whose discovery document gives the authorization and token URLs")
+	authLoginCmd.Flags().StringVar(&authLoginAuthURL, "auth-url", "", "Authorization endpoint of the identity provider, overrides the issuer's")
+	authLoginCmd.Flags().StringVar(&authLoginTokenURL, "token-url", "", "Token endpoint of the identity provider, overrides the issuer's")
+	authLoginCmd.Flags().StringVar(&authLoginClientID, "client-id", "", "OAuth2 client ID registered with the identity provider")
+	authLoginCmd.Flags().StringVar(&authLoginClientSecret, "client-secret", "", "OAuth2 client secret, needed by the client-credentials grant")
+	authLoginCmd.Flags().BoolVar(&authLoginClientSecretStdin, "client-secret-stdin", false, "Reads the OAuth2 client secret from stdin")
+	authLoginCmd.Flags().StringVar(&authLoginGrant, "grant", codeGrant, "Grant used to get the token: code, for the authorization code flow with PKCE in a browser, or client-credentials")
+	authLoginCmd.Flags().StringVar(&authLoginScope, "scope", "", "Scopes to ask for, the code grant defaults to \"openid offline_access\"")
+	authLoginCmd.Flags().StringVar(&authLoginAudience, "audience", "", "Audience of the token, for identity providers which need one")
+	authLoginCmd.Flags().IntVar(&authLoginListenPort, "listen-port", 31111, "Port on 127.0.0.1 the browser is redirected to by the code grant")
+	authLoginCmd.Flags().BoolVar(&authLoginNoBrowser, "no-browser", false, "Print the authorization URL instead of opening a browser")
+	authLoginCmd.Flags().DurationVar(&authLoginTimeout, "timeout", 5*time.Minute, "How long to wait for the browser to be redirected back")
+	authLoginCmd.Flags().StringVar(&authLoginStore, "store", "", "Store the token in the file, keychain or age store, defaults to credentials.store in the config file")
+
+	authCmd.AddCommand(authLoginCmd)
+}
+
+var authLoginCmd = &cobra.Command{
+	Use:   `login --client-id CLIENT_ID (--issuer ISSUER_URL | --auth-url URL --token-url URL) [--grant code|client-credentials] [--gateway GATEWAY_URL]`,
+	Short: "Log in to a gateway through an OAuth2 or OpenID Connect identity provider",
+	Long: `Gets an access token for the gateway from an OAuth2 or OpenID Connect
+identity provider and saves it with the credentials of the gateway. The token
+is sent as a bearer token by every command which calls the gateway, and is
+renewed with its refresh token, or with the client credentials, when it
+expires.
+
+The code grant opens a browser at the identity provider and listens on
+127.0.0.1 for the redirect back, using PKCE so that no client secret is
+needed. Register http://127.0.0.1:31111/oauth/callback, or the --listen-port
+given, as a redirect URI of the client. The client-credentials grant is for
+CI jobs and other machines, and needs the client's secret.`,
+	Example: `  faas-cli auth login --issuer https://idp.example.com/realms/openfaas --client-id faas-cli \
+    --gateway https://openfaas.example.com
+  echo $CLIENT_SECRET | faas-cli auth login --grant client-credentials --client-secret-stdin \
+    --issuer https://idp.example.com --client-id ci --audience openfaas --gateway https://openfaas.example.com
+  faas-cli auth login --auth-url https://idp.example.com/authorize --token-url https://idp.example.com/token \
+    --client-id faas-cli --no-browser`,
+	RunE: runAuthLogin,
+}
+
+func runAuthLogin(cmd *cobra.Command, args []string) error {
+	if len(authLoginClientID) == 0 {
+		return fmt.Errorf("must provide --client-id")
+	}
+	if authLoginGrant != codeGrant && authLoginGrant != clientCredentialsGrant {
+		return fmt.Errorf("--grant must be %s or %s, not %s", codeGrant, clientCredentialsGrant, authLoginGrant)
+	}
+
+	clientSecret := strings.TrimSpace(authLoginClientSecret)
+	if authLoginClientSecretStdin {
+		if len(clientSecret) > 0 {
+			return fmt.Errorf("--client-secret and --client-secret-stdin are mutually exclusive")
+		}
+		secret, err := ioutil.ReadAll(authLoginInput)
+		if err != nil {
+			return err
+		}
+		clientSecret = strings.TrimSpace(string(secret))
+	}
+	if authLoginGrant == clientCredentialsGrant && len(clientSecret) == 0 {
+		return fmt.Errorf("the client-credentials grant needs --client-secret or --client-secret-stdin")
+	}
+
+	endpoints := proxy.OAuthEndpoints{AuthorizationURL: authLoginAuthURL, TokenURL: authLoginTokenURL}
+	if len(authLoginIssuer) > 0 {
+		discovered, err := proxy.DiscoverOAuthEndpoints(authLoginIssuer)
+		if err != nil {
+			return err
+		}
+		if len(endpoints.AuthorizationURL) == 0 {
+			endpoints.AuthorizationURL = discovered.AuthorizationURL
+		}
+		if len(endpoints.TokenURL) == 0 {
+			endpoints.TokenURL = discovered.TokenURL
+		}
+	}
+	if len(endpoints.TokenURL) == 0 {
+		return fmt.Errorf("must provide --issuer or --token-url")
+	}
+
+	token := config.OAuthToken{
+		TokenURL:     endpoints.TokenURL,
+		ClientID:     authLoginClientID,
+		ClientSecret: clientSecret,
+		Scope:        authLoginScope,
+		Audience:     authLoginAudience,
+	}
+
+	var issued config.OAuthToken
+	var err error
+	if authLoginGrant == clientCredentialsGrant {
+		token.Grant = config.ClientCredentialsGrant
+		issued, err = proxy.RequestOAuthToken(token.TokenURL, proxy.ClientCredentialsForm(token))
+	} else {
+		if len(endpoints.AuthorizationURL) == 0 {
+			return fmt.Errorf("the code grant needs --issuer or --auth-url")
+		}
+		if len(token.Scope) == 0 {
+			token.Scope = "openid offline_access"
+		}
+		token.Grant = config.AuthorizationCodeGrant
+		issued, err = authorizationCodeFlow(endpoints.AuthorizationURL, token)
+	}
+	if err != nil {
+		return err
+	}
+	token.AccessToken = issued.AccessToken
+	token.RefreshToken = issued.RefreshToken
+	token.Expiry = issued.Expiry
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	fmt.Println("Calling the OpenFaaS server to validate the token...")
+	err = validateGatewayAuth(gatewayAddress, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	}, "unable to login, the gateway rejected the token")
+	if err != nil {
+		return err
+	}
+
+	if err := config.UpdateOAuthToken(gatewayAddress, token, authLoginStore); err != nil {
+		return err
+	}
+
+	if token.Expiry.IsZero() {
+		fmt.Printf("token saved for %s %s\n", token.ClientID, gatewayAddress)
+	} else {
+		fmt.Printf("token saved for %s %s, it is renewed after %s\n", token.ClientID, gatewayAddress, token.Expiry.Local().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// authorizationCodeFlow sends the user to the identity provider in a browser
+// and exchanges the code it redirects back with for a token, using PKCE
+func authorizationCodeFlow(authURL string, token config.OAuthToken) (config.OAuthToken, error) {
+	verifier, err := randomURLSafe(32)
+	if err != nil {
+		return config.OAuthToken{}, err
+	}
+	state, err := randomURLSafe(16)
+	if err != nil {
+		return config.OAuthToken{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	listener, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(authLoginListenPort)))
+	if err != nil {
+		return config.OAuthToken{}, fmt.Errorf("unable to listen for the redirect from the identity provider: %s", err)
+	}
+	redirectURI := "http://" + listener.Addr().String() + oauthCallbackPath
+
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", token.ClientID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("scope", token.Scope)
+	query.Set("state", state)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	if len(token.Audience) > 0 {
+		query.Set("audience", token.Audience)
+	}
+	separator := "?"
+	if strings.Contains(authURL, "?") {
+		separator = "&"
+	}
+	address := authURL + separator + query.Encode()
+
+	// Only the first redirect is waited for, the sends don't block so that a
+	// browser retrying or a second tab can't hang the handler on a full channel
+	codes := make(chan string, 1)
+	failures := make(chan error, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != oauthCallbackPath {
+			http.NotFound(w, r)
+			return
+		}
+
+		callback := r.URL.Query()
+		switch {
+		case callback.Get("state") != state:
+			http.Error(w, "The state does not match the login, run faas-cli auth login again.", http.StatusBadRequest)
+			select {
+			case failures <- fmt.Errorf("the identity provider redirected back with another login's state"):
+			default:
+			}
+		case len(callback.Get("error")) > 0:
+			http.Error(w, "Login failed: "+callback.Get("error"), http.StatusBadRequest)
+			select {
+			case failures <- fmt.Errorf("the identity provider refused the login: %s %s", callback.Get("error"), callback.Get("error_description")):
+			default:
+			}
+		default:
+			fmt.Fprintln(w, "Logged in to OpenFaaS, you can close this window.")
+			select {
+			case codes <- callback.Get("code"):
+			default:
+			}
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	fmt.Printf("Log in at:\n\n  %s\n\n", address)
+	if !authLoginNoBrowser {
+		if err := openBrowser(address); err != nil {
+			fmt.Println("Unable to open a browser, open the URL above to log in.")
+		}
+	}
+
+	var code string
+	select {
+	case code = <-codes:
+	case err := <-failures:
+		return config.OAuthToken{}, err
+	case <-time.After(authLoginTimeout):
+		return config.OAuthToken{}, fmt.Errorf("timed out after %s waiting for the identity provider to redirect back", authLoginTimeout)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", config.AuthorizationCodeGrant)
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("client_id", token.ClientID)
+	form.Set("code_verifier", verifier)
+	if len(token.ClientSecret) > 0 {
+		form.Set("client_secret", token.ClientSecret)
+	}
+	return proxy.RequestOAuthToken(token.TokenURL, form)
+}
+
+// randomURLSafe gives n random bytes encoded for use in a URL
+func randomURLSafe(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,207 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/test"
+)
+
+// fakeIdentityProvider checks the PKCE verifier of code grants against the
+// challenge sent to /authorize and issues token-for-CLIENT_ID
+type fakeIdentityProvider struct {
+	*httptest.Server
+	challenge string
+	grants    []url.Values
+}
+
+func newFakeIdentityProvider() *fakeIdentityProvider {
+	idp := &fakeIdentityProvider{}
+	idp.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"authorization_endpoint": idp.URL + "/authorize",
+				"token_endpoint":         idp.URL + "/token",
+			})
+		case "/token":
+			r.ParseForm()
+			idp.grants = append(idp.grants, r.PostForm)
+			if r.PostForm.Get("grant_type") == "authorization_code" {
+				sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+				if base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge || r.PostForm.Get("code") != "code-1" {
+					w.WriteHeader(http.StatusBadRequest)
+					json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+					return
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token":  "token-for-" + r.PostForm.Get("client_id"),
+				"refresh_token": "refresh-1",
+				"expires_in":    3600,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return idp
+}
+
+// bearerGateway only lists functions for the token given
+func bearerGateway(token string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte("[]"))
+	}))
+}
+
+// useAuthLoginConfig saves the config to a temporary directory and returns
+// a func which removes it and resets the flags
+func useAuthLoginConfig() func() {
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-auth-login")
+	return func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+
+		authLoginIssuer, authLoginAuthURL, authLoginTokenURL = "", "", ""
+		authLoginClientID, authLoginGrant, authLoginScope, authLoginStore = "", codeGrant, "", ""
+		authLoginClientSecret, authLoginClientSecretStdin = "", false
+		authLoginAudience, authLoginListenPort, authLoginNoBrowser = "", 31111, false
+		authLoginInput = os.Stdin
+	}
+}
+
+func Test_authLogin_ClientCredentials(t *testing.T) {
+	defer useAuthLoginConfig()()
+	idp := newFakeIdentityProvider()
+	defer idp.Close()
+	gw := bearerGateway("token-for-ci")
+	defer gw.Close()
+
+	authLoginInput = strings.NewReader("s3cr3t\n")
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"auth", "login", "--grant", "client-credentials", "--client-secret-stdin",
+			"--issuer", idp.URL, "--client-id", "ci", "--audience", "openfaas", "--gateway", gw.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if len(idp.grants) != 1 || idp.grants[0].Get("client_secret") != "s3cr3t" || idp.grants[0].Get("audience") != "openfaas" {
+		t.Errorf("want a client_credentials grant with the secret, got %v", idp.grants)
+	}
+
+	token, err := config.LookupOAuthToken(gw.URL)
+	if err != nil || token == nil {
+		t.Fatalf("want the token saved, got %v", err)
+	}
+	if token.AccessToken != "token-for-ci" || token.Grant != config.ClientCredentialsGrant || token.ClientSecret != "s3cr3t" || token.TokenURL != idp.URL+"/token" {
+		t.Errorf("got %+v", token)
+	}
+}
+
+func Test_authLogin_CodeWithPKCE(t *testing.T) {
+	defer useAuthLoginConfig()()
+	idp := newFakeIdentityProvider()
+	defer idp.Close()
+	gw := bearerGateway("token-for-faas-cli")
+	defer gw.Close()
+
+	// The "browser" logs straight in and is redirected back with a code
+	originalOpen := openBrowser
+	defer func() { openBrowser = originalOpen }()
+	openBrowser = func(address string) error {
+		authorize, err := url.Parse(address)
+		if err != nil {
+			return err
+		}
+		query := authorize.Query()
+		if query.Get("code_challenge_method") != "S256" || query.Get("scope") != "openid offline_access" {
+			t.Errorf("want a PKCE authorization request, got %s", address)
+		}
+		idp.challenge = query.Get("code_challenge")
+
+		go func() {
+			res, err := http.Get(query.Get("redirect_uri") + "?code=code-1&state=" + url.QueryEscape(query.Get("state")))
+			if err == nil {
+				res.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	out := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"auth", "login", "--issuer", idp.URL, "--client-id", "faas-cli", "--listen-port", "0", "--gateway", gw.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "token saved for faas-cli "+gw.URL) {
+		t.Errorf("want the token saved, got:\n%s", out)
+	}
+
+	token, _ := config.LookupOAuthToken(gw.URL)
+	if token == nil || token.AccessToken != "token-for-faas-cli" || token.RefreshToken != "refresh-1" || token.Expired(time.Now()) {
+		t.Errorf("got %+v", token)
+	}
+}
+
+func Test_authLogin_StateMismatch(t *testing.T) {
+	defer useAuthLoginConfig()()
+	idp := newFakeIdentityProvider()
+	defer idp.Close()
+
+	originalOpen := openBrowser
+	defer func() { openBrowser = originalOpen }()
+	openBrowser = func(address string) error {
+		authorize, _ := url.Parse(address)
+		go func() {
+			res, err := http.Get(authorize.Query().Get("redirect_uri") + "?code=code-1&state=forged")
+			if err == nil {
+				res.Body.Close()
+			}
+		}()
+		return nil
+	}
+
+	var err error
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"auth", "login", "--issuer", idp.URL, "--client-id", "faas-cli", "--listen-port", "0"})
+		err = faasCmd.Execute()
+	})
+	want := "the identity provider redirected back with another login's state"
+	if err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+	if len(idp.grants) > 0 {
+		t.Errorf("want no code exchanged, got %v", idp.grants)
+	}
+}
+
+func Test_authLogin_ClientCredentialsNeedsSecret(t *testing.T) {
+	defer useAuthLoginConfig()()
+
+	faasCmd.SetArgs([]string{"auth", "login", "--grant", "client-credentials", "--client-id", "ci", "--token-url", "http://127.0.0.1:1/token"})
+	err := faasCmd.Execute()
+	want := "the client-credentials grant needs --client-secret or --client-secret-stdin"
+	if err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+}
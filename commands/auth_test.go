@@ -0,0 +1,41 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+func Test_printAuthStatus(t *testing.T) {
+	var out bytes.Buffer
+	printAuthStatus(&out, []config.CredentialStatus{
+		{Gateway: "http://one.test", Username: "admin", Store: config.FileStore, Location: "config file (plaintext)"},
+		{Gateway: "http://two.test", Store: config.KeychainStore, Location: "macOS Keychain", Error: "not found"},
+	})
+
+	for _, want := range []string{
+		"GATEWAY",
+		"http://one.test  admin",
+		"keychain  macOS Keychain           error: not found",
+		"1 credential(s) are stored in plaintext",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func Test_runAuthMigrate_InvalidStore(t *testing.T) {
+	migrateStore = "vault"
+	defer func() { migrateStore = "" }()
+
+	err := runAuthMigrate(nil, nil)
+	if err == nil || err.Error() != "--store must be file, keychain or age" {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+const backupVersion = 1
+
+var (
+	backupOutput              string
+	backupIncludeSecretValues bool
+	restoreInputFile          string
+	restoreYes                bool
+)
+
+// restoreInput is read for the confirmation before a backup is restored
+var restoreInput io.Reader = os.Stdin
+
+func init() {
+	backupCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	backupCmd.Flags().StringVarP(&backupOutput, "output", "o", "backup.tar.gz", "File to write the backup to")
+	backupCmd.Flags().BoolVar(&backupIncludeSecretValues, "include-secret-values", false, "Include the values of secrets, when the provider returns them")
+
+	restoreCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	restoreCmd.Flags().StringVarP(&restoreInputFile, "input", "i", "backup.tar.gz", "Backup file written by faas-cli backup")
+	restoreCmd.Flags().BoolVarP(&restoreYes, "yes", "y", false, "Restore without asking for confirmation")
+
+	faasCmd.AddCommand(backupCmd)
+	faasCmd.AddCommand(restoreCmd)
+}
+
+var backupCmd = &cobra.Command{
+	Use:   `backup [--gateway GATEWAY_URL] [--output FILE] [--include-secret-values]`,
+	Short: "Back up the functions and secrets of a gateway",
+	Long: `Writes the spec of every deployed function, the namespaces they are deployed
+to and the names of the secrets in each namespace to a .tar.gz file, which
+faas-cli restore re-creates on another gateway.
+
+Secret values are left out unless --include-secret-values is given, which needs
+a provider that returns them. Keep a backup with secret values somewhere safe.`,
+	Example: `  faas-cli backup --output backup.tar.gz
+  faas-cli backup -g https://openfaas.example.com --include-secret-values`,
+	RunE: runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   `restore [--gateway GATEWAY_URL] [--input FILE] [--yes]`,
+	Short: "Re-create the functions and secrets of a backup on a gateway",
+	Long: `Creates the secrets and deploys the functions from a file written by
+faas-cli backup. Existing functions are updated, existing secrets are left as
+they are. Secrets backed up without their values must be created before the
+functions which use them can start.`,
+	Example: `  faas-cli restore --input backup.tar.gz -g https://dr.example.com
+  faas-cli restore --input backup.tar.gz -g https://dr.example.com --yes`,
+	RunE: runRestore,
+}
+
+// backupManifest describes the contents of a backup
+type backupManifest struct {
+	Version              int       `json:"version"`
+	Gateway              string    `json:"gateway"`
+	Created              time.Time `json:"created"`
+	Namespaces           []string  `json:"namespaces,omitempty"`
+	SecretValuesIncluded bool      `json:"secret_values_included"`
+}
+
+// backup is everything written to a backup file
+type backup struct {
+	Manifest  backupManifest
+	Functions []proxy.FunctionStatus
+	Secrets   []proxy.Secret
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+
+	b, err := readGatewayBackup(gatewayAddress, backupIncludeSecretValues)
+	if err != nil {
+		return err
+	}
+
+	if err := writeBackup(backupOutput, b); err != nil {
+		return fmt.Errorf("unable to write %s: %s", backupOutput, err)
+	}
+
+	values := "names only"
+	if backupIncludeSecretValues {
+		values = "with values"
+	}
+	fmt.Printf("Backed up %d function(s) and %d secret(s) (%s) from %s to %s.\n",
+		len(b.Functions), len(b.Secrets), values, gatewayAddress, backupOutput)
+	return nil
+}
+
+// readGatewayBackup reads the functions and secrets from each namespace, or
+// from the default namespace when the provider has no namespaces
+func readGatewayBackup(gatewayAddress string, includeSecretValues bool) (*backup, error) {
+	namespaces, err := proxy.ListNamespaces(gatewayAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &backup{
+		Manifest: backupManifest{
+			Version:              backupVersion,
+			Gateway:              gatewayAddress,
+			Created:              time.Now().UTC(),
+			Namespaces:           namespaces,
+			SecretValuesIncluded: includeSecretValues,
+		},
+	}
+
+	scopes := namespaces
+	if len(scopes) == 0 {
+		scopes = []string{""}
+	}
+
+	for _, namespace := range scopes {
+		functions, err := proxy.ListFunctionStatusInNamespace(gatewayAddress, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, function := range functions {
+			function.Namespace = namespace
+			b.Functions = append(b.Functions, function)
+		}
+
+		secrets, err := proxy.ListSecrets(gatewayAddress, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range secrets {
+			if includeSecretValues && len(secret.Value) == 0 {
+				return nil, fmt.Errorf("the provider did not return the value of secret %s, back up without --include-secret-values", secret.Name)
+			}
+			if !includeSecretValues {
+				secret.Value = ""
+			}
+			secret.Namespace = namespace
+			b.Secrets = append(b.Secrets, secret)
+		}
+	}
+
+	sort.SliceStable(b.Functions, func(i, j int) bool {
+		return b.Functions[i].Namespace+"/"+b.Functions[i].Name < b.Functions[j].Namespace+"/"+b.Functions[j].Name
+	})
+	sort.SliceStable(b.Secrets, func(i, j int) bool {
+		return b.Secrets[i].Namespace+"/"+b.Secrets[i].Name < b.Secrets[j].Namespace+"/"+b.Secrets[j].Name
+	})
+	return b, nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+
+	b, err := readBackup(restoreInputFile)
+	if err != nil {
+		return fmt.Errorf("unable to read %s: %s", restoreInputFile, err)
+	}
+
+	if len(b.Manifest.Namespaces) > 0 {
+		namespaces, err := proxy.ListNamespaces(gatewayAddress)
+		if err != nil {
+			return err
+		}
+		if missing := missingNamespaces(b.Manifest.Namespaces, namespaces); len(missing) > 0 {
+			return fmt.Errorf("create the namespaces %v on %s before restoring", missing, gatewayAddress)
+		}
+	}
+
+	fmt.Printf("Restoring %d function(s) and %d secret(s) backed up from %s at %s to %s.\n",
+		len(b.Functions), len(b.Secrets), b.Manifest.Gateway, b.Manifest.Created.Format(time.RFC3339), gatewayAddress)
+	if !restoreYes && !confirm(restoreInput, "Restore the backup?") {
+		return fmt.Errorf("restore cancelled")
+	}
+
+	var needValues []string
+	for _, secret := range b.Secrets {
+		name := qualifiedName(secret.Name, secret.Namespace)
+		if len(secret.Value) == 0 {
+			exists, err := secretExists(gatewayAddress, secret)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				needValues = append(needValues, name)
+			}
+			continue
+		}
+
+		if err := proxy.CreateSecret(gatewayAddress, secret); err != nil {
+			fmt.Printf("Secret %s not created: %s\n", name, err)
+			continue
+		}
+		fmt.Printf("Created secret: %s.\n", name)
+	}
+
+	failed := 0
+	for _, function := range b.Functions {
+		fmt.Printf("Deploying: %s.\n", qualifiedName(function.Name, function.Namespace))
+
+		spec := restoredSpec(function)
+		statusCode := proxy.DeployFunction(gatewayAddress, spec)
+		recordDeploy(gatewayAddress, spec, statusCode)
+		if !deploySucceeded(statusCode) {
+			failed++
+		}
+	}
+
+	if len(needValues) > 0 {
+		fmt.Printf("\nThese secrets were backed up without their values, create them so that the functions which use them can start:\n")
+		for _, name := range needValues {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d function(s) could not be deployed", failed)
+	}
+	return nil
+}
+
+// restoredSpec deploys a backed up function as it was, updating it when it
+// already exists
+func restoredSpec(function proxy.FunctionStatus) *proxy.DeployFunctionSpec {
+	return &proxy.DeployFunctionSpec{
+		FProcess:     function.EnvProcess,
+		FunctionName: function.Name,
+		Image:        function.Image,
+		EnvVars:      function.EnvVars,
+		Network:      defaultNetwork,
+		Constraints:  function.Constraints,
+		Update:       true,
+		Secrets:      function.Secrets,
+		Labels:       function.Labels,
+		Annotations:  function.Annotations,
+		FunctionResourceRequest: proxy.FunctionResourceRequest{
+			Limits:   function.Limits,
+			Requests: function.Requests,
+		},
+		Namespace: function.Namespace,
+	}
+}
+
+func secretExists(gatewayAddress string, secret proxy.Secret) (bool, error) {
+	secrets, err := proxy.ListSecrets(gatewayAddress, secret.Namespace)
+	if err != nil {
+		return false, err
+	}
+	for _, existing := range secrets {
+		if existing.Name == secret.Name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func missingNamespaces(want []string, have []string) []string {
+	existing := map[string]bool{}
+	for _, namespace := range have {
+		existing[namespace] = true
+	}
+
+	var missing []string
+	for _, namespace := range want {
+		if !existing[namespace] {
+			missing = append(missing, namespace)
+		}
+	}
+	return missing
+}
+
+func qualifiedName(name string, namespace string) string {
+	if len(namespace) == 0 {
+		return name
+	}
+	return name + "." + namespace
+}
+
+// The files held in a backup archive
+const (
+	backupManifestFile  = "backup.json"
+	backupFunctionsFile = "functions.json"
+	backupSecretsFile   = "secrets.json"
+)
+
+func writeBackup(path string, b *backup) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	entries := []struct {
+		name  string
+		value interface{}
+	}{
+		{backupManifestFile, b.Manifest},
+		{backupFunctionsFile, b.Functions},
+		{backupSecretsFile, b.Secrets},
+	}
+	for _, entry := range entries {
+		data, err := json.MarshalIndent(entry.value, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: b.Manifest.Created,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := tarWriter.Write(data); err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+func readBackup(path string) (*backup, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	b := &backup{}
+	targets := map[string]interface{}{
+		backupManifestFile:  &b.Manifest,
+		backupFunctionsFile: &b.Functions,
+		backupSecretsFile:   &b.Secrets,
+	}
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		target, ok := targets[header.Name]
+		if !ok {
+			continue
+		}
+		data, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, target); err != nil {
+			return nil, fmt.Errorf("%s: %s", header.Name, err)
+		}
+	}
+
+	if b.Manifest.Version != backupVersion {
+		return nil, fmt.Errorf("not a backup written by faas-cli backup")
+	}
+	return b, nil
+}
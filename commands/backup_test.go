@@ -0,0 +1,193 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_runBackup_RoundTrip(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/namespaces",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []string{"openfaas-fn", "staging"},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions?namespace=openfaas-fn",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "figlet", Image: "functions/figlet:0.1", Secrets: []string{"api-key"}},
+			},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/secrets?namespace=openfaas-fn",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []proxy.Secret{{Name: "api-key", Value: "not-kept"}},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []proxy.FunctionStatus{{Name: "nodeinfo", Image: "functions/nodeinfo"}},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/secrets?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []proxy.Secret{},
+		},
+	})
+	defer s.Close()
+
+	gateway = s.URL
+	backupOutput = filepath.Join(dir, "backup.tar.gz")
+	defer func() {
+		gateway, backupOutput = defaultGateway, "backup.tar.gz"
+	}()
+
+	stdOut := test.CaptureStdout(func() {
+		err = runBackup(nil, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdOut, "Backed up 2 function(s) and 1 secret(s) (names only)") {
+		t.Errorf("unexpected output: %s", stdOut)
+	}
+
+	b, err := readBackup(backupOutput)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(b.Manifest.Namespaces, []string{"openfaas-fn", "staging"}) || b.Manifest.Gateway != s.URL {
+		t.Errorf("unexpected manifest: %+v", b.Manifest)
+	}
+	if len(b.Functions) != 2 || b.Functions[0].Name != "figlet" || b.Functions[0].Namespace != "openfaas-fn" || b.Functions[1].Namespace != "staging" {
+		t.Errorf("unexpected functions: %+v", b.Functions)
+	}
+	if !reflect.DeepEqual(b.Secrets, []proxy.Secret{{Name: "api-key", Namespace: "openfaas-fn"}}) {
+		t.Errorf("want secret names only, got %+v", b.Secrets)
+	}
+}
+
+func Test_readGatewayBackup_SecretValuesNotReturned(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/namespaces",
+			ResponseStatusCode: http.StatusNotFound,
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []proxy.FunctionStatus{},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []proxy.Secret{{Name: "api-key"}},
+		},
+	})
+	defer s.Close()
+
+	_, err := readGatewayBackup(s.URL, true)
+	if err == nil || !strings.Contains(err.Error(), "did not return the value of secret api-key") {
+		t.Errorf("want an error for the missing value, got %v", err)
+	}
+}
+
+func Test_runRestore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	restoreInputFile = filepath.Join(dir, "backup.tar.gz")
+	err = writeBackup(restoreInputFile, &backup{
+		Manifest: backupManifest{Version: backupVersion, Gateway: "http://primary:8080"},
+		Functions: []proxy.FunctionStatus{
+			{Name: "figlet", Image: "functions/figlet:0.1", Secrets: []string{"api-key", "db-password"}},
+		},
+		Secrets: []proxy.Secret{
+			{Name: "api-key", Value: "s3cr3t"},
+			{Name: "db-password"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusCreated,
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []proxy.Secret{{Name: "api-key"}},
+		},
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	gateway = s.URL
+	restoreYes = true
+	defer func() {
+		gateway, restoreYes, restoreInputFile = defaultGateway, false, "backup.tar.gz"
+	}()
+
+	stdOut := test.CaptureStdout(func() {
+		err = runRestore(nil, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"Restoring 1 function(s) and 2 secret(s) backed up from http://primary:8080",
+		"Created secret: api-key.",
+		"Deploying: figlet.",
+		"without their values, create them so that the functions which use them can start:\n  db-password\n",
+	} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in output:\n%s", want, stdOut)
+		}
+	}
+}
+
+func Test_missingNamespaces(t *testing.T) {
+	got := missingNamespaces([]string{"openfaas-fn", "staging"}, []string{"openfaas-fn"})
+	if !reflect.DeepEqual(got, []string{"staging"}) {
+		t.Errorf("want staging to be missing, got %v", got)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// batchPause waits between batches, it is swapped out in tests
+var batchPause = time.Sleep
+
+// batchOptions control how an operation on many functions is spread out
+type batchOptions struct {
+	// size is how many functions are worked on at once, 0 works on all of
+	// them at once without a checkpoint
+	size int
+
+	pause      time.Duration
+	resume     bool
+	checkpoint string
+}
+
+func addBatchFlags(cmd *cobra.Command, options *batchOptions, defaultSize int) {
+	cmd.Flags().IntVar(&options.size, "batch-size", defaultSize, "How many functions to work on at once")
+	cmd.Flags().DurationVar(&options.pause, "pause", 0, "How long to wait between batches, i.e. 30s")
+	cmd.Flags().BoolVar(&options.resume, "resume", false, "Continue an interrupted run from its checkpoint")
+	cmd.Flags().StringVar(&options.checkpoint, "checkpoint", "", "File the completed functions are recorded in, defaults to checkpoints/ACTION.json in the state directory")
+}
+
+// batchCheckpoint records the functions a batched operation has completed so
+// that it can be resumed when interrupted
+type batchCheckpoint struct {
+	Action    string    `json:"action"`
+	Gateway   string    `json:"gateway"`
+	Started   time.Time `json:"started"`
+	Completed []string  `json:"completed"`
+}
+
+// checkpointDirectory keeps the checkpoint of each interrupted run, it is
+// moved into the state directory before a command runs
+var checkpointDirectory = filepath.Join(".faas-cli", "checkpoints")
+
+func checkpointPath(action string, options batchOptions) string {
+	if len(options.checkpoint) > 0 {
+		return options.checkpoint
+	}
+	return filepath.Join(checkpointDirectory, action+".json")
+}
+
+// loadCheckpoint reads the checkpoint of an interrupted run, which must be
+// resumed or deleted before the operation is run again
+func loadCheckpoint(path string, action string, gateway string, resume bool) (*batchCheckpoint, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &batchCheckpoint{Action: action, Gateway: gateway, Started: time.Now().UTC()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoint := &batchCheckpoint{}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("unable to read the checkpoint %s: %s", path, err)
+	}
+
+	if !resume {
+		return nil, fmt.Errorf("an interrupted %s of %s from %s was found in %s, continue it with --resume or delete the file",
+			checkpoint.Action, checkpoint.Gateway, checkpoint.Started.Format(time.RFC3339), path)
+	}
+	if checkpoint.Action != action || checkpoint.Gateway != gateway {
+		return nil, fmt.Errorf("the checkpoint %s is for a %s of %s, not a %s of %s", path, checkpoint.Action, checkpoint.Gateway, action, gateway)
+	}
+	return checkpoint, nil
+}
+
+func saveCheckpoint(path string, checkpoint *batchCheckpoint) error {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// runBatches runs the action on each function, a batch at a time, and stops
+// after a batch in which any function failed. The functions completed so far
+// are kept in a checkpoint, which is removed once every function is done.
+func runBatches(action string, gateway string, names []string, options batchOptions, run func(name string) error) error {
+	if options.size <= 0 {
+		failed := 0
+		for _, name := range names {
+			if err := run(name); err != nil {
+				fmt.Printf("Unable to %s %s: %s\n", action, name, err)
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("unable to %s %d function(s)", action, failed)
+		}
+		return nil
+	}
+
+	path := checkpointPath(action, options)
+	checkpoint, err := loadCheckpoint(path, action, gateway, options.resume)
+	if err != nil {
+		return err
+	}
+
+	completed := map[string]bool{}
+	for _, name := range checkpoint.Completed {
+		completed[name] = true
+	}
+	var pending []string
+	for _, name := range names {
+		if !completed[name] {
+			pending = append(pending, name)
+		}
+	}
+	if len(checkpoint.Completed) > 0 {
+		fmt.Printf("Resuming: %d of %d function(s) were completed before.\n", len(names)-len(pending), len(names))
+	}
+
+	batches := (len(pending) + options.size - 1) / options.size
+	for batch := 0; batch < batches; batch++ {
+		start := batch * options.size
+		end := start + options.size
+		if end > len(pending) {
+			end = len(pending)
+		}
+
+		fmt.Printf("Batch %d of %d: %d function(s).\n", batch+1, batches, end-start)
+		failed := runBatch(action, pending[start:end], run, checkpoint)
+
+		if err := saveCheckpoint(path, checkpoint); err != nil {
+			return fmt.Errorf("unable to write the checkpoint %s: %s", path, err)
+		}
+		if failed > 0 {
+			return fmt.Errorf("unable to %s %d function(s) in batch %d, run again with --resume to continue", action, failed, batch+1)
+		}
+
+		if batch < batches-1 && options.pause > 0 {
+			fmt.Printf("Pausing for %s before the next batch.\n", options.pause)
+			batchPause(options.pause)
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	fmt.Printf("Completed %s of %d function(s) in %d batch(es).\n", action, len(pending), batches)
+	return nil
+}
+
+// runBatch runs the action on each function of the batch at the same time
+// and records those which succeeded in the checkpoint
+func runBatch(action string, names []string, run func(name string) error, checkpoint *batchCheckpoint) int {
+	errs := make([]error, len(names))
+
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			errs[i] = run(name)
+		}(i, name)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, name := range names {
+		if errs[i] != nil {
+			fmt.Printf("Unable to %s %s: %s\n", action, name, errs[i])
+			failed++
+			continue
+		}
+		checkpoint.Completed = append(checkpoint.Completed, name)
+	}
+	return failed
+}
@@ -0,0 +1,79 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func Test_runBatches_ResumesFromCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var pauses []time.Duration
+	batchPause = func(d time.Duration) { pauses = append(pauses, d) }
+	defer func() { batchPause = time.Sleep }()
+
+	options := batchOptions{size: 2, pause: 30 * time.Second, checkpoint: filepath.Join(dir, "checkpoint.json")}
+	names := []string{"a", "b", "c", "d", "e"}
+
+	var mu sync.Mutex
+	var ran []string
+	failing := map[string]bool{"c": true}
+	run := func(name string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, name)
+		if failing[name] {
+			return fmt.Errorf("gateway returned 500")
+		}
+		return nil
+	}
+
+	err = runBatches("redeploy", "http://gw", names, options, run)
+	if err == nil || !strings.Contains(err.Error(), "batch 2") {
+		t.Fatalf("want a failure in batch 2, got: %v", err)
+	}
+	sort.Strings(ran)
+	if !reflect.DeepEqual(ran, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("want the run to stop after the failed batch, ran: %v", ran)
+	}
+	if len(pauses) != 1 || pauses[0] != 30*time.Second {
+		t.Fatalf("want one pause between the batches, got: %v", pauses)
+	}
+
+	if err := runBatches("redeploy", "http://gw", names, options, run); err == nil || !strings.Contains(err.Error(), "--resume") {
+		t.Fatalf("want an error asking for --resume, got: %v", err)
+	}
+
+	options.resume = true
+	if err := runBatches("remove", "http://gw", names, options, run); err == nil {
+		t.Fatalf("want an error resuming a checkpoint of another action")
+	}
+
+	ran = nil
+	failing = map[string]bool{}
+	if err := runBatches("redeploy", "http://gw", names, options, run); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	sort.Strings(ran)
+	if !reflect.DeepEqual(ran, []string{"c", "e"}) {
+		t.Fatalf("want only the functions not completed before, ran: %v", ran)
+	}
+	if _, err := os.Stat(options.checkpoint); !os.IsNotExist(err) {
+		t.Fatalf("want the checkpoint removed once every function is done, got: %v", err)
+	}
+}
@@ -0,0 +1,267 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// benchPollInterval is how often the replicas of a function are read while
+// waiting for it to scale to zero, it is shortened in tests
+var benchPollInterval = time.Second
+
+var (
+	benchSamples      int
+	benchScaleTimeout time.Duration
+	benchData         string
+)
+
+func init() {
+	benchColdStartCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	benchColdStartCmd.Flags().IntVar(&benchSamples, "samples", 10, "Number of cold starts to measure")
+	benchColdStartCmd.Flags().DurationVar(&benchScaleTimeout, "scale-timeout", 2*time.Minute, "How long to wait for the function to scale to zero before each sample")
+	benchColdStartCmd.Flags().StringVar(&benchData, "data", "", "Body of each invocation")
+	benchColdStartCmd.Flags().StringVar(&contentType, "content-type", "text/plain", "The content-type HTTP header such as application/json")
+
+	benchCmd.AddCommand(benchColdStartCmd)
+	faasCmd.AddCommand(benchCmd)
+}
+
+var benchCmd = &cobra.Command{
+	Use:   `bench`,
+	Short: "Benchmark deployed functions",
+}
+
+var benchColdStartCmd = &cobra.Command{
+	Use:   `cold-start FUNCTION_NAME [--samples SAMPLES] [--gateway GATEWAY_URL]`,
+	Short: "Measure the cold start of a function scaled to zero",
+	Long: `Scales the function to zero replicas, waits until none are left, then
+invokes it through the gateway and measures the time to the first byte of the
+response. This is repeated for each sample and the distribution is reported,
+so that templates and scale to zero settings can be compared.
+
+The gateway must be able to scale functions from zero. The function is scaled
+back to the replicas it had once the benchmark is done.`,
+	Example: `  faas-cli bench cold-start url-ping
+  faas-cli bench cold-start url-ping --samples 20 --data https://example.com`,
+	RunE: runBenchColdStart,
+}
+
+// latencyStats summarises the samples of a benchmark
+type latencyStats struct {
+	Samples int
+	Min     time.Duration
+	Mean    time.Duration
+	StdDev  time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+func runBenchColdStart(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the function to benchmark")
+	}
+	name := args[0]
+	if benchSamples < 1 {
+		return fmt.Errorf("--samples must be at least 1")
+	}
+
+	var yamlGateway string
+	namespace := functionNamespace
+	if len(yamlFile) > 0 {
+		services, err := stack.ParseYAMLFile(yamlFile, "", "")
+		if err != nil {
+			return err
+		}
+		yamlGateway = services.Provider.GatewayURL
+		if function, ok := services.Functions[name]; ok {
+			namespace = namespaceOf(function)
+		}
+	}
+	gatewayAddress := strings.TrimRight(getGatewayURL(gateway, defaultGateway, yamlGateway), "/")
+
+	replicas, err := functionReplicas(gatewayAddress, name, namespace)
+	if err != nil {
+		return err
+	}
+	if replicas > 0 {
+		defer func() {
+			if scaleErr := proxy.ScaleFunctionInNamespace(gatewayAddress, name, namespace, replicas); scaleErr != nil {
+				fmt.Fprintf(os.Stderr, "Unable to scale %s back to %d replicas: %s\n", name, replicas, scaleErr)
+			}
+		}()
+	}
+
+	samples, err := benchColdStarts(os.Stdout, gatewayAddress, name, namespace, benchSamples, []byte(benchData))
+	if len(samples) > 0 {
+		fmt.Println()
+		printLatencyStats(os.Stdout, fmt.Sprintf("Cold start of %s", name), summarizeLatencies(samples))
+	}
+	return err
+}
+
+// benchColdStarts scales the function to zero before each invocation and
+// gives the time to the first byte of each response
+func benchColdStarts(w io.Writer, gatewayAddress string, name string, namespace string, count int, body []byte) ([]time.Duration, error) {
+	var samples []time.Duration
+	for i := 0; i < count; i++ {
+		if err := scaleToZero(gatewayAddress, name, namespace, benchScaleTimeout); err != nil {
+			return samples, err
+		}
+
+		ttfb, status, err := timeToFirstByte(gatewayAddress, qualifiedName(name, namespace), body, contentType)
+		if err != nil {
+			return samples, fmt.Errorf("sample %d: %s", i+1, err)
+		}
+		if status < 200 || status > 299 {
+			return samples, fmt.Errorf("sample %d: %s returned status %d", i+1, name, status)
+		}
+
+		fmt.Fprintf(w, "[%d/%d] %s\n", i+1, count, ttfb.Round(time.Millisecond))
+		samples = append(samples, ttfb)
+	}
+	return samples, nil
+}
+
+func functionReplicas(gatewayAddress string, name string, namespace string) (uint64, error) {
+	functions, err := proxy.ListFunctionsInNamespace(gatewayAddress, namespace)
+	if err != nil {
+		return 0, err
+	}
+	for _, function := range functions {
+		if function.Name == name {
+			return function.Replicas, nil
+		}
+	}
+	return 0, fmt.Errorf("function %s is not deployed to %s", qualifiedName(name, namespace), gatewayAddress)
+}
+
+// scaleToZero scales the function to zero replicas and waits until the
+// provider reports none
+func scaleToZero(gatewayAddress string, name string, namespace string, timeout time.Duration) error {
+	if err := proxy.ScaleFunctionInNamespace(gatewayAddress, name, namespace, 0); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		replicas, err := functionReplicas(gatewayAddress, name, namespace)
+		if err != nil {
+			return err
+		}
+		if replicas == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s still had %d replicas after %s", name, replicas, timeout)
+		}
+		time.Sleep(benchPollInterval)
+	}
+}
+
+// timeToFirstByte invokes the function and measures the time until the
+// first byte of its response arrives
+func timeToFirstByte(gatewayAddress string, name string, body []byte, contentType string) (time.Duration, int, error) {
+	req, err := http.NewRequest(http.MethodPost, gatewayAddress+"/function/"+name, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	proxy.SetAuth(req, gatewayAddress)
+
+	var started, firstByte time.Time
+	trace := &httptrace.ClientTrace{
+		GotFirstResponseByte: func() { firstByte = time.Now() },
+	}
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), trace))
+
+	client := proxy.MakeHTTPClient(nil)
+	started = time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, 0, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gatewayAddress)
+	}
+	io.Copy(ioutil.Discard, res.Body)
+	res.Body.Close()
+
+	if firstByte.IsZero() {
+		firstByte = time.Now()
+	}
+	return firstByte.Sub(started), res.StatusCode, nil
+}
+
+// summarizeLatencies gives the distribution of the samples, percentiles are
+// by nearest rank
+func summarizeLatencies(samples []time.Duration) latencyStats {
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats := latencyStats{Samples: len(sorted)}
+	if len(sorted) == 0 {
+		return stats
+	}
+
+	var total float64
+	for _, sample := range sorted {
+		total += float64(sample)
+	}
+	mean := total / float64(len(sorted))
+
+	var variance float64
+	for _, sample := range sorted {
+		variance += math.Pow(float64(sample)-mean, 2)
+	}
+	variance /= float64(len(sorted))
+
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		if rank < 1 {
+			rank = 1
+		}
+		return sorted[rank-1]
+	}
+
+	stats.Min = sorted[0]
+	stats.Max = sorted[len(sorted)-1]
+	stats.Mean = time.Duration(mean)
+	stats.StdDev = time.Duration(math.Sqrt(variance))
+	stats.P50 = percentile(50)
+	stats.P90 = percentile(90)
+	stats.P99 = percentile(99)
+	return stats
+}
+
+func printLatencyStats(w io.Writer, title string, stats latencyStats) {
+	fmt.Fprintf(w, "%s, %d samples:\n", title, stats.Samples)
+	for _, row := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"min", stats.Min},
+		{"mean", stats.Mean},
+		{"stddev", stats.StdDev},
+		{"p50", stats.P50},
+		{"p90", stats.P90},
+		{"p99", stats.P99},
+		{"max", stats.Max},
+	} {
+		fmt.Fprintf(w, "  %-7s %s\n", row.name, row.value.Round(time.Millisecond))
+	}
+}
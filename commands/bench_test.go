@@ -0,0 +1,128 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_summarizeLatencies(t *testing.T) {
+	var samples []time.Duration
+	for i := 10; i >= 1; i-- {
+		samples = append(samples, time.Duration(i)*100*time.Millisecond)
+	}
+
+	stats := summarizeLatencies(samples)
+	if stats.Samples != 10 || stats.Min != 100*time.Millisecond || stats.Max != time.Second {
+		t.Errorf("got %+v", stats)
+	}
+	if stats.Mean != 550*time.Millisecond || stats.P50 != 500*time.Millisecond || stats.P90 != 900*time.Millisecond || stats.P99 != time.Second {
+		t.Errorf("got %+v", stats)
+	}
+	if stats.StdDev < 287*time.Millisecond || stats.StdDev > 288*time.Millisecond {
+		t.Errorf("want a stddev of ~287ms, got %s", stats.StdDev)
+	}
+	if samples[0] != time.Second {
+		t.Errorf("the samples should not be sorted in place")
+	}
+
+	if empty := summarizeLatencies(nil); empty.Samples != 0 || empty.Max != 0 {
+		t.Errorf("got %+v", empty)
+	}
+}
+
+func Test_runBenchColdStart(t *testing.T) {
+	var lock sync.Mutex
+	replicas := uint64(2)
+	var scaledTo []uint64
+	invocations := 0
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case r.URL.Path == "/system/functions":
+			fmt.Fprintf(w, `[{"name":"figlet","replicas":%d}]`, replicas)
+		case r.URL.Path == "/system/scale-function/figlet":
+			var req struct {
+				Replicas uint64 `json:"replicas"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			replicas = req.Replicas
+			scaledTo = append(scaledTo, replicas)
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/function/figlet":
+			if replicas != 0 {
+				t.Errorf("invoked with %d replicas", replicas)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			invocations++
+			replicas = 1
+			w.Write(body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	gateway = s.URL
+	benchSamples = 3
+	benchScaleTimeout = time.Second
+	benchPollInterval = time.Millisecond
+	benchData = "hi"
+	defer func() {
+		gateway, benchSamples, benchScaleTimeout, benchPollInterval, benchData = defaultGateway, 10, 2*time.Minute, time.Second, ""
+	}()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		err = runBenchColdStart(nil, []string{"figlet"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if invocations != 3 {
+		t.Errorf("want 3 invocations, got %d", invocations)
+	}
+	if fmt.Sprint(scaledTo) != "[0 0 0 2]" {
+		t.Errorf("want three scales to zero and one back to 2, got %v", scaledTo)
+	}
+	for _, want := range []string{"[1/3] ", "[3/3] ", "Cold start of figlet, 3 samples:", "  p90 "} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in output:\n%s", want, stdOut)
+		}
+	}
+}
+
+func Test_runBenchColdStart_NotDeployed(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []map[string]interface{}{},
+		},
+	})
+	defer s.Close()
+
+	gateway = s.URL
+	defer func() { gateway = defaultGateway }()
+
+	err := runBenchColdStart(nil, []string{"figlet"})
+	if err == nil || !strings.Contains(err.Error(), "function figlet is not deployed") {
+		t.Errorf("got error %v", err)
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/morikuni/aec"
 	"github.com/openfaas/faas-cli/builder"
@@ -17,10 +20,23 @@ import (
 
 // Flags that are to be added to commands.
 var (
-	nocache    bool
-	squash     bool
-	parallel   int
-	shrinkwrap bool
+	nocache        bool
+	squash         bool
+	parallel       int
+	shrinkwrap     bool
+	buildArgOpts   []string
+	buildSecrets   []string
+	redactPatterns []string
+	buildInfo      bool
+	explainCacheOf string
+	useBuildKit    bool
+	buildSSH       []string
+	inlineCache    bool
+	cacheFrom      []string
+	platformFlag   string
+
+	// buildPlatforms are read from --platform by preRunBuild
+	buildPlatforms []string
 )
 
 func init() {
@@ -38,6 +54,27 @@ func init() {
 
 	buildCmd.Flags().BoolVar(&shrinkwrap, "shrinkwrap", false, "Just write files to ./build/ folder for shrink-wrapping")
 
+	buildCmd.Flags().StringArrayVar(&buildArgOpts, "build-arg", []string{}, "Add a build-arg for Docker (KEY=VALUE), the value is masked in the build output")
+	buildCmd.Flags().StringArrayVar(&buildSecrets, "build-secret", []string{}, "Mount a BuildKit secret (ID=VALUE) for RUN --mount=type=secret,id=ID, the value is masked in the build output")
+	buildCmd.Flags().BoolVar(&buildInfo, "build-info", false, "Write the git commit, build time, CLI version and image tag as JSON into each build context at the path given by the template's build_info")
+	buildCmd.Flags().StringVar(&explainCacheOf, "explain-cache", "", "Print the inputs hashed for a function's build and what changed since it was last built, without building")
+	buildCmd.Flags().BoolVar(&useBuildKit, "buildkit", false, "Build with BuildKit's buildctl instead of docker build, as does "+buildKitEnv+"=1")
+	buildCmd.Flags().StringArrayVar(&buildSSH, "ssh", []string{}, "Forward an ssh-agent socket or key to RUN --mount=type=ssh (default or ID=PATH), needs --buildkit")
+	buildCmd.Flags().BoolVar(&inlineCache, "inline-cache", false, "Export the build cache inline with the pushed image and import it from the last push, needs --buildkit")
+	buildCmd.Flags().StringArrayVar(&buildOptionNames, "build-option", []string{}, "Add the packages and build-args of this build option of the template or stack to every build, all adds every option")
+	buildCmd.Flags().StringVar(&buildProfileFlag, "build-profile", "", "Add the build-args, secrets and certificates of this profile from build.profiles in the config file to every build, instead of each function's build.profile")
+	buildCmd.Flags().StringArrayVar(&cacheFrom, "cache-from", []string{}, "Use the layers of this image as a build cache, i.e. the image last pushed from CI, as well as each function's build.cache_from")
+	buildCmd.Flags().StringVar(&platformFlag, "platform", "", "Build for these comma separated platforms, i.e. linux/amd64,linux/arm64, overriding each function's platforms. Several platforms are built and pushed as one multi-arch image with buildx unless the builder can do so itself")
+	buildCmd.Flags().StringVar(&dispatchURL, "dispatch", "", "Post each build as a job to the webhook of an external build service, which builds and pushes the image, and wait for it. "+dispatchTokenEnv+" is sent as a bearer token")
+	buildCmd.Flags().StringVar(&remoteBuilderURL, "remote-builder", "", "Send each build context to the builder API at this URL, such as the OpenFaaS Pro builder, which builds and pushes the image and streams its logs back")
+	buildCmd.Flags().StringVar(&payloadSecretFile, "payload-secret", "", "File holding the secret each build context sent to --remote-builder is signed with")
+	buildCmd.Flags().DurationVar(&dispatchTimeout, "dispatch-timeout", 30*time.Minute, "How long a dispatched or --remote-builder build may take")
+	buildCmd.Flags().BoolVar(&changedOnly, "changed-only", false, "Skip functions whose handler, template and build-args are unchanged since their last successful build, which is recorded in the state directory")
+	buildCmd.Flags().DurationVar(&buildDeadline, "deadline", 0, "Warn when the build of the stack is estimated to take longer than this, from the recorded durations of each function's last builds")
+	buildCmd.Flags().StringVar(&buildLogDir, "log-dir", "", "Write the output of each function's build to DIR/FUNCTION.log and print a summary table at the end, for readable --parallel builds")
+	buildCmd.Flags().StringVarP(&buildOutput, "output", "o", buildOutputText, "Output format of the build, json writes each build event to stdout as a line of JSON and the rest to stderr")
+	buildCmd.Flags().StringArrayVar(&redactPatterns, "redact", []string{}, "Mask matches of a regular expression in the build output")
+
 	// Set bash-completion.
 	_ = buildCmd.Flags().SetAnnotation("handler", cobra.BashCompSubdirsInDir, []string{})
 
@@ -54,15 +91,54 @@ var buildCmd = &cobra.Command{
                  [--no-cache] [--squash]
                  [--regex "REGEX"]
 				 [--filter "WILDCARD"]
-				 [--parallel PARALLEL_DEPTH]`,
+				 [--parallel PARALLEL_DEPTH]
+                 [--build-arg KEY=VALUE ...]
+                 [--build-secret ID=VALUE ...]
+                 [--buildkit] [--ssh default|ID=PATH ...] [--inline-cache]
+                 [--cache-from IMAGE ...] [--build-profile NAME]
+                 [--build-option NAME|all ...]
+                 [--platform linux/amd64,linux/arm64]
+                 [--dispatch URL] [--dispatch-timeout DURATION]
+                 [--remote-builder URL] [--payload-secret FILE]
+                 [--log-dir DIR] [--changed-only]
+                 [--explain-cache FUNCTION_NAME]
+                 [--output text|json]`,
 	Short: "Builds OpenFaaS function containers",
 	Long: `Builds OpenFaaS function containers either via the supplied YAML config using
 the "--yaml" flag (which may contain multiple function definitions), or directly
-via flags.`,
+via flags.
+
+With --parallel, functions in a group of the stack's build_groups are built
+in order of the group's priority, highest first, and no more of a group's
+functions are built at once than its concurrency:
+
+  build_groups:
+    critical:
+      priority: 10
+    batch:
+      concurrency: 1
+  functions:
+    api:
+      build:
+        group: critical`,
 	Example: `  faas-cli build -f https://domain/path/myfunctions.yml
   faas-cli build -f ./stack.yml --no-cache
   faas-cli build -f ./stack.yml --filter "*gif*"
   faas-cli build -f ./stack.yml --regex "fn[0-9]_.*"
+  faas-cli build -f ./stack.yml --build-arg NPM_TOKEN=$NPM_TOKEN
+  faas-cli build -f ./stack.yml --build-secret npmrc="$(cat ~/.npmrc)"
+  faas-cli build -f ./stack.yml --build-info
+  faas-cli build -f ./stack.yml --buildkit --ssh default --inline-cache
+  faas-cli build -f ./stack.yml --cache-from alexellis/url-ping:latest
+  faas-cli build -f ./stack.yml --build-profile corp-mirror
+  faas-cli build -f ./stack.yml --build-option dev
+  faas-cli build -f ./stack.yml --platform linux/amd64,linux/arm64
+  faas-cli build -f ./stack.yml --dispatch https://builds.example.com/jobs
+  faas-cli build -f ./stack.yml --remote-builder http://builder:8080 --payload-secret ./payload.txt
+  faas-cli build -f ./stack.yml --parallel 4 --log-dir ./logs
+  faas-cli build -f ./stack.yml --changed-only
+  faas-cli build -f ./stack.yml --explain-cache url-ping
+  faas-cli build -f ./stack.yml --output json > build-events.jsonl
   faas-cli build --image=my_image --lang=python --handler=/path/to/fn/ 
                  --name=my_fn --squash`,
 	PreRunE: preRunBuild,
@@ -73,10 +149,51 @@ via flags.`,
 func preRunBuild(cmd *cobra.Command, args []string) error {
 	language, _ = validateLanguageFlag(language)
 
-	return nil
+	if err := useConfiguredBuilder(useBuildKit); err != nil {
+		return err
+	}
+	if len(dispatchURL) > 0 && len(remoteBuilderURL) > 0 {
+		return fmt.Errorf("give either --dispatch or --remote-builder")
+	}
+	if len(dispatchURL) > 0 && !shrinkwrap {
+		if err := useDispatchBuilder(dispatchURL, dispatchTimeout); err != nil {
+			return err
+		}
+	}
+	if len(remoteBuilderURL) > 0 && !shrinkwrap {
+		if err := useRemoteBuilder(remoteBuilderURL, payloadSecretFile, dispatchTimeout); err != nil {
+			return err
+		}
+	}
+
+	if err := validateBuildSSH(buildSSH); err != nil {
+		return err
+	}
+
+	if err := validateBuildOutput(buildOutput); err != nil {
+		return err
+	}
+
+	var err error
+	if buildPlatforms, err = parsePlatforms(platformFlag); err != nil {
+		return err
+	}
+	if !shrinkwrap {
+		useMultiPlatformBuilder(buildPlatforms)
+	}
+
+	if shrinkwrap || len(explainCacheOf) > 0 {
+		return nil
+	}
+
+	return checkBuildTools()
 }
 
-func runBuild(cmd *cobra.Command, args []string) error {
+func runBuild(cmd *cobra.Command, args []string) (err error) {
+	if buildOutput == buildOutputJSON {
+		finish := startJSONBuildOutput()
+		defer func() { finish(err) }()
+	}
 
 	var services stack.Services
 	if len(yamlFile) > 0 {
@@ -89,13 +206,104 @@ func runBuild(cmd *cobra.Command, args []string) error {
 			services = *parsedServices
 		}
 	}
+	overridePlatforms(&services, buildPlatforms)
+	if err := checkBuildGroups(services); err != nil {
+		return err
+	}
+	if err := checkMatrices(services); err != nil {
+		return err
+	}
 
-	if pullErr := PullTemplates(DefaultTemplateRepository); pullErr != nil {
+	if pullErr := pullStackTemplates(services, stackLanguages(services, language)); pullErr != nil {
 		return fmt.Errorf("could not pull templates for OpenFaaS: %v", pullErr)
 	}
 
+	flagBuildArgs, err := parseMap(buildArgOpts, "build-arg")
+	if err != nil {
+		return fmt.Errorf("error parsing build-args: %v", err)
+	}
+
+	profiles, profilesDir, err := loadBuildProfiles(stackFunctions(services))
+	if len(profilesDir) > 0 {
+		defer os.RemoveAll(profilesDir)
+	}
+	if err != nil {
+		return err
+	}
+	activeBuildProfiles = profiles
+	defer func() { activeBuildProfiles = nil }()
+
+	optionFunctions := stackFunctions(services)
+	if len(services.Functions) == 0 {
+		optionFunctions = []stack.Function{{Name: functionName, Language: language}}
+	}
+	if activeBuildOptions, err = loadBuildOptions(services, optionFunctions, buildOptionNames); err != nil {
+		return err
+	}
+	defer func() { activeBuildOptions = nil }()
+
+	if len(explainCacheOf) > 0 {
+		if len(services.Functions) > 0 {
+			return explainCache(os.Stdout, &services, explainCacheOf, flagBuildArgs)
+		}
+		if explainCacheOf != functionName {
+			return fmt.Errorf("give the function to explain with --name %s, or its YAML file with -f", explainCacheOf)
+		}
+		profileArgs, _ := withBuildProfile(stack.Function{}, withBuildOptions(stack.Function{Name: functionName}, flagBuildArgs), nil)
+		return explainFunctionCache(os.Stdout, handler, functionName, language, profileArgs, "")
+	}
+
+	secretValues, err := parseMap(buildSecrets, "build-secret")
+	if err != nil {
+		return fmt.Errorf("error parsing build-secrets: %v", err)
+	}
+
+	if err := setBuildRedactor(mergeMap(buildProfileValues(profiles), flagBuildArgs), secretValues); err != nil {
+		return err
+	}
+	defer builder.SetRedactor(nil)
+
+	builder.SetBuildInfo(buildInfo)
+	defer builder.SetBuildInfo(false)
+
+	secretFiles, secretsDir, err := writeBuildSecrets(secretValues)
+	if err != nil {
+		return err
+	}
+	if len(secretsDir) > 0 {
+		defer os.RemoveAll(secretsDir)
+	}
+
+	if !shrinkwrap {
+		activeBuildTimes = readBuildTimes(buildTimesPath)
+		defer func() { activeBuildTimes = nil }()
+	}
+
 	if len(services.Functions) > 0 {
-		build(&services, parallel, shrinkwrap)
+		var buildArgMap map[string]string
+		if len(services.BaseImages) > 0 && !shrinkwrap {
+			var baseErr error
+			buildArgMap, baseErr = builder.BuildBaseImages(services.BaseImages, nocache, squash)
+			if baseErr != nil {
+				return baseErr
+			}
+		}
+
+		if len(buildLogDir) > 0 && !shrinkwrap {
+			finish := startBuildLogs(buildLogDir)
+			defer finish()
+		}
+
+		if changedOnly && !shrinkwrap {
+			cache, cacheErr := readBuildCache(buildCachePath)
+			if cacheErr != nil {
+				return cacheErr
+			}
+			activeBuildCache = cache
+			defer func() { activeBuildCache = nil }()
+		}
+
+		build(&services, parallel, shrinkwrap, buildArgMap, flagBuildArgs, secretFiles)
 	} else {
 		if len(image) == 0 {
 			return fmt.Errorf("please provide a valid --image name for your Docker image")
@@ -106,28 +314,113 @@ func runBuild(cmd *cobra.Command, args []string) error {
 		if len(functionName) == 0 {
 			return fmt.Errorf("please provide the deployed --name of your function")
 		}
-		builder.BuildImage(image, handler, functionName, language, nocache, squash, shrinkwrap)
+		if len(language) == 0 {
+			return fmt.Errorf("please provide the --lang of your function")
+		}
+		buildArgs, functionSecrets := withBuildProfile(stack.Function{}, withBuildOptions(stack.Function{Name: functionName}, flagBuildArgs), secretFiles)
+		started := time.Now()
+		builder.BuildImage(builder.BuildOptions{
+			Image:        image,
+			Handler:      handler,
+			FunctionName: functionName,
+			Language:     language,
+			NoCache:      nocache,
+			Squash:       squash,
+			Shrinkwrap:   shrinkwrap,
+			BuildArgs:    buildArgs,
+			Platform:     strings.Join(buildPlatforms, ","),
+			Secrets:      functionSecrets,
+			SSH:          buildSSH,
+			InlineCache:  inlineCache,
+			CacheFrom:    cacheFrom,
+		})
+		observeBuild(functionName, started)
+		if !shrinkwrap {
+			recordBuildTime(functionName, started)
+			recordFingerprint(handler, functionName, language, buildArgs, strings.Join(buildPlatforms, ","))
+		}
 	}
 
 	return nil
 }
 
-func build(services *stack.Services, queueDepth int, shrinkwrap bool) {
-	wg := sync.WaitGroup{}
+// build builds each function in the stack, flagBuildArgs take precedence over
+// the build_args of each function
+func build(services *stack.Services, queueDepth int, shrinkwrap bool, buildArgMap map[string]string, flagBuildArgs map[string]string, secretFiles map[string]string) {
+	var names []string
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var functions []stack.Function
+	for _, k := range names {
+		function := services.Functions[k]
+		if function.SkipBuild {
+			fmt.Printf("Skipping build of: %s.\n", function.Name)
+			if activeBuildSummary != nil {
+				activeBuildSummary.skipped(k, function.Image)
+			}
+			continue
+		}
 
-	workChannel := make(chan stack.Function)
+		function.Name = k
+		matrix, err := expandMatrix(function)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for _, expanded := range matrix {
+			functions = append(functions, platformBuilds(expanded)...)
+		}
+	}
+
+	scheduler := newBuildScheduler(scheduleBuild(functions, queueDepth), services.BuildGroups)
+	wg := sync.WaitGroup{}
 
 	for i := 0; i < queueDepth; i++ {
+		wg.Add(1)
 		go func(index int) {
-			wg.Add(1)
-			for function := range workChannel {
+			for {
+				function, ok := scheduler.next()
+				if !ok {
+					break
+				}
 				fmt.Printf(aec.YellowF.Apply("[%d] > Building %s.\n"), index, function.Name)
+				allBuildArgs, functionSecrets := withBuildProfile(function, withBuildOptions(function, mergeMap(mergeMap(buildArgMap, function.BuildArgs), flagBuildArgs)), secretFiles)
 				if len(function.Language) == 0 {
 					fmt.Println("Please provide a valid language for your function.")
+				} else if unchanged, inputsDigest := unchangedSinceBuild(function, allBuildArgs); unchanged {
+					fmt.Printf("Skipping build of: %s, its inputs are unchanged since it was last built.\n", function.Name)
+					if activeBuildSummary != nil {
+						activeBuildSummary.skipped(function.Name, function.Image)
+					}
 				} else {
-					builder.BuildImage(function.Image, function.Handler, function.Name, function.Language, nocache, squash, shrinkwrap)
+					started := time.Now()
+					builder.BuildImage(builder.BuildOptions{
+						Image:        function.Image,
+						Handler:      function.Handler,
+						FunctionName: function.Name,
+						Language:     function.Language,
+						NoCache:      nocache,
+						Squash:       squash,
+						Shrinkwrap:   shrinkwrap,
+						BuildArgs:    allBuildArgs,
+						Platform:     buildPlatform(function),
+						Secrets:      functionSecrets,
+						SSH:          buildSSH,
+						InlineCache:  inlineCache,
+						CacheFrom:    functionCacheFrom(function, cacheFrom),
+					})
+					observeBuild(function.Name, started)
+					if !shrinkwrap {
+						recordBuildTime(function.Name, started)
+						recordFingerprint(function.Handler, function.Name, function.Language, allBuildArgs, buildPlatform(function))
+						recordBuiltInputs(function, inputsDigest)
+					}
 				}
 				fmt.Printf(aec.YellowF.Apply("[%d] < Building %s done.\n"), index, function.Name)
+				scheduler.done(function)
 			}
 
 			fmt.Printf(aec.YellowF.Apply("[%d] worker done.\n"), index)
@@ -135,27 +428,15 @@ func build(services *stack.Services, queueDepth int, shrinkwrap bool) {
 		}(i)
 	}
 
-	for k, function := range services.Functions {
-		if function.SkipBuild {
-			fmt.Printf("Skipping build of: %s.\n", function.Name)
-		} else {
-			function.Name = k
-			workChannel <- function
-		}
-	}
-
-	close(workChannel)
-
 	wg.Wait()
-
 }
 
 // PullTemplates pulls templates from Github from the master zip download file.
 func PullTemplates(templateURL string) error {
 	var err error
-	exists, err := os.Stat("./template")
+	exists, err := os.Stat(stack.TemplateDirectory)
 	if err != nil || exists == nil {
-		log.Println("No templates found in current directory.")
+		log.Printf("No templates found in %s.\n", stack.TemplateDirectory)
 
 		err = fetchTemplates(templateURL, false)
 		if err != nil {
@@ -165,3 +446,67 @@ func PullTemplates(templateURL string) error {
 	}
 	return err
 }
+
+// pullLanguageTemplates pulls just the templates of the languages given which
+// are not in the template folder yet
+func pullLanguageTemplates(templateURL string, languages []string) error {
+	var missing []string
+	for _, language := range languages {
+		if !stack.IsValidTemplate(language) {
+			missing = append(missing, language)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	log.Printf("Templates %v not found in %s.\n", missing, stack.TemplateDirectory)
+	if err := fetchLanguageTemplates(templateURL, true, missing); err != nil {
+		log.Println("Unable to download templates from Github.")
+		return err
+	}
+	return nil
+}
+
+// stackLanguages lists the languages of the functions which will be built,
+// or the language given when there are no functions
+func stackLanguages(services stack.Services, language string) []string {
+	if len(services.Functions) == 0 {
+		if len(language) == 0 {
+			return nil
+		}
+		return []string{language}
+	}
+
+	var languages []string
+	for _, function := range services.Functions {
+		if function.SkipBuild || len(function.Language) == 0 || contains(languages, function.Language) {
+			continue
+		}
+		languages = append(languages, function.Language)
+	}
+	sort.Strings(languages)
+	return languages
+}
+
+// functionCacheFrom gives the function's build.cache_from followed by the
+// images from --cache-from which it doesn't already list
+func functionCacheFrom(function stack.Function, flagImages []string) []string {
+	var images []string
+	if function.Build != nil {
+		images = append(images, function.Build.CacheFrom...)
+	}
+	for _, image := range flagImages {
+		found := false
+		for _, existing := range images {
+			if existing == image {
+				found = true
+				break
+			}
+		}
+		if !found {
+			images = append(images, image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,89 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// recordFingerprint saves the inputs of a build so that --explain-cache can
+// show what changed since, failing to do so does not fail the build
+func recordFingerprint(handler string, functionName string, language string, buildArgMap map[string]string, platform string) {
+	fingerprint, err := builder.NewFingerprint(handler, functionName, language, buildArgMap, platform)
+	if err == nil {
+		err = builder.SaveFingerprint(fingerprint)
+	}
+	if err != nil {
+		fmt.Printf("Unable to record the build inputs of %s: %s\n", functionName, err)
+	}
+}
+
+// explainCache prints the inputs hashed for each build of the named function
+// and what changed since its last build
+func explainCache(w io.Writer, services *stack.Services, name string, flagBuildArgs map[string]string) error {
+	buildArgMap := builder.BaseImageBuildArgs(services.BaseImages)
+
+	found := false
+	for k, function := range services.Functions {
+		function.Name = k
+		matrix, err := expandMatrix(function)
+		if err != nil {
+			return err
+		}
+		for _, expanded := range matrix {
+			for _, platformFunction := range platformBuilds(expanded) {
+				if k != name && platformFunction.Name != name {
+					continue
+				}
+				found = true
+
+				allBuildArgs, _ := withBuildProfile(platformFunction, withBuildOptions(platformFunction, mergeMap(mergeMap(buildArgMap, platformFunction.BuildArgs), flagBuildArgs)), nil)
+				if err := explainFunctionCache(w, platformFunction.Handler, platformFunction.Name, platformFunction.Language, allBuildArgs, buildPlatform(platformFunction)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("function %s was not found in %s", name, yamlFile)
+	}
+	return nil
+}
+
+func explainFunctionCache(w io.Writer, handler string, functionName string, language string, buildArgMap map[string]string, platform string) error {
+	fingerprint, err := builder.NewFingerprint(handler, functionName, language, buildArgMap, platform)
+	if err != nil {
+		return fmt.Errorf("unable to hash the build inputs of %s: %s", functionName, err)
+	}
+	fingerprint.Write(w)
+
+	previous, err := builder.LastFingerprint(functionName)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case previous == nil:
+		fmt.Fprintf(w, "\n%s has not been built from this folder, so there is nothing to compare with.\n", functionName)
+	case previous.Digest == fingerprint.Digest:
+		fmt.Fprintf(w, "\nThe inputs match the last build of %s, Docker can reuse its cached layers unless the cache was pruned or --no-cache is used.\n", functionName)
+	default:
+		fmt.Fprintf(w, "\nChanged since the last build of %s:\n", functionName)
+		for _, change := range fingerprint.Diff(previous) {
+			fmt.Fprintf(w, "  %s\n", change)
+		}
+		fmt.Fprintln(w, "Layers from the first Dockerfile step which copies a changed file or uses a changed build-arg are rebuilt.")
+	}
+
+	if buildInfo {
+		fmt.Fprintln(w, "Note: --build-info writes the build time into the build context, so layers which copy it are rebuilt every time.")
+	}
+	fmt.Fprintln(w)
+	return nil
+}
@@ -0,0 +1,62 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_explainCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-explain-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(fingerprintDirectory string) { builder.FingerprintDirectory = fingerprintDirectory }(builder.FingerprintDirectory)
+	builder.FingerprintDirectory = filepath.Join(dir, ".fingerprints")
+
+	handler := filepath.Join(dir, "url-ping")
+	os.MkdirAll(handler, 0700)
+	ioutil.WriteFile(filepath.Join(handler, "Dockerfile"), []byte("FROM alpine"), 0600)
+
+	services := &stack.Services{
+		Functions: map[string]stack.Function{
+			"url-ping": {Language: "dockerfile", Handler: handler, Image: "url-ping:0.1"},
+		},
+	}
+
+	var out bytes.Buffer
+	if err := explainCache(&out, services, "url-ping", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Files (1):") || !strings.Contains(out.String(), "has not been built from this folder") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	recordFingerprint(handler, "url-ping", "dockerfile", nil, "")
+	ioutil.WriteFile(filepath.Join(handler, "Dockerfile"), []byte("FROM alpine:3.7"), 0600)
+
+	out.Reset()
+	if err := explainCache(&out, services, "url-ping", map[string]string{"DEBUG": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Changed since the last build of url-ping:", "  build-arg added: DEBUG", "  file changed: Dockerfile"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+
+	if err := explainCache(&out, services, "missing", nil); err == nil {
+		t.Errorf("want an error for an unknown function")
+	}
+}
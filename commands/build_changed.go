@@ -0,0 +1,117 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// buildCachePath keeps the digest of the inputs of each function's last
+// successful build for --changed-only, it is moved into the state directory
+// before a command runs
+var buildCachePath = filepath.Join(".faas-cli", "build-cache.json")
+
+var changedOnly bool
+
+// buildCacheEntry is the last successful build of a function
+type buildCacheEntry struct {
+	Digest string    `json:"digest"`
+	Image  string    `json:"image"`
+	Built  time.Time `json:"built"`
+}
+
+// buildCache is saved after each successful build so that the builds which
+// finished before a failure are kept
+type buildCache struct {
+	Functions map[string]buildCacheEntry `json:"functions"`
+
+	path string
+	lock sync.Mutex
+}
+
+// activeBuildCache is set while building with --changed-only
+var activeBuildCache *buildCache
+
+// readBuildCache reads the cache at path, a missing file gives an empty one
+func readBuildCache(path string) (*buildCache, error) {
+	cache := &buildCache{Functions: map[string]buildCacheEntry{}, path: path}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, cache); err != nil {
+		return nil, fmt.Errorf("unable to parse %s, remove it to rebuild every function: %s", path, err)
+	}
+	if cache.Functions == nil {
+		cache.Functions = map[string]buildCacheEntry{}
+	}
+	return cache, nil
+}
+
+// unchanged is true when the function was last built into the same image
+// from inputs with the same digest
+func (c *buildCache) unchanged(function string, image string, digest string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.Functions[function]
+	return ok && entry.Digest == digest && entry.Image == image
+}
+
+// record saves the digest of a function's successful build
+func (c *buildCache) record(function string, image string, digest string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.Functions[function] = buildCacheEntry{Digest: digest, Image: image, Built: time.Now().UTC()}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.path, data, 0600)
+}
+
+// unchangedSinceBuild is true with --changed-only when the inputs of the
+// function's build match its last successful build. The digest of the inputs
+// is given so that it can be recorded once the function is built.
+func unchangedSinceBuild(function stack.Function, buildArgs map[string]string) (bool, string) {
+	if activeBuildCache == nil {
+		return false, ""
+	}
+
+	fingerprint, err := builder.NewFingerprint(function.Handler, function.Name, function.Language, buildArgs, buildPlatform(function))
+	if err != nil {
+		fmt.Printf("Unable to hash the build inputs of %s, building it: %s\n", function.Name, err)
+		return false, ""
+	}
+	return activeBuildCache.unchanged(function.Name, function.Image, fingerprint.Digest), fingerprint.Digest
+}
+
+// recordBuiltInputs saves the digest of a successful build for
+// --changed-only, failing to do so does not fail the build
+func recordBuiltInputs(function stack.Function, digest string) {
+	if activeBuildCache == nil || len(digest) == 0 {
+		return
+	}
+	if err := activeBuildCache.record(function.Name, function.Image, digest); err != nil {
+		fmt.Printf("Unable to record the build of %s in %s: %s\n", function.Name, buildCachePath, err)
+	}
+}
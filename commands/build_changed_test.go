@@ -0,0 +1,80 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_unchangedSinceBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-changed-only")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	handler := filepath.Join(dir, "url-ping")
+	os.MkdirAll(handler, 0700)
+	ioutil.WriteFile(filepath.Join(handler, "Dockerfile"), []byte("FROM alpine"), 0600)
+	function := stack.Function{Name: "url-ping", Language: "dockerfile", Handler: handler, Image: "url-ping:0.1"}
+
+	if unchanged, digest := unchangedSinceBuild(function, nil); unchanged || len(digest) > 0 {
+		t.Errorf("want nothing hashed without --changed-only")
+	}
+
+	cachePath := filepath.Join(dir, ".faas-cli", "build-cache.json")
+	cache, err := readBuildCache(cachePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeBuildCache = cache
+	defer func() { activeBuildCache = nil }()
+
+	unchanged, digest := unchangedSinceBuild(function, nil)
+	if unchanged || len(digest) == 0 {
+		t.Fatalf("want a function which was never built to be built")
+	}
+	recordBuiltInputs(function, digest)
+
+	// The recorded build is read back as a later run would
+	if activeBuildCache, err = readBuildCache(cachePath); err != nil {
+		t.Fatal(err)
+	}
+	if unchanged, _ := unchangedSinceBuild(function, nil); !unchanged {
+		t.Errorf("want the function skipped when nothing changed")
+	}
+	if unchanged, _ := unchangedSinceBuild(function, map[string]string{"DEBUG": "1"}); unchanged {
+		t.Errorf("want the function built when a build-arg changed")
+	}
+
+	retagged := function
+	retagged.Image = "url-ping:0.2"
+	if unchanged, _ := unchangedSinceBuild(retagged, nil); unchanged {
+		t.Errorf("want the function built when its image changed")
+	}
+
+	ioutil.WriteFile(filepath.Join(handler, "Dockerfile"), []byte("FROM alpine:3.7"), 0600)
+	if unchanged, _ := unchangedSinceBuild(function, nil); unchanged {
+		t.Errorf("want the function built when its handler changed")
+	}
+}
+
+func Test_readBuildCache_Invalid(t *testing.T) {
+	file, err := ioutil.TempFile("", "build-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	file.WriteString("{")
+	file.Close()
+
+	if _, err := readBuildCache(file.Name()); err == nil {
+		t.Errorf("want an error for a corrupt cache")
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+// dispatchTokenEnv is sent as a bearer token to the build service
+const dispatchTokenEnv = "FAAS_DISPATCH_TOKEN"
+
+var (
+	dispatchURL     string
+	dispatchTimeout time.Duration
+
+	remoteBuilderURL  string
+	payloadSecretFile string
+)
+
+// useDispatchBuilder sends the builds to the webhook at address instead of
+// the builder in the config file
+func useDispatchBuilder(address string, timeout time.Duration) error {
+	parsed, err := url.Parse(address)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return fmt.Errorf("--dispatch must be the http(s) URL of a build service, not %q", address)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("--dispatch-timeout must be more than 0")
+	}
+
+	builder.SetBackend(builder.DispatchBuilder{URL: address, Token: os.Getenv(dispatchTokenEnv), Timeout: timeout})
+	return nil
+}
+
+// useRemoteBuilder sends each build context to the builder API at address,
+// signed with the secret in payloadSecretFile when it is given
+func useRemoteBuilder(address string, payloadSecretFile string, timeout time.Duration) error {
+	parsed, err := url.Parse(address)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || len(parsed.Host) == 0 {
+		return fmt.Errorf("--remote-builder must be the http(s) URL of a builder API, not %q", address)
+	}
+	if timeout <= 0 {
+		return fmt.Errorf("--dispatch-timeout must be more than 0")
+	}
+
+	var payloadSecret string
+	if len(payloadSecretFile) > 0 {
+		data, err := ioutil.ReadFile(payloadSecretFile)
+		if err != nil {
+			return fmt.Errorf("unable to read the payload secret: %s", err)
+		}
+		payloadSecret = strings.TrimSpace(string(data))
+	}
+
+	builder.SetBackend(builder.RemoteBuilderAPI{URL: address, PayloadSecret: payloadSecret, Timeout: timeout})
+	return nil
+}
@@ -0,0 +1,220 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// buildTimeSamples is how many of each function's last builds are averaged
+// for its estimate
+const buildTimeSamples = 5
+
+// buildTimesPath keeps how long each function's last builds took, it is moved
+// into the state directory before a command runs
+var buildTimesPath = filepath.Join(".faas-cli", "build-times.json")
+
+var buildDeadline time.Duration
+
+// buildTimes are the durations of each function's last successful builds,
+// in seconds, saved after each build
+type buildTimes struct {
+	Functions map[string][]float64 `json:"functions"`
+
+	path string
+	lock sync.Mutex
+}
+
+// activeBuildTimes is set while building the functions of a stack
+var activeBuildTimes *buildTimes
+
+// readBuildTimes reads the build times at path, a missing file gives none.
+// The times only order and estimate the build, so a file which can't be
+// parsed is started again.
+func readBuildTimes(path string) *buildTimes {
+	times := &buildTimes{Functions: map[string][]float64{}, path: path}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("Unable to read the build times in %s: %s\n", path, err)
+		}
+		return times
+	}
+	if err := json.Unmarshal(data, times); err != nil {
+		fmt.Printf("Unable to parse the build times in %s, they will be recorded again: %s\n", path, err)
+	}
+	if times.Functions == nil {
+		times.Functions = map[string][]float64{}
+	}
+	return times
+}
+
+// estimate is the average of the function's last builds, ok is false when
+// it has not been built
+func (t *buildTimes) estimate(function string) (time.Duration, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	samples := t.Functions[function]
+	if len(samples) == 0 {
+		return 0, false
+	}
+
+	var total float64
+	for _, sample := range samples {
+		total += sample
+	}
+	return time.Duration(total / float64(len(samples)) * float64(time.Second)), true
+}
+
+// record adds the duration of a function's successful build, keeping the
+// last buildTimeSamples of them
+func (t *buildTimes) record(function string, duration time.Duration) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	samples := append(t.Functions[function], duration.Seconds())
+	if len(samples) > buildTimeSamples {
+		samples = samples[len(samples)-buildTimeSamples:]
+	}
+	t.Functions[function] = samples
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.path, data, 0600)
+}
+
+// recordBuildTime saves how long a successful build took, failing to do so
+// does not fail the build
+func recordBuildTime(function string, started time.Time) {
+	if activeBuildTimes == nil {
+		return
+	}
+	if err := activeBuildTimes.record(function, time.Since(started)); err != nil {
+		fmt.Printf("Unable to record the build time of %s in %s: %s\n", function, buildTimesPath, err)
+	}
+}
+
+// buildEstimates gives the estimate of each function, those never built are
+// given the average of the others so that they are neither first nor last.
+// unknown counts them.
+func buildEstimates(times *buildTimes, functions []stack.Function) (map[string]time.Duration, int) {
+	estimates := map[string]time.Duration{}
+	var known time.Duration
+	var missing []string
+	for _, function := range functions {
+		if estimate, ok := times.estimate(function.Name); ok {
+			estimates[function.Name] = estimate
+			known += estimate
+		} else {
+			missing = append(missing, function.Name)
+		}
+	}
+
+	if len(missing) < len(functions) {
+		average := known / time.Duration(len(functions)-len(missing))
+		for _, name := range missing {
+			estimates[name] = average
+		}
+	}
+	return estimates, len(missing)
+}
+
+// longestFirst orders the functions by their estimates, longest first, so
+// that a long build doesn't start last and hold up the end of a parallel
+// build. Functions with the same estimate keep their order.
+func longestFirst(functions []stack.Function, estimates map[string]time.Duration) []stack.Function {
+	ordered := make([]stack.Function, len(functions))
+	copy(ordered, functions)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return estimates[ordered[i].Name] > estimates[ordered[j].Name]
+	})
+	return ordered
+}
+
+// estimateBuild gives how long the functions take when each is built by the
+// first of the workers to become free, in the order given
+func estimateBuild(functions []stack.Function, estimates map[string]time.Duration, workers int) time.Duration {
+	if workers < 1 {
+		workers = 1
+	}
+	free := make([]time.Duration, workers)
+
+	var total time.Duration
+	for _, function := range functions {
+		earliest := 0
+		for i := range free {
+			if free[i] < free[earliest] {
+				earliest = i
+			}
+		}
+		free[earliest] += estimates[function.Name]
+		if free[earliest] > total {
+			total = free[earliest]
+		}
+	}
+	return total
+}
+
+// scheduleBuild orders the functions longest first by their recorded build
+// times, prints the estimated time of the build and warns when it is longer
+// than --deadline
+func scheduleBuild(functions []stack.Function, workers int) []stack.Function {
+	if activeBuildTimes == nil || len(functions) == 0 {
+		return functions
+	}
+
+	estimates, unknown := buildEstimates(activeBuildTimes, functions)
+	if unknown == len(functions) {
+		if buildDeadline > 0 {
+			fmt.Printf("Warning: none of the functions have been built before, so the --deadline of %s can't be checked.\n", buildDeadline)
+		}
+		return functions
+	}
+
+	ordered := longestFirst(functions, estimates)
+	eta := estimateBuild(ordered, estimates, workers)
+
+	message := fmt.Sprintf("Estimated build time of %d function(s): %s", len(functions), eta.Round(time.Second))
+	if unknown > 0 {
+		message += fmt.Sprintf(", %d of them have not been built before", unknown)
+	}
+	fmt.Println(message + ".")
+
+	if buildDeadline > 0 && eta > buildDeadline {
+		fmt.Printf("Warning: the build is estimated to take %s, longer than the --deadline of %s.\n", eta.Round(time.Second), buildDeadline)
+		if longest := estimates[ordered[0].Name]; longest > buildDeadline {
+			fmt.Printf("%s alone takes %s, so more parallelism won't meet the deadline.\n", ordered[0].Name, longest.Round(time.Second))
+		} else if needed := workersForDeadline(ordered, estimates, workers); needed > 0 {
+			fmt.Printf("It may be met with --parallel %d.\n", needed)
+		}
+	}
+	return ordered
+}
+
+// workersForDeadline gives the fewest workers estimated to build the
+// functions within --deadline, or 0 when no more than there are functions do
+func workersForDeadline(functions []stack.Function, estimates map[string]time.Duration, workers int) int {
+	for n := workers + 1; n <= len(functions); n++ {
+		if estimateBuild(functions, estimates, n) <= buildDeadline {
+			return n
+		}
+	}
+	return 0
+}
@@ -0,0 +1,122 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func functionNames(functions []stack.Function) []string {
+	var names []string
+	for _, function := range functions {
+		names = append(names, function.Name)
+	}
+	return names
+}
+
+func Test_buildTimes_RecordAndEstimate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-build-times")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build-times.json")
+	times := readBuildTimes(path)
+	if _, ok := times.estimate("api"); ok {
+		t.Fatal("want no estimate before a build")
+	}
+
+	for i := 1; i <= buildTimeSamples+2; i++ {
+		if err := times.record("api", time.Duration(i)*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the last five builds, of 3s to 7s, are kept
+	reread := readBuildTimes(path)
+	if estimate, ok := reread.estimate("api"); !ok || estimate != 5*time.Second {
+		t.Errorf("want an estimate of 5s, got %s %v", estimate, ok)
+	}
+}
+
+func Test_readBuildTimes_Corrupt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-build-times")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build-times.json")
+	ioutil.WriteFile(path, []byte("{"), 0600)
+
+	var times *buildTimes
+	test.CaptureStdout(func() { times = readBuildTimes(path) })
+	if len(times.Functions) != 0 {
+		t.Errorf("want the build times started again, got %v", times.Functions)
+	}
+}
+
+func Test_scheduleBuild(t *testing.T) {
+	defer func() {
+		activeBuildTimes = nil
+		buildDeadline = 0
+	}()
+
+	activeBuildTimes = &buildTimes{Functions: map[string][]float64{
+		"api":    {60},
+		"worker": {240},
+		"ui":     {120},
+	}}
+	functions := []stack.Function{{Name: "api"}, {Name: "new"}, {Name: "ui"}, {Name: "worker"}}
+
+	buildDeadline = 4 * time.Minute
+	var ordered []stack.Function
+	stdOut := test.CaptureStdout(func() {
+		ordered = scheduleBuild(functions, 2)
+	})
+
+	// new has never been built and is given the average, 140s
+	if got := strings.Join(functionNames(ordered), ","); got != "worker,new,ui,api" {
+		t.Errorf("want the longest builds first, got %s", got)
+	}
+
+	for _, want := range []string{
+		"Estimated build time of 4 function(s): 5m0s, 1 of them have not been built before.",
+		"Warning: the build is estimated to take 5m0s, longer than the --deadline of 4m0s.",
+		"It may be met with --parallel 3.",
+	} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q, got:\n%s", want, stdOut)
+		}
+	}
+
+	buildDeadline = 3 * time.Minute
+	stdOut = test.CaptureStdout(func() {
+		scheduleBuild(functions, 2)
+	})
+	if !strings.Contains(stdOut, "worker alone takes 4m0s") {
+		t.Errorf("want the deadline reported as unreachable, got:\n%s", stdOut)
+	}
+}
+
+func Test_estimateBuild(t *testing.T) {
+	estimates := map[string]time.Duration{"a": 3 * time.Minute, "b": 2 * time.Minute, "c": 2 * time.Minute, "d": time.Minute}
+	functions := []stack.Function{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}}
+
+	cases := map[int]time.Duration{1: 8 * time.Minute, 2: 4 * time.Minute, 4: 3 * time.Minute}
+	for workers, want := range cases {
+		if got := estimateBuild(functions, estimates, workers); got != want {
+			t.Errorf("%d workers: want %s, got %s", workers, want, got)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+var buildLogDir string
+
+// buildSummary follows the build events of each function for the table
+// printed with --log-dir
+type buildSummary struct {
+	lock sync.Mutex
+	rows map[string]*buildSummaryRow
+}
+
+type buildSummaryRow struct {
+	Function string
+	Status   string
+	Duration time.Duration
+	Image    string
+}
+
+func newBuildSummary() *buildSummary {
+	return &buildSummary{rows: map[string]*buildSummaryRow{}}
+}
+
+// record updates the function of a start, complete or error event
+func (s *buildSummary) record(event builder.Event) {
+	status := ""
+	switch event.Type {
+	case builder.EventStart:
+		status = "building"
+	case builder.EventComplete:
+		status = "built"
+	case builder.EventError:
+		status = "failed"
+	default:
+		return
+	}
+	if len(event.Function) == 0 {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	row, ok := s.rows[event.Function]
+	if !ok {
+		row = &buildSummaryRow{Function: event.Function}
+		s.rows[event.Function] = row
+	}
+	row.Status = status
+	row.Image = event.Image
+	row.Duration = time.Duration(event.Duration * float64(time.Second))
+}
+
+// skipped records a function which was not built
+func (s *buildSummary) skipped(function string, image string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.rows[function] = &buildSummaryRow{Function: function, Status: "skipped", Image: image}
+}
+
+// print writes the table of functions sorted by name, followed by where the
+// log of each failed build is
+func (s *buildSummary) print(w io.Writer, logDir string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	names := make([]string, 0, len(s.rows))
+	for name := range s.rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FUNCTION\tSTATUS\tDURATION\tIMAGE")
+	var failed []string
+	for _, name := range names {
+		row := s.rows[name]
+		duration := "-"
+		if row.Duration > 0 {
+			duration = row.Duration.Round(100 * time.Millisecond).String()
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", row.Function, row.Status, duration, row.Image)
+		if row.Status == "failed" {
+			failed = append(failed, name)
+		}
+	}
+	table.Flush()
+
+	for _, name := range failed {
+		fmt.Fprintf(w, "See %s for why %s failed.\n", builder.LogFile(logDir, name), name)
+	}
+}
+
+// activeBuildSummary is set while building with --log-dir
+var activeBuildSummary *buildSummary
+
+// startBuildLogs writes the output of each function's build to its own file
+// in dir. The func returned prints the summary table and stops the logs, the
+// table is also printed when a failed build exits.
+func startBuildLogs(dir string) func() {
+	summary := newBuildSummary()
+	activeBuildSummary = summary
+
+	builder.SetLogDir(dir)
+	builder.SetEventListener(summary.record)
+	builder.SetExitHook(func() {
+		fmt.Fprintln(os.Stdout)
+		summary.print(os.Stdout, dir)
+	})
+
+	return func() {
+		builder.SetExitHook(nil)
+		builder.SetEventListener(nil)
+		builder.SetLogDir("")
+		activeBuildSummary = nil
+
+		fmt.Println()
+		summary.print(os.Stdout, dir)
+	}
+}
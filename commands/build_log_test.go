@@ -0,0 +1,36 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+func Test_buildSummary_print(t *testing.T) {
+	summary := newBuildSummary()
+	summary.record(builder.Event{Type: builder.EventStart, Function: "resize", Image: "resize:0.1"})
+	summary.record(builder.Event{Type: builder.EventProgress, Function: "resize", Message: "Step 1/4 : FROM alpine"})
+	summary.record(builder.Event{Type: builder.EventComplete, Function: "resize", Image: "resize:0.1", Duration: 12.34})
+	summary.record(builder.Event{Type: builder.EventStart, Function: "thumb", Image: "thumb:0.1"})
+	summary.record(builder.Event{Type: builder.EventError, Function: "thumb", Image: "thumb:0.1", Duration: 3})
+	summary.record(builder.Event{Type: builder.EventStart, Function: "crop", Image: "crop:0.1"})
+	summary.skipped("base", "base:0.1")
+
+	var out bytes.Buffer
+	summary.print(&out, "logs")
+
+	want := `FUNCTION  STATUS    DURATION  IMAGE
+base      skipped   -         base:0.1
+crop      building  -         crop:0.1
+resize    built     12.3s     resize:0.1
+thumb     failed    3s        thumb:0.1
+See logs/thumb.log for why thumb failed.
+`
+	if out.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, out.String())
+	}
+}
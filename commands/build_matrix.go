@@ -0,0 +1,91 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+var invalidTagChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// expandMatrix gives one function per combination of the values in the
+// function's matrix, each with its own name, image tag and build-args. A
+// function without a matrix is returned as it is. A key without any values
+// is an error, it would otherwise give no combinations and build nothing.
+func expandMatrix(function stack.Function) ([]stack.Function, error) {
+	if len(function.Matrix) == 0 {
+		return []stack.Function{function}, nil
+	}
+
+	keys := make([]string, 0, len(function.Matrix))
+	for key := range function.Matrix {
+		if len(function.Matrix[key]) == 0 {
+			return nil, fmt.Errorf("function %s: matrix key %s has no values", function.Name, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	combinations := []map[string]string{{}}
+	for _, key := range keys {
+		var next []map[string]string
+		for _, combination := range combinations {
+			for _, value := range function.Matrix[key] {
+				expanded := mergeMap(combination, map[string]string{key: value})
+				next = append(next, expanded)
+			}
+		}
+		combinations = next
+	}
+
+	var functions []stack.Function
+	for _, combination := range combinations {
+		var values []string
+		for _, key := range keys {
+			values = append(values, invalidTagChars.ReplaceAllString(combination[key], "_"))
+		}
+		suffix := strings.Join(values, "-")
+
+		expanded := function
+		expanded.Matrix = nil
+		expanded.Name = function.Name + "-" + suffix
+		expanded.Image = matrixImage(function.Image, suffix)
+		expanded.BuildArgs = mergeMap(function.BuildArgs, combination)
+
+		functions = append(functions, expanded)
+	}
+
+	return functions, nil
+}
+
+// checkMatrices makes sure the matrix of each function in the stack can be
+// expanded before anything is built or pushed
+func checkMatrices(services stack.Services) error {
+	for name, function := range services.Functions {
+		function.Name = name
+		if _, err := expandMatrix(function); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matrixImage appends the suffix to the image's tag, using latest when the
+// image has no tag
+func matrixImage(image string, suffix string) string {
+	tag := "latest"
+
+	lastSlash := strings.LastIndex(image, "/")
+	if lastColon := strings.LastIndex(image, ":"); lastColon > lastSlash {
+		tag = image[lastColon+1:]
+		image = image[:lastColon]
+	}
+
+	return image + ":" + tag + "-" + suffix
+}
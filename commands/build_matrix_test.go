@@ -0,0 +1,91 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_expandMatrix(t *testing.T) {
+	function := stack.Function{
+		Name:      "resizer",
+		Image:     "registry:5000/acme/resizer:0.1",
+		BuildArgs: map[string]string{"ADDITIONAL_PACKAGE": "imagemagick"},
+		Matrix: map[string][]string{
+			"PYTHON_VERSION": {"3.10", "3.11"},
+			"DISTRO":         {"alpine"},
+		},
+	}
+
+	functions, err := expandMatrix(function)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(functions) != 2 {
+		t.Fatalf("want 2 combinations, got %d", len(functions))
+	}
+
+	expected := stack.Function{
+		Name:  "resizer-alpine-3.10",
+		Image: "registry:5000/acme/resizer:0.1-alpine-3.10",
+		BuildArgs: map[string]string{
+			"ADDITIONAL_PACKAGE": "imagemagick",
+			"PYTHON_VERSION":     "3.10",
+			"DISTRO":             "alpine",
+		},
+	}
+	if !reflect.DeepEqual(functions[0], expected) {
+		t.Errorf("want: %+v, got: %+v", expected, functions[0])
+	}
+
+	if functions[1].Image != "registry:5000/acme/resizer:0.1-alpine-3.11" {
+		t.Errorf("unexpected image for the second combination: %s", functions[1].Image)
+	}
+}
+
+func Test_expandMatrix_NoMatrix(t *testing.T) {
+	function := stack.Function{Name: "resizer", Image: "acme/resizer"}
+
+	functions, err := expandMatrix(function)
+	if err != nil || len(functions) != 1 || !reflect.DeepEqual(functions[0], function) {
+		t.Fatalf("want the function unchanged, got: %+v", functions)
+	}
+}
+
+func Test_expandMatrix_EmptyValues(t *testing.T) {
+	function := stack.Function{
+		Name:   "resizer",
+		Image:  "acme/resizer",
+		Matrix: map[string][]string{"PYTHON_VERSION": {"3.10"}, "DISTRO": {}},
+	}
+
+	_, err := expandMatrix(function)
+	want := "function resizer: matrix key DISTRO has no values"
+	if err == nil || err.Error() != want {
+		t.Fatalf("want %q, got %v", want, err)
+	}
+
+	services := stack.Services{Functions: map[string]stack.Function{"resizer": function}}
+	if err := checkMatrices(services); err == nil || err.Error() != want {
+		t.Fatalf("want %q from the stack check, got %v", want, err)
+	}
+}
+
+func Test_matrixImage(t *testing.T) {
+	testCases := map[string]string{
+		"acme/resizer":                 "acme/resizer:latest-py3",
+		"acme/resizer:0.1":             "acme/resizer:0.1-py3",
+		"registry:5000/acme/resizer":   "registry:5000/acme/resizer:latest-py3",
+		"registry:5000/acme/resizer:2": "registry:5000/acme/resizer:2-py3",
+	}
+
+	for image, expected := range testCases {
+		if actual := matrixImage(image, "py3"); actual != expected {
+			t.Errorf("image %s, want: %s, got: %s", image, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// buildOptionNames are added to the build_options of every function
+var buildOptionNames []string
+
+// activeBuildOptions are the build-args of the build options and the fprocess
+// of each function, set while building or publishing a stack
+var activeBuildOptions map[string]map[string]string
+
+// loadBuildOptions resolves the build options of each function, with those
+// given by --build-option, into the build-args they add. The function's own
+// fprocess is added in the template's fprocess build-arg
+func loadBuildOptions(services stack.Services, functions []stack.Function, flagOptions []string) (map[string]map[string]string, error) {
+	resolved := map[string]map[string]string{}
+	for _, function := range functions {
+		if function.SkipBuild {
+			continue
+		}
+
+		fprocessArgs, err := fprocessBuildArgs(function)
+		if err != nil {
+			return nil, err
+		}
+		if len(fprocessArgs) > 0 {
+			resolved[function.Name] = fprocessArgs
+		}
+
+		names := append(append([]string{}, function.BuildOptions...), flagOptions...)
+		if len(names) == 0 {
+			continue
+		}
+
+		templateOptions, err := templateBuildOptions(function.Language)
+		if err != nil {
+			return nil, err
+		}
+		packages, buildArgs, err := stack.ResolveBuildOptions(templateOptions, services.BuildOptions, names)
+		if err != nil {
+			return nil, fmt.Errorf("unable to use the build options of %s: %s", function.Name, err)
+		}
+		if len(packages) > 0 {
+			buildArgs[stack.AdditionalPackageBuildArg] = strings.Join(packages, " ")
+		}
+		resolved[function.Name] = mergeMap(fprocessArgs, buildArgs)
+	}
+	return resolved, nil
+}
+
+// templateBuildOptions reads the build options of a language template, a
+// function built from its own Dockerfile has none
+func templateBuildOptions(language string) ([]stack.BuildOption, error) {
+	templateYAML := filepath.Join(stack.TemplateDirectory, language, "template.yml")
+	if _, err := os.Stat(templateYAML); os.IsNotExist(err) {
+		return nil, nil
+	}
+	langTemplate, err := stack.ParseYAMLForLanguageTemplate(templateYAML)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the build options of template %s: %s", language, err)
+	}
+	return langTemplate.BuildOptions, nil
+}
+
+// withBuildOptions adds the build-args of the function's build options, a
+// build-arg given for the function wins except for ADDITIONAL_PACKAGE, whose
+// packages are added to those of the options
+func withBuildOptions(function stack.Function, buildArgs map[string]string) map[string]string {
+	optionArgs, ok := activeBuildOptions[function.Name]
+	if !ok {
+		return buildArgs
+	}
+
+	merged := mergeMap(optionArgs, buildArgs)
+	packages, fromOptions := optionArgs[stack.AdditionalPackageBuildArg]
+	if existing := buildArgs[stack.AdditionalPackageBuildArg]; fromOptions && len(existing) > 0 {
+		merged[stack.AdditionalPackageBuildArg] = packages + " " + existing
+	}
+	return merged
+}
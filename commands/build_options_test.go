@@ -0,0 +1,63 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_loadBuildOptions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-build-options")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(templateDirectory string) { stack.TemplateDirectory = templateDirectory }(stack.TemplateDirectory)
+	stack.TemplateDirectory = dir
+	os.MkdirAll(filepath.Join(dir, "python3"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "python3", "template.yml"), []byte(`language: python3
+build_options:
+  - name: dev
+    packages: [make, gcc]
+`), 0600)
+
+	services := stack.Services{
+		BuildOptions: []stack.BuildOption{
+			{Name: "dev", Packages: []string{"musl-dev"}},
+			{Name: "pg", Packages: []string{"postgresql-dev"}, BuildArgs: map[string]string{"PG": "11"}},
+		},
+	}
+	functions := []stack.Function{
+		{Name: "api", Language: "python3", BuildOptions: []string{"pg"}},
+		{Name: "web", Language: "dockerfile"},
+	}
+
+	resolved, err := loadBuildOptions(services, functions, []string{"dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resolved["api"][stack.AdditionalPackageBuildArg]; got != "postgresql-dev make gcc musl-dev" {
+		t.Errorf("want the packages of pg and dev, got %q", got)
+	}
+	if got := resolved["web"][stack.AdditionalPackageBuildArg]; got != "musl-dev" {
+		t.Errorf("want only the stack's dev option without a template, got %q", got)
+	}
+
+	defer func() { activeBuildOptions = nil }()
+	activeBuildOptions = resolved
+	args := withBuildOptions(functions[0], map[string]string{"PG": "12", stack.AdditionalPackageBuildArg: "curl"})
+	if args["PG"] != "12" || args[stack.AdditionalPackageBuildArg] != "postgresql-dev make gcc musl-dev curl" {
+		t.Errorf("want the function's build-args to win and packages added, got %v", args)
+	}
+
+	if _, err := loadBuildOptions(services, functions, []string{"java"}); err == nil {
+		t.Errorf("want an error for an unknown option")
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+// Values of build --output
+const (
+	buildOutputText = "text"
+	buildOutputJSON = "json"
+)
+
+var buildOutput string
+
+func validateBuildOutput(output string) error {
+	if output != buildOutputText && output != buildOutputJSON {
+		return fmt.Errorf("--output must be %s or %s, not %q", buildOutputText, buildOutputJSON, output)
+	}
+	return nil
+}
+
+// startJSONBuildOutput writes the build events to stdout as JSON lines and
+// moves everything else printed to stderr so that stdout can be parsed. The
+// func returned puts stdout back and emits err, when set, as an error event.
+func startJSONBuildOutput() func(err error) {
+	stdout := os.Stdout
+	builder.SetEventWriter(stdout)
+	os.Stdout = os.Stderr
+
+	return func(err error) {
+		if err != nil {
+			builder.EmitEvent(builder.Event{Type: builder.EventError, Message: err.Error()})
+		}
+		builder.SetEventWriter(nil)
+		os.Stdout = stdout
+	}
+}
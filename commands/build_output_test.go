@@ -0,0 +1,65 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+func Test_validateBuildOutput(t *testing.T) {
+	for _, output := range []string{"text", "json"} {
+		if err := validateBuildOutput(output); err != nil {
+			t.Errorf("want %s accepted, got %s", output, err)
+		}
+	}
+	if err := validateBuildOutput("yaml"); err == nil {
+		t.Errorf("want yaml rejected")
+	}
+}
+
+func Test_startJSONBuildOutput(t *testing.T) {
+	out, err := ioutil.TempFile("", "build-output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(out.Name())
+
+	stdout := os.Stdout
+	os.Stdout = out
+	defer func() { os.Stdout = stdout }()
+
+	finish := startJSONBuildOutput()
+	if os.Stdout != os.Stderr {
+		t.Errorf("want stdout moved to stderr while building")
+	}
+	builder.EmitEvent(builder.Event{Type: builder.EventStart, Function: "fn"})
+	finish(fmt.Errorf("could not pull templates"))
+
+	if os.Stdout != out {
+		t.Errorf("want stdout put back")
+	}
+
+	data, err := ioutil.ReadFile(out.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("want 2 events, got %q", lines)
+	}
+	var last builder.Event
+	if err := json.Unmarshal([]byte(lines[1]), &last); err != nil {
+		t.Fatal(err)
+	}
+	if last.Type != builder.EventError || last.Message != "could not pull templates" {
+		t.Errorf("want the error as an event, got %+v", last)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// validPlatform is os/arch with an optional variant, i.e. linux/arm/v7
+var validPlatform = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// platformTemplateSuffixes maps an architecture onto the suffix of the
+// templates written for it, such as node-armhf
+var platformTemplateSuffixes = map[string]string{
+	"arm":   "-armhf",
+	"arm64": "-arm64",
+}
+
+// expandPlatforms gives one function per platform, each building an image
+// tagged for its platform. A function without platforms is returned as it is.
+func expandPlatforms(function stack.Function) []stack.Function {
+	if len(function.Platforms) == 0 {
+		return []stack.Function{function}
+	}
+
+	var functions []stack.Function
+	for _, platform := range function.Platforms {
+		suffix := platformSuffix(platform)
+
+		expanded := function
+		expanded.Platforms = []string{platform}
+		expanded.Name = function.Name + "-" + suffix
+		expanded.Image = matrixImage(function.Image, suffix)
+		expanded.Language = platformLanguage(function.Language, platform)
+
+		functions = append(functions, expanded)
+	}
+
+	return functions
+}
+
+// platformBuilds gives the builds of a function: one for all of its platforms
+// when the builder makes multi-arch images, otherwise one per platform
+func platformBuilds(function stack.Function) []stack.Function {
+	if len(function.Platforms) > 1 && builder.Backend().Capabilities().MultiPlatform {
+		return []stack.Function{function}
+	}
+	return expandPlatforms(function)
+}
+
+// buildPlatform is the platform of a function given by platformBuilds, the
+// platforms of a multi-arch build are separated by commas
+func buildPlatform(function stack.Function) string {
+	return strings.Join(function.Platforms, ",")
+}
+
+// parsePlatforms reads the comma separated platforms given with --platform
+func parsePlatforms(value string) ([]string, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	var platforms []string
+	for _, platform := range strings.Split(value, ",") {
+		platform = strings.TrimSpace(platform)
+		if !validPlatform.MatchString(platform) {
+			return nil, fmt.Errorf("--platform %q must be os/arch[/variant], i.e. linux/arm64", platform)
+		}
+		platforms = append(platforms, platform)
+	}
+	return platforms, nil
+}
+
+// useMultiPlatformBuilder switches to buildx when several platforms are given
+// and the builder can't build them into one image
+func useMultiPlatformBuilder(platforms []string) {
+	if len(platforms) > 1 && !builder.Backend().Capabilities().MultiPlatform {
+		fmt.Printf("The %s builder can't build several platforms into one image, using buildx.\n", builder.Backend().Name())
+		builder.SetBackend(builder.BuildxBuilder{})
+	}
+}
+
+// overridePlatforms sets the platforms of every function in the stack
+func overridePlatforms(services *stack.Services, platforms []string) {
+	if len(platforms) == 0 {
+		return
+	}
+	for name, function := range services.Functions {
+		function.Platforms = platforms
+		services.Functions[name] = function
+	}
+}
+
+// platformSuffix turns linux/arm/v7 into linux-arm-v7 for use in an image tag
+func platformSuffix(platform string) string {
+	return strings.Replace(platform, "/", "-", -1)
+}
+
+// splitPlatform splits os/arch/variant, variant is optional
+func splitPlatform(platform string) (string, string, string) {
+	parts := strings.SplitN(platform, "/", 3)
+	for len(parts) < 3 {
+		parts = append(parts, "")
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+// platformLanguage picks the architecture's own template, i.e. node-armhf for
+// node on linux/arm/v7, when one has been pulled
+func platformLanguage(language string, platform string) string {
+	_, arch, _ := splitPlatform(platform)
+
+	suffix, ok := platformTemplateSuffixes[arch]
+	if !ok || len(language) == 0 || strings.ToLower(language) == "dockerfile" || strings.HasSuffix(language, suffix) {
+		return language
+	}
+
+	if _, err := os.Stat(filepath.Join(stack.TemplateDirectory, language+suffix)); err == nil {
+		return language + suffix
+	}
+	return language
+}
@@ -0,0 +1,144 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_expandPlatforms(t *testing.T) {
+	function := stack.Function{
+		Name:      "url-ping",
+		Image:     "alexellis/faas-url-ping:0.2",
+		Language:  "dockerfile",
+		Platforms: []string{"linux/amd64", "linux/arm/v7"},
+	}
+
+	functions := expandPlatforms(function)
+	if len(functions) != 2 {
+		t.Fatalf("want 2 platforms, got %d", len(functions))
+	}
+
+	expected := stack.Function{
+		Name:      "url-ping-linux-arm-v7",
+		Image:     "alexellis/faas-url-ping:0.2-linux-arm-v7",
+		Language:  "dockerfile",
+		Platforms: []string{"linux/arm/v7"},
+	}
+	if !reflect.DeepEqual(functions[1], expected) {
+		t.Errorf("want: %+v, got: %+v", expected, functions[1])
+	}
+	if buildPlatform(functions[1]) != "linux/arm/v7" {
+		t.Errorf("want build platform linux/arm/v7, got %q", buildPlatform(functions[1]))
+	}
+
+	unchanged := stack.Function{Name: "url-ping", Image: "alexellis/faas-url-ping"}
+	if functions := expandPlatforms(unchanged); len(functions) != 1 || buildPlatform(functions[0]) != "" {
+		t.Errorf("want a function without platforms unchanged, got %+v", functions)
+	}
+}
+
+func Test_platformBuilds(t *testing.T) {
+	defer builder.SetBackend(nil)
+
+	function := stack.Function{
+		Name:      "url-ping",
+		Image:     "alexellis/faas-url-ping:0.2",
+		Platforms: []string{"linux/amd64", "linux/arm64"},
+	}
+
+	if builds := platformBuilds(function); len(builds) != 2 {
+		t.Errorf("want a build per platform with docker, got %d", len(builds))
+	}
+
+	builder.SetBackend(builder.BuildxBuilder{})
+	builds := platformBuilds(function)
+	if len(builds) != 1 || builds[0].Image != function.Image {
+		t.Fatalf("want one multi-arch build of %s with buildx, got %+v", function.Image, builds)
+	}
+	if got := buildPlatform(builds[0]); got != "linux/amd64,linux/arm64" {
+		t.Errorf("want both platforms built together, got %q", got)
+	}
+}
+
+func Test_parsePlatforms(t *testing.T) {
+	platforms, err := parsePlatforms("linux/amd64, linux/arm/v7")
+	if err != nil || !reflect.DeepEqual(platforms, []string{"linux/amd64", "linux/arm/v7"}) {
+		t.Errorf("want both platforms, got %v %v", platforms, err)
+	}
+
+	if platforms, err := parsePlatforms(""); err != nil || platforms != nil {
+		t.Errorf("want no platforms, got %v %v", platforms, err)
+	}
+
+	if _, err := parsePlatforms("linux/amd64,arm64"); err == nil || !strings.Contains(err.Error(), `"arm64"`) {
+		t.Errorf("want an error naming the invalid platform, got %v", err)
+	}
+
+	services := stack.Services{Functions: map[string]stack.Function{
+		"api":  {Platforms: []string{"linux/amd64"}},
+		"cron": {},
+	}}
+	overridePlatforms(&services, []string{"linux/arm64"})
+	for name, function := range services.Functions {
+		if !reflect.DeepEqual(function.Platforms, []string{"linux/arm64"}) {
+			t.Errorf("want --platform to override the platforms of %s, got %v", name, function.Platforms)
+		}
+	}
+}
+
+func Test_platformLanguage(t *testing.T) {
+	templateDir, err := ioutil.TempDir("", "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(templateDir)
+
+	os.MkdirAll(filepath.Join(templateDir, "node"), 0700)
+	os.MkdirAll(filepath.Join(templateDir, "node-armhf"), 0700)
+
+	defer func(original string) { stack.TemplateDirectory = original }(stack.TemplateDirectory)
+	stack.TemplateDirectory = templateDir
+
+	testCases := []struct {
+		language string
+		platform string
+		want     string
+	}{
+		{"node", "linux/arm/v7", "node-armhf"},
+		{"node", "linux/amd64", "node"},
+		{"node", "linux/arm64", "node"},
+		{"node-armhf", "linux/arm/v6", "node-armhf"},
+		{"python", "linux/arm/v7", "python"},
+		{"dockerfile", "linux/arm/v7", "dockerfile"},
+	}
+
+	for _, testCase := range testCases {
+		if got := platformLanguage(testCase.language, testCase.platform); got != testCase.want {
+			t.Errorf("%s on %s: want %s, got %s", testCase.language, testCase.platform, testCase.want, got)
+		}
+	}
+}
+
+func Test_manifestAnnotateCommand(t *testing.T) {
+	got := manifestAnnotateCommand("acme/fn:0.1", "acme/fn:0.1-linux-arm-v7", "linux/arm/v7")
+	want := []string{"docker", "manifest", "annotate", "--os", "linux", "--arch", "arm", "--variant", "v7", "acme/fn:0.1", "acme/fn:0.1-linux-arm-v7"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+
+	got = manifestAnnotateCommand("acme/fn:0.1", "acme/fn:0.1-linux-amd64", "linux/amd64")
+	want = []string{"docker", "manifest", "annotate", "--os", "linux", "--arch", "amd64", "acme/fn:0.1", "acme/fn:0.1-linux-amd64"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
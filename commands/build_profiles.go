@@ -0,0 +1,195 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// caCertificatesSecret is the id of the secret which holds the certificates
+// of a build profile
+const caCertificatesSecret = "ca-certificates"
+
+var buildProfileFlag string
+
+// buildProfile is a build profile from the config file which is ready to be
+// given to a build
+type buildProfile struct {
+	BuildArgs map[string]string
+
+	// Secrets maps the id of each secret to its file
+	Secrets map[string]string
+}
+
+// activeBuildProfiles are the profiles used by the functions being built
+var activeBuildProfiles map[string]buildProfile
+
+// functionBuildProfile is --build-profile, or else the function's
+// build.profile
+func functionBuildProfile(function stack.Function) string {
+	if len(buildProfileFlag) > 0 {
+		return buildProfileFlag
+	}
+	if function.Build != nil {
+		return function.Build.Profile
+	}
+	return ""
+}
+
+// loadBuildProfiles reads the profiles used by the functions from the config
+// file. The certificates of each profile are joined into one file in a new
+// folder which the caller removes once the build is done.
+func loadBuildProfiles(functions []stack.Function) (map[string]buildProfile, string, error) {
+	used := map[string]bool{}
+	for _, function := range functions {
+		if name := functionBuildProfile(function); len(name) > 0 {
+			used[name] = true
+		}
+	}
+	if len(buildProfileFlag) > 0 {
+		used[buildProfileFlag] = true
+	}
+	if len(used) == 0 {
+		return nil, "", nil
+	}
+
+	cfg, err := config.ReadConfigFile()
+	if err != nil {
+		return nil, "", err
+	}
+	var configured map[string]config.BuildProfile
+	if cfg.Build != nil {
+		configured = cfg.Build.Profiles
+	}
+
+	profiles := map[string]buildProfile{}
+	dir := ""
+	for name := range used {
+		profile, ok := configured[name]
+		if !ok {
+			return nil, dir, fmt.Errorf("build profile %s is not in the config file, %s", name, availableBuildProfiles(configured))
+		}
+
+		prepared := buildProfile{BuildArgs: map[string]string{}, Secrets: map[string]string{}}
+		for arg, value := range profile.BuildArgs {
+			prepared.BuildArgs[arg] = os.ExpandEnv(value)
+		}
+		for id, file := range profile.Secrets {
+			if !validSecretID.MatchString(id) {
+				return nil, dir, fmt.Errorf("build profile %s: secret id %q may only contain letters, numbers, '.', '_' and '-'", name, id)
+			}
+			path, err := existingFile(file)
+			if err != nil {
+				return nil, dir, fmt.Errorf("build profile %s: secret %s: %s", name, id, err)
+			}
+			prepared.Secrets[id] = path
+		}
+
+		if len(profile.Certs) > 0 {
+			if len(dir) == 0 {
+				if dir, err = ioutil.TempDir("", "faas-cli-build-profiles"); err != nil {
+					return nil, "", err
+				}
+			}
+			bundle, err := writeCertBundle(filepath.Join(dir, name+".pem"), profile.Certs)
+			if err != nil {
+				return nil, dir, fmt.Errorf("build profile %s: %s", name, err)
+			}
+			prepared.Secrets[caCertificatesSecret] = bundle
+		}
+
+		if len(prepared.Secrets) > 0 {
+			os.Setenv("DOCKER_BUILDKIT", "1")
+		}
+		profiles[name] = prepared
+	}
+	return profiles, dir, nil
+}
+
+func availableBuildProfiles(configured map[string]config.BuildProfile) string {
+	if len(configured) == 0 {
+		return "add it under build.profiles"
+	}
+	names := make([]string, 0, len(configured))
+	for name := range configured {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return "choose from: " + strings.Join(names, ", ")
+}
+
+func existingFile(file string) (string, error) {
+	path, err := homedir.Expand(file)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writeCertBundle joins the PEM files into one file at path
+func writeCertBundle(path string, certs []string) (string, error) {
+	var bundle []byte
+	for _, cert := range certs {
+		certPath, err := existingFile(cert)
+		if err != nil {
+			return "", err
+		}
+		data, err := ioutil.ReadFile(certPath)
+		if err != nil {
+			return "", err
+		}
+		if !strings.Contains(string(data), "-----BEGIN CERTIFICATE-----") {
+			return "", fmt.Errorf("%s is not a PEM certificate", cert)
+		}
+		bundle = append(bundle, data...)
+		if len(data) > 0 && data[len(data)-1] != '\n' {
+			bundle = append(bundle, '\n')
+		}
+	}
+	return path, ioutil.WriteFile(path, bundle, 0600)
+}
+
+// withBuildProfile adds the build-args and secrets of the function's profile
+// under its own, so that build_args and the flags take precedence
+func withBuildProfile(function stack.Function, buildArgs map[string]string, secrets map[string]string) (map[string]string, map[string]string) {
+	profile, ok := activeBuildProfiles[functionBuildProfile(function)]
+	if !ok {
+		return buildArgs, secrets
+	}
+	return mergeMap(profile.BuildArgs, buildArgs), mergeMap(profile.Secrets, secrets)
+}
+
+// buildProfileValues are the build-arg values of the profiles, which are
+// masked in the build output as they may hold credentials
+func buildProfileValues(profiles map[string]buildProfile) map[string]string {
+	values := map[string]string{}
+	for name, profile := range profiles {
+		for arg, value := range profile.BuildArgs {
+			values[name+"/"+arg] = value
+		}
+	}
+	return values
+}
+
+// stackFunctions lists the functions of a stack with their names set
+func stackFunctions(services stack.Services) []stack.Function {
+	functions := make([]stack.Function, 0, len(services.Functions))
+	for name, function := range services.Functions {
+		function.Name = name
+		functions = append(functions, function)
+	}
+	return functions
+}
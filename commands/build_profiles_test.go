@@ -0,0 +1,107 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+const testCert = "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----"
+
+func writeBuildProfilesConfig(t *testing.T, dir string) {
+	ioutil.WriteFile(filepath.Join(dir, "npmrc"), []byte("registry=https://npm.corp/"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "corp.pem"), []byte(testCert), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "root.pem"), []byte(testCert+"\n"), 0600)
+
+	configYAML := `build:
+  profiles:
+    corp-mirror:
+      build_args:
+        PIP_INDEX_URL: https://${MIRROR_TOKEN}@pypi.corp/simple
+        NPM_REGISTRY: https://npm.corp/
+      secrets:
+        npmrc: ` + filepath.Join(dir, "npmrc") + `
+      certs:
+        - ` + filepath.Join(dir, "corp.pem") + `
+        - ` + filepath.Join(dir, "root.pem") + `
+    public: {}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, config.DefaultFile), []byte(configYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_loadBuildProfiles(t *testing.T) {
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-build-profiles")
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+		os.Unsetenv("MIRROR_TOKEN")
+	}()
+	writeBuildProfilesConfig(t, config.DefaultDir)
+	os.Setenv("MIRROR_TOKEN", "s3cret")
+
+	functions := []stack.Function{
+		{Name: "api", Build: &stack.FunctionBuild{Profile: "corp-mirror"}, BuildArgs: map[string]string{"NPM_REGISTRY": "https://npm.team/"}},
+		{Name: "static"},
+	}
+	profiles, dir, err := loadBuildProfiles(functions)
+	if len(dir) > 0 {
+		defer os.RemoveAll(dir)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	activeBuildProfiles = profiles
+	defer func() { activeBuildProfiles = nil }()
+
+	buildArgs, secrets := withBuildProfile(functions[0], functions[0].BuildArgs, map[string]string{"token": "/tmp/token"})
+	wantArgs := map[string]string{"PIP_INDEX_URL": "https://s3cret@pypi.corp/simple", "NPM_REGISTRY": "https://npm.team/"}
+	if !reflect.DeepEqual(buildArgs, wantArgs) {
+		t.Errorf("want the function's build_args over the profile's, got %v", buildArgs)
+	}
+	if secrets["npmrc"] != filepath.Join(config.DefaultDir, "npmrc") || secrets["token"] != "/tmp/token" {
+		t.Errorf("want the profile's secrets alongside the others, got %v", secrets)
+	}
+
+	bundle, err := ioutil.ReadFile(secrets[caCertificatesSecret])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(bundle), "BEGIN CERTIFICATE") != 2 {
+		t.Errorf("want both certificates in the bundle, got:\n%s", bundle)
+	}
+
+	if buildArgs, _ := withBuildProfile(functions[1], nil, nil); len(buildArgs) != 0 {
+		t.Errorf("want nothing added to a function without a profile, got %v", buildArgs)
+	}
+
+	buildProfileFlag = "missing"
+	defer func() { buildProfileFlag = "" }()
+	if _, _, err := loadBuildProfiles(functions); err == nil || !strings.Contains(err.Error(), "choose from: corp-mirror, public") {
+		t.Errorf("want an unknown profile rejected with the available ones, got %v", err)
+	}
+}
+
+func Test_functionBuildProfile(t *testing.T) {
+	function := stack.Function{Build: &stack.FunctionBuild{Profile: "corp-mirror"}}
+	if got := functionBuildProfile(function); got != "corp-mirror" {
+		t.Errorf("want the function's profile, got %q", got)
+	}
+
+	buildProfileFlag = "public"
+	defer func() { buildProfileFlag = "" }()
+	if got := functionBuildProfile(function); got != "public" {
+		t.Errorf("want --build-profile to take precedence, got %q", got)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// buildScheduler hands the functions of a stack to the build workers, those
+// of higher priority groups first, and holds a function back while its group
+// is building as many functions as its concurrency allows
+type buildScheduler struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	pending []stack.Function
+	groups  map[string]stack.BuildGroup
+	running map[string]int
+}
+
+func newBuildScheduler(functions []stack.Function, groups map[string]stack.BuildGroup) *buildScheduler {
+	pending := make([]stack.Function, len(functions))
+	copy(pending, functions)
+
+	// The order within a group is kept, so functions can be given sorted
+	sort.SliceStable(pending, func(i, j int) bool {
+		return groups[buildGroup(pending[i])].Priority > groups[buildGroup(pending[j])].Priority
+	})
+
+	scheduler := &buildScheduler{
+		pending: pending,
+		groups:  groups,
+		running: map[string]int{},
+	}
+	scheduler.cond = sync.NewCond(&scheduler.mu)
+	return scheduler
+}
+
+// next waits for a function which can be built, ok is false once every
+// function has been handed out
+func (s *buildScheduler) next() (stack.Function, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.pending) > 0 {
+		for i, function := range s.pending {
+			group := buildGroup(function)
+			if limit := s.groups[group].Concurrency; limit > 0 && s.running[group] >= limit {
+				continue
+			}
+
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			s.running[group]++
+			return function, true
+		}
+		s.cond.Wait()
+	}
+	return stack.Function{}, false
+}
+
+// done frees the place of a function handed out by next in its group
+func (s *buildScheduler) done(function stack.Function) {
+	s.mu.Lock()
+	s.running[buildGroup(function)]--
+	s.mu.Unlock()
+	s.cond.Broadcast()
+}
+
+// buildGroup is the name of the function's build group, empty when it has
+// none
+func buildGroup(function stack.Function) string {
+	if function.Build == nil {
+		return ""
+	}
+	return function.Build.Group
+}
+
+// checkBuildGroups makes sure each function's build group is defined in the
+// stack and that no group is given a negative concurrency
+func checkBuildGroups(services stack.Services) error {
+	for name, group := range services.BuildGroups {
+		if group.Concurrency < 0 {
+			return fmt.Errorf("build group %s must have a concurrency of 0 or more, not %d", name, group.Concurrency)
+		}
+	}
+
+	var names []string
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		group := buildGroup(services.Functions[name])
+		if len(group) == 0 {
+			continue
+		}
+		if _, ok := services.BuildGroups[group]; !ok {
+			return fmt.Errorf("function %s is in build group %s, which is not in the build_groups of the stack", name, group)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func groupedFunction(name string, group string) stack.Function {
+	function := stack.Function{Name: name}
+	if len(group) > 0 {
+		function.Build = &stack.FunctionBuild{Group: group}
+	}
+	return function
+}
+
+func Test_buildScheduler_Priority(t *testing.T) {
+	scheduler := newBuildScheduler([]stack.Function{
+		groupedFunction("cron", "batch"),
+		groupedFunction("docs", ""),
+		groupedFunction("api", "critical"),
+		groupedFunction("auth", "critical"),
+	}, map[string]stack.BuildGroup{
+		"critical": {Priority: 10},
+		"batch":    {Priority: -1},
+	})
+
+	var order []string
+	for {
+		function, ok := scheduler.next()
+		if !ok {
+			break
+		}
+		order = append(order, function.Name)
+		scheduler.done(function)
+	}
+
+	want := []string{"api", "auth", "docs", "cron"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("want %v, got %v", want, order)
+	}
+}
+
+func Test_buildScheduler_Concurrency(t *testing.T) {
+	var functions []stack.Function
+	for _, name := range []string{"a", "b", "c", "d"} {
+		functions = append(functions, groupedFunction("batch-"+name, "batch"), groupedFunction("web-"+name, ""))
+	}
+	scheduler := newBuildScheduler(functions, map[string]stack.BuildGroup{"batch": {Concurrency: 1}})
+
+	var mu sync.Mutex
+	running, most := map[string]int{}, map[string]int{}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				function, ok := scheduler.next()
+				if !ok {
+					return
+				}
+				group := buildGroup(function)
+
+				mu.Lock()
+				running[group]++
+				if running[group] > most[group] {
+					most[group] = running[group]
+				}
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				running[group]--
+				mu.Unlock()
+				scheduler.done(function)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if most["batch"] != 1 {
+		t.Errorf("want one batch function built at a time, got %d", most["batch"])
+	}
+	if most[""] < 2 {
+		t.Errorf("want the other functions built in parallel, got %d at most", most[""])
+	}
+}
+
+func Test_checkBuildGroups(t *testing.T) {
+	services := stack.Services{
+		Functions: map[string]stack.Function{
+			"api":  groupedFunction("api", "critical"),
+			"cron": groupedFunction("cron", ""),
+		},
+		BuildGroups: map[string]stack.BuildGroup{"critical": {Priority: 10}},
+	}
+	if err := checkBuildGroups(services); err != nil {
+		t.Errorf("want no error, got %s", err)
+	}
+
+	services.Functions["cron"] = groupedFunction("cron", "batch")
+	want := "function cron is in build group batch, which is not in the build_groups of the stack"
+	if err := checkBuildGroups(services); err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+
+	services.BuildGroups["batch"] = stack.BuildGroup{Concurrency: -1}
+	if err := checkBuildGroups(services); err == nil {
+		t.Errorf("want an error for a negative concurrency")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/config"
+)
+
+var validSecretID = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// setBuildRedactor masks the values of build-args and secrets given as flags,
+// along with the redaction patterns from the flags and config file, in all
+// output from the build
+func setBuildRedactor(flagBuildArgs map[string]string, secretValues map[string]string) error {
+	var values []string
+	for _, value := range flagBuildArgs {
+		values = append(values, value)
+	}
+	for _, value := range secretValues {
+		values = append(values, value)
+	}
+
+	patterns := redactPatterns
+	if cfg, err := config.ReadConfigFile(); err == nil && cfg.Build != nil {
+		patterns = append(cfg.Build.RedactPatterns, patterns...)
+	}
+
+	if len(values) == 0 && len(patterns) == 0 {
+		builder.SetRedactor(nil)
+		return nil
+	}
+
+	redactor, err := builder.NewRedactor(values, patterns)
+	if err != nil {
+		return err
+	}
+
+	builder.SetRedactor(redactor)
+	return nil
+}
+
+// writeBuildSecrets writes each secret to its own file in a new folder for
+// docker build --secret and turns on BuildKit, which is needed to mount them.
+// The caller removes the folder once the build is done.
+func writeBuildSecrets(secretValues map[string]string) (map[string]string, string, error) {
+	if len(secretValues) == 0 {
+		return nil, "", nil
+	}
+
+	for id := range secretValues {
+		if !validSecretID.MatchString(id) {
+			return nil, "", fmt.Errorf("build-secret id %q may only contain letters, numbers, '.', '_' and '-'", id)
+		}
+	}
+
+	dir, err := ioutil.TempDir("", "faas-cli-build-secrets")
+	if err != nil {
+		return nil, "", err
+	}
+
+	secretFiles := map[string]string{}
+	for id, value := range secretValues {
+		path := filepath.Join(dir, id)
+		if err := ioutil.WriteFile(path, []byte(value), 0600); err != nil {
+			os.RemoveAll(dir)
+			return nil, "", err
+		}
+		secretFiles[id] = path
+	}
+
+	os.Setenv("DOCKER_BUILDKIT", "1")
+	return secretFiles, dir, nil
+}
+
+// validateBuildSSH checks each --ssh is default, which needs a running
+// ssh-agent, or ID=PATH to an agent socket or key which exists
+func validateBuildSSH(specs []string) error {
+	for _, spec := range specs {
+		id, path := spec, ""
+		if index := strings.Index(spec, "="); index >= 0 {
+			id, path = spec[:index], spec[index+1:]
+		}
+
+		if !validSecretID.MatchString(id) {
+			return fmt.Errorf("ssh id %q may only contain letters, numbers, '.', '_' and '-'", id)
+		}
+		if len(path) == 0 {
+			if id != "default" {
+				return fmt.Errorf("give --ssh %s=PATH to the agent socket or key to forward", id)
+			}
+			if len(os.Getenv("SSH_AUTH_SOCK")) == 0 {
+				return fmt.Errorf("--ssh default forwards your ssh-agent but SSH_AUTH_SOCK is not set, start an agent or give --ssh default=PATH")
+			}
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("unable to forward --ssh %s: %s", id, err)
+		}
+	}
+	return nil
+}
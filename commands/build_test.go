@@ -4,10 +4,18 @@
 package commands
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
 )
 
 func Test_build(t *testing.T) {
+	defer stubBuildTools(nil)()
 
 	aTests := [][]string{
 		{"build"},
@@ -23,3 +31,72 @@ func Test_build(t *testing.T) {
 		}
 	}
 }
+
+func Test_stackLanguages(t *testing.T) {
+	services := stack.Services{
+		Functions: map[string]stack.Function{
+			"api":    {Language: "python3"},
+			"worker": {Language: "node"},
+			"cron":   {Language: "python3"},
+			"nats":   {Language: "dockerfile", SkipBuild: true},
+		},
+	}
+
+	if got := stackLanguages(services, ""); !reflect.DeepEqual(got, []string{"node", "python3"}) {
+		t.Errorf("want the languages of the functions built, got: %v", got)
+	}
+	if got := stackLanguages(stack.Services{}, "go"); !reflect.DeepEqual(got, []string{"go"}) {
+		t.Errorf("want the --lang given without a YAML file, got: %v", got)
+	}
+	if got := stackLanguages(stack.Services{}, ""); got != nil {
+		t.Errorf("want no languages, got: %v", got)
+	}
+}
+
+func Test_validateBuildSSH(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-ssh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key := filepath.Join(dir, "id_rsa")
+	ioutil.WriteFile(key, []byte("key"), 0600)
+
+	defer os.Setenv("SSH_AUTH_SOCK", os.Getenv("SSH_AUTH_SOCK"))
+	os.Unsetenv("SSH_AUTH_SOCK")
+
+	if err := validateBuildSSH([]string{"github=" + key, "default=" + key}); err != nil {
+		t.Errorf("want keys which exist to be forwarded, got: %s", err)
+	}
+
+	cases := map[string]string{
+		"default":                    "SSH_AUTH_SOCK is not set",
+		"github":                     "give --ssh github=PATH",
+		"git hub=" + key:             "may only contain",
+		"github=" + dir + "/missing": "unable to forward --ssh github",
+	}
+	for spec, want := range cases {
+		if err := validateBuildSSH([]string{spec}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("--ssh %s: want an error containing %q, got: %v", spec, want, err)
+		}
+	}
+
+	os.Setenv("SSH_AUTH_SOCK", filepath.Join(dir, "agent.sock"))
+	if err := validateBuildSSH([]string{"default"}); err != nil {
+		t.Errorf("want the running agent to be forwarded, got: %s", err)
+	}
+}
+
+func Test_functionCacheFrom(t *testing.T) {
+	function := stack.Function{Build: &stack.FunctionBuild{CacheFrom: []string{"fn:latest", "fn:cache"}}}
+
+	got := functionCacheFrom(function, []string{"fn:cache", "base:latest"})
+	want := []string{"fn:latest", "fn:cache", "base:latest"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if got := functionCacheFrom(stack.Function{}, nil); len(got) != 0 {
+		t.Errorf("want no images without build.cache_from or --cache-from, got %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/config"
+)
+
+// lookPath and dockerServerVersion are swapped out in tests
+var (
+	lookPath = exec.LookPath
+
+	dockerServerVersion = func() (string, error) {
+		out, err := exec.Command("docker", "version", "--format", "{{.Server.Version}}").CombinedOutput()
+		return strings.TrimSpace(string(out)), err
+	}
+)
+
+// buildAlternatives are the ways of building without a local Docker daemon
+var buildAlternatives = []struct {
+	flag        string
+	description string
+}{
+	{"--shrinkwrap", "write each function's build context to ./build/ to be built elsewhere, i.e. in CI"},
+	{"--dispatch", "send each build to the webhook of an external build service"},
+	{"--remote-builder", "send each build context to a builder API, such as the OpenFaaS Pro builder"},
+}
+
+// buildKitEnv chooses the buildkit builder over the config file when set to 1
+const buildKitEnv = "FAAS_BUILDKIT"
+
+// useConfiguredBuilder chooses the builder named by build.builder in the
+// config file, or buildkit when it is asked for with --buildkit or
+// FAAS_BUILDKIT=1
+func useConfiguredBuilder(buildKit bool) error {
+	cfg, err := config.ReadConfigFile()
+	if err != nil {
+		return err
+	}
+
+	name, remoteHost := "", ""
+	if cfg.Build != nil {
+		name, remoteHost = cfg.Build.Builder, cfg.Build.RemoteHost
+	}
+	if buildKit || os.Getenv(buildKitEnv) == "1" {
+		name = "buildkit"
+	}
+
+	backend, err := builder.NewBackend(name, remoteHost)
+	if err != nil {
+		return err
+	}
+	builder.SetBackend(backend)
+	return nil
+}
+
+// checkBuildTools makes sure the builder can be run before any function is
+// built and gives a single error explaining how to install it or what to use
+// instead
+func checkBuildTools() error {
+	backend := builder.Backend()
+	if !backend.Capabilities().Daemon {
+		// Nothing is run locally by a builder without a command
+		if len(backend.Command()) == 0 {
+			return nil
+		}
+		if _, err := lookPath(backend.Command()); err != nil {
+			hints := []string{"Install " + backend.Command() + ", or choose another builder with build.builder in the config file"}
+			if backend.Command() == "buildctl" {
+				hints = append(hints, "buildctl is released with BuildKit: https://github.com/moby/buildkit/releases, and talks to the buildkitd in BUILDKIT_HOST")
+			}
+			return buildToolsError(fmt.Sprintf("%s is needed by the %s builder but was not found in your PATH", backend.Command(), backend.Name()), hints)
+		}
+		return nil
+	}
+
+	if _, err := lookPath("docker"); err != nil {
+		return buildToolsError("docker is needed to build functions but was not found in your PATH", dockerInstallHints())
+	}
+
+	if out, err := dockerServerVersion(); err != nil {
+		hints := []string{"Start the Docker daemon, or set DOCKER_HOST to a remote one"}
+		if len(out) > 0 {
+			hints = append([]string{"docker said: " + out}, hints...)
+		}
+		return buildToolsError("docker was found but the Docker daemon could not be reached", hints)
+	}
+
+	return nil
+}
+
+func dockerInstallHints() []string {
+	var hints []string
+
+	switch runtime.GOOS {
+	case "darwin":
+		hints = append(hints, "Install Docker for Mac: https://docs.docker.com/docker-for-mac/install/")
+	case "windows":
+		hints = append(hints, "Install Docker for Windows: https://docs.docker.com/docker-for-windows/install/")
+	default:
+		hints = append(hints, "Install Docker CE: https://docs.docker.com/install/ or run: curl -sSL https://get.docker.com | sh")
+	}
+
+	if _, err := lookPath("podman"); err == nil {
+		hints = append(hints, "podman was found, install podman-docker or link podman as docker to build with it")
+	}
+	if _, err := lookPath("buildctl"); err == nil {
+		hints = append(hints, "buildctl was found, build with it without docker with --buildkit")
+	}
+
+	return hints
+}
+
+func buildToolsError(problem string, hints []string) error {
+	message := problem + "\n"
+	for _, hint := range hints {
+		message += "\n  " + hint
+	}
+
+	message += "\n\nAlternatives which do not need docker:"
+	for _, alternative := range buildAlternatives {
+		message += fmt.Sprintf("\n  %-14s %s", alternative.flag, alternative.description)
+	}
+
+	return fmt.Errorf("%s", message)
+}
@@ -0,0 +1,216 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/config"
+)
+
+// stubBuildTools pretends only the named tools are installed, docker is
+// always installed when tools is nil, and returns a func to undo the stub
+func stubBuildTools(tools []string) func() {
+	originalLookPath, originalServerVersion := lookPath, dockerServerVersion
+
+	lookPath = func(file string) (string, error) {
+		if tools == nil {
+			return "/usr/bin/" + file, nil
+		}
+		for _, tool := range tools {
+			if tool == file {
+				return "/usr/bin/" + file, nil
+			}
+		}
+		return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+	}
+	dockerServerVersion = func() (string, error) {
+		return "18.06.0-ce", nil
+	}
+
+	return func() {
+		lookPath, dockerServerVersion = originalLookPath, originalServerVersion
+	}
+}
+
+func Test_checkBuildTools(t *testing.T) {
+	defer stubBuildTools([]string{"docker"})()
+
+	if err := checkBuildTools(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func Test_checkBuildTools_MissingDocker(t *testing.T) {
+	defer stubBuildTools([]string{"podman"})()
+
+	err := checkBuildTools()
+	if err == nil {
+		t.Fatalf("want error when docker is missing")
+	}
+
+	for _, want := range []string{"not found in your PATH", "Install Docker", "podman was found", "--shrinkwrap"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want error to contain %q, got:\n%s", want, err)
+		}
+	}
+}
+
+func Test_checkBuildTools_DaemonDown(t *testing.T) {
+	defer stubBuildTools([]string{"docker"})()
+	dockerServerVersion = func() (string, error) {
+		return "Cannot connect to the Docker daemon", fmt.Errorf("exit status 1")
+	}
+
+	err := checkBuildTools()
+	if err == nil || !strings.Contains(err.Error(), "could not be reached") || !strings.Contains(err.Error(), "Cannot connect") {
+		t.Fatalf("want error about the daemon, got: %v", err)
+	}
+}
+
+func Test_build_ShrinkwrapSkipsToolCheck(t *testing.T) {
+	defer stubBuildTools([]string{})()
+	defer func() { shrinkwrap = false }()
+
+	shrinkwrap = true
+	if err := preRunBuild(nil, nil); err != nil {
+		t.Fatalf("want no tool check with --shrinkwrap, got: %s", err)
+	}
+
+	shrinkwrap = false
+	if err := preRunBuild(nil, nil); err == nil {
+		t.Fatalf("want tool check without --shrinkwrap")
+	}
+}
+
+func Test_checkBuildTools_DaemonlessBuilder(t *testing.T) {
+	defer stubBuildTools([]string{"docker"})()
+	defer builder.SetBackend(nil)
+
+	builder.SetBackend(builder.PodmanBuilder{})
+	err := checkBuildTools()
+	if err == nil || !strings.Contains(err.Error(), "podman is needed by the podman builder") {
+		t.Fatalf("want an error about podman, got: %v", err)
+	}
+
+	builder.SetBackend(builder.RemoteBuilder{Host: "ssh://builder"})
+	dockerServerVersion = func() (string, error) {
+		return "", fmt.Errorf("no local daemon")
+	}
+	if err := checkBuildTools(); err != nil {
+		t.Fatalf("want no check of the local daemon for the remote builder, got: %s", err)
+	}
+}
+
+func Test_printBuilders(t *testing.T) {
+	defer stubBuildTools([]string{"docker"})()
+
+	var out bytes.Buffer
+	printBuilders(&out, []builder.Builder{builder.DockerBuilder{}, builder.KoBuilder{}}, "ko")
+
+	want := `   BUILDER  COMMAND  FOUND  BUILD-ARGS  SECRETS  SQUASH  PLATFORMS  MULTI-ARCH  SSH  INLINE-CACHE  CACHE-FROM  TEMPLATES  PUSHES
+   docker   docker   yes    yes         yes      yes     yes        no          no   no            yes         yes        no
+*  ko       ko       no     no          no       no      yes        no          no   no            no          no         yes
+`
+	if out.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func Test_useConfiguredBuilder_BuildKit(t *testing.T) {
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-builder")
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+		os.Unsetenv(buildKitEnv)
+		builder.SetBackend(nil)
+	}()
+
+	if err := useConfiguredBuilder(false); err != nil {
+		t.Fatal(err)
+	}
+	if name := builder.Backend().Name(); name != "docker" {
+		t.Errorf("want the docker builder by default, got %s", name)
+	}
+
+	if err := useConfiguredBuilder(true); err != nil {
+		t.Fatal(err)
+	}
+	if name := builder.Backend().Name(); name != "buildkit" {
+		t.Errorf("want --buildkit to choose the buildkit builder, got %s", name)
+	}
+
+	os.Setenv(buildKitEnv, "1")
+	if err := useConfiguredBuilder(false); err != nil {
+		t.Fatal(err)
+	}
+	if name := builder.Backend().Name(); name != "buildkit" {
+		t.Errorf("want %s=1 to choose the buildkit builder, got %s", buildKitEnv, name)
+	}
+}
+
+func Test_useDispatchBuilder(t *testing.T) {
+	defer builder.SetBackend(nil)
+
+	if err := useDispatchBuilder("builds.example.com", time.Minute); err == nil {
+		t.Errorf("want a URL without a scheme rejected")
+	}
+	if err := useDispatchBuilder("https://builds.example.com/jobs", 0); err == nil {
+		t.Errorf("want a timeout of 0 rejected")
+	}
+
+	os.Setenv(dispatchTokenEnv, "s3cret")
+	defer os.Unsetenv(dispatchTokenEnv)
+	if err := useDispatchBuilder("https://builds.example.com/jobs", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	backend, ok := builder.Backend().(builder.DispatchBuilder)
+	if !ok || backend.Token != "s3cret" || backend.URL != "https://builds.example.com/jobs" {
+		t.Errorf("want the dispatch builder chosen with the token, got %+v", builder.Backend())
+	}
+
+	defer stubBuildTools([]string{})()
+	if err := checkBuildTools(); err != nil {
+		t.Errorf("want no local tools needed to dispatch, got %s", err)
+	}
+}
+
+func Test_useRemoteBuilder(t *testing.T) {
+	defer builder.SetBackend(nil)
+
+	if err := useRemoteBuilder("builder:8080", "", time.Minute); err == nil {
+		t.Errorf("want a URL without a scheme rejected")
+	}
+
+	dir, err := ioutil.TempDir("", "faas-cli-remote-builder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "payload.txt")
+	ioutil.WriteFile(secretFile, []byte("s3cret\n"), 0600)
+
+	if err := useRemoteBuilder("http://builder:8080", secretFile, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	backend, ok := builder.Backend().(builder.RemoteBuilderAPI)
+	if !ok || backend.PayloadSecret != "s3cret" || backend.URL != "http://builder:8080" {
+		t.Errorf("want the remote builder chosen with the payload secret, got %+v", builder.Backend())
+	}
+
+	defer stubBuildTools([]string{})()
+	if err := checkBuildTools(); err != nil {
+		t.Errorf("want no local tools needed for a remote builder, got %s", err)
+	}
+}
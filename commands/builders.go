@@ -0,0 +1,76 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	faasCmd.AddCommand(buildersCmd)
+}
+
+var buildersCmd = &cobra.Command{
+	Use:   `builders`,
+	Short: "List the backends functions can be built with and what each supports",
+	Long: `Lists each backend faas-cli build can use, whether its command was found and
+which build features it supports. The backend marked * is chosen with the
+config file:
+
+  build:
+    builder: podman
+
+The remote builder runs docker against the daemon in build.remote_host.`,
+	RunE: runBuilders,
+}
+
+func runBuilders(cmd *cobra.Command, args []string) error {
+	if err := useConfiguredBuilder(false); err != nil {
+		return err
+	}
+
+	var backends []builder.Builder
+	for _, name := range builder.BackendNames() {
+		backend, err := builder.NewBackend(name, "")
+		if err != nil {
+			return err
+		}
+		backends = append(backends, backend)
+	}
+
+	printBuilders(os.Stdout, backends, builder.Backend().Name())
+	return nil
+}
+
+func printBuilders(w io.Writer, backends []builder.Builder, selected string) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "\tBUILDER\tCOMMAND\tFOUND\tBUILD-ARGS\tSECRETS\tSQUASH\tPLATFORMS\tMULTI-ARCH\tSSH\tINLINE-CACHE\tCACHE-FROM\tTEMPLATES\tPUSHES")
+	for _, backend := range backends {
+		marker := ""
+		if backend.Name() == selected {
+			marker = "*"
+		}
+		_, lookErr := lookPath(backend.Command())
+
+		capabilities := backend.Capabilities()
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", marker, backend.Name(), backend.Command(), yesNo(lookErr == nil),
+			yesNo(capabilities.BuildArgs), yesNo(capabilities.Secrets), yesNo(capabilities.Squash),
+			yesNo(capabilities.Platforms), yesNo(capabilities.MultiPlatform), yesNo(capabilities.SSH), yesNo(capabilities.InlineCache), yesNo(capabilities.CacheFrom),
+			yesNo(capabilities.Dockerfile), yesNo(capabilities.Pushes))
+	}
+	table.Flush()
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
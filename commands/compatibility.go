@@ -0,0 +1,229 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// imageLabels reads the labels of an image from the local Docker daemon, or
+// from its registry when it is not held locally
+var imageLabels = func(image string) (map[string]string, error) {
+	out, err := exec.Command("docker", "image", "inspect", "--format", "{{json .Config.Labels}}", image).Output()
+	if err != nil {
+		out, err = exec.Command("docker", "buildx", "imagetools", "inspect", "--format", "{{json .Image.Config.Labels}}", image).Output()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	labels := map[string]string{}
+	if err := json.Unmarshal(bytes.TrimSpace(out), &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
+
+// compatibilityRule is a known incompatibility between a watchdog and the
+// gateway, which leaves a function returning 502s on its first invocations
+type compatibilityRule struct {
+	Watchdog string
+
+	// Mode of the of-watchdog, empty matches every mode
+	Mode string
+
+	// MinWatchdog and MinGateway are the first versions the watchdog works
+	// with, empty when any version does
+	MinWatchdog string
+	MinGateway  string
+
+	Reason string
+}
+
+var compatibilityRules = []compatibilityRule{
+	{
+		Watchdog:    stack.OfWatchdog,
+		Mode:        stack.StaticMode,
+		MinWatchdog: "0.8.1",
+		Reason:      "the static mode needs of-watchdog 0.8.1 or newer, older watchdogs exit on start",
+	},
+	{
+		Watchdog:    stack.ClassicWatchdog,
+		MinWatchdog: "0.9.0",
+		Reason:      "classic watchdogs before 0.9.0 have no /_/health endpoint, so providers which probe it never mark the function ready",
+	},
+	{
+		Watchdog:   stack.OfWatchdog,
+		MinGateway: "0.8.0",
+		Reason:     "gateways before 0.8.0 route requests before the of-watchdog is ready, so the first invocations return 502",
+	},
+}
+
+// componentRule is a known incompatibility between this CLI and a version of
+// the container runtime, gateway or provider it works with
+type componentRule struct {
+	// Component is docker, podman, gateway or the name of a provider such as
+	// faas-netes
+	Component string
+
+	// MinVersion is the first version the CLI works with
+	MinVersion string
+
+	Reason string
+}
+
+var componentRules = []componentRule{
+	{
+		Component:  "docker",
+		MinVersion: "17.05",
+		Reason:     "the templates use multi-stage builds, which need Docker 17.05 or newer",
+	},
+	{
+		Component:  "gateway",
+		MinVersion: "0.13.0",
+		Reason:     "gateways before 0.13.0 do not serve /system/logs, so faas-cli logs fails",
+	},
+	{
+		Component:  "faas-netes",
+		MinVersion: "0.12.0",
+		Reason:     "faas-netes before 0.12.0 ignores --namespace and deploys every function to its own namespace",
+	},
+}
+
+// componentWarnings gives a warning for each known incompatibility of the
+// components by name, components whose version is unknown or not a release
+// are skipped
+func componentWarnings(versions map[string]string) []string {
+	var warnings []string
+	for _, rule := range componentRules {
+		version, ok := versions[rule.Component]
+		if !ok {
+			continue
+		}
+		// Drop suffixes such as 17.03.1-ce or 1.0.0+dirty
+		release := strings.FieldsFunc(version, func(r rune) bool { return r == '-' || r == '+' })
+		if len(release) == 0 {
+			continue
+		}
+		if older, ok := versionBefore(release[0], rule.MinVersion); ok && older {
+			warnings = append(warnings, fmt.Sprintf("%s (%s is %s)", rule.Reason, rule.Component, version))
+		}
+	}
+	return warnings
+}
+
+// compatibilityChecker checks the watchdog recorded in each image against
+// the gateway, which is only asked for its version once it is needed
+type compatibilityChecker struct {
+	gateway string
+
+	fetched bool
+	release string
+}
+
+func newCompatibilityChecker(gateway string) *compatibilityChecker {
+	return &compatibilityChecker{gateway: gateway}
+}
+
+// warn prints a warning for each known incompatibility of the function's
+// image, images without watchdog labels or which cannot be read are skipped
+func (c *compatibilityChecker) warn(functionName string, image string) {
+	for _, warning := range c.check(image) {
+		fmt.Printf("Warning: function %s may not start: %s.\n", functionName, warning)
+	}
+}
+
+func (c *compatibilityChecker) check(image string) []string {
+	labels, err := imageLabels(image)
+	if err != nil {
+		return nil
+	}
+	watchdog := stack.LabelledWatchdog(labels)
+	if watchdog == nil {
+		return nil
+	}
+
+	var warnings []string
+	for _, rule := range compatibilityRules {
+		if rule.Watchdog != watchdog.Type || (len(rule.Mode) > 0 && rule.Mode != watchdog.Mode) {
+			continue
+		}
+
+		if len(rule.MinWatchdog) > 0 {
+			if older, ok := versionBefore(watchdog.Version, rule.MinWatchdog); ok && older {
+				warnings = append(warnings, fmt.Sprintf("%s (image has %s %s)", rule.Reason, watchdog, watchdog.Version))
+			}
+		}
+		if len(rule.MinGateway) > 0 {
+			release := c.gatewayRelease()
+			if older, ok := versionBefore(release, rule.MinGateway); ok && older {
+				warnings = append(warnings, fmt.Sprintf("%s (gateway is %s)", rule.Reason, release))
+			}
+		}
+	}
+	return warnings
+}
+
+// gatewayRelease is empty when the gateway does not report its version
+func (c *compatibilityChecker) gatewayRelease() string {
+	if !c.fetched {
+		c.fetched = true
+		if info, err := proxy.GetSystemInfo(c.gateway); err == nil && info != nil {
+			c.release = info.Version.Release
+		}
+	}
+	return c.release
+}
+
+// versionBefore reports whether version is older than minimum, ok is false
+// when either is not a dotted number, i.e. a dev build
+func versionBefore(version string, minimum string) (bool, bool) {
+	a, ok := parseVersion(version)
+	if !ok {
+		return false, false
+	}
+	b, ok := parseVersion(minimum)
+	if !ok {
+		return false, false
+	}
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x < y, true
+		}
+	}
+	return false, true
+}
+
+func parseVersion(version string) ([]int, bool) {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if len(version) == 0 {
+		return nil, false
+	}
+
+	var parts []int
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		parts = append(parts, number)
+	}
+	return parts, true
+}
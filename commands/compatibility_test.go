@@ -0,0 +1,139 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_versionBefore(t *testing.T) {
+	cases := []struct {
+		version, minimum string
+		older, ok        bool
+	}{
+		{"0.7.7", "0.8.1", true, true},
+		{"0.8.1", "0.8.1", false, true},
+		{"0.10.0", "0.8.1", false, true},
+		{"v0.8", "0.8.0", false, true},
+		{"0.7", "0.7.1", true, true},
+		{"dev", "0.8.0", false, false},
+		{"", "0.8.0", false, false},
+	}
+	for _, c := range cases {
+		older, ok := versionBefore(c.version, c.minimum)
+		if older != c.older || ok != c.ok {
+			t.Errorf("%s before %s: want %v %v, got %v %v", c.version, c.minimum, c.older, c.ok, older, ok)
+		}
+	}
+}
+
+func stubImageLabels(labels map[string]map[string]string) func() {
+	original := imageLabels
+	imageLabels = func(image string) (map[string]string, error) {
+		if l, ok := labels[image]; ok {
+			return l, nil
+		}
+		return nil, fmt.Errorf("no such image: %s", image)
+	}
+	return func() { imageLabels = original }
+}
+
+func Test_compatibilityChecker_WarnsForOldGateway(t *testing.T) {
+	defer stubImageLabels(map[string]map[string]string{
+		"fn:0.1": {stack.WatchdogLabel: stack.OfWatchdog, stack.WatchdogModeLabel: stack.StaticMode, stack.WatchdogVersionLabel: "0.7.7"},
+		"fn:0.2": {stack.WatchdogLabel: stack.OfWatchdog, stack.WatchdogModeLabel: stack.HTTPMode, stack.WatchdogVersionLabel: "0.8.4"},
+	})()
+
+	// The gateway is only asked for its version once
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/info",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: map[string]interface{}{
+				"version": map[string]string{"release": "0.7.9"},
+			},
+		},
+	})
+	defer s.Close()
+
+	checker := newCompatibilityChecker(s.URL)
+
+	warnings := checker.check("fn:0.1")
+	if len(warnings) != 2 {
+		t.Fatalf("want warnings for the watchdog and the gateway, got: %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "of-watchdog (static) 0.7.7") || !strings.Contains(warnings[1], "gateway is 0.7.9") {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	if warnings := checker.check("fn:0.2"); len(warnings) != 1 {
+		t.Errorf("want a warning for the gateway, got: %v", warnings)
+	}
+}
+
+func Test_compatibilityChecker_SkipsUnlabelledImages(t *testing.T) {
+	defer stubImageLabels(map[string]map[string]string{
+		"golang": {"maintainer": "someone"},
+	})()
+
+	// No request is made to the gateway
+	s := test.MockHttpServer(t, []test.Request{})
+	defer s.Close()
+
+	checker := newCompatibilityChecker(s.URL)
+	for _, image := range []string{"golang", "missing"} {
+		if warnings := checker.check(image); len(warnings) > 0 {
+			t.Errorf("%s: want no warnings, got: %v", image, warnings)
+		}
+	}
+}
+
+func Test_compatibilityChecker_CompatibleImage(t *testing.T) {
+	defer stubImageLabels(map[string]map[string]string{
+		"fn:0.3": {stack.WatchdogLabel: stack.OfWatchdog, stack.WatchdogModeLabel: stack.HTTPMode, stack.WatchdogVersionLabel: "0.8.4"},
+	})()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/info",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: map[string]interface{}{
+				"version": map[string]string{"release": "0.18.2"},
+			},
+		},
+	})
+	defer s.Close()
+
+	if warnings := newCompatibilityChecker(s.URL).check("fn:0.3"); len(warnings) > 0 {
+		t.Errorf("want no warnings, got: %v", warnings)
+	}
+}
+
+func Test_componentWarnings(t *testing.T) {
+	warnings := componentWarnings(map[string]string{
+		"docker":     "17.03.1-ce",
+		"gateway":    "0.18.2",
+		"faas-netes": "0.11.1",
+		"faasd":      "0.1.0",
+	})
+	want := []string{
+		"the templates use multi-stage builds, which need Docker 17.05 or newer (docker is 17.03.1-ce)",
+		"faas-netes before 0.12.0 ignores --namespace and deploys every function to its own namespace (faas-netes is 0.11.1)",
+	}
+	if fmt.Sprint(warnings) != fmt.Sprint(want) {
+		t.Errorf("want %v, got %v", want, warnings)
+	}
+
+	if warnings := componentWarnings(map[string]string{"docker": "dev", "gateway": ""}); len(warnings) > 0 {
+		t.Errorf("want unknown versions to be skipped, got %v", warnings)
+	}
+}
@@ -0,0 +1,443 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas/gateway/requests"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+var dashboardInterval time.Duration
+
+func init() {
+	dashboardCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	dashboardCmd.Flags().DurationVar(&dashboardInterval, "interval", 2*time.Second, "How often to refresh replica counts and invocation rates")
+
+	faasCmd.AddCommand(dashboardCmd)
+}
+
+var dashboardCmd = &cobra.Command{
+	Use:   `dashboard [-f YAML_FILE] [--gateway GATEWAY_URL] [--interval DURATION]`,
+	Short: "Interactive dashboard of deployed functions",
+	Long: `Shows an interactive dashboard of the functions on a gateway, or of the
+functions in a stack file when one is given, with their replicas and invocation
+rates refreshed live.
+
+Keys:
+  up/down, k/j  select a function
+  i             invoke the function with an empty body
+  l             show the function's most recent logs
+  s             scale the function to a number of replicas
+  r             redeploy the function from the stack file
+  q, ctrl+c     quit`,
+	Example: `  faas-cli dashboard
+  faas-cli dashboard -f ./stack.yml --interval 5s`,
+	RunE: runDashboard,
+}
+
+const dashboardLogLines = 20
+
+// dashboardRow is a function shown on the dashboard
+type dashboardRow struct {
+	Name        string
+	Image       string
+	Replicas    uint64
+	Invocations float64
+	// Rate is invocations per second since the previous refresh
+	Rate     float64
+	Deployed bool
+}
+
+type dashboard struct {
+	gateway   string
+	namespace string
+	services  *stack.Services
+
+	rows     []dashboardRow
+	selected int
+	polled   time.Time
+
+	// status is a one line message, detail holds the output of invoke or logs
+	status string
+	detail []string
+
+	// prompt is shown while the replicas for scale are typed
+	prompt string
+	input  string
+}
+
+func runDashboard(cmd *cobra.Command, args []string) error {
+	var services *stack.Services
+	var yamlGateway string
+	if len(yamlFile) > 0 {
+		parsedServices, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+		if err != nil {
+			return err
+		}
+		services = parsedServices
+		yamlGateway = services.Provider.GatewayURL
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return fmt.Errorf("the dashboard needs an interactive terminal")
+	}
+
+	d := &dashboard{
+		gateway:   getGatewayURL(gateway, defaultGateway, yamlGateway),
+		namespace: functionNamespace,
+		services:  services,
+	}
+	if err := d.refresh(time.Now()); err != nil {
+		return err
+	}
+
+	state, err := terminal.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		terminal.Restore(fd, state)
+		fmt.Print("\x1b[?25h\x1b[2J\x1b[H")
+	}()
+
+	keys := make(chan []byte)
+	go func() {
+		buf := make([]byte, 32)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- append([]byte{}, buf[:n]...)
+		}
+	}()
+
+	ticker := time.NewTicker(dashboardInterval)
+	defer ticker.Stop()
+
+	for {
+		width, height, sizeErr := terminal.GetSize(fd)
+		if sizeErr != nil {
+			width, height = 100, 30
+		}
+		var screen bytes.Buffer
+		d.render(&screen, width, height)
+		os.Stdout.Write(screen.Bytes())
+
+		select {
+		case <-ticker.C:
+			if err := d.refresh(time.Now()); err != nil {
+				d.status = err.Error()
+			}
+		case input, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			for _, key := range parseKeys(input) {
+				if d.handleKey(key) {
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// refresh reads the deployed functions and works out each one's invocation rate
+func (d *dashboard) refresh(now time.Time) error {
+	functions, err := proxy.ListFunctionsInNamespace(d.gateway, d.namespace)
+	if err != nil {
+		return err
+	}
+	d.update(functions, now)
+	return nil
+}
+
+func (d *dashboard) update(functions []requests.Function, now time.Time) {
+	previous := map[string]dashboardRow{}
+	for _, row := range d.rows {
+		previous[row.Name] = row
+	}
+	elapsed := now.Sub(d.polled).Seconds()
+
+	var selectedName string
+	if d.selected < len(d.rows) {
+		selectedName = d.rows[d.selected].Name
+	}
+
+	deployed := map[string]requests.Function{}
+	for _, function := range functions {
+		deployed[function.Name] = function
+	}
+
+	// With a stack file only its functions are shown, deployed or not
+	var names []string
+	if d.services != nil {
+		for name := range d.services.Functions {
+			names = append(names, name)
+		}
+	} else {
+		for name := range deployed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	rows := make([]dashboardRow, 0, len(names))
+	for _, name := range names {
+		row := dashboardRow{Name: name}
+		if function, ok := deployed[name]; ok {
+			row.Deployed = true
+			row.Image = function.Image
+			row.Replicas = function.Replicas
+			row.Invocations = function.InvocationCount
+
+			if last, seen := previous[name]; seen && last.Deployed && elapsed > 0 && row.Invocations >= last.Invocations {
+				row.Rate = (row.Invocations - last.Invocations) / elapsed
+			}
+		} else if d.services != nil {
+			row.Image = d.services.Functions[name].Image
+		}
+		rows = append(rows, row)
+	}
+
+	d.rows = rows
+	d.polled = now
+	d.selected = 0
+	for i, row := range rows {
+		if row.Name == selectedName {
+			d.selected = i
+		}
+	}
+}
+
+// parseKeys names the keys in a read from a raw terminal
+func parseKeys(input []byte) []string {
+	var keys []string
+	for i := 0; i < len(input); i++ {
+		switch {
+		case input[i] == 0x1b && i+2 < len(input) && input[i+1] == '[':
+			switch input[i+2] {
+			case 'A':
+				keys = append(keys, "up")
+			case 'B':
+				keys = append(keys, "down")
+			}
+			i += 2
+		case input[i] == 0x1b:
+			keys = append(keys, "esc")
+		case input[i] == 0x03:
+			keys = append(keys, "ctrl+c")
+		case input[i] == '\r' || input[i] == '\n':
+			keys = append(keys, "enter")
+		case input[i] == 0x7f || input[i] == 0x08:
+			keys = append(keys, "backspace")
+		default:
+			keys = append(keys, string(input[i]))
+		}
+	}
+	return keys
+}
+
+// handleKey acts on a key press and reports whether to quit
+func (d *dashboard) handleKey(key string) bool {
+	if key == "ctrl+c" {
+		return true
+	}
+
+	if len(d.prompt) > 0 {
+		d.handlePromptKey(key)
+		return false
+	}
+
+	switch key {
+	case "q":
+		return true
+	case "up", "k":
+		if d.selected > 0 {
+			d.selected--
+		}
+	case "down", "j":
+		if d.selected < len(d.rows)-1 {
+			d.selected++
+		}
+	case "i":
+		if row, ok := d.selectedRow(); ok {
+			d.invoke(row.Name)
+		}
+	case "l":
+		if row, ok := d.selectedRow(); ok {
+			d.logs(row.Name)
+		}
+	case "s":
+		if row, ok := d.selectedRow(); ok {
+			d.prompt = fmt.Sprintf("Scale %s to replicas (currently %d): ", row.Name, row.Replicas)
+			d.input = ""
+		}
+	case "r":
+		if row, ok := d.selectedRow(); ok {
+			d.redeploy(row.Name)
+		}
+	}
+	return false
+}
+
+func (d *dashboard) handlePromptKey(key string) {
+	switch key {
+	case "esc":
+		d.prompt, d.input = "", ""
+		d.status = "Scale cancelled."
+	case "backspace":
+		if len(d.input) > 0 {
+			d.input = d.input[:len(d.input)-1]
+		}
+	case "enter":
+		d.prompt = ""
+		replicas, err := strconv.ParseUint(d.input, 10, 64)
+		if err != nil {
+			d.status = fmt.Sprintf("%q is not a number of replicas.", d.input)
+			return
+		}
+		if row, ok := d.selectedRow(); ok {
+			d.scale(row.Name, replicas)
+		}
+	default:
+		if len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+			d.input += key
+		}
+	}
+}
+
+func (d *dashboard) selectedRow() (dashboardRow, bool) {
+	if d.selected >= len(d.rows) {
+		return dashboardRow{}, false
+	}
+	return d.rows[d.selected], true
+}
+
+func (d *dashboard) invoke(name string) {
+	started := time.Now()
+	body := []byte{}
+	response, err := proxy.InvokeFunction(d.gateway, qualifiedName(name, d.namespace), &body, "text/plain", nil)
+	if err != nil {
+		d.status = fmt.Sprintf("Invoking %s failed: %s", name, err)
+		d.detail = nil
+		return
+	}
+	d.status = fmt.Sprintf("Invoked %s in %s:", name, time.Since(started).Round(time.Millisecond))
+	d.detail = strings.Split(strings.TrimRight(string(*response), "\n"), "\n")
+}
+
+func (d *dashboard) logs(name string) {
+	messages, err := proxy.GetLogsInNamespace(d.gateway, name, d.namespace, dashboardLogLines)
+	if err != nil {
+		d.status = err.Error()
+		d.detail = nil
+		return
+	}
+	d.status = fmt.Sprintf("Last %d log lines of %s:", len(messages), name)
+	d.detail = nil
+	for _, message := range messages {
+		d.detail = append(d.detail, message.Timestamp.Format("15:04:05")+" "+strings.TrimRight(message.Text, "\n"))
+	}
+}
+
+func (d *dashboard) scale(name string, replicas uint64) {
+	if err := proxy.ScaleFunctionInNamespace(d.gateway, name, d.namespace, replicas); err != nil {
+		d.status = fmt.Sprintf("Scaling %s failed: %s", name, err)
+		return
+	}
+	d.status = fmt.Sprintf("Scaled %s to %d replica(s).", name, replicas)
+}
+
+func (d *dashboard) redeploy(name string) {
+	if d.services == nil {
+		d.status = "Redeploy needs a stack file, pass one with -f."
+		return
+	}
+
+	single := *d.services
+	single.Provider.GatewayURL = d.gateway
+	single.Functions = map[string]stack.Function{name: d.services.Functions[name]}
+
+	// deployStack prints its progress, which would scroll the dashboard
+	stdout := os.Stdout
+	if devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0); err == nil {
+		os.Stdout = devNull
+		defer devNull.Close()
+	}
+	err := deployStack(&single, DeployFlags{update: true}, nil)
+	os.Stdout = stdout
+
+	if err != nil {
+		d.status = fmt.Sprintf("Redeploying %s failed: %s", name, err)
+		return
+	}
+	d.status = fmt.Sprintf("Redeployed %s.", name)
+}
+
+// render draws the dashboard, using \r\n as the terminal is in raw mode
+func (d *dashboard) render(w io.Writer, width int, height int) {
+	var lines []string
+	lines = append(lines, fmt.Sprintf("OpenFaaS dashboard - %s - refreshed %s", d.gateway, d.polled.Format("15:04:05")))
+	lines = append(lines, "")
+	lines = append(lines, fmt.Sprintf("  %-30s %-40s %8s %12s %8s", "FUNCTION", "IMAGE", "REPLICAS", "INVOCATIONS", "RATE/S"))
+
+	for i, row := range d.rows {
+		cursor := "  "
+		if i == d.selected {
+			cursor = "> "
+		}
+		if !row.Deployed {
+			lines = append(lines, fmt.Sprintf("%s%-30s %-40s %8s", cursor, truncate(row.Name, 30), truncate(row.Image, 40), "not deployed"))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s%-30s %-40s %8d %12d %8.2f", cursor, truncate(row.Name, 30), truncate(row.Image, 40),
+			row.Replicas, int64(row.Invocations), row.Rate))
+	}
+	if len(d.rows) == 0 {
+		lines = append(lines, "  No functions found.")
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, "up/down select  i invoke  l logs  s scale  r redeploy  q quit")
+	if len(d.prompt) > 0 {
+		lines = append(lines, d.prompt+d.input)
+	} else if len(d.status) > 0 {
+		lines = append(lines, d.status)
+	}
+	lines = append(lines, d.detail...)
+
+	if height > 0 && len(lines) > height-1 {
+		lines = lines[:height-1]
+	}
+
+	io.WriteString(w, "\x1b[?25l\x1b[H\x1b[2J")
+	for _, line := range lines {
+		io.WriteString(w, truncate(line, width)+"\r\n")
+	}
+}
+
+func truncate(value string, width int) string {
+	if width <= 0 || len(value) <= width {
+		return value
+	}
+	if width <= 2 {
+		return value[:width]
+	}
+	return value[:width-2] + ".."
+}
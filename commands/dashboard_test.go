@@ -0,0 +1,132 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+	"github.com/openfaas/faas/gateway/requests"
+)
+
+func Test_dashboard_update(t *testing.T) {
+	start := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := &dashboard{}
+
+	d.update([]requests.Function{
+		{Name: "b", Image: "b:1", Replicas: 1, InvocationCount: 10},
+		{Name: "a", Image: "a:1", Replicas: 2, InvocationCount: 100},
+	}, start)
+	d.selected = 1
+
+	d.update([]requests.Function{
+		{Name: "a", Image: "a:1", Replicas: 2, InvocationCount: 120},
+		{Name: "b", Image: "b:1", Replicas: 3, InvocationCount: 10},
+		{Name: "c", Image: "c:1", Replicas: 1, InvocationCount: 5},
+	}, start.Add(10*time.Second))
+
+	want := []dashboardRow{
+		{Name: "a", Image: "a:1", Replicas: 2, Invocations: 120, Rate: 2, Deployed: true},
+		{Name: "b", Image: "b:1", Replicas: 3, Invocations: 10, Rate: 0, Deployed: true},
+		{Name: "c", Image: "c:1", Replicas: 1, Invocations: 5, Rate: 0, Deployed: true},
+	}
+	if !reflect.DeepEqual(d.rows, want) {
+		t.Errorf("want rows %+v, got %+v", want, d.rows)
+	}
+	if d.selected != 1 {
+		t.Errorf("want b to stay selected, got index %d", d.selected)
+	}
+}
+
+func Test_dashboard_update_StackFunctions(t *testing.T) {
+	d := &dashboard{services: &stack.Services{Functions: map[string]stack.Function{
+		"deployed": {Image: "deployed:1"},
+		"pending":  {Image: "pending:1"},
+	}}}
+
+	d.update([]requests.Function{
+		{Name: "deployed", Image: "deployed:1", Replicas: 1},
+		{Name: "other", Image: "other:1", Replicas: 1},
+	}, time.Now())
+
+	if len(d.rows) != 2 || d.rows[0].Name != "deployed" || !d.rows[0].Deployed || d.rows[1].Name != "pending" || d.rows[1].Deployed {
+		t.Errorf("want only the stack's functions, got %+v", d.rows)
+	}
+}
+
+func Test_parseKeys(t *testing.T) {
+	got := parseKeys([]byte("j\x1b[A\x1b[B2\r\x7f\x1b\x03"))
+	want := []string{"j", "up", "down", "2", "enter", "backspace", "esc", "ctrl+c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func Test_dashboard_handleKey_Scale(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/system/scale-function/b",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+	})
+	defer s.Close()
+
+	d := &dashboard{gateway: s.URL, rows: []dashboardRow{{Name: "a"}, {Name: "b", Replicas: 1}}}
+
+	for _, key := range []string{"down", "down", "s", "3", "x", "5", "backspace", "enter"} {
+		if d.handleKey(key) {
+			t.Fatalf("%s should not quit", key)
+		}
+	}
+
+	if d.status != "Scaled b to 3 replica(s)." {
+		t.Errorf("got status %q", d.status)
+	}
+	if !d.handleKey("q") {
+		t.Errorf("q should quit")
+	}
+}
+
+func Test_dashboard_handleKey_RedeployWithoutStack(t *testing.T) {
+	d := &dashboard{rows: []dashboardRow{{Name: "a"}}}
+	d.handleKey("r")
+
+	if d.status != "Redeploy needs a stack file, pass one with -f." {
+		t.Errorf("got status %q", d.status)
+	}
+}
+
+func Test_dashboard_render(t *testing.T) {
+	d := &dashboard{
+		gateway: "http://127.0.0.1:8080",
+		rows: []dashboardRow{
+			{Name: "figlet", Image: "functions/figlet:0.1", Replicas: 2, Invocations: 42, Rate: 1.5, Deployed: true},
+			{Name: "pending", Image: "pending:1"},
+		},
+		selected: 1,
+		status:   "Invoked figlet in 10ms:",
+		detail:   []string{"hello"},
+	}
+
+	var out bytes.Buffer
+	d.render(&out, 120, 40)
+
+	for _, want := range []string{
+		"  figlet                         functions/figlet:0.1                            2           42     1.50\r\n",
+		"> pending",
+		"not deployed",
+		"Invoked figlet in 10ms:\r\nhello\r\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("want %q in:\n%s", want, out.String())
+		}
+	}
+}
@@ -6,11 +6,20 @@ package commands
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v2"
 
+	"github.com/openfaas/faas-cli/audit"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/hooks"
+	"github.com/openfaas/faas-cli/policy"
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/spf13/cobra"
@@ -24,6 +33,33 @@ type DeployFlags struct {
 	constraints []string
 	secrets     []string
 	labelOpts   []string
+	wait        bool
+	waitTimeout time.Duration
+
+	rollbackOnFailure bool
+	readyTimeout      time.Duration
+
+	canary int
+
+	overridePolicy string
+
+	imagePrefixOverrides []string
+
+	onlyChanged bool
+
+	ordered bool
+
+	skipCompatibilityCheck bool
+
+	prefetch        bool
+	prefetchTimeout time.Duration
+
+	ttl    time.Duration
+	suffix string
+
+	receiptPath    string
+	receiptURL     string
+	receiptKeyFile string
 }
 
 var deployFlags DeployFlags
@@ -49,6 +85,25 @@ func init() {
 	deployCmd.Flags().StringArrayVar(&deployFlags.constraints, "constraint", []string{}, "Apply a constraint to the function")
 	deployCmd.Flags().StringArrayVar(&deployFlags.secrets, "secret", []string{}, "Give the function access to a secure secret")
 
+	deployCmd.Flags().BoolVar(&deployFlags.wait, "wait", false, "Wait for each function's health check to pass after deploying")
+	deployCmd.Flags().DurationVar(&deployFlags.waitTimeout, "wait-timeout", 2*time.Minute, "How long to wait for a function to become ready with --wait")
+	deployCmd.Flags().BoolVar(&deployFlags.rollbackOnFailure, "rollback-on-failure", false, "Re-deploy the previous revision of a function which does not become ready")
+	deployCmd.Flags().IntVar(&deployFlags.canary, "canary", 0, "Deploy each function as NAME"+canarySuffix+" with this percentage of its traffic, finalized with faas-cli promote")
+	deployCmd.Flags().DurationVar(&deployFlags.readyTimeout, "ready-timeout", 2*time.Minute, "How long a function has to become ready before --rollback-on-failure rolls it back")
+
+	deployCmd.Flags().StringArrayVar(&deployFlags.imagePrefixOverrides, "image-prefix-override", []string{}, "Rewrite an image registry or prefix, i.e. docker.io=internal-mirror.example.com")
+	deployCmd.Flags().BoolVar(&deployFlags.onlyChanged, "only-changed", false, "Only deploy functions whose image digest or resolved configuration differ from what is deployed")
+	deployCmd.Flags().BoolVar(&deployFlags.prefetch, "prefetch", false, "Pull each new image onto the nodes before the function is switched over to it, for providers with a prefetch API")
+	deployCmd.Flags().DurationVar(&deployFlags.prefetchTimeout, "prefetch-timeout", 10*time.Minute, "How long --prefetch waits for an image to be pulled onto every node")
+	deployCmd.Flags().BoolVar(&deployFlags.skipCompatibilityCheck, "skip-compatibility-check", false, "Do not check the watchdog recorded in each image against the gateway's version")
+	deployCmd.Flags().BoolVar(&deployFlags.ordered, "ordered", false, "Deploy functions after those in their depends_on, waiting for each dependency to become ready")
+	deployCmd.Flags().DurationVar(&deployFlags.ttl, "ttl", 0, "Record when the functions expire, i.e. 2h, so that cleanup --expired removes them")
+	deployCmd.Flags().StringVar(&deployFlags.suffix, "suffix", "", "Deploy each function as NAME-SUFFIX, i.e. a pull request's preview environment")
+	deployCmd.Flags().StringVar(&deployFlags.receiptPath, "receipt", "", "Write a signed receipt of what was deployed to this file")
+	deployCmd.Flags().StringVar(&deployFlags.receiptURL, "receipt-url", "", "POST a signed receipt of what was deployed to this webhook")
+	deployCmd.Flags().StringVar(&deployFlags.receiptKeyFile, "receipt-key-file", "", "File holding the key receipts are signed with, "+receiptKeyEnv+" is used when not given")
+	deployCmd.Flags().StringVar(&deployFlags.overridePolicy, "override-policy", "", "Deploy during a freeze window in "+policy.DefaultPolicyFile+", giving the REASON for the change")
+
 	// Set bash-completion.
 	_ = deployCmd.Flags().SetAnnotation("handler", cobra.BashCompSubdirsInDir, []string{})
 
@@ -72,12 +127,48 @@ var deployCmd = &cobra.Command{
                   [--constraint PLACEMENT_CONSTRAINT ...]
                   [--regex "REGEX"]
                   [--filter "WILDCARD"]
-				  [--secret "SECRET_NAME"]`,
+				  [--secret "SECRET_NAME"]
+                  [--wait] [--wait-timeout DURATION]
+                  [--rollback-on-failure] [--ready-timeout DURATION]
+                  [--canary WEIGHT]
+                  [--override-policy REASON]
+                  [--image-prefix-override FROM=TO ...]
+                  [--only-changed]
+                  [--ordered]
+                  [--ttl DURATION] [--suffix SUFFIX]
+                  [--receipt FILE] [--receipt-url URL] [--receipt-key-file KEY_FILE]`,
 
 	Short: "Deploy OpenFaaS functions",
 	Long: `Deploys OpenFaaS function containers either via the supplied YAML config using
 the "--yaml" flag (which may contain multiple function definitions), or directly
-via flags. Note: --replace and --update are mutually exclusive.`,
+via flags. Note: --replace and --update are mutually exclusive.
+
+With --only-changed each function's image digest is read from its registry and
+compared, along with a hash of its resolved configuration, to what was recorded
+when it was last deployed. Functions which match are skipped.
+
+With --ordered each function is deployed after the functions in its depends_on,
+and only once their health checks pass, so functions which call a dependency
+while starting up do not fail during a full rollout.
+
+With --suffix each function is deployed as NAME-SUFFIX, and with --ttl it is
+annotated with when it expires, giving short-lived previews of a stack such as
+one per pull request. "faas-cli cleanup --expired" removes them once expired.
+
+With --receipt or --receipt-url a receipt of who deployed which image digests
+and configuration hashes, when, and to which gateway is signed with an
+hmac-sha256 key and written to a file or posted to a webhook. Receipts are
+checked with "faas-cli receipt verify".
+
+With --rollback-on-failure the revision of each function already deployed is
+recorded before it is updated. When the new revision does not pass its health
+check within --ready-timeout the previous one is deployed again and the deploy
+fails.
+
+With --canary the new revision of each function is deployed alongside it as
+NAME-canary, annotated with the percentage of traffic the provider routes to
+it, or the weight in the function's canary section. The function must already
+be deployed. "faas-cli promote NAME" replaces the function with its canary.`,
 	Example: `  faas-cli deploy -f https://domain/path/myfunctions.yml
   faas-cli deploy -f ./stack.yml
   faas-cli deploy -f ./stack.yml --label canary=true
@@ -85,6 +176,15 @@ via flags. Note: --replace and --update are mutually exclusive.`,
   faas-cli deploy -f ./stack.yml --regex "fn[0-9]_.*"
   faas-cli deploy -f ./stack.yml --replace=false --update=true
   faas-cli deploy -f ./stack.yml --replace=true --update=false
+  faas-cli deploy -f ./stack.yml --wait --wait-timeout 5m
+  faas-cli deploy -f ./stack.yml --rollback-on-failure --ready-timeout 1m
+  faas-cli deploy -f ./stack.yml --filter url-ping --canary 10
+  faas-cli deploy -f ./stack.yml --override-policy "hotfix for INC-1234"
+  faas-cli deploy -f ./stack.yml --image-prefix-override docker.io=internal-mirror.example.com
+  faas-cli deploy -f ./stack.yml --only-changed
+  faas-cli deploy -f ./stack.yml --ordered
+  faas-cli deploy -f ./stack.yml --ttl 2h --suffix pr-123
+  faas-cli deploy -f ./stack.yml --receipt ./receipt.json --receipt-key-file ./receipt.key
   faas-cli deploy --image=alexellis/faas-url-ping --name=url-ping
   faas-cli deploy --image=my_image --name=my_fn --handler=/path/to/fn/
                   --gateway=http://remote-site.com:8080 --lang=python
@@ -101,7 +201,21 @@ func preRunDeploy(cmd *cobra.Command, args []string) error {
 }
 
 func runDeploy(cmd *cobra.Command, args []string) error {
-	return RunDeploy(args, image, fprocess, functionName, deployFlags)
+	if err := startReceipt(deployFlags); err != nil {
+		return err
+	}
+
+	err := RunDeploy(args, image, fprocess, functionName, deployFlags)
+
+	// The receipt covers the functions deployed before any failure
+	if receiptErr := finishReceipt(deployFlags); receiptErr != nil {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, receiptErr)
+			return err
+		}
+		return receiptErr
+	}
+	return err
 }
 
 func RunDeploy(
@@ -119,6 +233,13 @@ func RunDeploy(
 		return fmt.Errorf("cannot specify --update and --replace at the same time")
 	}
 
+	if err := validatePreview(deployFlags.ttl, deployFlags.suffix); err != nil {
+		return err
+	}
+	if err := validateCanary(deployFlags.canary, deployFlags); err != nil {
+		return err
+	}
+
 	var services stack.Services
 	if len(yamlFile) > 0 {
 		parsedServices, err := stack.ParseYAMLFile(yamlFile, regex, filter)
@@ -138,87 +259,486 @@ func RunDeploy(
 		}
 	}
 
+	changePolicy, err := loadPolicy(&services)
+	if err != nil {
+		return err
+	}
+
 	if len(services.Functions) > 0 {
-		if len(services.Provider.Network) == 0 {
-			services.Provider.Network = defaultNetwork
+		if err := deployStack(&services, deployFlags, changePolicy); err != nil {
+			return err
+		}
+	} else {
+		if len(image) == 0 {
+			return fmt.Errorf("please provide a --image to be deployed")
+		}
+		if len(functionName) == 0 {
+			return fmt.Errorf("please provide a --name for your function as it will be deployed on FaaS")
+		}
+
+		envvars, err := parseMap(deployFlags.envvarOpts, "env")
+		if err != nil {
+			return fmt.Errorf("error parsing envvars: %v", err)
 		}
 
-		for k, function := range services.Functions {
+		labelMap, labelErr := parseMap(deployFlags.labelOpts, "label")
+		if labelErr != nil {
+			return fmt.Errorf("error parsing labels: %v", labelErr)
+		}
 
-			function.Name = k
-			fmt.Printf("Deploying: %s.\n", function.Name)
+		functionName = previewName(functionName, deployFlags.suffix)
 
-			var functionConstraints []string
-			if function.Constraints != nil {
-				functionConstraints = *function.Constraints
-			} else if len(deployFlags.constraints) > 0 {
-				functionConstraints = deployFlags.constraints
+		if err := enforceEnvironmentPolicy(changePolicy, []policy.Workload{{Name: functionName, Labels: labelMap, Environment: envvars}}); err != nil {
+			return err
+		}
+
+		annotations, policyErr := enforcePolicy(changePolicy, functionName, deployFlags.overridePolicy)
+		if policyErr != nil {
+			return policyErr
+		}
+		annotations = mergeMap(annotations, previewAnnotations(deployFlags.ttl, deployFlags.suffix, time.Now()))
+
+		overrides, overrideErr := imageOverrides(deployFlags.imagePrefixOverrides)
+		if overrideErr != nil {
+			return overrideErr
+		}
+		image = overriddenImage(image, overrides)
+
+		if !deployFlags.skipCompatibilityCheck {
+			newCompatibilityChecker(gateway).warn(functionName, image)
+		}
+
+		if deployFlags.prefetch {
+			request := proxy.PrefetchRequest{Image: image, Namespace: functionNamespace, Constraints: deployFlags.constraints}
+			if err := newPrefetcher(gateway, deployFlags.prefetchTimeout).prefetch(functionName, request); err != nil {
+				return err
 			}
+		}
 
-			if len(function.Secrets) > 0 {
-				deployFlags.secrets = mergeSlice(function.Secrets, deployFlags.secrets)
+		if err := runHooks(hooks.Event{Hook: hooks.PreDeploy, Action: audit.Deploy, Gateway: gateway, Function: functionName, Image: image}); err != nil {
+			return err
+		}
+
+		previous, err := previousRevisions(gateway, []string{functionNamespace}, deployFlags)
+		if err != nil {
+			return err
+		}
+
+		started := time.Now()
+		spec := &proxy.DeployFunctionSpec{
+			FProcess:     fprocess,
+			FunctionName: functionName,
+			Namespace:    functionNamespace,
+			Image:        image,
+			Language:     language,
+			Replace:      deployFlags.replace,
+			EnvVars:      envvars,
+			Network:      network,
+			Constraints:  deployFlags.constraints,
+			Update:       deployFlags.update,
+			Secrets:      deployFlags.secrets,
+			Labels:       labelMap,
+			Annotations:  annotations,
+		}
+		if err := adaptForFaasd(gateway, spec); err != nil {
+			return err
+		}
+		if deployFlags.canary > 0 {
+			if err := applyCanary(gateway, spec, deployFlags.canary); err != nil {
+				return err
 			}
+		}
+		statusCode := proxy.DeployFunction(gateway, spec)
+		observeDeploy(spec.FunctionName, statusCode, started)
+		recordDeploy(gateway, spec, statusCode)
 
-			fileEnvironment, err := readFiles(function.EnvironmentFile)
-			if err != nil {
+		if deployFlags.rollbackOnFailure && deploySucceeded(statusCode) {
+			if err := verifyOrRollback(gateway, network, spec.FunctionName, spec.Namespace, nil, previous, deployFlags.readyTimeout); err != nil {
 				return err
 			}
+		} else if deployFlags.wait && deploySucceeded(statusCode) {
+			if err := waitForFunction(gateway, spec.FunctionName, spec.Namespace, nil, deployFlags.waitTimeout); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deployStack deploys each of the functions in the parsed stack, a nil
+// changePolicy allows every deployment
+func deployStack(services *stack.Services, deployFlags DeployFlags, changePolicy *policy.Policy) error {
+	_, err := deployFunctions(services, deployFlags, changePolicy)
+	return err
+}
+
+// deployFunctions deploys the stack as deployStack does and also returns the
+// functions which the provider failed to deploy
+func deployFunctions(services *stack.Services, deployFlags DeployFlags, changePolicy *policy.Policy) (map[string]bool, error) {
+	// ready and failed track dependencies for --ordered
+	ready := map[string]bool{}
+	failed := map[string]bool{}
+
+	if len(services.Provider.Network) == 0 {
+		services.Provider.Network = defaultNetwork
+	}
+
+	overrides, overrideErr := imageOverrides(deployFlags.imagePrefixOverrides)
+	if overrideErr != nil {
+		return failed, overrideErr
+	}
+
+	applyPreviewSuffix(services, deployFlags.suffix)
+	preview := previewAnnotations(deployFlags.ttl, deployFlags.suffix, time.Now())
+
+	var deployed map[string]deployedFunction
+	skipped := 0
+	if deployFlags.onlyChanged {
+		var err error
+		if deployed, err = deployedFunctions(services.Provider.GatewayURL, stackNamespaces(services)); err != nil {
+			return failed, fmt.Errorf("unable to list the deployed functions for --only-changed: %s", err)
+		}
+	}
+
+	names, err := stack.DeploymentOrder(services.Functions)
+	if err != nil {
+		return failed, err
+	}
+
+	workloads, err := stackWorkloads(services, names, deployFlags)
+	if err != nil {
+		return failed, err
+	}
+	if err := enforceEnvironmentPolicy(changePolicy, workloads); err != nil {
+		return failed, err
+	}
+
+	previous, err := previousRevisions(services.Provider.GatewayURL, stackNamespaces(services), deployFlags)
+	if err != nil {
+		return failed, err
+	}
+
+	pullSecrets, err := registryPullSecrets(*services)
+	if err != nil {
+		return failed, err
+	}
+
+	var compatibility *compatibilityChecker
+	if !deployFlags.skipCompatibilityCheck {
+		compatibility = newCompatibilityChecker(services.Provider.GatewayURL)
+	}
+
+	var prefetch *prefetcher
+	if deployFlags.prefetch {
+		prefetch = newPrefetcher(services.Provider.GatewayURL, deployFlags.prefetchTimeout)
+	}
+
+	for _, k := range names {
+		function := services.Functions[k]
+		function.Name = k
+
+		var functionConstraints []string
+		if function.Constraints != nil {
+			functionConstraints = *function.Constraints
+		} else if len(deployFlags.constraints) > 0 {
+			functionConstraints = deployFlags.constraints
+		}
+
+		// Secrets are merged per function so that the config hash does not
+		// depend on the order functions are deployed in
+		functionSecrets := deployFlags.secrets
+		if len(function.Secrets) > 0 {
+			functionSecrets = mergeSlice(function.Secrets, deployFlags.secrets)
+		}
+		// The pull secret of the function's registry credential lets the
+		// provider pull its image from a private registry
+		if pullSecret, ok := pullSecrets[k]; ok {
+			functionSecrets = mergeSlice([]string{pullSecret}, functionSecrets)
+		}
+
+		fileEnvironment, err := readFiles(function.EnvironmentFile)
+		if err != nil {
+			return failed, err
+		}
 
-			labelMap := map[string]string{}
-			if function.Labels != nil {
-				labelMap = *function.Labels
+		labelMap := map[string]string{}
+		if function.Labels != nil {
+			labelMap = *function.Labels
+		}
+
+		labelArgumentMap, labelErr := parseMap(deployFlags.labelOpts, "label")
+		if labelErr != nil {
+			return failed, fmt.Errorf("error parsing labels: %v", labelErr)
+		}
+
+		allLabels := mergeMap(labelMap, labelArgumentMap)
+
+		allEnvironment, envErr := compileEnvironment(deployFlags.envvarOpts, functionEnvironment(function), fileEnvironment)
+		if envErr != nil {
+			return failed, envErr
+		}
+
+		// Get FProcess to use from the template's template.yml, if a template is
+		// being used, unless the function gives its own
+		if languageExistsNotDockerfile(function.Language) {
+			if len(function.FProcess) > 0 {
+				if _, fprocessErr := checkFProcess(function); fprocessErr != nil {
+					return failed, fprocessErr
+				}
+			} else {
+				templateFProcess, fprocessErr := deriveFprocess(function)
+				if fprocessErr != nil {
+					return failed, fprocessErr
+				}
+				function.FProcess = templateFProcess
 			}
 
-			labelArgumentMap, labelErr := parseMap(deployFlags.labelOpts, "label")
-			if labelErr != nil {
-				return fmt.Errorf("error parsing labels: %v", labelErr)
+			if watchdogErr := checkWatchdog(function); watchdogErr != nil {
+				return failed, watchdogErr
 			}
+		}
 
-			allLabels := mergeMap(labelMap, labelArgumentMap)
+		// The function's own annotations win over those of its metadata
+		annotations := function.Metadata.Annotations()
+		if function.Annotations != nil {
+			annotations = mergeMap(annotations, *function.Annotations)
+		}
 
-			allEnvironment, envErr := compileEnvironment(deployFlags.envvarOpts, function.Environment, fileEnvironment)
-			if envErr != nil {
-				return envErr
+		healthCheckAnnotations, healthCheckErr := healthCheckAnnotations(function.HealthCheck)
+		if healthCheckErr != nil {
+			return failed, fmt.Errorf("function %s: %s", function.Name, healthCheckErr)
+		}
+		annotations = mergeMap(annotations, healthCheckAnnotations)
+		annotations = mergeMap(annotations, preview)
+
+		function.Image = overriddenImage(function.Image, overrides)
+
+		functionResourceRequest1 := proxy.FunctionResourceRequest{
+			Limits:   function.Limits,
+			Requests: function.Requests,
+		}
+
+		spec := &proxy.DeployFunctionSpec{
+			FProcess:                function.FProcess,
+			FunctionName:            function.Name,
+			Namespace:               namespaceOf(function),
+			Image:                   function.Image,
+			Language:                function.Language,
+			Replace:                 deployFlags.replace,
+			EnvVars:                 allEnvironment,
+			Network:                 services.Provider.Network,
+			Constraints:             functionConstraints,
+			Update:                  deployFlags.update,
+			Secrets:                 functionSecrets,
+			Labels:                  allLabels,
+			Annotations:             annotations,
+			FunctionResourceRequest: functionResourceRequest1,
+		}
+		if err := adaptForFaasd(services.Provider.GatewayURL, spec); err != nil {
+			return failed, err
+		}
+
+		// The hash leaves out policy annotations, so unchanged functions
+		// are skipped without needing an override during a freeze
+		hash := configHash(spec)
+		reason := ""
+		if deployFlags.onlyChanged {
+			digest, digestErr := registryDigest(function.Image)
+			if digestErr != nil {
+				return failed, fmt.Errorf("unable to read the digest of %s for %s: %s", function.Image, function.Name, digestErr)
 			}
+			deployedFunction, found := deployed[qualifiedName(function.Name, spec.Namespace)]
+			reason = changeReason(deployedFunction, found, digest, hash)
+			if len(reason) == 0 {
+				fmt.Printf("Skipping: %s, its image digest and configuration are unchanged.\n", function.Name)
+				skipped++
+				continue
+			}
+			annotations[imageDigestAnnotation] = digest
+		}
+		annotations[configHashAnnotation] = hash
 
-			// Get FProcess to use from the ./template/template.yml, if a template is being used
-			if languageExistsNotDockerfile(function.Language) {
-				var fprocessErr error
-				function.FProcess, fprocessErr = deriveFprocess(function)
-				if fprocessErr != nil {
-					return fprocessErr
-				}
+		policyAnnotations, policyErr := enforcePolicy(changePolicy, function.Name, deployFlags.overridePolicy)
+		if policyErr != nil {
+			return failed, policyErr
+		}
+		spec.Annotations = mergeMap(annotations, policyAnnotations)
+
+		weight := 0
+		if deployFlags.canary > 0 {
+			weight = canaryWeight(deployFlags.canary, function)
+			if err := applyCanary(services.Provider.GatewayURL, spec, weight); err != nil {
+				return failed, err
 			}
+		}
+
+		if compatibility != nil {
+			compatibility.warn(function.Name, function.Image)
+		}
 
-			functionResourceRequest1 := proxy.FunctionResourceRequest{
-				Limits:   function.Limits,
-				Requests: function.Requests,
+		if deployFlags.ordered {
+			if err := waitForDependencies(services, function, ready, failed, deployFlags.waitTimeout); err != nil {
+				return failed, err
 			}
+		}
 
-			proxy.DeployFunction(function.FProcess, services.Provider.GatewayURL, function.Name, function.Image, function.Language, deployFlags.replace, allEnvironment, services.Provider.Network, functionConstraints, deployFlags.update, deployFlags.secrets, allLabels, functionResourceRequest1)
+		if prefetch != nil {
+			if err := prefetch.prefetch(function.Name, proxy.PrefetchRequest{Image: spec.Image, Namespace: spec.Namespace, Constraints: spec.Constraints}); err != nil {
+				return failed, err
+			}
 		}
-	} else {
-		if len(image) == 0 {
-			return fmt.Errorf("please provide a --image to be deployed")
+
+		if err := runHooks(hooks.Event{Hook: hooks.PreDeploy, Action: audit.Deploy, Gateway: services.Provider.GatewayURL, Function: function.Name, Image: function.Image}); err != nil {
+			return failed, err
 		}
-		if len(functionName) == 0 {
-			return fmt.Errorf("please provide a --name for your function as it will be deployed on FaaS")
+
+		if len(reason) > 0 {
+			fmt.Printf("Deploying: %s (%s).\n", function.Name, reason)
+		} else if weight > 0 {
+			fmt.Printf("Deploying: %s as %s with %d%% of its traffic.\n", function.Name, spec.FunctionName, weight)
+		} else {
+			fmt.Printf("Deploying: %s.\n", function.Name)
 		}
 
-		envvars, err := parseMap(deployFlags.envvarOpts, "env")
+		started := time.Now()
+		statusCode := proxy.DeployFunction(services.Provider.GatewayURL, spec)
+		observeDeploy(spec.FunctionName, statusCode, started)
+		recordDeploy(services.Provider.GatewayURL, spec, statusCode)
+
+		if !deploySucceeded(statusCode) {
+			failed[function.Name] = true
+		} else if deployFlags.rollbackOnFailure {
+			if err := verifyOrRollback(services.Provider.GatewayURL, services.Provider.Network, spec.FunctionName, spec.Namespace, function.HealthCheck, previous, deployFlags.readyTimeout); err != nil {
+				return failed, err
+			}
+			ready[function.Name] = true
+		} else if deployFlags.wait {
+			if err := waitForFunction(services.Provider.GatewayURL, spec.FunctionName, spec.Namespace, function.HealthCheck, deployFlags.waitTimeout); err != nil {
+				return failed, err
+			}
+			ready[function.Name] = true
+		}
+	}
+
+	if deployFlags.onlyChanged {
+		fmt.Printf("%d function(s) unchanged and skipped.\n", skipped)
+	}
+	return failed, nil
+}
+
+// waitForDependencies waits for each of the function's dependencies in the
+// stack to become ready, those left out by --filter are not waited for
+func waitForDependencies(services *stack.Services, function stack.Function, ready map[string]bool, failed map[string]bool, timeout time.Duration) error {
+	for _, dependency := range function.DependsOn {
+		dependencyFunction, ok := services.Functions[dependency]
+		if !ok || ready[dependency] {
+			continue
+		}
+		if failed[dependency] {
+			return fmt.Errorf("not deploying %s, its dependency %s failed to deploy", function.Name, dependency)
+		}
+
+		if err := waitForFunction(services.Provider.GatewayURL, dependency, namespaceOf(dependencyFunction), dependencyFunction.HealthCheck, timeout); err != nil {
+			return fmt.Errorf("not deploying %s, its dependency %s did not become ready: %s", function.Name, dependency, err)
+		}
+		ready[dependency] = true
+	}
+	return nil
+}
+
+func deploySucceeded(statusCode int) bool {
+	return statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices
+}
+
+// recordDeploy records the outcome of a deployment in the audit log and any
+// receipt being collected, and runs the on-failure hooks when it failed
+func recordDeploy(gateway string, spec *proxy.DeployFunctionSpec, statusCode int) {
+	recordAudit(audit.Entry{
+		Action:     audit.Deploy,
+		Gateway:    gateway,
+		Function:   spec.FunctionName,
+		Image:      spec.Image,
+		Digest:     auditDigest(spec.Image, spec.Annotations),
+		StatusCode: statusCode,
+		Success:    deploySucceeded(statusCode),
+	})
+	addToReceipt(gateway, spec, statusCode)
+
+	if !deploySucceeded(statusCode) {
+		runHooks(hooks.Event{Hook: hooks.OnFailure, Action: audit.Deploy, Gateway: gateway, Function: spec.FunctionName, Image: spec.Image, StatusCode: statusCode})
+	}
+}
+
+// healthCheckAnnotations maps a function's health check onto the annotations
+// read by the provider when it configures probes
+func healthCheckAnnotations(healthCheck *stack.HealthCheck) (map[string]string, error) {
+	annotations := map[string]string{}
+	if healthCheck == nil {
+		return annotations, nil
+	}
+
+	if len(healthCheck.Path) > 0 {
+		annotations["com.openfaas.health.http.path"] = healthCheck.Path
+	}
+
+	if len(healthCheck.InitialDelay) > 0 {
+		if _, err := time.ParseDuration(healthCheck.InitialDelay); err != nil {
+			return nil, fmt.Errorf("invalid healthcheck initial_delay: %s", err)
+		}
+		annotations["com.openfaas.health.http.initialDelay"] = healthCheck.InitialDelay
+	}
+
+	if len(healthCheck.Interval) > 0 {
+		interval, err := time.ParseDuration(healthCheck.Interval)
 		if err != nil {
-			return fmt.Errorf("error parsing envvars: %v", err)
+			return nil, fmt.Errorf("invalid healthcheck interval: %s", err)
 		}
+		annotations["com.openfaas.health.http.periodSeconds"] = strconv.Itoa(int(math.Ceil(interval.Seconds())))
+	}
 
-		labelMap, labelErr := parseMap(deployFlags.labelOpts, "label")
-		if labelErr != nil {
-			return fmt.Errorf("error parsing labels: %v", labelErr)
+	return annotations, nil
+}
+
+// waitForFunction probes the health path of the function in the namespace
+// until it passes or the timeout is reached
+func waitForFunction(gateway string, functionName string, namespace string, healthCheck *stack.HealthCheck, timeout time.Duration) error {
+	healthPath := proxy.DefaultHealthPath
+	interval := time.Second
+	var initialDelay time.Duration
+
+	if healthCheck != nil {
+		if len(healthCheck.Path) > 0 {
+			healthPath = healthCheck.Path
+		}
+		if parsed, err := time.ParseDuration(healthCheck.Interval); err == nil && parsed > 0 {
+			interval = parsed
+		}
+		if parsed, err := time.ParseDuration(healthCheck.InitialDelay); err == nil {
+			initialDelay = parsed
 		}
-		functionResourceRequest1 := proxy.FunctionResourceRequest{}
-		proxy.DeployFunction(fprocess, gateway, functionName, image, language, deployFlags.replace, envvars, network, deployFlags.constraints, deployFlags.update, deployFlags.secrets, labelMap, functionResourceRequest1)
 	}
 
-	return nil
+	functionName = qualifiedName(functionName, namespace)
+	fmt.Printf("Waiting for %s to become ready on %s.\n", functionName, healthPath)
+	deadline := time.Now().Add(timeout)
+	time.Sleep(initialDelay)
+
+	for {
+		ready, err := proxy.FunctionReady(gateway, functionName, healthPath)
+		if err != nil {
+			return err
+		}
+		if ready {
+			fmt.Printf("Function %s is ready.\n", functionName)
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("function %s did not become ready within %s", functionName, timeout)
+		}
+		time.Sleep(interval)
+	}
 }
 
 func mergeSlice(values []string, overlay []string) []string {
@@ -238,21 +758,38 @@ func mergeSlice(values []string, overlay []string) []string {
 	return results
 }
 
+// readFiles merges the environment of each environment_file, a later file
+// overrides the variables of those before it. Files are YAML with an
+// environment map, or dotenv files such as prod.env or .env.staging.
 func readFiles(files []string) (map[string]string, error) {
 	envs := make(map[string]string)
 
 	for _, file := range files {
 		bytesOut, readErr := ioutil.ReadFile(file)
 		if readErr != nil {
-			return nil, readErr
+			if os.IsNotExist(readErr) {
+				return nil, fmt.Errorf("environment_file %s was not found", file)
+			}
+			return nil, fmt.Errorf("unable to read environment_file %s: %s", file, readErr)
 		}
 
-		envFile := stack.EnvironmentFile{}
-		unmarshalErr := yaml.Unmarshal(bytesOut, &envFile)
-		if unmarshalErr != nil {
-			return nil, unmarshalErr
+		fileEnvironment := map[string]string{}
+		if stack.IsDotEnv(file) {
+			parsed, parseErr := stack.ParseDotEnv(bytesOut)
+			if parseErr != nil {
+				return nil, fmt.Errorf("unable to parse environment_file %s: %s", file, parseErr)
+			}
+			fileEnvironment = parsed
+		} else {
+			envFile := stack.EnvironmentFile{}
+			unmarshalErr := yaml.Unmarshal(bytesOut, &envFile)
+			if unmarshalErr != nil {
+				return nil, fmt.Errorf("unable to parse environment_file %s: %s", file, unmarshalErr)
+			}
+			fileEnvironment = envFile.Environment
 		}
-		for k, v := range envFile.Environment {
+
+		for k, v := range fileEnvironment {
 			envs[k] = v
 		}
 	}
@@ -304,6 +841,13 @@ func getGatewayURL(argumentURL string, defaultURL string, yamlURL string) string
 		gatewayURL = defaultURL
 	}
 
+	// A name such as "staging" is a profile saved by login --profile
+	if len(gatewayURL) > 0 && !strings.Contains(gatewayURL, "://") {
+		if contextURL, err := config.LookupContext(gatewayURL); err == nil {
+			gatewayURL = contextURL
+		}
+	}
+
 	gatewayURL = strings.ToLower(strings.TrimRight(gatewayURL, "/"))
 	if !strings.HasPrefix(gatewayURL, "http") {
 		gatewayURL = fmt.Sprintf("http://%s", gatewayURL)
@@ -312,6 +856,12 @@ func getGatewayURL(argumentURL string, defaultURL string, yamlURL string) string
 	return gatewayURL
 }
 
+// functionEnvironment gives the function's environment in the YAML file, its
+// logging block is overridden by variables set in environment
+func functionEnvironment(function stack.Function) map[string]string {
+	return mergeMap(function.Logging.Environment(), function.Environment)
+}
+
 func compileEnvironment(envvarOpts []string, yamlEnvironment map[string]string, fileEnvironment map[string]string) (map[string]string, error) {
 	envvarArguments, err := parseMap(envvarOpts, "env")
 	if err != nil {
@@ -325,7 +875,7 @@ func compileEnvironment(envvarOpts []string, yamlEnvironment map[string]string,
 func deriveFprocess(function stack.Function) (string, error) {
 	var fprocess string
 
-	pathToTemplateYAML := "./template/" + function.Language + "/template.yml"
+	pathToTemplateYAML := filepath.Join(stack.TemplateDirectory, function.Language, "template.yml")
 	if _, err := os.Stat(pathToTemplateYAML); os.IsNotExist(err) {
 		return "", err
 	}
@@ -346,6 +896,28 @@ func deriveFprocess(function stack.Function) (string, error) {
 	return fprocess, nil
 }
 
+// checkWatchdog makes sure the function gives the watchdog of its template
+// what it needs and warns about settings the watchdog ignores
+func checkWatchdog(function stack.Function) error {
+	watchdog, err := stack.TemplateWatchdog(function.Language)
+	if err != nil {
+		return fmt.Errorf("function %s: template %s: %s", function.Name, function.Language, err)
+	}
+
+	if len(function.FProcess) == 0 && watchdog.NeedsFProcess() && !watchdog.FProcessInImage {
+		return fmt.Errorf("function %s: the %s watchdog of template %s needs an fprocess, set it in the template's template.yml or the function's fprocess",
+			function.Name, watchdog, function.Language)
+	}
+
+	if function.HealthCheck != nil && len(function.HealthCheck.Path) > 0 &&
+		function.HealthCheck.Path != proxy.DefaultHealthPath && !watchdog.CustomHealthPath() {
+		fmt.Printf("Warning: function %s uses the %s watchdog which only answers health checks on %s, healthcheck path %s will not be served.\n",
+			function.Name, watchdog, proxy.DefaultHealthPath, function.HealthCheck.Path)
+	}
+
+	return nil
+}
+
 func languageExistsNotDockerfile(language string) bool {
 	return len(language) > 0 && strings.ToLower(language) != "dockerfile"
 }
@@ -0,0 +1,70 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// Annotations on a canary which the provider reads to route a share of the
+// primary function's traffic to it
+const (
+	canaryPrimaryAnnotation = "com.openfaas.canary.primary"
+	canaryWeightAnnotation  = "com.openfaas.canary.weight"
+)
+
+// validateCanary checks the weight given to deploy --canary, 0 deploys as
+// usual
+func validateCanary(weight int, deployFlags DeployFlags) error {
+	if weight == 0 {
+		return nil
+	}
+	if weight < 0 || weight > 99 {
+		return fmt.Errorf("--canary must be a percentage of traffic from 1 to 99, got %d", weight)
+	}
+	if deployFlags.onlyChanged {
+		return fmt.Errorf("--canary cannot be used with --only-changed")
+	}
+	if deployFlags.replace {
+		return fmt.Errorf("--canary cannot be used with --replace")
+	}
+	return nil
+}
+
+// canaryWeight gives the share of traffic for a function's canary, the
+// weight in its canary section takes the place of the flag's
+func canaryWeight(weight int, function stack.Function) int {
+	if function.Canary != nil && function.Canary.Weight > 0 {
+		return function.Canary.Weight
+	}
+	return weight
+}
+
+// applyCanary deploys the spec as the canary of its function, which must
+// already be deployed, annotated with the share of traffic it receives
+func applyCanary(gateway string, spec *proxy.DeployFunctionSpec, weight int) error {
+	if weight < 1 || weight > 99 {
+		return fmt.Errorf("the canary weight of %s must be from 1 to 99, got %d", spec.FunctionName, weight)
+	}
+
+	_, found, err := proxy.GetFunctionInfo(gateway, spec.FunctionName)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("unable to deploy a canary of %s, it is not deployed yet", spec.FunctionName)
+	}
+
+	primary := spec.FunctionName
+	spec.FunctionName = primary + canarySuffix
+	spec.Annotations = mergeMap(spec.Annotations, map[string]string{
+		canaryPrimaryAnnotation: primary,
+		canaryWeightAnnotation:  strconv.Itoa(weight),
+	})
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+type deployedSpec struct {
+	Service     string            `json:"service"`
+	Image       string            `json:"image"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+func Test_deployStack_Canary(t *testing.T) {
+	var deployed []deployedSpec
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/system/function/api":
+			w.Write([]byte(`{"name":"api","image":"api:1"}`))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/system/function/"):
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPut && r.URL.Path == "/system/functions":
+			var spec deployedSpec
+			json.NewDecoder(r.Body).Decode(&spec)
+			deployed = append(deployed, spec)
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"api": {Image: "api:2", Canary: &stack.Canary{Weight: 5}},
+		},
+	}
+
+	var deployErr error
+	stdOut := test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, canary: 10}, nil)
+	})
+	if deployErr != nil {
+		t.Fatal(deployErr)
+	}
+
+	if len(deployed) != 1 || deployed[0].Service != "api-canary" || deployed[0].Image != "api:2" {
+		t.Fatalf("want api:2 deployed as api-canary, got %+v", deployed)
+	}
+	if got := deployed[0].Annotations; got[canaryPrimaryAnnotation] != "api" || got[canaryWeightAnnotation] != "5" {
+		t.Errorf("want the weight from the stack, got annotations %v", got)
+	}
+	if !strings.Contains(stdOut, "Deploying: api as api-canary with 5% of its traffic.") {
+		t.Errorf("unexpected output:\n%s", stdOut)
+	}
+
+	services.Functions = map[string]stack.Function{"web": {Image: "web:2"}}
+	test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, canary: 10}, nil)
+	})
+	if deployErr == nil || deployErr.Error() != "unable to deploy a canary of web, it is not deployed yet" {
+		t.Errorf("got error %v", deployErr)
+	}
+}
+
+func Test_validateCanary(t *testing.T) {
+	cases := []struct {
+		weight int
+		flags  DeployFlags
+		err    string
+	}{
+		{weight: 0, flags: DeployFlags{onlyChanged: true}},
+		{weight: 10, flags: DeployFlags{update: true}},
+		{weight: 100, err: "--canary must be a percentage of traffic from 1 to 99, got 100"},
+		{weight: -5, err: "--canary must be a percentage of traffic from 1 to 99, got -5"},
+		{weight: 10, flags: DeployFlags{onlyChanged: true}, err: "--canary cannot be used with --only-changed"},
+		{weight: 10, flags: DeployFlags{replace: true}, err: "--canary cannot be used with --replace"},
+	}
+
+	for _, c := range cases {
+		err := validateCanary(c.weight, c.flags)
+		if (err == nil && len(c.err) > 0) || (err != nil && err.Error() != c.err) {
+			t.Errorf("weight %d: want error %q, got %v", c.weight, c.err, err)
+		}
+	}
+}
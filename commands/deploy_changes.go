@@ -0,0 +1,128 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+// Annotations recorded on deploy so that --only-changed can tell whether a
+// function needs to be deployed again
+const (
+	imageDigestAnnotation = "com.openfaas.image-digest"
+	configHashAnnotation  = "com.openfaas.config-hash"
+)
+
+// registryDigest reads the digest of an image's manifest from its registry
+var registryDigest = func(image string) (string, error) {
+	out, err := exec.Command("docker", "buildx", "imagetools", "inspect", "--format", "{{json .Manifest}}", image).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("%s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", err
+	}
+	return parseManifestDigest(out)
+}
+
+func parseManifestDigest(manifest []byte) (string, error) {
+	var parsed struct {
+		Digest string `json:"digest"`
+	}
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		return "", fmt.Errorf("unable to read the manifest: %s", err)
+	}
+	if len(parsed.Digest) == 0 {
+		return "", fmt.Errorf("the manifest has no digest")
+	}
+	return parsed.Digest, nil
+}
+
+// deployedFunction is what a previous deploy recorded about a function
+type deployedFunction struct {
+	Digest     string
+	ConfigHash string
+}
+
+// deployedFunctions reads the recorded digest and config hash of each
+// function deployed to the namespaces, keyed by qualified name
+func deployedFunctions(gateway string, namespaces []string) (map[string]deployedFunction, error) {
+	statuses, err := functionStatusInNamespaces(gateway, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	deployed := map[string]deployedFunction{}
+	for name, status := range statuses {
+		deployed[name] = deployedFunction{
+			Digest:     status.Annotations[imageDigestAnnotation],
+			ConfigHash: status.Annotations[configHashAnnotation],
+		}
+	}
+	return deployed, nil
+}
+
+// configHash hashes the resolved spec of a function, leaving out the deploy
+// mode and the annotations recorded by the CLI itself, including the expiry
+// which changes on every deploy
+func configHash(spec *proxy.DeployFunctionSpec) string {
+	annotations := map[string]string{}
+	for key, value := range spec.Annotations {
+		if key != imageDigestAnnotation && key != configHashAnnotation && key != expiresAnnotation {
+			annotations[key] = value
+		}
+	}
+
+	resolved := struct {
+		FProcess    string
+		Image       string
+		EnvVars     map[string]string
+		Network     string
+		Constraints []string
+		Secrets     []string
+		Labels      map[string]string
+		Annotations map[string]string
+		Resources   proxy.FunctionResourceRequest
+	}{
+		FProcess:    spec.FProcess,
+		Image:       spec.Image,
+		EnvVars:     spec.EnvVars,
+		Network:     spec.Network,
+		Constraints: spec.Constraints,
+		Secrets:     spec.Secrets,
+		Labels:      spec.Labels,
+		Annotations: annotations,
+		Resources:   spec.FunctionResourceRequest,
+	}
+
+	// Maps are marshalled with sorted keys, so the hash is stable
+	data, _ := json.Marshal(resolved)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// changeReason explains why a function has to be deployed, it is empty when
+// the deployed digest and config hash both match
+func changeReason(deployed deployedFunction, found bool, digest string, hash string) string {
+	switch {
+	case !found:
+		return "not deployed"
+	case len(deployed.Digest) == 0 || len(deployed.ConfigHash) == 0:
+		return "no digest recorded by a previous deploy"
+	case deployed.Digest != digest && deployed.ConfigHash != hash:
+		return "image digest and configuration changed"
+	case deployed.Digest != digest:
+		return "image digest changed"
+	case deployed.ConfigHash != hash:
+		return "configuration changed"
+	}
+	return ""
+}
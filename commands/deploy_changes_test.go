@@ -0,0 +1,143 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_parseManifestDigest(t *testing.T) {
+	digest, err := parseManifestDigest([]byte(`{"mediaType":"application/vnd.oci.image.index.v1+json","digest":"sha256:abc","size":100}`))
+	if err != nil || digest != "sha256:abc" {
+		t.Errorf("want sha256:abc, got %q, %v", digest, err)
+	}
+
+	if _, err := parseManifestDigest([]byte(`{}`)); err == nil {
+		t.Errorf("want an error for a manifest without a digest")
+	}
+}
+
+func Test_configHash(t *testing.T) {
+	spec := func() *proxy.DeployFunctionSpec {
+		return &proxy.DeployFunctionSpec{
+			FunctionName: "fn",
+			Image:        "alexellis/fn:0.1",
+			EnvVars:      map[string]string{"a": "1", "b": "2"},
+			Labels:       map[string]string{},
+			Annotations:  map[string]string{"topic": "cron"},
+		}
+	}
+
+	base := configHash(spec())
+
+	recorded := spec()
+	recorded.Annotations[imageDigestAnnotation] = "sha256:abc"
+	recorded.Annotations[configHashAnnotation] = base
+	recorded.Update = true
+	if configHash(recorded) != base {
+		t.Errorf("the recorded annotations and deploy mode should not change the hash")
+	}
+
+	changed := spec()
+	changed.EnvVars["b"] = "3"
+	if configHash(changed) == base {
+		t.Errorf("an environment change should change the hash")
+	}
+}
+
+func Test_changeReason(t *testing.T) {
+	recorded := deployedFunction{Digest: "sha256:a", ConfigHash: "h1"}
+
+	testCases := []struct {
+		name     string
+		deployed deployedFunction
+		found    bool
+		digest   string
+		hash     string
+		want     string
+	}{
+		{name: "not deployed", want: "not deployed"},
+		{name: "nothing recorded", found: true, digest: "sha256:a", hash: "h1", want: "no digest recorded by a previous deploy"},
+		{name: "unchanged", deployed: recorded, found: true, digest: "sha256:a", hash: "h1", want: ""},
+		{name: "new digest", deployed: recorded, found: true, digest: "sha256:b", hash: "h1", want: "image digest changed"},
+		{name: "new config", deployed: recorded, found: true, digest: "sha256:a", hash: "h2", want: "configuration changed"},
+		{name: "both", deployed: recorded, found: true, digest: "sha256:b", hash: "h2", want: "image digest and configuration changed"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			got := changeReason(testCase.deployed, testCase.found, testCase.digest, testCase.hash)
+			if got != testCase.want {
+				t.Errorf("want %q, got %q", testCase.want, got)
+			}
+		})
+	}
+}
+
+func Test_deployStack_OnlyChanged(t *testing.T) {
+	oldDigest := registryDigest
+	defer func() { registryDigest = oldDigest }()
+	registryDigest = func(image string) (string, error) {
+		return "sha256:" + image, nil
+	}
+
+	unchangedHash := configHash(&proxy.DeployFunctionSpec{
+		Image:   "unchanged:1",
+		EnvVars: map[string]string{},
+		Network: defaultNetwork,
+		Labels:  map[string]string{},
+	})
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "unchanged", Annotations: map[string]string{
+					imageDigestAnnotation: "sha256:unchanged:1",
+					configHashAnnotation:  unchangedHash,
+				}},
+			},
+		},
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"unchanged": {Image: "unchanged:1"},
+			"changed":   {Image: "changed:2"},
+		},
+	}
+
+	var deployErr error
+	stdOut := test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, onlyChanged: true}, nil)
+	})
+	if deployErr != nil {
+		t.Fatalf("got error %s", deployErr)
+	}
+
+	for _, want := range []string{
+		"Skipping: unchanged, its image digest and configuration are unchanged.",
+		"Deploying: changed (not deployed).",
+		"1 function(s) unchanged and skipped.",
+	} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in output:\n%s", want, stdOut)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+// prefetchPollInterval is how often a prefetch is checked, it is swapped out
+// in tests
+var prefetchPollInterval = 2 * time.Second
+
+// prefetcher pulls the image of each function onto the nodes before the
+// function is switched over to it, so that its new replicas start without a
+// long pull. Each image is pulled once per deploy.
+type prefetcher struct {
+	gateway string
+	timeout time.Duration
+
+	unsupported bool
+	pulled      map[string]bool
+}
+
+func newPrefetcher(gateway string, timeout time.Duration) *prefetcher {
+	return &prefetcher{gateway: gateway, timeout: timeout, pulled: map[string]bool{}}
+}
+
+// prefetch waits for the image to be pulled onto the nodes, when the provider
+// has no prefetch API the image is pulled as the function is deployed
+func (p *prefetcher) prefetch(functionName string, request proxy.PrefetchRequest) error {
+	key := request.Namespace + "/" + request.Image
+	if p.unsupported || p.pulled[key] {
+		return nil
+	}
+
+	fmt.Printf("Prefetching: %s for %s.\n", request.Image, functionName)
+	status, supported, err := proxy.StartPrefetch(p.gateway, request)
+	if err != nil {
+		return fmt.Errorf("unable to prefetch %s for %s: %s", request.Image, functionName, err)
+	}
+	if !supported {
+		p.unsupported = true
+		fmt.Printf("Warning: the gateway at %s has no prefetch API, images are pulled as the functions are deployed.\n", p.gateway)
+		return nil
+	}
+
+	deadline := time.Now().Add(p.timeout)
+	for !status.Done() {
+		if len(status.Error) > 0 {
+			return fmt.Errorf("unable to prefetch %s for %s: %s", request.Image, functionName, status.Error)
+		}
+		if time.Now().Add(prefetchPollInterval).After(deadline) {
+			return fmt.Errorf("%s was pulled onto %d of %d node(s) within %s, %s was not deployed", request.Image, status.Pulled, status.Nodes, p.timeout, functionName)
+		}
+		time.Sleep(prefetchPollInterval)
+
+		if status, err = proxy.GetPrefetchStatus(p.gateway, request.Image, request.Namespace); err != nil {
+			return fmt.Errorf("unable to prefetch %s for %s: %s", request.Image, functionName, err)
+		}
+	}
+
+	fmt.Printf("Prefetched: %s onto %d node(s).\n", request.Image, status.Nodes)
+	p.pulled[key] = true
+	return nil
+}
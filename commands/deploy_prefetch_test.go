@@ -0,0 +1,103 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_prefetcher(t *testing.T) {
+	defer func(interval time.Duration) { prefetchPollInterval = interval }(prefetchPollInterval)
+	prefetchPollInterval = time.Millisecond
+
+	cases := []struct {
+		name     string
+		statuses []proxy.PrefetchStatus
+		timeout  time.Duration
+		wantErr  string
+	}{
+		{
+			name:     "pulled onto every node",
+			statuses: []proxy.PrefetchStatus{{Nodes: 2}, {Nodes: 2, Pulled: 1}, {Nodes: 2, Pulled: 2}},
+			timeout:  time.Minute,
+		},
+		{
+			name:     "pull failed",
+			statuses: []proxy.PrefetchStatus{{Nodes: 2}, {Nodes: 2, Error: "manifest unknown"}},
+			timeout:  time.Minute,
+			wantErr:  "unable to prefetch model:2 for classify: manifest unknown",
+		},
+		{
+			name:     "timed out",
+			statuses: []proxy.PrefetchStatus{{Nodes: 2}},
+			timeout:  0,
+			wantErr:  "model:2 was pulled onto 0 of 2 node(s) within 0s, classify was not deployed",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			requests := 0
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := c.statuses[len(c.statuses)-1]
+				if requests < len(c.statuses) {
+					status = c.statuses[requests]
+				}
+				requests++
+				json.NewEncoder(w).Encode(status)
+			}))
+			defer s.Close()
+
+			p := newPrefetcher(s.URL, c.timeout)
+			var err error
+			test.CaptureStdout(func() {
+				err = p.prefetch("classify", proxy.PrefetchRequest{Image: "model:2"})
+			})
+
+			if len(c.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				before := requests
+				p.prefetch("classify-batch", proxy.PrefetchRequest{Image: "model:2"})
+				if requests != before {
+					t.Errorf("want an image prefetched once, got %d more requests", requests-before)
+				}
+				return
+			}
+			if err == nil || err.Error() != c.wantErr {
+				t.Errorf("want error %q, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_prefetcher_NotSupported(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusNotImplemented)
+	defer s.Close()
+
+	p := newPrefetcher(s.URL, time.Minute)
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		err = p.prefetch("classify", proxy.PrefetchRequest{Image: "model:2"})
+		if err == nil {
+			err = p.prefetch("train", proxy.PrefetchRequest{Image: "trainer:1"})
+		}
+	})
+
+	if err != nil {
+		t.Fatalf("want the deploy to go ahead without a prefetch API, got: %s", err)
+	}
+	if strings.Count(stdOut, "has no prefetch API") != 1 {
+		t.Errorf("want one warning, got %q", stdOut)
+	}
+}
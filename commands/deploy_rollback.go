@@ -0,0 +1,56 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// previousRevisions records what is deployed to the namespaces before
+// --rollback-on-failure updates it, keyed by qualified name, nil when the
+// flag is not set
+func previousRevisions(gateway string, namespaces []string, deployFlags DeployFlags) (map[string]proxy.FunctionStatus, error) {
+	if !deployFlags.rollbackOnFailure {
+		return nil, nil
+	}
+	previous, err := functionStatusInNamespaces(gateway, namespaces)
+	if err != nil {
+		return nil, fmt.Errorf("unable to record the deployed functions for --rollback-on-failure: %s", err)
+	}
+	return previous, nil
+}
+
+// verifyOrRollback waits for a function to become ready, re-deploying its
+// previous revision when it does not
+func verifyOrRollback(gateway string, network string, functionName string, namespace string, healthCheck *stack.HealthCheck, previous map[string]proxy.FunctionStatus, timeout time.Duration) error {
+	readyErr := waitForFunction(gateway, functionName, namespace, healthCheck, timeout)
+	if readyErr == nil {
+		return nil
+	}
+
+	revision, ok := previous[qualifiedName(functionName, namespace)]
+	if !ok {
+		return fmt.Errorf("%s, there is no previous revision to roll back to", readyErr)
+	}
+
+	fmt.Printf("Rolling back: %s to %s.\n", qualifiedName(functionName, namespace), revision.Image)
+	spec := restoredSpec(revision)
+	if len(network) > 0 {
+		spec.Network = network
+	}
+	statusCode := proxy.DeployFunction(gateway, spec)
+	recordDeploy(gateway, spec, statusCode)
+	if !deploySucceeded(statusCode) {
+		return fmt.Errorf("%s, and rolling back to %s failed with status %d", readyErr, revision.Image, statusCode)
+	}
+
+	if err := waitForFunction(gateway, functionName, namespace, healthCheck, timeout); err != nil {
+		return fmt.Errorf("%s, and after rolling back to %s: %s", readyErr, revision.Image, err)
+	}
+	return fmt.Errorf("%s, rolled back to %s", readyErr, revision.Image)
+}
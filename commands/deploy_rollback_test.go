@@ -0,0 +1,133 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+// rollbackGateway serves api with the deployed image, or no functions when
+// it is empty, where only api:1 passes its health check
+func rollbackGateway(t *testing.T, deployed string) (*httptest.Server, func() []string) {
+	var lock sync.Mutex
+	image := deployed
+	var images []string
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/system/functions":
+			if len(deployed) == 0 {
+				w.Write([]byte(`[]`))
+				return
+			}
+			w.Write([]byte(`[{"name":"api","image":"` + deployed + `"}]`))
+		case r.Method == http.MethodPut && r.URL.Path == "/system/functions":
+			var spec struct {
+				Image string `json:"image"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+				t.Errorf("unable to decode the deployment: %s", err)
+			}
+			image = spec.Image
+			images = append(images, spec.Image)
+			w.WriteHeader(http.StatusAccepted)
+		case r.URL.Path == "/function/api/_/health":
+			if image != "api:1" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return s, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return images
+	}
+}
+
+func Test_deployStack_RollbackOnFailure(t *testing.T) {
+	s, images := rollbackGateway(t, "api:1")
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"api": {Image: "api:2", HealthCheck: &stack.HealthCheck{Interval: "10ms"}},
+		},
+	}
+
+	var deployErr error
+	stdOut := test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, rollbackOnFailure: true, readyTimeout: 100 * time.Millisecond}, nil)
+	})
+	if deployErr == nil || deployErr.Error() != "function api did not become ready within 100ms, rolled back to api:1" {
+		t.Errorf("want the deploy to fail after rolling back, got %v", deployErr)
+	}
+	if got := strings.Join(images(), ","); got != "api:2,api:1" {
+		t.Errorf("want api:2 then api:1 deployed, got %s", got)
+	}
+	if !strings.Contains(stdOut, "Rolling back: api to api:1.") || !strings.Contains(stdOut, "Function api is ready.") {
+		t.Errorf("unexpected output:\n%s", stdOut)
+	}
+}
+
+func Test_deployStack_RollbackOnFailure_Ready(t *testing.T) {
+	s, images := rollbackGateway(t, "api:0")
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"api": {Image: "api:1", HealthCheck: &stack.HealthCheck{Interval: "10ms"}},
+		},
+	}
+
+	var deployErr error
+	test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, rollbackOnFailure: true, readyTimeout: time.Second}, nil)
+	})
+	if deployErr != nil {
+		t.Fatal(deployErr)
+	}
+	if got := strings.Join(images(), ","); got != "api:1" {
+		t.Errorf("want only api:1 deployed, got %s", got)
+	}
+}
+
+func Test_deployStack_RollbackOnFailure_NoPreviousRevision(t *testing.T) {
+	s, images := rollbackGateway(t, "")
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"api": {Image: "api:2", HealthCheck: &stack.HealthCheck{Interval: "10ms"}},
+		},
+	}
+
+	var deployErr error
+	test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, rollbackOnFailure: true, readyTimeout: 100 * time.Millisecond}, nil)
+	})
+	if deployErr == nil || !strings.HasSuffix(deployErr.Error(), "there is no previous revision to roll back to") {
+		t.Errorf("got error %v", deployErr)
+	}
+	if got := strings.Join(images(), ","); got != "api:2" {
+		t.Errorf("want only api:2 deployed, got %s", got)
+	}
+}
@@ -4,10 +4,18 @@
 package commands
 
 import (
+	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-cli/test"
 )
 
@@ -70,6 +78,34 @@ func Test_getGatewayURL(t *testing.T) {
 	}
 }
 
+func Test_getGatewayURL_Profile(t *testing.T) {
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-profiles")
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+	}()
+
+	if err := config.SaveContext("staging", "https://Staging.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]string{
+		"staging":        "https://staging.example.com",
+		"prod":           "http://prod",
+		"localhost:8080": "http://localhost:8080",
+	}
+	for argument, want := range cases {
+		if url := getGatewayURL(argument, defaultGateway, ""); url != want {
+			t.Errorf("%s: want %s, got %s", argument, want, url)
+		}
+	}
+
+	if url := getGatewayURL(defaultGateway, defaultGateway, "staging"); url != "https://staging.example.com" {
+		t.Errorf("want the profile in the YAML file to be resolved, got %s", url)
+	}
+}
+
 func Test_deploy(t *testing.T) {
 	s := test.MockHttpServer(t, []test.Request{
 		{
@@ -98,3 +134,286 @@ func Test_deploy(t *testing.T) {
 		t.Fatalf("Output is not as expected:\n%s", stdOut)
 	}
 }
+
+func Test_healthCheckAnnotations(t *testing.T) {
+	annotations, err := healthCheckAnnotations(&stack.HealthCheck{
+		Path:         "/healthz",
+		Interval:     "1500ms",
+		InitialDelay: "5s",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := map[string]string{
+		"com.openfaas.health.http.path":          "/healthz",
+		"com.openfaas.health.http.initialDelay":  "5s",
+		"com.openfaas.health.http.periodSeconds": "2",
+	}
+	if !reflect.DeepEqual(annotations, expected) {
+		t.Fatalf("want: %v, got: %v", expected, annotations)
+	}
+
+	if _, err := healthCheckAnnotations(&stack.HealthCheck{Interval: "often"}); err == nil {
+		t.Fatal("expected an error for an invalid interval")
+	}
+}
+
+func Test_waitForFunction_UsesHealthCheckPath(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/function/test-function/healthz",
+			ResponseStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/function/test-function/healthz",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	var err error
+	test.CaptureStdout(func() {
+		err = waitForFunction(s.URL, "test-function", "", &stack.HealthCheck{Path: "/healthz", Interval: "10ms"}, time.Second)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
+
+func Test_waitForFunction_InNamespace(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/function/test-function.staging/_/health",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		err = waitForFunction(s.URL, "test-function", "staging", nil, time.Second)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(stdOut, "Function test-function.staging is ready.") {
+		t.Errorf("want the qualified name in the output:\n%s", stdOut)
+	}
+}
+
+func Test_checkWatchdog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-watchdog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(templateDirectory string) { stack.TemplateDirectory = templateDirectory }(stack.TemplateDirectory)
+	stack.TemplateDirectory = dir
+
+	templates := map[string]string{
+		"classic":       "FROM alpine\nCMD [\"fwatchdog\"]\n",
+		"classic-image": "FROM alpine\nENV fprocess=\"cat\"\nCMD [\"fwatchdog\"]\n",
+		"of-http":       "FROM openfaas/of-watchdog:0.2.1 as watchdog\nENV mode=\"http\"\n",
+	}
+	for name, dockerfile := range templates {
+		os.MkdirAll(filepath.Join(dir, name), 0700)
+		ioutil.WriteFile(filepath.Join(dir, name, "template.yml"), []byte("language: "+name+"\n"), 0600)
+		ioutil.WriteFile(filepath.Join(dir, name, "Dockerfile"), []byte(dockerfile), 0600)
+	}
+
+	cases := []struct {
+		name        string
+		function    stack.Function
+		wantErr     bool
+		wantWarning bool
+	}{
+		{"classic without fprocess", stack.Function{Name: "fn", Language: "classic"}, true, false},
+		{"classic with fprocess", stack.Function{Name: "fn", Language: "classic", FProcess: "cat"}, false, false},
+		{"classic with fprocess in the image", stack.Function{Name: "fn", Language: "classic-image"}, false, false},
+		{"classic with a custom health path", stack.Function{Name: "fn", Language: "classic", FProcess: "cat", HealthCheck: &stack.HealthCheck{Path: "/ready"}}, false, true},
+		{"of-watchdog http with a custom health path", stack.Function{Name: "fn", Language: "of-http", FProcess: "node index.js", HealthCheck: &stack.HealthCheck{Path: "/ready"}}, false, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var err error
+			stdOut := test.CaptureStdout(func() {
+				err = checkWatchdog(c.function)
+			})
+
+			if c.wantErr != (err != nil) {
+				t.Errorf("want error %t, got %v", c.wantErr, err)
+			}
+			if c.wantWarning != strings.Contains(stdOut, "Warning:") {
+				t.Errorf("want warning %t, got %q", c.wantWarning, stdOut)
+			}
+		})
+	}
+}
+
+func Test_deployStack_Ordered(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/function/db/_/health",
+			ResponseStatusCode: http.StatusOK,
+		},
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"api": {Image: "api:1", DependsOn: []string{"db"}},
+			"db":  {Image: "db:1"},
+		},
+	}
+
+	var deployErr error
+	stdOut := test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, ordered: true, waitTimeout: time.Second}, nil)
+	})
+	if deployErr != nil {
+		t.Fatalf("got error %s", deployErr)
+	}
+
+	db := strings.Index(stdOut, "Deploying: db.")
+	waiting := strings.Index(stdOut, "Waiting for db to become ready")
+	api := strings.Index(stdOut, "Deploying: api.")
+	if db == -1 || waiting < db || api < waiting {
+		t.Errorf("want db deployed and ready before api:\n%s", stdOut)
+	}
+}
+
+func Test_deployStack_OrderedDependencyFailed(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusInternalServerError,
+		},
+	})
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"api": {Image: "api:1", DependsOn: []string{"db"}},
+			"db":  {Image: "db:1"},
+		},
+	}
+
+	var deployErr error
+	test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, ordered: true, waitTimeout: time.Second}, nil)
+	})
+	if deployErr == nil || deployErr.Error() != "not deploying api, its dependency db failed to deploy" {
+		t.Errorf("want an error for the failed dependency, got %v", deployErr)
+	}
+}
+
+func Test_deploy_StackFromStdin(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	dir, err := ioutil.TempDir("", "faas-cli-stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The environment_file is only found relative to --workdir
+	if err := ioutil.WriteFile(filepath.Join(dir, "env.yml"), []byte("environment:\n  REGION: eu\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stack.Stdin = strings.NewReader(`provider:
+  name: faas
+functions:
+  api:
+    image: api:0.1
+    environment_file:
+      - env.yml
+`)
+	defer func() { stack.Stdin = os.Stdin }()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"deploy", "-f", "-", "--workdir", dir, "--gateway=" + s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if !strings.Contains(stdOut, "Deploying: api.") {
+		t.Fatalf("Output is not as expected:\n%s", stdOut)
+	}
+}
+
+func Test_functionEnvironment_LoggingOverriddenByEnvironment(t *testing.T) {
+	function := stack.Function{
+		Logging:     &stack.Logging{Level: "debug", Format: "json"},
+		Environment: map[string]string{"LOG_LEVEL": "error", "REGION": "eu"},
+	}
+
+	want := map[string]string{"LOG_LEVEL": "error", "LOG_FORMAT": "json", "REGION": "eu"}
+	if got := functionEnvironment(function); !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+}
+
+func Test_readFiles_YAMLAndDotEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-environment-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	common := filepath.Join(dir, "common.yml")
+	staging := filepath.Join(dir, "staging.env")
+	ioutil.WriteFile(common, []byte("environment:\n  REGION: eu\n  LOG_LEVEL: info\n"), 0600)
+	ioutil.WriteFile(staging, []byte("LOG_LEVEL=debug\nDB_HOST=db.staging\n"), 0600)
+
+	got, err := readFiles([]string{common, staging})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]string{"REGION": "eu", "LOG_LEVEL": "debug", "DB_HOST": "db.staging"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want the later file to win, got %v", got)
+	}
+
+	missing := filepath.Join(dir, "prod.env")
+	if _, err := readFiles([]string{common, missing}); err == nil || err.Error() != "environment_file "+missing+" was not found" {
+		t.Errorf("want an error naming the missing file, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// deprecation is a flag or stack field slated for removal
+type deprecation struct {
+	Code           string `json:"code"`
+	Kind           string `json:"kind"`
+	Name           string `json:"name"`
+	Replacement    string `json:"replacement"`
+	RemovalVersion string `json:"removal_version"`
+
+	// command and flag identify a deprecated flag
+	command string
+	flag    string
+
+	// inFunction reports whether a function uses a deprecated field
+	inFunction func(function stack.Function) bool
+}
+
+// deprecations known to this version of the CLI, codes are never re-used
+var deprecations = []deprecation{
+	{
+		Code:           "DEP001",
+		Kind:           "flag",
+		Name:           "deploy --replace",
+		Replacement:    "--update",
+		RemovalVersion: "0.7.0",
+		command:        "deploy",
+		flag:           "replace",
+	},
+	{
+		Code:           "DEP002",
+		Kind:           "flag",
+		Name:           "build --squash",
+		Replacement:    "a multi-stage Dockerfile",
+		RemovalVersion: "0.7.0",
+		command:        "build",
+		flag:           "squash",
+	},
+	{
+		Code:           "DEP003",
+		Kind:           "field",
+		Name:           "functions.*.lang ending in -armhf",
+		Replacement:    "the template without -armhf and platforms: [linux/arm/v7]",
+		RemovalVersion: "0.7.0",
+		inFunction: func(function stack.Function) bool {
+			return strings.HasSuffix(function.Language, "-armhf")
+		},
+	},
+}
+
+// deprecationUse is a deprecation found in a stack file
+type deprecationUse struct {
+	deprecation
+	Function string `json:"function,omitempty"`
+}
+
+var (
+	deprecationsJSON bool
+	deprecationsAll  bool
+)
+
+// warnedDeprecations makes sure each code is only printed once per run
+var warnedDeprecations = map[string]bool{}
+
+var deprecationOutput io.Writer = os.Stderr
+
+func init() {
+	deprecationsCmd.Flags().BoolVar(&deprecationsJSON, "json", false, "Print the deprecations as JSON")
+	deprecationsCmd.Flags().BoolVar(&deprecationsAll, "all", false, "List every deprecation even when a YAML file is found")
+
+	faasCmd.AddCommand(deprecationsCmd)
+}
+
+// deprecationsCmd lists deprecated flags and stack fields
+var deprecationsCmd = &cobra.Command{
+	Use:   `deprecations [-f YAML_FILE] [--all] [--json]`,
+	Short: "List deprecated flags and stack fields",
+	Long: `Lists the flags and stack fields which are slated for removal along with their
+replacement and the version they will be removed in. When a YAML file is given
+only the deprecated fields used by its functions are listed, so that a stack can
+be audited before upgrading the CLI.`,
+	Example: `  faas-cli deprecations --all
+  faas-cli deprecations -f ./stack.yml
+  faas-cli deprecations -f ./stack.yml --json`,
+	RunE: runDeprecations,
+}
+
+func runDeprecations(cmd *cobra.Command, args []string) error {
+	var uses []deprecationUse
+
+	if len(yamlFile) > 0 && !deprecationsAll {
+		services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+		if err != nil {
+			return err
+		}
+
+		uses = stackDeprecations(services)
+		if len(uses) == 0 && !deprecationsJSON {
+			fmt.Printf("No deprecated fields found in %s.\n", yamlFile)
+			return nil
+		}
+	} else {
+		for _, d := range deprecations {
+			uses = append(uses, deprecationUse{deprecation: d})
+		}
+	}
+
+	if deprecationsJSON {
+		if uses == nil {
+			uses = []deprecationUse{}
+		}
+		out, err := json.MarshalIndent(uses, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CODE\tNAME\tFUNCTION\tREPLACEMENT\tREMOVAL")
+	for _, use := range uses {
+		function := use.Function
+		if len(function) == 0 {
+			function = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", use.Code, use.Name, function, use.Replacement, use.RemovalVersion)
+	}
+	return w.Flush()
+}
+
+// stackDeprecations finds the deprecated fields used by each function
+func stackDeprecations(services *stack.Services) []deprecationUse {
+	names := make([]string, 0, len(services.Functions))
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var uses []deprecationUse
+	for _, name := range names {
+		for _, d := range deprecations {
+			if d.inFunction != nil && d.inFunction(services.Functions[name]) {
+				uses = append(uses, deprecationUse{deprecation: d, Function: name})
+			}
+		}
+	}
+	return uses
+}
+
+// warnDeprecated prints a warning for the deprecation once per run
+func warnDeprecated(d deprecation, function string) {
+	if warnedDeprecations[d.Code] {
+		return
+	}
+	warnedDeprecations[d.Code] = true
+
+	subject := d.Name
+	if len(function) > 0 {
+		subject = fmt.Sprintf("%s (function %s)", d.Name, function)
+	}
+	fmt.Fprintf(deprecationOutput, "Warning: [%s] %s is deprecated and will be removed in %s, use %s instead.\n",
+		d.Code, subject, d.RemovalVersion, d.Replacement)
+}
+
+// warnDeprecations runs before every command and warns about deprecated
+// flags that were set and deprecated fields in a local stack file
+func warnDeprecations(cmd *cobra.Command, args []string) error {
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		for _, d := range deprecations {
+			if d.command == cmd.Name() && d.flag == f.Name {
+				warnDeprecated(d, "")
+			}
+		}
+	})
+
+	if cmd == deprecationsCmd || len(yamlFile) == 0 {
+		return nil
+	}
+
+	// Remote stacks are not fetched twice, they can be audited with "faas-cli deprecations"
+	if parsed, err := url.Parse(yamlFile); err == nil && len(parsed.Scheme) > 1 {
+		return nil
+	}
+	if _, err := os.Stat(yamlFile); err != nil {
+		return nil
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		// The command itself reports problems with the stack file
+		return nil
+	}
+
+	for _, use := range stackDeprecations(services) {
+		warnDeprecated(use.deprecation, use.Function)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_stackDeprecations(t *testing.T) {
+	services := &stack.Services{
+		Functions: map[string]stack.Function{
+			"b-fn": {Language: "node-armhf"},
+			"a-fn": {Language: "python-armhf"},
+			"c-fn": {Language: "node"},
+		},
+	}
+
+	uses := stackDeprecations(services)
+	if len(uses) != 2 {
+		t.Fatalf("want 2 deprecated fields, got %d", len(uses))
+	}
+	if uses[0].Function != "a-fn" || uses[0].Code != "DEP003" || uses[1].Function != "b-fn" {
+		t.Errorf("unexpected deprecations: %+v", uses)
+	}
+}
+
+func Test_warnDeprecated_OncePerRun(t *testing.T) {
+	var out bytes.Buffer
+	defer func(original map[string]bool, originalOutput io.Writer) {
+		warnedDeprecations = original
+		deprecationOutput = originalOutput
+	}(warnedDeprecations, deprecationOutput)
+	warnedDeprecations = map[string]bool{}
+	deprecationOutput = &out
+
+	warnDeprecated(deprecations[0], "")
+	warnDeprecated(deprecations[0], "")
+
+	warning := out.String()
+	if strings.Count(warning, "[DEP001]") != 1 {
+		t.Fatalf("want a single warning, got %q", warning)
+	}
+	if !strings.Contains(warning, "removed in 0.7.0") || !strings.Contains(warning, "use --update") {
+		t.Errorf("want the removal version and replacement, got %q", warning)
+	}
+}
+
+func Test_deprecationCodesUnique(t *testing.T) {
+	seen := map[string]bool{}
+	for _, d := range deprecations {
+		if seen[d.Code] {
+			t.Errorf("deprecation code %s is used twice", d.Code)
+		}
+		seen[d.Code] = true
+
+		if (len(d.flag) > 0) == (d.inFunction != nil) {
+			t.Errorf("deprecation %s must be for either a flag or a stack field", d.Code)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var describeOutput string
+
+// describedAnnotations are set by deploy itself, so they are left out of the
+// stack written by describe --output yaml
+var describedAnnotations = []string{configHashAnnotation, imageDigestAnnotation}
+
+// describedStack puts the provider before the function in the YAML written
+// by describe --output yaml
+type describedStack struct {
+	Provider  stack.Provider            `yaml:"provider"`
+	Functions map[string]stack.Function `yaml:"functions"`
+}
+
+func init() {
+	describeCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	describeCmd.Flags().StringVarP(&describeOutput, "output", "o", "text", "Output format: text, or yaml for a stack file which deploys the function as it is")
+
+	faasCmd.AddCommand(describeCmd)
+}
+
+var describeCmd = &cobra.Command{
+	Use:   `describe FUNCTION_NAME [--gateway GATEWAY_URL] [--output text|yaml]`,
+	Short: "Describe a deployed function",
+	Long: `Shows what the gateway knows of a deployed function: its replicas, invocations,
+image, environment, labels, annotations, secrets and resources.
+
+--output yaml writes a stack file for the function as it is deployed, which
+can be kept in source control or deployed elsewhere with "faas-cli deploy -f".
+The function is given skip_build, since the stack has no handler to build.`,
+	Example: `  faas-cli describe figlet
+  faas-cli describe figlet --gateway https://openfaas.example.com --namespace staging
+  faas-cli describe figlet --output yaml > figlet.yml`,
+	RunE: runDescribe,
+}
+
+func runDescribe(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the function")
+	}
+	name := args[0]
+
+	if describeOutput != "text" && describeOutput != "yaml" {
+		return fmt.Errorf("--output must be text or yaml, not %s", describeOutput)
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	status, found, err := proxy.GetFunctionInfoInNamespace(gatewayAddress, name, functionNamespace)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("function %s was not found on %s", qualifiedName(name, functionNamespace), gatewayAddress)
+	}
+	if len(status.Name) == 0 {
+		status.Name = name
+	}
+	if len(status.Namespace) == 0 {
+		status.Namespace = functionNamespace
+	}
+
+	if describeOutput == "yaml" {
+		out, err := yaml.Marshal(describeStack(gatewayAddress, status))
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+		return nil
+	}
+
+	printFunction(os.Stdout, gatewayAddress, status)
+	return nil
+}
+
+func printFunction(w io.Writer, gatewayAddress string, status proxy.FunctionStatus) {
+	table := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(table, "Name:\t%s\n", status.Name)
+	if len(status.Namespace) > 0 {
+		fmt.Fprintf(table, "Namespace:\t%s\n", status.Namespace)
+	}
+	fmt.Fprintf(table, "Image:\t%s\n", status.Image)
+	fmt.Fprintf(table, "Replicas:\t%d\n", status.Replicas)
+	fmt.Fprintf(table, "Invocations:\t%d\n", int64(status.InvocationCount))
+	if status.CreatedAt != nil {
+		fmt.Fprintf(table, "Created:\t%s\n", status.CreatedAt.Local().Format(time.RFC3339))
+	}
+	if len(status.EnvProcess) > 0 {
+		fmt.Fprintf(table, "Process:\t%s\n", status.EnvProcess)
+	}
+	fmt.Fprintf(table, "URL:\t%s/function/%s\n", strings.TrimRight(gatewayAddress, "/"), qualifiedName(status.Name, status.Namespace))
+	printDescribedMetadata(table, stack.MetadataFromAnnotations(status.Annotations))
+
+	printDescribedMap(table, "Environment", status.EnvVars)
+	printDescribedMap(table, "Labels", status.Labels)
+	printDescribedMap(table, "Annotations", status.Annotations)
+	printDescribedList(table, "Secrets", status.Secrets)
+	printDescribedList(table, "Constraints", status.Constraints)
+	if status.Limits != nil {
+		fmt.Fprintf(table, "Limits:\t%s\n", listResources(status.Limits))
+	}
+	if status.Requests != nil {
+		fmt.Fprintf(table, "Requests:\t%s\n", listResources(status.Requests))
+	}
+	table.Flush()
+}
+
+// printDescribedMetadata prints the fields of the function's metadata which
+// are set, so that its owners can be found
+func printDescribedMetadata(w io.Writer, metadata *stack.Metadata) {
+	if metadata == nil {
+		return
+	}
+	for _, field := range []struct{ title, value string }{
+		{"Owner", metadata.Owner},
+		{"Slack", metadata.Slack},
+		{"Repository", metadata.Repository},
+		{"Runbook", metadata.Runbook},
+		{"README", metadata.Readme},
+	} {
+		if len(field.value) > 0 {
+			fmt.Fprintf(w, "%s:\t%s\n", field.title, field.value)
+		}
+	}
+}
+
+// printDescribedMap prints one KEY=VALUE per line sorted by key, or <none>
+func printDescribedMap(w io.Writer, title string, values map[string]string) {
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = key + "=" + values[key]
+	}
+	printDescribedList(w, title, lines)
+}
+
+func printDescribedList(w io.Writer, title string, values []string) {
+	if len(values) == 0 {
+		fmt.Fprintf(w, "%s:\t<none>\n", title)
+		return
+	}
+	for i, value := range values {
+		if i == 0 {
+			fmt.Fprintf(w, "%s:\t%s\n", title, value)
+			continue
+		}
+		fmt.Fprintf(w, "\t%s\n", value)
+	}
+}
+
+// describeStack gives a stack which deploys the function as it is deployed
+func describeStack(gatewayAddress string, status proxy.FunctionStatus) describedStack {
+	function := stack.Function{
+		Image:       status.Image,
+		FProcess:    status.EnvProcess,
+		Namespace:   status.Namespace,
+		Environment: status.EnvVars,
+		Secrets:     status.Secrets,
+		SkipBuild:   true,
+		Limits:      status.Limits,
+		Requests:    status.Requests,
+	}
+	if len(status.Constraints) > 0 {
+		constraints := status.Constraints
+		function.Constraints = &constraints
+	}
+	if len(status.Labels) > 0 {
+		labels := status.Labels
+		function.Labels = &labels
+	}
+
+	annotations := map[string]string{}
+	for key, value := range status.Annotations {
+		annotations[key] = value
+	}
+	for _, key := range describedAnnotations {
+		delete(annotations, key)
+	}
+
+	// Metadata is written as it is given in a stack rather than as annotations
+	if metadata := stack.MetadataFromAnnotations(annotations); metadata != nil {
+		function.Metadata = metadata
+		for key := range metadata.Annotations() {
+			delete(annotations, key)
+		}
+	}
+	if len(annotations) > 0 {
+		function.Annotations = &annotations
+	}
+
+	return describedStack{
+		Provider:  stack.Provider{Name: "faas", GatewayURL: gatewayAddress},
+		Functions: map[string]stack.Function{status.Name: function},
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// callsFile keeps the asynchronous invocations queued by invoke --async
+	// in the config folder
+	callsFile = "calls.json"
+
+	// maxRecordedCalls is how many calls are kept, the oldest are dropped
+	maxRecordedCalls = 100
+)
+
+// callRecord is an asynchronous invocation queued by the CLI
+type callRecord struct {
+	ID          string    `json:"id"`
+	Function    string    `json:"function"`
+	Gateway     string    `json:"gateway"`
+	Queued      time.Time `json:"queued"`
+	CallbackURL string    `json:"callback_url,omitempty"`
+}
+
+func init() {
+	describeCallCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://, defaults to the gateway the call was queued on")
+
+	faasCmd.AddCommand(describeCallCmd)
+}
+
+var describeCallCmd = &cobra.Command{
+	Use:   `describe-call CALL_ID [--gateway GATEWAY_URL]`,
+	Short: "Describe an asynchronous invocation",
+	Long: `Describes an invocation queued with "faas-cli invoke --async" by the call id
+printed when it was queued, which the gateway gives in the X-Call-Id header.
+
+The function, gateway and callback URL are read from the calls recorded by the
+CLI, and the status of the call is read from the gateway when its provider
+keeps a record of each call, such as a queue backed by NATS Streaming.
+Otherwise the result is only delivered to the callback URL, with the call id in
+its X-Call-Id header.`,
+	Example: `  faas-cli invoke resize --async --callback-url https://example.com/done < image.png
+  faas-cli describe-call 9c7f1a46-2e0b-4e3b-a0a5-3f0f8a1c6a5e`,
+	RunE: runDescribeCall,
+}
+
+func runDescribeCall(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the id of the call")
+	}
+	callID := args[0]
+
+	record, recorded, err := lookupCall(callID)
+	if err != nil {
+		return err
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	if recorded && !cmd.Flags().Changed("gateway") {
+		gatewayAddress = record.Gateway
+	}
+
+	status, found, err := proxy.GetCallStatus(gatewayAddress, callID)
+	if err != nil {
+		return err
+	}
+	if !recorded && !found {
+		return fmt.Errorf("call %s was not queued from this machine and %s has no record of it", callID, gatewayAddress)
+	}
+
+	if !recorded {
+		record = callRecord{ID: callID, Function: status.Function, Gateway: gatewayAddress}
+	}
+	printCall(os.Stdout, record, status, found)
+	return nil
+}
+
+func printCall(w io.Writer, record callRecord, status proxy.CallStatus, found bool) {
+	table := tabwriter.NewWriter(w, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(table, "ID:\t%s\n", record.ID)
+	fmt.Fprintf(table, "Function:\t%s\n", record.Function)
+	fmt.Fprintf(table, "Gateway:\t%s\n", record.Gateway)
+	if !record.Queued.IsZero() {
+		fmt.Fprintf(table, "Queued:\t%s\n", record.Queued.Local().Format(time.RFC3339))
+	}
+	if len(record.CallbackURL) > 0 {
+		fmt.Fprintf(table, "Callback URL:\t%s\n", record.CallbackURL)
+	}
+
+	if !found {
+		fmt.Fprintf(table, "Status:\tunknown, the gateway keeps no record of calls\n")
+		table.Flush()
+		return
+	}
+
+	fmt.Fprintf(table, "Status:\t%s\n", status.Status)
+	if status.StatusCode > 0 {
+		fmt.Fprintf(table, "Status code:\t%d\n", status.StatusCode)
+	}
+	if status.Duration > 0 {
+		fmt.Fprintf(table, "Duration:\t%s\n", time.Duration(status.Duration*float64(time.Second)).Round(time.Millisecond))
+	}
+	if !status.Finished.IsZero() {
+		fmt.Fprintf(table, "Finished:\t%s\n", status.Finished.Local().Format(time.RFC3339))
+	}
+	table.Flush()
+}
+
+func callsPath() (string, error) {
+	dir, err := homedir.Expand(config.DefaultDir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, callsFile), nil
+}
+
+func readCalls() ([]callRecord, error) {
+	path, err := callsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []callRecord
+	if err := json.Unmarshal(data, &calls); err != nil {
+		return nil, fmt.Errorf("unable to read the calls in %s: %s", path, err)
+	}
+	return calls, nil
+}
+
+// recordCall keeps a queued call so that describe-call can find where it was
+// queued, the oldest calls are dropped
+func recordCall(call callRecord) error {
+	calls, err := readCalls()
+	if err != nil {
+		return err
+	}
+
+	calls = append(calls, call)
+	if len(calls) > maxRecordedCalls {
+		calls = calls[len(calls)-maxRecordedCalls:]
+	}
+
+	data, err := json.MarshalIndent(calls, "", "  ")
+	if err != nil {
+		return err
+	}
+	path, err := callsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func lookupCall(callID string) (callRecord, bool, error) {
+	calls, err := readCalls()
+	if err != nil {
+		return callRecord{}, false, err
+	}
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i].ID == callID {
+			return calls[i], true, nil
+		}
+	}
+	return callRecord{}, false, nil
+}
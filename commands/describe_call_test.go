@@ -0,0 +1,103 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_describeCall_QueuedWithCallback(t *testing.T) {
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-calls")
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+		invokeAsync, callbackURL = false, ""
+	}()
+
+	var callback string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/async-function/resize":
+			callback = r.Header.Get("X-Callback-Url")
+			w.Header().Set("X-Call-Id", "call-7")
+			w.WriteHeader(http.StatusAccepted)
+		case "/system/call/call-7":
+			w.Write([]byte(`{"id":"call-7","function":"resize","status":"completed","statusCode":200,"duration":1.5}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	faasCmd.SetArgs([]string{"invoke", "resize", "--gateway=" + s.URL, "--data", "x", "--async", "--callback-url", "http://example.com/done"})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	if callback != "http://example.com/done" {
+		t.Errorf("want the callback URL sent, got %q", callback)
+	}
+
+	record, recorded, err := lookupCall("call-7")
+	if err != nil || !recorded {
+		t.Fatalf("want the call recorded, got %v, %v", recorded, err)
+	}
+	if record.Function != "resize" || record.Gateway != s.URL || record.CallbackURL != "http://example.com/done" {
+		t.Errorf("got %+v", record)
+	}
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"describe-call", "call-7"})
+		err = faasCmd.Execute()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Gateway:      " + s.URL + "\n", "Callback URL: http://example.com/done\n", "Status:       completed\n", "Duration:     1.5s\n"} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in the output:\n%s", want, stdOut)
+		}
+	}
+}
+
+func Test_invoke_CallbackURLNeedsAsync(t *testing.T) {
+	defer func() { callbackURL = "" }()
+
+	faasCmd.SetArgs([]string{"invoke", "resize", "--data", "x", "--callback-url", "http://example.com/done"})
+	err := faasCmd.Execute()
+	if err == nil || err.Error() != "--callback-url can only be used with --async" {
+		t.Errorf("want an error, got %v", err)
+	}
+}
+
+func Test_recordCall_KeepsTheLatest(t *testing.T) {
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-calls")
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+	}()
+
+	for i := 0; i < maxRecordedCalls+5; i++ {
+		if err := recordCall(callRecord{ID: fmt.Sprintf("call-%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	calls, err := readCalls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != maxRecordedCalls || calls[0].ID != "call-5" {
+		t.Errorf("want the oldest 5 dropped, got %d calls starting with %q", len(calls), calls[0].ID)
+	}
+}
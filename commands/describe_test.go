@@ -0,0 +1,104 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+var describedFunction = proxy.FunctionStatus{
+	Name:            "figlet",
+	Image:           "functions/figlet:0.1",
+	Replicas:        2,
+	InvocationCount: 42,
+	EnvProcess:      "figlet",
+	EnvVars:         map[string]string{"write_debug": "true", "read_timeout": "10s"},
+	Labels:          map[string]string{"team": "fonts"},
+	Annotations:     map[string]string{"topic": "banners", configHashAnnotation: "abc", stack.OwnerAnnotation: "fonts-team", stack.RunbookAnnotation: "https://wiki.example.com/figlet"},
+	Secrets:         []string{"api-key"},
+	Limits:          &stack.FunctionResources{Memory: "128Mi"},
+}
+
+func Test_describe(t *testing.T) {
+	defer func() { describeOutput = "text" }()
+	resetForTest()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       describedFunction,
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       describedFunction,
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"describe", "figlet", "--gateway=" + s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	for _, want := range []string{"Replicas:    2", "Invocations: 42", "Environment: read_timeout=10s\n             write_debug=true", "Secrets:     api-key", "Limits:      memory=128Mi", "URL:         " + s.URL + "/function/figlet", "Owner:       fonts-team", "Runbook:     https://wiki.example.com/figlet"} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in the description, got:\n%s", want, stdOut)
+		}
+	}
+
+	stdOut = test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"describe", "figlet", "--gateway=" + s.URL, "--output", "yaml"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	services, err := stack.ParseYAMLData([]byte(stdOut), "", "")
+	if err != nil {
+		t.Fatalf("want a stack which parses, got %s:\n%s", err, stdOut)
+	}
+	function := services.Functions["figlet"]
+	if services.Provider.GatewayURL != s.URL || function.Image != "functions/figlet:0.1" || function.FProcess != "figlet" || !function.SkipBuild {
+		t.Errorf("want the function as deployed, got %+v", function)
+	}
+	if !reflect.DeepEqual(*function.Annotations, map[string]string{"topic": "banners"}) {
+		t.Errorf("want the annotations deploy sets left out, got %v", *function.Annotations)
+	}
+	if function.Metadata == nil || function.Metadata.Owner != "fonts-team" || function.Metadata.Runbook != "https://wiki.example.com/figlet" {
+		t.Errorf("want the metadata written as metadata, got %+v", function.Metadata)
+	}
+}
+
+func Test_describe_NotFound(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet?namespace=staging",
+			ResponseStatusCode: http.StatusNotFound,
+		},
+	})
+	defer s.Close()
+
+	faasCmd.SetArgs([]string{"describe", "figlet", "--gateway=" + s.URL, "--namespace", "staging"})
+	err := faasCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "function figlet.staging was not found") {
+		t.Errorf("want a not found error, got %v", err)
+	}
+}
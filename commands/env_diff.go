@@ -0,0 +1,171 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// sensitiveEnvKey matches the names of environment variables whose values
+// are masked by env diff
+var sensitiveEnvKey = regexp.MustCompile(`(?i)(secret|passw|token|key|credential|auth|private)`)
+
+func init() {
+	envDiffCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+
+	envCmd.AddCommand(envDiffCmd)
+	faasCmd.AddCommand(envCmd)
+}
+
+var envCmd = &cobra.Command{
+	Use:   `env`,
+	Short: "Inspect the environment of functions",
+}
+
+var envDiffCmd = &cobra.Command{
+	Use:   `diff FUNCTION_NAME -f YAML_FILE [--gateway GATEWAY_URL]`,
+	Short: "Compare a function's environment in the YAML file with the deployed one",
+	Long: `Resolves a function's environment from the YAML file, including its
+environment_file entries, and compares it with the environment of the function
+deployed to the gateway.
+
+  + KEY  is in the YAML file but not deployed
+  - KEY  is deployed but not in the YAML file
+  ~ KEY  has a different value
+
+The values of variables whose names look sensitive, such as API_TOKEN or
+DB_PASSWORD, are masked.`,
+	Example: `  faas-cli env diff api -f ./stack.yml
+  faas-cli env diff api -f ./stack.yml -g https://openfaas.example.com`,
+	RunE: runEnvDiff,
+}
+
+// envChange is a variable which differs between the YAML file and the gateway
+type envChange struct {
+	Key      string
+	Local    string
+	Deployed string
+
+	// Kind is + when only in the YAML file, - when only deployed and ~ when
+	// the values differ
+	Kind string
+}
+
+func runEnvDiff(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the function")
+	}
+	name := args[0]
+
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the function with --yaml")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, "", "")
+	if err != nil {
+		return err
+	}
+	function, ok := services.Functions[name]
+	if !ok {
+		return fmt.Errorf("function %s was not found in %s", name, yamlFile)
+	}
+
+	fileEnvironment, err := readFiles(function.EnvironmentFile)
+	if err != nil {
+		return err
+	}
+	local, err := compileEnvironment(nil, functionEnvironment(function), fileEnvironment)
+	if err != nil {
+		return err
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, services.Provider.GatewayURL)
+	deployedFunctions, err := functionStatusByName(gatewayAddress, "")
+	if err != nil {
+		return err
+	}
+	deployed, ok := deployedFunctions[name]
+	if !ok {
+		return fmt.Errorf("function %s is not deployed to %s", name, gatewayAddress)
+	}
+
+	fmt.Printf("Environment of %s in %s compared with %s:\n\n", name, yamlFile, gatewayAddress)
+	changes, unchanged := diffEnvironment(local, deployed.EnvVars)
+	printEnvDiff(os.Stdout, changes, unchanged)
+	return nil
+}
+
+// diffEnvironment lists the variables which differ, sorted by name, and counts
+// those which match
+func diffEnvironment(local map[string]string, deployed map[string]string) ([]envChange, int) {
+	keys := map[string]bool{}
+	for key := range local {
+		keys[key] = true
+	}
+	for key := range deployed {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var changes []envChange
+	unchanged := 0
+	for _, key := range sorted {
+		localValue, inLocal := local[key]
+		deployedValue, isDeployed := deployed[key]
+
+		switch {
+		case !isDeployed:
+			changes = append(changes, envChange{Key: key, Local: localValue, Kind: "+"})
+		case !inLocal:
+			changes = append(changes, envChange{Key: key, Deployed: deployedValue, Kind: "-"})
+		case localValue != deployedValue:
+			changes = append(changes, envChange{Key: key, Local: localValue, Deployed: deployedValue, Kind: "~"})
+		default:
+			unchanged++
+		}
+	}
+	return changes, unchanged
+}
+
+func printEnvDiff(w io.Writer, changes []envChange, unchanged int) {
+	for _, change := range changes {
+		switch change.Kind {
+		case "+":
+			fmt.Fprintln(w, aec.GreenF.Apply(fmt.Sprintf("+ %s=%s", change.Key, maskEnvValue(change.Key, change.Local))))
+		case "-":
+			fmt.Fprintln(w, aec.RedF.Apply(fmt.Sprintf("- %s=%s", change.Key, maskEnvValue(change.Key, change.Deployed))))
+		default:
+			if sensitiveEnvKey.MatchString(change.Key) {
+				fmt.Fprintln(w, aec.YellowF.Apply(fmt.Sprintf("~ %s: the masked values differ", change.Key)))
+			} else {
+				fmt.Fprintln(w, aec.YellowF.Apply(fmt.Sprintf("~ %s: %s (deployed) -> %s (YAML file)", change.Key, change.Deployed, change.Local)))
+			}
+		}
+	}
+
+	if len(changes) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d variable(s) differ, %d unchanged.\n", len(changes), unchanged)
+}
+
+func maskEnvValue(key string, value string) string {
+	if sensitiveEnvKey.MatchString(key) {
+		return "********"
+	}
+	return value
+}
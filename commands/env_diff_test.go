@@ -0,0 +1,116 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_diffEnvironment(t *testing.T) {
+	local := map[string]string{"LOG_LEVEL": "info", "NEW": "1", "SAME": "x"}
+	deployed := map[string]string{"LOG_LEVEL": "debug", "OLD": "2", "SAME": "x"}
+
+	changes, unchanged := diffEnvironment(local, deployed)
+
+	want := []envChange{
+		{Key: "LOG_LEVEL", Local: "info", Deployed: "debug", Kind: "~"},
+		{Key: "NEW", Local: "1", Kind: "+"},
+		{Key: "OLD", Deployed: "2", Kind: "-"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("want changes %v, got %v", want, changes)
+	}
+	if unchanged != 1 {
+		t.Fatalf("want 1 unchanged, got %d", unchanged)
+	}
+}
+
+func Test_printEnvDiff_MasksSensitiveValues(t *testing.T) {
+	changes := []envChange{
+		{Key: "API_TOKEN", Local: "abc", Deployed: "def", Kind: "~"},
+		{Key: "DB_PASSWORD", Local: "hunter2", Kind: "+"},
+		{Key: "LOG_LEVEL", Local: "info", Deployed: "debug", Kind: "~"},
+	}
+
+	var out bytes.Buffer
+	printEnvDiff(&out, changes, 2)
+
+	output := out.String()
+	for _, secret := range []string{"abc", "def", "hunter2"} {
+		if strings.Contains(output, secret) {
+			t.Fatalf("want %q masked, got:\n%s", secret, output)
+		}
+	}
+	for _, want := range []string{"~ API_TOKEN: the masked values differ", "+ DB_PASSWORD=********", "~ LOG_LEVEL: debug (deployed) -> info (YAML file)", "3 variable(s) differ, 2 unchanged."} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("want %q in output, got:\n%s", want, output)
+		}
+	}
+}
+
+func Test_runEnvDiff(t *testing.T) {
+	resetForTest()
+
+	dir, err := ioutil.TempDir("", "faas-cli-env-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api", Image: "api:0.1", EnvVars: map[string]string{"LOG_LEVEL": "debug", "REGION": "eu"}},
+			},
+		},
+	})
+	defer s.Close()
+
+	envFile := filepath.Join(dir, "env.yml")
+	if err := ioutil.WriteFile(envFile, []byte("environment:\n  REGION: eu\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	stackFile := filepath.Join(dir, "stack.yml")
+	stackYAML := `provider:
+  name: faas
+functions:
+  api:
+    lang: go
+    handler: ./api
+    image: api:0.1
+    environment:
+      LOG_LEVEL: info
+    environment_file:
+      - ` + envFile + `
+`
+	if err := ioutil.WriteFile(stackFile, []byte(stackYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	output := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"env", "diff", "api", "-f", stackFile, "-g", s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, want := range []string{"~ LOG_LEVEL: debug (deployed) -> info (YAML file)", "1 variable(s) differ, 1 unchanged."} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("want %q in output, got:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// Status of a function in an envs diff report
+const (
+	envsSame     = "same"
+	envsDiffers  = "differs"
+	envsOnlyFrom = "only_in_from"
+	envsOnlyTo   = "only_in_to"
+)
+
+var (
+	envsDiffJSON     bool
+	envsDiffExitCode bool
+)
+
+func init() {
+	envsDiffCmd.Flags().BoolVar(&envsDiffJSON, "json", false, "Print the report as JSON")
+	envsDiffCmd.Flags().BoolVar(&envsDiffExitCode, "exit-code", false, "Exit with an error when any function differs")
+
+	envsCmd.AddCommand(envsDiffCmd)
+	faasCmd.AddCommand(envsCmd)
+}
+
+var envsCmd = &cobra.Command{
+	Use:   `envs`,
+	Short: "Compare the gateways a stack is deployed to",
+}
+
+var envsDiffCmd = &cobra.Command{
+	Use:   `diff FROM TO [-f YAML_FILE] [--json] [--exit-code]`,
+	Short: "Compare what is deployed to two gateways",
+	Long: `Compares the functions deployed to two gateways, given as the names of
+contexts from the config file or as URLs: their image digests, environment,
+replicas and the secrets they use. This answers whether prod runs what was
+validated on staging.
+
+With a YAML file only the functions of the stack are compared, narrowed with
+--filter or --regex, otherwise every function deployed to either gateway is.
+
+The values of variables whose names look sensitive, such as API_TOKEN or
+DB_PASSWORD, are masked, including in the JSON report.`,
+	Example: `  faas-cli envs diff staging prod -f ./stack.yml
+  faas-cli envs diff staging prod -f ./stack.yml --json
+  faas-cli envs diff https://staging.example.com prod --exit-code`,
+	RunE: runEnvsDiff,
+}
+
+// envsDiffReport is the divergence of the functions deployed to two gateways
+type envsDiffReport struct {
+	From      string               `json:"from"`
+	To        string               `json:"to"`
+	Functions []functionDivergence `json:"functions"`
+	Diverged  int                  `json:"diverged"`
+}
+
+// functionDivergence is how a function differs between the gateways, only
+// what differs is set
+type functionDivergence struct {
+	Function string `json:"function"`
+	Status   string `json:"status"`
+
+	Image    *divergentValue `json:"image,omitempty"`
+	Digest   *divergentValue `json:"digest,omitempty"`
+	Replicas *divergentValue `json:"replicas,omitempty"`
+
+	Environment []divergentEnv `json:"environment,omitempty"`
+
+	SecretsOnlyInFrom []string `json:"secrets_only_in_from,omitempty"`
+	SecretsOnlyInTo   []string `json:"secrets_only_in_to,omitempty"`
+}
+
+type divergentValue struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// divergentEnv is a variable which differs, a missing side is left out
+type divergentEnv struct {
+	Key  string  `json:"key"`
+	From *string `json:"from,omitempty"`
+	To   *string `json:"to,omitempty"`
+}
+
+func runEnvsDiff(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("please provide the two contexts or gateway URLs to compare")
+	}
+
+	fromGateway, err := config.LookupContext(args[0])
+	if err != nil {
+		return err
+	}
+	toGateway, err := config.LookupContext(args[1])
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	if len(yamlFile) > 0 {
+		services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+		if err != nil {
+			return err
+		}
+		if len(services.Functions) == 0 {
+			return fmt.Errorf("no functions in %s match the filter", yamlFile)
+		}
+		for name := range services.Functions {
+			names = append(names, name)
+		}
+	}
+
+	report, err := diffGateways(fromGateway, toGateway, names)
+	if err != nil {
+		return err
+	}
+
+	if envsDiffJSON {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	} else {
+		fmt.Printf("Comparing %s (%s) with %s (%s):\n\n", args[0], fromGateway, args[1], toGateway)
+		printEnvsDiff(os.Stdout, report, args[0], args[1])
+	}
+
+	if envsDiffExitCode && report.Diverged > 0 {
+		return fmt.Errorf("%d function(s) differ between %s and %s", report.Diverged, args[0], args[1])
+	}
+	return nil
+}
+
+// diffGateways compares the named functions on both gateways, or every
+// function deployed to either when names is empty
+func diffGateways(fromGateway string, toGateway string, names []string) (envsDiffReport, error) {
+	report := envsDiffReport{From: fromGateway, To: toGateway, Functions: []functionDivergence{}}
+
+	from, err := functionStatusByName(fromGateway, "")
+	if err != nil {
+		return report, err
+	}
+	to, err := functionStatusByName(toGateway, "")
+	if err != nil {
+		return report, err
+	}
+
+	if len(names) == 0 {
+		seen := map[string]bool{}
+		for _, deployed := range []map[string]proxy.FunctionStatus{from, to} {
+			for name := range deployed {
+				if !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fromStatus, inFrom := from[name]
+		toStatus, inTo := to[name]
+
+		var divergence functionDivergence
+		switch {
+		case !inFrom && !inTo:
+			continue
+		case !inTo:
+			divergence = functionDivergence{Function: name, Status: envsOnlyFrom}
+		case !inFrom:
+			divergence = functionDivergence{Function: name, Status: envsOnlyTo}
+		default:
+			divergence = diffFunction(fromStatus, toStatus)
+		}
+
+		if divergence.Status != envsSame {
+			report.Diverged++
+		}
+		report.Functions = append(report.Functions, divergence)
+	}
+	return report, nil
+}
+
+// diffFunction compares a function deployed to both gateways
+func diffFunction(from proxy.FunctionStatus, to proxy.FunctionStatus) functionDivergence {
+	divergence := functionDivergence{Function: from.Name, Status: envsSame}
+
+	// A digest which can't be read is left out, the images are still compared
+	fromDigest, fromErr := deployedDigest(from)
+	toDigest, toErr := deployedDigest(to)
+	if fromErr == nil && toErr == nil && fromDigest != toDigest {
+		divergence.Digest = &divergentValue{From: fromDigest, To: toDigest}
+	}
+	if from.Image != to.Image && (divergence.Digest != nil || fromErr != nil || toErr != nil) {
+		divergence.Image = &divergentValue{From: from.Image, To: to.Image}
+	}
+
+	if from.Replicas != to.Replicas {
+		divergence.Replicas = &divergentValue{From: fmt.Sprintf("%d", from.Replicas), To: fmt.Sprintf("%d", to.Replicas)}
+	}
+
+	changes, _ := diffEnvironment(from.EnvVars, to.EnvVars)
+	for _, change := range changes {
+		env := divergentEnv{Key: change.Key}
+		if change.Kind != "-" {
+			value := maskEnvValue(change.Key, change.Local)
+			env.From = &value
+		}
+		if change.Kind != "+" {
+			value := maskEnvValue(change.Key, change.Deployed)
+			env.To = &value
+		}
+		divergence.Environment = append(divergence.Environment, env)
+	}
+
+	divergence.SecretsOnlyInFrom = missingFrom(from.Secrets, to.Secrets)
+	divergence.SecretsOnlyInTo = missingFrom(to.Secrets, from.Secrets)
+
+	if divergence.Digest != nil || divergence.Image != nil || divergence.Replicas != nil ||
+		len(divergence.Environment) > 0 || len(divergence.SecretsOnlyInFrom) > 0 || len(divergence.SecretsOnlyInTo) > 0 {
+		divergence.Status = envsDiffers
+	}
+	return divergence
+}
+
+// missingFrom gives the values of a which are not in b, sorted
+func missingFrom(a []string, b []string) []string {
+	inB := map[string]bool{}
+	for _, value := range b {
+		inB[value] = true
+	}
+
+	var missing []string
+	for _, value := range a {
+		if !inB[value] {
+			missing = append(missing, value)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func printEnvsDiff(w io.Writer, report envsDiffReport, fromName string, toName string) {
+	for _, function := range report.Functions {
+		switch function.Status {
+		case envsSame:
+			fmt.Fprintf(w, "= %s\n", function.Function)
+			continue
+		case envsOnlyFrom:
+			fmt.Fprintln(w, aec.RedF.Apply(fmt.Sprintf("- %s (only on %s)", function.Function, fromName)))
+			continue
+		case envsOnlyTo:
+			fmt.Fprintln(w, aec.GreenF.Apply(fmt.Sprintf("+ %s (only on %s)", function.Function, toName)))
+			continue
+		}
+
+		fmt.Fprintln(w, aec.YellowF.Apply(fmt.Sprintf("~ %s", function.Function)))
+		if function.Image != nil {
+			fmt.Fprintf(w, "    image: %s -> %s\n", function.Image.From, function.Image.To)
+		}
+		if function.Digest != nil {
+			fmt.Fprintf(w, "    digest: %s -> %s\n", function.Digest.From, function.Digest.To)
+		}
+		if function.Replicas != nil {
+			fmt.Fprintf(w, "    replicas: %s -> %s\n", function.Replicas.From, function.Replicas.To)
+		}
+		for _, env := range function.Environment {
+			switch {
+			case env.To == nil:
+				fmt.Fprintf(w, "    env %s=%s only on %s\n", env.Key, *env.From, fromName)
+			case env.From == nil:
+				fmt.Fprintf(w, "    env %s=%s only on %s\n", env.Key, *env.To, toName)
+			case sensitiveEnvKey.MatchString(env.Key):
+				fmt.Fprintf(w, "    env %s: the masked values differ\n", env.Key)
+			default:
+				fmt.Fprintf(w, "    env %s: %s -> %s\n", env.Key, *env.From, *env.To)
+			}
+		}
+		for _, secret := range function.SecretsOnlyInFrom {
+			fmt.Fprintf(w, "    secret %s only on %s\n", secret, fromName)
+		}
+		for _, secret := range function.SecretsOnlyInTo {
+			fmt.Fprintf(w, "    secret %s only on %s\n", secret, toName)
+		}
+	}
+
+	if len(report.Functions) > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d of %d function(s) differ.\n", report.Diverged, len(report.Functions))
+}
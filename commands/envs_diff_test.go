@@ -0,0 +1,101 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_diffGateways(t *testing.T) {
+	oldDigest := registryDigest
+	defer func() { registryDigest = oldDigest }()
+	registryDigest = func(image string) (string, error) {
+		switch image {
+		case "api:1", "api:latest":
+			return "sha256:aaa", nil
+		case "web:2":
+			return "sha256:ccc", nil
+		}
+		return "", fmt.Errorf("unknown image %s", image)
+	}
+
+	staging := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api", Image: "api:1", Replicas: 1, EnvVars: map[string]string{"mode": "live"}},
+				{Name: "web", Image: "web:2", Replicas: 1, Secrets: []string{"db", "token"},
+					EnvVars: map[string]string{"level": "debug", "api_token": "staging", "extra": "1"}},
+				{Name: "worker", Image: "worker:1"},
+			},
+		},
+	})
+	defer staging.Close()
+
+	prod := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api", Image: "api:latest", Replicas: 1, EnvVars: map[string]string{"mode": "live"}},
+				{Name: "web", Image: "web@sha256:bbb", Replicas: 3, Secrets: []string{"db", "cert"},
+					EnvVars: map[string]string{"level": "info", "api_token": "prod"}},
+				{Name: "cron", Image: "cron:1"},
+			},
+		},
+	})
+	defer prod.Close()
+
+	report, err := diffGateways(staging.URL, prod.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var statuses []string
+	for _, function := range report.Functions {
+		statuses = append(statuses, function.Function+":"+function.Status)
+	}
+	if got := strings.Join(statuses, ","); got != "api:same,cron:only_in_to,web:differs,worker:only_in_from" {
+		t.Fatalf("got %s", got)
+	}
+	if report.Diverged != 3 {
+		t.Errorf("want 3 diverged, got %d", report.Diverged)
+	}
+
+	web := report.Functions[2]
+	if web.Digest == nil || web.Digest.From != "sha256:ccc" || web.Digest.To != "sha256:bbb" || web.Image == nil {
+		t.Errorf("want the image and digest to differ, got %+v %+v", web.Image, web.Digest)
+	}
+	if web.Replicas == nil || web.Replicas.From != "1" || web.Replicas.To != "3" {
+		t.Errorf("want the replicas to differ, got %+v", web.Replicas)
+	}
+	if strings.Join(web.SecretsOnlyInFrom, ",") != "token" || strings.Join(web.SecretsOnlyInTo, ",") != "cert" {
+		t.Errorf("got secrets %v and %v", web.SecretsOnlyInFrom, web.SecretsOnlyInTo)
+	}
+
+	out, _ := json.Marshal(web.Environment)
+	if want := `[{"key":"api_token","from":"********","to":"********"},{"key":"extra","from":"1"},{"key":"level","from":"debug","to":"info"}]`; string(out) != want {
+		t.Errorf("want environment %s, got %s", want, out)
+	}
+
+	var buf bytes.Buffer
+	printEnvsDiff(&buf, report, "staging", "prod")
+	text := buf.String()
+	for _, want := range []string{"= api\n", "    replicas: 1 -> 3\n", "    env api_token: the masked values differ\n", "    secret cert only on prod\n", "3 of 4 function(s) differ.\n"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("want %q in the output:\n%s", want, text)
+		}
+	}
+}
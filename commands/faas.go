@@ -5,10 +5,14 @@ package commands
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/docker/docker/pkg/term"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
 	"github.com/spf13/cobra"
 )
 
@@ -23,6 +27,7 @@ var (
 	yamlFile string
 	regex    string
 	filter   string
+	workdir  string
 )
 
 // Flags that are to be added to subset of commands.
@@ -36,6 +41,35 @@ var (
 	language     string
 )
 
+// stackFilesFlag is -f, the first file given is the YAML file and each one
+// after it is merged over it as one of stack.OverlayFiles
+type stackFilesFlag struct {
+	// given is set once -f has been parsed for this run, so that a default
+	// stack.yml is replaced rather than overlaid
+	given bool
+}
+
+func (f *stackFilesFlag) Set(value string) error {
+	if !f.given {
+		yamlFile = value
+		stack.OverlayFiles = nil
+		f.given = true
+		return nil
+	}
+	stack.OverlayFiles = append(stack.OverlayFiles, value)
+	return nil
+}
+
+func (f *stackFilesFlag) String() string {
+	return yamlFile
+}
+
+func (f *stackFilesFlag) Type() string {
+	return "string"
+}
+
+var stackFiles stackFilesFlag
+
 var stat = func(filename string) (os.FileInfo, error) {
 	return os.Stat(filename)
 }
@@ -45,21 +79,87 @@ func resetForTest() {
 	yamlFile = ""
 	regex = ""
 	filter = ""
+	workdir = ""
+	functionNamespace = ""
+	stack.EnvSubst = false
+	stack.OverlayFiles = nil
+	stackFiles.given = false
 }
 
 func init() {
 	// Setup terminal std
 	term.StdStreams()
 
-	faasCmd.PersistentFlags().StringVarP(&yamlFile, "yaml", "f", "", "Path to YAML file describing function(s)")
+	faasCmd.PersistentFlags().VarP(&stackFiles, "yaml", "f", "Path to YAML file describing function(s), or - to read it from stdin. Repeat it to merge more files over the first, i.e. -f stack.yml -f prod.yml")
+	faasCmd.PersistentFlags().StringVar(&workdir, "workdir", "", "Directory relative paths in the YAML file, such as handlers, are resolved from")
 	faasCmd.PersistentFlags().StringVarP(&regex, "regex", "", "", "Regex to match with function names in YAML file")
 	faasCmd.PersistentFlags().StringVarP(&filter, "filter", "", "", "Wildcard to match with function names in YAML file")
+	faasCmd.PersistentFlags().BoolVar(&stack.EnvSubst, "envsubst", false, "Expand ${VAR} and ${VAR:-default} from the environment anywhere in the YAML file before it is parsed")
+	faasCmd.PersistentFlags().StringVar(&stack.TemplateDirectory, "template-dir", defaultTemplateDirectory(), "Folder language templates are read from and pulled into, also set by FAAS_TEMPLATE_DIR")
+
+	faasCmd.PersistentPreRunE = persistentPreRun
 
 	// Set Bash completion options
 	validYAMLFilenames := []string{"yaml", "yml"}
 	_ = faasCmd.PersistentFlags().SetAnnotation("yaml", cobra.BashCompFilenameExt, validYAMLFilenames)
 }
 
+// persistentPreRun moves into --workdir, keeps state in --state-dir, warns
+// about deprecated flags and stack fields, asks for rejected gateway
+// credentials again and starts the metrics server before running any command
+func persistentPreRun(cmd *cobra.Command, args []string) error {
+	// The next -f starts a new list of files
+	stackFiles.given = false
+
+	if err := changeWorkdir(); err != nil {
+		return err
+	}
+	if err := useStateDir(); err != nil {
+		return err
+	}
+	if err := warnDeprecations(cmd, args); err != nil {
+		return err
+	}
+	proxy.Reauthenticate = reauthenticate
+	return startMetrics()
+}
+
+// changeWorkdir moves into --workdir so that relative paths in the stack are
+// resolved from there, a local YAML file is still found from where faas-cli
+// was run
+func changeWorkdir() error {
+	if len(workdir) == 0 {
+		return nil
+	}
+
+	var err error
+	if yamlFile, err = absoluteStackFile(yamlFile); err != nil {
+		return err
+	}
+	for i, overlayFile := range stack.OverlayFiles {
+		if stack.OverlayFiles[i], err = absoluteStackFile(overlayFile); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Chdir(workdir); err != nil {
+		return fmt.Errorf("unable to use --workdir: %s", err)
+	}
+	return nil
+}
+
+// absoluteStackFile makes the path of a local YAML file absolute, URLs and
+// stdin are left as they are
+func absoluteStackFile(file string) (string, error) {
+	if len(file) == 0 || file == stack.StdinFile {
+		return file, nil
+	}
+	if parsed, err := url.Parse(file); err == nil && len(parsed.Scheme) > 1 {
+		return file, nil
+	}
+	return filepath.Abs(file)
+}
+
 // Execute TODO
 func Execute(customArgs []string) {
 	checkAndSetDefaultYaml()
@@ -74,6 +174,15 @@ func Execute(customArgs []string) {
 	}
 }
 
+// defaultTemplateDirectory gives FAAS_TEMPLATE_DIR when it is set or ./template
+func defaultTemplateDirectory() string {
+	if templateDir, exists := os.LookupEnv("FAAS_TEMPLATE_DIR"); exists && len(templateDir) > 0 {
+		return templateDir
+	}
+
+	return "./template"
+}
+
 func checkAndSetDefaultYaml() {
 	// Check if there is a default yaml file and set it
 	if _, err := stat(defaultYAML); err == nil {
@@ -88,11 +197,15 @@ var faasCmd = &cobra.Command{
 	Short: "Manage your OpenFaaS functions from the command line",
 	Long: `
 Manage your OpenFaaS functions from the command line`,
-	Run: runFaas,
+	RunE: runFaas,
 }
 
 // runFaas TODO
-func runFaas(cmd *cobra.Command, args []string) {
+func runFaas(cmd *cobra.Command, args []string) error {
+	if rootVersion {
+		return runVersion(cmd, args)
+	}
+
 	printFiglet()
-	cmd.Help()
+	return cmd.Help()
 }
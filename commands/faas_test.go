@@ -3,7 +3,10 @@ package commands
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
 )
 
 var mockStatParams string
@@ -58,3 +61,62 @@ func TestDoesNotLoadDefaultYAMLWhenMissing(t *testing.T) {
 		t.Fatalf("Expected yamlFile to be blank got %v\n", yamlFile)
 	}
 }
+
+func Test_defaultTemplateDirectory(t *testing.T) {
+	os.Unsetenv("FAAS_TEMPLATE_DIR")
+	if dir := defaultTemplateDirectory(); dir != "./template" {
+		t.Fatalf("Expected ./template got %v\n", dir)
+	}
+
+	os.Setenv("FAAS_TEMPLATE_DIR", "/opt/openfaas/template")
+	defer os.Unsetenv("FAAS_TEMPLATE_DIR")
+
+	if dir := defaultTemplateDirectory(); dir != "/opt/openfaas/template" {
+		t.Fatalf("Expected /opt/openfaas/template got %v\n", dir)
+	}
+}
+
+func Test_changeWorkdir(t *testing.T) {
+	defer resetForTest()
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+
+	dir, err := ioutil.TempDir("", "faas-cli-workdir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlFile = "stack.yml"
+	workdir = dir
+	if err := changeWorkdir(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if want := filepath.Join(cwd, "stack.yml"); yamlFile != want {
+		t.Errorf("want the YAML file resolved to %s, got %s", want, yamlFile)
+	}
+	now, _ := os.Getwd()
+	if resolved, _ := filepath.EvalSymlinks(dir); now != resolved && now != dir {
+		t.Errorf("want the working directory %s, got %s", dir, now)
+	}
+
+	for _, unchanged := range []string{stack.StdinFile, "https://example.com/stack.yml"} {
+		yamlFile = unchanged
+		if err := changeWorkdir(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if yamlFile != unchanged {
+			t.Errorf("want %s unchanged, got %s", unchanged, yamlFile)
+		}
+	}
+
+	workdir = filepath.Join(dir, "missing")
+	if err := changeWorkdir(); err == nil {
+		t.Errorf("expected an error for a missing --workdir")
+	}
+}
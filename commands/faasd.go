@@ -0,0 +1,290 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// faasdNamespace is the only namespace faasd runs functions in
+	faasdNamespace = "openfaas-fn"
+
+	// faasdScaleLabelPrefix starts the labels which configure autoscaling,
+	// faasd runs one replica of each function and ignores them
+	faasdScaleLabelPrefix = "com.openfaas.scale."
+
+	defaultFaasdComposeFile = "/var/lib/faasd/docker-compose.yaml"
+	faasdSecretDirectory    = "/var/lib/faasd-provider/secrets/" + faasdNamespace
+)
+
+var (
+	faasdComposeFile string
+	faasdServicePort int
+	faasdRestart     bool
+)
+
+var (
+	// systemInfo and restartFaasd are swapped out in tests
+	systemInfo   = proxy.GetSystemInfo
+	restartFaasd = func() error {
+		output, err := exec.Command("systemctl", "restart", "faasd").CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("unable to restart faasd: %s", strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
+	// faasdGateways remembers which gateways are faasd so /system/info is
+	// only read once per gateway
+	faasdGateways = map[string]bool{}
+)
+
+func init() {
+	faasdInstallServiceCmd.Flags().StringVar(&faasdComposeFile, "compose-file", defaultFaasdComposeFile, "faasd's docker-compose.yaml to add the services to")
+	faasdInstallServiceCmd.Flags().IntVar(&faasdServicePort, "port", 0, "Publish the service's port 8080 on this port of the host's loopback interface, only for a single function")
+	faasdInstallServiceCmd.Flags().BoolVar(&faasdRestart, "restart", false, "Restart faasd with systemctl so that the services are started")
+
+	faasdCmd.AddCommand(faasdInstallServiceCmd)
+	faasCmd.AddCommand(faasdCmd)
+}
+
+var faasdCmd = &cobra.Command{
+	Use:   `faasd`,
+	Short: "Helpers for faasd and OpenFaaS Edge",
+	Long: `Helpers for faasd and OpenFaaS Edge, which run functions with containerd on a
+single host.
+
+Other commands detect faasd from the gateway's /system/info. deploy refuses
+namespaces other than openfaas-fn and drops com.openfaas.scale.* labels with a
+warning, as faasd runs one replica of each function in openfaas-fn.`,
+}
+
+var faasdInstallServiceCmd = &cobra.Command{
+	Use:   `install-service -f YAML_FILE [FUNCTION_NAME...] [--compose-file PATH] [--port PORT] [--restart]`,
+	Short: "Run functions as always-on faasd services",
+	Long: `Adds the functions in the YAML file, or those named, to faasd's
+docker-compose.yaml as services. faasd starts its services when it starts and
+keeps them running, so a function on an edge device works without the gateway
+and without being invoked first, i.e. to read from a sensor.
+
+Each service gets the function's image, its environment and environment_file
+entries, and its secrets from faasd's secret store mounted where functions
+find them. A service of the same name is replaced. Comments in the compose
+file are not kept. Run faasd install-service as root on the device, then
+restart faasd, or pass --restart.`,
+	Example: `  sudo faas-cli faasd install-service -f stack.yml
+  sudo faas-cli faasd install-service -f stack.yml sensor-reader --port 8081 --restart`,
+	RunE: runFaasdInstallService,
+}
+
+// isFaasd reports whether the gateway is faasd or OpenFaaS Edge, gateways
+// which cannot be asked are treated as other providers
+func isFaasd(gateway string) bool {
+	faasd, checked := faasdGateways[gateway]
+	if !checked {
+		info, err := systemInfo(gateway)
+		faasd = err == nil && info.Faasd()
+		faasdGateways[gateway] = faasd
+	}
+	return faasd
+}
+
+// adaptForFaasd rejects a namespace faasd can't deploy to and removes the
+// scaling labels it ignores, saying so for each one. The gateway is only
+// asked whether it is faasd when the function uses either of them.
+func adaptForFaasd(gateway string, spec *proxy.DeployFunctionSpec) error {
+	var ignored []string
+	for label := range spec.Labels {
+		if strings.HasPrefix(label, faasdScaleLabelPrefix) {
+			ignored = append(ignored, label)
+		}
+	}
+	otherNamespace := len(spec.Namespace) > 0 && spec.Namespace != faasdNamespace
+	if (!otherNamespace && len(ignored) == 0) || !isFaasd(gateway) {
+		return nil
+	}
+
+	if otherNamespace {
+		return fmt.Errorf("function %s: faasd only runs functions in the %s namespace, not %s, remove --namespace or the function's namespace",
+			spec.FunctionName, faasdNamespace, spec.Namespace)
+	}
+
+	sort.Strings(ignored)
+	for _, label := range ignored {
+		fmt.Printf("Warning: faasd does not scale functions, ignoring label %s on %s.\n", label, spec.FunctionName)
+		delete(spec.Labels, label)
+	}
+	return nil
+}
+
+func runFaasdInstallService(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the functions to install with -f")
+	}
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+
+	names := args
+	if len(names) == 0 {
+		for name := range services.Functions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no functions were found in %s", yamlFile)
+	}
+	if faasdServicePort > 0 && len(names) > 1 {
+		return fmt.Errorf("--port can only be given for a single function, found %d", len(names))
+	}
+
+	var entries []yaml.MapItem
+	for _, name := range names {
+		function, ok := services.Functions[name]
+		if !ok {
+			return fmt.Errorf("function %s was not found in %s", name, yamlFile)
+		}
+		function.Name = name
+
+		entry, err := faasdService(function, faasdServicePort)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, yaml.MapItem{Key: name, Value: entry})
+	}
+
+	if err := addFaasdServices(faasdComposeFile, entries); err != nil {
+		return err
+	}
+	for _, name := range names {
+		fmt.Printf("Service %s added to %s.\n", name, faasdComposeFile)
+	}
+
+	if !faasdRestart {
+		fmt.Println("Run \"sudo systemctl restart faasd\" to start the services.")
+		return nil
+	}
+	return restartFaasd()
+}
+
+// faasdService gives the docker-compose service which runs the function
+func faasdService(function stack.Function, port int) (yaml.MapSlice, error) {
+	if len(function.Image) == 0 {
+		return nil, fmt.Errorf("function %s has no image", function.Name)
+	}
+
+	fileEnvironment, err := readFiles(function.EnvironmentFile)
+	if err != nil {
+		return nil, err
+	}
+	environment, err := compileEnvironment(nil, functionEnvironment(function), fileEnvironment)
+	if err != nil {
+		return nil, err
+	}
+	if len(function.FProcess) > 0 {
+		environment["fprocess"] = function.FProcess
+	}
+
+	// containerd needs the registry of every image
+	service := yaml.MapSlice{{Key: "image", Value: qualifyImage(function.Image)}}
+
+	if len(environment) > 0 {
+		var keys []string
+		for key := range environment {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var env yaml.MapSlice
+		for _, key := range keys {
+			env = append(env, yaml.MapItem{Key: key, Value: environment[key]})
+		}
+		service = append(service, yaml.MapItem{Key: "environment", Value: env})
+	}
+
+	if len(function.Secrets) > 0 {
+		var volumes []yaml.MapSlice
+		for _, secret := range function.Secrets {
+			volumes = append(volumes, yaml.MapSlice{
+				{Key: "type", Value: "bind"},
+				{Key: "source", Value: path.Join(faasdSecretDirectory, secret)},
+				{Key: "target", Value: path.Join("/var/openfaas/secrets", secret)},
+			})
+		}
+		service = append(service, yaml.MapItem{Key: "volumes", Value: volumes})
+	}
+
+	if port > 0 {
+		service = append(service, yaml.MapItem{Key: "ports", Value: []string{fmt.Sprintf("127.0.0.1:%d:8080", port)}})
+	}
+	return service, nil
+}
+
+// addFaasdServices adds or replaces the services in the compose file,
+// keeping the order of everything else in it
+func addFaasdServices(composeFile string, entries []yaml.MapItem) error {
+	data, err := ioutil.ReadFile(composeFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s was not found, is faasd installed? Give its compose file with --compose-file", composeFile)
+		}
+		return err
+	}
+
+	var compose yaml.MapSlice
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return fmt.Errorf("unable to parse %s: %s", composeFile, err)
+	}
+
+	servicesIndex := -1
+	for i, item := range compose {
+		if item.Key == "services" {
+			servicesIndex = i
+		}
+	}
+	if servicesIndex < 0 {
+		compose = append(compose, yaml.MapItem{Key: "services", Value: yaml.MapSlice{}})
+		servicesIndex = len(compose) - 1
+	}
+
+	services, _ := compose[servicesIndex].Value.(yaml.MapSlice)
+	for _, entry := range entries {
+		replaced := false
+		for i := range services {
+			if services[i].Key == entry.Key {
+				services[i].Value = entry.Value
+				replaced = true
+			}
+		}
+		if !replaced {
+			services = append(services, entry)
+		}
+	}
+	compose[servicesIndex].Value = services
+
+	out, err := yaml.Marshal(compose)
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(composeFile)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(composeFile, out, info.Mode().Perm())
+}
@@ -0,0 +1,142 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+// fakeSystemInfo makes every gateway report the provider until the returned
+// function is called
+func fakeSystemInfo(provider string, calls *int) func() {
+	original := systemInfo
+	faasdGateways = map[string]bool{}
+	systemInfo = func(gateway string) (*proxy.SystemInfo, error) {
+		*calls++
+		info := &proxy.SystemInfo{}
+		info.Provider.Name = provider
+		return info, nil
+	}
+	return func() {
+		systemInfo = original
+		faasdGateways = map[string]bool{}
+	}
+}
+
+func Test_adaptForFaasd(t *testing.T) {
+	calls := 0
+	defer fakeSystemInfo("faasd", &calls)()
+
+	spec := &proxy.DeployFunctionSpec{FunctionName: "sensor", Labels: map[string]string{"team": "edge"}}
+	if err := adaptForFaasd("http://faasd:8080", spec); err != nil || calls != 0 {
+		t.Fatalf("want the gateway left alone for a function without namespaces or scaling labels, got %d call(s), %v", calls, err)
+	}
+
+	spec.Labels["com.openfaas.scale.min"] = "2"
+	spec.Labels["com.openfaas.scale.max"] = "5"
+	stdOut := test.CaptureStdout(func() {
+		if err := adaptForFaasd("http://faasd:8080", spec); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+	if len(spec.Labels) != 1 || spec.Labels["team"] != "edge" {
+		t.Errorf("want only the scaling labels removed, got %v", spec.Labels)
+	}
+	if !strings.Contains(stdOut, "ignoring label com.openfaas.scale.max on sensor") {
+		t.Errorf("want a warning for each scaling label, got %q", stdOut)
+	}
+
+	spec.Namespace = "staging"
+	if err := adaptForFaasd("http://faasd:8080", spec); err == nil || !strings.Contains(err.Error(), "openfaas-fn") {
+		t.Errorf("want an error for a namespace other than openfaas-fn, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("want /system/info read once per gateway, got %d", calls)
+	}
+}
+
+func Test_adaptForFaasd_OtherProviders(t *testing.T) {
+	calls := 0
+	defer fakeSystemInfo("faas-netes", &calls)()
+
+	spec := &proxy.DeployFunctionSpec{FunctionName: "api", Namespace: "staging", Labels: map[string]string{"com.openfaas.scale.min": "2"}}
+	if err := adaptForFaasd("http://gateway:8080", spec); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if spec.Labels["com.openfaas.scale.min"] != "2" {
+		t.Errorf("want the scaling labels kept for faas-netes, got %v", spec.Labels)
+	}
+}
+
+func Test_faasdInstallService(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-faasd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	stackYAML := `provider:
+  name: faas
+functions:
+  sensor:
+    image: alexellis/sensor:0.1.0
+    environment:
+      interval: 5s
+    secrets:
+      - api-key
+`
+	composeFile := filepath.Join(dir, "docker-compose.yaml")
+	composeYAML := `version: "3.7"
+services:
+  nats:
+    image: docker.io/library/nats-streaming:0.22.0
+  sensor:
+    image: docker.io/alexellis/sensor:0.0.1
+`
+	if err := ioutil.WriteFile(stackFile, []byte(stackYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(composeFile, []byte(composeYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	resetForTest()
+	faasCmd.SetArgs([]string{"faasd", "install-service", "-f", stackFile, "--compose-file", composeFile, "--port", "8081"})
+	stdOut := test.CaptureStdout(func() {
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+	if !strings.Contains(stdOut, "systemctl restart faasd") {
+		t.Errorf("want a reminder to restart faasd, got %q", stdOut)
+	}
+
+	data, _ := ioutil.ReadFile(composeFile)
+	want := `version: "3.7"
+services:
+  nats:
+    image: docker.io/library/nats-streaming:0.22.0
+  sensor:
+    image: docker.io/alexellis/sensor:0.1.0
+    environment:
+      interval: 5s
+    volumes:
+    - type: bind
+      source: /var/lib/faasd-provider/secrets/openfaas-fn/api-key
+      target: /var/openfaas/secrets/api-key
+    ports:
+    - 127.0.0.1:8081:8080
+`
+	if string(data) != want {
+		t.Errorf("want compose file:\n%s\ngot:\n%s", want, data)
+	}
+}
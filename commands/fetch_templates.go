@@ -4,25 +4,77 @@
 package commands
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/mitchellh/go-homedir"
 	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-cli/versioncontrol"
 )
 
 // DefaultTemplateRepository contains the Git repo for the official templates
 const DefaultTemplateRepository = "https://github.com/openfaas/templates.git"
 
-const templateDirectory = "./template/"
+// repositoryTemplateDirectory is where templates are found within a template repository
+const repositoryTemplateDirectory = "./template/"
+
+// gitTokenEnvironment holds a token for cloning private template
+// repositories over HTTPS
+const gitTokenEnvironment = "FAAS_GIT_TOKEN"
+
+// templateSource is a repository of templates. Ref is a branch, tag or commit
+// to check out, the default branch when empty, and Path is the folder in the
+// repository holding the templates.
+type templateSource struct {
+	Repository string
+	Ref        string
+	Path       string
+}
+
+var commitRef = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+
+// parseTemplateSource splits a ref given as REPOSITORY#REF from a git URL,
+// archives are taken as they are
+func parseTemplateSource(templateURL string, templatePath string) templateSource {
+	source := templateSource{Repository: templateURL, Path: templatePath}
+	if len(source.Path) == 0 {
+		source.Path = repositoryTemplateDirectory
+	}
+
+	if i := strings.LastIndex(templateURL, "#"); i > 0 && !isArchiveURL(templateURL[:i]) {
+		source.Repository, source.Ref = templateURL[:i], templateURL[i+1:]
+	}
+	return source
+}
 
 // fetchTemplates fetch code templates from GitHub master zip file.
 func fetchTemplates(templateURL string, overwrite bool) error {
-	if len(templateURL) == 0 {
-		return fmt.Errorf("pass valid templateURL")
+	return fetchLanguageTemplates(templateURL, overwrite, nil)
+}
+
+// fetchLanguageTemplates fetches only the templates of the languages given, or
+// every template in the repository when there are none
+func fetchLanguageTemplates(templateURL string, overwrite bool, languages []string) error {
+	_, _, err := fetchSourceTemplates(parseTemplateSource(templateURL, ""), overwrite, languages)
+	return err
+}
+
+// fetchSourceTemplates fetches the templates of the languages given, or every
+// template, from the source. It gives the languages fetched and, for git
+// repositories, the commit they were fetched from.
+func fetchSourceTemplates(source templateSource, overwrite bool, languages []string) ([]string, string, error) {
+	if len(source.Repository) == 0 {
+		return nil, "", fmt.Errorf("pass valid templateURL")
 	}
 
 	dir, err := ioutil.TempDir("", "openFaasTemplates")
@@ -33,25 +85,269 @@ func fetchTemplates(templateURL string, overwrite bool) error {
 		defer os.RemoveAll(dir) // clean up
 	}
 
-	log.Printf("Attempting to expand templates from %s\n", templateURL)
+	log.Printf("Attempting to expand templates from %s\n", source)
 	pullDebugPrint(fmt.Sprintf("Temp files in %s", dir))
-	args := map[string]string{"dir": dir, "repo": templateURL}
-	if err := versioncontrol.GitClone.Invoke(".", args); err != nil {
-		return err
+
+	repoPath, commit, err := fetchTemplateSources(source, dir, templateDownloadConfig(), languages)
+	if err != nil {
+		return nil, "", err
 	}
 
-	preExistingLanguages, fetchedLanguages, err := moveTemplates(dir, overwrite)
+	preExistingLanguages, fetchedLanguages, err := moveTemplates(repoPath, source.Path, overwrite, languages)
 	if err != nil {
-		return err
+		return nil, "", err
+	}
+
+	if missing := missingLanguages(languages, fetchedLanguages, preExistingLanguages); len(missing) > 0 {
+		return nil, "", fmt.Errorf("template(s) %v not found in %s", missing, source)
 	}
 
 	if len(preExistingLanguages) > 0 {
 		log.Printf("Cannot overwrite the following %d template(s): %v\n", len(preExistingLanguages), preExistingLanguages)
 	}
 
-	log.Printf("Fetched %d template(s) : %v from %s\n", len(fetchedLanguages), fetchedLanguages, templateURL)
+	log.Printf("Fetched %d template(s) : %v from %s\n", len(fetchedLanguages), fetchedLanguages, source)
 
-	return err
+	return fetchedLanguages, commit, nil
+}
+
+// String gives the repository with its ref and path when they are set
+func (s templateSource) String() string {
+	description := s.Repository
+	if len(s.Ref) > 0 {
+		description += "#" + s.Ref
+	}
+	if cleaned := path.Clean(s.Path); cleaned != path.Clean(repositoryTemplateDirectory) {
+		description += " (" + cleaned + ")"
+	}
+	return description
+}
+
+// templateDownloadConfig reads the template settings from the config file
+// and fills in the defaults
+func templateDownloadConfig() config.TemplateConfig {
+	templateConfig := config.TemplateConfig{}
+
+	if cfg, err := config.ReadConfigFile(); err == nil && cfg.Templates != nil {
+		templateConfig = *cfg.Templates
+	}
+
+	if templateConfig.Retries < 1 {
+		templateConfig.Retries = 3
+	}
+
+	if len(templateConfig.RetryBackoff) == 0 {
+		templateConfig.RetryBackoff = "2s"
+	}
+
+	return templateConfig
+}
+
+// fetchTemplateSources tries the template URL and then each of its mirrors,
+// retrying each with a backoff, and returns the folder holding the fetched
+// repository and the commit checked out, which is empty for archives. Git
+// repositories are checked out sparsely when only some languages are needed.
+func fetchTemplateSources(templateSource templateSource, dir string, templateConfig config.TemplateConfig, languages []string) (string, string, error) {
+	backoff, err := time.ParseDuration(templateConfig.RetryBackoff)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid retry_backoff for templates in config: %s", err)
+	}
+
+	templateURL := templateSource.Repository
+	sources := append([]string{templateURL}, templateConfig.Mirrors[templateURL]...)
+
+	var lastErr error
+	for _, source := range sources {
+		wait := backoff
+		for attempt := 1; attempt <= templateConfig.Retries; attempt++ {
+			attemptDir, err := ioutil.TempDir(dir, "source")
+			if err != nil {
+				return "", "", err
+			}
+
+			mirror := templateSource
+			mirror.Repository = source
+			var commit string
+			commit, lastErr = fetchTemplateSource(mirror, attemptDir, templateConfig.Checksums[source], languages)
+			if lastErr == nil {
+				return findTemplateRoot(attemptDir, templateSource.Path), commit, nil
+			}
+
+			log.Printf("Attempt %d of %d to fetch templates from %s failed: %s\n", attempt, templateConfig.Retries, source, lastErr)
+			if attempt < templateConfig.Retries {
+				time.Sleep(wait)
+				wait = wait * 2
+			}
+		}
+	}
+
+	if len(sources) > 1 {
+		return "", "", fmt.Errorf("unable to fetch templates from %s or its %d mirror(s): %s", templateURL, len(sources)-1, lastErr)
+	}
+
+	return "", "", lastErr
+}
+
+// fetchTemplateSource clones a git repository at its ref, or downloads and
+// expands an archive, into dir and gives the commit cloned
+func fetchTemplateSource(templateSource templateSource, dir string, checksum string, languages []string) (string, error) {
+	source := templateSource.Repository
+	if isArchiveURL(source) {
+		archivePath, err := downloadArchive(source, checksum)
+		if err != nil {
+			return "", err
+		}
+
+		if err := extractArchive(archivePath, dir); err != nil {
+			// A corrupt archive would be resumed onto by every retry
+			os.Remove(archivePath)
+			return "", fmt.Errorf("unable to expand %s: %s", source, err)
+		}
+
+		return "", os.Remove(archivePath)
+	}
+
+	env, err := gitCloneEnv(source)
+	if err != nil {
+		return "", err
+	}
+
+	args := map[string]string{"dir": dir, "repo": source, "ref": templateSource.Ref}
+	if err := cloneTemplates(templateSource, args, languages, env); err != nil {
+		return "", err
+	}
+	return versioncontrol.GitHeadCommit.Output(".", args, env)
+}
+
+// gitCloneEnv gives the SSH command for SSH URLs and the token in
+// FAAS_GIT_TOKEN, when it is set, for HTTPS URLs
+func gitCloneEnv(source string) ([]string, error) {
+	if versioncontrol.IsSSHURL(source) {
+		sshOptions, err := gitSSHOptions(source)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(sshOptions.KeyFile) == 0 {
+			if agentErr := versioncontrol.CheckAgent(); agentErr != nil {
+				log.Printf("No SSH key given and %s\n", agentErr)
+			}
+		}
+		return sshOptions.Env(), nil
+	}
+
+	// The token is sent as a header so that it isn't in the URL git prints
+	if token := os.Getenv(gitTokenEnvironment); len(token) > 0 && strings.HasPrefix(source, "https://") {
+		credentials := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+		return []string{
+			"GIT_CONFIG_COUNT=1",
+			"GIT_CONFIG_KEY_0=http.extraHeader",
+			"GIT_CONFIG_VALUE_0=Authorization: Basic " + credentials,
+		}, nil
+	}
+	return nil, nil
+}
+
+// cloneTemplates checks out the ref of the repository, sparsely when only
+// some languages are needed. A commit can't be cloned by name, so its
+// repository is cloned whole.
+func cloneTemplates(source templateSource, args map[string]string, languages []string, env []string) error {
+	if commitRef.MatchString(source.Ref) {
+		return versioncontrol.GitCloneCommit.InvokeWithEnv(".", args, env)
+	}
+
+	if len(languages) > 0 {
+		if err := sparseCloneTemplates(source, args, languages, env); err == nil {
+			return nil
+		}
+
+		// Older versions of git can't check out sparsely, so fall back to
+		// the whole repository
+		pullDebugPrint("Sparse checkout failed, cloning the whole repository")
+		if err := os.RemoveAll(args["dir"]); err != nil {
+			return err
+		}
+		if err := os.MkdirAll(args["dir"], 0700); err != nil {
+			return err
+		}
+	}
+
+	if len(source.Ref) > 0 {
+		return versioncontrol.GitCloneRef.InvokeWithEnv(".", args, env)
+	}
+	return versioncontrol.GitClone.InvokeWithEnv(".", args, env)
+}
+
+// sparseCloneTemplates clones the repository without its files, then checks
+// out just the templates of the languages given
+func sparseCloneTemplates(source templateSource, args map[string]string, languages []string, env []string) error {
+	clone := versioncontrol.GitSparseClone
+	if len(source.Ref) > 0 {
+		clone = versioncontrol.GitSparseCloneRef
+	}
+	if err := clone.InvokeWithEnv(".", args, env); err != nil {
+		return err
+	}
+
+	for _, language := range languages {
+		pathArgs := map[string]string{"dir": args["dir"], "path": path.Join(source.Path, language)}
+		if err := versioncontrol.GitSparseCheckoutAdd.InvokeWithEnv(".", pathArgs, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingLanguages lists the languages wanted which were neither fetched nor
+// already present
+func missingLanguages(languages []string, fetched []string, existing []string) []string {
+	found := map[string]bool{}
+	for _, language := range append(fetched, existing...) {
+		found[language] = true
+	}
+
+	var missing []string
+	for _, language := range languages {
+		if !found[language] {
+			missing = append(missing, language)
+		}
+	}
+	return missing
+}
+
+// gitSSHOptions combines the git section of the config file with the SSH
+// flags for cloning the repository, the flags win
+func gitSSHOptions(repo string) (versioncontrol.SSHOptions, error) {
+	options := versioncontrol.SSHOptions{}
+
+	if cfg, err := config.ReadConfigFile(); err == nil && cfg.Git != nil {
+		options.KeyFile = cfg.Git.SSHKey
+		if deployKey, ok := cfg.Git.DeployKeys[repo]; ok {
+			options.KeyFile = deployKey
+		}
+		options.KnownHostsFile = cfg.Git.KnownHosts
+		options.HostKeyChecking = cfg.Git.HostKeyChecking
+	}
+
+	if len(sshFlags.KeyFile) > 0 {
+		options.KeyFile = sshFlags.KeyFile
+	}
+	if len(sshFlags.KnownHostsFile) > 0 {
+		options.KnownHostsFile = sshFlags.KnownHostsFile
+	}
+	if len(sshFlags.HostKeyChecking) > 0 {
+		options.HostKeyChecking = sshFlags.HostKeyChecking
+	}
+
+	var err error
+	if options.KeyFile, err = homedir.Expand(options.KeyFile); err != nil {
+		return options, err
+	}
+	if options.KnownHostsFile, err = homedir.Expand(options.KnownHostsFile); err != nil {
+		return options, err
+	}
+
+	return options, options.Validate()
 }
 
 // canWriteLanguage tells whether the language can be expanded from the zip or not.
@@ -72,7 +368,7 @@ func canWriteLanguage(availableLanguages map[string]bool, language string, overw
 
 // Takes a language input (e.g. "node"), tells whether or not it is OK to download
 func templateFolderExists(language string, overwrite bool) bool {
-	dir := templateDirectory + language
+	dir := filepath.Join(stack.TemplateDirectory, language)
 	if _, err := os.Stat(dir); err == nil && !overwrite {
 		// The directory template/language/ exists
 		return false
@@ -80,7 +376,10 @@ func templateFolderExists(language string, overwrite bool) bool {
 	return true
 }
 
-func moveTemplates(repoPath string, overwrite bool) ([]string, []string, error) {
+// moveTemplates copies the templates of the languages given, or of every
+// language when there are none, from templatePath in the repository to the
+// template folder
+func moveTemplates(repoPath string, templatePath string, overwrite bool, languages []string) ([]string, []string, error) {
 	var (
 		existingLanguages []string
 		fetchedLanguages  []string
@@ -89,9 +388,14 @@ func moveTemplates(repoPath string, overwrite bool) ([]string, []string, error)
 
 	availableLanguages := make(map[string]bool)
 
-	templateDir := filepath.Join(repoPath, templateDirectory)
+	templateDir := filepath.Join(repoPath, templatePath)
 	templates, err := ioutil.ReadDir(templateDir)
 	if err != nil {
+		// A sparse checkout of languages the repository doesn't have leaves
+		// no template folder, they are reported as missing by the caller
+		if len(languages) > 0 && os.IsNotExist(err) {
+			return nil, nil, nil
+		}
 		return nil, nil, fmt.Errorf("can't find templates in: %s", repoPath)
 	}
 
@@ -100,13 +404,16 @@ func moveTemplates(repoPath string, overwrite bool) ([]string, []string, error)
 			continue
 		}
 		language := file.Name()
+		if len(languages) > 0 && !contains(languages, language) {
+			continue
+		}
 
 		canWrite := canWriteLanguage(availableLanguages, language, overwrite)
 		if canWrite {
 			fetchedLanguages = append(fetchedLanguages, language)
 			// Do cp here
 			languageSrc := filepath.Join(templateDir, language)
-			languageDest := filepath.Join(templateDirectory, language)
+			languageDest := filepath.Join(stack.TemplateDirectory, language)
 			builder.CopyFiles(languageSrc, languageDest)
 		} else {
 			existingLanguages = append(existingLanguages, language)
@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/openfaas/faas-cli/builder"
@@ -35,6 +36,33 @@ func Test_PullTemplates(t *testing.T) {
 	})
 }
 
+func Test_pullLanguageTemplates(t *testing.T) {
+	localTemplateRepository := setupLocalTemplateRepo(t)
+	defer os.RemoveAll(localTemplateRepository)
+	defer tearDownFetchTemplates(t)
+
+	if err := pullLanguageTemplates(localTemplateRepository, []string{"ruby"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join("template", "ruby", "template.yml")); err != nil {
+		t.Errorf("want the ruby template pulled: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join("template", "dockerfile")); err == nil {
+		t.Errorf("want only the languages used pulled, found the dockerfile template")
+	}
+
+	// Templates already present are not fetched again
+	if err := pullLanguageTemplates("", []string{"ruby"}); err != nil {
+		t.Errorf("want no fetch for a template which is present, got: %s", err)
+	}
+
+	err := pullLanguageTemplates(localTemplateRepository, []string{"ruby", "cobol"})
+	if err == nil || !strings.Contains(err.Error(), "[cobol] not found") {
+		t.Errorf("want an error for a language missing from the repository, got: %v", err)
+	}
+}
+
 // setupLocalTemplateRepo will create a local copy of the core OpenFaaS templates, this
 // can be refered to as a local git repository.
 func setupLocalTemplateRepo(t *testing.T) string {
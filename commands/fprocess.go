@@ -0,0 +1,42 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// fprocessBuildArgs checks the function's own fprocess against its template
+// and gives the build-arg which passes it to the template's Dockerfile
+func fprocessBuildArgs(function stack.Function) (map[string]string, error) {
+	if len(function.FProcess) == 0 || !languageExistsNotDockerfile(function.Language) {
+		return nil, nil
+	}
+
+	override, err := checkFProcess(function)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(override.BuildArg) == 0 {
+		fmt.Printf("Warning: template %s takes no fprocess build-arg, the fprocess of %s is only set when it is deployed.\n", function.Language, function.Name)
+		return nil, nil
+	}
+	return map[string]string{override.BuildArg: function.FProcess}, nil
+}
+
+// checkFProcess makes sure the function's own fprocess is one its template
+// expects
+func checkFProcess(function stack.Function) (*stack.FProcessOverride, error) {
+	override, err := stack.TemplateFProcessOverride(function.Language)
+	if err != nil {
+		return nil, fmt.Errorf("function %s: template %s: %s", function.Name, function.Language, err)
+	}
+	if err := override.Check(function.FProcess); err != nil {
+		return nil, fmt.Errorf("function %s: %s", function.Name, err)
+	}
+	return override, nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_loadBuildOptions_FProcess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-fprocess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(templateDirectory string) { stack.TemplateDirectory = templateDirectory }(stack.TemplateDirectory)
+	stack.TemplateDirectory = dir
+
+	templates := map[string][2]string{
+		"node":   {"language: node\nfprocess: node index.js\nfprocess_pattern: ^node \n", "FROM node:8-alpine\nARG FPROCESS=\"node index.js\"\nENV fprocess=\"${FPROCESS}\"\n"},
+		"python": {"language: python\nfprocess: python index.py\n", "FROM python:3-alpine\nENV fprocess=\"python index.py\"\n"},
+	}
+	for name, files := range templates {
+		os.MkdirAll(filepath.Join(dir, name), 0700)
+		ioutil.WriteFile(filepath.Join(dir, name, "template.yml"), []byte(files[0]), 0600)
+		ioutil.WriteFile(filepath.Join(dir, name, "Dockerfile"), []byte(files[1]), 0600)
+	}
+
+	functions := []stack.Function{
+		{Name: "api", Language: "node", FProcess: "node --max-old-space-size=256 index.js"},
+		{Name: "default", Language: "node"},
+	}
+	resolved, err := loadBuildOptions(stack.Services{}, functions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resolved["api"]["FPROCESS"]; got != "node --max-old-space-size=256 index.js" {
+		t.Errorf("want the fprocess given in the template's build-arg, got %q", got)
+	}
+	if _, ok := resolved["default"]; ok {
+		t.Errorf("want no build-args for a function which keeps the template's fprocess, got %v", resolved["default"])
+	}
+
+	_, err = loadBuildOptions(stack.Services{}, []stack.Function{{Name: "api", Language: "node", FProcess: "python index.py"}}, nil)
+	if err == nil || !strings.Contains(err.Error(), "fprocess_pattern") {
+		t.Errorf("want an fprocess the template does not expect rejected, got %v", err)
+	}
+
+	stdOut := test.CaptureStdout(func() {
+		resolved, err = loadBuildOptions(stack.Services{}, []stack.Function{{Name: "worker", Language: "python", FProcess: "python -u index.py"}}, nil)
+	})
+	if err != nil || len(resolved) > 0 {
+		t.Errorf("want no build-args for a template without an fprocess build-arg, got %v, %v", resolved, err)
+	}
+	if !strings.Contains(stdOut, "Warning: template python takes no fprocess build-arg") {
+		t.Errorf("want a warning for a template without an fprocess build-arg, got %q", stdOut)
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/policy"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	gcPrefix string
+	gcDryRun bool
+	gcYes    bool
+)
+
+// gcInput is read for the confirmation before functions are removed, it is
+// swapped out in tests
+var gcInput io.Reader = os.Stdin
+
+func init() {
+	gcCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	gcCmd.Flags().StringVar(&gcPrefix, "prefix", "", "Only remove deployed functions whose names start with this prefix")
+	gcCmd.Flags().BoolVar(&gcDryRun, "dry-run", false, "Print the functions which would be removed")
+	gcCmd.Flags().BoolVarP(&gcYes, "yes", "y", false, "Remove the functions without asking for confirmation")
+	gcCmd.Flags().BoolVar(&forceRemove, "force", false, "Remove functions even when they are annotated with "+protectAnnotation+"=true")
+	gcCmd.Flags().StringVar(&overridePolicy, "override-policy", "", "Remove during a freeze window in "+policy.DefaultPolicyFile+", giving the REASON for the change")
+
+	faasCmd.AddCommand(gcCmd)
+}
+
+var gcCmd = &cobra.Command{
+	Use:   `gc -f YAML_FILE --prefix PREFIX [--gateway GATEWAY_URL] [--dry-run] [--yes]`,
+	Short: "Remove deployed functions which are no longer in the stack",
+	Long: `Removes the functions deployed on the gateway whose names start with --prefix
+but which are not in the YAML file, such as those left behind when a function
+is renamed or dropped from the stack. The prefix keeps the functions of other
+teams on a shared gateway out of reach, so it must be given.
+
+The functions to be removed are listed and confirmed before they are removed,
+unless --yes is given. --regex and --filter are ignored, since every function
+in the YAML file is kept.
+
+Functions annotated with openfaas.com/protect=true are skipped and listed
+unless --force is given. Canaries, which are removed by "faas-cli promote", and
+previews deployed with --ttl or --suffix, which are removed by "faas-cli
+cleanup", are never collected. Only the functions in --namespace are collected.`,
+	Example: `  faas-cli gc -f stack.yml --prefix myteam- --dry-run
+  faas-cli gc -f stack.yml --prefix myteam-
+  faas-cli gc -f stack.yml --prefix myteam- --yes --gateway https://shared.example.com`,
+	RunE: runGC,
+}
+
+func runGC(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("give the stack to keep with -f")
+	}
+	if len(strings.TrimSpace(gcPrefix)) == 0 {
+		return fmt.Errorf("give --prefix to choose the functions which may be removed")
+	}
+
+	parsedServices, err := stack.ParseYAMLFile(yamlFile, "", "")
+	if err != nil {
+		return err
+	}
+	var services stack.Services
+	if parsedServices != nil {
+		services = *parsedServices
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, services.Provider.GatewayURL)
+	deployed, err := proxy.ListFunctionAnnotationsInNamespace(gatewayAddress, functionNamespace)
+	if err != nil {
+		return err
+	}
+
+	// Functions the stack deploys to other namespaces are not on this list
+	kept := map[string]stack.Function{}
+	for name, function := range services.Functions {
+		if namespaceOf(function) == functionNamespace {
+			kept[name] = function
+		}
+	}
+
+	names := orphanedFunctions(deployed, kept, gcPrefix)
+	if len(names) == 0 {
+		fmt.Printf("No functions starting with %s to remove.\n", gcPrefix)
+		return nil
+	}
+
+	var remove, skipped []string
+	for _, name := range names {
+		if !forceRemove && isProtected(deployed[name]) {
+			skipped = append(skipped, name)
+			continue
+		}
+		remove = append(remove, name)
+	}
+
+	if len(skipped) > 0 {
+		fmt.Printf("Skipping protected functions, use --force to remove them: %s\n", strings.Join(skipped, ", "))
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Functions starting with %s which are not in %s:\n", gcPrefix, yamlFile)
+	for _, name := range remove {
+		fmt.Printf("- %s\n", name)
+	}
+
+	if gcDryRun {
+		return nil
+	}
+	if !gcYes && !confirm(gcInput, fmt.Sprintf("Remove %d function(s) from %s?", len(remove), gatewayAddress)) {
+		return fmt.Errorf("no functions were removed")
+	}
+
+	changePolicy, err := loadPolicy(&services)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, name := range remove {
+		if _, err := enforcePolicy(changePolicy, name, overridePolicy); err != nil {
+			return err
+		}
+
+		fmt.Printf("Deleting: %s.\n", name)
+		removeErr := proxy.DeleteFunctionInNamespace(gatewayAddress, name, functionNamespace)
+		recordRemove(gatewayAddress, name, removeErr)
+		if removeErr != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("unable to remove %d of %d function(s)", failed, len(remove))
+	}
+	return nil
+}
+
+// orphanedFunctions lists, sorted, the deployed functions which start with
+// the prefix but are not in the stack. Canaries and previews are deployed
+// under other names than the stack's, so they are left alone.
+func orphanedFunctions(deployed map[string]map[string]string, functions map[string]stack.Function, prefix string) []string {
+	var names []string
+	for name, annotations := range deployed {
+		if _, inStack := functions[name]; inStack || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if isCanaryOrPreview(annotations) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isCanaryOrPreview is true for canaries, which promote removes, and for
+// previews deployed with --ttl or --suffix, which cleanup removes
+func isCanaryOrPreview(annotations map[string]string) bool {
+	for _, annotation := range []string{canaryPrimaryAnnotation, expiresAnnotation, previewAnnotation} {
+		if _, ok := annotations[annotation]; ok {
+			return true
+		}
+	}
+	return false
+}
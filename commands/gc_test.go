@@ -0,0 +1,159 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_orphanedFunctions(t *testing.T) {
+	deployed := map[string]map[string]string{
+		"myteam-api":        {},
+		"myteam-old-api":    {},
+		"myteam-db":         {},
+		"other-api":         {},
+		"myteam-api-canary": {"com.openfaas.canary.primary": "myteam-api"},
+		"myteam-api-pr-12":  {"com.openfaas.expires-at": "2018-03-01T12:00:00Z", "com.openfaas.preview": "pr-12"},
+	}
+	functions := map[string]stack.Function{
+		"myteam-api": {},
+		"myteam-db":  {},
+	}
+
+	if got := orphanedFunctions(deployed, functions, "myteam-"); !reflect.DeepEqual(got, []string{"myteam-old-api"}) {
+		t.Errorf("want only the function missing from the stack with the prefix, got: %v", got)
+	}
+}
+
+func writeGCStack(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "faas-cli-gc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "stack.yml")
+	stackYAML := `provider:
+  name: faas
+functions:
+  myteam-api:
+    image: api:1
+`
+	if err := ioutil.WriteFile(path, []byte(stackYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func gcDeployed() test.Request {
+	return test.Request{
+		Method:             http.MethodGet,
+		Uri:                "/system/functions",
+		ResponseStatusCode: http.StatusOK,
+		ResponseBody: []proxy.FunctionStatus{
+			{Name: "myteam-api"},
+			{Name: "myteam-renamed"},
+			{Name: "myteam-kept", Annotations: map[string]string{protectAnnotation: "true"}},
+			{Name: "other-api"},
+		},
+	}
+}
+
+func Test_gc_DryRun(t *testing.T) {
+	resetForTest()
+	defer func() { gcPrefix, gcDryRun = "", false }()
+
+	stackFile := writeGCStack(t)
+	defer os.RemoveAll(filepath.Dir(stackFile))
+
+	s := test.MockHttpServer(t, []test.Request{gcDeployed()})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"gc", "-f", stackFile, "--prefix", "myteam-", "--dry-run", "--gateway=" + s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if !strings.Contains(stdOut, "- myteam-renamed") || strings.Contains(stdOut, "- myteam-api") || strings.Contains(stdOut, "- other-api") {
+		t.Errorf("want only the renamed function listed, got: %s", stdOut)
+	}
+	if !strings.Contains(stdOut, "Skipping protected functions, use --force to remove them: myteam-kept") {
+		t.Errorf("want the protected function skipped, got: %s", stdOut)
+	}
+	if strings.Contains(stdOut, "Deleting:") {
+		t.Errorf("want nothing removed on a dry run, got: %s", stdOut)
+	}
+}
+
+func Test_gc_Confirmed(t *testing.T) {
+	resetForTest()
+	defer func() { gcPrefix, gcInput = "", os.Stdin }()
+
+	stackFile := writeGCStack(t)
+	defer os.RemoveAll(filepath.Dir(stackFile))
+
+	s := test.MockHttpServer(t, []test.Request{
+		gcDeployed(),
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	gcInput = strings.NewReader("y\n")
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"gc", "-f", stackFile, "--prefix", "myteam-", "--gateway=" + s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if !strings.Contains(stdOut, "Deleting: myteam-renamed.") {
+		t.Errorf("want the renamed function removed, got: %s", stdOut)
+	}
+}
+
+func Test_gc_Declined(t *testing.T) {
+	resetForTest()
+	defer func() { gcPrefix, gcInput = "", os.Stdin }()
+
+	stackFile := writeGCStack(t)
+	defer os.RemoveAll(filepath.Dir(stackFile))
+
+	s := test.MockHttpServer(t, []test.Request{gcDeployed()})
+	defer s.Close()
+
+	gcInput = strings.NewReader("n\n")
+	var err error
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"gc", "-f", stackFile, "--prefix", "myteam-", "--gateway=" + s.URL})
+		err = faasCmd.Execute()
+	})
+
+	if err == nil || err.Error() != "no functions were removed" {
+		t.Errorf("want the removal declined, got: %v", err)
+	}
+}
+
+func Test_gc_RequiresPrefix(t *testing.T) {
+	resetForTest()
+
+	faasCmd.SetArgs([]string{"gc", "-f", "stack.yml"})
+	err := faasCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--prefix") {
+		t.Errorf("want an error asking for --prefix, got: %v", err)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/openfaas/faas-cli/hooks"
+)
+
+// hookConfig gives the hooks run at each lifecycle point, nil when there are none
+var hookConfig = func() (hooks.Config, error) {
+	return hooks.Load(hooks.DefaultFile)
+}
+
+// runHooks runs the hooks for the event's lifecycle point. A failing required
+// pre-deploy hook returns an error, other failures are reported as warnings.
+func runHooks(event hooks.Event) error {
+	config, err := hookConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to read %s: %s\n", hooks.DefaultFile, err)
+		return nil
+	}
+
+	event.Time = time.Now().UTC()
+	event.User = auditUser()
+
+	for _, hook := range config[event.Hook] {
+		err := hook.Run(event, os.Stdout)
+		if err == nil {
+			continue
+		}
+
+		if hook.Required && event.Hook == hooks.PreDeploy {
+			return fmt.Errorf("%s hook %s failed for %s: %s", event.Hook, hook.Command, event.Function, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s hook %s failed for %s: %s\n", event.Hook, hook.Command, event.Function, err)
+	}
+	return nil
+}
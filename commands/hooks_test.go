@@ -0,0 +1,85 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/hooks"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func stubHooks(t *testing.T, data string) func() {
+	config, err := hooks.Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	original := hookConfig
+	hookConfig = func() (hooks.Config, error) { return config, nil }
+	return func() { hookConfig = original }
+}
+
+func Test_deploy_RequiredPreDeployHookStopsDeploy(t *testing.T) {
+	resetForTest()
+	defer stubHooks(t, "pre-deploy:\n  - command: \"false\"\n    required: true\n")()
+
+	// No request reaches the gateway
+	s := test.MockHttpServer(t, []test.Request{})
+	defer s.Close()
+
+	var err error
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"deploy", "--gateway=" + s.URL, "--image=golang", "--name=test-function"})
+		err = faasCmd.Execute()
+	})
+
+	if err == nil || !strings.Contains(err.Error(), "pre-deploy hook false failed for test-function") {
+		t.Fatalf("want the deploy stopped by the hook, got: %v", err)
+	}
+}
+
+func Test_deploy_OnFailureHookReceivesEvent(t *testing.T) {
+	resetForTest()
+
+	dir, err := ioutil.TempDir("", "faas-cli-hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	eventFile := filepath.Join(dir, "event.json")
+	defer stubHooks(t, "pre-deploy:\n  - command: \"false\"\non-failure:\n  - command: sh\n    args: [\"-c\", \"cat > "+eventFile+"\"]\n")()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusInternalServerError,
+		},
+	})
+	defer s.Close()
+
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"deploy", "--gateway=" + s.URL, "--image=golang", "--name=test-function"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("an optional pre-deploy hook should not stop the deploy: %s", err)
+		}
+	})
+
+	event, err := ioutil.ReadFile(eventFile)
+	if err != nil {
+		t.Fatalf("want the on-failure hook to run: %s", err)
+	}
+	for _, want := range []string{`"hook":"on-failure"`, `"action":"deploy"`, `"function":"test-function"`, `"status_code":500`} {
+		if !strings.Contains(string(event), want) {
+			t.Errorf("want %s in the event, got: %s", want, event)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+const defaultRegistry = "docker.io"
+
+// imageOverrides combines the image_overrides from the config file with the
+// --image-prefix-override flags, the flags win for the same prefix
+func imageOverrides(overrideOpts []string) (map[string]string, error) {
+	overrides := map[string]string{}
+	if cfg, err := config.ReadConfigFile(); err == nil {
+		overrides = mergeMap(overrides, cfg.ImageOverrides)
+	}
+
+	flagOverrides, err := parseMap(overrideOpts, "image-prefix-override")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing image prefix overrides: %v", err)
+	}
+
+	return mergeMap(overrides, flagOverrides), nil
+}
+
+// rewriteImage replaces the longest matching prefix of the image, matched on
+// whole path segments. Images without a registry are matched as docker.io, so
+// "docker.io" or "docker.io/library" can be rewritten to a pull-through cache.
+func rewriteImage(image string, overrides map[string]string) string {
+	qualified := qualifyImage(image)
+
+	var matched, remainder, replacement string
+	for prefix, value := range overrides {
+		prefix = strings.TrimRight(prefix, "/")
+		if len(prefix) == 0 || len(prefix) <= len(matched) {
+			continue
+		}
+
+		for _, candidate := range []string{image, qualified} {
+			if candidate == prefix || strings.HasPrefix(candidate, prefix+"/") {
+				matched = prefix
+				remainder = strings.TrimPrefix(candidate, prefix)
+				replacement = strings.TrimRight(value, "/")
+				break
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		return image
+	}
+	return replacement + remainder
+}
+
+// overriddenImage rewrites the image and reports when it was changed
+func overriddenImage(image string, overrides map[string]string) string {
+	rewritten := rewriteImage(image, overrides)
+	if rewritten != image {
+		fmt.Printf("Rewriting image: %s to %s.\n", image, rewritten)
+	}
+	return rewritten
+}
+
+// qualifyImage adds the implicit docker.io registry and library namespace
+func qualifyImage(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return image
+	}
+
+	if len(parts) == 1 {
+		return defaultRegistry + "/library/" + image
+	}
+	return defaultRegistry + "/" + image
+}
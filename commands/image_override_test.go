@@ -0,0 +1,41 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import "testing"
+
+func Test_rewriteImage(t *testing.T) {
+	overrides := map[string]string{
+		"docker.io":                 "internal-mirror.example.com",
+		"docker.io/functions/":      "internal-mirror.example.com/openfaas-functions",
+		"gcr.io":                    "gcr-cache.example.com",
+		"registry.example.com:5000": "registry.internal:5000",
+	}
+
+	testCases := []struct {
+		image string
+		want  string
+	}{
+		{"alexellis/faas-url-ping:0.2", "internal-mirror.example.com/alexellis/faas-url-ping:0.2"},
+		{"nginx", "internal-mirror.example.com/library/nginx"},
+		{"docker.io/alexellis/figlet", "internal-mirror.example.com/alexellis/figlet"},
+		{"functions/alpine:latest", "internal-mirror.example.com/openfaas-functions/alpine:latest"},
+		{"gcr.io/project/fn:1.0", "gcr-cache.example.com/project/fn:1.0"},
+		{"gcr.io.evil.com/fn", "gcr.io.evil.com/fn"},
+		{"registry.example.com:5000/fn", "registry.internal:5000/fn"},
+		{"quay.io/acme/fn", "quay.io/acme/fn"},
+	}
+
+	for _, testCase := range testCases {
+		if got := rewriteImage(testCase.image, overrides); got != testCase.want {
+			t.Errorf("%s: want %s, got %s", testCase.image, testCase.want, got)
+		}
+	}
+}
+
+func Test_rewriteImage_NoOverrides(t *testing.T) {
+	if got := rewriteImage("alexellis/faas-url-ping", nil); got != "alexellis/faas-url-ping" {
+		t.Errorf("want the image unchanged, got %s", got)
+	}
+}
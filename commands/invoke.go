@@ -6,16 +6,36 @@ package commands
 import (
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/openfaas/faas-cli/audit"
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/version"
 	"github.com/spf13/cobra"
 )
 
 var (
-	contentType string
-	query       []string
+	contentType     string
+	query           []string
+	responseFilter  string
+	harFile         string
+	harMaxBodySize  int
+	invokeAuthFlags stack.FunctionAuth
+	streamResponse  bool
+	traceContextArg string
+	newTrace        bool
+	traceURL        string
+	invokeData      string
+	invokeDataFile  string
+	invokeHeaders   []string
+	invokeMethod    string
+	invokeAsync     bool
+	callbackURL     string
+	invokeInclude   bool
 )
 
 func init() {
@@ -25,17 +45,82 @@ func init() {
 
 	invokeCmd.Flags().StringVar(&contentType, "content-type", "text/plain", "The content-type HTTP header such as application/json")
 	invokeCmd.Flags().StringArrayVar(&query, "query", []string{}, "pass query-string options")
+	invokeCmd.Flags().StringVarP(&invokeData, "data", "d", "", "Request body, instead of reading it from STDIN")
+	invokeCmd.Flags().StringVar(&invokeDataFile, "data-file", "", "File to read the request body from, instead of STDIN")
+	invokeCmd.Flags().StringArrayVarP(&invokeHeaders, "header", "H", []string{}, "Add a request header (NAME: VALUE)")
+	invokeCmd.Flags().StringVarP(&invokeMethod, "method", "X", http.MethodPost, "HTTP method of the request, GET and HEAD do not read STDIN")
+	invokeCmd.Flags().BoolVar(&invokeAsync, "async", false, "Queue the invocation on the gateway's async endpoint")
+	invokeCmd.Flags().StringVar(&callbackURL, "callback-url", "", "URL the result of an --async invocation is posted to")
+	invokeCmd.Flags().BoolVarP(&invokeInclude, "include", "i", false, "Print the response's status line and headers before its body")
+	invokeCmd.Flags().StringVar(&responseFilter, "response-filter", "", "Filter a JSON response with a path such as .result.items[0].id")
+	invokeCmd.Flags().StringVar(&harFile, "har", "", "Record the request and response into a HAR file such as session.har")
+	invokeCmd.Flags().IntVar(&harMaxBodySize, "har-max-body", 64*1024, "Bytes of each body to keep in the HAR file")
+
+	invokeCmd.Flags().BoolVar(&streamResponse, "stream", false, "Print the response as it arrives, the default for functions whose template uses the of-watchdog in streaming mode")
+
+	invokeCmd.Flags().StringVar(&invokeAuthFlags.Type, "auth", "", "Authenticate with the function itself using basic, bearer, hmac or none, overrides its auth in the YAML file")
+	invokeCmd.Flags().StringVar(&invokeAuthFlags.Username, "auth-user", "", "Username for --auth basic")
+	invokeCmd.Flags().StringVar(&invokeAuthFlags.Password, "auth-password", "", "Password for --auth basic")
+	invokeCmd.Flags().StringVar(&invokeAuthFlags.Token, "auth-token", "", "Token for --auth bearer")
+	invokeCmd.Flags().StringVar(&invokeAuthFlags.Key, "auth-key", "", "Key used to sign the body for --auth hmac")
+
+	invokeCmd.Flags().StringVar(&traceContextArg, "trace-context", "", "Propagate this W3C traceparent, or trace id, to the function in traceparent and B3 headers")
+	invokeCmd.Flags().BoolVar(&newTrace, "new-trace", false, "Start a new trace and propagate it to the function in traceparent and B3 headers")
+	invokeCmd.Flags().StringVar(&traceURL, "trace-url", "", "Link to the trace printed with --trace-context or --new-trace, "+traceURLPlaceholder+" is replaced by its id. Defaults to tracing.url in the config file")
 
 	faasCmd.AddCommand(invokeCmd)
 }
 
 var invokeCmd = &cobra.Command{
-	Use:   `invoke FUNCTION_NAME [--gateway GATEWAY_URL] [--content-type CONTENT_TYPE] [--query PARAM=VALUE]`,
+	Use: `invoke FUNCTION_NAME [--gateway GATEWAY_URL] [--content-type CONTENT_TYPE] [--query PARAM=VALUE]
+                  [--data BODY|--data-file FILE] [--header "NAME: VALUE" ...] [--method METHOD]
+                  [--async [--callback-url URL]] [--include] [--response-filter PATH] [--har FILE] [--auth TYPE]
+                  [--new-trace|--trace-context TRACEPARENT]
+                  [--expect-status CODE] [--expect-body-regex REGEX] [--expect-max-duration DURATION]`,
 	Short: "Invoke an OpenFaaS function",
-	Long:  `Invokes an OpenFaaS function and reads from STDIN for the body of the request`,
+	Long: `Invokes an OpenFaaS function and reads from STDIN for the body of the request,
+or from --data or --data-file. The request is a POST unless --method is given,
+--header adds headers which take precedence over --content-type, and --include
+prints the status line and headers of the response before its body.
+
+--async queues the invocation on the gateway's /async-function/ endpoint, its
+call id is printed to STDERR and recorded for "faas-cli describe-call". With
+--callback-url the queue-worker posts the function's response to that URL.
+
+Responses from functions whose template uses the of-watchdog in streaming
+mode are printed as they arrive, this can be changed with --stream.
+
+--response-filter prints the values of a JSON response found by a jq-style
+path such as .result.items[0].id, one per line, without needing jq. It is not
+named --filter because that flag already chooses the functions of the YAML
+file for every command.
+
+Functions behind their own authentication can be invoked with the "auth"
+section of the function in the YAML file or with --auth and its flags, which
+take precedence. Basic and bearer credentials are sent instead of the
+gateway's, hmac signs the body in the X-Hub-Signature header.
+
+--new-trace or --trace-context send W3C traceparent and B3 headers so that the
+invocation can be found in a tracing backend. The trace id is printed to
+STDERR with a link to the trace when tracing.url is set in the config file, i.e.
+http://jaeger:16686/trace/{trace_id}.
+
+--expect-status, --expect-body-regex and --expect-max-duration check the
+response once it has been printed, and exit with an error describing each
+expectation which was not met, for smoke tests in scripts.`,
 	Example: `  faas-cli invoke echo --gateway https://domain:port
   faas-cli invoke echo --gateway https://domain:port --content-type application/json
-  faas-cli invoke env --query repo=faas-cli --query org=openfaas`,
+  faas-cli invoke env --query repo=faas-cli --query org=openfaas
+  faas-cli invoke env --method GET --header "X-Api-Version: 2" --include
+  faas-cli invoke search --data '{"q": 1}' --content-type application/json
+  faas-cli invoke resize --data-file ./image.png --async
+  faas-cli invoke resize --data-file ./image.png --async --callback-url https://example.com/done
+  echo '{"q": 1}' | faas-cli invoke search --response-filter '.result.items[0].id'
+  echo '{"q": 1}' | faas-cli invoke search --har session.har
+  echo '{"q": 1}' | faas-cli invoke search --auth bearer --auth-token $TOKEN
+  echo '{"q": 1}' | faas-cli invoke webhook -f ./stack.yml --auth hmac --auth-key $KEY
+  echo '{"q": 1}' | faas-cli invoke search --new-trace --trace-url "http://tempo:3200/trace/{trace_id}"
+  faas-cli invoke health -X GET --expect-status 200 --expect-body-regex 'ok' --expect-max-duration 500ms`,
 	RunE: runInvoke,
 }
 
@@ -62,24 +147,209 @@ func runInvoke(cmd *cobra.Command, args []string) error {
 
 	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway)
 
-	stat, _ := os.Stdin.Stat()
-	if (stat.Mode() & os.ModeCharDevice) != 0 {
-		fmt.Fprintf(os.Stderr, "Reading from STDIN - hit (Control + D) to stop.\n")
+	var stackAuth *stack.FunctionAuth
+	stream := streamResponse
+	namespace := functionNamespace
+	if function, ok := services.Functions[functionName]; ok {
+		stackAuth = function.Auth
+		namespace = namespaceOf(function)
+
+		// --response-filter and --expect-body-regex need the whole response so
+		// they turn off streaming by default
+		if !cmd.Flags().Changed("stream") && len(responseFilter) == 0 && len(expectBodyRegex) == 0 && languageExistsNotDockerfile(function.Language) {
+			if watchdog, err := stack.TemplateWatchdog(function.Language); err == nil {
+				stream = watchdog.Streams()
+			}
+		}
+	}
+	if stream && len(responseFilter) > 0 {
+		return fmt.Errorf("--response-filter needs the whole response so it cannot be used with --stream")
+	}
+	if stream && len(expectBodyRegex) > 0 {
+		return fmt.Errorf("--expect-body-regex needs the whole response so it cannot be used with --stream")
+	}
+	expectations, err := parseInvokeExpectations(expectStatus, expectBodyRegex, expectMaxDuration)
+	if err != nil {
+		return err
+	}
+	auth, err := invokeAuth(stackAuth, invokeAuthFlags)
+	if err != nil {
+		return fmt.Errorf("function %s: %s", functionName, err)
+	}
+
+	if newTrace && len(traceContextArg) > 0 {
+		return fmt.Errorf("give either --new-trace or --trace-context")
+	}
+	if newTrace || len(traceContextArg) > 0 {
+		var trace traceContext
+		if newTrace {
+			trace, err = newTraceContext()
+		} else {
+			trace, err = parseTraceContext(traceContextArg)
+		}
+		if err != nil {
+			return err
+		}
+
+		proxy.InvokeHeaders = trace.Headers()
+		defer func() { proxy.InvokeHeaders = nil }()
+		printTrace(os.Stderr, trace, traceURLTemplate(traceURL))
 	}
 
-	functionInput, err := ioutil.ReadAll(os.Stdin)
+	headers, err := parseHeaders(invokeHeaders)
 	if err != nil {
-		return fmt.Errorf("unable to read standard input: %s", err.Error())
+		return err
+	}
+	if len(callbackURL) > 0 {
+		if !invokeAsync {
+			return fmt.Errorf("--callback-url can only be used with --async")
+		}
+		headers.Set("X-Callback-Url", callbackURL)
 	}
 
-	response, err := proxy.InvokeFunction(gatewayAddress, functionName, &functionInput, contentType, query)
+	functionInput, err := invokeBody(invokeData, invokeDataFile, invokeMethod)
 	if err != nil {
 		return err
 	}
 
+	if len(harFile) > 0 {
+		recorder := proxy.NewHARRecorder(version.BuildVersion(), harMaxBodySize)
+		proxy.InvokeTransport = recorder.Wrap
+		defer func() {
+			proxy.InvokeTransport = nil
+			if writeErr := recorder.WriteFile(harFile); writeErr != nil {
+				fmt.Fprintf(os.Stderr, "Unable to write HAR file %s: %s\n", harFile, writeErr)
+			}
+		}()
+	}
+
+	var status int
+	options := proxy.InvokeOptions{
+		Method:    invokeMethod,
+		Headers:   headers,
+		Async:     invokeAsync,
+		AnyStatus: expectations.status != 0,
+		OnResponse: func(res *http.Response) {
+			status = res.StatusCode
+			if invokeInclude {
+				printResponseHeaders(os.Stdout, res)
+			}
+			if callID := res.Header.Get("X-Call-Id"); invokeAsync && len(callID) > 0 {
+				fmt.Fprintf(os.Stderr, "Queued %s with call id %s.\n", functionName, callID)
+				call := callRecord{ID: callID, Function: functionName, Gateway: gatewayAddress, Queued: time.Now().UTC(), CallbackURL: callbackURL}
+				if err := recordCall(call); err != nil {
+					fmt.Fprintf(os.Stderr, "Unable to record call %s: %s\n", callID, err)
+				}
+			}
+		},
+	}
+
+	started := time.Now()
+	if stream {
+		_, err := proxy.InvokeFunctionWithOptions(gatewayAddress, qualifiedName(functionName, namespace), &functionInput, contentType, query, auth, options, os.Stdout)
+		recordInvoke(gatewayAddress, functionName, err)
+		if err != nil || !expectations.set() {
+			return err
+		}
+		return expectations.check(functionName, status, nil, time.Since(started))
+	}
+
+	response, err := proxy.InvokeFunctionWithOptions(gatewayAddress, qualifiedName(functionName, namespace), &functionInput, contentType, query, auth, options, nil)
+	took := time.Since(started)
+	recordInvoke(gatewayAddress, functionName, err)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
 	if response != nil {
-		os.Stdout.Write(*response)
+		body = *response
+		if len(responseFilter) > 0 {
+			filtered, err := applyJSONFilter(body, responseFilter)
+			if err != nil {
+				return err
+			}
+			fmt.Println(filtered)
+		} else {
+			os.Stdout.Write(body)
+		}
 	}
 
+	if expectations.set() {
+		return expectations.check(functionName, status, body, took)
+	}
 	return nil
 }
+
+// invokeBody reads the request body from --data, --data-file, where - is
+// STDIN, or from STDIN unless the method sends no body
+func invokeBody(data string, dataFile string, method string) ([]byte, error) {
+	switch {
+	case len(data) > 0 && len(dataFile) > 0:
+		return nil, fmt.Errorf("give either --data or --data-file")
+	case len(data) > 0:
+		return []byte(data), nil
+	case len(dataFile) > 0 && dataFile != "-":
+		return ioutil.ReadFile(dataFile)
+	case len(dataFile) == 0 && (strings.EqualFold(method, http.MethodGet) || strings.EqualFold(method, http.MethodHead)):
+		return []byte{}, nil
+	}
+
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) != 0 {
+		fmt.Fprintf(os.Stderr, "Reading from STDIN - hit (Control + D) to stop.\n")
+	}
+
+	input, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read standard input: %s", err.Error())
+	}
+	return input, nil
+}
+
+func recordInvoke(gateway string, functionName string, invokeErr error) {
+	recordAudit(audit.Entry{
+		Action:   audit.Invoke,
+		Gateway:  gateway,
+		Function: functionName,
+		Success:  invokeErr == nil,
+		Error:    auditError(invokeErr),
+	})
+}
+
+// invokeAuth merges the function's auth from the YAML file with the --auth
+// flags, nil is returned when the function needs no auth of its own
+func invokeAuth(stackAuth *stack.FunctionAuth, flags stack.FunctionAuth) (*stack.FunctionAuth, error) {
+	auth := stack.FunctionAuth{}
+	if stackAuth != nil {
+		auth = *stackAuth
+		auth.Username = os.ExpandEnv(auth.Username)
+		auth.Password = os.ExpandEnv(auth.Password)
+		auth.Token = os.ExpandEnv(auth.Token)
+		auth.Key = os.ExpandEnv(auth.Key)
+	}
+
+	if len(flags.Type) > 0 {
+		auth.Type = flags.Type
+	}
+	if len(flags.Username) > 0 {
+		auth.Username = flags.Username
+	}
+	if len(flags.Password) > 0 {
+		auth.Password = flags.Password
+	}
+	if len(flags.Token) > 0 {
+		auth.Token = flags.Token
+	}
+	if len(flags.Key) > 0 {
+		auth.Key = flags.Key
+	}
+
+	if len(auth.Type) == 0 || auth.Type == stack.AuthNone {
+		return nil, nil
+	}
+	if err := proxy.ValidateFunctionAuth(&auth); err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
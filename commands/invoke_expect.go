@@ -0,0 +1,80 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	expectStatus      int
+	expectBodyRegex   string
+	expectMaxDuration time.Duration
+)
+
+func init() {
+	invokeCmd.Flags().IntVar(&expectStatus, "expect-status", 0, "Fail unless the function responds with this HTTP status code")
+	invokeCmd.Flags().StringVar(&expectBodyRegex, "expect-body-regex", "", "Fail unless the response body matches this regular expression")
+	invokeCmd.Flags().DurationVar(&expectMaxDuration, "expect-max-duration", 0, "Fail when the invocation takes longer than this, such as 500ms")
+}
+
+// invokeExpectations are checked against the response of an invocation so
+// that a smoke test fails with a non-zero exit code
+type invokeExpectations struct {
+	status      int
+	body        *regexp.Regexp
+	maxDuration time.Duration
+}
+
+func parseInvokeExpectations(status int, bodyRegex string, maxDuration time.Duration) (invokeExpectations, error) {
+	expectations := invokeExpectations{status: status, maxDuration: maxDuration}
+	if status != 0 && (status < 100 || status > 599) {
+		return expectations, fmt.Errorf("--expect-status %d is not an HTTP status code", status)
+	}
+	if maxDuration < 0 {
+		return expectations, fmt.Errorf("--expect-max-duration must be positive")
+	}
+	if len(bodyRegex) > 0 {
+		body, err := regexp.Compile(bodyRegex)
+		if err != nil {
+			return expectations, fmt.Errorf("invalid --expect-body-regex: %s", err)
+		}
+		expectations.body = body
+	}
+	return expectations, nil
+}
+
+// set is true when any expectation was given
+func (e invokeExpectations) set() bool {
+	return e.status != 0 || e.body != nil || e.maxDuration > 0
+}
+
+// check gives an error listing each expectation the response did not meet
+func (e invokeExpectations) check(functionName string, status int, body []byte, took time.Duration) error {
+	var failures []string
+	if e.status != 0 && status != e.status {
+		failures = append(failures, fmt.Sprintf("expected status %d, got %d", e.status, status))
+	}
+	if e.body != nil && !e.body.Match(body) {
+		failures = append(failures, fmt.Sprintf("expected the body to match %q, got %q", e.body.String(), truncateBody(body, 200)))
+	}
+	if e.maxDuration > 0 && took > e.maxDuration {
+		failures = append(failures, fmt.Sprintf("expected a response within %s, took %s", e.maxDuration, took.Round(time.Millisecond)))
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("assertion failed for %s: %s", functionName, strings.Join(failures, "; "))
+}
+
+func truncateBody(body []byte, max int) string {
+	if len(body) <= max {
+		return string(body)
+	}
+	return string(body[:max]) + "..."
+}
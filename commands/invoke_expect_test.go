@@ -0,0 +1,62 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_invokeExpectations_check(t *testing.T) {
+	expectations, err := parseInvokeExpectations(200, `"status":\s*"ok"`, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := expectations.check("health", 200, []byte(`{"status": "ok"}`), 120*time.Millisecond); err != nil {
+		t.Errorf("want the expectations met, got %s", err)
+	}
+
+	err = expectations.check("health", 503, []byte(`{"status": "down"}`), 812*time.Millisecond)
+	want := `assertion failed for health: expected status 200, got 503; expected the body to match "\"status\":\\s*\"ok\"", got "{\"status\": \"down\"}"; expected a response within 500ms, took 812ms`
+	if err == nil || err.Error() != want {
+		t.Errorf("want %s, got %v", want, err)
+	}
+}
+
+func Test_parseInvokeExpectations_Invalid(t *testing.T) {
+	if _, err := parseInvokeExpectations(42, "", 0); err == nil || err.Error() != "--expect-status 42 is not an HTTP status code" {
+		t.Errorf("want an error for the status, got %v", err)
+	}
+	if _, err := parseInvokeExpectations(0, "(", 0); err == nil {
+		t.Errorf("want an error for the regex")
+	}
+}
+
+func Test_invoke_ExpectStatus(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("down"))
+	}))
+	defer s.Close()
+
+	defer func() { expectStatus, expectBodyRegex, invokeData = 0, "", "" }()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"invoke", "health", "--gateway=" + s.URL, "--data", "ping", "--expect-status", "200", "--expect-body-regex", "ok"})
+		err = faasCmd.Execute()
+	})
+
+	if stdOut != "down" {
+		t.Errorf("want the body printed, got %q", stdOut)
+	}
+	if err == nil || err.Error() != `assertion failed for health: expected status 200, got 500; expected the body to match "ok", got "down"` {
+		t.Errorf("want the assertion to fail, got %v", err)
+	}
+}
@@ -5,12 +5,17 @@ package commands
 
 import (
 	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"testing"
 
 	"io/ioutil"
 
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-cli/test"
 )
 
@@ -48,3 +53,143 @@ func Test_invoke(t *testing.T) {
 		t.Fatalf("Output is not as expected:\n%s", stdOut)
 	}
 }
+
+func Test_invoke_ResponseFilter(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/function/search",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       map[string]interface{}{"result": map[string]interface{}{"id": "abc"}},
+		},
+	})
+	defer s.Close()
+
+	os.Stdin, _ = ioutil.TempFile("", "stdin")
+	os.Stdin.WriteString(`{"q": 1}`)
+	os.Stdin.Seek(0, 0)
+	defer func() {
+		os.Remove(os.Stdin.Name())
+		responseFilter = ""
+	}()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"invoke",
+			"--gateway=" + s.URL,
+			"--response-filter=.result.id",
+			"search",
+		})
+		faasCmd.Execute()
+	})
+
+	if strings.TrimSpace(stdOut) != "abc" {
+		t.Fatalf("want the filtered response, got:\n%s", stdOut)
+	}
+}
+
+func Test_invokeAuth(t *testing.T) {
+	os.Setenv("FAAS_TEST_TOKEN", "from-env")
+	defer os.Unsetenv("FAAS_TEST_TOKEN")
+
+	stackAuth := &stack.FunctionAuth{Type: stack.AuthBearer, Token: "${FAAS_TEST_TOKEN}"}
+
+	auth, err := invokeAuth(stackAuth, stack.FunctionAuth{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if auth.Token != "from-env" {
+		t.Errorf("want the token expanded from the environment, got %q", auth.Token)
+	}
+	if stackAuth.Token != "${FAAS_TEST_TOKEN}" {
+		t.Errorf("the stack's auth should not be modified")
+	}
+
+	auth, err = invokeAuth(stackAuth, stack.FunctionAuth{Token: "from-flag"})
+	if err != nil || auth.Token != "from-flag" {
+		t.Errorf("want the flag to take precedence, got %+v, %v", auth, err)
+	}
+
+	auth, err = invokeAuth(stackAuth, stack.FunctionAuth{Type: stack.AuthNone})
+	if err != nil || auth != nil {
+		t.Errorf("want no auth with --auth none, got %+v, %v", auth, err)
+	}
+
+	if _, err = invokeAuth(nil, stack.FunctionAuth{Type: stack.AuthBasic}); err == nil {
+		t.Errorf("want an error for basic auth without a username")
+	}
+
+	auth, err = invokeAuth(nil, stack.FunctionAuth{})
+	if err != nil || auth != nil {
+		t.Errorf("want no auth by default, got %+v, %v", auth, err)
+	}
+}
+
+func Test_invoke_HTTPControl(t *testing.T) {
+	var method, uri, body, header, contentType string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, uri = r.Method, r.RequestURI
+		header, contentType = r.Header.Get("X-Api-Version"), r.Header.Get("Content-Type")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+
+		w.Header().Set("X-Call-Id", "call-1")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer s.Close()
+
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-invoke")
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+		invokeData, invokeHeaders, invokeMethod, invokeAsync, invokeInclude = "", nil, http.MethodPost, false, false
+		contentType, query = "text/plain", nil
+	}()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"invoke", "resize",
+			"--gateway=" + s.URL,
+			"--method", "put",
+			"--data", `{"w":10}`,
+			"--header", "X-Api-Version: 2",
+			"--header", "Content-Type: application/json",
+			"--query", "size=small",
+			"--async",
+			"--include",
+		})
+		err = faasCmd.Execute()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if method != http.MethodPut || uri != "/async-function/resize?size=small" {
+		t.Errorf("got %s %s", method, uri)
+	}
+	if body != `{"w":10}` || header != "2" || contentType != "application/json" {
+		t.Errorf("got body %q, header %q and content type %q", body, header, contentType)
+	}
+	if !strings.HasPrefix(stdOut, "HTTP/1.1 202 Accepted\n") || !strings.Contains(stdOut, "X-Call-Id: call-1\n") {
+		t.Errorf("want the status line and headers printed, got:\n%s", stdOut)
+	}
+}
+
+func Test_invokeBody(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "faas-cli-invoke")
+	defer os.RemoveAll(dir)
+	dataFile := filepath.Join(dir, "body.json")
+	ioutil.WriteFile(dataFile, []byte(`{"q":1}`), 0600)
+
+	if body, err := invokeBody("", dataFile, http.MethodPost); err != nil || string(body) != `{"q":1}` {
+		t.Errorf("want the body read from --data-file, got %q, %v", body, err)
+	}
+	if body, err := invokeBody("", "", "get"); err != nil || len(body) != 0 {
+		t.Errorf("want no body for GET, got %q, %v", body, err)
+	}
+	if _, err := invokeBody("x", dataFile, http.MethodPost); err == nil || err.Error() != "give either --data or --data-file" {
+		t.Errorf("got error %v", err)
+	}
+}
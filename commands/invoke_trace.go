@@ -0,0 +1,109 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+// traceURLPlaceholder is replaced by the trace id in a trace URL
+const traceURLPlaceholder = "{trace_id}"
+
+var (
+	traceParent = regexp.MustCompile(`^([0-9a-f]{2})-([0-9a-f]{32})-([0-9a-f]{16})-([0-9a-f]{2})$`)
+	traceID     = regexp.MustCompile(`^[0-9a-f]{32}$`)
+)
+
+// traceContext is propagated to a function in W3C traceparent and B3 headers
+type traceContext struct {
+	TraceID string
+	SpanID  string
+	Sampled bool
+}
+
+// parseTraceContext reads a W3C traceparent, i.e.
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01, or a bare trace id
+// which is given a new span id
+func parseTraceContext(value string) (traceContext, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if match := traceParent.FindStringSubmatch(value); match != nil {
+		if match[1] == "ff" || strings.Trim(match[2], "0") == "" || strings.Trim(match[3], "0") == "" {
+			return traceContext{}, fmt.Errorf("invalid traceparent %q", value)
+		}
+		return traceContext{TraceID: match[2], SpanID: match[3], Sampled: match[4] == "01"}, nil
+	}
+
+	if traceID.MatchString(value) && strings.Trim(value, "0") != "" {
+		spanID, err := randomHex(8)
+		if err != nil {
+			return traceContext{}, err
+		}
+		return traceContext{TraceID: value, SpanID: spanID, Sampled: true}, nil
+	}
+
+	return traceContext{}, fmt.Errorf("--trace-context must be a W3C traceparent or a 32 character hex trace id, not %q", value)
+}
+
+// newTraceContext starts a sampled trace with random ids
+func newTraceContext() (traceContext, error) {
+	trace, err := randomHex(16)
+	if err != nil {
+		return traceContext{}, err
+	}
+	span, err := randomHex(8)
+	if err != nil {
+		return traceContext{}, err
+	}
+	return traceContext{TraceID: trace, SpanID: span, Sampled: true}, nil
+}
+
+func randomHex(size int) (string, error) {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("unable to generate a trace id: %s", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Headers gives the W3C traceparent and the B3 headers for the context
+func (t traceContext) Headers() map[string]string {
+	flags, sampled := "00", "0"
+	if t.Sampled {
+		flags, sampled = "01", "1"
+	}
+	return map[string]string{
+		"traceparent":  fmt.Sprintf("00-%s-%s-%s", t.TraceID, t.SpanID, flags),
+		"X-B3-TraceId": t.TraceID,
+		"X-B3-SpanId":  t.SpanID,
+		"X-B3-Sampled": sampled,
+	}
+}
+
+// traceURLTemplate is --trace-url, or tracing.url from the config file
+func traceURLTemplate(flagURL string) string {
+	if len(flagURL) > 0 {
+		return flagURL
+	}
+	cfg, err := config.ReadConfigFile()
+	if err != nil || cfg.Tracing == nil {
+		return ""
+	}
+	return cfg.Tracing.URL
+}
+
+// printTrace writes the trace id and, when there is a URL template, the link
+// to the trace
+func printTrace(w io.Writer, trace traceContext, urlTemplate string) {
+	fmt.Fprintf(w, "Trace ID: %s\n", trace.TraceID)
+	if len(urlTemplate) > 0 {
+		fmt.Fprintf(w, "Trace: %s\n", strings.Replace(urlTemplate, traceURLPlaceholder, trace.TraceID, -1))
+	}
+}
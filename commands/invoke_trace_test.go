@@ -0,0 +1,76 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func Test_parseTraceContext(t *testing.T) {
+	trace, err := parseTraceContext("00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"traceparent":  "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"X-B3-TraceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"X-B3-SpanId":  "00f067aa0ba902b7",
+		"X-B3-Sampled": "1",
+	}
+	if got := trace.Headers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	bare, err := parseTraceContext("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bare.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || len(bare.SpanID) != 16 || !bare.Sampled {
+		t.Errorf("want a new sampled span in the trace, got %+v", bare)
+	}
+
+	for _, invalid := range []string{
+		"abc",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	} {
+		if _, err := parseTraceContext(invalid); err == nil {
+			t.Errorf("want %q rejected", invalid)
+		}
+	}
+}
+
+func Test_newTraceContext(t *testing.T) {
+	first, err := newTraceContext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := newTraceContext()
+	if len(first.TraceID) != 32 || len(first.SpanID) != 16 || first.TraceID == second.TraceID {
+		t.Errorf("want random ids, got %+v and %+v", first, second)
+	}
+	if _, err := parseTraceContext(first.Headers()["traceparent"]); err != nil {
+		t.Errorf("want a valid traceparent, got %s", err)
+	}
+}
+
+func Test_printTrace(t *testing.T) {
+	trace := traceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}
+
+	var out bytes.Buffer
+	printTrace(&out, trace, "http://jaeger:16686/trace/{trace_id}")
+	want := "Trace ID: 4bf92f3577b34da6a3ce929d0e0e4736\nTrace: http://jaeger:16686/trace/4bf92f3577b34da6a3ce929d0e0e4736\n"
+	if out.String() != want {
+		t.Errorf("want %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	printTrace(&out, trace, "")
+	if out.String() != "Trace ID: 4bf92f3577b34da6a3ce929d0e0e4736\n" {
+		t.Errorf("want only the id without a URL template, got %q", out.String())
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// filterStep is one field lookup, index or iteration of a filter expression
+type filterStep struct {
+	key     string
+	index   int
+	isIndex bool
+	iterate bool
+}
+
+// parseJSONFilter parses a jq-style path such as .result.items[0].id,
+// .["a key"] or .items[].name
+func parseJSONFilter(expression string) ([]filterStep, error) {
+	expression = strings.TrimSpace(expression)
+	if !strings.HasPrefix(expression, ".") {
+		return nil, fmt.Errorf("filter must start with '.': %s", expression)
+	}
+
+	var steps []filterStep
+	i := 0
+	for i < len(expression) {
+		switch expression[i] {
+		case '.':
+			i++
+			start := i
+			for i < len(expression) && expression[i] != '.' && expression[i] != '[' {
+				i++
+			}
+			if i > start {
+				steps = append(steps, filterStep{key: expression[start:i]})
+			}
+		case '[':
+			end := strings.IndexByte(expression[i:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("missing ']' in filter: %s", expression)
+			}
+			inner := strings.TrimSpace(expression[i+1 : i+end])
+			i += end + 1
+
+			switch {
+			case len(inner) == 0:
+				steps = append(steps, filterStep{iterate: true})
+			case strings.HasPrefix(inner, `"`):
+				key, err := strconv.Unquote(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid key %s in filter: %s", inner, expression)
+				}
+				steps = append(steps, filterStep{key: key})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, fmt.Errorf("invalid index %s in filter: %s", inner, expression)
+				}
+				steps = append(steps, filterStep{index: index, isIndex: true})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q at position %d in filter: %s", expression[i], i, expression)
+		}
+	}
+
+	return steps, nil
+}
+
+// applyJSONFilter applies a filter expression to a JSON document and returns
+// one line per result, strings are printed without quotes
+func applyJSONFilter(document []byte, expression string) (string, error) {
+	steps, err := parseJSONFilter(expression)
+	if err != nil {
+		return "", err
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(document, &value); err != nil {
+		return "", fmt.Errorf("unable to apply filter, the response is not valid JSON: %s", err)
+	}
+
+	results := []interface{}{value}
+	for _, step := range steps {
+		var next []interface{}
+		for _, result := range results {
+			selected, err := selectFilterStep(result, step)
+			if err != nil {
+				return "", err
+			}
+			next = append(next, selected...)
+		}
+		results = next
+	}
+
+	var out []string
+	for _, result := range results {
+		if str, ok := result.(string); ok {
+			out = append(out, str)
+			continue
+		}
+
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, string(encoded))
+	}
+
+	return strings.Join(out, "\n"), nil
+}
+
+func selectFilterStep(value interface{}, step filterStep) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{nil}, nil
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if step.isIndex {
+			return nil, fmt.Errorf("cannot index an object with %d", step.index)
+		}
+		if step.iterate {
+			keys := make([]string, 0, len(typed))
+			for k := range typed {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			var values []interface{}
+			for _, k := range keys {
+				values = append(values, typed[k])
+			}
+			return values, nil
+		}
+		return []interface{}{typed[step.key]}, nil
+	case []interface{}:
+		if step.iterate {
+			return typed, nil
+		}
+		if !step.isIndex {
+			return nil, fmt.Errorf("cannot index an array with %q", step.key)
+		}
+		index := step.index
+		if index < 0 {
+			index += len(typed)
+		}
+		if index < 0 || index >= len(typed) {
+			return []interface{}{nil}, nil
+		}
+		return []interface{}{typed[index]}, nil
+	default:
+		return nil, fmt.Errorf("cannot select from %v", value)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"testing"
+)
+
+func Test_applyJSONFilter(t *testing.T) {
+	document := []byte(`{"result": {"items": [{"id": "a1", "tags": ["x", "y"]}, {"id": "b2", "size": 3}]}, "a key": true}`)
+
+	testCases := []struct {
+		title      string
+		expression string
+		expected   string
+	}{
+		{title: "Identity", expression: ".", expected: `{"a key":true,"result":{"items":[{"id":"a1","tags":["x","y"]},{"id":"b2","size":3}]}}`},
+		{title: "Nested string", expression: ".result.items[0].id", expected: "a1"},
+		{title: "Negative index", expression: ".result.items[-1].size", expected: "3"},
+		{title: "Quoted key", expression: `.["a key"]`, expected: "true"},
+		{title: "Iterate", expression: ".result.items[].id", expected: "a1\nb2"},
+		{title: "Array value", expression: ".result.items[0].tags", expected: `["x","y"]`},
+		{title: "Missing key", expression: ".result.missing", expected: "null"},
+		{title: "Out of range", expression: ".result.items[5]", expected: "null"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.title, func(t *testing.T) {
+			actual, err := applyJSONFilter(document, testCase.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if actual != testCase.expected {
+				t.Errorf("want: %q, got: %q", testCase.expected, actual)
+			}
+		})
+	}
+}
+
+func Test_applyJSONFilter_Errors(t *testing.T) {
+	document := []byte(`{"items": [1, 2]}`)
+
+	for _, expression := range []string{"items", ".items[0", ".items.id", ".items[x]"} {
+		if _, err := applyJSONFilter(document, expression); err == nil {
+			t.Errorf("expected an error for %s", expression)
+		}
+	}
+
+	if _, err := applyJSONFilter([]byte("not json"), ".id"); err == nil {
+		t.Errorf("expected an error for a response which is not JSON")
+	}
+}
@@ -4,33 +4,77 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/stack"
+	"github.com/ryanuber/go-glob"
 	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
 )
 
 var (
 	verboseList bool
+	listSort    string
+	listFilters []string
+	listOutput  string
 )
 
+// listedFunction is a function as printed by list --output json or yaml
+type listedFunction struct {
+	Name            string            `json:"name" yaml:"name"`
+	Namespace       string            `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	Image           string            `json:"image" yaml:"image"`
+	InvocationCount int64             `json:"invocationCount" yaml:"invocationCount"`
+	Replicas        uint64            `json:"replicas" yaml:"replicas"`
+	Labels          map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	CreatedAt       *time.Time        `json:"createdAt,omitempty" yaml:"createdAt,omitempty"`
+	Limits          *listedResources  `json:"limits,omitempty" yaml:"limits,omitempty"`
+	Requests        *listedResources  `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Metadata        *stack.Metadata   `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+type listedResources struct {
+	Memory string `json:"memory,omitempty" yaml:"memory,omitempty"`
+	CPU    string `json:"cpu,omitempty" yaml:"cpu,omitempty"`
+}
+
 func init() {
 	// Setup flags that are used by multiple commands (variables defined in faas.go)
 	listCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
 
 	listCmd.Flags().BoolVarP(&verboseList, "verbose", "v", false, "Verbose output for the function list")
+	listCmd.Flags().StringVar(&listSort, "sort", "name", "Sort the functions by name, invocations or replicas, the most first")
+	listCmd.Flags().StringArrayVar(&listFilters, "filter", []string{}, "Only list the functions with a label, label=KEY=VALUE or label=KEY, or whose name matches a wildcard")
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", "table", "Output format: table, wide, json or yaml")
 
 	faasCmd.AddCommand(listCmd)
 }
 
 var listCmd = &cobra.Command{
-	Use:     `list [--gateway GATEWAY_URL] [--verbose]`,
+	Use: `list [--gateway GATEWAY_URL] [--verbose] [--sort name|invocations|replicas]
+               [--filter label=KEY=VALUE] [--output table|wide|json|yaml]`,
 	Aliases: []string{"ls"},
 	Short:   "List OpenFaaS functions",
-	Long:    `Lists OpenFaaS functions either on a local or remote gateway`,
+	Long: `Lists OpenFaaS functions either on a local or remote gateway.
+
+--filter keeps the functions with a label, given as label=KEY=VALUE or
+label=KEY, or whose name matches a wildcard such as "api-*". A function must
+match every --filter given. --output wide adds the image, when the function was
+created, its resource limits, for providers which report them, and the owner
+and Slack channel given by the function's metadata.`,
 	Example: `  faas-cli list
-  faas-cli list --gateway https://localhost:8080 --verbose`,
+  faas-cli list --gateway https://localhost:8080 --verbose
+  faas-cli list --sort invocations --filter label=team=payments
+  faas-cli list --output wide --filter "api-*"
+  faas-cli list --output json | jq '.[].name'`,
 	RunE: runList,
 }
 
@@ -52,11 +96,51 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	gatewayAddress = getGatewayURL(gateway, defaultGateway, yamlGateway)
 
-	functions, err := proxy.ListFunctions(gatewayAddress)
+	switch listSort {
+	case "name", "invocations", "replicas":
+	default:
+		return fmt.Errorf("--sort must be name, invocations or replicas, not %s", listSort)
+	}
+	switch listOutput {
+	case "table", "wide", "json", "yaml":
+	default:
+		return fmt.Errorf("--output must be table, wide, json or yaml, not %s", listOutput)
+	}
+	for _, listFilter := range listFilters {
+		if strings.HasPrefix(listFilter, "label=") && len(listFilter) == len("label=") {
+			return fmt.Errorf("--filter label= needs a label, i.e. label=team=payments")
+		}
+	}
+
+	statuses, err := proxy.ListFunctionStatusInNamespace(gatewayAddress, functionNamespace)
 	if err != nil {
 		return err
 	}
 
+	functions := listedFunctions(statuses, listFilters, listSort)
+
+	switch listOutput {
+	case "json":
+		out, err := json.MarshalIndent(functions, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	case "yaml":
+		out, err := yaml.Marshal(functions)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(out))
+	case "wide":
+		printWideList(os.Stdout, functions)
+	default:
+		printList(functions)
+	}
+	return nil
+}
+
+func printList(functions []listedFunction) {
 	if verboseList {
 		fmt.Printf("%-30s\t%-40s\t%-15s\t%-5s\n", "Function", "Image", "Invocations", "Replicas")
 		for _, function := range functions {
@@ -64,14 +148,116 @@ func runList(cmd *cobra.Command, args []string) error {
 			if len(function.Image) > 40 {
 				functionImage = functionImage[0:38] + ".."
 			}
-			fmt.Printf("%-30s\t%-40s\t%-15d\t%-5d\n", function.Name, functionImage, int64(function.InvocationCount), function.Replicas)
+			fmt.Printf("%-30s\t%-40s\t%-15d\t%-5d\n", function.Name, functionImage, function.InvocationCount, function.Replicas)
 		}
 	} else {
 		fmt.Printf("%-30s\t%-15s\t%-5s\n", "Function", "Invocations", "Replicas")
 		for _, function := range functions {
-			fmt.Printf("%-30s\t%-15d\t%-5d\n", function.Name, int64(function.InvocationCount), function.Replicas)
+			fmt.Printf("%-30s\t%-15d\t%-5d\n", function.Name, function.InvocationCount, function.Replicas)
+		}
+	}
+}
 
+func printWideList(w io.Writer, functions []listedFunction) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "Function\tImage\tInvocations\tReplicas\tCreated\tLimits\tOwner")
+	for _, function := range functions {
+		created := "-"
+		if function.CreatedAt != nil {
+			created = function.CreatedAt.Local().Format("2006-01-02 15:04")
 		}
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%s\t%s\t%s\n", function.Name, function.Image, function.InvocationCount, function.Replicas, created, function.Limits, listedOwner(function.Metadata))
 	}
-	return nil
+	table.Flush()
+}
+
+// listedOwner gives the owner and Slack channel of a function's metadata
+func listedOwner(metadata *stack.Metadata) string {
+	if metadata == nil || len(metadata.Owner) == 0 && len(metadata.Slack) == 0 {
+		return "-"
+	}
+	if len(metadata.Owner) == 0 {
+		return metadata.Slack
+	}
+	if len(metadata.Slack) == 0 {
+		return metadata.Owner
+	}
+	return fmt.Sprintf("%s (%s)", metadata.Owner, metadata.Slack)
+}
+
+func (r *listedResources) String() string {
+	if r == nil {
+		return "-"
+	}
+	var limits []string
+	if len(r.Memory) > 0 {
+		limits = append(limits, "memory="+r.Memory)
+	}
+	if len(r.CPU) > 0 {
+		limits = append(limits, "cpu="+r.CPU)
+	}
+	return strings.Join(limits, " ")
+}
+
+// listedFunctions keeps the functions which match every filter, sorted by
+// name or by the most invocations or replicas
+func listedFunctions(statuses []proxy.FunctionStatus, filters []string, sortBy string) []listedFunction {
+	functions := []listedFunction{}
+	for _, status := range statuses {
+		if !matchesListFilters(status, filters) {
+			continue
+		}
+		functions = append(functions, listedFunction{
+			Name:            status.Name,
+			Namespace:       status.Namespace,
+			Image:           status.Image,
+			InvocationCount: int64(status.InvocationCount),
+			Replicas:        status.Replicas,
+			Labels:          status.Labels,
+			CreatedAt:       status.CreatedAt,
+			Limits:          listResources(status.Limits),
+			Requests:        listResources(status.Requests),
+			Metadata:        stack.MetadataFromAnnotations(status.Annotations),
+		})
+	}
+
+	sort.SliceStable(functions, func(i, j int) bool {
+		switch sortBy {
+		case "invocations":
+			if functions[i].InvocationCount != functions[j].InvocationCount {
+				return functions[i].InvocationCount > functions[j].InvocationCount
+			}
+		case "replicas":
+			if functions[i].Replicas != functions[j].Replicas {
+				return functions[i].Replicas > functions[j].Replicas
+			}
+		}
+		return functions[i].Name < functions[j].Name
+	})
+	return functions
+}
+
+func matchesListFilters(status proxy.FunctionStatus, filters []string) bool {
+	for _, listFilter := range filters {
+		if !strings.HasPrefix(listFilter, "label=") {
+			if !glob.Glob(listFilter, status.Name) {
+				return false
+			}
+			continue
+		}
+
+		label := strings.SplitN(strings.TrimPrefix(listFilter, "label="), "=", 2)
+		value, ok := status.Labels[label[0]]
+		if !ok || len(label) == 2 && value != label[1] {
+			return false
+		}
+	}
+	return true
+}
+
+func listResources(resources *stack.FunctionResources) *listedResources {
+	if resources == nil || len(resources.Memory) == 0 && len(resources.CPU) == 0 {
+		return nil
+	}
+	return &listedResources{Memory: resources.Memory, CPU: resources.CPU}
 }
@@ -4,10 +4,16 @@
 package commands
 
 import (
+	"encoding/json"
 	"net/http"
+	"reflect"
 	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas-cli/test"
 	"github.com/openfaas/faas/gateway/requests"
 )
@@ -78,3 +84,112 @@ func Test_list_errors(t *testing.T) {
 		t.Fatal("No error found while testing missing yaml")
 	}
 }
+
+func Test_listedFunctions(t *testing.T) {
+	statuses := []proxy.FunctionStatus{
+		{Name: "web", Replicas: 1, InvocationCount: 50, Labels: map[string]string{"team": "web"}},
+		{Name: "api", Replicas: 3, InvocationCount: 10, Labels: map[string]string{"team": "payments"}},
+		{Name: "api-v2", Replicas: 2, InvocationCount: 90, Labels: map[string]string{"team": "payments", "tier": "gold"}},
+	}
+
+	names := func(functions []listedFunction) []string {
+		var got []string
+		for _, function := range functions {
+			got = append(got, function.Name)
+		}
+		return got
+	}
+
+	cases := []struct {
+		name    string
+		filters []string
+		sortBy  string
+		want    []string
+	}{
+		{"by name", nil, "name", []string{"api", "api-v2", "web"}},
+		{"by invocations", nil, "invocations", []string{"api-v2", "web", "api"}},
+		{"by replicas", nil, "replicas", []string{"api", "api-v2", "web"}},
+		{"by label value", []string{"label=team=payments"}, "name", []string{"api", "api-v2"}},
+		{"by label", []string{"label=tier"}, "name", []string{"api-v2"}},
+		{"by name wildcard and label", []string{"api*", "label=team=payments"}, "invocations", []string{"api-v2", "api"}},
+		{"nothing matches", []string{"label=team=ops"}, "name", nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := names(listedFunctions(statuses, c.filters, c.sortBy)); !reflect.DeepEqual(got, c.want) {
+				t.Errorf("want %v, got %v", c.want, got)
+			}
+		})
+	}
+}
+
+func Test_list_Output(t *testing.T) {
+	defer func() { listOutput, listSort, listFilters = "table", "name", []string{} }()
+
+	created := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	statuses := []proxy.FunctionStatus{
+		{Name: "api", Image: "api:0.2", Replicas: 2, InvocationCount: 7, CreatedAt: &created, Limits: &stack.FunctionResources{Memory: "128Mi"},
+			Annotations: map[string]string{stack.OwnerAnnotation: "payments", stack.SlackAnnotation: "#payments-oncall"}},
+		{Name: "web", Image: "web:0.1", Replicas: 1},
+	}
+
+	s := test.MockHttpServer(t, []test.Request{
+		{Method: http.MethodGet, Uri: "/system/functions", ResponseStatusCode: http.StatusOK, ResponseBody: statuses},
+		{Method: http.MethodGet, Uri: "/system/functions", ResponseStatusCode: http.StatusOK, ResponseBody: statuses},
+	})
+	defer s.Close()
+
+	resetForTest()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"list", "--gateway=" + s.URL, "--output", "json", "--filter", "api"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var listed []listedFunction
+	if err := json.Unmarshal([]byte(stdOut), &listed); err != nil {
+		t.Fatalf("want JSON, got %q: %s", stdOut, err)
+	}
+	if len(listed) != 1 || listed[0].Name != "api" || listed[0].InvocationCount != 7 || listed[0].Limits.Memory != "128Mi" {
+		t.Errorf("want only api listed, got %+v", listed)
+	}
+	if len(listed) == 1 && (listed[0].Metadata == nil || listed[0].Metadata.Owner != "payments") {
+		t.Errorf("want the owner of api listed, got %+v", listed[0].Metadata)
+	}
+
+	listFilters = []string{}
+	stdOut = test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"list", "--gateway=" + s.URL, "--output", "wide"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	lines := strings.Split(strings.TrimSpace(stdOut), "\n")
+	if len(lines) != 3 || !strings.Contains(lines[0], "Created") || !strings.Contains(lines[1], "api:0.2") || !strings.Contains(lines[1], "memory=128Mi") ||
+		!strings.Contains(lines[1], "payments (#payments-oncall)") {
+		t.Fatalf("want the image, created date, limits and owner, got:\n%s", stdOut)
+	}
+	if fields := strings.Fields(lines[2]); fields[len(fields)-3] != "-" || fields[len(fields)-2] != "-" || fields[len(fields)-1] != "-" {
+		t.Errorf("want placeholders for what the provider does not report, got %q", lines[2])
+	}
+}
+
+func Test_list_InvalidFlags(t *testing.T) {
+	defer func() { listOutput, listSort = "table", "name" }()
+	resetForTest()
+
+	faasCmd.SetArgs([]string{"list", "--sort", "size"})
+	if err := faasCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--sort") {
+		t.Errorf("want an error for an unknown sort, got %v", err)
+	}
+
+	listSort = "name"
+	faasCmd.SetArgs([]string{"list", "--output", "csv"})
+	if err := faasCmd.Execute(); err == nil || !strings.Contains(err.Error(), "--output") {
+		t.Errorf("want an error for an unknown output, got %v", err)
+	}
+}
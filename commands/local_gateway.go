@@ -0,0 +1,436 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas/gateway/requests"
+	"github.com/spf13/cobra"
+)
+
+const localContainerPrefix = "faas-local-"
+
+// localGatewayShutdownTimeout is how long requests in flight are given to
+// finish when the gateway is stopped
+const localGatewayShutdownTimeout = 30 * time.Second
+
+var (
+	localGatewayPort    int
+	localQueueWorkers   int
+	localQueueSize      int
+	localKeepContainers bool
+)
+
+func init() {
+	localGatewayCmd.Flags().IntVarP(&localGatewayPort, "port", "p", 8080, "Port to serve the local gateway on")
+	localGatewayCmd.Flags().IntVar(&localQueueWorkers, "queue-workers", 1, "Number of workers processing asynchronous invocations")
+	localGatewayCmd.Flags().IntVar(&localQueueSize, "queue-size", 100, "Asynchronous invocations held in the queue before new ones are refused")
+	localGatewayCmd.Flags().BoolVar(&localKeepContainers, "keep", false, "Leave the function containers running on exit")
+
+	faasCmd.AddCommand(localGatewayCmd)
+}
+
+// localGatewayCmd runs the functions from a stack and routes to them like the gateway
+var localGatewayCmd = &cobra.Command{
+	Use:   `local-gateway -f YAML_FILE [--port PORT] [--queue-workers WORKERS]`,
+	Short: "Run the functions in a stack behind a local gateway",
+	Long: `Starts a container for each function in the YAML file and serves a lightweight
+gateway which routes /function/NAME to them, so that functions which call each
+other can be tested offline. Requests to /async-function/NAME are accepted with
+202, queued and then invoked in the background; the result is POSTed to the
+URL in the X-Callback-Url header when one is given.
+
+The function containers can reach the local gateway at http://gateway:PORT.
+Images must already be built, the containers are removed on exit.`,
+	Example: `  faas-cli local-gateway -f ./stack.yml
+  faas-cli local-gateway -f ./stack.yml --port 8081 --queue-workers 4
+  faas-cli local-gateway -f ./stack.yml --filter "*gif*"
+  faas-cli local-gateway -f ./stack.yml --metrics-listen :9090`,
+	RunE: runLocalGateway,
+}
+
+func runLocalGateway(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide a stack file with --yaml/-f")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return fmt.Errorf("docker is needed to run functions for the local gateway: %s", err)
+	}
+
+	routes := map[string]string{}
+	var containers []string
+	defer func() {
+		if !localKeepContainers {
+			stopLocalContainers(containers)
+		}
+	}()
+
+	for name, function := range services.Functions {
+		function.Name = name
+
+		port, err := freePort()
+		if err != nil {
+			return err
+		}
+
+		container, err := startLocalFunction(function, port, localGatewayPort)
+		if err != nil {
+			return err
+		}
+		containers = append(containers, container)
+
+		routes[name] = fmt.Sprintf("http://127.0.0.1:%d", port)
+		fmt.Printf("Started: %s on port %d.\n", name, port)
+	}
+
+	gateway := newLocalGateway(routes, localQueueSize)
+	gateway.startWorkers(localQueueWorkers)
+
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", localGatewayPort),
+		Handler: gateway,
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		ctx, cancel := context.WithTimeout(context.Background(), localGatewayShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			server.Close()
+		}
+	}()
+
+	fmt.Printf("Local gateway listening on http://127.0.0.1:%d\n", localGatewayPort)
+	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	gateway.stopWorkers()
+	return nil
+}
+
+// startLocalFunction runs the function's image with docker and publishes its
+// watchdog on hostPort, the container name is returned
+func startLocalFunction(function stack.Function, hostPort int, gatewayPort int) (string, error) {
+	fileEnvironment, err := readFiles(function.EnvironmentFile)
+	if err != nil {
+		return "", err
+	}
+
+	environment, err := compileEnvironment([]string{}, functionEnvironment(function), fileEnvironment)
+	if err != nil {
+		return "", err
+	}
+
+	if len(function.FProcess) == 0 && languageExistsNotDockerfile(function.Language) {
+		if fprocess, err := deriveFprocess(function); err == nil {
+			function.FProcess = fprocess
+		}
+	}
+	if len(function.FProcess) > 0 {
+		environment["fprocess"] = function.FProcess
+	}
+	environment["gateway_url"] = fmt.Sprintf("http://gateway:%d", gatewayPort)
+
+	container := localContainerPrefix + function.Name
+	dockerArgs := []string{"run", "-d", "--rm",
+		"--name", container,
+		"--add-host", "gateway:host-gateway",
+		"-p", fmt.Sprintf("127.0.0.1:%d:8080", hostPort),
+	}
+
+	keys := make([]string, 0, len(environment))
+	for key := range environment {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		dockerArgs = append(dockerArgs, "-e", key+"="+environment[key])
+	}
+	dockerArgs = append(dockerArgs, function.Image)
+
+	out, err := exec.Command("docker", dockerArgs...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("unable to start %s: %s", function.Name, strings.TrimSpace(string(out)))
+	}
+
+	return container, nil
+}
+
+func stopLocalContainers(containers []string) {
+	for _, container := range containers {
+		fmt.Printf("Removing: %s.\n", container)
+		exec.Command("docker", "rm", "-f", container).Run()
+	}
+}
+
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
+
+// asyncInvocation is a queued request to /async-function/
+type asyncInvocation struct {
+	callID       string
+	functionName string
+	path         string
+	rawQuery     string
+	header       http.Header
+	body         []byte
+	callbackURL  string
+}
+
+// localGateway routes invocations to the upstream URL of each function
+type localGateway struct {
+	routes  map[string]string
+	proxies map[string]*httputil.ReverseProxy
+	queue   chan asyncInvocation
+	workers sync.WaitGroup
+	client  http.Client
+	calls   int64
+	lock    sync.Mutex
+
+	// queuing counts the requests which may still send to queue, it is only
+	// closed once they are done and stopped refuses any more
+	queuing sync.WaitGroup
+	stopped bool
+}
+
+func newLocalGateway(routes map[string]string, queueSize int) *localGateway {
+	gateway := &localGateway{
+		routes:  routes,
+		proxies: map[string]*httputil.ReverseProxy{},
+		queue:   make(chan asyncInvocation, queueSize),
+		client:  http.Client{Timeout: 5 * time.Minute},
+	}
+
+	for name, upstream := range routes {
+		target, err := url.Parse(upstream)
+		if err == nil {
+			gateway.proxies[name] = httputil.NewSingleHostReverseProxy(target)
+		}
+	}
+
+	return gateway
+}
+
+func (g *localGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/function/"):
+		g.serveFunction(w, r)
+	case strings.HasPrefix(r.URL.Path, "/async-function/"):
+		g.serveAsyncFunction(w, r)
+	case r.URL.Path == "/system/functions" && r.Method == http.MethodGet:
+		g.serveList(w)
+	case r.URL.Path == "/healthz":
+		w.WriteHeader(http.StatusOK)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// splitFunctionPath turns /function/name/sub/path into name and /sub/path
+func splitFunctionPath(path string, prefix string) (string, string) {
+	rest := strings.TrimPrefix(path, prefix)
+	if i := strings.Index(rest, "/"); i != -1 {
+		return rest[:i], rest[i:]
+	}
+	return rest, "/"
+}
+
+func (g *localGateway) serveFunction(w http.ResponseWriter, r *http.Request) {
+	name, path := splitFunctionPath(r.URL.Path, "/function/")
+
+	proxy, ok := g.proxies[name]
+	if !ok {
+		http.Error(w, fmt.Sprintf("function %s not found in the stack", name), http.StatusNotFound)
+		return
+	}
+
+	r.URL.Path = path
+
+	started := time.Now()
+	recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	proxy.ServeHTTP(recorder, r)
+	observeInvocation(name, recorder.statusCode, started)
+}
+
+func (g *localGateway) serveAsyncFunction(w http.ResponseWriter, r *http.Request) {
+	name, path := splitFunctionPath(r.URL.Path, "/async-function/")
+
+	if _, ok := g.routes[name]; !ok {
+		http.Error(w, fmt.Sprintf("function %s not found in the stack", name), http.StatusNotFound)
+		return
+	}
+
+	g.lock.Lock()
+	if g.stopped {
+		g.lock.Unlock()
+		http.Error(w, "the gateway is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	g.queuing.Add(1)
+	g.lock.Unlock()
+	defer g.queuing.Done()
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	invocation := asyncInvocation{
+		callID:       g.nextCallID(),
+		functionName: name,
+		path:         path,
+		rawQuery:     r.URL.RawQuery,
+		header:       r.Header,
+		body:         body,
+		callbackURL:  r.Header.Get("X-Callback-Url"),
+	}
+
+	select {
+	case g.queue <- invocation:
+		w.Header().Set("X-Call-Id", invocation.callID)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "the queue is full", http.StatusTooManyRequests)
+	}
+}
+
+func (g *localGateway) serveList(w http.ResponseWriter) {
+	functions := []requests.Function{}
+	for name := range g.routes {
+		functions = append(functions, requests.Function{Name: name, Replicas: 1})
+	}
+	sort.Slice(functions, func(i, j int) bool {
+		return functions[i].Name < functions[j].Name
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(functions)
+}
+
+func (g *localGateway) nextCallID() string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.calls++
+	return strconv.FormatInt(g.calls, 10)
+}
+
+func (g *localGateway) startWorkers(count int) {
+	if count < 1 {
+		count = 1
+	}
+
+	for i := 0; i < count; i++ {
+		g.workers.Add(1)
+		go func() {
+			defer g.workers.Done()
+			for invocation := range g.queue {
+				g.invokeAsync(invocation)
+			}
+		}()
+	}
+}
+
+// stopWorkers refuses new asynchronous invocations, waits for the requests
+// queuing them and then for the workers to drain the queue
+func (g *localGateway) stopWorkers() {
+	g.lock.Lock()
+	g.stopped = true
+	g.lock.Unlock()
+
+	g.queuing.Wait()
+	close(g.queue)
+	g.workers.Wait()
+}
+
+// invokeAsync calls the function and POSTs its response to the callback URL
+func (g *localGateway) invokeAsync(invocation asyncInvocation) {
+	target := g.routes[invocation.functionName] + invocation.path
+	if len(invocation.rawQuery) > 0 {
+		target += "?" + invocation.rawQuery
+	}
+
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(invocation.body))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Async call %s to %s failed: %s\n", invocation.callID, invocation.functionName, err)
+		return
+	}
+	for key, values := range invocation.header {
+		if key == "X-Callback-Url" {
+			continue
+		}
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	req.Header.Set("X-Call-Id", invocation.callID)
+
+	started := time.Now()
+	res, err := g.client.Do(req)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Async call %s to %s failed: %s\n", invocation.callID, invocation.functionName, err)
+		return
+	}
+	result, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	observeInvocation(invocation.functionName, res.StatusCode, started)
+
+	fmt.Printf("Async call %s to %s returned %d in %s.\n", invocation.callID, invocation.functionName, res.StatusCode, time.Since(started).Round(time.Millisecond))
+
+	if len(invocation.callbackURL) == 0 {
+		return
+	}
+
+	callback, err := http.NewRequest(http.MethodPost, invocation.callbackURL, bytes.NewReader(result))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Callback for call %s failed: %s\n", invocation.callID, err)
+		return
+	}
+	callback.Header.Set("Content-Type", res.Header.Get("Content-Type"))
+	callback.Header.Set("X-Call-Id", invocation.callID)
+	callback.Header.Set("X-Function-Status", strconv.Itoa(res.StatusCode))
+
+	callbackRes, err := g.client.Do(callback)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Callback for call %s failed: %s\n", invocation.callID, err)
+		return
+	}
+	callbackRes.Body.Close()
+}
@@ -0,0 +1,171 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/metrics"
+	"github.com/openfaas/faas/gateway/requests"
+)
+
+func Test_localGateway_RoutesToFunction(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte(r.URL.Path + "?" + r.URL.RawQuery + " " + string(body)))
+	}))
+	defer upstream.Close()
+
+	gateway := httptest.NewServer(newLocalGateway(map[string]string{"echo": upstream.URL}, 1))
+	defer gateway.Close()
+
+	cases := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{"/function/echo?a=1", http.StatusOK, "/?a=1 ping"},
+		{"/function/echo/sub/path", http.StatusOK, "/sub/path? ping"},
+		{"/function/missing", http.StatusNotFound, "function missing not found"},
+	}
+
+	for _, c := range cases {
+		res, err := http.Post(gateway.URL+c.path, "text/plain", strings.NewReader("ping"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != c.wantStatus {
+			t.Errorf("%s: want status %d, got %d", c.path, c.wantStatus, res.StatusCode)
+		}
+		if !strings.Contains(string(body), c.wantBody) {
+			t.Errorf("%s: want body containing %q, got %q", c.path, c.wantBody, string(body))
+		}
+	}
+
+	var metricsOut bytes.Buffer
+	metrics.DefaultRegistry.Write(&metricsOut)
+	if !strings.Contains(metricsOut.String(), `faas_cli_local_gateway_invocations_total{function="echo",code="200"} 2`) {
+		t.Errorf("want the invocations counted, got:\n%s", metricsOut.String())
+	}
+}
+
+func Test_localGateway_ListsFunctions(t *testing.T) {
+	gateway := httptest.NewServer(newLocalGateway(map[string]string{"b": "http://127.0.0.1:1", "a": "http://127.0.0.1:2"}, 1))
+	defer gateway.Close()
+
+	res, err := http.Get(gateway.URL + "/system/functions")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer res.Body.Close()
+
+	var functions []requests.Function
+	if err := json.NewDecoder(res.Body).Decode(&functions); err != nil {
+		t.Fatalf("unable to decode list: %s", err)
+	}
+	if len(functions) != 2 || functions[0].Name != "a" || functions[1].Name != "b" {
+		t.Fatalf("want functions a and b, got %+v", functions)
+	}
+}
+
+func Test_localGateway_AsyncCallback(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.Header.Get("X-Callback-Url")) > 0 {
+			t.Errorf("the callback URL should not be passed to the function")
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write([]byte("processed " + string(body)))
+	}))
+	defer upstream.Close()
+
+	type callbackResult struct {
+		body   string
+		status string
+		callID string
+	}
+	results := make(chan callbackResult, 1)
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		results <- callbackResult{string(body), r.Header.Get("X-Function-Status"), r.Header.Get("X-Call-Id")}
+	}))
+	defer callback.Close()
+
+	localGateway := newLocalGateway(map[string]string{"worker": upstream.URL}, 1)
+	localGateway.startWorkers(1)
+	defer localGateway.stopWorkers()
+
+	gateway := httptest.NewServer(localGateway)
+	defer gateway.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, gateway.URL+"/async-function/worker", strings.NewReader("job"))
+	req.Header.Set("X-Callback-Url", callback.URL)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusAccepted {
+		t.Fatalf("want 202, got %d", res.StatusCode)
+	}
+	callID := res.Header.Get("X-Call-Id")
+
+	select {
+	case result := <-results:
+		if result.body != "processed job" || result.status != "200" || result.callID != callID {
+			t.Fatalf("unexpected callback: %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the callback")
+	}
+}
+
+func Test_localGateway_AsyncQueueFull(t *testing.T) {
+	gateway := httptest.NewServer(newLocalGateway(map[string]string{"worker": "http://127.0.0.1:1"}, 1))
+	defer gateway.Close()
+
+	statuses := []int{}
+	for i := 0; i < 2; i++ {
+		res, err := http.Post(gateway.URL+"/async-function/worker", "text/plain", strings.NewReader("job"))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		res.Body.Close()
+		statuses = append(statuses, res.StatusCode)
+	}
+
+	if statuses[0] != http.StatusAccepted || statuses[1] != http.StatusTooManyRequests {
+		t.Fatalf("want 202 then 429 with no workers running, got %v", statuses)
+	}
+}
+
+func Test_localGateway_AsyncAfterStop(t *testing.T) {
+	localGateway := newLocalGateway(map[string]string{"worker": "http://127.0.0.1:1"}, 1)
+	localGateway.startWorkers(1)
+	localGateway.stopWorkers()
+
+	gateway := httptest.NewServer(localGateway)
+	defer gateway.Close()
+
+	// The queue is closed, the request must be refused rather than sent on it
+	res, err := http.Post(gateway.URL+"/async-function/worker", "text/plain", strings.NewReader("job"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("want 503 once the workers are stopped, got %d", res.StatusCode)
+	}
+}
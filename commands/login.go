@@ -5,6 +5,7 @@ package commands
 
 import (
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -20,6 +21,8 @@ var (
 	username      string
 	password      string
 	passwordStdin bool
+	loginStore    string
+	loginProfile  string
 )
 
 func init() {
@@ -27,16 +30,26 @@ func init() {
 	loginCmd.Flags().StringVarP(&username, "username", "u", "", "Gateway username")
 	loginCmd.Flags().StringVarP(&password, "password", "p", "", "Gateway password")
 	loginCmd.Flags().BoolVar(&passwordStdin, "password-stdin", false, "Reads the gateway password from stdin")
+	loginCmd.Flags().StringVar(&loginProfile, "profile", "", "Save the gateway as a named profile which can be given to --gateway in place of its URL")
+	loginCmd.Flags().StringVar(&loginStore, "store", "", "Store the credentials in the file, keychain or age store, defaults to credentials.store in the config file")
 
 	faasCmd.AddCommand(loginCmd)
 }
 
 var loginCmd = &cobra.Command{
-	Use:   `login [--username USERNAME] [--password PASSWORD] [--gateway GATEWAY_URL]`,
+	Use:   `login [--username USERNAME] [--password PASSWORD] [--gateway GATEWAY_URL] [--profile NAME]`,
 	Short: "Log in to OpenFaaS gateway",
-	Long:  "Log in to OpenFaaS gateway.\nIf no gateway is specified, the default local one will be used.",
+	Long: `Log in to OpenFaaS gateway.
+If no gateway is specified, the default local one will be used.
+
+--profile saves the gateway under a name in the contexts of
+~/.openfaas/config.yml, so that "--gateway NAME" can be given to any command
+in place of the URL. Each gateway keeps its own credentials.`,
 	Example: `  faas-cli login -u user -p password --gateway http://localhost:8080
-  cat ~/faas_pass.txt | faas-cli login -u user --password-stdin --gateway https://openfaas.mydomain.com`,
+  cat ~/faas_pass.txt | faas-cli login -u user --password-stdin --gateway https://openfaas.mydomain.com
+  faas-cli login -u user --password-stdin --store keychain
+  cat ~/staging_pass.txt | faas-cli login -u user --password-stdin --gateway https://staging.mydomain.com --profile staging
+  faas-cli list --gateway staging`,
 	RunE: runLogin,
 }
 
@@ -81,7 +94,7 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	if err := config.UpdateAuthConfig(gateway, username, password); err != nil {
+	if err := config.UpdateAuthConfigInStore(gateway, username, password, loginStore); err != nil {
 		return err
 	}
 
@@ -91,10 +104,26 @@ func runLogin(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println("credentials saved for", user, gateway)
 
+	if len(loginProfile) > 0 {
+		if err := config.SaveContext(loginProfile, gateway); err != nil {
+			return err
+		}
+		fmt.Printf("profile %s saved for %s\n", loginProfile, gateway)
+	}
+
 	return nil
 }
 
 func validateLogin(gatewayURL string, user string, pass string) error {
+	return validateGatewayAuth(gatewayURL, func(req *http.Request) {
+		req.SetBasicAuth(user, pass)
+	}, "unable to login, either username or password is incorrect")
+}
+
+// validateGatewayAuth lists the functions on the gateway with the
+// credentials set by setAuth to check that they are accepted, rejected is
+// the error given when they are not
+func validateGatewayAuth(gatewayURL string, setAuth func(*http.Request), rejected string) error {
 	tr := &http.Transport{
 		DisableKeepAlives: false,
 		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
@@ -109,7 +138,7 @@ func validateLogin(gatewayURL string, user string, pass string) error {
 		return fmt.Errorf("invalid URL: %s", gatewayURL)
 	}
 
-	req.SetBasicAuth(user, pass)
+	setAuth(req)
 	res, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gatewayURL)
@@ -127,7 +156,7 @@ func validateLogin(gatewayURL string, user string, pass string) error {
 	case http.StatusOK:
 		return nil
 	case http.StatusUnauthorized:
-		return fmt.Errorf("unable to login, either username or password is incorrect")
+		return errors.New(rejected)
 	default:
 		bytesOut, err := ioutil.ReadAll(res.Body)
 		if err == nil {
@@ -11,18 +11,22 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var logoutProfile string
+
 func init() {
 	logoutCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	logoutCmd.Flags().StringVar(&logoutProfile, "profile", "", "Log out from the gateway of a profile saved by login --profile and remove the profile")
 
 	faasCmd.AddCommand(logoutCmd)
 }
 
 var logoutCmd = &cobra.Command{
-	Use:     `logout [--gateway GATEWAY_URL]`,
-	Short:   "Log out from OpenFaaS gateway",
-	Long:    "Log out from OpenFaaS gateway.\nIf no gateway is specified, the default local one will be used.",
-	Example: `  faas-cli logout --gateway https://openfaas.mydomain.com`,
-	RunE:    runLogout,
+	Use:   `logout [--gateway GATEWAY_URL] [--profile NAME]`,
+	Short: "Log out from OpenFaaS gateway",
+	Long:  "Log out from OpenFaaS gateway.\nIf no gateway is specified, the default local one will be used.",
+	Example: `  faas-cli logout --gateway https://openfaas.mydomain.com
+  faas-cli logout --profile staging`,
+	RunE: runLogout,
 }
 
 func runLogout(cmd *cobra.Command, args []string) error {
@@ -30,6 +34,18 @@ func runLogout(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("gateway cannot be an empty string")
 	}
 
+	if len(logoutProfile) > 0 {
+		profileGateway, err := config.LookupContext(logoutProfile)
+		if err != nil {
+			return err
+		}
+		gateway = profileGateway
+	} else if !strings.Contains(gateway, "://") {
+		if profileGateway, err := config.LookupContext(strings.TrimSpace(gateway)); err == nil {
+			gateway = profileGateway
+		}
+	}
+
 	gateway = strings.TrimRight(strings.TrimSpace(gateway), "/")
 	err := config.RemoveAuthConfig(gateway)
 	if err != nil {
@@ -37,5 +53,12 @@ func runLogout(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println("credentials removed for", gateway)
 
+	if len(logoutProfile) > 0 {
+		if _, err := config.RemoveContext(logoutProfile); err != nil {
+			return err
+		}
+		fmt.Println("profile removed:", logoutProfile)
+	}
+
 	return nil
 }
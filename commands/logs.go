@@ -0,0 +1,202 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/audit"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	logsTail      int
+	logsParseJSON bool
+	logsFields    []string
+	logsFollow    bool
+	logsSince     string
+	logsInstance  string
+	logsFormat    string
+)
+
+func init() {
+	logsCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	logsCmd.Flags().IntVar(&logsTail, "tail", 100, "How many of the most recent lines to show, -1 shows all of them")
+	logsCmd.Flags().BoolVar(&logsFollow, "follow", false, "Keep printing lines as they are logged, reconnecting when the gateway drops the stream")
+	logsCmd.Flags().StringVar(&logsSince, "since", "", "Only show lines logged since a duration ago such as 15m or 2h, or since a time such as 2018-06-01T10:00:00Z")
+	logsCmd.Flags().StringVar(&logsInstance, "instance", "", "Only show the lines of this replica of the function")
+	logsCmd.Flags().StringVar(&logsFormat, "format", "plain", "Print lines as plain text or as json, one object per line")
+	logsCmd.Flags().BoolVar(&logsParseJSON, "parse-json", false, "Pretty-print lines logged as JSON, colored by their level")
+	logsCmd.Flags().StringArrayVar(&logsFields, "field", []string{}, "Only show JSON lines with this field, i.e. request_id=abc, implies --parse-json")
+
+	faasCmd.AddCommand(logsCmd)
+}
+
+var logsCmd = &cobra.Command{
+	Use: `logs FUNCTION_NAME [--tail LINES] [--since 15m] [--follow]
+                   [--instance NAME] [--format plain|json]
+                   [--parse-json] [--field KEY=VALUE]`,
+	Short: "Show the recent logs of a function",
+	Long: `Shows the most recent lines logged by a function, from providers which serve
+/system/logs. With --follow lines are printed as they are logged, and when the
+gateway drops the connection the stream is reopened from the last line printed.
+
+--format json prints each line as it was read from the gateway, with the
+function, instance and time, for other tools to read.
+
+With --parse-json, lines logged as JSON objects are shown as their time, level
+and message followed by their other fields, and colored by level. Functions
+given logging.format: json in the YAML file log this way. --field keeps only
+the JSON lines whose field has the value given.`,
+	Example: `  faas-cli logs figlet
+  faas-cli logs api --parse-json
+  faas-cli logs api --field request_id=abc --field level=error
+  faas-cli logs api --follow --since 15m
+  faas-cli logs api --tail -1 --instance api-7d9c8b5f4-x2lqz --format json`,
+	RunE: runLogs,
+}
+
+func runLogs(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the function")
+	}
+	name := args[0]
+
+	fields, err := parseMap(logsFields, "field")
+	if err != nil {
+		return err
+	}
+	if logsFormat != "plain" && logsFormat != "json" {
+		return fmt.Errorf("--format must be plain or json, not %s", logsFormat)
+	}
+
+	request := proxy.LogRequest{Name: name, Namespace: functionNamespace, Instance: logsInstance, Tail: logsTail, Follow: logsFollow}
+	if len(logsSince) > 0 {
+		if request.Since, err = audit.ParseSince(logsSince, time.Now()); err != nil {
+			return fmt.Errorf("invalid --since: %s", err)
+		}
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	return proxy.StreamLogs(gatewayAddress, request, func(message proxy.LogMessage) {
+		printLogMessage(os.Stdout, message, logsFormat, fields)
+	})
+}
+
+// printLogMessage prints a line in the format given, JSON lines are still
+// matched against fields
+func printLogMessage(w io.Writer, message proxy.LogMessage, format string, fields map[string]string) {
+	text := strings.TrimRight(message.Text, "\n")
+	switch {
+	case format == "json":
+		if len(fields) > 0 && !matchesLogFields(text, fields) {
+			return
+		}
+		out, _ := json.Marshal(message)
+		fmt.Fprintln(w, string(out))
+	case !logsParseJSON && len(fields) == 0:
+		fmt.Fprintf(w, "%s %s\n", message.Timestamp.Format("2006-01-02T15:04:05Z07:00"), text)
+	default:
+		printLogLine(w, message, text, fields)
+	}
+}
+
+// matchesLogFields is true for a line logged as JSON with each of the fields
+func matchesLogFields(text string, fields map[string]string) bool {
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &entry); err != nil {
+		return false
+	}
+	for key, value := range fields {
+		if logFieldString(entry[key]) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Fields which hold the level, message and time of a structured log line
+var (
+	logLevelFields   = []string{"level", "lvl", "severity"}
+	logMessageFields = []string{"msg", "message"}
+	logTimeFields    = []string{"time", "ts", "timestamp"}
+)
+
+// printLogLine pretty-prints a line logged as JSON, other lines are printed
+// as they are unless fields are being matched
+func printLogLine(w io.Writer, message proxy.LogMessage, text string, fields map[string]string) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(text), &entry); err != nil {
+		if len(fields) == 0 {
+			fmt.Fprintf(w, "%s %s\n", message.Timestamp.Format("15:04:05"), text)
+		}
+		return
+	}
+	if !matchesLogFields(text, fields) {
+		return
+	}
+
+	level := takeLogField(entry, logLevelFields)
+	msg := takeLogField(entry, logMessageFields)
+	timestamp := takeLogField(entry, logTimeFields)
+	if len(timestamp) == 0 {
+		timestamp = message.Timestamp.Format("15:04:05")
+	}
+
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	line := fmt.Sprintf("%s %-5s %s", timestamp, strings.ToUpper(level), msg)
+	for _, key := range keys {
+		line += fmt.Sprintf(" %s=%s", key, logFieldString(entry[key]))
+	}
+	fmt.Fprintln(w, logLevelColor(level).Apply(line))
+}
+
+// takeLogField removes and returns the first of the names present
+func takeLogField(entry map[string]interface{}, names []string) string {
+	for _, name := range names {
+		if value, ok := entry[name]; ok {
+			delete(entry, name)
+			return logFieldString(value)
+		}
+	}
+	return ""
+}
+
+func logFieldString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		out, _ := json.Marshal(v)
+		return string(out)
+	}
+}
+
+func logLevelColor(level string) aec.ANSI {
+	switch strings.ToLower(level) {
+	case "error", "fatal", "panic", "critical":
+		return aec.RedF
+	case "warn", "warning":
+		return aec.YellowF
+	case "debug", "trace":
+		return aec.LightBlackF
+	default:
+		return aec.DefaultF
+	}
+}
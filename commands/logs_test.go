@@ -0,0 +1,103 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_printLogLine(t *testing.T) {
+	message := proxy.LogMessage{Timestamp: time.Date(2018, 6, 10, 12, 30, 0, 0, time.UTC)}
+
+	var out bytes.Buffer
+	printLogLine(&out, message, `{"level":"error","msg":"lookup failed","request_id":"abc","attempt":2}`, nil)
+	if got := out.String(); !strings.Contains(got, "12:30:00 ERROR lookup failed attempt=2 request_id=abc") {
+		t.Errorf("unexpected line: %q", got)
+	}
+
+	out.Reset()
+	printLogLine(&out, message, "plain text", nil)
+	if got := out.String(); got != "12:30:00 plain text\n" {
+		t.Errorf("want plain lines as they are, got: %q", got)
+	}
+}
+
+func Test_printLogLine_Fields(t *testing.T) {
+	message := proxy.LogMessage{Timestamp: time.Now()}
+	fields := map[string]string{"request_id": "abc"}
+
+	var out bytes.Buffer
+	printLogLine(&out, message, `{"msg":"one","request_id":"abc"}`, fields)
+	printLogLine(&out, message, `{"msg":"two","request_id":"def"}`, fields)
+	printLogLine(&out, message, "plain text", fields)
+
+	got := out.String()
+	if !strings.Contains(got, "one") || strings.Contains(got, "two") || strings.Contains(got, "plain") {
+		t.Errorf("want only the line with request_id=abc, got: %q", got)
+	}
+}
+
+func Test_runLogs(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/logs?follow=false&name=api&tail=100",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       proxy.LogMessage{Name: "api", Text: `{"level":"info","msg":"started"}`},
+		},
+	})
+	defer s.Close()
+
+	output := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"logs", "api", "-g", s.URL, "--parse-json"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if !strings.Contains(output, "INFO  started") {
+		t.Fatalf("want the pretty-printed line, got:\n%s", output)
+	}
+}
+
+func Test_runLogs_SinceInstanceAndJSON(t *testing.T) {
+	defer func() { logsSince, logsInstance, logsFormat = "", "", "plain" }()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/logs?follow=false&instance=api-1&name=api&since=2018-06-01T10%3A00%3A00Z&tail=100",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       proxy.LogMessage{Name: "api", Instance: "api-1", Text: "started"},
+		},
+	})
+	defer s.Close()
+
+	output := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"logs", "api", "-g", s.URL, "--since", "2018-06-01T10:00:00Z", "--instance", "api-1", "--format", "json"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if !strings.HasPrefix(output, `{"name":"api","instance":"api-1",`) || !strings.HasSuffix(output, `"text":"started"}`+"\n") {
+		t.Fatalf("want the line as JSON, got:\n%s", output)
+	}
+}
+
+func Test_runLogs_InvalidFormat(t *testing.T) {
+	defer func() { logsFormat = "plain" }()
+
+	faasCmd.SetArgs([]string{"logs", "api", "--format", "yaml"})
+	if err := faasCmd.Execute(); err == nil || err.Error() != "--format must be plain or json, not yaml" {
+		t.Fatalf("want an error for the format, got %v", err)
+	}
+}
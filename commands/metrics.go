@@ -0,0 +1,87 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/openfaas/faas-cli/metrics"
+)
+
+// metricsListen is the address metrics are served on, such as ":9090"
+var metricsListen string
+
+var metricsStarted bool
+
+var (
+	buildsTotal = metrics.DefaultRegistry.NewCounter("faas_cli_builds_total",
+		"Function images built by outcome", "function", "status")
+	buildDuration = metrics.DefaultRegistry.NewHistogram("faas_cli_build_duration_seconds",
+		"Time taken to build each function image", metrics.DefaultBuckets, "function")
+	deploysTotal = metrics.DefaultRegistry.NewCounter("faas_cli_deploys_total",
+		"Function deployments by outcome", "function", "status")
+	deployDuration = metrics.DefaultRegistry.NewHistogram("faas_cli_deploy_duration_seconds",
+		"Latency of each call to the gateway to deploy a function", metrics.DefaultBuckets, "function")
+	localInvocationsTotal = metrics.DefaultRegistry.NewCounter("faas_cli_local_gateway_invocations_total",
+		"Invocations handled by local-gateway by status code", "function", "code")
+	localInvocationDuration = metrics.DefaultRegistry.NewHistogram("faas_cli_local_gateway_invocation_duration_seconds",
+		"Time taken by functions to respond through local-gateway", metrics.DefaultBuckets, "function")
+)
+
+func init() {
+	faasCmd.PersistentFlags().StringVar(&metricsListen, "metrics-listen", "", "Serve Prometheus metrics for builds, deployments and local-gateway on this address, i.e. :9090")
+}
+
+// startMetrics serves metrics on --metrics-listen once per run
+func startMetrics() error {
+	if len(metricsListen) == 0 || metricsStarted {
+		return nil
+	}
+
+	if err := metrics.Serve(metricsListen); err != nil {
+		return err
+	}
+	metricsStarted = true
+
+	fmt.Fprintf(os.Stderr, "Serving metrics on %s/metrics\n", metricsListen)
+	return nil
+}
+
+func metricsStatus(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
+
+// observeBuild records a build which finished, builds which fail exit the CLI
+func observeBuild(function string, started time.Time) {
+	buildsTotal.Inc(function, metricsStatus(true))
+	buildDuration.Observe(time.Since(started).Seconds(), function)
+}
+
+func observeDeploy(function string, statusCode int, started time.Time) {
+	deploysTotal.Inc(function, metricsStatus(deploySucceeded(statusCode)))
+	deployDuration.Observe(time.Since(started).Seconds(), function)
+}
+
+func observeInvocation(function string, statusCode int, started time.Time) {
+	localInvocationsTotal.Inc(function, strconv.Itoa(statusCode))
+	localInvocationDuration.Observe(time.Since(started).Seconds(), function)
+}
+
+// statusRecorder remembers the status code written by a reverse proxy
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
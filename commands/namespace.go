@@ -0,0 +1,99 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// functionNamespace is the namespace functions and secrets are managed in,
+// the provider's default when empty
+var functionNamespace string
+
+func init() {
+	faasCmd.PersistentFlags().StringVarP(&functionNamespace, "namespace", "n", "", "Namespace of the functions and secrets, defaults to the provider's")
+
+	namespacesCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+
+	faasCmd.AddCommand(namespacesCmd)
+}
+
+var namespacesCmd = &cobra.Command{
+	Use:   `namespaces [--gateway GATEWAY_URL]`,
+	Short: "List the namespaces functions can be deployed to",
+	Long: `Lists the namespaces on the gateway which functions can be deployed to, for
+providers which support more than one such as faas-netes. Give one of them to
+other commands with --namespace, or set namespace: on a function in the YAML
+file.`,
+	Example: `  faas-cli namespaces
+  faas-cli deploy -f stack.yml --namespace staging
+  faas-cli list --namespace staging`,
+	RunE: runNamespaces,
+}
+
+func runNamespaces(cmd *cobra.Command, args []string) error {
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	namespaces, err := proxy.ListNamespaces(gatewayAddress)
+	if err != nil {
+		return err
+	}
+	if namespaces == nil {
+		if isFaasd(gatewayAddress) {
+			return fmt.Errorf("the gateway at %s is faasd, which runs every function in the %s namespace", gatewayAddress, faasdNamespace)
+		}
+		return fmt.Errorf("the gateway at %s does not support namespaces", gatewayAddress)
+	}
+
+	sort.Strings(namespaces)
+	for _, namespace := range namespaces {
+		fmt.Println(namespace)
+	}
+	return nil
+}
+
+// namespaceOf gives the namespace a function in the stack is deployed to,
+// its own namespace wins over --namespace
+func namespaceOf(function stack.Function) string {
+	if len(function.Namespace) > 0 {
+		return function.Namespace
+	}
+	return functionNamespace
+}
+
+// stackNamespaces gives each namespace the functions of the stack are
+// deployed to, sorted
+func stackNamespaces(services *stack.Services) []string {
+	seen := map[string]bool{}
+	var namespaces []string
+	for _, function := range services.Functions {
+		namespace := namespaceOf(function)
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// functionStatusInNamespaces lists the functions deployed to each namespace,
+// keyed by their name qualified with the namespace
+func functionStatusInNamespaces(gateway string, namespaces []string) (map[string]proxy.FunctionStatus, error) {
+	byName := map[string]proxy.FunctionStatus{}
+	for _, namespace := range namespaces {
+		statuses, err := functionStatusByName(gateway, namespace)
+		if err != nil {
+			return nil, err
+		}
+		for name, status := range statuses {
+			byName[qualifiedName(name, namespace)] = status
+		}
+	}
+	return byName, nil
+}
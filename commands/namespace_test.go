@@ -0,0 +1,90 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_namespaceOf(t *testing.T) {
+	defer resetForTest()
+	functionNamespace = "staging"
+
+	if got := namespaceOf(stack.Function{}); got != "staging" {
+		t.Errorf("want --namespace for a function without one, got: %q", got)
+	}
+	if got := namespaceOf(stack.Function{Namespace: "payments"}); got != "payments" {
+		t.Errorf("want the function's own namespace, got: %q", got)
+	}
+}
+
+func Test_namespaces(t *testing.T) {
+	resetForTest()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/namespaces",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []string{"staging", "openfaas-fn"},
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"namespaces", "--gateway=" + s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if stdOut != "openfaas-fn\nstaging\n" {
+		t.Errorf("want the namespaces sorted, got: %q", stdOut)
+	}
+}
+
+func Test_namespaces_NotSupported(t *testing.T) {
+	resetForTest()
+
+	s := test.MockHttpServerStatus(t, http.StatusNotFound, http.StatusNotFound)
+	defer s.Close()
+
+	faasCmd.SetArgs([]string{"namespaces", "--gateway=" + s.URL})
+	err := faasCmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "does not support namespaces") {
+		t.Errorf("want an error for a provider without namespaces, got: %v", err)
+	}
+}
+
+func Test_remove_Namespace(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []map[string]interface{}{},
+		},
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/functions?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"remove", "--gateway=" + s.URL, "--namespace", "staging", "test-function"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Choices for a function appended with new --append whose name or image is
+// already in the stack
+const (
+	conflictAsk       = "ask"
+	conflictRename    = "rename"
+	conflictOverwrite = "overwrite"
+	conflictAbort     = "abort"
+)
+
+var onConflict string
+
+var (
+	// newInput is read for the choice when --append collides, it is
+	// swapped out in tests
+	newInput io.Reader = os.Stdin
+
+	// newInteractive reports whether the choice can be asked for, it is
+	// swapped out in tests
+	newInteractive = func() bool {
+		return terminal.IsTerminal(int(os.Stdin.Fd()))
+	}
+)
+
+func init() {
+	newFunctionCmd.Flags().StringVar(&onConflict, "on-conflict", conflictAsk, "What to do when the name or image of a function given with --append is already in the stack: ask, rename, overwrite or abort")
+}
+
+// appendResolution is the name the function is appended under and the
+// entries of the stack it replaces
+type appendResolution struct {
+	Name    string
+	Replace []string
+}
+
+// stackNames reads the names and images of the functions in a stack file
+// without validating the rest of it
+func stackNames(stackYAML []byte) (map[string]string, error) {
+	var parsed struct {
+		Functions map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"functions"`
+	}
+	if err := yaml.Unmarshal(stackYAML, &parsed); err != nil {
+		return nil, err
+	}
+
+	images := map[string]string{}
+	for name, function := range parsed.Functions {
+		images[name] = function.Image
+	}
+	return images, nil
+}
+
+// appendConflicts gives the functions in the stack with the name, or with the
+// image a function of that name is given by new
+func appendConflicts(images map[string]string, name string) []string {
+	newImage := newFunctionImage(name)
+
+	var conflicts []string
+	for existing, image := range images {
+		if existing == name || image == newImage {
+			conflicts = append(conflicts, existing)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// freeFunctionName adds the first number to name which gives a name no
+// function, image or folder uses
+func freeFunctionName(images map[string]string, name string) string {
+	for i := 2; ; i++ {
+		candidate := name + "-" + strconv.Itoa(i)
+		if len(appendConflicts(images, candidate)) > 0 {
+			continue
+		}
+		if _, err := os.Stat(candidate); err == nil {
+			continue
+		}
+		return candidate
+	}
+}
+
+// resolveAppend decides, with --on-conflict or by asking, how a function is
+// appended to the stack file when its name or image is already there
+func resolveAppend(stackFile string, name string, choice string) (appendResolution, error) {
+	resolution := appendResolution{Name: name}
+
+	stackYAML, err := ioutil.ReadFile(stackFile)
+	if err != nil {
+		return resolution, err
+	}
+	images, err := stackNames(stackYAML)
+	if err != nil {
+		return resolution, fmt.Errorf("unable to parse %s to append to it: %s", stackFile, err)
+	}
+
+	conflicts := appendConflicts(images, name)
+	if len(conflicts) == 0 {
+		return resolution, nil
+	}
+
+	suggested := freeFunctionName(images, name)
+	var reader *bufio.Reader
+
+	switch choice {
+	case conflictAsk:
+		if !newInteractive() {
+			return resolution, fmt.Errorf("%s already has a function named %s or using its image, choose what to do with --on-conflict rename, overwrite or abort", stackFile, name)
+		}
+
+		fmt.Printf("%s already has %s with the name or image %s.\n", stackFile, strings.Join(conflicts, ", "), name)
+		fmt.Print("[r]ename, [o]verwrite or [a]bort? ")
+		reader = bufio.NewReader(newInput)
+		answer, _ := reader.ReadString('\n')
+		switch strings.ToLower(strings.TrimSpace(answer)) {
+		case "r", conflictRename:
+			choice = conflictRename
+		case "o", conflictOverwrite:
+			choice = conflictOverwrite
+		default:
+			choice = conflictAbort
+		}
+	case conflictRename, conflictOverwrite, conflictAbort:
+	default:
+		return resolution, fmt.Errorf("--on-conflict must be ask, rename, overwrite or abort, not %s", choice)
+	}
+
+	switch choice {
+	case conflictRename:
+		resolution.Name = suggested
+		if reader != nil {
+			fmt.Printf("New name [%s]: ", suggested)
+			answer, _ := reader.ReadString('\n')
+			if answer = strings.TrimSpace(answer); len(answer) > 0 {
+				if len(appendConflicts(images, answer)) > 0 {
+					return resolution, fmt.Errorf("%s is also already in %s", answer, stackFile)
+				}
+				resolution.Name = answer
+			}
+		}
+		newPrintf("Appending the function as %s.\n", resolution.Name)
+	case conflictOverwrite:
+		resolution.Replace = conflicts
+		newPrintf("Replacing %s in %s.\n", strings.Join(conflicts, ", "), stackFile)
+	default:
+		return resolution, fmt.Errorf("not appending %s, %s already has %s", name, stackFile, strings.Join(conflicts, ", "))
+	}
+	return resolution, nil
+}
+
+// removeStackEntries removes the entries of the functions from the stack
+// file, keeping the comments and layout of the rest of it
+func removeStackEntries(stackYAML string, names []string) (string, error) {
+	for _, name := range names {
+		lines := strings.Split(stackYAML, "\n")
+		start, end, err := stackEntryBounds(lines, name)
+		if err != nil {
+			return "", err
+		}
+		stackYAML = strings.Join(append(lines[:start:start], lines[end:]...), "\n")
+	}
+	return stackYAML, nil
+}
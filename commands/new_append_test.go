@@ -0,0 +1,147 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+const appendStack = `provider:
+  name: faas
+  gateway: http://127.0.0.1:8080
+
+functions:
+  # resizes images
+  resizer:
+    lang: python
+    handler: ./resizer
+    image: resizer
+  thumbnailer:
+    lang: python
+    handler: ./thumbnailer
+    image: resizer-2
+  reader:
+    lang: go
+    handler: ./reader
+    image: reader
+`
+
+func writeAppendStack(t *testing.T) string {
+	file, err := ioutil.TempFile("", "faas-cli-append-*.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(appendStack)
+	file.Close()
+	return file.Name()
+}
+
+func Test_resolveAppend(t *testing.T) {
+	stackFile := writeAppendStack(t)
+	defer os.Remove(stackFile)
+
+	originalInteractive := newInteractive
+	defer func() { newInteractive = originalInteractive }()
+	newInteractive = func() bool { return false }
+
+	cases := []struct {
+		name    string
+		choice  string
+		want    appendResolution
+		wantErr string
+	}{
+		{name: "writer", choice: conflictAsk, want: appendResolution{Name: "writer"}},
+		{name: "resizer", choice: conflictAsk, wantErr: "--on-conflict"},
+		{name: "resizer", choice: conflictAbort, wantErr: "not appending resizer"},
+		{name: "resizer", choice: "merge", wantErr: "must be ask, rename, overwrite or abort"},
+		// resizer-2 is taken as an image
+		{name: "resizer", choice: conflictRename, want: appendResolution{Name: "resizer-3"}},
+		{name: "resizer-2", choice: conflictOverwrite, want: appendResolution{Name: "resizer-2", Replace: []string{"thumbnailer"}}},
+	}
+
+	for _, c := range cases {
+		var got appendResolution
+		var err error
+		test.CaptureStdout(func() {
+			got, err = resolveAppend(stackFile, c.name, c.choice)
+		})
+		if len(c.wantErr) > 0 {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s with %s: want an error containing %q, got %v", c.name, c.choice, c.wantErr, err)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s with %s: want %+v, got %+v, %v", c.name, c.choice, c.want, got, err)
+		}
+	}
+}
+
+func Test_resolveAppend_Asks(t *testing.T) {
+	stackFile := writeAppendStack(t)
+	defer os.Remove(stackFile)
+
+	originalInteractive, originalInput := newInteractive, newInput
+	defer func() { newInteractive, newInput = originalInteractive, originalInput }()
+	newInteractive = func() bool { return true }
+
+	cases := []struct {
+		input string
+		want  appendResolution
+	}{
+		{input: "r\n\n", want: appendResolution{Name: "reader-2"}},
+		{input: "rename\nwriter\n", want: appendResolution{Name: "writer"}},
+		{input: "o\n", want: appendResolution{Name: "reader", Replace: []string{"reader"}}},
+	}
+
+	for _, c := range cases {
+		newInput = strings.NewReader(c.input)
+		var got appendResolution
+		var err error
+		stdOut := test.CaptureStdout(func() {
+			got, err = resolveAppend(stackFile, "reader", conflictAsk)
+		})
+		if !strings.Contains(stdOut, "[r]ename, [o]verwrite or [a]bort?") {
+			t.Errorf("want the choices to be asked for, got %q", stdOut)
+		}
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%q: want %+v, got %+v, %v", c.input, c.want, got, err)
+		}
+	}
+
+	newInput = strings.NewReader("\n")
+	test.CaptureStdout(func() {
+		if _, err := resolveAppend(stackFile, "reader", conflictAsk); err == nil {
+			t.Errorf("want an empty answer to abort")
+		}
+	})
+}
+
+func Test_removeStackEntries(t *testing.T) {
+	got, err := removeStackEntries(appendStack, []string{"thumbnailer", "reader"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `provider:
+  name: faas
+  gateway: http://127.0.0.1:8080
+
+functions:
+  # resizes images
+  resizer:
+    lang: python
+    handler: ./resizer
+    image: resizer
+`
+	if got != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, got)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+var stackEntryField = regexp.MustCompile(`^(\s+)(handler|image):(\s*)(.*)$`)
+
+// runNewFromFunction copies the handler and stack entry of an existing
+// function under a new name and image
+func runNewFromFunction(name string, sourceName string, stackFile string, newImage string) error {
+	if len(stackFile) == 0 {
+		return fmt.Errorf("please provide the YAML file which defines %s with --yaml", sourceName)
+	}
+
+	services, err := stack.ParseYAMLFile(stackFile, "", "")
+	if err != nil {
+		return err
+	}
+
+	source, ok := services.Functions[sourceName]
+	if !ok {
+		return fmt.Errorf("function %s was not found in %s", sourceName, stackFile)
+	}
+	if _, exists := services.Functions[name]; exists {
+		return fmt.Errorf("function %s already exists in %s", name, stackFile)
+	}
+
+	if len(newImage) == 0 {
+		newImage, err = renamedImage(source.Image, sourceName, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	var handler string
+	if len(source.Handler) > 0 {
+		handler = renamedHandler(source.Handler, name)
+		if _, statErr := os.Stat(handler); statErr == nil {
+			return fmt.Errorf("folder: %s already exists", handler)
+		}
+	}
+
+	stackBytes, err := ioutil.ReadFile(stackFile)
+	if err != nil {
+		return err
+	}
+	updated, err := copyStackEntry(string(stackBytes), sourceName, name, handler, newImage)
+	if err != nil {
+		return err
+	}
+
+	if len(handler) > 0 {
+		if err := builder.CopyFiles(source.Handler, handler); err != nil {
+			return fmt.Errorf("unable to copy the handler of %s: %s", sourceName, err)
+		}
+		fmt.Printf("Folder: %s created from %s.\n", handler, source.Handler)
+	}
+
+	if err := ioutil.WriteFile(stackFile, []byte(updated), 0600); err != nil {
+		return fmt.Errorf("error writing stack file %s", err)
+	}
+
+	fmt.Printf("Function %s created from %s with image %s.\n", name, sourceName, newImage)
+	fmt.Printf("Stack file updated: %s\n", stackFile)
+	return nil
+}
+
+// renamedImage swaps the source function's name for the new name in the
+// image, i.e. alexellis/url-ping:0.1 becomes alexellis/url-pong:0.1
+func renamedImage(image string, sourceName string, name string) (string, error) {
+	repository, tag := image, ""
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		repository, tag = image[:i], image[i:]
+	}
+
+	segment := repository[strings.LastIndex(repository, "/")+1:]
+	if !strings.Contains(segment, sourceName) {
+		return "", fmt.Errorf("the image %s does not contain the name %s, please give the new image with --image", image, sourceName)
+	}
+
+	return repository[:len(repository)-len(segment)] + strings.Replace(segment, sourceName, name, 1) + tag, nil
+}
+
+// renamedHandler places the new handler next to the source's handler
+func renamedHandler(handler string, name string) string {
+	renamed := filepath.Join(filepath.Dir(filepath.Clean(handler)), name)
+	if strings.HasPrefix(handler, "./") && !strings.HasPrefix(renamed, ".") {
+		renamed = "./" + renamed
+	}
+	return renamed
+}
+
+// copyStackEntry inserts a copy of the source function's entry straight after
+// it, keeping the comments and layout of the stack file
+func copyStackEntry(stackYAML string, sourceName string, name string, handler string, image string) (string, error) {
+	lines := strings.Split(stackYAML, "\n")
+	entryKey := stackEntryKey(sourceName)
+
+	start, end, err := stackEntryBounds(lines, sourceName)
+	if err != nil {
+		return "", err
+	}
+
+	entry := make([]string, 0, end-start)
+	entry = append(entry, entryKey.ReplaceAllString(lines[start], "${1}${2}"+name+"${3}:${4}"))
+
+	// Only the entry's own fields are renamed, not nested values
+	fieldIndent := -1
+	for _, line := range lines[start+1 : end] {
+		trimmed := strings.TrimSpace(line)
+		if fieldIndent == -1 && len(trimmed) > 0 && !strings.HasPrefix(trimmed, "#") {
+			fieldIndent = lineIndent(line)
+		}
+
+		match := stackEntryField.FindStringSubmatch(line)
+		if match != nil && len(match[1]) == fieldIndent && !(match[2] == "handler" && len(handler) == 0) {
+			value := image
+			if match[2] == "handler" {
+				value = handler
+			}
+			line = match[1] + match[2] + ":" + match[3] + value
+		}
+		entry = append(entry, line)
+	}
+
+	updated := append([]string{}, lines[:end]...)
+	updated = append(updated, entry...)
+	updated = append(updated, lines[end:]...)
+	return strings.Join(updated, "\n"), nil
+}
+
+func stackEntryKey(name string) *regexp.Regexp {
+	return regexp.MustCompile(`^(\s+)(["']?)` + regexp.QuoteMeta(name) + `(["']?):(\s*(#.*)?)$`)
+}
+
+// stackEntryBounds finds the lines of a function's entry under functions, end
+// is the line after its last field. Blank lines and comments at the end of an
+// entry are left out.
+func stackEntryBounds(lines []string, name string) (int, int, error) {
+	entryKey := stackEntryKey(name)
+
+	start, indent := -1, 0
+	inFunctions := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "functions:") {
+			inFunctions = true
+			continue
+		}
+		if !inFunctions {
+			continue
+		}
+		if match := entryKey.FindStringSubmatch(line); match != nil {
+			start, indent = i, len(match[1])
+			break
+		}
+	}
+	if start == -1 {
+		return -1, -1, fmt.Errorf("unable to find the entry of %s under functions", name)
+	}
+
+	// The entry ends before the first line which is not indented further
+	end := start + 1
+	for i := start + 1; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if lineIndent(lines[i]) <= indent {
+			break
+		}
+		end = i + 1
+	}
+	return start, end, nil
+}
+
+func lineIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
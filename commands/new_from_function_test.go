@@ -0,0 +1,149 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+const fromFunctionStack = `provider:
+  name: faas
+  gateway: http://localhost:8080
+
+functions:
+  # pings a URL
+  url-ping:
+    lang: python
+    handler: ./functions/url-ping
+    image: alexellis/url-ping:0.1
+    environment:
+      image: not-renamed
+
+  figlet:
+    lang: dockerfile
+    handler: ./figlet
+    image: functions/figlet
+`
+
+func Test_copyStackEntry(t *testing.T) {
+	updated, err := copyStackEntry(fromFunctionStack, "url-ping", "url-pong", "./functions/url-pong", "alexellis/url-pong:0.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `provider:
+  name: faas
+  gateway: http://localhost:8080
+
+functions:
+  # pings a URL
+  url-ping:
+    lang: python
+    handler: ./functions/url-ping
+    image: alexellis/url-ping:0.1
+    environment:
+      image: not-renamed
+  url-pong:
+    lang: python
+    handler: ./functions/url-pong
+    image: alexellis/url-pong:0.1
+    environment:
+      image: not-renamed
+
+  figlet:
+    lang: dockerfile
+    handler: ./figlet
+    image: functions/figlet
+`
+	if updated != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, updated)
+	}
+
+	if _, err := copyStackEntry(fromFunctionStack, "missing", "new", "", ""); err == nil {
+		t.Errorf("want an error for a missing function")
+	}
+}
+
+func Test_renamedImage(t *testing.T) {
+	cases := []struct {
+		image   string
+		want    string
+		wantErr bool
+	}{
+		{"alexellis/url-ping:0.1", "alexellis/url-pong:0.1", false},
+		{"registry:5000/team/url-ping", "registry:5000/team/url-pong", false},
+		{"url-ping-fn:latest", "url-pong-fn:latest", false},
+		{"alexellis/pinger:0.1", "", true},
+		{"url-ping/pinger:0.1", "", true},
+	}
+
+	for _, c := range cases {
+		got, err := renamedImage(c.image, "url-ping", "url-pong")
+		if c.wantErr != (err != nil) {
+			t.Errorf("%s: want error %t, got %v", c.image, c.wantErr, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: want %q, got %q", c.image, c.want, got)
+		}
+	}
+}
+
+func Test_renamedHandler(t *testing.T) {
+	cases := map[string]string{
+		"./url-ping":           "./url-pong",
+		"./functions/url-ping": "./functions/url-pong",
+		"functions/url-ping/":  "functions/url-pong",
+	}
+	for handler, want := range cases {
+		if got := renamedHandler(handler, "url-pong"); got != want {
+			t.Errorf("%s: want %q, got %q", handler, want, got)
+		}
+	}
+}
+
+func Test_runNewFromFunction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-from-function")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cwd, _ := os.Getwd()
+	defer os.Chdir(cwd)
+	os.Chdir(dir)
+
+	os.MkdirAll(filepath.Join("functions", "url-ping"), 0700)
+	ioutil.WriteFile(filepath.Join("functions", "url-ping", "handler.py"), []byte("def handle(req):\n    return req\n"), 0600)
+	ioutil.WriteFile("stack.yml", []byte(fromFunctionStack), 0600)
+
+	test.CaptureStdout(func() {
+		err = runNewFromFunction("url-pong", "url-ping", "stack.yml", "")
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join("functions", "url-pong", "handler.py")); err != nil {
+		t.Errorf("want the handler copied: %s", err)
+	}
+
+	services, err := stack.ParseYAMLFile("stack.yml", "", "")
+	if err != nil {
+		t.Fatalf("the stack file is not valid: %s", err)
+	}
+	function := services.Functions["url-pong"]
+	if function.Image != "alexellis/url-pong:0.1" || function.Handler != "./functions/url-pong" || function.Language != "python" {
+		t.Errorf("unexpected entry for url-pong: %+v", function)
+	}
+
+	if err := runNewFromFunction("url-pong", "url-ping", "stack.yml", ""); err == nil {
+		t.Errorf("want an error when the function already exists")
+	}
+}
@@ -17,8 +17,11 @@ import (
 )
 
 var (
-	appendFile string
-	list       bool
+	appendFile   string
+	list         bool
+	fromFunction string
+	newPrefix    string
+	newQuiet     bool
 )
 
 func init() {
@@ -27,20 +30,42 @@ func init() {
 
 	newFunctionCmd.Flags().BoolVar(&list, "list", false, "List available languages")
 	newFunctionCmd.Flags().StringVarP(&appendFile, "append", "a", "", "Append to existing YAML file")
+	newFunctionCmd.Flags().StringVar(&fromFunction, "from-function", "", "Copy the handler and YAML entry of this function from the YAML file given with --yaml")
+	newFunctionCmd.Flags().StringVar(&image, "image", "", "Image for a function created with --from-function, by default the source's image with its name replaced")
+	newFunctionCmd.Flags().StringVarP(&newPrefix, "prefix", "p", os.Getenv("OPENFAAS_PREFIX"), "Registry or Docker Hub account to prefix the image with, i.e. ghcr.io/acme, defaults to OPENFAAS_PREFIX")
+	newFunctionCmd.Flags().BoolVarP(&newQuiet, "quiet", "q", false, "Skip the banner and progress messages")
 
 	faasCmd.AddCommand(newFunctionCmd)
 }
 
 // newFunctionCmd displays newFunction information
 var newFunctionCmd = &cobra.Command{
-	Use:   "new FUNCTION_NAME --lang=FUNCTION_LANGUAGE [--gateway=http://domain:port] | --list | --append=STACK_FILE)",
+	Use:   "new FUNCTION_NAME --lang=FUNCTION_LANGUAGE [--gateway=http://domain:port] [--prefix=REGISTRY] [--quiet] | --list | --append=STACK_FILE | --from-function=FUNCTION_NAME -f STACK_FILE)",
 	Short: "Create a new template in the current folder with the name given as name",
 	Long: `The new command creates a new function based upon hello-world in the given
-language or type in --list for a list of languages available.`,
+language or type in --list for a list of languages available.
+
+The handler folder is copied from the template and the function is written to
+FUNCTION_NAME.yml, or appended to the stack given with --append. --prefix, or
+OPENFAAS_PREFIX, puts the image in a registry or account i.e. ghcr.io/acme,
+and --quiet leaves out the banner and progress messages for scripts.
+
+With --from-function the handler and YAML entry of an existing function are
+copied under the new name, the handler is placed next to the original and the
+image has the original function's name replaced unless --image is given.
+
+When a function given with --append has the name or image of one already in
+the stack, new asks whether to rename the new function, overwrite the existing
+entry and its handler folder, or abort. --on-conflict makes the choice without
+asking, for scripts, and is needed when new is not run in a terminal.`,
 	Example: `faas-cli new chatbot --lang node
   faas-cli new text-parser --lang python --gateway http://mydomain:8080
+  faas-cli new text-parser --lang python --prefix ghcr.io/acme --quiet
   faas-cli new text-reader --lang python --append stack.yml
-  faas-cli new --list`,
+  faas-cli new text-reader --lang python --append stack.yml --on-conflict rename
+  faas-cli new --list
+  faas-cli new url-pong --from-function url-ping -f stack.yml
+  faas-cli new url-pong --from-function url-ping -f stack.yml --image alexellis/pong:0.1`,
 	PreRunE: preRunNewFunction,
 	RunE:    runNewFunction,
 }
@@ -56,7 +81,7 @@ func runNewFunction(cmd *cobra.Command, args []string) error {
 	if list == true {
 		var availableTemplates []string
 
-		templateFolders, err := ioutil.ReadDir(templateDirectory)
+		templateFolders, err := ioutil.ReadDir(stack.TemplateDirectory)
 
 		if err != nil {
 			return fmt.Errorf("no language templates were found. Please run 'faas-cli template pull'")
@@ -79,6 +104,10 @@ func runNewFunction(cmd *cobra.Command, args []string) error {
 
 	functionName = args[0]
 
+	if len(fromFunction) > 0 {
+		return runNewFromFunction(functionName, fromFunction, yamlFile, image)
+	}
+
 	if len(language) == 0 {
 		return fmt.Errorf("you must supply a function language with the --lang flag")
 	}
@@ -89,6 +118,7 @@ func runNewFunction(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("%s is unavailable or not supported", language)
 	}
 
+	resolution := appendResolution{Name: functionName}
 	appendMode := len(appendFile) > 0
 	if appendMode {
 		if (strings.HasSuffix(appendFile, ".yml") || strings.HasSuffix(appendFile, ".yaml")) == false {
@@ -98,14 +128,26 @@ func runNewFunction(cmd *cobra.Command, args []string) error {
 		if _, statErr := os.Stat(appendFile); statErr != nil {
 			return fmt.Errorf("unable to find file: %s - %s", appendFile, statErr.Error())
 		}
+
+		var err error
+		if resolution, err = resolveAppend(appendFile, functionName, onConflict); err != nil {
+			return err
+		}
+		functionName = resolution.Name
 	}
 
 	if _, err := os.Stat(functionName); err == nil {
-		return fmt.Errorf("folder: %s already exists", functionName)
+		// Overwriting a function replaces its handler with the template's
+		if !contains(resolution.Replace, functionName) {
+			return fmt.Errorf("folder: %s already exists", functionName)
+		}
+		if err := os.RemoveAll(functionName); err != nil {
+			return fmt.Errorf("folder: could not remove %s : %s", functionName, err)
+		}
 	}
 
 	if err := os.Mkdir(functionName, 0700); err == nil {
-		fmt.Printf("Folder: %s created.\n", functionName)
+		newPrintf("Folder: %s created.\n", functionName)
 	} else {
 		return fmt.Errorf("folder: could not create %s : %s", functionName, err)
 	}
@@ -114,7 +156,7 @@ func runNewFunction(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("got unexpected error while updating .gitignore file: %s", err)
 	}
 
-	builder.CopyFiles(filepath.Join("template", language, "function"), functionName)
+	builder.CopyFiles(filepath.Join(stack.TemplateDirectory, language, "function"), functionName)
 
 	var stackYaml string
 
@@ -132,12 +174,14 @@ functions:
 		`  ` + functionName + `:
     lang: ` + language + `
     handler: ./` + functionName + `
-    image: ` + functionName + `
+    image: ` + newFunctionImage(functionName) + `
 `
 
-	printFiglet()
-	fmt.Println()
-	fmt.Printf("Function created in folder: %s\n", functionName)
+	if !newQuiet {
+		printFiglet()
+		fmt.Println()
+	}
+	newPrintf("Function created in folder: %s\n", functionName)
 
 	var stackWriteErr error
 
@@ -146,14 +190,18 @@ functions:
 		if readErr != nil {
 			fmt.Printf("unable to read %s to append, %s", appendFile, readErr)
 		}
-		buffer := string(originalBytes) + stackYaml
+		original, removeErr := removeStackEntries(string(originalBytes), resolution.Replace)
+		if removeErr != nil {
+			return removeErr
+		}
+		buffer := original + stackYaml
 
 		stackWriteErr = ioutil.WriteFile(appendFile, []byte(buffer), 0600)
 		if stackWriteErr != nil {
 			return fmt.Errorf("error writing stack file %s", stackWriteErr)
 		}
 
-		fmt.Printf("Stack file updated: %s\n", appendFile)
+		newPrintf("Stack file updated: %s\n", appendFile)
 	} else {
 
 		stackWriteErr = ioutil.WriteFile("./"+functionName+".yml", []byte(stackYaml), 0600)
@@ -161,12 +209,29 @@ functions:
 			return fmt.Errorf("error writing stack file %s", stackWriteErr)
 		}
 
-		fmt.Printf("Stack file written: %s\n", functionName+".yml")
+		newPrintf("Stack file written: %s\n", functionName+".yml")
 	}
 
 	return nil
 }
 
+// newFunctionImage gives the image of a new function, its name in the
+// registry or account given with --prefix
+func newFunctionImage(name string) string {
+	prefix := strings.TrimRight(newPrefix, "/")
+	if len(prefix) == 0 {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// newPrintf prints the progress of new unless --quiet is given
+func newPrintf(format string, a ...interface{}) {
+	if !newQuiet {
+		fmt.Printf(format, a...)
+	}
+}
+
 func printAvailableTemplates(availableTemplates []string) string {
 	var result string
 	sort.Sort(StrSort(availableTemplates))
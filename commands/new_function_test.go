@@ -139,6 +139,35 @@ func Test_newFunctionTests(t *testing.T) {
 	}
 }
 
+func Test_newFunction_PrefixQuiet(t *testing.T) {
+	templatePullLocalTemplateRepo(t)
+	defer tearDownFetchTemplates(t)
+	defer func() {
+		newPrefix = ""
+		newQuiet = false
+		os.RemoveAll("prefixed")
+		os.Remove("prefixed.yml")
+	}()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"new", "prefixed", "--lang=ruby", "--prefix=ghcr.io/acme/", "--quiet"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+	if len(stdOut) > 0 {
+		t.Errorf("want no output with --quiet, got:\n%s", stdOut)
+	}
+
+	services, err := stack.ParseYAMLFile("prefixed.yml", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := services.Functions["prefixed"].Image; image != "ghcr.io/acme/prefixed" {
+		t.Errorf("want the image prefixed, got %s", image)
+	}
+}
+
 func Test_newFunctionListCmds(t *testing.T) {
 	// Download templates
 	templatePullLocalTemplateRepo(t)
@@ -0,0 +1,350 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// patchDirectory keeps the last patch of each function, it is moved into the
+// state directory before a command runs
+var patchDirectory = filepath.Join(".faas-cli", "patches")
+
+// patchLanguages are the prefixes of the templates whose handler is copied
+// into the image as source, so that changed files can be layered on top
+var patchLanguages = []string{"node", "python"}
+
+// patchDependencyFiles are installed from while building, so changing them
+// needs a build
+var patchDependencyFiles = []string{"package.json", "package-lock.json", "yarn.lock", "requirements.txt"}
+
+var (
+	patchHandlerPath string
+	patchSkipPush    bool
+	patchSkipDeploy  bool
+)
+
+// buildPatchImage is swapped out in tests
+var buildPatchImage = func(contextDir string, image string) {
+	builder.ExecCommand(contextDir, []string{"docker", "build", "-t", image, "."})
+}
+
+// patchRecord is the image made by a function's last patch and the handler
+// files it holds, it only applies while the build it was based on is the last
+type patchRecord struct {
+	Function   string             `json:"function"`
+	Image      string             `json:"image"`
+	BaseDigest string             `json:"base_digest"`
+	Files      []builder.FileHash `json:"files"`
+}
+
+func init() {
+	patchCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	patchCmd.Flags().StringVar(&patchHandlerPath, "handler-path", "/home/app/function", "Folder in the image which the template copies the handler to")
+	patchCmd.Flags().BoolVar(&patchSkipPush, "skip-push", false, "Skip pushing the patched image, i.e. for a local cluster which shares the Docker daemon")
+	patchCmd.Flags().BoolVar(&patchSkipDeploy, "skip-deploy", false, "Skip deploying the patched image")
+
+	faasCmd.AddCommand(patchCmd)
+}
+
+var patchCmd = &cobra.Command{
+	Use:   `patch FUNCTION_NAME -f YAML_FILE [--handler-path PATH] [--skip-push] [--skip-deploy]`,
+	Short: "Layer changed handler files onto the last built image [experimental]",
+	Long: `Experimental: for node and python functions, copies only the handler files
+which changed since the last build onto the last built image as one new layer,
+then tags, pushes and deploys it, instead of building the template again. It is
+meant for quick fixes in development environments.
+
+The image is tagged with the tag of the function's image and -patch- followed
+by a hash of the changes, so that the provider pulls it. Patches build on each
+other until the function is built again.
+
+A full build is needed when the template changed, when handler files were
+removed, or when the dependencies of the handler, such as package.json or
+requirements.txt, changed, as they are installed while building.`,
+	Example: `  faas-cli patch url-ping -f stack.yml
+  faas-cli patch url-ping -f stack.yml --skip-push
+  faas-cli patch api -f stack.yml --handler-path /home/app/function`,
+	RunE: runPatch,
+}
+
+func runPatch(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide the name of the function to patch")
+	}
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the function with -f")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+	name := args[0]
+	function, ok := services.Functions[name]
+	if !ok {
+		return fmt.Errorf("function %s was not found in %s", name, yamlFile)
+	}
+	function.Name = name
+	if err := checkPatchable(function); err != nil {
+		return err
+	}
+
+	started := time.Now()
+	patch, err := preparePatch(function)
+	if err != nil {
+		return err
+	}
+	if len(patch.changed) == 0 {
+		fmt.Printf("No handler files of %s changed since it was last built or patched.\n", name)
+		return nil
+	}
+
+	contextDir, err := writePatchContext(function.Handler, patch.base, patch.changed, patchHandlerPath)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(contextDir)
+
+	fmt.Printf("Patching %s with %s onto %s\n", name, strings.Join(patch.changed, ", "), patch.base)
+	buildPatchImage(contextDir, patch.image)
+
+	if !patchSkipPush {
+		environments, err := registryEnvironments(*services)
+		if err != nil {
+			return err
+		}
+		fmt.Println(pushWithSummary(name, patch.image, functionRegistryEnvironment(*services, function, environments)))
+	}
+
+	if err := savePatchRecord(patch.record); err != nil {
+		fmt.Printf("Unable to record the patch of %s: %s\n", name, err)
+	}
+
+	if !patchSkipDeploy {
+		function.Image = patch.image
+		services.Functions = map[string]stack.Function{name: function}
+		services.Provider.GatewayURL = getGatewayURL(gateway, defaultGateway, services.Provider.GatewayURL)
+
+		changePolicy, err := loadPolicy(services)
+		if err != nil {
+			return err
+		}
+		if err := deployStack(services, DeployFlags{update: true}, changePolicy); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Patched %s as %s in %s.\n", name, patch.image, time.Since(started).Round(100*time.Millisecond))
+	return nil
+}
+
+// checkPatchable only allows functions whose handler is copied into the image
+// as it is
+func checkPatchable(function stack.Function) error {
+	if len(function.Image) == 0 {
+		return fmt.Errorf("function %s has no image to patch", function.Name)
+	}
+	if len(function.Platforms) > 0 {
+		return fmt.Errorf("function %s is built for several platforms, which patch doesn't support", function.Name)
+	}
+	for _, prefix := range patchLanguages {
+		if strings.HasPrefix(strings.ToLower(function.Language), prefix) {
+			return nil
+		}
+	}
+	return fmt.Errorf("patch supports the %s templates, not %s, build the function instead", strings.Join(patchLanguages, " and "), function.Language)
+}
+
+// functionPatch is a patch to build, changed lists the handler files to copy,
+// relative to the handler
+type functionPatch struct {
+	base    string
+	image   string
+	changed []string
+	record  patchRecord
+}
+
+// preparePatch compares the handler with the last build of the function, or
+// with its last patch since that build, and names the image of the patch
+func preparePatch(function stack.Function) (functionPatch, error) {
+	last, err := builder.LastFingerprint(function.Name)
+	if err != nil {
+		return functionPatch{}, err
+	}
+	if last == nil {
+		return functionPatch{}, fmt.Errorf("%s has not been built from this folder, build it before patching", function.Name)
+	}
+
+	current, err := builder.NewFingerprint(function.Handler, function.Name, function.Language, nil, "")
+	if err != nil {
+		return functionPatch{}, fmt.Errorf("unable to hash the handler of %s: %s", function.Name, err)
+	}
+	if current.TemplateDigest != last.TemplateDigest {
+		return functionPatch{}, fmt.Errorf("the %s template changed since %s was last built, build it instead", function.Language, function.Name)
+	}
+
+	base, previous := function.Image, last.Files
+	if record, err := readPatchRecord(function.Name); err != nil {
+		return functionPatch{}, err
+	} else if record != nil && record.BaseDigest == last.Digest {
+		base, previous = record.Image, record.Files
+	}
+
+	changed, err := changedHandlerFiles(current.Files, previous)
+	if err != nil {
+		return functionPatch{}, fmt.Errorf("%s, build %s instead", err, function.Name)
+	}
+	for _, name := range changed {
+		if contains(patchDependencyFiles, path.Base(name)) {
+			return functionPatch{}, fmt.Errorf("%s changed and its dependencies are installed while building, build %s instead", name, function.Name)
+		}
+	}
+
+	patch := functionPatch{
+		base:    base,
+		changed: changed,
+		record: patchRecord{
+			Function:   function.Name,
+			BaseDigest: last.Digest,
+			Files:      current.Files,
+		},
+	}
+	if len(changed) > 0 {
+		repository, tag := builder.SplitImageTag(function.Image)
+		patch.image = repository + ":" + tag + "-patch-" + patchDigest(current.Files, changed)
+		patch.record.Image = patch.image
+	}
+	return patch, nil
+}
+
+// changedHandlerFiles lists the handler files, under function/ in the
+// fingerprint, which were added or changed since previous
+func changedHandlerFiles(files []builder.FileHash, previous []builder.FileHash) ([]string, error) {
+	const handlerPrefix = "function/"
+
+	current := map[string]string{}
+	for _, file := range files {
+		current[file.Path] = file.SHA256
+	}
+
+	var changed []string
+	for _, file := range files {
+		if !strings.HasPrefix(file.Path, handlerPrefix) {
+			continue
+		}
+		found := false
+		for _, before := range previous {
+			if before.Path == file.Path {
+				found = before.SHA256 == file.SHA256
+				break
+			}
+		}
+		if !found {
+			changed = append(changed, strings.TrimPrefix(file.Path, handlerPrefix))
+		}
+	}
+
+	for _, before := range previous {
+		if _, ok := current[before.Path]; !ok && strings.HasPrefix(before.Path, handlerPrefix) {
+			return nil, fmt.Errorf("%s was removed, which a patch can't do", strings.TrimPrefix(before.Path, handlerPrefix))
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// patchDigest hashes the changed files so that the same patch gives the
+// same tag
+func patchDigest(files []builder.FileHash, changed []string) string {
+	hash := sha256.New()
+	for _, file := range files {
+		for _, name := range changed {
+			if file.Path == "function/"+name {
+				fmt.Fprintf(hash, "%s %s\n", file.SHA256, file.Path)
+			}
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:8]
+}
+
+// writePatchContext writes a build context whose Dockerfile copies the
+// changed files of the handler onto base
+func writePatchContext(handler string, base string, changed []string, handlerPath string) (string, error) {
+	contextDir, err := ioutil.TempDir("", "faas-cli-patch")
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range changed {
+		data, err := ioutil.ReadFile(filepath.Join(handler, filepath.FromSlash(name)))
+		if err != nil {
+			os.RemoveAll(contextDir)
+			return "", err
+		}
+		target := filepath.Join(contextDir, "patch", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			os.RemoveAll(contextDir)
+			return "", err
+		}
+		if err := ioutil.WriteFile(target, data, 0644); err != nil {
+			os.RemoveAll(contextDir)
+			return "", err
+		}
+	}
+
+	dockerfile := fmt.Sprintf("FROM %s\nCOPY patch/ %s/\n", base, path.Clean(handlerPath))
+	if err := ioutil.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte(dockerfile), 0644); err != nil {
+		os.RemoveAll(contextDir)
+		return "", err
+	}
+	return contextDir, nil
+}
+
+func patchRecordPath(functionName string) string {
+	return filepath.Join(patchDirectory, functionName+".json")
+}
+
+// readPatchRecord reads the function's last patch, it is nil when the
+// function has not been patched
+func readPatchRecord(functionName string) (*patchRecord, error) {
+	data, err := ioutil.ReadFile(patchRecordPath(functionName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var record patchRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("unable to read the last patch of %s: %s", functionName, err)
+	}
+	return &record, nil
+}
+
+func savePatchRecord(record patchRecord) error {
+	if err := os.MkdirAll(patchDirectory, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(patchRecordPath(record.Function), data, 0600)
+}
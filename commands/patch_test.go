@@ -0,0 +1,180 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+// patchFixture builds a node function from a fake template in a temporary
+// folder and returns the folder and a func to undo it
+func patchFixture(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "faas-cli-patch")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	originalFingerprints, originalPatches, originalTemplates := builder.FingerprintDirectory, patchDirectory, stack.TemplateDirectory
+	builder.FingerprintDirectory = filepath.Join(dir, "fingerprints")
+	patchDirectory = filepath.Join(dir, "patches")
+	stack.TemplateDirectory = filepath.Join(dir, "template")
+
+	os.MkdirAll(filepath.Join(stack.TemplateDirectory, "node18"), 0700)
+	ioutil.WriteFile(filepath.Join(stack.TemplateDirectory, "node18", "Dockerfile"), []byte("FROM node:18"), 0600)
+	ioutil.WriteFile(filepath.Join(stack.TemplateDirectory, "node18", "template.yml"), []byte("language: node18\nfprocess: node index.js\n"), 0600)
+
+	handler := filepath.Join(dir, "api")
+	os.MkdirAll(filepath.Join(handler, "lib"), 0700)
+	ioutil.WriteFile(filepath.Join(handler, "handler.js"), []byte("module.exports = 1"), 0600)
+	ioutil.WriteFile(filepath.Join(handler, "lib", "util.js"), []byte("module.exports = 2"), 0600)
+	ioutil.WriteFile(filepath.Join(handler, "package.json"), []byte("{}"), 0600)
+	recordFingerprint(handler, "api", "node18", nil, "")
+
+	return dir, func() {
+		builder.FingerprintDirectory, patchDirectory, stack.TemplateDirectory = originalFingerprints, originalPatches, originalTemplates
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_preparePatch(t *testing.T) {
+	dir, undo := patchFixture(t)
+	defer undo()
+
+	function := stack.Function{Name: "api", Language: "node18", Handler: filepath.Join(dir, "api"), Image: "acme/api:0.1"}
+
+	patch, err := preparePatch(function)
+	if err != nil || len(patch.changed) != 0 {
+		t.Fatalf("want nothing to patch after a build, got %v %v", patch.changed, err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "api", "lib", "util.js"), []byte("module.exports = 3"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "api", "new.js"), []byte("module.exports = 4"), 0600)
+
+	patch, err = preparePatch(function)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := []string{"lib/util.js", "new.js"}; !reflect.DeepEqual(patch.changed, want) {
+		t.Errorf("want %v, got %v", want, patch.changed)
+	}
+	if patch.base != "acme/api:0.1" || !strings.HasPrefix(patch.image, "acme/api:0.1-patch-") {
+		t.Errorf("want a patch of acme/api:0.1, got %s onto %s", patch.image, patch.base)
+	}
+
+	// A second patch builds on the first and only copies what changed since
+	if err := savePatchRecord(patch.record); err != nil {
+		t.Fatal(err)
+	}
+	ioutil.WriteFile(filepath.Join(dir, "api", "handler.js"), []byte("module.exports = 5"), 0600)
+	second, err := preparePatch(function)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if second.base != patch.image || !reflect.DeepEqual(second.changed, []string{"handler.js"}) {
+		t.Errorf("want handler.js patched onto %s, got %v onto %s", patch.image, second.changed, second.base)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "api", "package.json"), []byte(`{"dependencies": {}}`), 0600)
+	if _, err := preparePatch(function); err == nil || !strings.Contains(err.Error(), "package.json changed") {
+		t.Errorf("want an error for a changed package.json, got %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "api", "package.json"))
+	os.Remove(filepath.Join(dir, "api", "new.js"))
+	if _, err := preparePatch(function); err == nil || !strings.Contains(err.Error(), "new.js was removed") {
+		t.Errorf("want an error for a removed file, got %v", err)
+	}
+}
+
+func Test_checkPatchable(t *testing.T) {
+	if err := checkPatchable(stack.Function{Name: "api", Language: "python3-http", Image: "acme/api"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := checkPatchable(stack.Function{Name: "api", Language: "golang-middleware", Image: "acme/api"}); err == nil {
+		t.Errorf("want an error for a compiled language")
+	}
+	if err := checkPatchable(stack.Function{Name: "api", Language: "node18", Image: "acme/api", Platforms: []string{"linux/arm64"}}); err == nil {
+		t.Errorf("want an error for a multi-arch function")
+	}
+}
+
+func Test_patch_BuildsPushesAndDeploys(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	dir, undo := patchFixture(t)
+	defer undo()
+
+	var calls []string
+	originalBuild, originalPush, originalSizes := buildPatchImage, dockerPush, imageLayerSizes
+	defer func() { buildPatchImage, dockerPush, imageLayerSizes = originalBuild, originalPush, originalSizes }()
+	buildPatchImage = func(contextDir string, image string) {
+		dockerfile, _ := ioutil.ReadFile(filepath.Join(contextDir, "Dockerfile"))
+		_, err := os.Stat(filepath.Join(contextDir, "patch", "handler.js"))
+		calls = append(calls, fmt.Sprintf("build %s %q %v", image, dockerfile, err == nil))
+	}
+	dockerPush = func(image string, env []string) string {
+		calls = append(calls, "push "+image)
+		return ""
+	}
+	imageLayerSizes = func(image string, env []string) (map[string]int64, error) { return nil, fmt.Errorf("no sizes") }
+
+	yamlPath := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(yamlPath, []byte(fmt.Sprintf(`provider:
+  name: faas
+functions:
+  api:
+    lang: node18
+    handler: %s
+    image: acme/api:0.1
+`, filepath.Join(dir, "api"))), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "api", "handler.js"), []byte("module.exports = 3"), 0600)
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	// runPatch is called directly as faas-cli would move the fingerprints
+	// into the state directory
+	yamlFile, gateway = yamlPath, s.URL
+	defer func() { gateway = defaultGateway }()
+	stdOut := test.CaptureStdout(func() {
+		if err := runPatch(patchCmd, []string{"api"}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	record, err := readPatchRecord("api")
+	if err != nil || record == nil {
+		t.Fatalf("want the patch recorded, got %v", err)
+	}
+	want := []string{
+		fmt.Sprintf("build %s %q true", record.Image, "FROM acme/api:0.1\nCOPY patch/ /home/app/function/\n"),
+		"push " + record.Image,
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("want %v, got %v", want, calls)
+	}
+	for _, line := range []string{"Patching api with handler.js onto acme/api:0.1", "Deploying: api.", "Patched api as " + record.Image} {
+		if !strings.Contains(stdOut, line) {
+			t.Errorf("want %q in:\n%s", line, stdOut)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pipelineInput           string
+	pipelineOutput          string
+	pipelineIntermediateDir string
+)
+
+func init() {
+	pipelineRunCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	pipelineRunCmd.Flags().StringVar(&pipelineInput, "input", "", "File given to the first step, standard input is read when not given")
+	pipelineRunCmd.Flags().StringVar(&pipelineOutput, "output", "", "File the response of the last step is written to, instead of standard output")
+	pipelineRunCmd.Flags().StringVar(&pipelineIntermediateDir, "intermediate-dir", "", "Folder the response of each step is written to, i.e. 01-fetch.out")
+
+	pipelineCmd.AddCommand(pipelineRunCmd)
+	pipelineCmd.AddCommand(pipelineListCmd)
+	faasCmd.AddCommand(pipelineCmd)
+}
+
+var pipelineCmd = &cobra.Command{
+	Use:   `pipeline`,
+	Short: "Run the pipelines of functions defined in a stack file",
+	Long: `Runs the pipelines in the "pipelines" section of a stack file. Each step
+invokes a function with the response of the step before it, from faas-cli, so
+that a chain of functions can be tried before it is wired up in a workflow
+engine.`,
+}
+
+var pipelineRunCmd = &cobra.Command{
+	Use:   `run PIPELINE_NAME -f YAML_FILE [--input FILE] [--output FILE] [--intermediate-dir DIR]`,
+	Short: "Invoke each step of a pipeline with the response of the one before",
+	Example: `  faas-cli pipeline run thumbnail -f stack.yml --input ./photo.jpg --output ./thumb.png
+  echo https://example.com | faas-cli pipeline run crawl --intermediate-dir ./steps`,
+	RunE: runPipeline,
+}
+
+var pipelineListCmd = &cobra.Command{
+	Use:     `list -f YAML_FILE`,
+	Aliases: []string{"ls"},
+	Short:   "List the pipelines in a stack file and their steps",
+	RunE:    runPipelineList,
+}
+
+func runPipeline(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the pipeline to run")
+	}
+
+	services, err := parsePipelines()
+	if err != nil {
+		return err
+	}
+	pipeline, ok := services.Pipelines[args[0]]
+	if !ok {
+		return fmt.Errorf("pipeline %s was not found in %s", args[0], yamlFile)
+	}
+
+	input, err := readPipelineInput(pipelineInput)
+	if err != nil {
+		return err
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, services.Provider.GatewayURL)
+	output, err := runPipelineSteps(os.Stderr, gatewayAddress, services, pipeline, input, pipelineIntermediateDir)
+	if err != nil {
+		return fmt.Errorf("pipeline %s: %s", args[0], err)
+	}
+
+	if len(pipelineOutput) > 0 {
+		return ioutil.WriteFile(pipelineOutput, output, 0600)
+	}
+	_, err = os.Stdout.Write(output)
+	return err
+}
+
+func runPipelineList(cmd *cobra.Command, args []string) error {
+	services, err := parsePipelines()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(services.Pipelines))
+	for name := range services.Pipelines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%s:", name)
+		for i, step := range services.Pipelines[name].Steps {
+			if i > 0 {
+				fmt.Print(" ->")
+			}
+			fmt.Printf(" %s", step.Function)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// parsePipelines reads the whole stack, the steps of a pipeline may invoke
+// functions which --regex or --filter would leave out
+func parsePipelines() (*stack.Services, error) {
+	if len(yamlFile) == 0 {
+		return nil, fmt.Errorf("please provide the YAML file which defines the pipeline with -f")
+	}
+	services, err := stack.ParseYAMLFile(yamlFile, "", "")
+	if err != nil {
+		return nil, err
+	}
+	if len(services.Pipelines) == 0 {
+		return nil, fmt.Errorf("no pipelines are defined in %s", yamlFile)
+	}
+	return services, nil
+}
+
+func readPipelineInput(path string) ([]byte, error) {
+	if len(path) > 0 {
+		return ioutil.ReadFile(path)
+	}
+
+	stat, _ := os.Stdin.Stat()
+	if (stat.Mode() & os.ModeCharDevice) != 0 {
+		fmt.Fprintf(os.Stderr, "Reading from STDIN - hit (Control + D) to stop.\n")
+	}
+	input, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read standard input: %s", err)
+	}
+	return input, nil
+}
+
+// runPipelineSteps invokes each step with the response of the one before it
+// and logs the size and duration of each to w. The response of each step is
+// also written to intermediateDir when it is given.
+func runPipelineSteps(w io.Writer, gatewayAddress string, services *stack.Services, pipeline stack.Pipeline, input []byte, intermediateDir string) ([]byte, error) {
+	if len(intermediateDir) > 0 {
+		if err := os.MkdirAll(intermediateDir, 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	body := input
+	for i, step := range pipeline.Steps {
+		auth, err := invokeAuth(services.Functions[step.Function].Auth, stack.FunctionAuth{})
+		if err != nil {
+			return nil, fmt.Errorf("step %d %s: %s", i+1, step.Function, err)
+		}
+
+		contentType := step.ContentType
+		if len(contentType) == 0 {
+			contentType = "text/plain"
+		}
+
+		requestSize := len(body)
+		started := time.Now()
+		response, err := proxy.InvokeFunctionWithAuth(gatewayAddress, step.Function, &body, contentType, step.Query, auth)
+		recordInvoke(gatewayAddress, step.Function, err)
+		if err != nil {
+			fmt.Fprintf(w, "[%d/%d] %s failed after %s\n", i+1, len(pipeline.Steps), step.Function, time.Since(started).Round(time.Millisecond))
+			return nil, fmt.Errorf("step %d %s: %s", i+1, step.Function, err)
+		}
+
+		body = []byte{}
+		if response != nil {
+			body = *response
+		}
+		fmt.Fprintf(w, "[%d/%d] %s: %d bytes in, %d bytes out in %s\n", i+1, len(pipeline.Steps), step.Function,
+			requestSize, len(body), time.Since(started).Round(time.Millisecond))
+
+		if len(intermediateDir) > 0 {
+			path := filepath.Join(intermediateDir, fmt.Sprintf("%02d-%s.out", i+1, step.Function))
+			if err := ioutil.WriteFile(path, body, 0600); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return body, nil
+}
@@ -0,0 +1,72 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_runPipelineSteps(t *testing.T) {
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Path == "/function/crop" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write(append(body, []byte("|"+strings.TrimPrefix(r.URL.Path, "/function/"))...))
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "faas-cli-pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	services := &stack.Services{Functions: map[string]stack.Function{"fetch": {}, "resize": {}, "crop": {}}}
+	pipeline := stack.Pipeline{Steps: []stack.PipelineStep{
+		{Function: "fetch"},
+		{Function: "resize", Query: []string{"width=200"}},
+	}}
+
+	var log bytes.Buffer
+	output, err := runPipelineSteps(&log, s.URL, services, pipeline, []byte("photo"), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(output) != "photo|fetch|resize" {
+		t.Errorf("want each step given the response of the one before, got %q", output)
+	}
+	if queries[1] != "width=200" {
+		t.Errorf("want the step's query sent, got %q", queries[1])
+	}
+	if !strings.Contains(log.String(), "[1/2] fetch: 5 bytes in, 11 bytes out") || !strings.Contains(log.String(), "[2/2] resize: 11 bytes in, 18 bytes out") {
+		t.Errorf("want each step logged, got:\n%s", log.String())
+	}
+
+	intermediate, err := ioutil.ReadFile(filepath.Join(dir, "01-fetch.out"))
+	if err != nil || string(intermediate) != "photo|fetch" {
+		t.Errorf("want the response of the first step kept, got %q %v", intermediate, err)
+	}
+
+	pipeline.Steps = append(pipeline.Steps, stack.PipelineStep{Function: "crop"}, stack.PipelineStep{Function: "fetch"})
+	log.Reset()
+	if _, err := runPipelineSteps(&log, s.URL, services, pipeline, []byte("photo"), ""); err == nil || !strings.HasPrefix(err.Error(), "step 3 crop:") {
+		t.Errorf("want the pipeline stopped at the failing step, got %v", err)
+	}
+	if !strings.Contains(log.String(), "[3/4] crop failed") || strings.Contains(log.String(), "[4/4]") {
+		t.Errorf("want the failing step logged and no step run after it, got:\n%s", log.String())
+	}
+}
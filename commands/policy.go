@@ -0,0 +1,111 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/policy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// overridePolicy is the reason given by remove and promote for changing a
+// function during a freeze window
+var overridePolicy string
+
+// policyNow is swapped out in tests to land inside or outside a freeze window
+var policyNow = time.Now
+
+// enforcePolicy refuses to change a protected function during a freeze window
+// unless an override reason is given. When the freeze is overridden the reason
+// is returned as an annotation so it is recorded against the function.
+func enforcePolicy(changePolicy *policy.Policy, functionName string, overrideReason string) (map[string]string, error) {
+	annotations := map[string]string{}
+
+	err := changePolicy.Check(functionName, policyNow())
+	if err == nil {
+		return annotations, nil
+	}
+
+	if len(overrideReason) == 0 {
+		return nil, fmt.Errorf("%s, pass --override-policy REASON to continue", err)
+	}
+
+	fmt.Printf("Overriding policy for %s: %s\n", functionName, overrideReason)
+	annotations[policy.OverrideAnnotation] = overrideReason
+	return annotations, nil
+}
+
+// loadPolicy reads the policy file from the current folder, falling back to
+// the policy of the stack, which may be nil
+func loadPolicy(services *stack.Services) (*policy.Policy, error) {
+	changePolicy, err := policy.Load(policy.DefaultPolicyFile)
+	if err != nil || changePolicy != nil {
+		return changePolicy, err
+	}
+
+	if services != nil {
+		return services.Policy, nil
+	}
+	return nil, nil
+}
+
+// enforceEnvironmentPolicy checks the resolved environment of every function
+// before any is deployed, and fails with a report of each violation
+func enforceEnvironmentPolicy(changePolicy *policy.Policy, workloads []policy.Workload) error {
+	var report []string
+	for _, workload := range workloads {
+		for _, violation := range changePolicy.CheckEnvironment(workload) {
+			report = append(report, fmt.Sprintf("  %s: %s", workload.Name, violation))
+		}
+	}
+
+	if len(report) == 0 {
+		return nil
+	}
+	return fmt.Errorf("the environment breaks the policy, nothing was deployed:\n%s", strings.Join(report, "\n"))
+}
+
+// stackWorkloads resolves the environment and labels of the functions as
+// deploy does, for checking them against the policy
+func stackWorkloads(services *stack.Services, names []string, deployFlags DeployFlags) ([]policy.Workload, error) {
+	labelArgumentMap, err := parseMap(deployFlags.labelOpts, "label")
+	if err != nil {
+		return nil, fmt.Errorf("error parsing labels: %v", err)
+	}
+
+	var workloads []policy.Workload
+	for _, name := range names {
+		function := services.Functions[name]
+
+		fileEnvironment, err := readFiles(function.EnvironmentFile)
+		if err != nil {
+			return nil, err
+		}
+		environment, err := compileEnvironment(deployFlags.envvarOpts, functionEnvironment(function), fileEnvironment)
+		if err != nil {
+			return nil, err
+		}
+
+		labels := map[string]string{}
+		if function.Labels != nil {
+			labels = *function.Labels
+		}
+
+		group := ""
+		if function.Build != nil {
+			group = function.Build.Group
+		}
+
+		workloads = append(workloads, policy.Workload{
+			Name:        name,
+			Labels:      mergeMap(labels, labelArgumentMap),
+			Group:       group,
+			Environment: environment,
+		})
+	}
+	return workloads, nil
+}
@@ -0,0 +1,126 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/policy"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_enforcePolicy(t *testing.T) {
+	changePolicy, err := policy.Parse([]byte(`freeze_windows: [{name: friday, cron: "* * * * 5", timezone: UTC}]
+protected_functions: ["payments-*"]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	defer func() { policyNow = time.Now }()
+	policyNow = func() time.Time {
+		return time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	}
+
+	t.Run("refuses a protected function", func(t *testing.T) {
+		_, err := enforcePolicy(changePolicy, "payments-api", "")
+		if err == nil || !strings.Contains(err.Error(), "--override-policy") {
+			t.Fatalf("want error asking for --override-policy, got %v", err)
+		}
+	})
+
+	t.Run("allows an unprotected function", func(t *testing.T) {
+		annotations, err := enforcePolicy(changePolicy, "url-ping", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if len(annotations) != 0 {
+			t.Fatalf("want no annotations, got %v", annotations)
+		}
+	})
+
+	t.Run("records the override reason", func(t *testing.T) {
+		annotations, err := enforcePolicy(changePolicy, "payments-api", "hotfix INC-1")
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if annotations[policy.OverrideAnnotation] != "hotfix INC-1" {
+			t.Fatalf("want override reason in annotations, got %v", annotations)
+		}
+	})
+
+	t.Run("allows everything without a policy", func(t *testing.T) {
+		if _, err := enforcePolicy(nil, "payments-api", ""); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
+
+func Test_loadPolicy_FallsBackToStack(t *testing.T) {
+	services, err := stack.ParseYAMLData([]byte(`provider:
+  name: faas
+policy:
+  freeze_windows: [{name: friday, cron: "* * * * 5", timezone: UTC}]
+`), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changePolicy, err := loadPolicy(services)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if changePolicy == nil || changePolicy.FreezeWindows[0].Name != "friday" {
+		t.Fatalf("want the stack's policy, got: %+v", changePolicy)
+	}
+
+	if changePolicy, err := loadPolicy(nil); err != nil || changePolicy != nil {
+		t.Fatalf("want no policy, got: %+v %v", changePolicy, err)
+	}
+}
+
+func Test_enforceEnvironmentPolicy(t *testing.T) {
+	changePolicy, err := policy.Parse([]byte(`environment:
+  - name: prod-logging
+    labels: {env: prod}
+    required: [LOG_LEVEL]
+  - name: no-debug
+    forbidden: ["DEBUG=true"]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	prodLabels := map[string]string{"env": "prod"}
+	services := &stack.Services{Functions: map[string]stack.Function{
+		"payments": {Labels: &prodLabels, Environment: map[string]string{"DEBUG": "true"}},
+		"reports":  {Environment: map[string]string{"DEBUG": "false"}},
+	}}
+
+	workloads, err := stackWorkloads(services, []string{"payments", "reports"}, DeployFlags{})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = enforceEnvironmentPolicy(changePolicy, workloads)
+	if err == nil {
+		t.Fatalf("want an error for payments")
+	}
+	for _, want := range []string{"payments: LOG_LEVEL is required by prod-logging", "payments: DEBUG=true is forbidden by no-debug"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in the report, got:\n%s", want, err)
+		}
+	}
+	if strings.Contains(err.Error(), "reports") {
+		t.Errorf("want reports to pass, got:\n%s", err)
+	}
+
+	// Flags are part of the resolved environment
+	workloads, err = stackWorkloads(services, []string{"payments"}, DeployFlags{envvarOpts: []string{"LOG_LEVEL=info", "DEBUG=false"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := enforceEnvironmentPolicy(changePolicy, workloads); err != nil {
+		t.Errorf("want --env to satisfy the policy, got %s", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// Annotations recorded on preview deployments made with --ttl and --suffix
+const (
+	expiresAnnotation = "com.openfaas.expires-at"
+	previewAnnotation = "com.openfaas.preview"
+)
+
+// validSuffix keeps suffixed function names valid DNS labels
+var validSuffix = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+var (
+	cleanupExpired bool
+	cleanupSuffix  string
+	cleanupDryRun  bool
+)
+
+// cleanupNow is swapped out in tests to expire previews
+var cleanupNow = time.Now
+
+func init() {
+	cleanupCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	cleanupCmd.Flags().BoolVar(&cleanupExpired, "expired", false, "Remove the functions whose --ttl has passed")
+	cleanupCmd.Flags().StringVar(&cleanupSuffix, "suffix", "", "Remove the functions deployed with this --suffix, i.e. when a pull request is closed")
+	cleanupCmd.Flags().BoolVar(&cleanupDryRun, "dry-run", false, "Print the functions which would be removed")
+
+	faasCmd.AddCommand(cleanupCmd)
+}
+
+var cleanupCmd = &cobra.Command{
+	Use:   `cleanup --expired | --suffix SUFFIX [--gateway GATEWAY_URL] [--dry-run]`,
+	Short: "Remove preview functions deployed with --ttl or --suffix",
+	Long: `Removes the preview functions made by "faas-cli deploy --ttl DURATION --suffix
+SUFFIX" once their time to live has passed, or all of those with a suffix.
+Run it on a schedule to keep a shared gateway free of stale previews.`,
+	Example: `  faas-cli cleanup --expired
+  faas-cli cleanup --suffix pr-123 --gateway https://previews.example.com
+  faas-cli cleanup --expired --dry-run`,
+	RunE: runCleanup,
+}
+
+func runCleanup(cmd *cobra.Command, args []string) error {
+	if !cleanupExpired && len(cleanupSuffix) == 0 {
+		return fmt.Errorf("give --expired or --suffix to choose the functions to remove")
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	deployed, err := proxy.ListFunctionAnnotationsInNamespace(gatewayAddress, functionNamespace)
+	if err != nil {
+		return err
+	}
+
+	names := previewsToRemove(deployed, cleanupExpired, cleanupSuffix, cleanupNow())
+	if len(names) == 0 {
+		fmt.Println("No preview functions to remove.")
+		return nil
+	}
+
+	failed := 0
+	for _, name := range names {
+		if cleanupDryRun {
+			fmt.Printf("Would delete: %s.\n", name)
+			continue
+		}
+
+		fmt.Printf("Deleting: %s.\n", name)
+		removeErr := proxy.DeleteFunctionInNamespace(gatewayAddress, name, functionNamespace)
+		recordRemove(gatewayAddress, name, removeErr)
+		if removeErr != nil {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("unable to remove %d of %d preview function(s)", failed, len(names))
+	}
+	return nil
+}
+
+// previewsToRemove lists, sorted, the functions which have expired or which
+// were deployed with the suffix
+func previewsToRemove(deployed map[string]map[string]string, expired bool, suffix string, now time.Time) []string {
+	var names []string
+	for name, annotations := range deployed {
+		if len(suffix) > 0 && annotations[previewAnnotation] == suffix {
+			names = append(names, name)
+			continue
+		}
+
+		if expired {
+			expiresAt, err := time.Parse(time.RFC3339, annotations[expiresAnnotation])
+			if err == nil && now.After(expiresAt) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validatePreview checks the --ttl and --suffix given to deploy
+func validatePreview(ttl time.Duration, suffix string) error {
+	if ttl < 0 {
+		return fmt.Errorf("--ttl must be a positive duration such as 2h")
+	}
+	if len(suffix) > 0 && !validSuffix.MatchString(suffix) {
+		return fmt.Errorf("--suffix %q must be lower-case letters, digits and dashes", suffix)
+	}
+	return nil
+}
+
+func previewName(name string, suffix string) string {
+	if len(suffix) == 0 {
+		return name
+	}
+	return name + "-" + suffix
+}
+
+// applyPreviewSuffix renames the functions in the stack, and the dependencies
+// between them, with the suffix
+func applyPreviewSuffix(services *stack.Services, suffix string) {
+	if len(suffix) == 0 {
+		return
+	}
+
+	renamed := make(map[string]stack.Function, len(services.Functions))
+	for name, function := range services.Functions {
+		dependsOn := make([]string, 0, len(function.DependsOn))
+		for _, dependency := range function.DependsOn {
+			dependsOn = append(dependsOn, previewName(dependency, suffix))
+		}
+		function.DependsOn = dependsOn
+		renamed[previewName(name, suffix)] = function
+	}
+	services.Functions = renamed
+}
+
+// previewAnnotations records when a preview expires and the suffix it was
+// deployed with
+func previewAnnotations(ttl time.Duration, suffix string, now time.Time) map[string]string {
+	annotations := map[string]string{}
+	if ttl > 0 {
+		annotations[expiresAnnotation] = now.Add(ttl).UTC().Format(time.RFC3339)
+	}
+	if len(suffix) > 0 {
+		annotations[previewAnnotation] = suffix
+	}
+	return annotations
+}
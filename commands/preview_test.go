@@ -0,0 +1,154 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_deployStack_Preview(t *testing.T) {
+	type deployRequest struct {
+		Service     string            `json:"service"`
+		Annotations map[string]string `json:"annotations"`
+	}
+
+	var deployed []deployRequest
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request deployRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		deployed = append(deployed, request)
+	}))
+	defer s.Close()
+
+	services := &stack.Services{
+		Provider: stack.Provider{GatewayURL: s.URL},
+		Functions: map[string]stack.Function{
+			"db":  {Image: "db:1"},
+			"api": {Image: "api:1", DependsOn: []string{"db"}},
+		},
+	}
+
+	var deployErr error
+	test.CaptureStdout(func() {
+		deployErr = deployStack(services, DeployFlags{update: true, ttl: 2 * time.Hour, suffix: "pr-123"}, nil)
+	})
+	if deployErr != nil {
+		t.Fatalf("unexpected error: %s", deployErr)
+	}
+
+	if len(deployed) != 2 || deployed[0].Service != "db-pr-123" || deployed[1].Service != "api-pr-123" {
+		t.Fatalf("want the suffixed functions deployed in order, got: %+v", deployed)
+	}
+
+	annotations := deployed[1].Annotations
+	if annotations[previewAnnotation] != "pr-123" {
+		t.Errorf("want the suffix recorded, got: %v", annotations)
+	}
+	expiresAt, err := time.Parse(time.RFC3339, annotations[expiresAnnotation])
+	if err != nil || expiresAt.Before(time.Now().Add(time.Hour)) {
+		t.Errorf("want an expiry two hours away, got: %q", annotations[expiresAnnotation])
+	}
+}
+
+func Test_previewsToRemove(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	deployed := map[string]map[string]string{
+		"api-pr-1":   {expiresAnnotation: "2018-06-01T11:00:00Z", previewAnnotation: "pr-1"},
+		"api-pr-2":   {expiresAnnotation: "2018-06-01T13:00:00Z", previewAnnotation: "pr-2"},
+		"db-pr-2":    {previewAnnotation: "pr-2"},
+		"production": {},
+	}
+
+	if got := previewsToRemove(deployed, true, "", now); !reflect.DeepEqual(got, []string{"api-pr-1"}) {
+		t.Errorf("want the expired previews, got: %v", got)
+	}
+	if got := previewsToRemove(deployed, false, "pr-2", now); !reflect.DeepEqual(got, []string{"api-pr-2", "db-pr-2"}) {
+		t.Errorf("want the previews with the suffix, got: %v", got)
+	}
+}
+
+func Test_cleanup_Expired(t *testing.T) {
+	resetForTest()
+	defer func() { cleanupExpired = false }()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api-pr-1", Annotations: map[string]string{expiresAnnotation: "2018-06-01T11:00:00Z"}},
+				{Name: "api", Annotations: map[string]string{}},
+			},
+		},
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"cleanup", "--expired", "--gateway=" + s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if !strings.Contains(stdOut, "Deleting: api-pr-1.") || strings.Contains(stdOut, "Deleting: api.") {
+		t.Errorf("want only the expired function removed, got: %s", stdOut)
+	}
+}
+
+func Test_cleanup_InNamespace(t *testing.T) {
+	resetForTest()
+	defer func() { cleanupExpired, functionNamespace = false, "" }()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api-pr-1", Annotations: map[string]string{expiresAnnotation: "2018-06-01T11:00:00Z"}},
+			},
+		},
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/functions?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"cleanup", "--expired", "--namespace=staging", "--gateway=" + s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
+
+func Test_validatePreview(t *testing.T) {
+	if err := validatePreview(time.Hour, "pr-123"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := validatePreview(-time.Hour, ""); err == nil {
+		t.Errorf("want an error for a negative --ttl")
+	}
+	if err := validatePreview(0, "PR_123"); err == nil {
+		t.Errorf("want an error for a suffix which isn't a DNS label")
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/openfaas/faas-cli/policy"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+const (
+	canarySuffix         = "-canary"
+	defaultPrometheusURL = "http://localhost:9090"
+)
+
+var (
+	prometheusURL  string
+	analysisWindow string
+	maxErrorRate   float64
+	maxP95Latency  string
+	allowNoTraffic bool
+)
+
+func init() {
+	promoteCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+
+	promoteCmd.Flags().StringVar(&prometheusURL, "prometheus-url", defaultPrometheusURL, "Prometheus URL used for canary analysis")
+	promoteCmd.Flags().StringVar(&analysisWindow, "analysis-window", "", "Analyse the canary's metrics over this window before promoting, i.e. 10m")
+	promoteCmd.Flags().Float64Var(&maxErrorRate, "max-error-rate", 0, "Override the highest ratio of 5xx responses allowed, i.e. 0.05")
+	promoteCmd.Flags().StringVar(&maxP95Latency, "max-p95-latency", "", "Override the highest 95th percentile latency allowed, i.e. 500ms")
+	promoteCmd.Flags().BoolVar(&allowNoTraffic, "allow-no-traffic", false, "Promote a canary which had no invocations over the analysis window")
+	promoteCmd.Flags().StringVar(&overridePolicy, "override-policy", "", "Promote during a freeze window in "+policy.DefaultPolicyFile+", giving the REASON for the change")
+
+	faasCmd.AddCommand(promoteCmd)
+}
+
+// promoteCmd replaces a function with the image of its canary
+var promoteCmd = &cobra.Command{
+	Use: `promote FUNCTION_NAME -f YAML_FILE [--gateway GATEWAY_URL]
+                  [--analysis-window WINDOW]
+                  [--prometheus-url PROMETHEUS_URL]
+                  [--max-error-rate RATIO]
+                  [--max-p95-latency DURATION]
+                  [--allow-no-traffic]
+                  [--override-policy REASON]`,
+	Short: "Promote a function's canary",
+	Long: `Promotes the canary of a function, deployed as FUNCTION_NAME-canary, by
+deploying the function from the YAML file with the canary's image and then
+removing the canary.
+
+When --analysis-window is given the canary's error rate and 95th percentile
+latency are read from Prometheus first and the promotion is refused if they
+breach the thresholds from the function's "canary" section or the flags. A
+canary which had no invocations over the window has no error rate to check, so
+it is only promoted with --allow-no-traffic.`,
+	Example: `  faas-cli promote url-ping -f ./stack.yml
+  faas-cli promote url-ping -f ./stack.yml --analysis-window 10m
+  faas-cli promote url-ping -f ./stack.yml --analysis-window 10m \
+    --max-error-rate 0.01 --max-p95-latency 250ms`,
+	RunE: runPromote,
+}
+
+func runPromote(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the function to promote")
+	}
+	functionName = args[0]
+
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file which defines %s with --yaml", functionName)
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, "", "")
+	if err != nil {
+		return err
+	}
+
+	function, ok := services.Functions[functionName]
+	if !ok {
+		return fmt.Errorf("function %s was not found in %s", functionName, yamlFile)
+	}
+
+	services.Provider.GatewayURL = getGatewayURL(gateway, defaultGateway, services.Provider.GatewayURL)
+
+	canaryName := functionName + canarySuffix
+	namespace := namespaceOf(function)
+	functions, err := proxy.ListFunctionsInNamespace(services.Provider.GatewayURL, namespace)
+	if err != nil {
+		return err
+	}
+
+	var canaryImage string
+	for _, deployed := range functions {
+		if deployed.Name == canaryName {
+			canaryImage = deployed.Image
+			break
+		}
+	}
+
+	if len(canaryImage) == 0 {
+		return fmt.Errorf("no canary named %s is deployed", canaryName)
+	}
+
+	if len(analysisWindow) > 0 {
+		thresholds := stack.Canary{}
+		if function.Canary != nil {
+			thresholds = *function.Canary
+		}
+		if cmd.Flag("max-error-rate").Changed {
+			thresholds.MaxErrorRate = maxErrorRate
+		}
+		if cmd.Flag("max-p95-latency").Changed {
+			thresholds.MaxP95Latency = maxP95Latency
+		}
+
+		breaches, err := analyseCanary(prometheusURL, canaryName, analysisWindow, thresholds, allowNoTraffic)
+		if err != nil {
+			return err
+		}
+
+		if len(breaches) > 0 {
+			for _, breach := range breaches {
+				fmt.Println(breach)
+			}
+			return fmt.Errorf("refusing to promote %s, the canary breached %d threshold(s)", functionName, len(breaches))
+		}
+	}
+
+	fmt.Printf("Promoting: %s with image %s.\n", functionName, canaryImage)
+
+	function.Image = canaryImage
+	services.Functions = map[string]stack.Function{functionName: function}
+
+	changePolicy, err := loadPolicy(services)
+	if err != nil {
+		return err
+	}
+
+	failed, err := deployFunctions(services, DeployFlags{update: true, overridePolicy: overridePolicy}, changePolicy)
+	if err != nil {
+		return err
+	}
+	// The canary is kept so the function still has the new image somewhere
+	if failed[functionName] {
+		return fmt.Errorf("the gateway did not deploy %s with image %s, keeping the canary %s", functionName, canaryImage, canaryName)
+	}
+
+	fmt.Printf("Removing canary: %s.\n", canaryName)
+	return proxy.DeleteFunctionInNamespace(services.Provider.GatewayURL, canaryName, namespace)
+}
+
+// analyseCanary queries Prometheus for the canary's error rate and p95
+// latency over the window and returns a message for each breached threshold.
+// A canary without invocations is an error unless allowNoTraffic is set.
+func analyseCanary(prometheusURL string, canaryName string, window string, thresholds stack.Canary, allowNoTraffic bool) ([]string, error) {
+	windowDuration, err := time.ParseDuration(window)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --analysis-window: %s", err)
+	}
+	promWindow := fmt.Sprintf("%ds", int64(windowDuration.Seconds()))
+
+	var breaches []string
+
+	errorRateQuery := fmt.Sprintf(`sum(rate(gateway_function_invocation_total{function_name="%s",code=~"5.."}[%s])) / sum(rate(gateway_function_invocation_total{function_name="%s"}[%s]))`,
+		canaryName, promWindow, canaryName, promWindow)
+
+	errorRate, found, err := proxy.QueryPrometheus(prometheusURL, errorRateQuery)
+	if err != nil {
+		return nil, err
+	}
+	if !found || math.IsNaN(errorRate) {
+		if !allowNoTraffic {
+			return nil, fmt.Errorf("canary %s had no invocations over %s so its error rate is unknown, send it traffic or give --allow-no-traffic", canaryName, window)
+		}
+		fmt.Printf("Canary %s had no invocations over %s, promoting it as --allow-no-traffic was given.\n", canaryName, window)
+		errorRate = 0
+	}
+	fmt.Printf("Canary %s error rate over %s: %.4f\n", canaryName, window, errorRate)
+
+	if thresholds.MaxErrorRate > 0 && errorRate > thresholds.MaxErrorRate {
+		breaches = append(breaches, fmt.Sprintf("error rate %.4f is above the maximum of %.4f", errorRate, thresholds.MaxErrorRate))
+	}
+
+	if len(thresholds.MaxP95Latency) > 0 {
+		maxLatency, err := time.ParseDuration(thresholds.MaxP95Latency)
+		if err != nil {
+			return nil, fmt.Errorf("invalid max_p95_latency: %s", err)
+		}
+
+		latencyQuery := fmt.Sprintf(`histogram_quantile(0.95, sum(rate(gateway_functions_seconds_bucket{function_name="%s"}[%s])) by (le))`,
+			canaryName, promWindow)
+
+		p95Seconds, found, err := proxy.QueryPrometheus(prometheusURL, latencyQuery)
+		if err != nil {
+			return nil, err
+		}
+
+		if found && !math.IsNaN(p95Seconds) {
+			p95 := time.Duration(p95Seconds * float64(time.Second))
+			fmt.Printf("Canary %s p95 latency over %s: %s\n", canaryName, window, p95)
+
+			if p95 > maxLatency {
+				breaches = append(breaches, fmt.Sprintf("p95 latency %s is above the maximum of %s", p95, maxLatency))
+			}
+		} else {
+			fmt.Printf("Canary %s has no latency samples over %s\n", canaryName, window)
+		}
+	}
+
+	return breaches, nil
+}
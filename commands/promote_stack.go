@@ -0,0 +1,245 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/policy"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// promotedFromAnnotation records the gateway a function was promoted from
+const promotedFromAnnotation = "com.openfaas.promoted-from"
+
+var (
+	promoteFrom        string
+	promoteTo          string
+	promoteYes         bool
+	promoteWait        bool
+	promoteWaitTimeout time.Duration
+)
+
+// promoteInput is read for the confirmation before a promotion is applied
+var promoteInput io.Reader = os.Stdin
+
+func init() {
+	promoteStackCmd.Flags().StringVar(&promoteFrom, "from", "", "Context name or gateway URL to read the deployed images from")
+	promoteStackCmd.Flags().StringVar(&promoteTo, "to", "", "Context name or gateway URL to deploy the images to")
+	promoteStackCmd.Flags().BoolVarP(&promoteYes, "yes", "y", false, "Promote without asking for confirmation")
+	promoteStackCmd.Flags().BoolVar(&promoteWait, "wait", false, "Wait for each function's health check to pass after deploying")
+	promoteStackCmd.Flags().DurationVar(&promoteWaitTimeout, "wait-timeout", 2*time.Minute, "How long to wait for a function to become ready with --wait")
+	promoteStackCmd.Flags().StringVar(&overridePolicy, "override-policy", "", "Promote during a freeze window in "+policy.DefaultPolicyFile+", giving the REASON for the change")
+
+	faasCmd.AddCommand(promoteStackCmd)
+}
+
+// promoteStackCmd deploys the images running on one gateway to another
+var promoteStackCmd = &cobra.Command{
+	Use: `promote-stack --from CONTEXT --to CONTEXT -f YAML_FILE
+                  [--regex "REGEX"] [--filter "WILDCARD"]
+                  [--yes] [--wait] [--wait-timeout DURATION]
+                  [--override-policy REASON]`,
+	Short: "Promote the functions of a stack from one gateway to another",
+	Long: `Reads the image digest of each function in the YAML file from the gateway
+given by --from and deploys the functions to the gateway given by --to pinned
+to exactly those digests, using the rest of their configuration from the YAML
+file. A preview of the image changes is shown before anything is deployed.
+
+--from and --to take a gateway URL or the name of a context from the config
+file:
+
+  contexts:
+    staging: https://staging.example.com
+    prod: https://openfaas.example.com`,
+	Example: `  faas-cli promote-stack --from staging --to prod -f ./stack.yml
+  faas-cli promote-stack --from staging --to prod -f ./stack.yml --filter "api-*" --yes --wait`,
+	RunE: runPromoteStack,
+}
+
+// promotion is the image a function will be deployed with and what runs now
+type promotion struct {
+	Function string
+	Current  string
+	Image    string
+	Digest   string
+}
+
+func runPromoteStack(cmd *cobra.Command, args []string) error {
+	if len(promoteFrom) == 0 || len(promoteTo) == 0 {
+		return fmt.Errorf("give the gateways to promote between with --from and --to")
+	}
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the stack with --yaml")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+	if len(services.Functions) == 0 {
+		return fmt.Errorf("no functions in %s match the filter", yamlFile)
+	}
+
+	fromGateway, err := config.LookupContext(promoteFrom)
+	if err != nil {
+		return err
+	}
+	toGateway, err := config.LookupContext(promoteTo)
+	if err != nil {
+		return err
+	}
+	if fromGateway == toGateway {
+		return fmt.Errorf("--from and --to are both %s", fromGateway)
+	}
+
+	promotions, err := planPromotion(services, fromGateway, toGateway)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Promoting from %s to %s:\n\n", fromGateway, toGateway)
+	printPromotion(os.Stdout, promotions)
+	fmt.Println()
+
+	if !promoteYes && !confirm(promoteInput, "Deploy these images?") {
+		return fmt.Errorf("promotion cancelled")
+	}
+
+	changePolicy, err := loadPolicy(services)
+	if err != nil {
+		return err
+	}
+
+	promoted := *services
+	promoted.Provider.GatewayURL = toGateway
+	promoted.Functions = map[string]stack.Function{}
+	for _, p := range promotions {
+		function := services.Functions[p.Function]
+		function.Image = p.Image
+
+		annotations := map[string]string{}
+		if function.Annotations != nil {
+			annotations = mergeMap(annotations, *function.Annotations)
+		}
+		annotations[imageDigestAnnotation] = p.Digest
+		annotations[promotedFromAnnotation] = fromGateway
+		function.Annotations = &annotations
+
+		promoted.Functions[p.Function] = function
+	}
+
+	return deployStack(&promoted, DeployFlags{
+		update:         true,
+		wait:           promoteWait,
+		waitTimeout:    promoteWaitTimeout,
+		overridePolicy: overridePolicy,
+	}, changePolicy)
+}
+
+// planPromotion pins each function to the digest deployed on the source
+// gateway and reads the image running on the target gateway
+func planPromotion(services *stack.Services, fromGateway string, toGateway string) ([]promotion, error) {
+	namespaces := stackNamespaces(services)
+	source, err := functionStatusInNamespaces(fromGateway, namespaces)
+	if err != nil {
+		return nil, err
+	}
+	target, err := functionStatusInNamespaces(toGateway, namespaces)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(services.Functions))
+	for name := range services.Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var promotions []promotion
+	for _, name := range names {
+		qualified := qualifiedName(name, namespaceOf(services.Functions[name]))
+		deployed, ok := source[qualified]
+		if !ok {
+			return nil, fmt.Errorf("function %s is not deployed to %s", name, fromGateway)
+		}
+
+		digest, err := deployedDigest(deployed)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the digest of %s on %s: %s", name, fromGateway, err)
+		}
+
+		promotions = append(promotions, promotion{
+			Function: name,
+			Current:  target[qualified].Image,
+			Image:    pinnedImage(deployed.Image, digest),
+			Digest:   digest,
+		})
+	}
+	return promotions, nil
+}
+
+// functionStatusByName lists the functions deployed to a namespace by name,
+// each keeps the namespace so that it can be deployed again
+func functionStatusByName(gateway string, namespace string) (map[string]proxy.FunctionStatus, error) {
+	statuses, err := proxy.ListFunctionStatusInNamespace(gateway, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string]proxy.FunctionStatus{}
+	for _, status := range statuses {
+		if len(status.Namespace) == 0 {
+			status.Namespace = namespace
+		}
+		byName[status.Name] = status
+	}
+	return byName, nil
+}
+
+// deployedDigest prefers a digest pinned in the image, then the digest
+// recorded when the function was deployed, then asks the registry
+func deployedDigest(status proxy.FunctionStatus) (string, error) {
+	if i := strings.Index(status.Image, "@"); i > -1 {
+		return status.Image[i+1:], nil
+	}
+	if digest := status.Annotations[imageDigestAnnotation]; len(digest) > 0 {
+		return digest, nil
+	}
+	return registryDigest(status.Image)
+}
+
+// pinnedImage replaces the tag or digest of an image with the digest
+func pinnedImage(image string, digest string) string {
+	if i := strings.Index(image, "@"); i > -1 {
+		image = image[:i]
+	} else if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + "@" + digest
+}
+
+func printPromotion(w io.Writer, promotions []promotion) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, p := range promotions {
+		switch {
+		case len(p.Current) == 0:
+			fmt.Fprintf(table, "+ function/%s\t\t%s\n", p.Function, p.Image)
+		case p.Current == p.Image:
+			fmt.Fprintf(table, "= function/%s\t%s\t(unchanged)\n", p.Function, p.Image)
+		default:
+			fmt.Fprintf(table, "~ function/%s\t%s ->\t%s\n", p.Function, p.Current, p.Image)
+		}
+	}
+	table.Flush()
+}
@@ -0,0 +1,156 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_pinnedImage(t *testing.T) {
+	cases := map[string]string{
+		"alexellis/url-ping:0.2":            "alexellis/url-ping@sha256:abc",
+		"alexellis/url-ping":                "alexellis/url-ping@sha256:abc",
+		"registry:5000/url-ping:0.2":        "registry:5000/url-ping@sha256:abc",
+		"registry:5000/url-ping":            "registry:5000/url-ping@sha256:abc",
+		"alexellis/url-ping@sha256:old":     "alexellis/url-ping@sha256:abc",
+		"alexellis/url-ping:0.2@sha256:old": "alexellis/url-ping:0.2@sha256:abc",
+	}
+	for image, want := range cases {
+		if got := pinnedImage(image, "sha256:abc"); got != want {
+			t.Errorf("%s: want %s, got %s", image, want, got)
+		}
+	}
+}
+
+func Test_deployedDigest(t *testing.T) {
+	oldDigest := registryDigest
+	defer func() { registryDigest = oldDigest }()
+	registryDigest = func(image string) (string, error) {
+		return "sha256:registry", nil
+	}
+
+	cases := []struct {
+		status proxy.FunctionStatus
+		want   string
+	}{
+		{proxy.FunctionStatus{Image: "fn@sha256:pinned"}, "sha256:pinned"},
+		{proxy.FunctionStatus{Image: "fn:1", Annotations: map[string]string{imageDigestAnnotation: "sha256:recorded"}}, "sha256:recorded"},
+		{proxy.FunctionStatus{Image: "fn:1"}, "sha256:registry"},
+	}
+	for _, c := range cases {
+		if got, _ := deployedDigest(c.status); got != c.want {
+			t.Errorf("%+v: want %s, got %s", c.status, c.want, got)
+		}
+	}
+}
+
+func Test_planPromotion(t *testing.T) {
+	staging := test.MockHttpServer(t, []test.Request{
+		{
+			Method: http.MethodGet,
+			Uri:    "/system/functions",
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api", Image: "team/api:1.1", Annotations: map[string]string{imageDigestAnnotation: "sha256:new"}},
+				{Name: "web", Image: "team/web@sha256:same"},
+			},
+		},
+	})
+	defer staging.Close()
+
+	prod := test.MockHttpServer(t, []test.Request{
+		{
+			Method: http.MethodGet,
+			Uri:    "/system/functions",
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api", Image: "team/api:1.0"},
+				{Name: "web", Image: "team/web@sha256:same"},
+			},
+		},
+	})
+	defer prod.Close()
+
+	services := &stack.Services{Functions: map[string]stack.Function{"api": {}, "web": {}}}
+	promotions, err := planPromotion(services, staging.URL, prod.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []promotion{
+		{Function: "api", Current: "team/api:1.0", Image: "team/api@sha256:new", Digest: "sha256:new"},
+		{Function: "web", Current: "team/web@sha256:same", Image: "team/web@sha256:same", Digest: "sha256:same"},
+	}
+	if !reflect.DeepEqual(promotions, want) {
+		t.Errorf("want %+v, got %+v", want, promotions)
+	}
+
+	var out bytes.Buffer
+	printPromotion(&out, append(promotions, promotion{Function: "new", Image: "team/new@sha256:x"}))
+	for _, line := range []string{
+		"~ function/api  team/api:1.0 ->       team/api@sha256:new",
+		"= function/web  team/web@sha256:same  (unchanged)",
+		"+ function/new                        team/new@sha256:x",
+	} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("want %q in:\n%s", line, out.String())
+		}
+	}
+}
+
+func Test_planPromotion_NotDeployed(t *testing.T) {
+	staging := test.MockHttpServer(t, []test.Request{{ResponseBody: []proxy.FunctionStatus{}}})
+	defer staging.Close()
+	prod := test.MockHttpServer(t, []test.Request{{ResponseBody: []proxy.FunctionStatus{}}})
+	defer prod.Close()
+
+	services := &stack.Services{Functions: map[string]stack.Function{"api": {}}}
+	_, err := planPromotion(services, staging.URL, prod.URL)
+	if err == nil || err.Error() != "function api is not deployed to "+staging.URL {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func Test_runPromoteStack_Cancelled(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	stackFile, _ := ioutil.TempFile("", "promote-stack")
+	defer os.Remove(stackFile.Name())
+	stackFile.WriteString("provider:\n  name: faas\nfunctions:\n  api:\n    lang: dockerfile\n    handler: ./api\n    image: team/api:1.1\n")
+	stackFile.Close()
+
+	staging := test.MockHttpServer(t, []test.Request{{ResponseBody: []proxy.FunctionStatus{{Name: "api", Image: "team/api@sha256:new"}}}})
+	defer staging.Close()
+	prod := test.MockHttpServer(t, []test.Request{{ResponseBody: []proxy.FunctionStatus{}}})
+	defer prod.Close()
+
+	yamlFile = stackFile.Name()
+	promoteFrom, promoteTo = staging.URL, prod.URL
+	promoteInput = strings.NewReader("n\n")
+	defer func() {
+		promoteFrom, promoteTo = "", ""
+		promoteInput = os.Stdin
+	}()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		err = runPromoteStack(nil, nil)
+	})
+
+	if err == nil || err.Error() != "promotion cancelled" {
+		t.Errorf("got error %v", err)
+	}
+	if !strings.Contains(stdOut, "+ function/api    team/api@sha256:new") {
+		t.Errorf("want the preview in:\n%s", stdOut)
+	}
+}
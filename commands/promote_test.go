@@ -0,0 +1,183 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func prometheusSample(value string) map[string]interface{} {
+	return map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "vector",
+			"result": []interface{}{
+				map[string]interface{}{
+					"metric": map[string]string{},
+					"value":  []interface{}{1519000000.0, value},
+				},
+			},
+		},
+	}
+}
+
+func Test_analyseCanary(t *testing.T) {
+	testCases := []struct {
+		title            string
+		errorRate        string
+		p95              string
+		thresholds       stack.Canary
+		expectedBreaches int
+	}{
+		{
+			title:            "Within thresholds",
+			errorRate:        "0.01",
+			p95:              "0.2",
+			thresholds:       stack.Canary{MaxErrorRate: 0.05, MaxP95Latency: "500ms"},
+			expectedBreaches: 0,
+		},
+		{
+			title:            "Error rate breached",
+			errorRate:        "0.1",
+			p95:              "0.2",
+			thresholds:       stack.Canary{MaxErrorRate: 0.05, MaxP95Latency: "500ms"},
+			expectedBreaches: 1,
+		},
+		{
+			title:            "Both breached",
+			errorRate:        "0.1",
+			p95:              "1.5",
+			thresholds:       stack.Canary{MaxErrorRate: 0.05, MaxP95Latency: "500ms"},
+			expectedBreaches: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.title, func(t *testing.T) {
+			s := test.MockHttpServer(t, []test.Request{
+				{
+					Method:       http.MethodGet,
+					ResponseBody: prometheusSample(testCase.errorRate),
+				},
+				{
+					Method:       http.MethodGet,
+					ResponseBody: prometheusSample(testCase.p95),
+				},
+			})
+			defer s.Close()
+
+			var breaches []string
+			var err error
+			test.CaptureStdout(func() {
+				breaches, err = analyseCanary(s.URL, "url-ping-canary", "10m", testCase.thresholds, false)
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if len(breaches) != testCase.expectedBreaches {
+				t.Errorf("want %d breaches, got %d: %v", testCase.expectedBreaches, len(breaches), breaches)
+			}
+		})
+	}
+}
+
+func Test_analyseCanary_InvalidWindow(t *testing.T) {
+	if _, err := analyseCanary("http://127.0.0.1:9090", "fn-canary", "ten minutes", stack.Canary{}, false); err == nil {
+		t.Fatal("expected an error for an invalid window")
+	}
+}
+
+func Test_analyseCanary_NoTraffic(t *testing.T) {
+	noSamples := map[string]interface{}{
+		"status": "success",
+		"data":   map[string]interface{}{"resultType": "vector", "result": []interface{}{}},
+	}
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:       http.MethodGet,
+			ResponseBody: noSamples,
+		},
+		{
+			Method:       http.MethodGet,
+			ResponseBody: prometheusSample("NaN"),
+		},
+		{
+			Method:       http.MethodGet,
+			ResponseBody: noSamples,
+		},
+	})
+	defer s.Close()
+
+	thresholds := stack.Canary{MaxErrorRate: 0.05, MaxP95Latency: "500ms"}
+	test.CaptureStdout(func() {
+		if _, err := analyseCanary(s.URL, "url-ping-canary", "10m", thresholds, false); err == nil || !strings.Contains(err.Error(), "--allow-no-traffic") {
+			t.Errorf("want promotion refused without samples, got %v", err)
+		}
+		if _, err := analyseCanary(s.URL, "url-ping-canary", "10m", thresholds, false); err == nil {
+			t.Errorf("want promotion refused for a NaN error rate")
+		}
+
+		breaches, err := analyseCanary(s.URL, "url-ping-canary", "10m", stack.Canary{MaxErrorRate: 0.05}, true)
+		if err != nil || len(breaches) != 0 {
+			t.Errorf("want no breaches with --allow-no-traffic, got %v %v", breaches, err)
+		}
+	})
+}
+
+func Test_promote_KeepsCanaryWhenDeployFails(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	dir, err := ioutil.TempDir("", "faas-cli-promote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	yamlPath := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(yamlPath, []byte(`provider:
+  name: faas
+functions:
+  api:
+    image: acme/api:0.1
+`), 0600)
+
+	var deleted []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/system/functions":
+			json.NewEncoder(w).Encode([]map[string]string{{"name": "api-canary", "image": "acme/api:0.2"}})
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+		case r.URL.Path == "/system/functions":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer s.Close()
+
+	yamlFile, gateway = yamlPath, s.URL
+	defer func() { gateway = defaultGateway }()
+
+	stdOut := test.CaptureStdout(func() {
+		err = promoteCmd.RunE(promoteCmd, []string{"api"})
+	})
+	if err == nil || !strings.Contains(err.Error(), "keeping the canary api-canary") {
+		t.Errorf("want the promotion to fail, got %v", err)
+	}
+	if len(deleted) > 0 || strings.Contains(stdOut, "Removing canary") {
+		t.Errorf("want the canary kept, got deletes %v:\n%s", deleted, stdOut)
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/hooks"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var publishExtraTags []string
+
+// buildFunctionImage and copyImage are swapped out in tests
+var (
+	buildFunctionImage = builder.BuildImage
+
+	// copyImage gives a published image another tag, in the registry when
+	// it was pushed by the builder or as a multi-arch manifest
+	copyImage = func(image string, target string, inRegistry bool, env []string) {
+		if inRegistry {
+			builder.ExecCommandWithEnv("./", []string{"docker", "buildx", "imagetools", "create", "--tag", target, image}, env)
+			return
+		}
+		builder.ExecCommand("./", []string{"docker", "tag", image, target})
+	}
+)
+
+func init() {
+	publishCmd.Flags().IntVar(&parallel, "parallel", 1, "Publish in parallel to depth specified.")
+	publishCmd.Flags().StringVar(&platformFlag, "platform", "", "Publish for these comma separated platforms, i.e. linux/amd64,linux/arm64, overriding each function's platforms")
+	publishCmd.Flags().StringArrayVar(&publishExtraTags, "extra-tag", []string{}, "Also publish each image with this tag, i.e. latest")
+	publishCmd.Flags().BoolVar(&nocache, "no-cache", false, "Do not use Docker's build cache")
+	publishCmd.Flags().StringArrayVar(&buildOptionNames, "build-option", []string{}, "Add the packages and build-args of this build option of the template or stack to every build, all adds every option")
+	publishCmd.Flags().StringVar(&buildProfileFlag, "build-profile", "", "Add the build-args, secrets and certificates of this profile from build.profiles in the config file to every build, instead of each function's build.profile")
+	publishCmd.Flags().StringArrayVar(&buildArgOpts, "build-arg", []string{}, "Add a build-arg for Docker (KEY=VALUE), the value is masked in the build output")
+
+	faasCmd.AddCommand(publishCmd)
+}
+
+var publishCmd = &cobra.Command{
+	Use:   `publish -f YAML_FILE [--platform PLATFORMS] [--parallel PARALLEL_DEPTH] [--extra-tag TAG ...]`,
+	Short: "Build and push OpenFaaS function images in one step",
+	Long: `Builds each function's image and pushes it to its registry, one function
+after another or --parallel at a time, instead of running build and then push.
+
+Functions with several platforms are published as one multi-arch image. A
+builder which pushes as it builds, such as buildx or buildkit, does so itself,
+otherwise the image of each platform is pushed and a manifest list is created
+with "docker manifest".`,
+	Example: `  faas-cli publish -f ./stack.yml
+  faas-cli publish -f ./stack.yml --platform linux/amd64,linux/arm64
+  faas-cli publish -f ./stack.yml --parallel 4 --extra-tag latest
+  faas-cli publish -f ./stack.yml --filter "*gif*" --build-arg NPM_TOKEN=$NPM_TOKEN`,
+	PreRunE: preRunPublish,
+	RunE:    runPublish,
+}
+
+func preRunPublish(cmd *cobra.Command, args []string) error {
+	if err := useConfiguredBuilder(false); err != nil {
+		return err
+	}
+
+	var err error
+	if buildPlatforms, err = parsePlatforms(platformFlag); err != nil {
+		return err
+	}
+	useMultiPlatformBuilder(buildPlatforms)
+
+	for _, tag := range publishExtraTags {
+		if len(tag) == 0 || strings.ContainsAny(tag, ":/@") {
+			return fmt.Errorf("--extra-tag %q must be a tag such as latest, not an image", tag)
+		}
+	}
+
+	return checkBuildTools()
+}
+
+func runPublish(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the functions to publish with -f")
+	}
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+	overridePlatforms(services, buildPlatforms)
+	if err := checkMatrices(*services); err != nil {
+		return err
+	}
+
+	if err := useRegistryConfig(pushDockerConfig, pushRegistryLogin); err != nil {
+		return err
+	}
+	environments, err := registryEnvironments(*services)
+	if err != nil {
+		return err
+	}
+
+	if pullErr := pullStackTemplates(*services, stackLanguages(*services, "")); pullErr != nil {
+		return fmt.Errorf("could not pull templates for OpenFaaS: %v", pullErr)
+	}
+
+	flagBuildArgs, err := parseMap(buildArgOpts, "build-arg")
+	if err != nil {
+		return fmt.Errorf("error parsing build-args: %v", err)
+	}
+	profiles, profilesDir, err := loadBuildProfiles(stackFunctions(*services))
+	if len(profilesDir) > 0 {
+		defer os.RemoveAll(profilesDir)
+	}
+	if err != nil {
+		return err
+	}
+	activeBuildProfiles = profiles
+	defer func() { activeBuildProfiles = nil }()
+
+	if activeBuildOptions, err = loadBuildOptions(*services, stackFunctions(*services), buildOptionNames); err != nil {
+		return err
+	}
+	defer func() { activeBuildOptions = nil }()
+
+	if err := setBuildRedactor(mergeMap(buildProfileValues(profiles), flagBuildArgs), nil); err != nil {
+		return err
+	}
+	defer builder.SetRedactor(nil)
+
+	buildArgMap := map[string]string{}
+	if len(services.BaseImages) > 0 {
+		if buildArgMap, err = builder.BuildBaseImages(services.BaseImages, nocache, false); err != nil {
+			return err
+		}
+	}
+
+	results := publishStack(services, parallel, buildArgMap, flagBuildArgs, environments)
+
+	fmt.Println()
+	printPushSummary(os.Stdout, results)
+	return nil
+}
+
+// publishStack builds and pushes the functions in the stack, queueDepth at a
+// time, each with the environment of its registry credential from
+// environments, and gives what was pushed by docker push
+func publishStack(services *stack.Services, queueDepth int, buildArgMap map[string]string, flagBuildArgs map[string]string, environments map[string][]string) []pushResult {
+	wg := sync.WaitGroup{}
+
+	var results []pushResult
+	var resultsLock sync.Mutex
+
+	workChannel := make(chan stack.Function)
+
+	for i := 0; i < queueDepth; i++ {
+		wg.Add(1)
+		go func(index int) {
+			for function := range workChannel {
+				fmt.Printf(aec.YellowF.Apply("[%d] > Publishing %s.\n"), index, function.Name)
+				if len(function.Image) == 0 {
+					fmt.Println("Please provide a valid Image value in the YAML file.")
+				} else {
+					functionResults := publishFunction(function, mergeMap(mergeMap(buildArgMap, function.BuildArgs), flagBuildArgs), functionRegistryEnvironment(*services, function, environments))
+
+					resultsLock.Lock()
+					results = append(results, functionResults...)
+					resultsLock.Unlock()
+				}
+				fmt.Printf(aec.YellowF.Apply("[%d] < Publishing %s done.\n"), index, function.Name)
+			}
+
+			fmt.Printf(aec.YellowF.Apply("[%d] worker done.\n"), index)
+			wg.Done()
+		}(i)
+	}
+
+	for k, function := range services.Functions {
+		if function.SkipBuild {
+			fmt.Printf("Skipping publish of: %s.\n", k)
+			continue
+		}
+		function.Name = k
+		matrix, err := expandMatrix(function)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for _, expanded := range matrix {
+			workChannel <- expanded
+		}
+	}
+
+	close(workChannel)
+
+	wg.Wait()
+
+	return results
+}
+
+// publishFunction builds a function's image and pushes it under its own tag
+// and each --extra-tag. A builder which pushes builds every platform into the
+// function's image, otherwise each platform is built and pushed on its own and
+// then joined by a manifest list. env points docker at the function's
+// registry credential.
+func publishFunction(function stack.Function, buildArgs map[string]string, env []string) []pushResult {
+	pushes := builder.Backend().Capabilities().Pushes
+
+	buildArgs, secrets := withBuildProfile(function, withBuildOptions(function, buildArgs), nil)
+
+	builds := []stack.Function{function}
+	if !pushes {
+		builds = expandPlatforms(function)
+	}
+	for _, build := range builds {
+		started := time.Now()
+		buildFunctionImage(builder.BuildOptions{
+			Image:        build.Image,
+			Handler:      build.Handler,
+			FunctionName: build.Name,
+			Language:     build.Language,
+			NoCache:      nocache,
+			BuildArgs:    buildArgs,
+			Secrets:      secrets,
+			Platform:     buildPlatform(build),
+			CacheFrom:    functionCacheFrom(build, nil),
+		})
+		observeBuild(build.Name, started)
+	}
+
+	var results []pushResult
+	manifest := len(function.Platforms) > 0
+	switch {
+	case pushes:
+	case manifest:
+		results = append(results, pushPlatforms(function, env)...)
+	default:
+		result := pushWithSummary(function.Name, function.Image, env)
+		fmt.Println(result)
+		results = append(results, result)
+	}
+
+	for _, tag := range publishExtraTags {
+		target := imageWithTag(function.Image, tag)
+		copyImage(function.Image, target, pushes || manifest, env)
+		if !pushes && !manifest {
+			result := pushWithSummary(function.Name, target, env)
+			fmt.Println(result)
+			results = append(results, result)
+		}
+	}
+
+	runHooks(hooks.Event{Hook: hooks.PostPush, Action: "publish", Function: function.Name, Image: function.Image})
+	return results
+}
+
+// imageWithTag replaces the tag of an image, i.e. alexellis/fn:0.2 and latest
+// give alexellis/fn:latest
+func imageWithTag(image string, tag string) string {
+	if lastColon := strings.LastIndex(image, ":"); lastColon > strings.LastIndex(image, "/") {
+		image = image[:lastColon]
+	}
+	return image + ":" + tag
+}
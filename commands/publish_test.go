@@ -0,0 +1,94 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// stubPublish records the builds, pushes and copies made by publishFunction
+// and returns a func to undo the stub
+func stubPublish(calls *[]string) func() {
+	originalBuild, originalCopy, originalPush, originalSizes := buildFunctionImage, copyImage, dockerPush, imageLayerSizes
+
+	buildFunctionImage = func(options builder.BuildOptions) {
+		*calls = append(*calls, fmt.Sprintf("build %s %s", options.Image, options.Platform))
+	}
+	copyImage = func(image string, target string, inRegistry bool, env []string) {
+		*calls = append(*calls, fmt.Sprintf("copy %s %s %v", image, target, inRegistry))
+	}
+	dockerPush = func(image string, env []string) string {
+		*calls = append(*calls, strings.TrimSpace("push "+image+" "+strings.Join(env, " ")))
+		return ""
+	}
+	imageLayerSizes = func(image string, env []string) (map[string]int64, error) { return nil, fmt.Errorf("no sizes") }
+
+	return func() {
+		buildFunctionImage, copyImage, dockerPush, imageLayerSizes = originalBuild, originalCopy, originalPush, originalSizes
+		publishExtraTags = nil
+		builder.SetBackend(nil)
+	}
+}
+
+func Test_publishFunction_Docker(t *testing.T) {
+	var calls []string
+	defer stubPublish(&calls)()
+	publishExtraTags = []string{"latest"}
+
+	results := publishFunction(stack.Function{Name: "url-ping", Image: "alexellis/url-ping:0.2"}, nil, nil)
+
+	want := []string{
+		"build alexellis/url-ping:0.2 ",
+		"push alexellis/url-ping:0.2",
+		"copy alexellis/url-ping:0.2 alexellis/url-ping:latest false",
+		"push alexellis/url-ping:latest",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("want:\n%q\ngot:\n%q", want, calls)
+	}
+	if len(results) != 2 {
+		t.Errorf("want both pushes in the summary, got %d", len(results))
+	}
+}
+
+func Test_publishFunction_PushingBuilder(t *testing.T) {
+	var calls []string
+	defer stubPublish(&calls)()
+	publishExtraTags = []string{"latest"}
+	builder.SetBackend(builder.BuildxBuilder{})
+
+	function := stack.Function{Name: "url-ping", Image: "alexellis/url-ping:0.2", Platforms: []string{"linux/amd64", "linux/arm64"}}
+	results := publishFunction(function, nil, nil)
+
+	want := []string{
+		"build alexellis/url-ping:0.2 linux/amd64,linux/arm64",
+		"copy alexellis/url-ping:0.2 alexellis/url-ping:latest true",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("want:\n%q\ngot:\n%q", want, calls)
+	}
+	if len(results) != 0 {
+		t.Errorf("want no docker push by faas-cli, got %d", len(results))
+	}
+}
+
+func Test_imageWithTag(t *testing.T) {
+	cases := map[string]string{
+		"alexellis/fn:0.2":          "alexellis/fn:latest",
+		"alexellis/fn":              "alexellis/fn:latest",
+		"registry:5000/team/fn:0.2": "registry:5000/team/fn:latest",
+		"registry:5000/team/fn":     "registry:5000/team/fn:latest",
+	}
+	for image, want := range cases {
+		if got := imageWithTag(image, "latest"); got != want {
+			t.Errorf("%s: want %s, got %s", image, want, got)
+		}
+	}
+}
@@ -5,10 +5,12 @@ package commands
 
 import (
 	"fmt"
+	"os"
 	"sync"
 
 	"github.com/morikuni/aec"
 	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/hooks"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/spf13/cobra"
 )
@@ -17,6 +19,7 @@ func init() {
 	faasCmd.AddCommand(pushCmd)
 
 	pushCmd.Flags().IntVar(&parallel, "parallel", 1, "Push images in parallel to depth specified.")
+	pushCmd.Flags().StringVar(&platformFlag, "platform", "", "The comma separated platforms given to build --platform, overriding each function's platforms")
 }
 
 // pushCmd handles pushing function container images to a remote repo
@@ -26,11 +29,25 @@ var pushCmd = &cobra.Command{
 	Long: `Pushes the OpenFaaS function container image(s) defined in the supplied YAML
 config to a remote repository.
 
-These container images must already be present in your local image cache.`,
+These container images must already be present in your local image cache.
+
+For functions with a list of platforms the image built for each platform is
+pushed and then a multi-arch manifest is pushed as the function's image, this
+needs the experimental "docker manifest" command. Nothing is pushed when the
+builder in the config file pushes as it builds, such as buildx or buildkit, or
+when several platforms are given with --platform, which build builds with
+buildx, as those images are already in the registry.
+
+A summary is printed at the end showing how many layers of each image were
+already in the registry and how many were uploaded. Sizes are read with
+"docker manifest inspect" and shown as unknown when it is not available.
+
+Credentials saved by registry-login are used when DOCKER_CONFIG is not set.`,
 
 	Example: `  faas-cli push -f https://domain/path/myfunctions.yml
   faas-cli push -f ./stack.yml
   faas-cli push -f ./stack.yml --parallel 4
+  faas-cli push -f ./stack.yml --docker-config ./credentials
   faas-cli push -f ./stack.yml --filter "*gif*"
   faas-cli push -f ./stack.yml --regex "fn[0-9]_.*"`,
 	RunE: runPush,
@@ -50,21 +67,110 @@ func runPush(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	platforms, err := parsePlatforms(platformFlag)
+	if err != nil {
+		return err
+	}
+	overridePlatforms(&services, platforms)
+	if err := checkMatrices(services); err != nil {
+		return err
+	}
+
+	// A builder which pushes as it builds has already pushed the images
+	if err := useConfiguredBuilder(false); err != nil {
+		return err
+	}
+	useMultiPlatformBuilder(platforms)
+
 	if len(services.Functions) > 0 {
-		pushStack(&services, parallel)
+		if err := useRegistryConfig(pushDockerConfig, pushRegistryLogin); err != nil {
+			return err
+		}
+		environments, err := registryEnvironments(services)
+		if err != nil {
+			return err
+		}
+		pushStack(&services, parallel, environments)
 	} else {
 		return fmt.Errorf("you must supply a valid YAML file")
 	}
 	return nil
 }
 
-func pushImage(image string) {
-	builder.ExecCommand("./", []string{"docker", "push", image})
+// platformManifest is a manifest list and the image built for each of its
+// platforms
+type platformManifest struct {
+	Image     string
+	Platforms []stack.Function
+}
+
+// platformManifests gives a manifest list for each combination of the
+// function's matrix, expanding the matrix and then the platforms as build does
+func platformManifests(function stack.Function) ([]platformManifest, error) {
+	matrix, err := expandMatrix(function)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifests []platformManifest
+	for _, expanded := range matrix {
+		manifests = append(manifests, platformManifest{
+			Image:     expanded.Image,
+			Platforms: expandPlatforms(expanded),
+		})
+	}
+	return manifests, nil
 }
 
-func pushStack(services *stack.Services, queueDepth int) {
+// pushPlatforms pushes the image built for each platform and then a
+// manifest list under the function's image so one name serves every platform
+func pushPlatforms(function stack.Function, env []string) []pushResult {
+	manifests, err := platformManifests(function)
+	if err != nil {
+		fmt.Println(err)
+		return nil
+	}
+
+	var results []pushResult
+	for _, manifest := range manifests {
+		create := []string{"docker", "manifest", "create", "--amend", manifest.Image}
+		for _, platformFunction := range manifest.Platforms {
+			result := pushWithSummary(function.Name, platformFunction.Image, env)
+			fmt.Println(result)
+			results = append(results, result)
+			create = append(create, platformFunction.Image)
+		}
+		builder.ExecCommandWithEnv("./", create, env)
+
+		for _, platformFunction := range manifest.Platforms {
+			builder.ExecCommandWithEnv("./", manifestAnnotateCommand(manifest.Image, platformFunction.Image, buildPlatform(platformFunction)), env)
+		}
+
+		builder.ExecCommandWithEnv("./", []string{"docker", "manifest", "push", "--purge", manifest.Image}, env)
+	}
+	return results
+}
+
+func manifestAnnotateCommand(manifest string, image string, platform string) []string {
+	platformOS, arch, variant := splitPlatform(platform)
+
+	command := []string{"docker", "manifest", "annotate", "--os", platformOS, "--arch", arch}
+	if len(variant) > 0 {
+		command = append(command, "--variant", variant)
+	}
+	return append(command, manifest, image)
+}
+
+// pushStack pushes the functions queueDepth at a time, each with the
+// environment of its registry credential from environments. Nothing is pushed
+// for a builder which pushes as it builds.
+func pushStack(services *stack.Services, queueDepth int, environments map[string][]string) {
+	backend := builder.Backend()
 	wg := sync.WaitGroup{}
 
+	var results []pushResult
+	var resultsLock sync.Mutex
+
 	workChannel := make(chan stack.Function)
 
 	for i := 0; i < queueDepth; i++ {
@@ -72,10 +178,29 @@ func pushStack(services *stack.Services, queueDepth int) {
 			wg.Add(1)
 			for function := range workChannel {
 				fmt.Printf(aec.YellowF.Apply("[%d] > Pushing %s.\n"), index, function.Name)
+				env := functionRegistryEnvironment(*services, function, environments)
 				if len(function.Image) == 0 {
 					fmt.Println("Please provide a valid Image value in the YAML file.")
+				} else if backend.Capabilities().Pushes {
+					fmt.Printf("Skipping push of %s, the %s builder pushed it while building.\n", function.Image, backend.Name())
+					runHooks(hooks.Event{Hook: hooks.PostPush, Action: "push", Function: function.Name, Image: function.Image})
+				} else if len(function.Platforms) > 0 {
+					platformResults := pushPlatforms(function, env)
+
+					resultsLock.Lock()
+					results = append(results, platformResults...)
+					resultsLock.Unlock()
+
+					runHooks(hooks.Event{Hook: hooks.PostPush, Action: "push", Function: function.Name, Image: function.Image})
 				} else {
-					pushImage(function.Image)
+					result := pushWithSummary(function.Name, function.Image, env)
+					fmt.Println(result)
+
+					resultsLock.Lock()
+					results = append(results, result)
+					resultsLock.Unlock()
+
+					runHooks(hooks.Event{Hook: hooks.PostPush, Action: "push", Function: function.Name, Image: function.Image})
 				}
 				fmt.Printf(aec.YellowF.Apply("[%d] < Pushing %s done.\n"), index, function.Name)
 			}
@@ -87,11 +212,20 @@ func pushStack(services *stack.Services, queueDepth int) {
 
 	for k, function := range services.Functions {
 		function.Name = k
-		workChannel <- function
+		matrix, err := expandMatrix(function)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		for _, expanded := range matrix {
+			workChannel <- expanded
+		}
 	}
 
 	close(workChannel)
 
 	wg.Wait()
 
+	fmt.Println()
+	printPushSummary(os.Stdout, results)
 }
@@ -0,0 +1,258 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/builder"
+)
+
+// pushedLayer is a layer of an image and whether the registry already had it
+type pushedLayer struct {
+	ID      string
+	Existed bool
+	// Size is the compressed size in bytes, or -1 when it is not known
+	Size int64
+}
+
+// pushResult records the layers sent for one image during push
+type pushResult struct {
+	Function string
+	Image    string
+	Layers   []pushedLayer
+}
+
+var (
+	// dockerPush pushes an image with env added to docker's environment and
+	// returns docker's output
+	dockerPush = func(image string, env []string) string {
+		return builder.ExecCommandWithEnvOutput("./", []string{"docker", "push", image}, env)
+	}
+
+	// imageLayerSizes maps the short layer IDs shown by docker push to
+	// the compressed size of each layer in the registry
+	imageLayerSizes = func(image string, env []string) (map[string]int64, error) {
+		inspect, err := exec.Command("docker", "image", "inspect", "--format", "{{json .RootFS.Layers}}", image).Output()
+		if err != nil {
+			return nil, err
+		}
+		manifestCmd := exec.Command("docker", "manifest", "inspect", image)
+		if len(env) > 0 {
+			manifestCmd.Env = append(os.Environ(), env...)
+		}
+		manifest, err := manifestCmd.Output()
+		if err != nil {
+			return nil, err
+		}
+		return parseLayerSizes(inspect, manifest)
+	}
+)
+
+var pushLayerStatus = regexp.MustCompile(`^([0-9a-f]{12}): (.+)$`)
+
+// parsePushOutput reads the final status of each layer from docker push,
+// in the order the layers were first listed
+func parsePushOutput(output io.Reader) []pushedLayer {
+	var order []string
+	statuses := map[string]string{}
+
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		match := pushLayerStatus.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if match == nil {
+			continue
+		}
+		if _, seen := statuses[match[1]]; !seen {
+			order = append(order, match[1])
+		}
+		statuses[match[1]] = match[2]
+	}
+
+	var layers []pushedLayer
+	for _, id := range order {
+		status := statuses[id]
+		switch {
+		case status == "Pushed":
+			layers = append(layers, pushedLayer{ID: id, Size: -1})
+		case status == "Layer already exists", strings.HasPrefix(status, "Mounted from"):
+			layers = append(layers, pushedLayer{ID: id, Existed: true, Size: -1})
+		}
+	}
+	return layers
+}
+
+// parseLayerSizes pairs the image's uncompressed layer digests, which docker
+// push shows, with the compressed layers of its manifest, which have sizes
+func parseLayerSizes(inspect []byte, manifest []byte) (map[string]int64, error) {
+	var diffIDs []string
+	if err := json.Unmarshal(inspect, &diffIDs); err != nil {
+		return nil, fmt.Errorf("unable to read the image's layers: %s", err)
+	}
+
+	var parsed struct {
+		Layers []struct {
+			Size int64 `json:"size"`
+		} `json:"layers"`
+	}
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		return nil, fmt.Errorf("unable to read the image's manifest: %s", err)
+	}
+	if len(parsed.Layers) != len(diffIDs) {
+		return nil, fmt.Errorf("the manifest has %d layers but the image has %d", len(parsed.Layers), len(diffIDs))
+	}
+
+	sizes := map[string]int64{}
+	for i, diffID := range diffIDs {
+		id := strings.TrimPrefix(diffID, "sha256:")
+		if len(id) > 12 {
+			id = id[:12]
+		}
+		sizes[id] = parsed.Layers[i].Size
+	}
+	return sizes, nil
+}
+
+// pushWithSummary pushes an image and records which of its layers were
+// uploaded, env points docker at the function's registry credential
+func pushWithSummary(function string, image string, env []string) pushResult {
+	result := pushResult{
+		Function: function,
+		Image:    image,
+		Layers:   parsePushOutput(strings.NewReader(dockerPush(image, env))),
+	}
+
+	if sizes, err := imageLayerSizes(image, env); err == nil {
+		for i, layer := range result.Layers {
+			if size, ok := sizes[layer.ID]; ok {
+				result.Layers[i].Size = size
+			}
+		}
+	}
+	return result
+}
+
+// totals counts the existing and uploaded layers and their sizes, a size
+// is -1 when any of its layers has an unknown size
+func (r pushResult) totals() (existing int, uploaded int, existingBytes int64, uploadedBytes int64) {
+	for _, layer := range r.Layers {
+		if layer.Existed {
+			existing++
+			existingBytes = addSize(existingBytes, layer.Size)
+		} else {
+			uploaded++
+			uploadedBytes = addSize(uploadedBytes, layer.Size)
+		}
+	}
+	return
+}
+
+func addSize(total int64, size int64) int64 {
+	if total < 0 || size < 0 {
+		return -1
+	}
+	return total + size
+}
+
+// String is printed when the push of an image finishes
+func (r pushResult) String() string {
+	existing, uploaded, existingBytes, uploadedBytes := r.totals()
+	return fmt.Sprintf("%s: %d layer(s) uploaded (%s), %d already in the registry (%s)",
+		r.Image, uploaded, formatSize(uploadedBytes), existing, formatSize(existingBytes))
+}
+
+func formatSize(size int64) string {
+	if size < 0 {
+		return "size unknown"
+	}
+
+	const unit = 1000
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size)
+	suffix := "B"
+	for _, prefix := range []string{"kB", "MB", "GB", "TB"} {
+		value /= unit
+		suffix = prefix
+		if value < unit {
+			break
+		}
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// printPushSummary prints a table of the layers pushed for each image and
+// points out layers which were uploaded for more than one image
+func printPushSummary(w io.Writer, results []pushResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Image < results[j].Image
+	})
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "FUNCTION\tIMAGE\tLAYERS\tEXISTING\tUPLOADED\tUPLOADED SIZE")
+
+	var totalExisting, totalUploaded int
+	var totalBytes int64
+	uploadedBy := map[string][]string{}
+	layerSizes := map[string]int64{}
+
+	for _, result := range results {
+		existing, uploaded, _, uploadedBytes := result.totals()
+		fmt.Fprintf(table, "%s\t%s\t%d\t%d\t%d\t%s\n", result.Function, result.Image,
+			len(result.Layers), existing, uploaded, formatSize(uploadedBytes))
+
+		totalExisting += existing
+		totalUploaded += uploaded
+		totalBytes = addSize(totalBytes, uploadedBytes)
+
+		for _, layer := range result.Layers {
+			if !layer.Existed {
+				uploadedBy[layer.ID] = append(uploadedBy[layer.ID], result.Function)
+				layerSizes[layer.ID] = layer.Size
+			}
+		}
+	}
+	fmt.Fprintf(table, "TOTAL\t\t%d\t%d\t%d\t%s\n", totalExisting+totalUploaded, totalExisting, totalUploaded, formatSize(totalBytes))
+	table.Flush()
+
+	var repeated int
+	var repeatedBytes int64
+	functions := map[string]bool{}
+	for id, by := range uploadedBy {
+		if len(by) < 2 {
+			continue
+		}
+		repeated++
+		repeatedBytes = addSize(repeatedBytes, layerSizes[id]*int64(len(by)-1))
+		for _, function := range by {
+			functions[function] = true
+		}
+	}
+
+	if repeated > 0 {
+		names := make([]string, 0, len(functions))
+		for function := range functions {
+			names = append(names, function)
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(w, "\n%d identical layer(s) were uploaded more than once (%s extra) for: %s.\n",
+			repeated, formatSize(repeatedBytes), strings.Join(names, ", "))
+		fmt.Fprintln(w, "Registries only share layers between repositories they can mount from, so push functions built from the same template to one registry and keep dependencies in early Dockerfile steps so their layers match.")
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPushOutput = `The push refers to repository [docker.io/alexellis/url-ping]
+5f70bf18a086: Preparing
+a3b5c80a4eba: Preparing
+9e9f5ec6b4d2: Preparing
+5f70bf18a086: Layer already exists
+9e9f5ec6b4d2: Mounted from library/python
+a3b5c80a4eba: Pushing [==>     ]  1.2MB/45.3MB
+a3b5c80a4eba: Pushed
+latest: digest: sha256:a4f3b1cd6e0c9a4c2c0e4b8a1c5e8d7f6a5b4c3d2e1f0a9b8c7d6e5f4a3b2c1d size: 948
+`
+
+func Test_parsePushOutput(t *testing.T) {
+	layers := parsePushOutput(strings.NewReader(testPushOutput))
+
+	want := []pushedLayer{
+		{ID: "5f70bf18a086", Existed: true, Size: -1},
+		{ID: "a3b5c80a4eba", Size: -1},
+		{ID: "9e9f5ec6b4d2", Existed: true, Size: -1},
+	}
+	if !reflect.DeepEqual(layers, want) {
+		t.Fatalf("want %v, got %v", want, layers)
+	}
+}
+
+func Test_parseLayerSizes(t *testing.T) {
+	inspect := []byte(`["sha256:5f70bf18a086007016e948b04aed3b82103a36bea41755b6cddfaf10ace3c6ef","sha256:a3b5c80a4eba0aa3ec8ec8ef5d0b1d9a6d4d8f6d6e3c3e06b1f8d6b4bdbf5a11"]`)
+	manifest := []byte(`{"schemaVersion":2,"layers":[{"size":32,"digest":"sha256:aa"},{"size":45300000,"digest":"sha256:bb"}]}`)
+
+	sizes, err := parseLayerSizes(inspect, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int64{"5f70bf18a086": 32, "a3b5c80a4eba": 45300000}
+	if !reflect.DeepEqual(sizes, want) {
+		t.Fatalf("want %v, got %v", want, sizes)
+	}
+
+	if _, err := parseLayerSizes(inspect, []byte(`{"layers":[{"size":32}]}`)); err == nil {
+		t.Fatalf("want an error when the layers do not match")
+	}
+}
+
+func Test_pushWithSummary(t *testing.T) {
+	defer func(push func(string, []string) string, sizes func(string, []string) (map[string]int64, error)) {
+		dockerPush = push
+		imageLayerSizes = sizes
+	}(dockerPush, imageLayerSizes)
+
+	dockerPush = func(image string, env []string) string { return testPushOutput }
+	imageLayerSizes = func(image string, env []string) (map[string]int64, error) {
+		return map[string]int64{"5f70bf18a086": 32, "a3b5c80a4eba": 45300000, "9e9f5ec6b4d2": 120000000}, nil
+	}
+
+	result := pushWithSummary("url-ping", "alexellis/url-ping:latest", nil)
+
+	want := "alexellis/url-ping:latest: 1 layer(s) uploaded (45.3 MB), 2 already in the registry (120.0 MB)"
+	if result.String() != want {
+		t.Fatalf("want %q, got %q", want, result.String())
+	}
+}
+
+func Test_printPushSummary(t *testing.T) {
+	results := []pushResult{
+		{Function: "b", Image: "alexellis/b:latest", Layers: []pushedLayer{
+			{ID: "aaaaaaaaaaaa", Existed: true, Size: 100},
+			{ID: "bbbbbbbbbbbb", Size: 2000000},
+		}},
+		{Function: "a", Image: "alexellis/a:latest", Layers: []pushedLayer{
+			{ID: "bbbbbbbbbbbb", Size: 2000000},
+			{ID: "cccccccccccc", Size: 500},
+		}},
+	}
+
+	var out bytes.Buffer
+	printPushSummary(&out, results)
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[1], "a ") || !strings.HasPrefix(lines[2], "b ") {
+		t.Errorf("want the images sorted, got:\n%s", out.String())
+	}
+	if !strings.Contains(lines[3], "4") || !strings.Contains(lines[3], "4.0 MB") {
+		t.Errorf("want totals of 4 layers and 4.0 MB uploaded, got %q", lines[3])
+	}
+	if !strings.Contains(out.String(), "1 identical layer(s) were uploaded more than once (2.0 MB extra) for: a, b.") {
+		t.Errorf("want the repeated layer pointed out, got:\n%s", out.String())
+	}
+}
+
+func Test_formatSize(t *testing.T) {
+	cases := map[int64]string{
+		-1:         "size unknown",
+		0:          "0 B",
+		999:        "999 B",
+		1500:       "1.5 kB",
+		45300000:   "45.3 MB",
+		2500000000: "2.5 GB",
+	}
+	for size, want := range cases {
+		if got := formatSize(size); got != want {
+			t.Errorf("%d: want %q, got %q", size, want, got)
+		}
+	}
+}
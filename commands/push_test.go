@@ -0,0 +1,52 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_platformManifests_Matrix(t *testing.T) {
+	function := stack.Function{
+		Name:      "resizer",
+		Image:     "acme/resizer:0.1",
+		Language:  "dockerfile",
+		Platforms: []string{"linux/amd64", "linux/arm64"},
+		Matrix:    map[string][]string{"PYTHON_VERSION": {"3.10", "3.11"}},
+	}
+
+	manifests, err := platformManifests(function)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(manifests) != 2 {
+		t.Fatalf("want a manifest list per matrix combination, got %d", len(manifests))
+	}
+
+	// The images must be the ones build gives each matrix combination and platform
+	matrix, _ := expandMatrix(function)
+	for i, expanded := range matrix {
+		if manifests[i].Image != expanded.Image {
+			t.Errorf("want manifest list %s, got %s", expanded.Image, manifests[i].Image)
+		}
+
+		var want, got []string
+		for _, build := range expandPlatforms(expanded) {
+			want = append(want, build.Image)
+		}
+		for _, platformFunction := range manifests[i].Platforms {
+			got = append(got, platformFunction.Image)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("want platform images %v, got %v", want, got)
+		}
+	}
+
+	if manifests[0].Platforms[1].Image != "acme/resizer:0.1-3.10-linux-arm64" {
+		t.Errorf("unexpected platform image: %s", manifests[0].Platforms[1].Image)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/proxy"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// reauthenticated records, by gateway, whether the credentials were renewed
+// so that the password is asked for at most once in a run
+var reauthenticated = map[string]bool{}
+
+// promptPassword asks for a password on the terminal, false is returned when
+// faas-cli is not run interactively. It is swapped out in tests.
+var promptPassword = func(prompt string) (string, bool) {
+	fd := int(os.Stdin.Fd())
+	if !terminal.IsTerminal(fd) {
+		return "", false
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := terminal.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimSpace(string(password)), true
+}
+
+// reauthenticate is called by the proxy when the gateway rejects the saved
+// credentials part way through a command. The password of the saved user is
+// asked for again, checked and saved so that the call can be retried instead
+// of failing the rest of a deploy. An oauth2 token saved by auth login is
+// renewed instead, the gateway may have revoked it before it expired.
+func reauthenticate(gateway string) bool {
+	if renewed, asked := reauthenticated[gateway]; asked {
+		return renewed
+	}
+	reauthenticated[gateway] = false
+
+	if token, err := config.LookupOAuthToken(gateway); err == nil && token != nil {
+		if _, err := proxy.RenewOAuthToken(gateway, *token); err != nil {
+			fmt.Fprintf(os.Stderr, "The gateway %s rejected the saved oauth2 token: %s\n", gateway, err)
+			return false
+		}
+		fmt.Fprintf(os.Stderr, "Renewed the oauth2 token for %s, retrying.\n", gateway)
+		reauthenticated[gateway] = true
+		return true
+	}
+
+	user, _, err := config.LookupAuthConfig(gateway)
+	if err != nil {
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "The gateway %s rejected the saved credentials for %s, they may have expired or been rotated.\n", gateway, user)
+	pass, ok := promptPassword(fmt.Sprintf("Password for %s: ", user))
+	if !ok || len(pass) == 0 {
+		return false
+	}
+
+	if err := validateLogin(gateway, user, pass); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false
+	}
+	if err := config.UpdateAuthConfigInStore(gateway, user, pass, savedStore(gateway)); err != nil {
+		fmt.Fprintf(os.Stderr, "Unable to save the credentials: %s\n", err)
+		return false
+	}
+
+	fmt.Fprintf(os.Stderr, "Credentials saved for %s %s, retrying.\n", user, gateway)
+	reauthenticated[gateway] = true
+	return true
+}
+
+// savedStore is the store the gateway's credentials were saved in, so that the
+// renewed password replaces them there
+func savedStore(gateway string) string {
+	cfg, err := config.ReadConfigFile()
+	if err != nil {
+		return ""
+	}
+	for _, auth := range cfg.AuthConfigs {
+		if auth.Gateway == gateway {
+			return auth.Store
+		}
+	}
+	return ""
+}
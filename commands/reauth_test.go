@@ -0,0 +1,66 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+func Test_reauthenticate(t *testing.T) {
+	originalDir, originalFile, originalPrompt := config.DefaultDir, config.DefaultFile, promptPassword
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-reauth")
+	config.DefaultFile = "config.yml"
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir, config.DefaultFile, promptPassword = originalDir, originalFile, originalPrompt
+		reauthenticated = map[string]bool{}
+	}()
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pass, _ := r.BasicAuth(); pass != "rotated" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer s.Close()
+
+	if reauthenticate(s.URL) {
+		t.Errorf("want no retry without saved credentials")
+	}
+
+	reauthenticated = map[string]bool{}
+	config.UpdateAuthConfig(s.URL, "admin", "expired")
+	prompts := 0
+	promptPassword = func(prompt string) (string, bool) {
+		prompts++
+		return "rotated", true
+	}
+
+	if !reauthenticate(s.URL) {
+		t.Fatalf("want the credentials renewed")
+	}
+	if _, pass, _ := config.LookupAuthConfig(s.URL); pass != "rotated" {
+		t.Errorf("want the new password saved, got %q", pass)
+	}
+	if !reauthenticate(s.URL) || prompts != 1 {
+		t.Errorf("want the password asked for once, asked %d time(s)", prompts)
+	}
+
+	reauthenticated = map[string]bool{}
+	promptPassword = func(prompt string) (string, bool) { return "wrong", true }
+	if reauthenticate(s.URL) {
+		t.Errorf("want a rejected password to not be retried")
+	}
+
+	reauthenticated = map[string]bool{}
+	promptPassword = func(prompt string) (string, bool) { return "", false }
+	if reauthenticate(s.URL) {
+		t.Errorf("want no retry when not run interactively")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/receipt"
+	"github.com/openfaas/faas-cli/version"
+	"github.com/spf13/cobra"
+)
+
+// receiptKeyEnv holds the key receipts are signed with when --receipt-key-file
+// is not given
+const receiptKeyEnv = "FAAS_RECEIPT_KEY"
+
+// receiptPostTimeout limits how long posting a receipt to --receipt-url takes
+const receiptPostTimeout = 30 * time.Second
+
+var receiptKeyFile string
+
+// deployReceipt collects the functions deployed while a receipt was asked for
+var deployReceipt *receipt.Receipt
+
+// receiptKey is read when deploy starts so a missing key is found before
+// anything is deployed
+var receiptKey []byte
+
+func init() {
+	receiptVerifyCmd.Flags().StringVar(&receiptKeyFile, "key-file", "", "File holding the key the receipt was signed with, "+receiptKeyEnv+" is used when not given")
+
+	receiptCmd.AddCommand(receiptVerifyCmd)
+	faasCmd.AddCommand(receiptCmd)
+}
+
+var receiptCmd = &cobra.Command{
+	Use:   `receipt`,
+	Short: "Check the receipts written by deploy --receipt",
+}
+
+var receiptVerifyCmd = &cobra.Command{
+	Use:   `verify RECEIPT_FILE [--key-file KEY_FILE]`,
+	Short: "Check a deploy receipt's signature and print what it records",
+	Example: `  faas-cli receipt verify ./receipt.json --key-file ./receipt.key
+  FAAS_RECEIPT_KEY=... faas-cli receipt verify ./receipt.json`,
+	RunE: runReceiptVerify,
+}
+
+func runReceiptVerify(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the receipt to verify")
+	}
+
+	key, err := readReceiptKey(receiptKeyFile)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		return err
+	}
+
+	verified, err := receipt.Verify(data, key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Receipt signature verified: %s deployed to %s at %s with faas-cli %s.\n", verified.User, verified.Gateway,
+		verified.Finished.Format(time.RFC3339), verified.CLIVersion)
+	for _, function := range verified.Functions {
+		status := "ok"
+		if !function.Success {
+			status = fmt.Sprintf("failed (%d)", function.StatusCode)
+		}
+		fmt.Printf("  %s\t%s\t%s\t%s\n", function.Name, function.Image, function.Digest, status)
+	}
+	return nil
+}
+
+// readReceiptKey reads the signing key from keyFile, or from the environment
+func readReceiptKey(keyFile string) ([]byte, error) {
+	if len(keyFile) == 0 {
+		if key := os.Getenv(receiptKeyEnv); len(key) > 0 {
+			return []byte(key), nil
+		}
+		return nil, fmt.Errorf("receipts are signed, give the key with --receipt-key-file or %s", receiptKeyEnv)
+	}
+
+	path, err := homedir.Expand(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the receipt key: %s", err)
+	}
+
+	key = []byte(strings.TrimSpace(string(key)))
+	if len(key) == 0 {
+		return nil, fmt.Errorf("the receipt key in %s is empty", keyFile)
+	}
+	return key, nil
+}
+
+// startReceipt begins collecting a receipt when deploy was given --receipt or
+// --receipt-url
+func startReceipt(deployFlags DeployFlags) error {
+	deployReceipt = nil
+	if len(deployFlags.receiptPath) == 0 && len(deployFlags.receiptURL) == 0 {
+		return nil
+	}
+
+	key, err := readReceiptKey(deployFlags.receiptKeyFile)
+	if err != nil {
+		return err
+	}
+	receiptKey = key
+
+	deployReceipt = &receipt.Receipt{
+		User:       auditUser(),
+		Started:    time.Now().UTC(),
+		CLIVersion: version.BuildVersion(),
+		CLICommit:  version.GitCommit,
+	}
+	return nil
+}
+
+// addToReceipt records a deployment in the receipt being collected
+func addToReceipt(gateway string, spec *proxy.DeployFunctionSpec, statusCode int) {
+	if deployReceipt == nil {
+		return
+	}
+	if len(deployReceipt.Gateway) == 0 {
+		deployReceipt.Gateway = gateway
+	}
+
+	digest := auditDigest(spec.Image, spec.Annotations)
+	if len(digest) == 0 {
+		var err error
+		if digest, err = registryDigest(spec.Image); err != nil {
+			fmt.Fprintf(os.Stderr, "Unable to read the digest of %s for the receipt: %s\n", spec.Image, err)
+		}
+	}
+
+	hash := spec.Annotations[configHashAnnotation]
+	if len(hash) == 0 {
+		hash = configHash(spec)
+	}
+
+	deployReceipt.Functions = append(deployReceipt.Functions, receipt.Function{
+		Name:       spec.FunctionName,
+		Image:      spec.Image,
+		Digest:     digest,
+		ConfigHash: hash,
+		StatusCode: statusCode,
+		Success:    deploySucceeded(statusCode),
+	})
+}
+
+// finishReceipt signs the receipt, then writes it to --receipt and posts it
+// to --receipt-url. Nothing is written when no function was deployed.
+func finishReceipt(deployFlags DeployFlags) error {
+	finished := deployReceipt
+	deployReceipt = nil
+	if finished == nil || len(finished.Functions) == 0 {
+		return nil
+	}
+
+	finished.Finished = time.Now().UTC()
+	if err := finished.Sign(receiptKey); err != nil {
+		return err
+	}
+
+	if len(deployFlags.receiptPath) > 0 {
+		if err := finished.Write(deployFlags.receiptPath); err != nil {
+			return fmt.Errorf("unable to write the receipt: %s", err)
+		}
+		fmt.Printf("Wrote the receipt for %d function(s) to %s.\n", len(finished.Functions), deployFlags.receiptPath)
+	}
+
+	if len(deployFlags.receiptURL) > 0 {
+		if err := finished.Post(deployFlags.receiptURL, receiptPostTimeout); err != nil {
+			return fmt.Errorf("unable to post the receipt: %s", err)
+		}
+		fmt.Printf("Posted the receipt for %d function(s) to %s.\n", len(finished.Functions), deployFlags.receiptURL)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/receipt"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_deploy_WritesSignedReceipt(t *testing.T) {
+	resetForTest()
+	defer func() {
+		deployFlags.receiptPath = ""
+		deployFlags.receiptKeyFile = ""
+	}()
+
+	oldDigest := registryDigest
+	defer func() { registryDigest = oldDigest }()
+	registryDigest = func(image string) (string, error) {
+		return "sha256:abc", nil
+	}
+
+	dir, err := ioutil.TempDir("", "faas-cli-receipt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "receipt.key")
+	if err := ioutil.WriteFile(keyFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	receiptFile := filepath.Join(dir, "receipt.json")
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"deploy", "--gateway=" + s.URL, "--image=golang", "--name=test-function",
+			"--receipt=" + receiptFile, "--receipt-key-file=" + keyFile})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if !strings.Contains(stdOut, "Wrote the receipt for 1 function(s)") {
+		t.Errorf("want the receipt reported, got: %s", stdOut)
+	}
+
+	data, err := ioutil.ReadFile(receiptFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := receipt.Verify(data, []byte("secret"))
+	if err != nil {
+		t.Fatalf("want a receipt signed with the key, got: %s", err)
+	}
+
+	function := verified.Functions[0]
+	if verified.Gateway != s.URL || function.Name != "test-function" || function.Digest != "sha256:abc" || !function.Success || len(function.ConfigHash) == 0 {
+		t.Errorf("unexpected receipt: %+v", verified)
+	}
+}
+
+func Test_deploy_ReceiptNeedsKey(t *testing.T) {
+	resetForTest()
+	defer func() { deployFlags.receiptPath = "" }()
+	os.Unsetenv(receiptKeyEnv)
+
+	// Nothing is deployed without a key to sign the receipt
+	s := test.MockHttpServer(t, []test.Request{})
+	defer s.Close()
+
+	var err error
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"deploy", "--gateway=" + s.URL, "--image=golang", "--name=test-function", "--receipt=receipt.json"})
+		err = faasCmd.Execute()
+	})
+
+	if err == nil || !strings.Contains(err.Error(), receiptKeyEnv) {
+		t.Fatalf("want an error asking for the key, got: %v", err)
+	}
+}
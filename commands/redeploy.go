@@ -0,0 +1,144 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/policy"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	redeployBatch       batchOptions
+	redeployWait        bool
+	redeployWaitTimeout time.Duration
+)
+
+func init() {
+	redeployCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	redeployCmd.Flags().StringVar(&overridePolicy, "override-policy", "", "Redeploy during a freeze window in "+policy.DefaultPolicyFile+", giving the REASON for the change")
+	redeployCmd.Flags().BoolVar(&redeployWait, "wait", false, "Wait for the functions of a batch to pass their health checks before starting the next")
+	redeployCmd.Flags().DurationVar(&redeployWaitTimeout, "wait-timeout", 2*time.Minute, "How long --wait waits for each function")
+	addBatchFlags(redeployCmd, &redeployBatch, 5)
+
+	faasCmd.AddCommand(redeployCmd)
+}
+
+var redeployCmd = &cobra.Command{
+	Use: `redeploy -f YAML_FILE [--batch-size SIZE] [--pause DURATION] [--resume]
+  faas-cli redeploy FUNCTION_NAME... [--gateway GATEWAY_URL]`,
+	Short: "Roll deployed functions in batches, i.e. after a secret rotation",
+	Long: `Performs a rolling update of each deployed function in the YAML file, or of
+the functions named, as it is deployed. This picks up rotated secrets or moves
+functions onto new nodes without changing their configuration.
+
+Functions are rolled a batch at a time, with an optional pause between batches.
+The functions completed are recorded in a checkpoint file, so a run which is
+interrupted or stopped by a failure can be continued with --resume.`,
+	Example: `  faas-cli redeploy -f ./stack.yml --batch-size 5 --pause 30s
+  faas-cli redeploy -f ./stack.yml --filter "billing-*" --wait
+  faas-cli redeploy -f ./stack.yml --resume
+  faas-cli redeploy figlet nodeinfo -g https://openfaas.example.com`,
+	RunE: runRedeploy,
+}
+
+func runRedeploy(cmd *cobra.Command, args []string) error {
+	names := args
+	var services *stack.Services
+	yamlGateway := ""
+
+	if len(names) == 0 {
+		if len(yamlFile) == 0 {
+			return fmt.Errorf("please provide the functions to redeploy, or a YAML file with --yaml")
+		}
+
+		parsedServices, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+		if err != nil {
+			return err
+		}
+		services = parsedServices
+		yamlGateway = services.Provider.GatewayURL
+
+		// Dependencies are rolled before the functions which use them
+		if names, err = stack.DeploymentOrder(services.Functions); err != nil {
+			return err
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("no functions in %s match the filter", yamlFile)
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway)
+
+	changePolicy, err := loadPolicy(services)
+	if err != nil {
+		return err
+	}
+
+	// Functions are in --namespace, unless the YAML file gives their own
+	namespaceOfName := func(name string) string {
+		if services != nil {
+			return namespaceOf(services.Functions[name])
+		}
+		return functionNamespace
+	}
+	namespaces := []string{functionNamespace}
+	if services != nil {
+		namespaces = stackNamespaces(services)
+	}
+	deployed, err := functionStatusInNamespaces(gatewayAddress, namespaces)
+	if err != nil {
+		return err
+	}
+
+	var rolled, missing []string
+	policyAnnotations := map[string]map[string]string{}
+	for _, name := range names {
+		if _, ok := deployed[qualifiedName(name, namespaceOfName(name))]; !ok {
+			missing = append(missing, name)
+			continue
+		}
+
+		// Every function is checked before any are rolled
+		annotations, err := enforcePolicy(changePolicy, name, overridePolicy)
+		if err != nil {
+			return err
+		}
+		policyAnnotations[name] = annotations
+		rolled = append(rolled, name)
+	}
+
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		fmt.Printf("Skipping %d function(s) which are not deployed: %s.\n", len(missing), strings.Join(missing, ", "))
+	}
+
+	return runBatches("redeploy", gatewayAddress, rolled, redeployBatch, func(name string) error {
+		namespace := namespaceOfName(name)
+		spec := restartSpec(deployed[qualifiedName(name, namespace)], time.Now())
+		spec.Annotations = mergeMap(spec.Annotations, policyAnnotations[name])
+
+		fmt.Printf("Redeploying: %s.\n", name)
+		statusCode := proxy.DeployFunction(gatewayAddress, spec)
+		recordDeploy(gatewayAddress, spec, statusCode)
+		if !deploySucceeded(statusCode) {
+			return fmt.Errorf("the gateway returned %d", statusCode)
+		}
+
+		if redeployWait {
+			var healthCheck *stack.HealthCheck
+			if services != nil {
+				healthCheck = services.Functions[name].HealthCheck
+			}
+			return waitForFunction(gatewayAddress, name, namespace, healthCheck, redeployWaitTimeout)
+		}
+		return nil
+	})
+}
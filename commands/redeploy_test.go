@@ -0,0 +1,89 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_runRedeploy(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	dir, err := ioutil.TempDir("", "faas-cli-redeploy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []proxy.FunctionStatus{
+				{Name: "api", Image: "api:0.1"},
+				{Name: "db", Image: "db:0.1"},
+			},
+		},
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	stackYAML := `provider:
+  name: faas
+functions:
+  api:
+    image: api:0.1
+    depends_on:
+      - db
+  db:
+    image: db:0.1
+  worker:
+    image: worker:0.1
+`
+	if err := ioutil.WriteFile(stackFile, []byte(stackYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	checkpoint := filepath.Join(dir, "checkpoint.json")
+	output := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"redeploy", "-f", stackFile, "-g", s.URL, "--batch-size", "1", "--checkpoint", checkpoint})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, want := range []string{
+		"Skipping 1 function(s) which are not deployed: worker.",
+		"Batch 1 of 2: 1 function(s).\nRedeploying: db.",
+		"Batch 2 of 2: 1 function(s).\nRedeploying: api.",
+		"Completed redeploy of 2 function(s) in 2 batch(es).",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("want %q in output, got:\n%s", want, output)
+		}
+	}
+	if _, err := os.Stat(checkpoint); !os.IsNotExist(err) {
+		t.Fatalf("want the checkpoint removed, got: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/registry"
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// lookupRegistry is swapped out in tests
+var lookupRegistry = config.LookupRegistry
+
+// functionRegistryCredential gives the registry credential named by the
+// function, or by its build group when it names none
+func functionRegistryCredential(services stack.Services, function stack.Function) string {
+	if len(function.RegistryCredential) > 0 {
+		return function.RegistryCredential
+	}
+	if function.Build != nil && len(function.Build.Group) > 0 {
+		return services.BuildGroups[function.Build.Group].RegistryCredential
+	}
+	return ""
+}
+
+// stackRegistryCredentials gives the names of the registry credentials used
+// by the functions of the stack, in order
+func stackRegistryCredentials(services stack.Services) []string {
+	used := map[string]bool{}
+	var names []string
+	for _, function := range services.Functions {
+		name := functionRegistryCredential(services, function)
+		if len(name) > 0 && !used[name] {
+			used[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registryEnvironments gives the environment which points docker at each
+// registry credential used by the stack, so that a function is pushed with
+// its own credential. It is resolved before pushing so that an unknown or
+// incomplete credential stops the push before it starts.
+func registryEnvironments(services stack.Services) (map[string][]string, error) {
+	environments := map[string][]string{}
+	for _, name := range stackRegistryCredentials(services) {
+		credential, err := lookupRegistry(name)
+		if err != nil {
+			return nil, err
+		}
+		dir, err := registryDockerConfig(name, credential)
+		if err != nil {
+			return nil, fmt.Errorf("registry credential %s: %s", name, err)
+		}
+		environments[name] = []string{"DOCKER_CONFIG=" + dir}
+	}
+	return environments, nil
+}
+
+// functionRegistryEnvironment gives the environment to push the function's
+// image with, which is empty when it names no registry credential
+func functionRegistryEnvironment(services stack.Services, function stack.Function, environments map[string][]string) []string {
+	return environments[functionRegistryCredential(services, function)]
+}
+
+// registryDockerConfig gives the folder of the docker config for a registry
+// credential. A docker_config is used as it is, otherwise one is written to
+// registries in the state directory for the credential helper or for the
+// username and the password read from password_env.
+func registryDockerConfig(name string, credential config.RegistryCredential) (string, error) {
+	if len(credential.DockerConfig) > 0 {
+		dir, err := homedir.Expand(credential.DockerConfig)
+		if err != nil {
+			return "", err
+		}
+		if _, err := os.Stat(filepath.Join(dir, "config.json")); err != nil {
+			return "", fmt.Errorf("no docker config.json was found in %s", dir)
+		}
+		return dir, nil
+	}
+
+	if len(credential.Server) == 0 {
+		return "", fmt.Errorf("give a server, or a docker_config")
+	}
+
+	dir, err := homedir.Expand(filepath.Join(stateDir, "registries", name))
+	if err != nil {
+		return "", err
+	}
+	if len(credential.CredentialHelper) > 0 {
+		_, err := registry.WriteCredentialHelper(dir, credential.Server, credential.CredentialHelper)
+		return dir, err
+	}
+
+	if len(credential.Username) == 0 || len(credential.PasswordEnv) == 0 {
+		return "", fmt.Errorf("give a credential_helper, or a username and password_env")
+	}
+	password := os.Getenv(credential.PasswordEnv)
+	if len(password) == 0 {
+		return "", fmt.Errorf("the password in %s is not set", credential.PasswordEnv)
+	}
+	_, err = registry.WriteDockerConfig(dir, registry.Credentials{
+		Server:   credential.Server,
+		Username: credential.Username,
+		Password: password,
+	})
+	return dir, err
+}
+
+// registryPullSecrets gives the pull secret of the registry credential of
+// each function which has one
+func registryPullSecrets(services stack.Services) (map[string]string, error) {
+	pullSecrets := map[string]string{}
+	for functionName, function := range services.Functions {
+		name := functionRegistryCredential(services, function)
+		if len(name) == 0 {
+			continue
+		}
+		credential, err := lookupRegistry(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(credential.PullSecret) > 0 {
+			pullSecrets[functionName] = credential.PullSecret
+		}
+	}
+	return pullSecrets, nil
+}
@@ -0,0 +1,204 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/openfaas/faas-cli/builder"
+	"github.com/openfaas/faas-cli/config"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+// stubRegistries makes lookupRegistry read from registries and returns a
+// func to undo the stub
+func stubRegistries(registries map[string]config.RegistryCredential) func() {
+	original := lookupRegistry
+	lookupRegistry = func(name string) (config.RegistryCredential, error) {
+		credential, ok := registries[name]
+		if !ok {
+			return credential, fmt.Errorf("registry credential %s was not found in the registries of the config file", name)
+		}
+		return credential, nil
+	}
+	return func() { lookupRegistry = original }
+}
+
+func registryServices() stack.Services {
+	return stack.Services{
+		BuildGroups: map[string]stack.BuildGroup{
+			"backend": {RegistryCredential: "acme-ecr"},
+		},
+		Functions: map[string]stack.Function{
+			"api":    {Image: "123456789012.dkr.ecr.eu-west-1.amazonaws.com/api", Build: &stack.FunctionBuild{Group: "backend"}},
+			"worker": {Image: "ghcr.io/acme/worker", RegistryCredential: "ghcr", Build: &stack.FunctionBuild{Group: "backend"}},
+			"ui":     {Image: "acme/ui"},
+		},
+	}
+}
+
+func Test_functionRegistryCredential(t *testing.T) {
+	services := registryServices()
+
+	cases := map[string]string{"api": "acme-ecr", "worker": "ghcr", "ui": ""}
+	for name, want := range cases {
+		if got := functionRegistryCredential(services, services.Functions[name]); got != want {
+			t.Errorf("%s: want %q, got %q", name, want, got)
+		}
+	}
+}
+
+func Test_registryEnvironments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-registries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	originalStateDir := stateDir
+	defer func() { stateDir = originalStateDir }()
+	stateDir = dir
+
+	os.Setenv("GHCR_TOKEN", "token")
+	defer os.Unsetenv("GHCR_TOKEN")
+
+	defer stubRegistries(map[string]config.RegistryCredential{
+		"acme-ecr": {Server: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", CredentialHelper: "ecr-login"},
+		"ghcr":     {Server: "ghcr.io", Username: "acme", PasswordEnv: "GHCR_TOKEN"},
+	})()
+
+	environments, err := registryEnvironments(registryServices())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := map[string][]string{
+		"acme-ecr": {"DOCKER_CONFIG=" + filepath.Join(dir, "registries", "acme-ecr")},
+		"ghcr":     {"DOCKER_CONFIG=" + filepath.Join(dir, "registries", "ghcr")},
+	}
+	if !reflect.DeepEqual(environments, want) {
+		t.Errorf("want %v, got %v", want, environments)
+	}
+
+	helperConfig, _ := ioutil.ReadFile(filepath.Join(dir, "registries", "acme-ecr", "config.json"))
+	if !strings.Contains(string(helperConfig), `"ecr-login"`) {
+		t.Errorf("want the credential helper written, got %s", helperConfig)
+	}
+	authConfig, _ := ioutil.ReadFile(filepath.Join(dir, "registries", "ghcr", "config.json"))
+	if !strings.Contains(string(authConfig), `"ghcr.io"`) {
+		t.Errorf("want the ghcr.io auth written, got %s", authConfig)
+	}
+}
+
+func Test_registryEnvironments_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-registries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	originalStateDir := stateDir
+	defer func() { stateDir = originalStateDir }()
+	stateDir = dir
+
+	defer stubRegistries(map[string]config.RegistryCredential{
+		"acme-ecr": {Server: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", CredentialHelper: "ecr-login"},
+		"ghcr":     {Server: "ghcr.io", Username: "acme", PasswordEnv: "FAAS_CLI_TEST_UNSET"},
+	})()
+
+	_, err = registryEnvironments(registryServices())
+	if err == nil || !strings.Contains(err.Error(), "registry credential ghcr: the password in FAAS_CLI_TEST_UNSET is not set") {
+		t.Errorf("want an error for the unset password, got %v", err)
+	}
+
+	services := registryServices()
+	delete(services.Functions, "worker")
+	services.Functions["ui"] = stack.Function{Image: "acme/ui", RegistryCredential: "quay"}
+	_, err = registryEnvironments(services)
+	if err == nil || !strings.Contains(err.Error(), "quay was not found") {
+		t.Errorf("want an error for the unknown credential, got %v", err)
+	}
+}
+
+func Test_pushStack_RegistryCredentials(t *testing.T) {
+	original := dockerPush
+	defer func() { dockerPush = original }()
+
+	var pushes []string
+	var lock sync.Mutex
+	dockerPush = func(image string, env []string) string {
+		lock.Lock()
+		defer lock.Unlock()
+		pushes = append(pushes, strings.TrimSpace(image+" "+strings.Join(env, " ")))
+		return ""
+	}
+
+	services := registryServices()
+	environments := map[string][]string{
+		"acme-ecr": {"DOCKER_CONFIG=/state/registries/acme-ecr"},
+		"ghcr":     {"DOCKER_CONFIG=/state/registries/ghcr"},
+	}
+	test.CaptureStdout(func() { pushStack(&services, 1, environments) })
+
+	sort.Strings(pushes)
+	want := []string{
+		"123456789012.dkr.ecr.eu-west-1.amazonaws.com/api DOCKER_CONFIG=/state/registries/acme-ecr",
+		"acme/ui",
+		"ghcr.io/acme/worker DOCKER_CONFIG=/state/registries/ghcr",
+	}
+	if !reflect.DeepEqual(pushes, want) {
+		t.Errorf("want %v, got %v", want, pushes)
+	}
+}
+
+func Test_registryPullSecrets(t *testing.T) {
+	defer stubRegistries(map[string]config.RegistryCredential{
+		"acme-ecr": {Server: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", PullSecret: "acme-ecr-pull"},
+		"ghcr":     {Server: "ghcr.io"},
+	})()
+
+	pullSecrets, err := registryPullSecrets(registryServices())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := map[string]string{"api": "acme-ecr-pull"}; !reflect.DeepEqual(pullSecrets, want) {
+		t.Errorf("want %v, got %v", want, pullSecrets)
+	}
+}
+
+func Test_pushStack_SkipsPushingBuilder(t *testing.T) {
+	original := dockerPush
+	defer func() { dockerPush = original }()
+	defer builder.SetBackend(nil)
+
+	var pushes []string
+	dockerPush = func(image string, env []string) string {
+		pushes = append(pushes, image)
+		return ""
+	}
+	builder.SetBackend(builder.BuildxBuilder{})
+
+	services := stack.Services{
+		Functions: map[string]stack.Function{
+			"api": {Image: "acme/api:0.1", Platforms: []string{"linux/amd64", "linux/arm64"}},
+		},
+	}
+	stdOut := test.CaptureStdout(func() { pushStack(&services, 1, nil) })
+
+	if len(pushes) != 0 {
+		t.Errorf("want nothing pushed after a buildx build, got %v", pushes)
+	}
+	if !strings.Contains(stdOut, "Skipping push of acme/api:0.1, the buildx builder pushed it while building.") {
+		t.Errorf("want the skipped push explained:\n%s", stdOut)
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/registry"
+	"github.com/spf13/cobra"
+)
+
+var (
+	registryServer        string
+	registryUsername      string
+	registryPassword      string
+	registryPasswordStdin bool
+	registryECR           bool
+	registryGCP           bool
+	registryRegion        string
+	registryAccountID     string
+	registryLocation      string
+	registryConfigDir     string
+	pushDockerConfig      string
+	pushRegistryLogin     bool
+)
+
+// ecrLogin and gcpLogin are swapped out in tests
+var (
+	ecrLogin = registry.ECRLogin
+	gcpLogin = registry.GCPLogin
+)
+
+func init() {
+	registryLoginCmd.Flags().StringVar(&registryServer, "server", "", "Registry server, defaults to Docker Hub or to the registry of --ecr or --gcp")
+	registryLoginCmd.Flags().StringVarP(&registryUsername, "username", "u", "", "Registry username")
+	registryLoginCmd.Flags().StringVarP(&registryPassword, "password", "p", "", "Registry password or token")
+	registryLoginCmd.Flags().BoolVar(&registryPasswordStdin, "password-stdin", false, "Reads the registry password or token from stdin")
+	registryLoginCmd.Flags().BoolVar(&registryECR, "ecr", false, "Get a token for AWS ECR with the IAM credentials in the environment or the aws CLI")
+	registryLoginCmd.Flags().StringVar(&registryRegion, "region", "", "AWS region of the ECR registry, defaults to AWS_REGION")
+	registryLoginCmd.Flags().StringVar(&registryAccountID, "account-id", "", "AWS account ID of the ECR registry, defaults to the account of the credentials")
+	registryLoginCmd.Flags().BoolVar(&registryGCP, "gcp", false, "Get a token for GCP Artifact Registry or gcr.io with gcloud or the metadata server")
+	registryLoginCmd.Flags().StringVar(&registryLocation, "location", "", "Location of the Artifact Registry, i.e. europe-west1")
+	registryLoginCmd.Flags().StringVar(&registryConfigDir, "docker-config", "", "Folder to write config.json to, defaults to docker in the state directory")
+
+	for _, cmd := range []*cobra.Command{pushCmd, publishCmd} {
+		cmd.Flags().StringVar(&pushDockerConfig, "docker-config", "", "Folder of the docker config.json to push with instead of docker's own")
+		cmd.Flags().BoolVar(&pushRegistryLogin, "registry-login", false, "Push with the credentials saved by registry-login instead of docker's own")
+	}
+
+	faasCmd.AddCommand(registryLoginCmd)
+}
+
+var registryLoginCmd = &cobra.Command{
+	Use:   `registry-login [--server SERVER] [--username USERNAME] [--password-stdin] [--ecr|--gcp]`,
+	Short: "Log in to a container registry for push",
+	Long: `Saves credentials for a container registry to a docker config.json kept
+apart from ~/.docker, in docker in the state directory or --docker-config.
+push and publish use it when given --registry-login or --docker-config, so a
+CI pipeline doesn't need to run docker login first. Without them docker's own
+config is kept, along with its other logins, contexts and builders.
+
+--ecr exchanges the IAM credentials in AWS_ACCESS_KEY_ID and
+AWS_SECRET_ACCESS_KEY for an ECR token, or runs "aws ecr get-login-password"
+without them. --gcp gets an access token for Artifact Registry, or for the
+--server such as gcr.io, from GOOGLE_OAUTH_ACCESS_TOKEN, "gcloud auth
+print-access-token" or the metadata server. Both tokens expire, ECR tokens
+after 12 hours and Google tokens after an hour, so log in again before each
+push in long-running pipelines.`,
+	Example: `  cat ~/registry_pass.txt | faas-cli registry-login -u user --password-stdin
+  faas-cli registry-login --server ghcr.io -u user --password-stdin < token.txt
+  faas-cli registry-login --ecr --region eu-west-1
+  faas-cli registry-login --ecr --region eu-west-1 --account-id 123456789012
+  faas-cli registry-login --gcp --location europe-west1
+  faas-cli registry-login --gcp --server gcr.io --docker-config ./credentials`,
+	RunE: runRegistryLogin,
+}
+
+func runRegistryLogin(cmd *cobra.Command, args []string) error {
+	if registryECR && registryGCP {
+		return fmt.Errorf("--ecr and --gcp are mutually exclusive")
+	}
+
+	credentials, err := registryCredentials()
+	if err != nil {
+		return err
+	}
+
+	dir, err := registryConfigDirectory(registryConfigDir)
+	if err != nil {
+		return err
+	}
+	path, err := registry.WriteDockerConfig(dir, credentials)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("credentials for %s saved to %s\n", credentials.Server, path)
+	if len(registryConfigDir) > 0 {
+		fmt.Printf("push with --docker-config %s to use them\n", registryConfigDir)
+	} else {
+		fmt.Println("push with --registry-login to use them")
+	}
+	if !credentials.Expiry.IsZero() {
+		fmt.Printf("the token expires at %s\n", credentials.Expiry.Local().Format("2006-01-02 15:04:05"))
+	}
+	return nil
+}
+
+// registryCredentials gets the credentials from the cloud helper chosen by
+// the flags, or from the username and password
+func registryCredentials() (registry.Credentials, error) {
+	switch {
+	case registryECR:
+		credentials, err := ecrLogin(registryRegion, registryAccountID)
+		if err != nil {
+			return credentials, err
+		}
+		if len(registryServer) > 0 {
+			credentials.Server = registryServer
+		}
+		return credentials, nil
+	case registryGCP:
+		return gcpLogin(registryLocation, registryServer)
+	}
+
+	if len(registryUsername) == 0 {
+		return registry.Credentials{}, fmt.Errorf("must provide --username or -u, or use --ecr or --gcp")
+	}
+
+	password := registryPassword
+	if len(password) > 0 {
+		fmt.Println("WARNING! Using --password is insecure, consider using: cat ~/registry_pass.txt | faas-cli registry-login -u user --password-stdin")
+		if registryPasswordStdin {
+			return registry.Credentials{}, fmt.Errorf("--password and --password-stdin are mutually exclusive")
+		}
+	}
+	if registryPasswordStdin {
+		stdin, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return registry.Credentials{}, err
+		}
+		password = string(stdin)
+	}
+
+	password = strings.TrimSpace(password)
+	if len(password) == 0 {
+		return registry.Credentials{}, fmt.Errorf("must provide a non-empty password via --password or --password-stdin")
+	}
+
+	server := registryServer
+	if len(server) == 0 {
+		server = registry.DockerHubServer
+	}
+	return registry.Credentials{Server: server, Username: registryUsername, Password: password}, nil
+}
+
+// registryConfigDirectory gives dir, or docker in the state directory when
+// it is empty
+func registryConfigDirectory(dir string) (string, error) {
+	if len(dir) == 0 {
+		dir = filepath.Join(stateDir, "docker")
+	}
+	return homedir.Expand(dir)
+}
+
+// useRegistryConfig points docker at the config in dir, or at the one
+// written by registry-login when registryLogin is set. Otherwise docker's own
+// config is left alone, as the one written by registry-login only holds the
+// registries logged in to with it.
+func useRegistryConfig(dir string, registryLogin bool) error {
+	if len(dir) == 0 && !registryLogin {
+		return nil
+	}
+
+	configDir, err := registryConfigDirectory(dir)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(configDir, "config.json")); err != nil {
+		if len(dir) == 0 {
+			return fmt.Errorf("no docker config.json was found in %s, run faas-cli registry-login first", configDir)
+		}
+		return fmt.Errorf("no docker config.json was found in %s", configDir)
+	}
+	return os.Setenv("DOCKER_CONFIG", configDir)
+}
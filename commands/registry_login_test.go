@@ -0,0 +1,77 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/registry"
+)
+
+func Test_registryLogin_ECRWritesScopedConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-registry-login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	original := ecrLogin
+	defer func() { ecrLogin = original }()
+	ecrLogin = func(region string, accountID string) (registry.Credentials, error) {
+		return registry.Credentials{Server: accountID + ".dkr.ecr." + region + ".amazonaws.com", Username: "AWS", Password: "token"}, nil
+	}
+
+	resetForTest()
+	faasCmd.SetArgs([]string{"registry-login", "--ecr", "--region", "eu-west-1", "--account-id", "123456789012", "--docker-config", dir})
+	if err := faasCmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	if err != nil {
+		t.Fatalf("want config.json to be written: %s", err)
+	}
+	if !strings.Contains(string(data), "123456789012.dkr.ecr.eu-west-1.amazonaws.com") {
+		t.Errorf("want the ECR registry in config.json, got %s", data)
+	}
+}
+
+func Test_useRegistryConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-registry-login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if value, exists := os.LookupEnv("DOCKER_CONFIG"); exists {
+		defer os.Setenv("DOCKER_CONFIG", value)
+	} else {
+		defer os.Unsetenv("DOCKER_CONFIG")
+	}
+	originalStateDir := stateDir
+	defer func() { stateDir = originalStateDir }()
+	stateDir = dir
+
+	os.Unsetenv("DOCKER_CONFIG")
+	if err := useRegistryConfig("", true); err == nil || !strings.Contains(err.Error(), "run faas-cli registry-login first") {
+		t.Errorf("want an error for --registry-login before registry-login, got %v", err)
+	}
+	if err := useRegistryConfig(filepath.Join(dir, "missing"), false); err == nil {
+		t.Errorf("want an error for a --docker-config without config.json")
+	}
+
+	if _, err := registry.WriteDockerConfig(filepath.Join(dir, "docker"), registry.Credentials{Server: "gcr.io", Username: "u", Password: "p"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := useRegistryConfig("", false); err != nil || len(os.Getenv("DOCKER_CONFIG")) > 0 {
+		t.Errorf("want docker's own config kept without --registry-login, got %q, %v", os.Getenv("DOCKER_CONFIG"), err)
+	}
+	if err := useRegistryConfig("", true); err != nil || os.Getenv("DOCKER_CONFIG") != filepath.Join(dir, "docker") {
+		t.Errorf("want DOCKER_CONFIG set to the registry-login config, got %q, %v", os.Getenv("DOCKER_CONFIG"), err)
+	}
+}
@@ -5,7 +5,13 @@ package commands
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/openfaas/faas-cli/audit"
+	"github.com/openfaas/faas-cli/hooks"
+	"github.com/openfaas/faas-cli/policy"
 	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/stack"
 	"github.com/spf13/cobra"
@@ -14,10 +20,21 @@ import (
 func init() {
 	// Setup flags that are used by multiple commands (variables defined in faas.go)
 	removeCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	removeCmd.Flags().BoolVar(&forceRemove, "force", false, "Remove functions even when they are annotated with "+protectAnnotation+"=true")
+	removeCmd.Flags().StringVar(&overridePolicy, "override-policy", "", "Remove during a freeze window in "+policy.DefaultPolicyFile+", giving the REASON for the change")
+	addBatchFlags(removeCmd, &removeBatch, 0)
 
 	faasCmd.AddCommand(removeCmd)
 }
 
+// protectAnnotation marks a function which is skipped by remove unless --force is given
+const protectAnnotation = "openfaas.com/protect"
+
+var (
+	forceRemove bool
+	removeBatch batchOptions
+)
+
 // removeCmd deletes/removes OpenFaaS function containers
 var removeCmd = &cobra.Command{
 	Use: `remove FUNCTION_NAME [--gateway GATEWAY_URL]
@@ -26,12 +43,21 @@ var removeCmd = &cobra.Command{
 	Short:   "Remove deployed OpenFaaS functions",
 	Long: `Removes/deletes deployed OpenFaaS functions either via the supplied YAML config
 using the "--yaml" flag (which may contain multiple function definitions), or by
-explicitly specifying a function name.`,
+explicitly specifying a function name.
+
+Functions annotated with openfaas.com/protect=true, either in the YAML file or
+on the deployed function, are skipped and listed unless --force is given.
+
+With --batch-size the functions in the YAML file are removed a batch at a time
+and a run which is interrupted can be continued with --resume.`,
 	Example: `  faas-cli remove -f https://domain/path/myfunctions.yml
   faas-cli remove -f ./stack.yml
   faas-cli remove -f ./stack.yml --filter "*gif*"
   faas-cli remove -f ./stack.yml --regex "fn[0-9]_.*"
+  faas-cli remove url-ping --override-policy "retiring before the freeze ends"
   faas-cli remove url-ping
+  faas-cli remove -f ./stack.yml --force
+  faas-cli remove -f ./stack.yml --batch-size 10 --pause 10s
   faas-cli remove img2ansi --gateway==http://remote-site.com:8080`,
 	RunE: runDelete,
 }
@@ -54,16 +80,50 @@ func runDelete(cmd *cobra.Command, args []string) error {
 
 	gatewayAddress = getGatewayURL(gateway, defaultGateway, yamlGateway)
 
+	changePolicy, err := loadPolicy(&services)
+	if err != nil {
+		return err
+	}
+
+	var skipped []string
+
 	if len(services.Functions) > 0 {
 		if len(services.Provider.Network) == 0 {
 			services.Provider.Network = defaultNetwork
 		}
 
+		protected, err := protectedFunctions(gatewayAddress, &services)
+		if err != nil {
+			return err
+		}
+
+		var names []string
+		namespaces := map[string]string{}
 		for k, function := range services.Functions {
-			function.Name = k
-			fmt.Printf("Deleting: %s.\n", function.Name)
+			namespaces[k] = namespaceOf(function)
+			if protected[qualifiedName(k, namespaces[k])] {
+				skipped = append(skipped, k)
+				continue
+			}
+			names = append(names, k)
+		}
+		sort.Strings(names)
 
-			proxy.DeleteFunction(gatewayAddress, function.Name)
+		if removeBatch.size > 0 {
+			if err := removeInBatches(gatewayAddress, names, namespaces, changePolicy); err != nil {
+				return err
+			}
+		} else {
+			for _, name := range names {
+				if _, err := enforcePolicy(changePolicy, name, overridePolicy); err != nil {
+					return err
+				}
+
+				fmt.Printf("Deleting: %s.\n", name)
+
+				removeErr := proxy.DeleteFunctionInNamespace(gatewayAddress, name, namespaces[name])
+				recordRemove(gatewayAddress, name, removeErr)
+			}
 		}
 	} else {
 		if len(args) < 1 {
@@ -71,9 +131,107 @@ func runDelete(cmd *cobra.Command, args []string) error {
 		}
 
 		functionName = args[0]
-		fmt.Printf("Deleting: %s.\n", functionName)
-		proxy.DeleteFunction(gateway, functionName)
+		gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+
+		protected, err := protectedFunctions(gatewayAddress, nil)
+		if err != nil {
+			return err
+		}
+
+		if protected[qualifiedName(functionName, functionNamespace)] {
+			skipped = append(skipped, functionName)
+		} else {
+			if _, err := enforcePolicy(changePolicy, functionName, overridePolicy); err != nil {
+				return err
+			}
+
+			fmt.Printf("Deleting: %s.\n", functionName)
+			removeErr := proxy.DeleteFunctionInNamespace(gatewayAddress, functionName, functionNamespace)
+			recordRemove(gatewayAddress, functionName, removeErr)
+		}
+	}
+
+	if len(skipped) > 0 {
+		sort.Strings(skipped)
+		fmt.Printf("Skipped %d protected function(s): %s. Use --force to remove them.\n", len(skipped), strings.Join(skipped, ", "))
 	}
 
 	return nil
 }
+
+// removeInBatches checks every function against the policy before removing
+// any of them a batch at a time
+func removeInBatches(gatewayAddress string, names []string, namespaces map[string]string, changePolicy *policy.Policy) error {
+	for _, name := range names {
+		if _, err := enforcePolicy(changePolicy, name, overridePolicy); err != nil {
+			return err
+		}
+	}
+
+	return runBatches("remove", gatewayAddress, names, removeBatch, func(name string) error {
+		fmt.Printf("Deleting: %s.\n", name)
+
+		removeErr := proxy.DeleteFunctionInNamespace(gatewayAddress, name, namespaces[name])
+		recordRemove(gatewayAddress, name, removeErr)
+		return removeErr
+	})
+}
+
+// recordRemove records the outcome of a removal in the audit log and runs the
+// on-failure hooks when it failed
+func recordRemove(gateway string, functionName string, removeErr error) {
+	recordAudit(audit.Entry{
+		Action:   audit.Remove,
+		Gateway:  gateway,
+		Function: functionName,
+		Success:  removeErr == nil,
+		Error:    auditError(removeErr),
+	})
+
+	if removeErr != nil {
+		runHooks(hooks.Event{Hook: hooks.OnFailure, Action: audit.Remove, Gateway: gateway, Function: functionName, Error: removeErr.Error()})
+	}
+}
+
+// protectedFunctions finds the functions annotated with openfaas.com/protect
+// in the stack or on the gateway, by their names qualified with their
+// namespace. Nothing is protected with --force
+func protectedFunctions(gateway string, services *stack.Services) (map[string]bool, error) {
+	protected := map[string]bool{}
+	if forceRemove {
+		return protected, nil
+	}
+
+	namespaces := map[string]bool{}
+	if services != nil && len(services.Functions) > 0 {
+		for name, function := range services.Functions {
+			namespace := namespaceOf(function)
+			namespaces[namespace] = true
+			if function.Annotations != nil && isProtected(*function.Annotations) {
+				protected[qualifiedName(name, namespace)] = true
+			}
+		}
+	} else {
+		namespaces[functionNamespace] = true
+	}
+
+	for namespace := range namespaces {
+		deployed, err := proxy.ListFunctionAnnotationsInNamespace(gateway, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("unable to check for protected functions, use --force to remove without checking: %s", err)
+		}
+
+		for name, annotations := range deployed {
+			if isProtected(annotations) {
+				protected[qualifiedName(name, namespace)] = true
+			}
+		}
+	}
+
+	return protected, nil
+}
+
+func isProtected(annotations map[string]string) bool {
+	protect, err := strconv.ParseBool(annotations[protectAnnotation])
+	return err == nil && protect
+}
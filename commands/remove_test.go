@@ -4,14 +4,24 @@
 package commands
 
 import (
+	"io/ioutil"
 	"net/http"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/openfaas/faas-cli/config"
 	"github.com/openfaas/faas-cli/test"
 )
 
 func Test_remove(t *testing.T) {
 	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []map[string]interface{}{},
+		},
 		{
 			Method:             http.MethodDelete,
 			Uri:                "/system/functions",
@@ -29,3 +39,123 @@ func Test_remove(t *testing.T) {
 	})
 	faasCmd.Execute()
 }
+
+func Test_remove_Profile(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []map[string]interface{}{},
+		},
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	originalDir := config.DefaultDir
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-profiles")
+	defer func() {
+		os.RemoveAll(config.DefaultDir)
+		config.DefaultDir = originalDir
+	}()
+	if err := config.SaveContext("staging", s.URL); err != nil {
+		t.Fatal(err)
+	}
+
+	resetForTest()
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"remove",
+			"--gateway=staging",
+			"test-function",
+		})
+		err = faasCmd.Execute()
+	})
+	if err != nil || !strings.Contains(stdOut, "Deleting: test-function.") {
+		t.Fatalf("want the function removed from the profile's gateway, got %v:\n%s", err, stdOut)
+	}
+}
+
+func Test_remove_SkipsProtected(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []map[string]interface{}{
+				{"name": "test-function", "annotations": map[string]string{protectAnnotation: "true"}},
+			},
+		},
+	})
+	defer s.Close()
+
+	resetForTest()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"remove",
+			"--gateway=" + s.URL,
+			"test-function",
+		})
+		faasCmd.Execute()
+	})
+
+	if !strings.Contains(stdOut, "Skipped 1 protected function(s): test-function. Use --force to remove them.") {
+		t.Fatalf("Output is not as expected:\n%s", stdOut)
+	}
+	if strings.Contains(stdOut, "Deleting") {
+		t.Fatalf("Protected function should not be deleted:\n%s", stdOut)
+	}
+}
+
+func Test_remove_ForceRemovesProtected(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	resetForTest()
+	defer func() { forceRemove = false }()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"remove",
+			"--gateway=" + s.URL,
+			"--force",
+			"test-function",
+		})
+		faasCmd.Execute()
+	})
+
+	if !strings.Contains(stdOut, "Deleting: test-function.") {
+		t.Fatalf("Output is not as expected:\n%s", stdOut)
+	}
+}
+
+func Test_isProtected(t *testing.T) {
+	cases := []struct {
+		value string
+		want  bool
+	}{
+		{"true", true},
+		{"1", true},
+		{"false", false},
+		{"", false},
+		{"yes", false},
+	}
+	for _, c := range cases {
+		if got := isProtected(map[string]string{protectAnnotation: c.value}); got != c.want {
+			t.Errorf("%q: want %t, got %t", c.value, c.want, got)
+		}
+	}
+}
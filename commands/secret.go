@@ -0,0 +1,214 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+// restartAnnotation is changed on each consumer of a rotated secret so that
+// the provider performs a rolling update and mounts the new value
+const restartAnnotation = "com.openfaas.restarted-at"
+
+var (
+	secretFromFile         string
+	secretRestartConsumers bool
+	secretRotateYes        bool
+)
+
+// secretInput is read for the confirmation before a rotation is applied and
+// for the values of secrets given on STDIN
+var secretInput io.Reader = os.Stdin
+
+func init() {
+	secretCmd.PersistentFlags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+
+	secretRotateCmd.Flags().StringVar(&secretFromFile, "from-file", "", "Read the new value of the secret from this file")
+	secretRotateCmd.Flags().BoolVar(&secretRestartConsumers, "restart-consumers", false, "Perform a rolling restart of each function which uses the secret")
+	secretRotateCmd.Flags().BoolVarP(&secretRotateYes, "yes", "y", false, "Apply the rotation without asking for confirmation")
+
+	secretCmd.AddCommand(secretRotateCmd)
+	faasCmd.AddCommand(secretCmd)
+}
+
+// secretCmd groups the commands which manage secrets
+var secretCmd = &cobra.Command{
+	Use:   `secret`,
+	Short: "Manage secrets",
+	Long:  `Manage the secrets stored by the OpenFaaS provider`,
+}
+
+// secretRotateCmd updates a secret and restarts the functions which use it
+var secretRotateCmd = &cobra.Command{
+	Use: `rotate SECRET_NAME --from-file FILE [--gateway GATEWAY_URL]
+                  [--restart-consumers]
+                  [--yes]`,
+	Short: "Rotate the value of a secret",
+	Long: `Rotates a secret by replacing its value with the contents of a file. The
+functions which use the secret are found from the gateway and shown along with
+the changes before they are applied.
+
+Functions only read a secret when they start, so with --restart-consumers each
+of them is given a rolling restart after the secret is updated.`,
+	Example: `  faas-cli secret rotate db-password --from-file new.txt
+  faas-cli secret rotate db-password --from-file new.txt --restart-consumers
+  faas-cli secret rotate db-password --from-file new.txt --restart-consumers --yes`,
+	RunE: runSecretRotate,
+}
+
+func runSecretRotate(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the secret to rotate")
+	}
+	secretName := args[0]
+
+	if len(secretFromFile) == 0 {
+		return fmt.Errorf("please provide the new value of the secret with --from-file")
+	}
+
+	value, err := ioutil.ReadFile(secretFromFile)
+	if err != nil {
+		return fmt.Errorf("unable to read the new value of the secret: %s", err)
+	}
+	if len(value) == 0 {
+		return fmt.Errorf("%s is empty, refusing to rotate %s to an empty value", secretFromFile, secretName)
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+
+	functions, err := proxy.ListFunctionStatusInNamespace(gatewayAddress, functionNamespace)
+	if err != nil {
+		return err
+	}
+	consumers := secretConsumers(functions, secretName)
+	for i := range consumers {
+		if len(consumers[i].Namespace) == 0 {
+			consumers[i].Namespace = functionNamespace
+		}
+	}
+
+	printRotationPlan(secretName, len(value), consumers)
+
+	if !secretRotateYes && !confirm(secretInput, "Apply these changes?") {
+		return fmt.Errorf("secret rotation cancelled")
+	}
+
+	if err := proxy.UpdateSecret(gatewayAddress, proxy.Secret{Name: secretName, Namespace: functionNamespace, Value: string(value)}); err != nil {
+		return err
+	}
+	fmt.Printf("Updated secret: %s.\n", secretName)
+
+	if !secretRestartConsumers {
+		if len(consumers) > 0 {
+			fmt.Printf("Run again with --restart-consumers or redeploy %s to use the new value.\n", consumerNames(consumers))
+		}
+		return nil
+	}
+
+	var failed []string
+	for _, consumer := range consumers {
+		fmt.Printf("Restarting: %s.\n", consumer.Name)
+
+		statusCode, output := proxy.Deploy(gatewayAddress, restartSpec(consumer, time.Now()), true)
+		if statusCode != http.StatusOK && statusCode != http.StatusAccepted {
+			fmt.Print(output)
+			failed = append(failed, consumer.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("the secret was updated but these functions could not be restarted: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// secretConsumers finds the deployed functions which use the secret, sorted by name
+func secretConsumers(functions []proxy.FunctionStatus, secretName string) []proxy.FunctionStatus {
+	var consumers []proxy.FunctionStatus
+	for _, function := range functions {
+		for _, secret := range function.Secrets {
+			if secret == secretName {
+				consumers = append(consumers, function)
+				break
+			}
+		}
+	}
+
+	sort.Slice(consumers, func(i, j int) bool {
+		return consumers[i].Name < consumers[j].Name
+	})
+	return consumers
+}
+
+func consumerNames(consumers []proxy.FunctionStatus) string {
+	names := make([]string, len(consumers))
+	for i, consumer := range consumers {
+		names[i] = consumer.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// printRotationPlan shows the changes a rotation will make, the secret's
+// value is never printed
+func printRotationPlan(secretName string, size int, consumers []proxy.FunctionStatus) {
+	fmt.Printf("~ secret/%s (new value, %d bytes)\n", secretName, size)
+
+	action := "keeps the old value until restarted"
+	if secretRestartConsumers {
+		action = "rolling restart"
+	}
+	for _, consumer := range consumers {
+		fmt.Printf("~ function/%s (%s)\n", consumer.Name, action)
+	}
+	if len(consumers) == 0 {
+		fmt.Println("No deployed functions use this secret.")
+	}
+}
+
+// restartSpec re-deploys a function with its current spec and a changed
+// annotation so the provider replaces its replicas
+func restartSpec(function proxy.FunctionStatus, now time.Time) *proxy.DeployFunctionSpec {
+	annotations := map[string]string{}
+	for k, v := range function.Annotations {
+		annotations[k] = v
+	}
+	annotations[restartAnnotation] = now.UTC().Format(time.RFC3339)
+
+	return &proxy.DeployFunctionSpec{
+		FProcess:     function.EnvProcess,
+		FunctionName: function.Name,
+		Namespace:    function.Namespace,
+		Image:        function.Image,
+		EnvVars:      function.EnvVars,
+		Constraints:  function.Constraints,
+		Update:       true,
+		Secrets:      function.Secrets,
+		Labels:       function.Labels,
+		Annotations:  annotations,
+		FunctionResourceRequest: proxy.FunctionResourceRequest{
+			Limits:   function.Limits,
+			Requests: function.Requests,
+		},
+	}
+}
+
+// confirm asks a yes/no question, anything but y or yes is a no
+func confirm(input io.Reader, question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+
+	answer, _ := bufio.NewReader(input).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
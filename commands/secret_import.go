@@ -0,0 +1,232 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	secretImportDotEnv string
+	secretImportJSON   string
+	secretImportPrefix string
+	secretImportAsFile string
+	secretImportDryRun bool
+)
+
+func init() {
+	secretImportCmd.Flags().StringVar(&secretImportDotEnv, "from-dotenv", "", "Import each KEY=VALUE of this dotenv file")
+	secretImportCmd.Flags().StringVar(&secretImportJSON, "from-json", "", "Import each key of this JSON object, whose values must be strings")
+	secretImportCmd.Flags().StringVar(&secretImportPrefix, "prefix", "", "Prefix the name of each secret, i.e. myapp-")
+	secretImportCmd.Flags().StringVar(&secretImportAsFile, "as-file", "", "Import the entries as one secret with this name, whose value is the whole file")
+	secretImportCmd.Flags().BoolVar(&secretImportDryRun, "dry-run", false, "Print which secrets would be created or updated without changing them")
+
+	secretCmd.AddCommand(secretImportCmd)
+}
+
+var secretImportCmd = &cobra.Command{
+	Use: `import --from-dotenv FILE|--from-json FILE [--prefix PREFIX]
+                  [--as-file SECRET_NAME] [--dry-run]
+                  [--namespace NAMESPACE] [--gateway GATEWAY_URL]`,
+	Short: "Create or update a secret for each entry of a dotenv or JSON file",
+	Long: `Imports the entries of a dotenv or JSON file as secrets, i.e. to move the
+settings of an existing app to OpenFaaS. Each key becomes a secret named with
+--prefix and the key in lower case, with underscores turned into dashes, so
+DB_PASSWORD becomes myapp-db-password with --prefix myapp-.
+
+Secrets which exist are updated and the others are created. With --as-file the
+entries are imported as one secret holding the whole file, for functions which
+read their settings from a file. Entries with an empty value are skipped.
+
+The changes are printed before they are made, --dry-run only prints them.
+Values are never printed.`,
+	Example: `  faas-cli secret import --from-dotenv prod.env --prefix myapp- --dry-run
+  faas-cli secret import --from-dotenv prod.env --prefix myapp-
+  faas-cli secret import --from-json settings.json --namespace staging
+  faas-cli secret import --from-dotenv prod.env --as-file myapp-env`,
+	RunE: runSecretImport,
+}
+
+func runSecretImport(cmd *cobra.Command, args []string) error {
+	entries, err := readSecretImport(secretImportDotEnv, secretImportJSON)
+	if err != nil {
+		return err
+	}
+
+	var secrets []proxy.Secret
+	if len(secretImportAsFile) > 0 {
+		secret, err := combinedSecret(secretImportPrefix+secretImportAsFile, entries, len(secretImportJSON) > 0)
+		if err != nil {
+			return err
+		}
+		secrets = []proxy.Secret{secret}
+	} else if secrets, err = importedSecrets(secretImportPrefix, entries); err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		fmt.Println("No secrets to import.")
+		return nil
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	existing, err := proxy.ListSecrets(gatewayAddress, functionNamespace)
+	if err != nil {
+		return err
+	}
+	existingNames := map[string]bool{}
+	for _, secret := range existing {
+		existingNames[secret.Name] = true
+	}
+
+	printImportPlan(secrets, existingNames)
+	if secretImportDryRun {
+		return nil
+	}
+
+	var failed []string
+	for _, secret := range secrets {
+		if existingNames[secret.Name] {
+			err = proxy.UpdateSecret(gatewayAddress, secret)
+		} else {
+			err = proxy.CreateSecret(gatewayAddress, secret)
+		}
+		if err != nil {
+			fmt.Printf("Unable to import secret %s: %s\n", secret.Name, err)
+			failed = append(failed, secret.Name)
+			continue
+		}
+		if existingNames[secret.Name] {
+			fmt.Printf("Updated secret: %s.\n", secret.Name)
+		} else {
+			fmt.Printf("Created secret: %s.\n", secret.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to import: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// readSecretImport reads the entries of the dotenv or JSON file
+func readSecretImport(dotEnvFile string, jsonFile string) (map[string]string, error) {
+	switch {
+	case len(dotEnvFile) > 0 && len(jsonFile) > 0:
+		return nil, fmt.Errorf("give either --from-dotenv or --from-json")
+	case len(dotEnvFile) > 0:
+		data, err := ioutil.ReadFile(dotEnvFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the secrets to import: %s", err)
+		}
+		entries, err := stack.ParseDotEnv(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %s", dotEnvFile, err)
+		}
+		return entries, nil
+	case len(jsonFile) > 0:
+		data, err := ioutil.ReadFile(jsonFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the secrets to import: %s", err)
+		}
+		var parsed map[string]interface{}
+		if err := json.Unmarshal(data, &parsed); err != nil {
+			return nil, fmt.Errorf("unable to parse %s, it must be a JSON object: %s", jsonFile, err)
+		}
+		entries := map[string]string{}
+		for key, value := range parsed {
+			text, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("the value of %s in %s must be a string", key, jsonFile)
+			}
+			entries[key] = text
+		}
+		return entries, nil
+	default:
+		return nil, fmt.Errorf("please provide the secrets to import with --from-dotenv or --from-json")
+	}
+}
+
+// importedSecrets names a secret for each entry, sorted by name
+func importedSecrets(prefix string, entries map[string]string) ([]proxy.Secret, error) {
+	keys := map[string]string{}
+	var secrets []proxy.Secret
+	for key, value := range entries {
+		if len(value) == 0 {
+			fmt.Printf("Skipping %s, its value is empty.\n", key)
+			continue
+		}
+
+		name := prefix + secretNameOf(key)
+		if other, ok := keys[name]; ok {
+			return nil, fmt.Errorf("%s and %s would both be imported as secret %s", other, key, name)
+		}
+		keys[name] = key
+		secrets = append(secrets, proxy.Secret{Name: name, Namespace: functionNamespace, Value: value})
+	}
+
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+	return secrets, nil
+}
+
+// secretNameOf turns an environment variable's name into a secret name,
+// DB_PASSWORD becomes db-password
+func secretNameOf(key string) string {
+	return strings.Replace(strings.ToLower(key), "_", "-", -1)
+}
+
+// combinedSecret holds every entry in one secret, written back as JSON or as
+// sorted KEY=VALUE lines in the format of the file it was read from
+func combinedSecret(name string, entries map[string]string, asJSON bool) (proxy.Secret, error) {
+	var value string
+	if asJSON {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return proxy.Secret{}, err
+		}
+		value = string(data)
+	} else {
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		var lines []string
+		for _, key := range keys {
+			lines = append(lines, key+"="+entries[key])
+		}
+		value = strings.Join(lines, "\n")
+	}
+
+	if len(entries) == 0 {
+		return proxy.Secret{}, fmt.Errorf("there are no entries to import as secret %s", name)
+	}
+	return proxy.Secret{Name: name, Namespace: functionNamespace, Value: value}, nil
+}
+
+// printImportPlan shows which secrets will be created or updated, their
+// values are never printed
+func printImportPlan(secrets []proxy.Secret, existing map[string]bool) {
+	created, updated := 0, 0
+	for _, secret := range secrets {
+		if existing[secret.Name] {
+			fmt.Printf("~ secret/%s (new value, %d bytes)\n", secret.Name, len(secret.Value))
+			updated++
+		} else {
+			fmt.Printf("+ secret/%s (%d bytes)\n", secret.Name, len(secret.Value))
+			created++
+		}
+	}
+	fmt.Printf("%d to create, %d to update.\n", created, updated)
+}
@@ -0,0 +1,146 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func resetSecretImportFlags() {
+	secretImportDotEnv, secretImportJSON, secretImportPrefix, secretImportAsFile = "", "", "", ""
+	secretImportDryRun = false
+	functionNamespace = ""
+}
+
+// secretImportServer lists existing and records each secret created or
+// updated as "METHOD name=value"
+func secretImportServer(existing []proxy.Secret, sent *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			json.NewEncoder(w).Encode(existing)
+			return
+		}
+		var secret proxy.Secret
+		json.NewDecoder(r.Body).Decode(&secret)
+		*sent = append(*sent, r.Method+" "+secret.Name+"="+secret.Value)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+}
+
+func Test_secretImport_FromDotEnv(t *testing.T) {
+	defer resetSecretImportFlags()
+
+	dir, err := ioutil.TempDir("", "faas-cli-secret-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dotEnvFile := filepath.Join(dir, "prod.env")
+	ioutil.WriteFile(dotEnvFile, []byte("DB_PASSWORD=s3cr3t\nAPI_KEY=\"abc\"\nEMPTY=\n"), 0600)
+
+	var sent []string
+	s := secretImportServer([]proxy.Secret{{Name: "myapp-api-key"}}, &sent)
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"secret", "import", "--from-dotenv", dotEnvFile, "--prefix", "myapp-", "--dry-run", "-g", s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(sent) != 0 {
+		t.Errorf("want nothing changed with --dry-run, got %v", sent)
+	}
+	for _, line := range []string{"Skipping EMPTY, its value is empty.", "~ secret/myapp-api-key (new value, 3 bytes)", "+ secret/myapp-db-password (6 bytes)", "1 to create, 1 to update."} {
+		if !strings.Contains(stdOut, line) {
+			t.Errorf("want %q in:\n%s", line, stdOut)
+		}
+	}
+	if strings.Contains(stdOut, "s3cr3t") {
+		t.Errorf("want values left out of the plan:\n%s", stdOut)
+	}
+
+	secretImportDryRun = false
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"secret", "import", "--from-dotenv", dotEnvFile, "--prefix", "myapp-", "-g", s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if want := []string{"PUT myapp-api-key=abc", "POST myapp-db-password=s3cr3t"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("want %v, got %v", want, sent)
+	}
+}
+
+func Test_secretImport_AsFile(t *testing.T) {
+	defer resetSecretImportFlags()
+
+	dir, err := ioutil.TempDir("", "faas-cli-secret-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jsonFile := filepath.Join(dir, "settings.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"b": "2", "a": "1"}`), 0600)
+
+	var sent []string
+	s := secretImportServer(nil, &sent)
+	defer s.Close()
+
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"secret", "import", "--from-json", jsonFile, "--as-file", "settings", "-g", s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if want := []string{"POST settings={\n  \"a\": \"1\",\n  \"b\": \"2\"\n}"}; !reflect.DeepEqual(sent, want) {
+		t.Errorf("want %q, got %q", want, sent)
+	}
+}
+
+func Test_importedSecrets(t *testing.T) {
+	defer resetSecretImportFlags()
+
+	test.CaptureStdout(func() {
+		if _, err := importedSecrets("", map[string]string{"DB_HOST": "a", "db-host": "b"}); err == nil || !strings.Contains(err.Error(), "would both be imported as secret db-host") {
+			t.Errorf("want an error for keys with the same secret name, got %v", err)
+		}
+	})
+
+	secret, err := combinedSecret("env", map[string]string{"B": "2", "A": "1"}, false)
+	if err != nil || secret.Value != "A=1\nB=2" {
+		t.Errorf("want sorted KEY=VALUE lines, got %q %v", secret.Value, err)
+	}
+}
+
+func Test_readSecretImport_Errors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-secret-import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	jsonFile := filepath.Join(dir, "settings.json")
+	ioutil.WriteFile(jsonFile, []byte(`{"port": 8080}`), 0600)
+
+	if _, err := readSecretImport("", jsonFile); err == nil || !strings.Contains(err.Error(), "the value of port") {
+		t.Errorf("want an error for a number, got %v", err)
+	}
+	if _, err := readSecretImport("prod.env", jsonFile); err == nil || err.Error() != "give either --from-dotenv or --from-json" {
+		t.Errorf("want an error for both sources, got %v", err)
+	}
+	if _, err := readSecretImport("", ""); err == nil {
+		t.Errorf("want an error without a source")
+	}
+}
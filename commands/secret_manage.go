@@ -0,0 +1,186 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/spf13/cobra"
+)
+
+var (
+	secretValueFile string
+	secretLiteral   string
+	secretTrim      bool
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{secretCreateCmd, secretUpdateCmd} {
+		cmd.Flags().StringVar(&secretValueFile, "from-file", "", "Read the value of the secret from this file")
+		cmd.Flags().StringVar(&secretLiteral, "from-literal", "", "Value of the secret, which is kept in the shell's history")
+		cmd.Flags().BoolVar(&secretTrim, "trim", true, "Trim whitespace, such as a trailing newline, from the start and end of the value")
+	}
+
+	secretCmd.AddCommand(secretCreateCmd)
+	secretCmd.AddCommand(secretUpdateCmd)
+	secretCmd.AddCommand(secretListCmd)
+	secretCmd.AddCommand(secretRemoveCmd)
+}
+
+var secretCreateCmd = &cobra.Command{
+	Use: `create SECRET_NAME [--from-file FILE|--from-literal VALUE]
+                  [--namespace NAMESPACE] [--gateway GATEWAY_URL]`,
+	Short: "Create a secret",
+	Long: `Creates a secret for the functions which list it in their secrets. The value
+is read from --from-file, --from-literal or STDIN, and is never printed.`,
+	Example: `  faas-cli secret create db-password --from-file ./db-password.txt
+  echo -n $DB_PASSWORD | faas-cli secret create db-password
+  faas-cli secret create api-key --from-literal s3cr3t --namespace staging`,
+	RunE: runSecretCreate,
+}
+
+var secretUpdateCmd = &cobra.Command{
+	Use: `update SECRET_NAME [--from-file FILE|--from-literal VALUE]
+                  [--namespace NAMESPACE] [--gateway GATEWAY_URL]`,
+	Short: "Update the value of a secret",
+	Long: `Replaces the value of an existing secret, read from --from-file,
+--from-literal or STDIN. Functions read a secret when they start, use
+"faas-cli secret rotate --restart-consumers" to restart those which use it.`,
+	Example: `  faas-cli secret update db-password --from-file ./db-password.txt
+  echo -n $DB_PASSWORD | faas-cli secret update db-password`,
+	RunE: runSecretUpdate,
+}
+
+var secretListCmd = &cobra.Command{
+	Use:     `list [--namespace NAMESPACE] [--gateway GATEWAY_URL]`,
+	Aliases: []string{"ls"},
+	Short:   "List the names of secrets",
+	Example: `  faas-cli secret list
+  faas-cli secret list --namespace staging`,
+	RunE: runSecretList,
+}
+
+var secretRemoveCmd = &cobra.Command{
+	Use:     `remove SECRET_NAME... [--namespace NAMESPACE] [--gateway GATEWAY_URL]`,
+	Aliases: []string{"rm"},
+	Short:   "Remove secrets",
+	Example: `  faas-cli secret remove db-password
+  faas-cli secret remove db-password api-key --namespace staging`,
+	RunE: runSecretRemove,
+}
+
+func runSecretCreate(cmd *cobra.Command, args []string) error {
+	secret, err := secretFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if err := proxy.CreateSecret(getGatewayURL(gateway, defaultGateway, ""), secret); err != nil {
+		return err
+	}
+	fmt.Printf("Created secret: %s.\n", secret.Name)
+	return nil
+}
+
+func runSecretUpdate(cmd *cobra.Command, args []string) error {
+	secret, err := secretFromArgs(args)
+	if err != nil {
+		return err
+	}
+
+	if err := proxy.UpdateSecret(getGatewayURL(gateway, defaultGateway, ""), secret); err != nil {
+		return err
+	}
+	fmt.Printf("Updated secret: %s.\n", secret.Name)
+	return nil
+}
+
+func runSecretList(cmd *cobra.Command, args []string) error {
+	secrets, err := proxy.ListSecrets(getGatewayURL(gateway, defaultGateway, ""), functionNamespace)
+	if err != nil {
+		return err
+	}
+	if len(secrets) == 0 {
+		fmt.Println("No secrets found.")
+		return nil
+	}
+
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].Name < secrets[j].Name
+	})
+
+	table := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(table, "NAME")
+	for _, secret := range secrets {
+		fmt.Fprintln(table, secret.Name)
+	}
+	return table.Flush()
+}
+
+func runSecretRemove(cmd *cobra.Command, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("please provide the name of the secret to remove")
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, "")
+	var failed []string
+	for _, name := range args {
+		if err := proxy.RemoveSecret(gatewayAddress, proxy.Secret{Name: name, Namespace: functionNamespace}); err != nil {
+			fmt.Printf("Unable to remove secret %s: %s\n", name, err)
+			failed = append(failed, name)
+			continue
+		}
+		fmt.Printf("Removed secret: %s.\n", name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to remove: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// secretFromArgs gives the secret named by the first argument with its value
+// from --from-file, --from-literal or STDIN
+func secretFromArgs(args []string) (proxy.Secret, error) {
+	if len(args) < 1 {
+		return proxy.Secret{}, fmt.Errorf("please provide the name of the secret")
+	}
+	name := args[0]
+
+	var value []byte
+	var err error
+	switch {
+	case len(secretValueFile) > 0 && len(secretLiteral) > 0:
+		return proxy.Secret{}, fmt.Errorf("give either --from-file or --from-literal")
+	case len(secretLiteral) > 0:
+		value = []byte(secretLiteral)
+	case len(secretValueFile) > 0:
+		if value, err = ioutil.ReadFile(secretValueFile); err != nil {
+			return proxy.Secret{}, fmt.Errorf("unable to read the value of the secret: %s", err)
+		}
+	default:
+		if file, ok := secretInput.(*os.File); ok {
+			if stat, _ := file.Stat(); stat != nil && (stat.Mode()&os.ModeCharDevice) != 0 {
+				fmt.Fprintf(os.Stderr, "Reading the value of %s from STDIN - hit (Control + D) to stop.\n", name)
+			}
+		}
+		if value, err = ioutil.ReadAll(secretInput); err != nil {
+			return proxy.Secret{}, fmt.Errorf("unable to read standard input: %s", err)
+		}
+	}
+
+	if secretTrim {
+		value = []byte(strings.TrimSpace(string(value)))
+	}
+	if len(value) == 0 {
+		return proxy.Secret{}, fmt.Errorf("the value of secret %s is empty", name)
+	}
+	return proxy.Secret{Name: name, Namespace: functionNamespace, Value: string(value)}, nil
+}
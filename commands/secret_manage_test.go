@@ -0,0 +1,116 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func resetSecretManageFlags() {
+	secretValueFile, secretLiteral, functionNamespace = "", "", ""
+	secretTrim = true
+	secretInput = os.Stdin
+}
+
+func Test_secretCreate_FromStdin(t *testing.T) {
+	defer resetSecretManageFlags()
+
+	var method string
+	var sent proxy.Secret
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer s.Close()
+
+	secretInput = strings.NewReader("s3cr3t\n")
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"secret", "create", "db-password", "-g", s.URL, "--namespace", "staging"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if method != http.MethodPost || sent.Name != "db-password" || sent.Namespace != "staging" || sent.Value != "s3cr3t" {
+		t.Errorf("want the trimmed value created, got %s %+v", method, sent)
+	}
+	if strings.Contains(stdOut, "s3cr3t") || !strings.Contains(stdOut, "Created secret: db-password.") {
+		t.Errorf("want the secret created without printing its value, got %q", stdOut)
+	}
+}
+
+func Test_secretFromArgs(t *testing.T) {
+	defer resetSecretManageFlags()
+
+	secretLiteral, secretValueFile = "a", "b"
+	if _, err := secretFromArgs([]string{"api-key"}); err == nil || err.Error() != "give either --from-file or --from-literal" {
+		t.Errorf("want an error for both sources, got %v", err)
+	}
+
+	secretValueFile = ""
+	secretLiteral = "  "
+	if _, err := secretFromArgs([]string{"api-key"}); err == nil || err.Error() != "the value of secret api-key is empty" {
+		t.Errorf("want an error for an empty value, got %v", err)
+	}
+
+	secretTrim = false
+	if secret, err := secretFromArgs([]string{"api-key"}); err != nil || secret.Value != "  " {
+		t.Errorf("want the value kept as it is with --trim=false, got %q, %v", secret.Value, err)
+	}
+}
+
+func Test_secretListAndRemove(t *testing.T) {
+	defer resetSecretManageFlags()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []proxy.Secret{{Name: "token"}, {Name: "db-password"}},
+		},
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusNotFound,
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"secret", "list", "-g", s.URL})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if stdOut != "NAME\ndb-password\ntoken\n" {
+		t.Errorf("want the names sorted, got %q", stdOut)
+	}
+
+	var err error
+	stdOut = test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"secret", "remove", "db-password", "missing", "-g", s.URL})
+		err = faasCmd.Execute()
+	})
+	if err == nil || err.Error() != "unable to remove: missing" {
+		t.Errorf("want an error for the missing secret, got %v", err)
+	}
+	if !strings.Contains(stdOut, "Removed secret: db-password.") || !strings.Contains(stdOut, "Unable to remove secret missing: secret missing not found") {
+		t.Errorf("unexpected output: %q", stdOut)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+var secretTestFunctions = []map[string]interface{}{
+	{"name": "reader", "image": "alexellis/reader:0.1", "secrets": []string{"db-password", "api-key"}},
+	{"name": "other", "image": "alexellis/other:0.1", "secrets": []string{"api-key"}},
+}
+
+func writeSecretFile(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "faas-cli-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "new.txt")
+	if err := ioutil.WriteFile(file, []byte("s3cr3t-value"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file, func() { os.RemoveAll(dir) }
+}
+
+func resetSecretFlags() {
+	secretFromFile = ""
+	secretRestartConsumers = false
+	secretRotateYes = false
+	secretInput = os.Stdin
+}
+
+func Test_secretRotate_RestartsConsumers(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       secretTestFunctions,
+		},
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusOK,
+		},
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+	})
+	defer s.Close()
+
+	file, cleanup := writeSecretFile(t)
+	defer cleanup()
+
+	resetForTest()
+	defer resetSecretFlags()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"secret", "rotate", "db-password",
+			"--gateway=" + s.URL,
+			"--from-file=" + file,
+			"--restart-consumers",
+			"--yes",
+		})
+		faasCmd.Execute()
+	})
+
+	for _, want := range []string{
+		"~ secret/db-password (new value, 12 bytes)",
+		"~ function/reader (rolling restart)",
+		"Updated secret: db-password.",
+		"Restarting: reader.",
+	} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in output:\n%s", want, stdOut)
+		}
+	}
+	if strings.Contains(stdOut, "s3cr3t-value") {
+		t.Errorf("the secret's value must not be printed:\n%s", stdOut)
+	}
+	if strings.Contains(stdOut, "function/other") {
+		t.Errorf("functions without the secret should not be restarted:\n%s", stdOut)
+	}
+}
+
+func Test_secretRotate_InNamespace(t *testing.T) {
+	var restarted struct {
+		Service   string `json:"service"`
+		Namespace string `json:"namespace"`
+	}
+	var rotated proxy.Secret
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/system/functions":
+			if r.URL.Query().Get("namespace") != "staging" {
+				t.Errorf("want the functions listed in staging, got %s", r.URL.RawQuery)
+			}
+			json.NewEncoder(w).Encode(secretTestFunctions)
+		case r.URL.Path == "/system/secrets":
+			json.NewDecoder(r.Body).Decode(&rotated)
+		case r.URL.Path == "/system/functions":
+			json.NewDecoder(r.Body).Decode(&restarted)
+			w.WriteHeader(http.StatusAccepted)
+		}
+	}))
+	defer s.Close()
+
+	file, cleanup := writeSecretFile(t)
+	defer cleanup()
+
+	resetForTest()
+	defer resetSecretFlags()
+	defer func() { functionNamespace = "" }()
+
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"secret", "rotate", "db-password",
+			"--gateway=" + s.URL,
+			"--from-file=" + file,
+			"--namespace=staging",
+			"--restart-consumers",
+			"--yes",
+		})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if rotated.Namespace != "staging" {
+		t.Errorf("want the secret rotated in staging, got %q", rotated.Namespace)
+	}
+	if restarted.Service != "reader" || restarted.Namespace != "staging" {
+		t.Errorf("want reader restarted in staging, got %q in %q", restarted.Service, restarted.Namespace)
+	}
+}
+
+func Test_secretRotate_Cancelled(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       secretTestFunctions,
+		},
+	})
+	defer s.Close()
+
+	file, cleanup := writeSecretFile(t)
+	defer cleanup()
+
+	resetForTest()
+	defer resetSecretFlags()
+	secretInput = strings.NewReader("n\n")
+
+	var err error
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{
+			"secret", "rotate", "db-password",
+			"--gateway=" + s.URL,
+			"--from-file=" + file,
+		})
+		err = faasCmd.Execute()
+	})
+
+	if err == nil || err.Error() != "secret rotation cancelled" {
+		t.Fatalf("want the rotation to be cancelled, got: %v", err)
+	}
+	if !strings.Contains(stdOut, "~ function/reader (keeps the old value until restarted)") {
+		t.Errorf("want the plan in output:\n%s", stdOut)
+	}
+}
+
+func Test_secretConsumers(t *testing.T) {
+	functions := []proxy.FunctionStatus{
+		{Name: "zeta", Secrets: []string{"db-password"}},
+		{Name: "alpha", Secrets: []string{"other", "db-password"}},
+		{Name: "beta"},
+	}
+
+	consumers := secretConsumers(functions, "db-password")
+	if got := consumerNames(consumers); got != "alpha, zeta" {
+		t.Fatalf("want alpha, zeta, got %s", got)
+	}
+}
+
+func Test_restartSpec(t *testing.T) {
+	function := proxy.FunctionStatus{
+		Name:        "reader",
+		Namespace:   "staging",
+		Image:       "alexellis/reader:0.1",
+		EnvProcess:  "cat",
+		Secrets:     []string{"db-password"},
+		Annotations: map[string]string{"topic": "payments"},
+	}
+	now := time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	spec := restartSpec(function, now)
+
+	if spec.FunctionName != "reader" || spec.Image != "alexellis/reader:0.1" || spec.FProcess != "cat" || !spec.Update {
+		t.Fatalf("spec does not match the deployed function: %+v", spec)
+	}
+	if spec.Namespace != "staging" {
+		t.Errorf("want the function restarted in its namespace, got %q", spec.Namespace)
+	}
+	if spec.Annotations["topic"] != "payments" {
+		t.Errorf("existing annotations should be kept: %v", spec.Annotations)
+	}
+	if spec.Annotations[restartAnnotation] != "2018-03-01T12:00:00Z" {
+		t.Errorf("want the restart annotation set, got %v", spec.Annotations)
+	}
+	if _, ok := function.Annotations[restartAnnotation]; ok {
+		t.Errorf("the deployed function's annotations should not be modified")
+	}
+}
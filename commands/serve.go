@@ -0,0 +1,351 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/jsonrpc"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// defaultServeSocket is where serve --json-rpc listens by default
+const defaultServeSocket = "~/.openfaas/faas-cli.sock"
+
+var (
+	serveJSONRPC bool
+	serveSocket  string
+)
+
+func init() {
+	serveCmd.Flags().BoolVar(&serveJSONRPC, "json-rpc", false, "Serve JSON-RPC 2.0 with Content-Length framing, as used by language servers")
+	serveCmd.Flags().StringVar(&serveSocket, "socket", defaultServeSocket, "Unix socket to listen on")
+
+	faasCmd.AddCommand(serveCmd)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   `serve --json-rpc [--socket PATH]`,
+	Short: "Serve the CLI's operations to editors over a local socket",
+	Long: `Runs until interrupted, serving JSON-RPC 2.0 on a unix socket so that editor
+plugins can drive the CLI without starting a process for each request. Messages
+are framed with a Content-Length header as in the Language Server Protocol.
+
+Methods:
+
+  stack/parse     {"file", "regex", "filter"} gives the parsed stack, which is
+                  kept until the file changes
+  templates/list  {} gives the languages in the template folder
+  invoke          {"name", "body", "gateway", "file", "contentType", "query"}
+                  gives the function's response
+  build, deploy   {"file", "regex", "filter", "args"} run the command and give
+                  its exit code and output
+  shutdown        {} stops the server`,
+	Example: `  faas-cli serve --json-rpc
+  faas-cli serve --json-rpc --socket /tmp/faas-cli.sock`,
+	RunE: runServe,
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	if !serveJSONRPC {
+		return fmt.Errorf("give --json-rpc, it is the only protocol served")
+	}
+
+	socket, err := homedir.Expand(serveSocket)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return err
+	}
+
+	// A socket left behind by a server which didn't stop cleanly
+	if _, err := net.Dial("unix", socket); err == nil {
+		return fmt.Errorf("another server is listening on %s", socket)
+	}
+	os.Remove(socket)
+
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(socket)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	server := newRPCServer(func() { listener.Close() })
+	fmt.Printf("Serving JSON-RPC on %s\n", socket)
+	if err := server.Serve(listener); err != nil && !isClosedListener(err) {
+		return err
+	}
+	return nil
+}
+
+func isClosedListener(err error) bool {
+	opErr, ok := err.(*net.OpError)
+	return ok && opErr.Op == "accept"
+}
+
+// rpcMethods holds the state shared between calls
+type rpcMethods struct {
+	// lock serializes calls as the commands rely on package state
+	lock sync.Mutex
+
+	stacks   map[string]parsedStack
+	shutdown func()
+}
+
+// parsedStack is a stack kept until its file changes
+type parsedStack struct {
+	modTime time.Time
+	result  interface{}
+}
+
+type stackParams struct {
+	File   string   `json:"file"`
+	Regex  string   `json:"regex"`
+	Filter string   `json:"filter"`
+	Args   []string `json:"args"`
+}
+
+type invokeParams struct {
+	Name        string   `json:"name"`
+	Body        string   `json:"body"`
+	Gateway     string   `json:"gateway"`
+	File        string   `json:"file"`
+	ContentType string   `json:"contentType"`
+	Query       []string `json:"query"`
+}
+
+// commandResult is the outcome of a command run for build or deploy
+type commandResult struct {
+	ExitCode int    `json:"exitCode"`
+	Output   string `json:"output"`
+}
+
+// runCLI runs the CLI as a child process so that a failing build can't stop
+// the server, the exit code and combined output are returned
+var runCLI = func(args []string) (int, []byte, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	out, err := exec.Command(executable, args...).CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.Sys().(syscall.WaitStatus).ExitStatus(), out, nil
+	}
+	return 0, out, err
+}
+
+func newRPCServer(shutdown func()) *jsonrpc.Server {
+	methods := &rpcMethods{stacks: map[string]parsedStack{}, shutdown: shutdown}
+
+	server := jsonrpc.NewServer()
+	server.Handle("stack/parse", methods.locked(methods.parseStack))
+	server.Handle("templates/list", methods.locked(methods.listTemplates))
+	server.Handle("invoke", methods.locked(methods.invoke))
+	server.Handle("build", methods.locked(methods.command("build")))
+	server.Handle("deploy", methods.locked(methods.command("deploy")))
+	server.Handle("shutdown", func(params json.RawMessage) (interface{}, error) {
+		methods.shutdown()
+		return true, nil
+	})
+	return server
+}
+
+func (m *rpcMethods) locked(handler jsonrpc.Handler) jsonrpc.Handler {
+	return func(params json.RawMessage) (interface{}, error) {
+		m.lock.Lock()
+		defer m.lock.Unlock()
+		return handler(params)
+	}
+}
+
+func decodeParams(params json.RawMessage, value interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, value); err != nil {
+		return &jsonrpc.Error{Code: jsonrpc.InvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (m *rpcMethods) parseStack(params json.RawMessage) (interface{}, error) {
+	var p stackParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.File) == 0 {
+		return nil, &jsonrpc.Error{Code: jsonrpc.InvalidParams, Message: "file is required"}
+	}
+
+	// Local files are parsed again only once they change
+	key := p.File + "\x00" + p.Regex + "\x00" + p.Filter
+	info, statErr := os.Stat(p.File)
+	if statErr == nil {
+		if cached, ok := m.stacks[key]; ok && cached.modTime.Equal(info.ModTime()) {
+			return cached.result, nil
+		}
+	}
+
+	services, err := stack.ParseYAMLFile(p.File, p.Regex, p.Filter)
+	if err != nil {
+		return nil, err
+	}
+	result, err := jsonCompatible(services)
+	if err != nil {
+		return nil, err
+	}
+
+	if statErr == nil {
+		m.stacks[key] = parsedStack{modTime: info.ModTime(), result: result}
+	}
+	return result, nil
+}
+
+func (m *rpcMethods) listTemplates(params json.RawMessage) (interface{}, error) {
+	languages := []string{}
+
+	folders, err := ioutil.ReadDir(stack.TemplateDirectory)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, folder := range folders {
+		if folder.IsDir() && stack.IsValidTemplate(folder.Name()) {
+			languages = append(languages, folder.Name())
+		}
+	}
+	sort.Strings(languages)
+
+	return map[string]interface{}{"languages": languages}, nil
+}
+
+func (m *rpcMethods) invoke(params json.RawMessage) (interface{}, error) {
+	var p invokeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Name) == 0 {
+		return nil, &jsonrpc.Error{Code: jsonrpc.InvalidParams, Message: "name is required"}
+	}
+
+	var yamlGateway string
+	var auth *stack.FunctionAuth
+	if len(p.File) > 0 {
+		services, err := stack.ParseYAMLFile(p.File, "", "")
+		if err != nil {
+			return nil, err
+		}
+		yamlGateway = services.Provider.GatewayURL
+		if function, ok := services.Functions[p.Name]; ok {
+			if auth, err = invokeAuth(function.Auth, stack.FunctionAuth{}); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	contentType := p.ContentType
+	if len(contentType) == 0 {
+		contentType = "text/plain"
+	}
+
+	gatewayAddress := getGatewayURL(p.Gateway, defaultGateway, yamlGateway)
+	body := []byte(p.Body)
+	response, err := proxy.InvokeFunctionWithAuth(gatewayAddress, p.Name, &body, contentType, p.Query, auth)
+	recordInvoke(gatewayAddress, p.Name, err)
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]string{"body": ""}
+	if response != nil {
+		result["body"] = string(*response)
+	}
+	return result, nil
+}
+
+// command runs the CLI command with the stack and extra args
+func (m *rpcMethods) command(name string) jsonrpc.Handler {
+	return func(params json.RawMessage) (interface{}, error) {
+		var p stackParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+
+		args := []string{name}
+		if len(p.File) > 0 {
+			args = append(args, "--yaml", p.File)
+		}
+		if len(p.Regex) > 0 {
+			args = append(args, "--regex", p.Regex)
+		}
+		if len(p.Filter) > 0 {
+			args = append(args, "--filter", p.Filter)
+		}
+		args = append(args, p.Args...)
+
+		exitCode, out, err := runCLI(args)
+		if err != nil {
+			return nil, err
+		}
+		return commandResult{ExitCode: exitCode, Output: string(bytes.TrimSpace(out))}, nil
+	}
+}
+
+// jsonCompatible gives a value as it is written to YAML, with YAML's field
+// names, in a form which can be encoded as JSON
+func jsonCompatible(value interface{}) (interface{}, error) {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if err := yaml.Unmarshal(out, &parsed); err != nil {
+		return nil, err
+	}
+	return stringKeys(parsed), nil
+}
+
+// stringKeys converts the maps read from YAML to maps with string keys
+func stringKeys(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			converted[fmt.Sprintf("%v", key)] = stringKeys(item)
+		}
+		return converted
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringKeys(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
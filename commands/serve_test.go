@@ -0,0 +1,146 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/jsonrpc"
+	"github.com/openfaas/faas-cli/test"
+)
+
+// rpcClient calls the server over an in-memory connection
+type rpcClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	id     int
+}
+
+func newRPCClient(t *testing.T) *rpcClient {
+	client, server := net.Pipe()
+	go newRPCServer(func() {}).ServeConn(server)
+	return &rpcClient{conn: client, reader: bufio.NewReader(client)}
+}
+
+func (c *rpcClient) call(t *testing.T, method string, params interface{}) jsonrpc.Response {
+	c.id++
+	raw, _ := json.Marshal(params)
+	id := json.RawMessage([]byte{byte('0' + c.id)})
+	if err := jsonrpc.WriteMessage(c.conn, jsonrpc.Request{Version: jsonrpc.Version, ID: &id, Method: method, Params: raw}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := jsonrpc.ReadMessage(c.reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var response jsonrpc.Response
+	if err := json.Unmarshal(data, &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+func Test_serve_ParseStackIsCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(stackFile, []byte("provider:\n  name: faas\nfunctions:\n  api:\n    image: api:0.1\n"), 0600)
+	modTime := time.Now().Add(-time.Hour)
+	os.Chtimes(stackFile, modTime, modTime)
+
+	client := newRPCClient(t)
+	defer client.conn.Close()
+
+	response := client.call(t, "stack/parse", map[string]string{"file": stackFile})
+	if response.Error != nil {
+		t.Fatalf("unexpected error: %+v", response.Error)
+	}
+	want := map[string]interface{}{
+		"provider":  map[string]interface{}{"name": "faas"},
+		"functions": map[string]interface{}{"api": map[string]interface{}{"image": "api:0.1"}},
+	}
+	if !reflect.DeepEqual(response.Result, want) {
+		t.Fatalf("want %v, got: %v", want, response.Result)
+	}
+
+	// An unchanged file is not parsed again
+	ioutil.WriteFile(stackFile, []byte("provider:\n  name: faas\nfunctions:\n  api:\n    image: api:0.2\n"), 0600)
+	os.Chtimes(stackFile, modTime, modTime)
+	if response := client.call(t, "stack/parse", map[string]string{"file": stackFile}); !reflect.DeepEqual(response.Result, want) {
+		t.Errorf("want the cached stack, got: %v", response.Result)
+	}
+
+	os.Chtimes(stackFile, time.Now(), time.Now())
+	if response := client.call(t, "stack/parse", map[string]string{"file": stackFile}); reflect.DeepEqual(response.Result, want) {
+		t.Errorf("want the changed stack parsed again, got: %v", response.Result)
+	}
+
+	if response := client.call(t, "stack/parse", map[string]string{}); response.Error == nil || response.Error.Code != jsonrpc.InvalidParams {
+		t.Errorf("want invalid params without a file, got: %+v", response)
+	}
+}
+
+func Test_serve_BuildRunsCommand(t *testing.T) {
+	original := runCLI
+	defer func() { runCLI = original }()
+
+	var got []string
+	runCLI = func(args []string) (int, []byte, error) {
+		got = args
+		return 1, []byte("build failed\n"), nil
+	}
+
+	client := newRPCClient(t)
+	defer client.conn.Close()
+
+	response := client.call(t, "build", map[string]interface{}{"file": "stack.yml", "filter": "api*", "args": []string{"--parallel", "2"}})
+	if response.Error != nil {
+		t.Fatalf("unexpected error: %+v", response.Error)
+	}
+
+	if want := []string{"build", "--yaml", "stack.yml", "--filter", "api*", "--parallel", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("want args %v, got: %v", want, got)
+	}
+	if want := map[string]interface{}{"exitCode": float64(1), "output": "build failed"}; !reflect.DeepEqual(response.Result, want) {
+		t.Errorf("want %v, got: %v", want, response.Result)
+	}
+}
+
+func Test_serve_Invoke(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/function/echo",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       "hello",
+		},
+	})
+	defer s.Close()
+
+	client := newRPCClient(t)
+	defer client.conn.Close()
+
+	response := client.call(t, "invoke", map[string]string{"name": "echo", "body": "hi", "gateway": s.URL})
+	if response.Error != nil {
+		t.Fatalf("unexpected error: %+v", response.Error)
+	}
+	// The mock server encodes its response as JSON
+	if body := response.Result.(map[string]interface{})["body"]; body != `"hello"` {
+		t.Errorf("want the function's response, got: %v", body)
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+var stackEditAll bool
+
+func init() {
+	stackLabelCmd.Flags().BoolVar(&stackEditAll, "all", false, "Edit every function in the YAML file")
+	stackAnnotateCmd.Flags().BoolVar(&stackEditAll, "all", false, "Edit every function in the YAML file")
+
+	stackCmd.AddCommand(stackLabelCmd)
+	stackCmd.AddCommand(stackAnnotateCmd)
+	faasCmd.AddCommand(stackCmd)
+}
+
+var stackCmd = &cobra.Command{
+	Use:   `stack`,
+	Short: "Edit or resolve the functions in a stack file",
+}
+
+var stackLabelCmd = &cobra.Command{
+	Use:   `label [FUNCTION_NAME ...] KEY=VALUE ... KEY- ... -f YAML_FILE [--all]`,
+	Short: "Add, update or remove labels of functions in a stack file",
+	Long: `Sets each KEY=VALUE and removes each KEY- in the labels of the named
+functions, the functions matched by --regex or --filter, or every function with
+--all. The rest of the YAML file, including its comments, is left as it is.`,
+	Example: `  faas-cli stack label --all team=payments -f ./stack.yml
+  faas-cli stack label api web tier=frontend legacy- -f ./stack.yml
+  faas-cli stack label --filter "billing-*" com.openfaas.scale.min=2 -f ./stack.yml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStackEdit("labels", args)
+	},
+}
+
+var stackAnnotateCmd = &cobra.Command{
+	Use:   `annotate [FUNCTION_NAME ...] KEY=VALUE ... KEY- ... -f YAML_FILE [--all]`,
+	Short: "Add, update or remove annotations of functions in a stack file",
+	Long: `Sets each KEY=VALUE and removes each KEY- in the annotations of the named
+functions, the functions matched by --regex or --filter, or every function with
+--all. The rest of the YAML file, including its comments, is left as it is.`,
+	Example: `  faas-cli stack annotate --all team=payments -f ./stack.yml
+  faas-cli stack annotate cron-job topic=cron-function schedule- -f ./stack.yml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runStackEdit("annotations", args)
+	},
+}
+
+var stackEditKey = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+
+func runStackEdit(field string, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file to edit with --yaml")
+	}
+	if yamlFile == stack.StdinFile {
+		return fmt.Errorf("the stack is edited in place, it cannot be read from stdin")
+	}
+
+	var names []string
+	set := map[string]string{}
+	var remove []string
+	for _, arg := range args {
+		switch {
+		case strings.Contains(arg, "="):
+			parts := strings.SplitN(arg, "=", 2)
+			if !stackEditKey.MatchString(parts[0]) {
+				return fmt.Errorf("%q is not a valid key", parts[0])
+			}
+			set[parts[0]] = parts[1]
+		case strings.HasSuffix(arg, "-"):
+			remove = append(remove, strings.TrimSuffix(arg, "-"))
+		default:
+			names = append(names, arg)
+		}
+	}
+	if len(set) == 0 && len(remove) == 0 {
+		return fmt.Errorf("give at least one KEY=VALUE to set or KEY- to remove")
+	}
+
+	selected, err := selectStackFunctions(names)
+	if err != nil {
+		return err
+	}
+
+	stackBytes, err := ioutil.ReadFile(yamlFile)
+	if err != nil {
+		return err
+	}
+
+	updated := string(stackBytes)
+	for _, name := range selected {
+		if updated, err = editStackMap(updated, name, field, set, remove); err != nil {
+			return err
+		}
+	}
+
+	// The edit is only written when the result still parses
+	if _, err := stack.ParseYAMLData([]byte(updated), "", ""); err != nil {
+		return fmt.Errorf("the edited YAML file would not be valid: %s", err)
+	}
+	if err := ioutil.WriteFile(yamlFile, []byte(updated), 0600); err != nil {
+		return fmt.Errorf("error writing stack file %s", err)
+	}
+
+	fmt.Printf("Updated the %s of %d function(s) in %s: %s\n", field, len(selected), yamlFile, strings.Join(selected, ", "))
+	return nil
+}
+
+// selectStackFunctions gives the names of the functions to edit, sorted
+func selectStackFunctions(names []string) ([]string, error) {
+	filtered := len(regex) > 0 || len(filter) > 0
+	selections := 0
+	for _, chosen := range []bool{stackEditAll, len(names) > 0, filtered} {
+		if chosen {
+			selections++
+		}
+	}
+	if selections != 1 {
+		return nil, fmt.Errorf("choose the functions to edit with their names, --regex, --filter or --all")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(names) > 0 {
+		for _, name := range names {
+			if _, ok := services.Functions[name]; !ok {
+				return nil, fmt.Errorf("function %s was not found in %s", name, yamlFile)
+			}
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+
+	var selected []string
+	for name := range services.Functions {
+		selected = append(selected, name)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no functions in %s match the filter", yamlFile)
+	}
+	sort.Strings(selected)
+	return selected, nil
+}
+
+// editStackMap sets and removes keys of a map field, such as labels, in a
+// function's entry and leaves every other line of the stack file as it is
+func editStackMap(stackYAML string, name string, field string, set map[string]string, remove []string) (string, error) {
+	lines := strings.Split(stackYAML, "\n")
+	start, end, err := stackEntryBounds(lines, name)
+	if err != nil {
+		return "", err
+	}
+
+	fieldIndent := -1
+	fieldLine := -1
+	inlineEmpty := false
+	fieldKey := regexp.MustCompile(`^\s*` + regexp.QuoteMeta(field) + `:\s*(.*)$`)
+	for i := start + 1; i < end; i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if fieldIndent == -1 {
+			fieldIndent = lineIndent(lines[i])
+		}
+		if lineIndent(lines[i]) != fieldIndent {
+			continue
+		}
+
+		if match := fieldKey.FindStringSubmatch(lines[i]); match != nil {
+			value := strings.TrimSpace(match[1])
+			inlineEmpty = strings.HasPrefix(value, "{}")
+			if len(value) > 0 && !strings.HasPrefix(value, "#") && !inlineEmpty {
+				return "", fmt.Errorf("the %s of %s are written inline, move them onto their own lines first", field, name)
+			}
+			fieldLine = i
+			break
+		}
+	}
+	if fieldIndent == -1 {
+		return "", fmt.Errorf("the entry of %s has no fields", name)
+	}
+
+	// Each key of the map starts a line below the field, a value may
+	// continue on lines indented further
+	var keyLines []int
+	blockEnd := fieldLine
+	keyIndent := fieldIndent + 2
+	if fieldLine > -1 {
+		for i := fieldLine + 1; i < end; i++ {
+			trimmed := strings.TrimSpace(lines[i])
+			if len(trimmed) == 0 || strings.HasPrefix(trimmed, "#") {
+				continue
+			}
+			if lineIndent(lines[i]) <= fieldIndent {
+				break
+			}
+			if len(keyLines) == 0 {
+				keyIndent = lineIndent(lines[i])
+			}
+			if lineIndent(lines[i]) == keyIndent {
+				keyLines = append(keyLines, i)
+			}
+			blockEnd = i
+		}
+	}
+
+	removed := map[int]bool{}
+	found := map[string]bool{}
+	removing := false
+	for i := fieldLine + 1; fieldLine > -1 && i <= blockEnd; i++ {
+		if lineIndent(lines[i]) != keyIndent || len(strings.TrimSpace(lines[i])) == 0 {
+			// Continuation lines go with the key above them
+			removed[i] = removing && len(strings.TrimSpace(lines[i])) > 0
+			continue
+		}
+
+		key := mapLineKey(lines[i])
+		removing = false
+		for _, removeKey := range remove {
+			if key == removeKey {
+				removing = true
+			}
+		}
+		if removing {
+			removed[i] = true
+			continue
+		}
+		if value, ok := set[key]; ok {
+			lines[i] = strings.Repeat(" ", keyIndent) + yamlScalar(key) + ": " + yamlScalar(value)
+			found[key] = true
+		}
+	}
+
+	var added []string
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !found[key] {
+			added = append(added, strings.Repeat(" ", keyIndent)+yamlScalar(key)+": "+yamlScalar(set[key]))
+		}
+	}
+
+	remainingKeys := len(added)
+	for _, i := range keyLines {
+		if !removed[i] {
+			remainingKeys++
+		}
+	}
+
+	var updated []string
+	switch {
+	case fieldLine == -1 && len(added) > 0:
+		// A new field goes at the end of the entry
+		updated = append(updated, lines[:end]...)
+		updated = append(updated, strings.Repeat(" ", fieldIndent)+field+":")
+		updated = append(updated, added...)
+		updated = append(updated, lines[end:]...)
+	case fieldLine == -1:
+		return stackYAML, nil
+	default:
+		for i, line := range lines {
+			if removed[i] {
+				continue
+			}
+			if i == fieldLine {
+				if remainingKeys == 0 {
+					continue
+				}
+				if inlineEmpty {
+					line = strings.Repeat(" ", fieldIndent) + field + ":"
+				}
+			}
+			updated = append(updated, line)
+			if i == blockEnd {
+				updated = append(updated, added...)
+			}
+		}
+	}
+	return strings.Join(updated, "\n"), nil
+}
+
+// mapLineKey reads the key of a "key: value" line, which may be quoted
+func mapLineKey(line string) string {
+	trimmed := strings.TrimSpace(line)
+
+	var entry map[string]interface{}
+	if err := yaml.Unmarshal([]byte(trimmed), &entry); err == nil {
+		for key := range entry {
+			return key
+		}
+	}
+	return strings.TrimSpace(strings.SplitN(trimmed, ":", 2)[0])
+}
+
+// yamlScalar quotes a value when YAML would read it as something other than
+// a string, such as true or 10
+func yamlScalar(value string) string {
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSuffix(string(out), "\n")
+}
@@ -0,0 +1,166 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+const stackEditYAML = `provider:
+  name: faas
+  gateway: http://127.0.0.1:8080
+
+functions:
+  # The public API
+  api:
+    lang: python
+    handler: ./api
+    image: api:latest
+    labels:
+      tier: backend # set by the platform team
+      legacy: "true"
+  cron:
+    lang: go
+    handler: ./cron
+    image: cron:latest
+    annotations: {}
+`
+
+func Test_editStackMap(t *testing.T) {
+	cases := []struct {
+		name   string
+		fn     string
+		field  string
+		set    map[string]string
+		remove []string
+		want   []string
+		absent []string
+	}{
+		{
+			name:  "updates an existing key and adds a new one",
+			fn:    "api",
+			field: "labels",
+			set:   map[string]string{"tier": "frontend", "team": "payments"},
+			want:  []string{"      tier: frontend\n      legacy: \"true\"\n      team: payments\n  cron:", "  # The public API\n"},
+		},
+		{
+			name:  "adds a missing field at the end of the entry",
+			fn:    "api",
+			field: "annotations",
+			set:   map[string]string{"enabled": "true"},
+			want:  []string{"      legacy: \"true\"\n    annotations:\n      enabled: \"true\"\n  cron:"},
+		},
+		{
+			name:   "removes a key and keeps the rest",
+			fn:     "api",
+			field:  "labels",
+			remove: []string{"legacy"},
+			want:   []string{"    labels:\n      tier: backend # set by the platform team\n  cron:"},
+			absent: []string{"legacy"},
+		},
+		{
+			name:   "removes the field with its last key",
+			fn:     "api",
+			field:  "labels",
+			remove: []string{"legacy", "tier"},
+			want:   []string{"    image: api:latest\n  cron:"},
+			absent: []string{"labels:"},
+		},
+		{
+			name:   "fills an empty inline map",
+			fn:     "cron",
+			field:  "annotations",
+			set:    map[string]string{"topic": "cron-function"},
+			want:   []string{"    annotations:\n      topic: cron-function\n"},
+			absent: []string{"{}"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := editStackMap(stackEditYAML, c.fn, c.field, c.set, c.remove)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range c.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("want %q in:\n%s", want, got)
+				}
+			}
+			for _, absent := range c.absent {
+				if strings.Contains(got, absent) {
+					t.Errorf("did not want %q in:\n%s", absent, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_editStackMap_InlineMap(t *testing.T) {
+	stackYAML := strings.Replace(stackEditYAML, "annotations: {}", "annotations: {topic: cron}", 1)
+
+	_, err := editStackMap(stackYAML, "cron", "annotations", map[string]string{"a": "b"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "written inline") {
+		t.Errorf("want an error for an inline map, got %v", err)
+	}
+}
+
+func Test_runStackEdit_All(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-stack-edit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	resetForTest()
+	yamlFile = filepath.Join(dir, "stack.yml")
+	stackEditAll = true
+	defer func() {
+		resetForTest()
+		stackEditAll = false
+	}()
+	if err := ioutil.WriteFile(yamlFile, []byte(stackEditYAML), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stdOut := test.CaptureStdout(func() {
+		err = runStackEdit("annotations", []string{"team=payments"})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(stdOut, "Updated the annotations of 2 function(s) in "+yamlFile+": api, cron") {
+		t.Errorf("unexpected output: %s", stdOut)
+	}
+
+	data, _ := ioutil.ReadFile(yamlFile)
+	if got := strings.Count(string(data), "      team: payments\n"); got != 2 {
+		t.Errorf("want the annotation on both functions, got %d in:\n%s", got, data)
+	}
+	if !strings.Contains(string(data), "tier: backend # set by the platform team") {
+		t.Errorf("comments were not preserved:\n%s", data)
+	}
+}
+
+func Test_runStackEdit_Selection(t *testing.T) {
+	resetForTest()
+	yamlFile = "stack.yml"
+	defer resetForTest()
+
+	err := runStackEdit("labels", []string{"team=payments"})
+	if err == nil || !strings.Contains(err.Error(), "--all") {
+		t.Errorf("want an error when no functions are chosen, got %v", err)
+	}
+
+	err = runStackEdit("labels", []string{"api"})
+	if err == nil || !strings.Contains(err.Error(), "KEY=VALUE") {
+		t.Errorf("want an error when nothing is edited, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func init() {
+	stackCmd.AddCommand(stackResolveCmd)
+	stackCmd.AddCommand(stackRenderCmd)
+}
+
+var stackResolveCmd = &cobra.Command{
+	Use:   `resolve -f YAML_FILE [--regex REGEX] [--filter WILDCARD]`,
+	Short: "Print the effective stack once extends and defaults are applied",
+	Long: `Prints the stack as the other commands see it: the provider, defaults and
+policy of the stacks named by extends are inherited, and the defaults are
+applied to each function.`,
+	Example: `  faas-cli stack resolve -f ./stack.yml
+  faas-cli stack resolve -f ./stack.yml --filter "api-*"`,
+	RunE: runStackResolve,
+}
+
+var stackRenderCmd = &cobra.Command{
+	Use:   `render -f YAML_FILE [--regex REGEX] [--filter WILDCARD]`,
+	Short: "Print the stack with environment variables substituted",
+	Long: `Prints the stack as resolve does, with each ${VAR} and ${VAR:-default}
+expanded from the environment as --envsubst does, for checking what the other
+commands will see.`,
+	Example: `  TAG=0.2 faas-cli stack render -f ./stack.yml
+  faas-cli stack render -f ./stack.yml --filter "api-*"`,
+	RunE: runStackRender,
+}
+
+func runStackRender(cmd *cobra.Command, args []string) error {
+	defer func(envSubst bool) { stack.EnvSubst = envSubst }(stack.EnvSubst)
+	stack.EnvSubst = true
+
+	return runStackResolve(cmd, args)
+}
+
+func runStackResolve(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the stack with --yaml")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+
+	return printResolvedStack(os.Stdout, services)
+}
+
+// printResolvedStack writes the stack as YAML, its defaults are left out as
+// they have been applied to the functions
+func printResolvedStack(w io.Writer, services *stack.Services) error {
+	resolved := *services
+	resolved.Defaults = nil
+
+	out, err := yaml.Marshal(resolved)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(out)
+	return err
+}
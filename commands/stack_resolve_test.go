@@ -0,0 +1,134 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_printResolvedStack(t *testing.T) {
+	services, err := stack.ParseYAMLData([]byte(`provider:
+  name: faas
+defaults:
+  environment:
+    TEAM: platform
+functions:
+  api:
+    image: api:0.1
+`), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := printResolvedStack(&out, services); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := `functions:
+  api:
+    image: api:0.1
+    environment:
+      TEAM: platform
+provider:
+  name: faas
+`
+	if out.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func Test_stackRender(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+	os.Setenv("FAAS_TEST_TAG", "0.4")
+	defer os.Unsetenv("FAAS_TEST_TAG")
+
+	dir, err := ioutil.TempDir("", "faas-cli-stack-render")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(stackFile, []byte(`provider:
+  name: faas
+  gateway: ${FAAS_TEST_GATEWAY:-http://127.0.0.1:8080}
+functions:
+  api:
+    image: acme/api:${FAAS_TEST_TAG}
+`), 0600)
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"stack", "render", "-f", stackFile})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	for _, want := range []string{"image: acme/api:0.4", "gateway: http://127.0.0.1:8080"} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in the rendered stack, got:\n%s", want, stdOut)
+		}
+	}
+	if stack.EnvSubst {
+		t.Errorf("want substitution only for render")
+	}
+}
+
+func Test_stackResolve_OverlayFiles(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	dir, err := ioutil.TempDir("", "faas-cli-stack-overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(base, []byte(`provider:
+  name: faas
+functions:
+  api:
+    image: acme/api:0.1
+    environment:
+      REGION: eu
+      LOG_LEVEL: info
+  worker:
+    image: acme/worker:0.1
+`), 0600)
+	prod := filepath.Join(dir, "prod.yml")
+	ioutil.WriteFile(prod, []byte(`functions:
+  api:
+    image: acme/api:0.2
+    environment:
+      LOG_LEVEL: warn
+`), 0600)
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"stack", "resolve", "-f", base, "-f", prod})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	for _, want := range []string{"image: acme/api:0.2", "REGION: eu", "LOG_LEVEL: warn", "image: acme/worker:0.1"} {
+		if !strings.Contains(stdOut, want) {
+			t.Errorf("want %q in the merged stack, got:\n%s", want, stdOut)
+		}
+	}
+	if yamlFile != base || !reflect.DeepEqual(stack.OverlayFiles, []string{prod}) {
+		t.Errorf("want %s overlaid by %s, got %s and %v", base, prod, yamlFile, stack.OverlayFiles)
+	}
+}
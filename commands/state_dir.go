@@ -0,0 +1,83 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/builder"
+)
+
+// stateDir is where the CLI keeps what it writes while building and
+// deploying, so that the source tree can be mounted read-only
+var stateDir string
+
+func init() {
+	faasCmd.PersistentFlags().StringVar(&stateDir, "state-dir", defaultStateDirectory(), "Folder build contexts, the build cache and checkpoints are kept in, also set by FAAS_STATE_DIR")
+}
+
+// defaultStateDirectory gives FAAS_STATE_DIR when it is set, otherwise
+// faas-cli in XDG_STATE_HOME or its default of ~/.local/state
+func defaultStateDirectory() string {
+	if dir, exists := os.LookupEnv("FAAS_STATE_DIR"); exists && len(dir) > 0 {
+		return dir
+	}
+	if dir, exists := os.LookupEnv("XDG_STATE_HOME"); exists && filepath.IsAbs(dir) {
+		return filepath.Join(dir, "faas-cli")
+	}
+	return filepath.Join("~", ".local", "state", "faas-cli")
+}
+
+// absoluteStateDirectory expands ~ in dir and makes it absolute
+func absoluteStateDirectory(dir string) (string, error) {
+	expanded, err := homedir.Expand(dir)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(expanded)
+}
+
+// projectStateDirectory gives the folder in dir kept for the project in
+// workingDir, named after the folder and a hash of its path so that two
+// checkouts of a stack don't share a build cache
+func projectStateDirectory(dir string, workingDir string) (string, error) {
+	absolute, err := absoluteStateDirectory(dir)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(workingDir))
+	name := filepath.Base(workingDir) + "-" + hex.EncodeToString(sum[:])[:12]
+	return filepath.Join(absolute, "projects", name), nil
+}
+
+// useStateDir moves the build contexts, fingerprints, build cache, build
+// times, checkpoints and patches of the project in the working directory into
+// the state directory, which is kept as an absolute path for the commands
+// which write to it
+func useStateDir() error {
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	if stateDir, err = absoluteStateDirectory(stateDir); err != nil {
+		return err
+	}
+	project, err := projectStateDirectory(stateDir, workingDir)
+	if err != nil {
+		return err
+	}
+
+	builder.BuildDirectory = filepath.Join(project, "build")
+	builder.FingerprintDirectory = filepath.Join(project, "fingerprints")
+	buildCachePath = filepath.Join(project, "build-cache.json")
+	buildTimesPath = filepath.Join(project, "build-times.json")
+	checkpointDirectory = filepath.Join(project, "checkpoints")
+	patchDirectory = filepath.Join(project, "patches")
+	return nil
+}
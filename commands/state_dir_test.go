@@ -0,0 +1,82 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/builder"
+)
+
+func Test_defaultStateDirectory(t *testing.T) {
+	defer func(stateDir, xdgStateHome string) {
+		os.Setenv("FAAS_STATE_DIR", stateDir)
+		os.Setenv("XDG_STATE_HOME", xdgStateHome)
+	}(os.Getenv("FAAS_STATE_DIR"), os.Getenv("XDG_STATE_HOME"))
+
+	os.Setenv("FAAS_STATE_DIR", "")
+	os.Setenv("XDG_STATE_HOME", "")
+	if got := defaultStateDirectory(); got != filepath.Join("~", ".local", "state", "faas-cli") {
+		t.Errorf("want the XDG default, got %s", got)
+	}
+
+	os.Setenv("XDG_STATE_HOME", "/var/state")
+	if got := defaultStateDirectory(); got != "/var/state/faas-cli" {
+		t.Errorf("want XDG_STATE_HOME used, got %s", got)
+	}
+
+	os.Setenv("FAAS_STATE_DIR", "/ci/state")
+	if got := defaultStateDirectory(); got != "/ci/state" {
+		t.Errorf("want FAAS_STATE_DIR used, got %s", got)
+	}
+}
+
+func Test_useStateDir(t *testing.T) {
+	defer func(dir, build, fingerprints, cache, times, checkpoints, patches string) {
+		stateDir, builder.BuildDirectory, builder.FingerprintDirectory = dir, build, fingerprints
+		buildCachePath, buildTimesPath, checkpointDirectory, patchDirectory = cache, times, checkpoints, patches
+	}(stateDir, builder.BuildDirectory, builder.FingerprintDirectory, buildCachePath, buildTimesPath, checkpointDirectory, patchDirectory)
+
+	stateDir = "/ci/state"
+	if err := useStateDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	workingDir, _ := os.Getwd()
+	project, _ := projectStateDirectory(stateDir, workingDir)
+	if !strings.HasPrefix(project, "/ci/state/projects/commands-") {
+		t.Fatalf("want a folder named after the project, got %s", project)
+	}
+	if builder.BuildDirectory != filepath.Join(project, "build") || builder.FingerprintDirectory != filepath.Join(project, "fingerprints") ||
+		buildCachePath != filepath.Join(project, "build-cache.json") || buildTimesPath != filepath.Join(project, "build-times.json") ||
+		checkpointDirectory != filepath.Join(project, "checkpoints") || patchDirectory != filepath.Join(project, "patches") {
+		t.Errorf("want the state kept in %s, got %s, %s, %s and %s", project, builder.BuildDirectory, builder.FingerprintDirectory, buildCachePath, checkpointDirectory)
+	}
+
+	other, _ := projectStateDirectory(stateDir, "/src/other/commands")
+	if other == project {
+		t.Errorf("want checkouts of projects with the same name kept apart, got %s for both", other)
+	}
+}
+
+func Test_useStateDir_ExpandsHome(t *testing.T) {
+	defer func(dir, build, fingerprints, cache, times, checkpoints, patches string) {
+		stateDir, builder.BuildDirectory, builder.FingerprintDirectory = dir, build, fingerprints
+		buildCachePath, buildTimesPath, checkpointDirectory, patchDirectory = cache, times, checkpoints, patches
+	}(stateDir, builder.BuildDirectory, builder.FingerprintDirectory, buildCachePath, buildTimesPath, checkpointDirectory, patchDirectory)
+
+	stateDir = filepath.Join("~", ".local", "state", "faas-cli")
+	if err := useStateDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	home, _ := homedir.Dir()
+	if want := filepath.Join(home, ".local", "state", "faas-cli"); stateDir != want {
+		t.Errorf("want the state directory expanded to %s, got %s", want, stateDir)
+	}
+}
@@ -0,0 +1,280 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/openfaas/faas-cli/proxy"
+)
+
+// isArchiveURL tells whether a template source is an archive to download
+// rather than a git repository to clone
+func isArchiveURL(source string) bool {
+	path := source
+	if parsed, err := url.Parse(source); err == nil && len(parsed.Path) > 0 {
+		path = parsed.Path
+	}
+
+	return strings.HasSuffix(path, ".zip") || strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// partialArchivePath is where an archive is downloaded to, the name is stable
+// for each URL so that an interrupted download can be resumed
+func partialArchivePath(archiveURL string) string {
+	sum := sha256.Sum256([]byte(archiveURL))
+	ext := ".zip"
+	if !strings.HasSuffix(strings.SplitN(archiveURL, "?", 2)[0], ".zip") {
+		ext = ".tar.gz"
+	}
+
+	return filepath.Join(os.TempDir(), "openfaas-templates-"+hex.EncodeToString(sum[:6])+ext)
+}
+
+// downloadArchive downloads the archive, resuming a previous partial download
+// when the server supports ranges, and verifies its SHA256 when one is given
+func downloadArchive(archiveURL string, expectedSHA256 string) (string, error) {
+	archivePath := partialArchivePath(archiveURL)
+
+	var offset int64
+	if info, err := os.Stat(archivePath); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, archiveURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	client := proxy.MakeHTTPClient(nil)
+	res, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		file, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(file, res.Body)
+		file.Close()
+		if err != nil {
+			return "", fmt.Errorf("download of %s was interrupted: %s", archiveURL, err)
+		}
+	case http.StatusPartialContent:
+		pullDebugPrint(fmt.Sprintf("Resuming download of %s from byte %d", archiveURL, offset))
+		file, err := os.OpenFile(archivePath, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			return "", err
+		}
+		_, err = io.Copy(file, res.Body)
+		file.Close()
+		if err != nil {
+			return "", fmt.Errorf("download of %s was interrupted: %s", archiveURL, err)
+		}
+
+		// A resume which doesn't add up to the whole archive can't be
+		// resumed again, so it is started afresh next time
+		if start, total, ok := parseContentRange(res.Header.Get("Content-Range")); ok {
+			info, err := os.Stat(archivePath)
+			if err != nil {
+				return "", err
+			}
+			if start != offset || info.Size() != total {
+				os.Remove(archivePath)
+				return "", fmt.Errorf("resumed download of %s has %d bytes but the archive has %d, try again", archiveURL, info.Size(), total)
+			}
+		}
+	case http.StatusRequestedRangeNotSatisfiable:
+		// The previous download was already complete
+	default:
+		return "", fmt.Errorf("server returned unexpected status code: %d for %s", res.StatusCode, archiveURL)
+	}
+
+	if len(expectedSHA256) > 0 {
+		actual, err := fileSHA256(archivePath)
+		if err != nil {
+			return "", err
+		}
+
+		if !strings.EqualFold(actual, expectedSHA256) {
+			os.Remove(archivePath)
+			return "", fmt.Errorf("checksum mismatch for %s, expected %s but got %s", archiveURL, expectedSHA256, actual)
+		}
+	}
+
+	return archivePath, nil
+}
+
+// parseContentRange reads the first byte and the total size from a
+// Content-Range of "bytes start-end/total", ok is false when either is unknown
+func parseContentRange(contentRange string) (start int64, total int64, ok bool) {
+	var end int64
+	if _, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &start, &end, &total); err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// extractArchive expands a .zip or .tar.gz archive into dest
+func extractArchive(archivePath string, dest string) error {
+	if strings.HasSuffix(archivePath, ".zip") {
+		return extractZip(archivePath, dest)
+	}
+
+	return extractTarGz(archivePath, dest)
+}
+
+// archiveEntryPath joins the entry's name to dest and refuses names which
+// would escape dest
+func archiveEntryPath(dest string, name string) (string, error) {
+	target := filepath.Join(dest, name)
+	if !strings.HasPrefix(target, filepath.Clean(dest)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("archive entry %s is outside of the destination", name)
+	}
+
+	return target, nil
+}
+
+func extractZip(archivePath string, dest string) error {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for _, entry := range reader.File {
+		target, err := archiveEntryPath(dest, entry.Name)
+		if err != nil {
+			return err
+		}
+
+		if entry.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+			return err
+		}
+
+		src, err := entry.Open()
+		if err != nil {
+			return err
+		}
+
+		err = writeArchiveFile(target, src, entry.Mode())
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func extractTarGz(archivePath string, dest string) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := archiveEntryPath(dest, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0700); err != nil {
+				return err
+			}
+			if err := writeArchiveFile(target, tarReader, os.FileMode(header.Mode)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeArchiveFile(target string, src io.Reader, mode os.FileMode) error {
+	file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, src)
+	return err
+}
+
+// findTemplateRoot gives the folder holding the templatePath folder, archives
+// such as GitHub's wrap the repository in a single top-level folder
+func findTemplateRoot(dir string, templatePath string) string {
+	if _, err := os.Stat(filepath.Join(dir, templatePath)); err == nil {
+		return dir
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err == nil && len(entries) == 1 && entries[0].IsDir() {
+		return filepath.Join(dir, entries[0].Name())
+	}
+
+	return dir
+}
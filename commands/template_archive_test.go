@@ -0,0 +1,161 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+func makeTemplateTarGz(t *testing.T) []byte {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	files := map[string]string{
+		"templates-master/template/python/template.yml": "language: python\nfprocess: python index.py\n",
+		"templates-master/template/python/index.py":     "print('hi')\n",
+	}
+	for name, content := range files {
+		if err := tarWriter.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tarWriter.Write([]byte(content))
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+
+	return buf.Bytes()
+}
+
+func serveArchive(archive []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "templates.tar.gz", time.Time{}, bytes.NewReader(archive))
+	}))
+}
+
+func Test_fetchTemplateSources_FallsBackToMirror(t *testing.T) {
+	archive := makeTemplateTarGz(t)
+	sum := sha256.Sum256(archive)
+
+	s := serveArchive(archive)
+	defer s.Close()
+
+	mirror := s.URL + "/templates.tar.gz"
+	os.Remove(partialArchivePath(mirror))
+
+	dir, _ := ioutil.TempDir("", "openFaasTemplatesTest")
+	defer os.RemoveAll(dir)
+
+	missingRepo := filepath.Join(dir, "missing-repo")
+	repoPath, _, err := fetchTemplateSources(parseTemplateSource(missingRepo, ""), dir, config.TemplateConfig{
+		Retries:      1,
+		RetryBackoff: "1ms",
+		Mirrors:      map[string][]string{missingRepo: {mirror}},
+		Checksums:    map[string]string{mirror: hex.EncodeToString(sum[:])},
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(repoPath, "template", "python", "template.yml")); err != nil {
+		t.Fatalf("template was not expanded from the mirror: %s", err)
+	}
+}
+
+func Test_downloadArchive_ChecksumMismatch(t *testing.T) {
+	s := serveArchive(makeTemplateTarGz(t))
+	defer s.Close()
+
+	archiveURL := s.URL + "/templates.tar.gz"
+	os.Remove(partialArchivePath(archiveURL))
+
+	if _, err := downloadArchive(archiveURL, "0000"); err == nil {
+		t.Fatal("expected a checksum mismatch")
+	}
+
+	if _, err := os.Stat(partialArchivePath(archiveURL)); !os.IsNotExist(err) {
+		t.Fatal("archive with a bad checksum should be removed")
+	}
+}
+
+func Test_downloadArchive_Resumes(t *testing.T) {
+	archive := makeTemplateTarGz(t)
+	s := serveArchive(archive)
+	defer s.Close()
+
+	archiveURL := s.URL + "/resume.tar.gz"
+	partial := partialArchivePath(archiveURL)
+	ioutil.WriteFile(partial, archive[:len(archive)/2], 0600)
+	defer os.Remove(partial)
+
+	archivePath, err := downloadArchive(archiveURL, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	downloaded, _ := ioutil.ReadFile(archivePath)
+	if !bytes.Equal(downloaded, archive) {
+		t.Fatalf("resumed download does not match the archive, got %d bytes want %d", len(downloaded), len(archive))
+	}
+}
+
+func Test_fetchTemplateSource_RemovesCorruptArchive(t *testing.T) {
+	archive := makeTemplateTarGz(t)
+	s := serveArchive(archive)
+	defer s.Close()
+
+	// A partial download of an archive which has since changed
+	archiveURL := s.URL + "/corrupt.tar.gz"
+	partial := partialArchivePath(archiveURL)
+	ioutil.WriteFile(partial, bytes.Repeat([]byte("x"), len(archive)/2), 0600)
+	defer os.Remove(partial)
+
+	dir, _ := ioutil.TempDir("", "openFaasTemplatesTest")
+	defer os.RemoveAll(dir)
+
+	if _, err := fetchTemplateSource(parseTemplateSource(archiveURL, ""), dir, "", nil); err == nil {
+		t.Fatal("expected the corrupt archive to fail to expand")
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Fatal("a corrupt archive should be removed so the next fetch starts afresh")
+	}
+}
+
+func Test_parseContentRange(t *testing.T) {
+	if start, total, ok := parseContentRange("bytes 100-199/200"); !ok || start != 100 || total != 200 {
+		t.Errorf("want 100 of 200, got %d of %d %t", start, total, ok)
+	}
+	if _, _, ok := parseContentRange("bytes 100-199/*"); ok {
+		t.Errorf("want an unknown total not to parse")
+	}
+}
+
+func Test_isArchiveURL(t *testing.T) {
+	testCases := map[string]bool{
+		"https://github.com/openfaas/templates.git":                false,
+		"https://github.com/openfaas/templates/archive/master.zip": true,
+		"https://mirror.example.com/templates.tar.gz?token=abc":    true,
+		"https://mirror.example.com/templates.tgz":                 true,
+		"git@github.com:openfaas/templates.git":                    false,
+	}
+
+	for source, expected := range testCases {
+		if actual := isArchiveURL(source); actual != expected {
+			t.Errorf("%s want: %v, got: %v", source, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/openfaas/faas-cli/stack"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// pinnedTemplateSources gives an entry for configuration.templates for each
+// language, pinned to the commit when the source is a git repository
+func pinnedTemplateSources(source templateSource, commit string, languages []string) []stack.TemplateSource {
+	pinned := source.Repository
+	if len(commit) > 0 {
+		pinned += "#" + commit
+	} else if len(source.Ref) > 0 {
+		pinned += "#" + source.Ref
+	}
+
+	templatePath := path.Clean(source.Path)
+	if templatePath == path.Clean(repositoryTemplateDirectory) {
+		templatePath = ""
+	}
+
+	var sources []stack.TemplateSource
+	for _, language := range languages {
+		sources = append(sources, stack.TemplateSource{Name: language, Source: pinned, Path: templatePath})
+	}
+	return sources
+}
+
+// recordTemplateSources adds the templates to configuration.templates of the
+// stack file, replacing those of the same name. Only the configuration block
+// is rewritten, the comments and layout of the rest of the file are kept.
+func recordTemplateSources(stackFile string, sources []stack.TemplateSource) error {
+	if len(sources) == 0 {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(stackFile)
+	if err != nil {
+		return err
+	}
+
+	var parsed struct {
+		Configuration *stack.Configuration `yaml:"configuration"`
+	}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		return fmt.Errorf("unable to parse %s to record the templates: %s", stackFile, err)
+	}
+	configuration := parsed.Configuration
+	if configuration == nil {
+		configuration = &stack.Configuration{}
+	}
+
+	for _, source := range sources {
+		replaced := false
+		for i := range configuration.Templates {
+			if configuration.Templates[i].Name == source.Name {
+				configuration.Templates[i] = source
+				replaced = true
+			}
+		}
+		if !replaced {
+			configuration.Templates = append(configuration.Templates, source)
+		}
+	}
+
+	block, err := yaml.Marshal(yaml.MapSlice{{Key: "configuration", Value: configuration}})
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(stackFile)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(stackFile, []byte(replaceTopLevelBlock(string(data), "configuration", string(block))), info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	for _, source := range sources {
+		fmt.Printf("Recorded template %s from %s in %s\n", source.Name, source.Source, stackFile)
+	}
+	return nil
+}
+
+// replaceTopLevelBlock replaces the top-level key and everything indented
+// under it with block, or appends block when the key is not there
+func replaceTopLevelBlock(document string, key string, block string) string {
+	lines := strings.Split(document, "\n")
+
+	start := -1
+	for i, line := range lines {
+		if strings.HasPrefix(line, key+":") {
+			start = i
+			break
+		}
+	}
+
+	if start == -1 {
+		if len(document) > 0 && !strings.HasSuffix(document, "\n") {
+			document += "\n"
+		}
+		return document + "\n" + block
+	}
+
+	end := len(lines)
+	for i := start + 1; i < len(lines); i++ {
+		if len(strings.TrimSpace(lines[i])) > 0 && lineIndent(lines[i]) == 0 {
+			end = i
+			break
+		}
+	}
+
+	// Blank lines before the next key are kept to separate it from the block
+	for end > start+1 && len(strings.TrimSpace(lines[end-1])) == 0 {
+		end--
+	}
+
+	replacement := strings.Split(strings.TrimSuffix(block, "\n"), "\n")
+	updated := append(append(lines[:start:start], replacement...), lines[end:]...)
+	return strings.Join(updated, "\n")
+}
+
+// pullStackTemplates pulls the missing templates recorded in the stack's
+// configuration.templates from their sources, then the rest from the
+// default repository
+func pullStackTemplates(services stack.Services, languages []string) error {
+	if services.Configuration != nil {
+		var order []templateSource
+		wanted := map[templateSource][]string{}
+		for _, configured := range services.Configuration.Templates {
+			if !contains(languages, configured.Name) || stack.IsValidTemplate(configured.Name) {
+				continue
+			}
+
+			source := parseTemplateSource(configured.Source, configured.Path)
+			if _, seen := wanted[source]; !seen {
+				order = append(order, source)
+			}
+			wanted[source] = append(wanted[source], configured.Name)
+		}
+
+		for _, source := range order {
+			log.Printf("Templates %v not found in %s.\n", wanted[source], stack.TemplateDirectory)
+			if _, _, err := fetchSourceTemplates(source, true, wanted[source]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return pullLanguageTemplates(DefaultTemplateRepository, languages)
+}
@@ -0,0 +1,149 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/versioncontrol"
+)
+
+func Test_parseTemplateSource(t *testing.T) {
+	cases := []struct {
+		url  string
+		path string
+		want templateSource
+	}{
+		{"https://github.com/openfaas/templates", "", templateSource{"https://github.com/openfaas/templates", "", repositoryTemplateDirectory}},
+		{"https://github.com/openfaas/templates#1.2.0", "", templateSource{"https://github.com/openfaas/templates", "1.2.0", repositoryTemplateDirectory}},
+		{"git@github.com:acme/templates.git#release/v2", "build/templates", templateSource{"git@github.com:acme/templates.git", "release/v2", "build/templates"}},
+		{"https://example.com/templates.tar.gz#v1", "", templateSource{"https://example.com/templates.tar.gz#v1", "", repositoryTemplateDirectory}},
+	}
+
+	for _, c := range cases {
+		if got := parseTemplateSource(c.url, c.path); got != c.want {
+			t.Errorf("%s: want %+v, got %+v", c.url, c.want, got)
+		}
+	}
+}
+
+func Test_pinnedTemplateSources(t *testing.T) {
+	source := parseTemplateSource("https://github.com/acme/templates#main", "build/templates/")
+	got := pinnedTemplateSources(source, "0a1b2c3d", []string{"go", "node"})
+	want := []stack.TemplateSource{
+		{Name: "go", Source: "https://github.com/acme/templates#0a1b2c3d", Path: "build/templates"},
+		{Name: "node", Source: "https://github.com/acme/templates#0a1b2c3d", Path: "build/templates"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	got = pinnedTemplateSources(parseTemplateSource("https://example.com/templates.tar.gz", ""), "", []string{"go"})
+	if got[0].Source != "https://example.com/templates.tar.gz" || len(got[0].Path) > 0 {
+		t.Errorf("want an archive recorded as it is, got %+v", got[0])
+	}
+}
+
+func Test_recordTemplateSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-record-templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stackFile := filepath.Join(dir, "stack.yml")
+	original := `# the stack
+provider:
+  name: faas
+
+configuration:
+  templates:
+  - name: go
+    source: https://github.com/acme/templates#1111111
+
+functions:
+  # the api
+  api:
+    lang: go
+    handler: ./api
+`
+	if err := ioutil.WriteFile(stackFile, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err = recordTemplateSources(stackFile, []stack.TemplateSource{
+		{Name: "go", Source: "https://github.com/acme/templates#2222222"},
+		{Name: "node", Source: "https://github.com/acme/templates#2222222", Path: "node-templates"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(stackFile)
+	updated := string(data)
+	for _, kept := range []string{"# the stack\n", "# the api\n", "provider:\n  name: faas\n\n"} {
+		if !strings.Contains(updated, kept) {
+			t.Errorf("want %q kept, got:\n%s", kept, updated)
+		}
+	}
+	if strings.Contains(updated, "1111111") {
+		t.Errorf("want the go template replaced, got:\n%s", updated)
+	}
+
+	services, err := stack.ParseYAMLData(data, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []stack.TemplateSource{
+		{Name: "go", Source: "https://github.com/acme/templates#2222222"},
+		{Name: "node", Source: "https://github.com/acme/templates#2222222", Path: "node-templates"},
+	}
+	if services.Configuration == nil || !reflect.DeepEqual(want, services.Configuration.Templates) {
+		t.Errorf("want %+v recorded, got %+v", want, services.Configuration)
+	}
+	if _, ok := services.Functions["api"]; !ok {
+		t.Errorf("want the functions kept, got:\n%s", updated)
+	}
+}
+
+func Test_replaceTopLevelBlock_Appends(t *testing.T) {
+	got := replaceTopLevelBlock("provider:\n  name: faas", "configuration", "configuration:\n  templates: []\n")
+	want := "provider:\n  name: faas\n\nconfiguration:\n  templates: []\n"
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func Test_pullStackTemplates_PinnedCommit(t *testing.T) {
+	localTemplateRepository := setupLocalTemplateRepo(t)
+	defer os.RemoveAll(localTemplateRepository)
+	defer tearDownFetchTemplates(t)
+
+	commit, err := versioncontrol.GitHeadCommit.Output(".", map[string]string{"dir": localTemplateRepository}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	services := stack.Services{
+		Configuration: &stack.Configuration{
+			Templates: []stack.TemplateSource{{Name: "ruby", Source: localTemplateRepository + "#" + commit}},
+		},
+	}
+	if err := pullStackTemplates(services, []string{"ruby"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(filepath.Join("template", "ruby", "template.yml")); err != nil {
+		t.Errorf("want the ruby template pulled from its recorded source: %s", err)
+	}
+	if _, err := os.Stat(filepath.Join("template", "dockerfile")); err == nil {
+		t.Errorf("want only the recorded template pulled, found the dockerfile template")
+	}
+}
@@ -7,24 +7,33 @@ import (
 	"os"
 	"regexp"
 
+	"github.com/openfaas/faas-cli/versioncontrol"
 	"github.com/spf13/cobra"
 )
 
 const (
-	gitRemoteRepoRegex = `(?:git|ssh|https?|git@[-\w.]+):(\/\/)?(.*?)(\.git)?(\/?|\#[-\d\w._]+?)$`
+	gitRemoteRepoRegex = `(?:git|ssh|https?|git@[-\w.]+):(\/\/)?(.*?)(\.git)?(\/?|\#[-\d\w._\/]+?)$`
 )
 
 var (
-	repository string
-	overwrite  bool
-	pullDebug  bool
+	repository   string
+	overwrite    bool
+	pullDebug    bool
+	templatePath string
+
+	// sshFlags override the git section of the config file for SSH clones
+	sshFlags versioncontrol.SSHOptions
 )
 
-var supportedVerbs = [...]string{"pull"}
+var supportedVerbs = [...]string{"pull", "store"}
 
 func init() {
 	templatePullCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing templates?")
 	templatePullCmd.Flags().BoolVar(&pullDebug, "debug", false, "Enable debug output")
+	templatePullCmd.Flags().StringVar(&templatePath, "path", "", "Folder of the templates in the repository, defaults to template")
+	templatePullCmd.Flags().StringVar(&sshFlags.KeyFile, "ssh-key", "", "Private key, such as a deploy key, for cloning over SSH instead of ssh-agent")
+	templatePullCmd.Flags().StringVar(&sshFlags.KnownHostsFile, "known-hosts", "", "File of trusted host keys to verify the SSH server against")
+	templatePullCmd.Flags().StringVar(&sshFlags.HostKeyChecking, "host-key-checking", "", "How unknown host keys are treated: yes, no or accept-new")
 
 	faasCmd.AddCommand(templatePullCmd)
 }
@@ -47,7 +56,7 @@ Currently supported verbs: %v`, supportedVerbs)
 		if len(args) > 1 {
 
 			// assume it is a local repo
-			if _, err := os.Stat(args[1]); err == nil {
+			if _, err := os.Stat(parseTemplateSource(args[1], "").Repository); err == nil {
 				return nil
 			}
 
@@ -60,9 +69,25 @@ Currently supported verbs: %v`, supportedVerbs)
 	},
 	Short: "Downloads templates from the specified github repo",
 	Long: `Downloads the compressed github repo specified by [URL], and extracts the 'template'
-	directory from the root of the repo, if it exists.`,
-	Example: "faas-cli template pull https://github.com/openfaas/faas-cli",
-	Run:     runTemplatePull,
+	directory from the root of the repo, if it exists.
+
+	A branch, tag or commit is checked out when given after a # and --path picks
+	another folder of the repository than 'template'. With -f, the templates are
+	recorded in the stack's configuration.templates pinned to the commit pulled,
+	and build pulls them from there when they are missing.
+
+	Private repositories can be cloned over SSH with the keys in ssh-agent or a deploy
+	key given by --ssh-key or the "git" section of ~/.openfaas/config.yml, or over
+	HTTPS with a token in FAAS_GIT_TOKEN.`,
+	Example: `  faas-cli template pull https://github.com/openfaas/faas-cli
+  faas-cli template pull https://github.com/openfaas/templates#1.2.0
+  faas-cli template pull https://github.com/acme/functions#0a1b2c3 --path build/templates
+  faas-cli template pull https://github.com/acme/templates#main -f stack.yml
+  FAAS_GIT_TOKEN=$TOKEN faas-cli template pull https://github.com/acme/private-templates
+  faas-cli template pull git@github.com:acme/templates.git
+  faas-cli template pull git@github.com:acme/templates.git --ssh-key ~/.ssh/templates_deploy_key \
+    --known-hosts ./known_hosts --host-key-checking yes`,
+	Run: runTemplatePull,
 }
 
 func runTemplatePull(cmd *cobra.Command, args []string) {
@@ -72,7 +97,12 @@ func runTemplatePull(cmd *cobra.Command, args []string) {
 	}
 
 	fmt.Println("Fetch templates from repository: " + repository)
-	if err := fetchTemplates(repository, overwrite); err != nil {
+	source := parseTemplateSource(repository, templatePath)
+	languages, commit, err := fetchSourceTemplates(source, overwrite, nil)
+	if err == nil && cmd.Flags().Changed("yaml") && len(yamlFile) > 0 {
+		err = recordTemplateSources(yamlFile, pinnedTemplateSources(source, commit, languages))
+	}
+	if err != nil {
 		fmt.Println(err)
 
 		os.Exit(1)
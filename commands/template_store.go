@@ -0,0 +1,299 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/schema"
+	"github.com/spf13/cobra"
+)
+
+const (
+	defaultTemplateStore = "https://raw.githubusercontent.com/openfaas/store/master/templates.json"
+
+	// templateStoreCacheTTL is how long a downloaded index is used for
+	// before it is downloaded again
+	templateStoreCacheTTL = time.Hour
+)
+
+var (
+	templateStoreURL      string
+	templateStorePlatform string
+)
+
+func init() {
+	templateStoreCmd.PersistentFlags().StringVarP(&templateStoreURL, "url", "u", defaultTemplateStore, "URL of the template store's JSON index starting with http(s)://")
+	templateStoreListCmd.Flags().StringVarP(&templateStorePlatform, "platform", "p", "", "Only list templates for this platform, i.e. x86_64, armhf or arm64")
+	templateStoreListCmd.Flags().BoolVarP(&verboseDescription, "verbose", "v", false, "Show the full descriptions")
+	templateStorePullCmd.Flags().BoolVar(&overwrite, "overwrite", false, "Overwrite existing templates?")
+
+	templateStoreCmd.AddCommand(templateStoreListCmd)
+	templateStoreCmd.AddCommand(templateStoreDescribeCmd)
+	templateStoreCmd.AddCommand(templateStorePullCmd)
+	templatePullCmd.AddCommand(templateStoreCmd)
+}
+
+var templateStoreCmd = &cobra.Command{
+	Use:   `store`,
+	Short: "Browse and pull templates from a template store",
+	Long: `Lists, describes and pulls the community templates in a template store, a
+JSON index of templates and the repositories they are in. The index is cached
+in the state directory for an hour, and the cached copy is used when the store
+can't be reached.`,
+}
+
+var templateStoreListCmd = &cobra.Command{
+	Use:   `list [--url STORE_URL] [--platform PLATFORM]`,
+	Short: "List the templates in the store",
+	Example: `  faas-cli template store list
+  faas-cli template store list --platform arm64
+  faas-cli template store list --url https://example.com/templates.json`,
+	RunE: runTemplateStoreList,
+}
+
+var templateStoreDescribeCmd = &cobra.Command{
+	Use:   `describe [SOURCE/]TEMPLATE_NAME [--url STORE_URL]`,
+	Short: "Show a template's language, platform and repository",
+	Example: `  faas-cli template store describe golang-http
+  faas-cli template store describe openfaas/python3`,
+	RunE: runTemplateStoreDescribe,
+}
+
+var templateStorePullCmd = &cobra.Command{
+	Use:   `pull [SOURCE/]TEMPLATE_NAME [--url STORE_URL] [--overwrite]`,
+	Short: "Pull a template from the store by name",
+	Long: `Pulls the template from the repository the store gives for it, without
+pulling the other templates in that repository. Give SOURCE/ when templates
+from more than one source have the name.`,
+	Example: `  faas-cli template store pull golang-http
+  faas-cli template store pull openfaas/python3 --overwrite`,
+	RunE: runTemplateStorePull,
+}
+
+func runTemplateStoreList(cmd *cobra.Command, args []string) error {
+	templates, err := templateStoreIndex(templateStoreURL)
+	if err != nil {
+		return err
+	}
+
+	templates = filterTemplatesByPlatform(templates, templateStorePlatform)
+	if len(templates) == 0 {
+		fmt.Println("No templates were found.")
+		return nil
+	}
+
+	fmt.Print(renderTemplateStoreItems(templates))
+	return nil
+}
+
+func runTemplateStoreDescribe(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide the name of the template")
+	}
+
+	templates, err := templateStoreIndex(templateStoreURL)
+	if err != nil {
+		return err
+	}
+	item, err := findTemplate(templates, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(renderTemplateStoreItem(item))
+	return nil
+}
+
+func runTemplateStorePull(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("please provide the name of the template")
+	}
+
+	templates, err := templateStoreIndex(templateStoreURL)
+	if err != nil {
+		return err
+	}
+	item, err := findTemplate(templates, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Fetch template %s from repository: %s\n", item.Template, item.Repository)
+	return fetchLanguageTemplates(item.Repository, overwrite, []string{item.Template})
+}
+
+func filterTemplatesByPlatform(templates []schema.TemplateStoreItem, platform string) []schema.TemplateStoreItem {
+	if len(platform) == 0 {
+		return templates
+	}
+
+	var filtered []schema.TemplateStoreItem
+	for _, item := range templates {
+		if strings.EqualFold(item.Platform, platform) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// findTemplate finds a template by its name, or by SOURCE/NAME when
+// templates from several sources have the same name
+func findTemplate(templates []schema.TemplateStoreItem, name string) (schema.TemplateStoreItem, error) {
+	source := ""
+	if parts := strings.SplitN(name, "/", 2); len(parts) == 2 {
+		source, name = parts[0], parts[1]
+	}
+
+	var found []schema.TemplateStoreItem
+	for _, item := range templates {
+		if item.Template == name && (len(source) == 0 || item.Source == source) {
+			found = append(found, item)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return schema.TemplateStoreItem{}, fmt.Errorf("template %s was not found in the store", name)
+	case 1:
+		return found[0], nil
+	}
+
+	var sources []string
+	for _, item := range found {
+		if item.Repository != found[0].Repository {
+			sources = append(sources, item.Source+"/"+item.Template)
+		}
+	}
+	// The same template is listed once for each platform it supports
+	if len(sources) == 0 {
+		return found[0], nil
+	}
+	sources = append([]string{found[0].Source + "/" + found[0].Template}, sources...)
+	return schema.TemplateStoreItem{}, fmt.Errorf("more than one template is named %s, give one of: %s", name, strings.Join(sources, ", "))
+}
+
+func renderTemplateStoreItems(templates []schema.TemplateStoreItem) string {
+	sorted := append([]schema.TemplateStoreItem{}, templates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Template != sorted[j].Template {
+			return sorted[i].Template < sorted[j].Template
+		}
+		return sorted[i].Source < sorted[j].Source
+	})
+
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSOURCE\tPLATFORM\tDESCRIPTION")
+	for _, item := range sorted {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Template, item.Source, item.Platform, renderDescription(item.Description))
+	}
+	w.Flush()
+	return b.String()
+}
+
+func renderTemplateStoreItem(item schema.TemplateStoreItem) string {
+	var b bytes.Buffer
+	w := tabwriter.NewWriter(&b, 0, 0, 1, ' ', 0)
+	fmt.Fprintf(w, "Name:\t%s\n", item.Template)
+	fmt.Fprintf(w, "Source:\t%s\n", item.Source)
+	fmt.Fprintf(w, "Language:\t%s\n", item.Language)
+	fmt.Fprintf(w, "Platform:\t%s\n", item.Platform)
+	fmt.Fprintf(w, "Official:\t%s\n", item.Official)
+	fmt.Fprintf(w, "Repository:\t%s\n", item.Repository)
+	fmt.Fprintf(w, "Description:\t%s\n", item.Description)
+	w.Flush()
+	return b.String()
+}
+
+// templateStoreCachePath is where the index of the store is cached, named
+// after a hash of its URL so that several stores can be cached
+func templateStoreCachePath(store string) (string, error) {
+	dir, err := homedir.Expand(stateDir)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(store))
+	return filepath.Join(dir, "template-store", hex.EncodeToString(sum[:])[:16]+".json"), nil
+}
+
+// templateStoreIndex gives the templates in the store from the cache when it
+// is fresh, or downloads and caches them. A stale cache is used when the
+// store can't be reached.
+func templateStoreIndex(store string) ([]schema.TemplateStoreItem, error) {
+	cachePath, err := templateStoreCachePath(store)
+	if err != nil {
+		return nil, err
+	}
+
+	var cached []schema.TemplateStoreItem
+	cacheErr := fmt.Errorf("no cached index")
+	if info, statErr := os.Stat(cachePath); statErr == nil {
+		data, readErr := ioutil.ReadFile(cachePath)
+		if cacheErr = readErr; cacheErr == nil {
+			cacheErr = json.Unmarshal(data, &cached)
+		}
+		if cacheErr == nil && time.Since(info.ModTime()) < templateStoreCacheTTL {
+			return cached, nil
+		}
+	}
+
+	data, err := fetchTemplateStore(store)
+	if err != nil {
+		if cacheErr == nil {
+			fmt.Fprintf(os.Stderr, "Warning: using the cached template store index, %s\n", err)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	var templates []schema.TemplateStoreItem
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, fmt.Errorf("cannot parse result from template store on URL: %s\n%s", store, err.Error())
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err == nil {
+		ioutil.WriteFile(cachePath, data, 0600)
+	}
+	return templates, nil
+}
+
+func fetchTemplateStore(store string) ([]byte, error) {
+	timeout := 60 * time.Second
+	client := proxy.MakeHTTPClient(&timeout)
+
+	req, err := http.NewRequest(http.MethodGet, store, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to template store on URL: %s", store)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to template store on URL: %s", store)
+	}
+	defer res.Body.Close()
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read result from template store on URL: %s", store)
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("template store returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+	return bytesOut, nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/schema"
+	"github.com/openfaas/faas-cli/test"
+)
+
+const templateStoreJSON = `[
+  {"template": "golang-http", "platform": "x86_64", "language": "Go", "source": "openfaas", "description": "Golang HTTP template", "repo": "https://github.com/openfaas/golang-http-template", "official": "true"},
+  {"template": "golang-http", "platform": "arm64", "language": "Go", "source": "openfaas", "description": "Golang HTTP template", "repo": "https://github.com/openfaas/golang-http-template", "official": "true"},
+  {"template": "python3", "platform": "x86_64", "language": "Python", "source": "openfaas", "description": "Classic Python 3 template", "repo": "https://github.com/openfaas/templates", "official": "true"},
+  {"template": "python3", "platform": "x86_64", "language": "Python", "source": "acme", "description": "Python 3 with our base image", "repo": "https://github.com/acme/templates", "official": "false"}
+]`
+
+func Test_templateStoreList(t *testing.T) {
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(templateStoreJSON))
+	}))
+	defer s.Close()
+
+	dir, err := ioutil.TempDir("", "faas-cli-template-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	originalStateDir := stateDir
+	defer func() { stateDir = originalStateDir }()
+	stateDir = dir
+
+	resetForTest()
+	faasCmd.SetArgs([]string{"template", "store", "list", "--url", s.URL, "--platform", "arm64"})
+	stdOut := test.CaptureStdout(func() {
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if !strings.Contains(stdOut, "golang-http") || strings.Contains(stdOut, "python3") {
+		t.Errorf("want only the arm64 templates listed, got:\n%s", stdOut)
+	}
+
+	faasCmd.SetArgs([]string{"template", "store", "describe", "acme/python3", "--url", s.URL})
+	stdOut = test.CaptureStdout(func() {
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+	if !strings.Contains(stdOut, "https://github.com/acme/templates") {
+		t.Errorf("want the repository of acme/python3, got:\n%s", stdOut)
+	}
+	if requests != 1 {
+		t.Errorf("want the index cached after the first request, got %d requests", requests)
+	}
+}
+
+func Test_templateStoreIndex_UsesStaleCacheOffline(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(templateStoreJSON))
+	}))
+
+	dir, err := ioutil.TempDir("", "faas-cli-template-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	originalStateDir := stateDir
+	defer func() { stateDir = originalStateDir }()
+	stateDir = dir
+
+	if _, err := templateStoreIndex(s.URL); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	s.Close()
+
+	cachePath, _ := templateStoreCachePath(s.URL)
+	old := time.Now().Add(-2 * templateStoreCacheTTL)
+	os.Chtimes(cachePath, old, old)
+
+	templates, err := templateStoreIndex(s.URL)
+	if err != nil || len(templates) != 4 {
+		t.Errorf("want the stale cache used when the store is down, got %d templates, %v", len(templates), err)
+	}
+}
+
+func Test_findTemplate(t *testing.T) {
+	templates := []schema.TemplateStoreItem{
+		{Template: "golang-http", Platform: "x86_64", Source: "openfaas", Repository: "https://github.com/openfaas/golang-http-template"},
+		{Template: "golang-http", Platform: "arm64", Source: "openfaas", Repository: "https://github.com/openfaas/golang-http-template"},
+		{Template: "python3", Source: "openfaas", Repository: "https://github.com/openfaas/templates"},
+		{Template: "python3", Source: "acme", Repository: "https://github.com/acme/templates"},
+	}
+
+	if item, err := findTemplate(templates, "golang-http"); err != nil || item.Source != "openfaas" {
+		t.Errorf("want a template listed for several platforms found, got %+v, %v", item, err)
+	}
+	if _, err := findTemplate(templates, "python3"); err == nil || !strings.Contains(err.Error(), "acme/python3") {
+		t.Errorf("want an error listing the sources of python3, got %v", err)
+	}
+	if item, err := findTemplate(templates, "acme/python3"); err != nil || item.Repository != "https://github.com/acme/templates" {
+		t.Errorf("want acme's python3, got %+v, %v", item, err)
+	}
+	if _, err := findTemplate(templates, "cobol"); err == nil {
+		t.Errorf("want an error for a missing template")
+	}
+}
@@ -0,0 +1,270 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/openfaas/faas-cli/policy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+var (
+	upSkipPush   bool
+	upSkipDeploy bool
+	upKeepGoing  bool
+)
+
+// runUpStage runs faas-cli with args and returns its exit status as an
+// error. The builders and docker push exit faas-cli when they fail, so each
+// function is built and pushed by a faas-cli of its own. It is swapped out in
+// tests.
+var runUpStage = func(args []string) error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(executable, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+const (
+	upBuild  = "build"
+	upPush   = "push"
+	upDeploy = "deploy"
+)
+
+func init() {
+	upCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	upCmd.Flags().StringVar(&network, "network", defaultNetwork, "Name of the network")
+
+	upCmd.Flags().BoolVar(&nocache, "no-cache", false, "Do not use Docker's build cache")
+	upCmd.Flags().StringArrayVar(&buildArgOpts, "build-arg", []string{}, "Add a build-arg for Docker (KEY=VALUE), the value is masked in the build output")
+	upCmd.Flags().BoolVar(&changedOnly, "changed-only", false, "Skip building functions whose handler, template and build-args are unchanged since their last successful build")
+
+	upCmd.Flags().StringArrayVarP(&deployFlags.envvarOpts, "env", "e", []string{}, "Set one or more environment variables (ENVVAR=VALUE)")
+	upCmd.Flags().StringArrayVarP(&deployFlags.labelOpts, "label", "l", []string{}, "Set one or more label (LABEL=VALUE)")
+	upCmd.Flags().StringArrayVar(&deployFlags.secrets, "secret", []string{}, "Give the function access to a secure secret")
+	upCmd.Flags().BoolVar(&deployFlags.replace, "replace", false, "Remove and re-create existing function(s)")
+	upCmd.Flags().BoolVar(&deployFlags.update, "update", true, "Perform rolling update on existing function(s)")
+	upCmd.Flags().BoolVar(&deployFlags.wait, "wait", false, "Wait for each function's health check to pass after deploying")
+
+	upCmd.Flags().BoolVar(&upSkipPush, "skip-push", false, "Skip pushing the images, i.e. for a local cluster which shares the Docker daemon")
+	upCmd.Flags().BoolVar(&upSkipDeploy, "skip-deploy", false, "Skip deploying the functions")
+	upCmd.Flags().BoolVar(&upKeepGoing, "keep-going", false, "Carry on with the other functions when a stage of one fails, instead of stopping")
+
+	faasCmd.AddCommand(upCmd)
+}
+
+var upCmd = &cobra.Command{
+	Use: `up -f YAML_FILE [--skip-push] [--skip-deploy] [--keep-going]
+  faas-cli up -f YAML_FILE [--regex "REGEX"] [--filter "WILDCARD"]
+              [--build-arg KEY=VALUE ...] [--no-cache] [--changed-only]
+              [--gateway GATEWAY_URL] [--env ENVVAR=VALUE ...]
+              [--label LABEL=VALUE ...] [--secret SECRET_NAME ...]
+              [--replace] [--update=false] [--wait]`,
+	Short: "Build, push and deploy OpenFaaS functions",
+	Long: `Builds, pushes and then deploys each function in the supplied YAML config,
+as "faas-cli build", "faas-cli push" and "faas-cli deploy" would.
+
+A function whose build fails is not pushed, and one whose push fails is not
+deployed. By default up stops at the first failure, with --keep-going the other
+functions carry on through each stage. A table of what happened to each
+function is printed at the end and up fails when any stage of any function
+failed.`,
+	Example: `  faas-cli up -f ./stack.yml
+  faas-cli up -f ./stack.yml --filter "*gif*" --build-arg NPM_TOKEN=$NPM_TOKEN
+  faas-cli up -f ./stack.yml --skip-push --gateway http://127.0.0.1:8080
+  faas-cli up -f ./stack.yml --skip-deploy --changed-only
+  faas-cli up -f ./stack.yml --keep-going`,
+	RunE: runUp,
+}
+
+// upResult is the outcome of each stage of a function, stages which were not
+// run are left out
+type upResult struct {
+	function string
+	stages   map[string]string
+}
+
+func runUp(cmd *cobra.Command, args []string) error {
+	if len(yamlFile) == 0 {
+		return fmt.Errorf("please provide the YAML file of the functions with -f")
+	}
+	if yamlFile == stack.StdinFile {
+		return fmt.Errorf("up reads the YAML file for each stage, give it as a file rather than from stdin")
+	}
+	if deployFlags.update && deployFlags.replace && !upSkipDeploy {
+		return fmt.Errorf("cannot specify --update and --replace at the same time")
+	}
+
+	services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+	if err != nil {
+		return err
+	}
+	if len(services.Functions) == 0 {
+		return fmt.Errorf("no functions in %s matched", yamlFile)
+	}
+	services.Provider.GatewayURL = getGatewayURL(gateway, defaultGateway, services.Provider.GatewayURL)
+	if len(network) > 0 && network != defaultNetwork {
+		services.Provider.Network = network
+	}
+
+	// Dependencies are built and deployed first
+	names, err := stack.DeploymentOrder(services.Functions)
+	if err != nil {
+		return err
+	}
+
+	stages := []string{upBuild}
+	if !upSkipPush {
+		stages = append(stages, upPush)
+	}
+	if !upSkipDeploy {
+		stages = append(stages, upDeploy)
+	}
+
+	results, err := upStack(services, names, stages, upKeepGoing)
+	fmt.Println()
+	printUpSummary(os.Stdout, results, stages)
+	if err != nil {
+		return err
+	}
+	return upStatus(results)
+}
+
+// upStack runs each stage for every function which passed the stage before,
+// it stops at the first failure unless keepGoing is set
+func upStack(services *stack.Services, names []string, stages []string, keepGoing bool) ([]upResult, error) {
+	results := make([]upResult, len(names))
+	for i, name := range names {
+		results[i] = upResult{function: name, stages: map[string]string{}}
+	}
+
+	var changePolicy *policy.Policy
+	for _, stage := range stages {
+		if stage == upDeploy {
+			var err error
+			if changePolicy, err = loadPolicy(services); err != nil {
+				return results, err
+			}
+		}
+
+		for i, name := range names {
+			if failedStage(results[i]) != "" {
+				continue
+			}
+
+			var err error
+			if stage == upDeploy {
+				err = upDeployFunction(services, name, changePolicy)
+			} else {
+				err = runUpStage(upStageArgs(stage, name))
+			}
+
+			if err != nil {
+				results[i].stages[stage] = "failed"
+				fmt.Printf("The %s of %s failed: %s\n", stage, name, err)
+				if !keepGoing {
+					return results, nil
+				}
+				continue
+			}
+			results[i].stages[stage] = "done"
+		}
+	}
+	return results, nil
+}
+
+// upStageArgs passes the stack and the flags shared with faas-cli build and
+// push to the faas-cli which runs one stage of one function
+func upStageArgs(stage string, name string) []string {
+	args := []string{stage, "-f", yamlFile}
+	for _, overlayFile := range stack.OverlayFiles {
+		args = append(args, "-f", overlayFile)
+	}
+	args = append(args, "--filter", name, "--template-dir", stack.TemplateDirectory)
+	if stack.EnvSubst {
+		args = append(args, "--envsubst")
+	}
+
+	if stage == upBuild {
+		for _, buildArg := range buildArgOpts {
+			args = append(args, "--build-arg", buildArg)
+		}
+		if nocache {
+			args = append(args, "--no-cache")
+		}
+		if changedOnly {
+			args = append(args, "--changed-only")
+		}
+	}
+	return args
+}
+
+// upDeployFunction deploys one function of the stack, a deployment which
+// the provider rejects is an error
+func upDeployFunction(services *stack.Services, name string, changePolicy *policy.Policy) error {
+	functionServices := *services
+	functionServices.Functions = map[string]stack.Function{name: services.Functions[name]}
+
+	failed, err := deployFunctions(&functionServices, deployFlags, changePolicy)
+	if err != nil {
+		return err
+	}
+	if failed[name] {
+		return fmt.Errorf("the gateway did not deploy it")
+	}
+	return nil
+}
+
+// failedStage is the stage of the result which failed, or empty
+func failedStage(result upResult) string {
+	for stage, status := range result.stages {
+		if status == "failed" {
+			return stage
+		}
+	}
+	return ""
+}
+
+// upStatus fails when any function failed a stage, naming each of them
+func upStatus(results []upResult) error {
+	var failures []string
+	for _, result := range results {
+		if stage := failedStage(result); len(stage) > 0 {
+			failures = append(failures, fmt.Sprintf("%s (%s)", result.function, stage))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d function(s) failed: %s", len(failures), len(results), strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+func printUpSummary(w io.Writer, results []upResult, stages []string) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(table, "FUNCTION\t%s\n", strings.ToUpper(strings.Join(stages, "\t")))
+	for _, result := range results {
+		row := []string{result.function}
+		for _, stage := range stages {
+			status, ok := result.stages[stage]
+			if !ok {
+				status = "-"
+			}
+			row = append(row, status)
+		}
+		fmt.Fprintln(table, strings.Join(row, "\t"))
+	}
+	table.Flush()
+}
@@ -0,0 +1,167 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/openfaas/faas-cli/test"
+)
+
+// stubUpStages records the build and push of each function, failing those in
+// failures, and returns a func to undo the stub
+func stubUpStages(calls *[]string, failures map[string]bool) func() {
+	original := runUpStage
+	runUpStage = func(args []string) error {
+		call := args[0] + " " + args[4]
+		*calls = append(*calls, call)
+		if failures[call] {
+			return fmt.Errorf("exit status 1")
+		}
+		return nil
+	}
+	return func() { runUpStage = original }
+}
+
+func upServices(gatewayURL string) *stack.Services {
+	return &stack.Services{
+		Provider: stack.Provider{GatewayURL: gatewayURL},
+		Functions: map[string]stack.Function{
+			"api":    {Image: "acme/api:0.1"},
+			"worker": {Image: "acme/worker:0.1"},
+		},
+	}
+}
+
+func Test_upStack_StopsAtFirstFailure(t *testing.T) {
+	var calls []string
+	defer stubUpStages(&calls, map[string]bool{"build api": true})()
+
+	var results []upResult
+	test.CaptureStdout(func() {
+		results, _ = upStack(upServices(""), []string{"api", "worker"}, []string{upBuild, upPush, upDeploy}, false)
+	})
+
+	if want := []string{"build api"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("want %v, got %v", want, calls)
+	}
+	if err := upStatus(results); err == nil || err.Error() != "1 of 2 function(s) failed: api (build)" {
+		t.Errorf("want api's build to fail up, got %v", err)
+	}
+}
+
+func Test_upStack_KeepGoing(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusInternalServerError,
+		},
+	})
+	defer s.Close()
+
+	var calls []string
+	defer stubUpStages(&calls, map[string]bool{"push api": true})()
+
+	var results []upResult
+	stdOut := test.CaptureStdout(func() {
+		results, _ = upStack(upServices(s.URL), []string{"api", "worker"}, []string{upBuild, upPush, upDeploy}, true)
+	})
+
+	if want := []string{"build api", "build worker", "push api", "push worker"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("want %v, got %v", want, calls)
+	}
+	if !strings.Contains(stdOut, "Deploying: worker.") || strings.Contains(stdOut, "Deploying: api.") {
+		t.Errorf("want only worker deployed:\n%s", stdOut)
+	}
+	if err := upStatus(results); err == nil || err.Error() != "2 of 2 function(s) failed: api (push), worker (deploy)" {
+		t.Errorf("want api's push and worker's deploy to fail up, got %v", err)
+	}
+
+	var summary bytes.Buffer
+	printUpSummary(&summary, results, []string{upBuild, upPush, upDeploy})
+	want := `FUNCTION  BUILD  PUSH    DEPLOY
+api       done   failed  -
+worker    done   done    failed
+`
+	if summary.String() != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, summary.String())
+	}
+}
+
+func Test_upStageArgs(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	originalBuildArgs, originalTemplates := buildArgOpts, stack.TemplateDirectory
+	defer func() { buildArgOpts, stack.TemplateDirectory, nocache = originalBuildArgs, originalTemplates, false }()
+
+	yamlFile, stack.OverlayFiles, stack.TemplateDirectory = "stack.yml", []string{"prod.yml"}, "./template"
+	buildArgOpts, nocache = []string{"NPM_TOKEN=abc"}, true
+
+	want := []string{"build", "-f", "stack.yml", "-f", "prod.yml", "--filter", "api", "--template-dir", "./template", "--build-arg", "NPM_TOKEN=abc", "--no-cache"}
+	if got := upStageArgs(upBuild, "api"); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+	want = []string{"push", "-f", "stack.yml", "-f", "prod.yml", "--filter", "api", "--template-dir", "./template"}
+	if got := upStageArgs(upPush, "api"); !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func Test_up_SkipPush(t *testing.T) {
+	resetForTest()
+	defer resetForTest()
+
+	dir, err := ioutil.TempDir("", "faas-cli-up")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	yamlPath := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(yamlPath, []byte(`provider:
+  name: faas
+functions:
+  api:
+    image: acme/api:0.1
+`), 0600)
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/functions",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	var calls []string
+	defer stubUpStages(&calls, nil)()
+
+	yamlFile, gateway, upSkipPush = yamlPath, s.URL, true
+	defer func() { gateway, upSkipPush = defaultGateway, false }()
+
+	stdOut := test.CaptureStdout(func() {
+		if err := runUp(upCmd, nil); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if want := []string{"build api"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("want %v, got %v", want, calls)
+	}
+	if !strings.Contains(stdOut, "FUNCTION  BUILD  DEPLOY\napi       done   done") {
+		t.Errorf("want a summary without push:\n%s", stdOut)
+	}
+}
@@ -4,45 +4,223 @@
 package commands
 
 import (
+	"encoding/json"
 	"fmt"
+	"os/exec"
 	"runtime"
+	"strings"
 
 	"github.com/morikuni/aec"
+	"github.com/openfaas/faas-cli/proxy"
 	"github.com/openfaas/faas-cli/version"
 	"github.com/spf13/cobra"
 )
 
 // GitCommit injected at build-time
 var (
-	shortVersion bool
+	shortVersion  bool
+	versionOutput string
+
+	// rootVersion is faas-cli --version, which runs the version command
+	rootVersion bool
 )
 
+// containerRuntime gives the name and version of the container runtime used
+// to build functions, it is swapped out in tests
+var containerRuntime = func() (string, string, error) {
+	if _, err := lookPath("docker"); err == nil {
+		serverVersion, err := dockerServerVersion()
+		if err != nil {
+			return "docker", "", fmt.Errorf("unable to reach the Docker daemon: %s", serverVersion)
+		}
+		return "docker", serverVersion, nil
+	}
+
+	if _, err := lookPath("podman"); err == nil {
+		out, err := exec.Command("podman", "version", "--format", "{{.Client.Version}}").CombinedOutput()
+		if err != nil {
+			return "podman", "", fmt.Errorf("unable to run podman: %s", strings.TrimSpace(string(out)))
+		}
+		return "podman", strings.TrimSpace(string(out)), nil
+	}
+
+	return "", "", fmt.Errorf("neither docker nor podman was found in your PATH")
+}
+
+// versionReport is written by version --output json
+type versionReport struct {
+	CLI              cliVersion      `json:"cli"`
+	ContainerRuntime *runtimeVersion `json:"containerRuntime,omitempty"`
+	Gateway          *gatewayVersion `json:"gateway,omitempty"`
+	Warnings         []string        `json:"warnings,omitempty"`
+}
+
+type cliVersion struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate,omitempty"`
+	GoVersion string `json:"goVersion"`
+	Platform  string `json:"platform"`
+}
+
+type runtimeVersion struct {
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+type gatewayVersion struct {
+	URL      string           `json:"url"`
+	Version  string           `json:"version,omitempty"`
+	SHA      string           `json:"sha,omitempty"`
+	Provider *providerVersion `json:"provider,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
+
+type providerVersion struct {
+	Name          string `json:"name"`
+	Version       string `json:"version,omitempty"`
+	Orchestration string `json:"orchestration,omitempty"`
+}
+
 func init() {
 	versionCmd.Flags().BoolVar(&shortVersion, "short-version", false, "Just print Git SHA")
+	versionCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	versionCmd.Flags().StringVarP(&versionOutput, "output", "o", "text", "Output format: text or json")
+
+	faasCmd.Flags().BoolVar(&rootVersion, "version", false, "Display the version information, as the version command does")
 
 	faasCmd.AddCommand(versionCmd)
 }
 
 // versionCmd displays version information
 var versionCmd = &cobra.Command{
-	Use:   "version [--short-version]",
+	Use:   "version [--short-version] [--gateway GATEWAY_URL] [--output text|json]",
 	Short: "Display the clients version information",
 	Long: fmt.Sprintf(`The version command returns the current clients version information.
 
-This currently consists of the GitSHA from which the client was built.
+This consists of the GitSHA from which the client was built, its version, build
+date and Go version, the version of the local container runtime and the
+versions of the gateway and its provider when the gateway can be reached.
+Combinations which are known not to work together are printed as warnings.
 - https://github.com/openfaas/faas-cli/tree/%s`, version.GitCommit),
 	Example: `  faas-cli version
-  faas-cli version --short-version`,
-	Run: runVersion,
+  faas-cli version --short-version
+  faas-cli version --gateway https://openfaas.example.com --output json
+  faas-cli --version`,
+	RunE: runVersion,
 }
 
-func runVersion(cmd *cobra.Command, args []string) {
+func runVersion(cmd *cobra.Command, args []string) error {
 	if shortVersion {
 		fmt.Println(version.BuildVersion())
+		return nil
+	}
+	if versionOutput != "text" && versionOutput != "json" {
+		return fmt.Errorf("--output must be text or json, not %s", versionOutput)
+	}
+
+	report := buildVersionReport(getGatewayURL(gateway, defaultGateway, ""))
+	if versionOutput == "json" {
+		out, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+
+	printFiglet()
+	printVersionReport(report)
+	return nil
+}
+
+// buildVersionReport never fails, a runtime or gateway which cannot be
+// reached is reported with its error
+func buildVersionReport(gatewayAddress string) versionReport {
+	report := versionReport{
+		CLI: cliVersion{
+			Version:   version.BuildVersion(),
+			Commit:    version.GitCommit,
+			BuildDate: version.BuildDate,
+			GoVersion: runtime.Version(),
+			Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+		},
+	}
+	versions := map[string]string{}
+
+	name, runtimeRelease, err := containerRuntime()
+	report.ContainerRuntime = &runtimeVersion{Name: name, Version: runtimeRelease}
+	if err != nil {
+		report.ContainerRuntime.Error = err.Error()
 	} else {
-		printFiglet()
-		fmt.Printf("Commit: %s\n", version.GitCommit)
-		fmt.Printf("Version: %s\n", version.BuildVersion())
+		versions[name] = runtimeRelease
+	}
+
+	report.Gateway = &gatewayVersion{URL: gatewayAddress}
+	info, err := proxy.GetSystemInfo(gatewayAddress)
+	switch {
+	case err != nil:
+		report.Gateway.Error = err.Error()
+	case info == nil:
+		report.Gateway.Error = "the gateway does not report its version"
+	default:
+		report.Gateway.Version = info.Version.Release
+		report.Gateway.SHA = info.Version.SHA
+		versions["gateway"] = info.Version.Release
+		if len(info.Provider.Name) > 0 {
+			report.Gateway.Provider = &providerVersion{
+				Name:          info.Provider.Name,
+				Version:       info.Provider.Version,
+				Orchestration: info.Provider.Orchestration,
+			}
+			versions[info.Provider.Name] = info.Provider.Version
+		}
+	}
+
+	report.Warnings = componentWarnings(versions)
+	return report
+}
+
+func printVersionReport(report versionReport) {
+	fmt.Printf("Commit: %s\n", report.CLI.Commit)
+	fmt.Printf("Version: %s\n", report.CLI.Version)
+	if len(report.CLI.BuildDate) > 0 {
+		fmt.Printf("Build date: %s\n", report.CLI.BuildDate)
+	}
+	fmt.Printf("Go version: %s\n", report.CLI.GoVersion)
+	fmt.Printf("Platform: %s\n", report.CLI.Platform)
+
+	if containerRuntime := report.ContainerRuntime; containerRuntime != nil {
+		if len(containerRuntime.Error) > 0 {
+			fmt.Printf("Container runtime: %s\n", containerRuntime.Error)
+		} else {
+			fmt.Printf("Container runtime: %s %s\n", containerRuntime.Name, containerRuntime.Version)
+		}
+	}
+
+	if gateway := report.Gateway; gateway != nil {
+		fmt.Printf("\nGateway: %s\n", gateway.URL)
+		if len(gateway.Error) > 0 {
+			fmt.Printf(" unavailable: %s\n", gateway.Error)
+		} else {
+			fmt.Printf(" version: %s\n", gateway.Version)
+			fmt.Printf(" sha: %s\n", gateway.SHA)
+		}
+		if provider := gateway.Provider; provider != nil {
+			fmt.Printf("\nProvider: %s\n", provider.Name)
+			fmt.Printf(" version: %s\n", provider.Version)
+			if len(provider.Orchestration) > 0 {
+				fmt.Printf(" orchestration: %s\n", provider.Orchestration)
+			}
+		}
+	}
+
+	if len(report.Warnings) > 0 {
+		fmt.Println()
+	}
+	for _, warning := range report.Warnings {
+		fmt.Printf("Warning: %s.\n", warning)
 	}
 }
 
@@ -51,7 +229,7 @@ func printFiglet() {
 	if runtime.GOOS == "windows" {
 		figletColoured = aec.GreenF.Apply(figletStr)
 	}
-	fmt.Printf(figletColoured)
+	fmt.Print(figletColoured)
 }
 
 const figletStr = `  ___                   _____           ____
@@ -4,6 +4,9 @@
 package commands
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"regexp"
 	"testing"
 
@@ -58,3 +61,95 @@ func Test_addVersion_short_version(t *testing.T) {
 		t.Fatalf("Output is not as expected:\n%s", stdOut)
 	}
 }
+
+func stubContainerRuntime(name string, release string, err error) func() {
+	original := containerRuntime
+	containerRuntime = func() (string, string, error) {
+		return name, release, err
+	}
+	return func() {
+		containerRuntime = original
+	}
+}
+
+func Test_version_JSON(t *testing.T) {
+	resetForTest()
+	defer stubContainerRuntime("docker", "17.03.1-ce", nil)()
+	defer func() {
+		versionOutput = "text"
+	}()
+	shortVersion = false
+	version.GitCommit = "sha-test"
+	version.Version = "0.7.0"
+	version.BuildDate = "2018-06-01T12:00:00Z"
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/info",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: map[string]interface{}{
+				"provider": map[string]string{"provider": "faas-netes", "version": "0.11.1", "orchestration": "kubernetes"},
+				"version":  map[string]string{"release": "0.12.0", "sha": "abc123"},
+			},
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"version", "--gateway", s.URL, "--output", "json"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var report versionReport
+	if err := json.Unmarshal([]byte(stdOut), &report); err != nil {
+		t.Fatalf("want JSON, got %s: %s", stdOut, err)
+	}
+	if report.CLI.Version != "0.7.0" || report.CLI.Commit != "sha-test" || report.CLI.BuildDate != "2018-06-01T12:00:00Z" || len(report.CLI.GoVersion) == 0 {
+		t.Errorf("want the CLI's version, got %+v", report.CLI)
+	}
+	if report.ContainerRuntime == nil || report.ContainerRuntime.Name != "docker" || report.ContainerRuntime.Version != "17.03.1-ce" {
+		t.Errorf("want the container runtime, got %+v", report.ContainerRuntime)
+	}
+	if report.Gateway == nil || report.Gateway.Version != "0.12.0" || report.Gateway.SHA != "abc123" {
+		t.Fatalf("want the gateway's version, got %+v", report.Gateway)
+	}
+	if provider := report.Gateway.Provider; provider == nil || provider.Name != "faas-netes" || provider.Orchestration != "kubernetes" {
+		t.Errorf("want the provider's version, got %+v", provider)
+	}
+	if len(report.Warnings) != 3 {
+		t.Errorf("want warnings for docker, the gateway and faas-netes, got %v", report.Warnings)
+	}
+}
+
+func Test_version_UnreachableGateway(t *testing.T) {
+	resetForTest()
+	defer stubContainerRuntime("", "", fmt.Errorf("neither docker nor podman was found in your PATH"))()
+	defer func() {
+		rootVersion = false
+	}()
+	shortVersion = false
+	version.GitCommit = "sha-test"
+
+	s := test.MockHttpServerStatus(t, http.StatusNotFound)
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"--version"})
+		gateway = s.URL
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, want := range []string{"Commit: sha-test", "Container runtime: neither docker nor podman", "unavailable: the gateway does not report its version"} {
+		if found, err := regexp.MatchString(regexp.QuoteMeta(want), stdOut); err != nil || !found {
+			t.Errorf("want %q in the output:\n%s", want, stdOut)
+		}
+	}
+	if found, _ := regexp.MatchString("Warning:", stdOut); found {
+		t.Errorf("want no warnings without versions:\n%s", stdOut)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/stack"
+	"github.com/spf13/cobra"
+)
+
+// Conditions which faas-cli wait --for can wait for
+const (
+	waitReady     = "ready"
+	waitReplicas  = "replicas"
+	waitInvocable = "invocable"
+)
+
+var (
+	waitFor        string
+	waitTimeout    time.Duration
+	waitInterval   time.Duration
+	waitHealthPath string
+)
+
+// waitCondition is a parsed --for, Replicas is only set for replicas=N
+type waitCondition struct {
+	Kind     string
+	Replicas uint64
+}
+
+func init() {
+	waitCmd.Flags().StringVarP(&gateway, "gateway", "g", defaultGateway, "Gateway URL starting with http(s)://")
+	waitCmd.Flags().StringVar(&waitFor, "for", waitReady, "Condition to wait for: ready, replicas=N or invocable")
+	waitCmd.Flags().DurationVar(&waitTimeout, "timeout", 2*time.Minute, "How long to wait before giving up")
+	waitCmd.Flags().DurationVar(&waitInterval, "interval", time.Second, "How often the gateway is asked")
+	waitCmd.Flags().StringVar(&waitHealthPath, "health-path", proxy.DefaultHealthPath, "Path probed through the gateway for --for invocable")
+
+	faasCmd.AddCommand(waitCmd)
+}
+
+var waitCmd = &cobra.Command{
+	Use:   `wait [FUNCTION_NAME...] [--for ready|replicas=N|invocable] [--timeout DURATION]`,
+	Short: "Wait until functions meet a condition",
+	Long: `Blocks until each function meets the condition on the gateway, or exits with
+an error when the timeout is reached first, so that scripts and pipelines don't
+need their own polling.
+
+  ready        the function is deployed and has at least one available replica
+  replicas=N   the function has at least N available replicas
+  invocable    the function answers its health path through the gateway
+
+Every function in the YAML file given with -f is waited for when no names are
+given.`,
+	Example: `  faas-cli wait figlet
+  faas-cli wait figlet --for replicas=3 --timeout 5m
+  faas-cli deploy -f stack.yml && faas-cli wait -f stack.yml --for invocable
+  faas-cli wait figlet --namespace staging --for invocable --health-path /healthz`,
+	RunE: runWait,
+}
+
+func runWait(cmd *cobra.Command, args []string) error {
+	condition, err := parseWaitCondition(waitFor)
+	if err != nil {
+		return err
+	}
+
+	names := args
+	var yamlGateway string
+	if len(yamlFile) > 0 {
+		services, err := stack.ParseYAMLFile(yamlFile, regex, filter)
+		if err != nil {
+			return err
+		}
+		yamlGateway = services.Provider.GatewayURL
+		if len(names) == 0 {
+			for name := range services.Functions {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("please provide the name of a function to wait for, or a YAML file with -f")
+	}
+
+	gatewayAddress := getGatewayURL(gateway, defaultGateway, yamlGateway)
+	return waitForCondition(gatewayAddress, names, functionNamespace, condition, waitTimeout, waitInterval)
+}
+
+// parseWaitCondition parses ready, invocable or replicas=N
+func parseWaitCondition(value string) (waitCondition, error) {
+	switch value {
+	case waitReady, waitInvocable:
+		return waitCondition{Kind: value}, nil
+	}
+
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) == 2 && parts[0] == waitReplicas {
+		replicas, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil || replicas == 0 {
+			return waitCondition{}, fmt.Errorf("replicas must be a number above 0, not %s", parts[1])
+		}
+		return waitCondition{Kind: waitReplicas, Replicas: replicas}, nil
+	}
+	return waitCondition{}, fmt.Errorf("--for must be ready, replicas=N or invocable, not %s", value)
+}
+
+// String gives the condition as it is given to --for
+func (c waitCondition) String() string {
+	if c.Kind == waitReplicas {
+		return fmt.Sprintf("%s=%d", waitReplicas, c.Replicas)
+	}
+	return c.Kind
+}
+
+// waitForCondition asks the gateway about each function in turn until all
+// of them meet the condition or the timeout is reached
+func waitForCondition(gatewayAddress string, names []string, namespace string, condition waitCondition, timeout time.Duration, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+
+	for _, name := range names {
+		qualified := qualifiedName(name, namespace)
+		for {
+			met, state, err := checkWaitCondition(gatewayAddress, name, namespace, condition)
+			if err != nil {
+				return err
+			}
+			if met {
+				fmt.Printf("Function %s is %s.\n", qualified, condition)
+				break
+			}
+
+			if time.Now().Add(interval).After(deadline) {
+				return fmt.Errorf("function %s did not become %s within %s, %s", qualified, condition, timeout, state)
+			}
+			time.Sleep(interval)
+		}
+	}
+	return nil
+}
+
+// checkWaitCondition reports whether the function meets the condition now,
+// and otherwise describes what was found
+func checkWaitCondition(gatewayAddress string, name string, namespace string, condition waitCondition) (bool, string, error) {
+	status, found, err := proxy.GetFunctionInfoInNamespace(gatewayAddress, name, namespace)
+	if err != nil {
+		return false, "", err
+	}
+	if !found {
+		return false, "it is not deployed", nil
+	}
+
+	switch condition.Kind {
+	case waitReplicas:
+		return status.AvailableReplicas >= condition.Replicas, fmt.Sprintf("%d of %d replicas are available", status.AvailableReplicas, condition.Replicas), nil
+	case waitInvocable:
+		if status.AvailableReplicas == 0 {
+			return false, "no replicas are available", nil
+		}
+		invocable, err := proxy.FunctionReady(gatewayAddress, qualifiedName(name, namespace), waitHealthPath)
+		return invocable, fmt.Sprintf("%s did not answer", waitHealthPath), err
+	}
+	return status.AvailableReplicas > 0, "no replicas are available", nil
+}
@@ -0,0 +1,141 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package commands
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/proxy"
+	"github.com/openfaas/faas-cli/test"
+)
+
+func resetWaitFlags() {
+	waitFor = waitReady
+	waitTimeout = 2 * time.Minute
+	waitInterval = time.Second
+	waitHealthPath = proxy.DefaultHealthPath
+}
+
+func Test_parseWaitCondition(t *testing.T) {
+	cases := []struct {
+		value string
+		want  waitCondition
+		err   bool
+	}{
+		{value: "ready", want: waitCondition{Kind: waitReady}},
+		{value: "invocable", want: waitCondition{Kind: waitInvocable}},
+		{value: "replicas=3", want: waitCondition{Kind: waitReplicas, Replicas: 3}},
+		{value: "replicas=0", err: true},
+		{value: "replicas=many", err: true},
+		{value: "healthy", err: true},
+	}
+
+	for _, c := range cases {
+		got, err := parseWaitCondition(c.value)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: want an error, got %+v", c.value, got)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: want %+v, got %+v %v", c.value, c.want, got, err)
+		}
+	}
+}
+
+func Test_wait_Replicas(t *testing.T) {
+	resetForTest()
+	defer resetWaitFlags()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusNotFound,
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       proxy.FunctionStatus{Name: "figlet", Replicas: 3, AvailableReplicas: 1},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       proxy.FunctionStatus{Name: "figlet", Replicas: 3, AvailableReplicas: 3},
+		},
+	})
+	defer s.Close()
+
+	stdOut := test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"wait", "figlet", "--gateway=" + s.URL, "--for", "replicas=3", "--interval", "1ms"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+
+	if !strings.Contains(stdOut, "Function figlet is replicas=3.") {
+		t.Errorf("want the condition reported, got:\n%s", stdOut)
+	}
+}
+
+func Test_wait_Invocable(t *testing.T) {
+	resetForTest()
+	defer resetWaitFlags()
+
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       proxy.FunctionStatus{Name: "figlet", AvailableReplicas: 1},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/function/figlet/healthz",
+			ResponseStatusCode: http.StatusServiceUnavailable,
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       proxy.FunctionStatus{Name: "figlet", AvailableReplicas: 1},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/function/figlet/healthz",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	test.CaptureStdout(func() {
+		faasCmd.SetArgs([]string{"wait", "figlet", "--gateway=" + s.URL, "--for", "invocable", "--health-path", "/healthz", "--interval", "1ms"})
+		if err := faasCmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	})
+}
+
+func Test_waitForCondition_Timeout(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       proxy.FunctionStatus{Name: "figlet", AvailableReplicas: 0},
+		},
+	})
+	defer s.Close()
+
+	err := waitForCondition(s.URL, []string{"figlet"}, "", waitCondition{Kind: waitReady}, time.Millisecond, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "did not become ready within 1ms, no replicas are available") {
+		t.Errorf("want a timeout describing the function, got %v", err)
+	}
+}
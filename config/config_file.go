@@ -25,14 +25,164 @@ var (
 
 // ConfigFile for OpenFaaS CLI exclusively.
 type ConfigFile struct {
-	AuthConfigs []AuthConfig `yaml:"auths"`
-	FilePath    string       `yaml:"-"`
+	AuthConfigs []AuthConfig    `yaml:"auths"`
+	Templates   *TemplateConfig `yaml:"templates,omitempty"`
+
+	// ImageOverrides rewrites image registries or prefixes on deploy, i.e.
+	// docker.io: internal-mirror.example.com
+	ImageOverrides map[string]string `yaml:"image_overrides,omitempty"`
+
+	Git *GitConfig `yaml:"git,omitempty"`
+
+	Build *BuildConfig `yaml:"build,omitempty"`
+
+	Credentials *CredentialsConfig `yaml:"credentials,omitempty"`
+
+	// Contexts names gateways, i.e. staging: https://staging.example.com
+	Contexts map[string]string `yaml:"contexts,omitempty"`
+
+	Audit *AuditConfig `yaml:"audit,omitempty"`
+
+	Tracing *TracingConfig `yaml:"tracing,omitempty"`
+
+	// RateLimits bound the requests sent to a gateway, keyed by the name of
+	// its context or its URL
+	RateLimits map[string]RateLimitConfig `yaml:"rate_limits,omitempty"`
+
+	// Registries are named credentials which functions in a stack push
+	// with, given by registry_credential
+	Registries map[string]RegistryCredential `yaml:"registries,omitempty"`
+
+	FilePath string `yaml:"-"`
+}
+
+// RegistryCredential logs in to one registry, from a docker config of its
+// own, a docker credential helper, or a username and a password read from
+// the environment
+type RegistryCredential struct {
+	// Server is the registry's host, i.e. ghcr.io
+	Server string `yaml:"server,omitempty"`
+
+	// DockerConfig is a folder holding a config.json, such as one written
+	// by registry-login --docker-config
+	DockerConfig string `yaml:"docker_config,omitempty"`
+
+	// CredentialHelper is the suffix of a docker-credential-* program, i.e.
+	// ecr-login or gcloud
+	CredentialHelper string `yaml:"credential_helper,omitempty"`
+
+	Username string `yaml:"username,omitempty"`
+
+	// PasswordEnv names the environment variable holding the password or
+	// token, so that it is not written in the config file
+	PasswordEnv string `yaml:"password_env,omitempty"`
+
+	// PullSecret is the secret in the provider which holds the credentials
+	// for pulling the image, it is added to the secrets of the function
+	PullSecret string `yaml:"pull_secret,omitempty"`
+}
+
+// TemplateConfig controls how templates are downloaded
+type TemplateConfig struct {
+	// Retries is the number of attempts made for each source, defaults to 3
+	Retries int `yaml:"retries,omitempty"`
+
+	// RetryBackoff is the wait before the first retry which then doubles, defaults to 2s
+	RetryBackoff string `yaml:"retry_backoff,omitempty"`
+
+	// Mirrors lists fallback git repositories or .zip/.tar.gz archives by the repository they mirror
+	Mirrors map[string][]string `yaml:"mirrors,omitempty"`
+
+	// Checksums gives the expected SHA256 of an archive by its URL
+	Checksums map[string]string `yaml:"checksums,omitempty"`
+}
+
+// BuildConfig holds settings for faas-cli build
+type BuildConfig struct {
+	// Builder is the backend images are built with, defaults to docker
+	Builder string `yaml:"builder,omitempty"`
+
+	// RemoteHost is the Docker daemon used by the remote builder, i.e.
+	// ssh://user@builder or tcp://builder:2376
+	RemoteHost string `yaml:"remote_host,omitempty"`
+
+	// RedactPatterns are regular expressions masked in build output alongside
+	// the values of --build-arg and --build-secret
+	RedactPatterns []string `yaml:"redact_patterns,omitempty"`
+
+	// Profiles are named build settings, i.e. for a corporate package
+	// mirror, chosen with --build-profile or build.profile in a stack
+	Profiles map[string]BuildProfile `yaml:"profiles,omitempty"`
+}
+
+// BuildProfile is a collection of build-args, secrets and certificates which
+// are given to a build together
+type BuildProfile struct {
+	// BuildArgs may reference environment variables, i.e. ${PIP_TOKEN}
+	BuildArgs map[string]string `yaml:"build_args,omitempty"`
+
+	// Secrets maps the id of each BuildKit secret to the file holding it,
+	// i.e. npmrc: ~/.npmrc
+	Secrets map[string]string `yaml:"secrets,omitempty"`
+
+	// Certs are PEM files of CA certificates, given to the build as the
+	// ca-certificates secret
+	Certs []string `yaml:"certs,omitempty"`
+}
+
+// AuditConfig turns on the local audit log of deployments, removals and invocations
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+
+	// Directory holds the log files, defaults to ~/.openfaas/audit
+	Directory string `yaml:"directory,omitempty"`
+}
+
+// TracingConfig links invocations to a tracing backend such as Jaeger or Tempo
+type TracingConfig struct {
+	// URL of a trace with {trace_id} in place of its id, i.e.
+	// http://jaeger:16686/trace/{trace_id}
+	URL string `yaml:"url,omitempty"`
+}
+
+// RateLimitConfig bounds the requests sent to a gateway, unset values keep
+// the defaults
+type RateLimitConfig struct {
+	// Concurrency is how many requests may be in flight at once
+	Concurrency int `yaml:"concurrency,omitempty"`
+
+	// RequestsPerSecond is how often a request may be started
+	RequestsPerSecond float64 `yaml:"requests_per_second,omitempty"`
+
+	// MaxRetries is how many times a request answered with 429 or 503 is
+	// sent again after backing off
+	MaxRetries int `yaml:"max_retries,omitempty"`
+}
+
+// GitConfig controls SSH authentication when cloning repositories
+type GitConfig struct {
+	// SSHKey is the private key used for every repository, ssh-agent is used when empty
+	SSHKey string `yaml:"ssh_key,omitempty"`
+
+	// DeployKeys gives a private key by repository URL, overriding SSHKey
+	DeployKeys map[string]string `yaml:"deploy_keys,omitempty"`
+
+	// KnownHosts replaces ~/.ssh/known_hosts for verifying host keys
+	KnownHosts string `yaml:"known_hosts,omitempty"`
+
+	// HostKeyChecking is yes, no or accept-new
+	HostKeyChecking string `yaml:"host_key_checking,omitempty"`
 }
 
 type AuthConfig struct {
 	Gateway string `yaml:"gateway,omitempty"`
-	Auth    string `yaml:"auth,omitempty"`
-	Token   string `yaml:"token,omitempty"`
+
+	// Auth is BasicAuth or OAuth2Auth
+	Auth  string `yaml:"auth,omitempty"`
+	Token string `yaml:"token,omitempty"`
+
+	// Store is where the credentials are kept, empty for the config file
+	Store string `yaml:"store,omitempty"`
 }
 
 // New initializes a config file for the given file path
@@ -123,9 +273,159 @@ func (configFile *ConfigFile) load() error {
 	if len(conf.AuthConfigs) > 0 {
 		configFile.AuthConfigs = conf.AuthConfigs
 	}
+	configFile.Templates = conf.Templates
+	configFile.ImageOverrides = conf.ImageOverrides
+	configFile.Git = conf.Git
+	configFile.Build = conf.Build
+	configFile.Credentials = conf.Credentials
+	configFile.Contexts = conf.Contexts
+	configFile.Audit = conf.Audit
+	configFile.Tracing = conf.Tracing
+	configFile.RateLimits = conf.RateLimits
+	configFile.Registries = conf.Registries
 	return nil
 }
 
+// ReadConfigFile reads the config file from the default location, an empty
+// config is returned when the file has not been written yet
+func ReadConfigFile() (*ConfigFile, error) {
+	if !fileExists() {
+		return &ConfigFile{AuthConfigs: make([]AuthConfig, 0)}, nil
+	}
+
+	configPath, err := EnsureFile()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := New(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.load(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// LookupContext gives the gateway URL of a named context, a URL is returned as it is
+func LookupContext(name string) (string, error) {
+	if strings.HasPrefix(name, "http://") || strings.HasPrefix(name, "https://") {
+		return name, nil
+	}
+
+	cfg, err := ReadConfigFile()
+	if err != nil {
+		return "", err
+	}
+
+	gateway, ok := cfg.Contexts[name]
+	if !ok {
+		return "", fmt.Errorf("context %s was not found in the contexts of the config file", name)
+	}
+	return gateway, nil
+}
+
+// SaveContext names a gateway in the contexts of the config file, replacing
+// the gateway a context of the same name pointed to
+func SaveContext(name string, gateway string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("context name cannot be an empty string")
+	}
+	if strings.Contains(name, "://") {
+		return fmt.Errorf("context name %s cannot be a URL", name)
+	}
+
+	configPath, err := EnsureFile()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := New(configPath)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.load(); err != nil {
+		return err
+	}
+
+	if cfg.Contexts == nil {
+		cfg.Contexts = map[string]string{}
+	}
+	cfg.Contexts[name] = strings.TrimRight(gateway, "/")
+	return cfg.save()
+}
+
+// RemoveContext removes a context from the config file, giving the gateway it
+// pointed to
+func RemoveContext(name string) (string, error) {
+	if !fileExists() {
+		return "", fmt.Errorf("config file not found")
+	}
+
+	configPath, err := EnsureFile()
+	if err != nil {
+		return "", err
+	}
+
+	cfg, err := New(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	if err := cfg.load(); err != nil {
+		return "", err
+	}
+
+	gateway, ok := cfg.Contexts[name]
+	if !ok {
+		return "", fmt.Errorf("context %s was not found in the contexts of the config file", name)
+	}
+	delete(cfg.Contexts, name)
+	return gateway, cfg.save()
+}
+
+// LookupRateLimit gives the rate limit of a gateway from the config file,
+// matched by its URL or the name of a context which points to it
+func LookupRateLimit(gateway string) (RateLimitConfig, bool) {
+	cfg, err := ReadConfigFile()
+	if err != nil || len(cfg.RateLimits) == 0 {
+		return RateLimitConfig{}, false
+	}
+
+	gateway = strings.TrimRight(gateway, "/")
+	if limit, ok := cfg.RateLimits[gateway]; ok {
+		return limit, true
+	}
+	for name, url := range cfg.Contexts {
+		if strings.TrimRight(url, "/") != gateway {
+			continue
+		}
+		if limit, ok := cfg.RateLimits[name]; ok {
+			return limit, true
+		}
+	}
+	return RateLimitConfig{}, false
+}
+
+// LookupRegistry gives the named registry credential from the registries of
+// the config file
+func LookupRegistry(name string) (RegistryCredential, error) {
+	cfg, err := ReadConfigFile()
+	if err != nil {
+		return RegistryCredential{}, err
+	}
+
+	credential, ok := cfg.Registries[name]
+	if !ok {
+		return RegistryCredential{}, fmt.Errorf("registry credential %s was not found in the registries of the config file", name)
+	}
+	return credential, nil
+}
+
 // EncodeAuth encodes the username and password strings to base64
 func EncodeAuth(username string, password string) string {
 	input := username + ":" + password
@@ -148,8 +448,15 @@ func DecodeAuth(input string) (string, string, error) {
 	return arr[0], arr[1], nil
 }
 
-// UpdateAuthConfig creates or updates the username and password for a given gateway
+// UpdateAuthConfig creates or updates the username and password for a given
+// gateway in the store set by credentials.store
 func UpdateAuthConfig(gateway string, username string, password string) error {
+	return UpdateAuthConfigInStore(gateway, username, password, "")
+}
+
+// UpdateAuthConfigInStore creates or updates the username and password for a
+// given gateway in the named store, an empty store uses credentials.store
+func UpdateAuthConfigInStore(gateway string, username string, password string, store string) error {
 	_, err := url.ParseRequestURI(gateway)
 	if err != nil || len(gateway) < 1 {
 		return fmt.Errorf("invalid gateway URL")
@@ -177,14 +484,36 @@ func UpdateAuthConfig(gateway string, username string, password string) error {
 		return err
 	}
 
+	if len(store) == 0 {
+		store = cfg.defaultStore()
+	}
+	return cfg.saveAuth(gateway, BasicAuth, EncodeAuth(username, password), store)
+}
+
+// saveAuth keeps the secret for a gateway in the named store and replaces
+// the gateway's entry in the config file
+func (configFile *ConfigFile) saveAuth(gateway string, authType string, secret string, store string) error {
+	credentials, err := configFile.storeFor(store)
+	if err != nil {
+		return err
+	}
+
+	token, err := credentials.save(gateway, secret)
+	if err != nil {
+		return fmt.Errorf("unable to save the credentials for %s in the %s store: %s", gateway, store, err)
+	}
+
 	auth := AuthConfig{
 		Gateway: gateway,
-		Auth:    "basic",
-		Token:   EncodeAuth(username, password),
+		Auth:    authType,
+		Token:   token,
+	}
+	if store != FileStore {
+		auth.Store = store
 	}
 
 	index := -1
-	for i, v := range cfg.AuthConfigs {
+	for i, v := range configFile.AuthConfigs {
 		if gateway == v.Gateway {
 			index = i
 			break
@@ -192,12 +521,18 @@ func UpdateAuthConfig(gateway string, username string, password string) error {
 	}
 
 	if index == -1 {
-		cfg.AuthConfigs = append(cfg.AuthConfigs, auth)
+		configFile.AuthConfigs = append(configFile.AuthConfigs, auth)
 	} else {
-		cfg.AuthConfigs[index] = auth
+		previous := configFile.AuthConfigs[index]
+		configFile.AuthConfigs[index] = auth
+		if previous.Store == KeychainStore && auth.Store != KeychainStore {
+			if old, err := configFile.storeFor(previous.Store); err == nil {
+				old.remove(gateway)
+			}
+		}
 	}
 
-	if err := cfg.save(); err != nil {
+	if err := configFile.save(); err != nil {
 		return err
 	}
 
@@ -226,7 +561,14 @@ func LookupAuthConfig(gateway string) (string, string, error) {
 
 	for _, v := range cfg.AuthConfigs {
 		if gateway == v.Gateway {
-			user, pass, err := DecodeAuth(v.Token)
+			if v.Auth == OAuth2Auth {
+				return "", "", fmt.Errorf("the credentials for %s are an oauth2 token", gateway)
+			}
+			encoded, err := cfg.loadAuth(v)
+			if err != nil {
+				return "", "", err
+			}
+			user, pass, err := DecodeAuth(encoded)
 			if err != nil {
 				return "", "", err
 			}
@@ -266,6 +608,14 @@ func RemoveAuthConfig(gateway string) error {
 	}
 
 	if index > -1 {
+		credentials, err := cfg.storeFor(cfg.AuthConfigs[index].Store)
+		if err != nil {
+			return err
+		}
+		if err := credentials.remove(gateway); err != nil {
+			return fmt.Errorf("unable to remove the credentials for %s: %s", gateway, err)
+		}
+
 		cfg.AuthConfigs = removeAuthByIndex(cfg.AuthConfigs, index)
 		if err := cfg.save(); err != nil {
 			return err
@@ -217,3 +217,186 @@ func Test_RemoveAuthConfig_WithUnknownGateway(t *testing.T) {
 		t.Errorf("Error not matched: %s", err.Error())
 	}
 }
+
+func Test_ReadConfigFile_Templates(t *testing.T) {
+	DefaultDir, _ = ioutil.TempDir("", "faas-cli-file-test")
+	DefaultFile = "templates.yml"
+	defer os.RemoveAll(DefaultDir)
+
+	cfg, err := ReadConfigFile()
+	if err != nil {
+		t.Fatalf("got error %s", err.Error())
+	}
+	if cfg.Templates != nil {
+		t.Fatalf("expected no template config before the file is written")
+	}
+
+	configPath, _ := EnsureFile()
+	ioutil.WriteFile(configPath, []byte(`templates:
+  retries: 5
+  retry_backoff: 1s
+  mirrors:
+    https://github.com/openfaas/templates.git:
+    - https://mirror.example.com/templates.tar.gz
+  checksums:
+    https://mirror.example.com/templates.tar.gz: abc123
+image_overrides:
+  docker.io: mirror.example.com
+git:
+  ssh_key: ~/.ssh/id_ed25519
+  host_key_checking: accept-new
+tracing:
+  url: http://jaeger:16686/trace/{trace_id}
+`), 0600)
+
+	// Saving auth must keep the template settings
+	if err := UpdateAuthConfig("http://openfaas.test", "admin", "pass"); err != nil {
+		t.Fatalf("got error %s", err.Error())
+	}
+
+	cfg, err = ReadConfigFile()
+	if err != nil {
+		t.Fatalf("got error %s", err.Error())
+	}
+
+	if cfg.Templates == nil || cfg.Templates.Retries != 5 || cfg.Templates.RetryBackoff != "1s" {
+		t.Fatalf("unexpected template config: %+v", cfg.Templates)
+	}
+	mirrors := cfg.Templates.Mirrors["https://github.com/openfaas/templates.git"]
+	if len(mirrors) != 1 || mirrors[0] != "https://mirror.example.com/templates.tar.gz" {
+		t.Errorf("unexpected mirrors: %v", mirrors)
+	}
+	if cfg.Templates.Checksums["https://mirror.example.com/templates.tar.gz"] != "abc123" {
+		t.Errorf("unexpected checksums: %v", cfg.Templates.Checksums)
+	}
+	if cfg.ImageOverrides["docker.io"] != "mirror.example.com" {
+		t.Errorf("unexpected image overrides: %v", cfg.ImageOverrides)
+	}
+	if cfg.Git == nil || cfg.Git.SSHKey != "~/.ssh/id_ed25519" || cfg.Git.HostKeyChecking != "accept-new" {
+		t.Errorf("unexpected git config: %+v", cfg.Git)
+	}
+	if cfg.Tracing == nil || cfg.Tracing.URL != "http://jaeger:16686/trace/{trace_id}" {
+		t.Errorf("unexpected tracing config: %+v", cfg.Tracing)
+	}
+	if len(cfg.AuthConfigs) != 1 {
+		t.Errorf("expected the auth config to be saved")
+	}
+}
+
+func Test_LookupContext(t *testing.T) {
+	DefaultDir, _ = ioutil.TempDir("", "faas-cli-file-test")
+	DefaultFile = "contexts.yml"
+	defer os.RemoveAll(DefaultDir)
+
+	configPath, _ := EnsureFile()
+	ioutil.WriteFile(configPath, []byte("contexts:\n  staging: https://staging.example.com\n"), 0600)
+
+	cases := map[string]string{
+		"staging":               "https://staging.example.com",
+		"http://127.0.0.1:8080": "http://127.0.0.1:8080",
+	}
+	for name, want := range cases {
+		gateway, err := LookupContext(name)
+		if err != nil || gateway != want {
+			t.Errorf("%s: want %s, got %s, %v", name, want, gateway, err)
+		}
+	}
+
+	if _, err := LookupContext("prod"); err == nil || err.Error() != "context prod was not found in the contexts of the config file" {
+		t.Errorf("got error %v", err)
+	}
+}
+
+func Test_SaveContext_RemoveContext(t *testing.T) {
+	DefaultDir, _ = ioutil.TempDir("", "faas-cli-file-test")
+	DefaultFile = "save-contexts.yml"
+	defer os.RemoveAll(DefaultDir)
+
+	if err := SaveContext("staging", "https://staging.example.com/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveContext("prod", "https://prod.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveContext("https://prod.example.com", "https://prod.example.com"); err == nil {
+		t.Errorf("want an error for a context named by a URL")
+	}
+
+	gateway, err := LookupContext("staging")
+	if err != nil || gateway != "https://staging.example.com" {
+		t.Errorf("want the staging gateway, got %s, %v", gateway, err)
+	}
+
+	gateway, err = RemoveContext("staging")
+	if err != nil || gateway != "https://staging.example.com" {
+		t.Errorf("want the removed gateway, got %s, %v", gateway, err)
+	}
+	if _, err := LookupContext("staging"); err == nil {
+		t.Errorf("want staging to be removed")
+	}
+	if _, err := LookupContext("prod"); err != nil {
+		t.Errorf("want prod to be kept, got %v", err)
+	}
+	if _, err := RemoveContext("staging"); err == nil {
+		t.Errorf("want an error removing an unknown context")
+	}
+}
+
+func Test_LookupRateLimit(t *testing.T) {
+	DefaultDir, _ = ioutil.TempDir("", "faas-cli-file-test")
+	DefaultFile = "rate-limits.yml"
+	defer os.RemoveAll(DefaultDir)
+
+	if _, ok := LookupRateLimit("https://staging.example.com"); ok {
+		t.Fatalf("want no rate limit before the file is written")
+	}
+
+	configPath, _ := EnsureFile()
+	ioutil.WriteFile(configPath, []byte(`contexts:
+  staging: https://staging.example.com/
+rate_limits:
+  staging:
+    concurrency: 2
+    requests_per_second: 5
+  http://127.0.0.1:8080:
+    max_retries: 1
+`), 0600)
+
+	limit, ok := LookupRateLimit("https://staging.example.com")
+	if !ok || limit.Concurrency != 2 || limit.RequestsPerSecond != 5 {
+		t.Errorf("want the limit of the staging context, got %+v, %v", limit, ok)
+	}
+	limit, ok = LookupRateLimit("http://127.0.0.1:8080/")
+	if !ok || limit.MaxRetries != 1 {
+		t.Errorf("want the limit of the gateway URL, got %+v, %v", limit, ok)
+	}
+	if _, ok := LookupRateLimit("https://prod.example.com"); ok {
+		t.Errorf("want no limit for a gateway without an entry")
+	}
+}
+
+func Test_LookupRegistry(t *testing.T) {
+	DefaultDir, _ = ioutil.TempDir("", "faas-cli-file-test")
+	DefaultFile = "registries.yml"
+	defer os.RemoveAll(DefaultDir)
+
+	configPath, _ := EnsureFile()
+	ioutil.WriteFile(configPath, []byte(`registries:
+  acme-ecr:
+    server: 123456789012.dkr.ecr.eu-west-1.amazonaws.com
+    credential_helper: ecr-login
+    pull_secret: acme-ecr-pull
+`), 0600)
+
+	credential, err := LookupRegistry("acme-ecr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.CredentialHelper != "ecr-login" || credential.PullSecret != "acme-ecr-pull" {
+		t.Errorf("want the acme-ecr credential, got %+v", credential)
+	}
+
+	if _, err := LookupRegistry("ghcr"); err == nil || !strings.Contains(err.Error(), "ghcr was not found") {
+		t.Errorf("want an error for an unknown credential, got %v", err)
+	}
+}
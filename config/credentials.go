@@ -0,0 +1,306 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+)
+
+// Credential stores, FileStore keeps the base64 encoded credentials in the
+// config file itself
+const (
+	FileStore     = "file"
+	KeychainStore = "keychain"
+	AgeStore      = "age"
+)
+
+// keychainService names the entries written to the OS keychain
+const keychainService = "openfaas-cli"
+
+// CredentialsConfig chooses where the credentials saved by login are kept
+type CredentialsConfig struct {
+	// Store is file, keychain or age, defaults to file
+	Store string `yaml:"store,omitempty"`
+
+	// AgeIdentity is the age identity file used by the age store
+	AgeIdentity string `yaml:"age_identity,omitempty"`
+}
+
+// CredentialStatus describes where the credentials for a gateway are kept
+type CredentialStatus struct {
+	Gateway  string `json:"gateway"`
+	Username string `json:"username,omitempty"`
+	Store    string `json:"store"`
+	Location string `json:"location"`
+	Error    string `json:"error,omitempty"`
+}
+
+// credentialStore keeps the encoded credentials for a gateway, the token it
+// returns is written to the config file
+type credentialStore interface {
+	save(gateway string, secret string) (string, error)
+	load(gateway string, token string) (string, error)
+	remove(gateway string) error
+	location() string
+}
+
+// runCredentialCommand runs a keychain or age command with input on stdin so
+// that secrets never appear in the process list
+var runCredentialCommand = func(input string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if _, lookErr := exec.LookPath(name); lookErr != nil {
+			return "", fmt.Errorf("%s was not found in the PATH", name)
+		}
+		return "", fmt.Errorf("%s failed: %s", name, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(stdout.String(), "\n"), nil
+}
+
+// keychainOS picks the keychain tool, it is only changed by tests
+var keychainOS = runtime.GOOS
+
+// storeFor gives the store named by an auth entry, an empty name is the file
+func (configFile *ConfigFile) storeFor(name string) (credentialStore, error) {
+	switch name {
+	case "", FileStore:
+		return fileStore{}, nil
+	case KeychainStore:
+		if keychainOS != "darwin" && keychainOS != "linux" {
+			return nil, fmt.Errorf("the keychain store is only supported on macOS and on Linux with secret-tool")
+		}
+		return keychainStore{goos: keychainOS}, nil
+	case AgeStore:
+		if configFile.Credentials == nil || len(configFile.Credentials.AgeIdentity) == 0 {
+			return nil, fmt.Errorf("the age store needs credentials.age_identity in the config file")
+		}
+		identity, err := homedir.Expand(configFile.Credentials.AgeIdentity)
+		if err != nil {
+			return nil, err
+		}
+		return ageStore{identity: identity}, nil
+	}
+	return nil, fmt.Errorf("credential store %q must be file, keychain or age", name)
+}
+
+// defaultStore is the store new credentials are saved to
+func (configFile *ConfigFile) defaultStore() string {
+	if configFile.Credentials == nil || len(configFile.Credentials.Store) == 0 {
+		return FileStore
+	}
+	return configFile.Credentials.Store
+}
+
+type fileStore struct{}
+
+func (fileStore) save(gateway string, secret string) (string, error) { return secret, nil }
+
+func (fileStore) load(gateway string, token string) (string, error) { return token, nil }
+
+func (fileStore) remove(gateway string) error { return nil }
+
+func (fileStore) location() string { return "config file (plaintext)" }
+
+type keychainStore struct {
+	goos string
+}
+
+func (k keychainStore) save(gateway string, secret string) (string, error) {
+	var err error
+	if k.goos == "darwin" {
+		_, err = runCredentialCommand(
+			fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", keychainService, gateway, secret),
+			"security", "-i")
+	} else {
+		_, err = runCredentialCommand(secret, "secret-tool", "store", "--label", "OpenFaaS gateway "+gateway,
+			"service", keychainService, "gateway", gateway)
+	}
+	return "", err
+}
+
+func (k keychainStore) load(gateway string, token string) (string, error) {
+	if k.goos == "darwin" {
+		return runCredentialCommand("", "security", "find-generic-password", "-s", keychainService, "-a", gateway, "-w")
+	}
+	return runCredentialCommand("", "secret-tool", "lookup", "service", keychainService, "gateway", gateway)
+}
+
+func (k keychainStore) remove(gateway string) error {
+	var err error
+	if k.goos == "darwin" {
+		_, err = runCredentialCommand("", "security", "delete-generic-password", "-s", keychainService, "-a", gateway)
+	} else {
+		_, err = runCredentialCommand("", "secret-tool", "clear", "service", keychainService, "gateway", gateway)
+	}
+	return err
+}
+
+func (k keychainStore) location() string {
+	if k.goos == "darwin" {
+		return "macOS Keychain"
+	}
+	return "Secret Service (secret-tool)"
+}
+
+type ageStore struct {
+	identity string
+}
+
+func (a ageStore) save(gateway string, secret string) (string, error) {
+	recipient, err := runCredentialCommand("", "age-keygen", "-y", a.identity)
+	if err != nil {
+		return "", err
+	}
+	return runCredentialCommand(secret, "age", "--encrypt", "--armor", "--recipient", recipient)
+}
+
+func (a ageStore) load(gateway string, token string) (string, error) {
+	return runCredentialCommand(token, "age", "--decrypt", "--identity", a.identity)
+}
+
+func (a ageStore) remove(gateway string) error { return nil }
+
+func (a ageStore) location() string {
+	return "config file (encrypted with age identity " + a.identity + ")"
+}
+
+// loadAuth gives the base64 encoded credentials, or the oauth2 token, of an
+// entry from its store
+func (configFile *ConfigFile) loadAuth(auth AuthConfig) (string, error) {
+	credentials, err := configFile.storeFor(auth.Store)
+	if err != nil {
+		return "", err
+	}
+	encoded, err := credentials.load(auth.Gateway, auth.Token)
+	if err != nil {
+		return "", fmt.Errorf("unable to read the credentials for %s: %s", auth.Gateway, err)
+	}
+	return encoded, nil
+}
+
+// AuthStatus reports where the credentials for each gateway are stored and
+// whether they can be read
+func AuthStatus() ([]CredentialStatus, error) {
+	cfg, err := ReadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]CredentialStatus, 0, len(cfg.AuthConfigs))
+	for _, auth := range cfg.AuthConfigs {
+		status := CredentialStatus{Gateway: auth.Gateway, Store: auth.Store}
+		if len(status.Store) == 0 {
+			status.Store = FileStore
+		}
+
+		credentials, err := cfg.storeFor(auth.Store)
+		if err != nil {
+			status.Error = err.Error()
+			statuses = append(statuses, status)
+			continue
+		}
+		status.Location = credentials.location()
+
+		encoded, err := cfg.loadAuth(auth)
+		if err == nil && auth.Auth == OAuth2Auth {
+			token := OAuthToken{}
+			if err = json.Unmarshal([]byte(encoded), &token); err == nil {
+				status.Username = token.ClientID + " (oauth2)"
+			}
+		} else if err == nil {
+			status.Username, _, err = DecodeAuth(encoded)
+		}
+		if err != nil {
+			status.Error = err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// MigrateAuthConfigs moves the credentials of every gateway into the named
+// store and makes it the default for new logins, returning the gateways moved
+func MigrateAuthConfigs(store string) ([]string, error) {
+	if !fileExists() {
+		return nil, fmt.Errorf("config file not found")
+	}
+
+	configPath, err := EnsureFile()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := New(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cfg.load(); err != nil {
+		return nil, err
+	}
+
+	target, err := cfg.storeFor(store)
+	if err != nil {
+		return nil, err
+	}
+
+	var moved []string
+	var previous []AuthConfig
+	for i, auth := range cfg.AuthConfigs {
+		current := auth.Store
+		if len(current) == 0 {
+			current = FileStore
+		}
+		if current == store {
+			continue
+		}
+
+		encoded, err := cfg.loadAuth(auth)
+		if err != nil {
+			return nil, err
+		}
+		token, err := target.save(auth.Gateway, encoded)
+		if err != nil {
+			return nil, fmt.Errorf("unable to save the credentials for %s in the %s store: %s", auth.Gateway, store, err)
+		}
+
+		previous = append(previous, auth)
+		cfg.AuthConfigs[i].Token = token
+		cfg.AuthConfigs[i].Store = store
+		if store == FileStore {
+			cfg.AuthConfigs[i].Store = ""
+		}
+		moved = append(moved, auth.Gateway)
+	}
+
+	if cfg.Credentials == nil {
+		cfg.Credentials = &CredentialsConfig{}
+	}
+	cfg.Credentials.Store = store
+
+	if err := cfg.save(); err != nil {
+		return nil, err
+	}
+
+	// Old copies are only removed once the config points at the new store
+	for _, auth := range previous {
+		if old, err := cfg.storeFor(auth.Store); err == nil {
+			old.remove(auth.Gateway)
+		}
+	}
+	return moved, nil
+}
@@ -0,0 +1,251 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeCredentialCommands stands in for secret-tool and age, keeping the
+// keychain in a map and "encrypting" by reversing the input
+func fakeCredentialCommands(keychain map[string]string, calls *[]string) func(string, string, ...string) (string, error) {
+	return func(input string, name string, args ...string) (string, error) {
+		*calls = append(*calls, name+" "+strings.Join(args, " "))
+		switch name {
+		case "secret-tool":
+			gateway := args[len(args)-1]
+			switch args[0] {
+			case "store":
+				keychain[gateway] = input
+				return "", nil
+			case "lookup":
+				secret, ok := keychain[gateway]
+				if !ok {
+					return "", fmt.Errorf("secret-tool failed: not found")
+				}
+				return secret, nil
+			case "clear":
+				delete(keychain, gateway)
+				return "", nil
+			}
+		case "age-keygen":
+			return "age1recipient", nil
+		case "age":
+			if args[0] == "--encrypt" {
+				return "AGE:" + reverse(input), nil
+			}
+			return reverse(strings.TrimPrefix(input, "AGE:")), nil
+		}
+		return "", fmt.Errorf("unexpected command %s", name)
+	}
+}
+
+func reverse(s string) string {
+	out := []byte(s)
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// useFakeCredentialCommands swaps the keychain for an in-memory one and
+// returns a func to undo it
+func useFakeCredentialCommands() (map[string]string, *[]string, func()) {
+	keychain := map[string]string{}
+	calls := &[]string{}
+
+	oldRun, oldOS := runCredentialCommand, keychainOS
+	runCredentialCommand = fakeCredentialCommands(keychain, calls)
+	keychainOS = "linux"
+	return keychain, calls, func() {
+		runCredentialCommand, keychainOS = oldRun, oldOS
+	}
+}
+
+func writeTestConfig(t *testing.T, file string, contents string) string {
+	DefaultDir, _ = ioutil.TempDir("", "faas-cli-file-test")
+	DefaultFile = file
+	path := filepath.Join(DefaultDir, DefaultFile)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func Test_UpdateAuthConfigInStore_Keychain(t *testing.T) {
+	keychain, _, undo := useFakeCredentialCommands()
+	defer undo()
+	path := writeTestConfig(t, "credentials1.yml", "")
+	gatewayURL := "http://openfaas.test"
+
+	if err := UpdateAuthConfigInStore(gatewayURL, "admin", "secret", KeychainStore); err != nil {
+		t.Fatalf("got error %s", err)
+	}
+
+	written, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(written), EncodeAuth("admin", "secret")) {
+		t.Errorf("the credentials were written to the config file:\n%s", written)
+	}
+	if !strings.Contains(string(written), "store: keychain") {
+		t.Errorf("the entry does not record its store:\n%s", written)
+	}
+	if keychain[gatewayURL] != EncodeAuth("admin", "secret") {
+		t.Errorf("the keychain was not updated, got %q", keychain[gatewayURL])
+	}
+
+	user, pass, err := LookupAuthConfig(gatewayURL)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if user != "admin" || pass != "secret" {
+		t.Errorf("got user %s and pass %s", user, pass)
+	}
+
+	if err := RemoveAuthConfig(gatewayURL); err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if _, ok := keychain[gatewayURL]; ok {
+		t.Errorf("the keychain entry was not removed")
+	}
+}
+
+func Test_UpdateAuthConfig_AgeFromConfig(t *testing.T) {
+	_, _, undo := useFakeCredentialCommands()
+	defer undo()
+	path := writeTestConfig(t, "credentials2.yml", `credentials:
+  store: age
+  age_identity: /tmp/identity.txt
+`)
+	gatewayURL := "http://openfaas.test"
+
+	if err := UpdateAuthConfig(gatewayURL, "admin", "secret"); err != nil {
+		t.Fatalf("got error %s", err)
+	}
+
+	written, _ := ioutil.ReadFile(path)
+	if !strings.Contains(string(written), "AGE:") || strings.Contains(string(written), EncodeAuth("admin", "secret")) {
+		t.Errorf("the credentials were not encrypted:\n%s", written)
+	}
+
+	user, pass, err := LookupAuthConfig(gatewayURL)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if user != "admin" || pass != "secret" {
+		t.Errorf("got user %s and pass %s", user, pass)
+	}
+}
+
+func Test_UpdateAuthConfigInStore_AgeWithoutIdentity(t *testing.T) {
+	_, _, undo := useFakeCredentialCommands()
+	defer undo()
+	writeTestConfig(t, "credentials3.yml", "")
+
+	err := UpdateAuthConfigInStore("http://openfaas.test", "admin", "secret", AgeStore)
+	if err == nil || !strings.Contains(err.Error(), "age_identity") {
+		t.Errorf("want an error about age_identity, got %v", err)
+	}
+}
+
+func Test_UpdateAuthConfigInStore_KeychainUnsupported(t *testing.T) {
+	_, _, undo := useFakeCredentialCommands()
+	defer undo()
+	keychainOS = "plan9"
+	writeTestConfig(t, "credentials4.yml", "")
+
+	err := UpdateAuthConfigInStore("http://openfaas.test", "admin", "secret", KeychainStore)
+	if err == nil || !strings.Contains(err.Error(), "only supported") {
+		t.Errorf("want an unsupported error, got %v", err)
+	}
+}
+
+func Test_AuthStatus(t *testing.T) {
+	keychain, _, undo := useFakeCredentialCommands()
+	defer undo()
+	keychain["http://keychain.test"] = EncodeAuth("kc", "pass")
+	writeTestConfig(t, "credentials5.yml", `auths:
+- gateway: http://plain.test
+  auth: basic
+  token: `+EncodeAuth("plain", "pass")+`
+- gateway: http://keychain.test
+  auth: basic
+  store: keychain
+- gateway: http://missing.test
+  auth: basic
+  store: keychain
+`)
+
+	statuses, err := AuthStatus()
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(statuses) != 3 {
+		t.Fatalf("want 3 statuses, got %d", len(statuses))
+	}
+
+	want := []CredentialStatus{
+		{Gateway: "http://plain.test", Username: "plain", Store: FileStore, Location: "config file (plaintext)"},
+		{Gateway: "http://keychain.test", Username: "kc", Store: KeychainStore, Location: "Secret Service (secret-tool)"},
+	}
+	for i, w := range want {
+		if statuses[i] != w {
+			t.Errorf("status %d: want %+v, got %+v", i, w, statuses[i])
+		}
+	}
+	if len(statuses[2].Error) == 0 {
+		t.Errorf("want an error for a credential missing from the keychain")
+	}
+}
+
+func Test_MigrateAuthConfigs(t *testing.T) {
+	keychain, _, undo := useFakeCredentialCommands()
+	defer undo()
+	path := writeTestConfig(t, "credentials6.yml", `auths:
+- gateway: http://one.test
+  auth: basic
+  token: `+EncodeAuth("one", "pass1")+`
+- gateway: http://two.test
+  auth: basic
+  token: `+EncodeAuth("two", "pass2")+`
+`)
+
+	moved, err := MigrateAuthConfigs(KeychainStore)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if strings.Join(moved, ",") != "http://one.test,http://two.test" {
+		t.Errorf("got moved %v", moved)
+	}
+
+	written, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(written), "token:") {
+		t.Errorf("tokens were left in the config file:\n%s", written)
+	}
+	if !strings.Contains(string(written), "credentials:\n  store: keychain") {
+		t.Errorf("the default store was not updated:\n%s", written)
+	}
+	if len(keychain) != 2 {
+		t.Errorf("want 2 keychain entries, got %d", len(keychain))
+	}
+
+	user, pass, err := LookupAuthConfig("http://two.test")
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if user != "two" || pass != "pass2" {
+		t.Errorf("got user %s and pass %s", user, pass)
+	}
+
+	moved, err = MigrateAuthConfigs(FileStore)
+	if err != nil {
+		t.Fatalf("got error %s", err)
+	}
+	if len(moved) != 2 || len(keychain) != 0 {
+		t.Errorf("want both moved back and the keychain emptied, got %v and %d entries", moved, len(keychain))
+	}
+}
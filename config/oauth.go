@@ -0,0 +1,116 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Auth types of the credentials saved for a gateway, BasicAuth is saved by
+// login and OAuth2Auth by auth login
+const (
+	BasicAuth  = "basic"
+	OAuth2Auth = "oauth2"
+)
+
+// OAuthGrant is the grant a token was issued by
+const (
+	AuthorizationCodeGrant = "authorization_code"
+	ClientCredentialsGrant = "client_credentials"
+)
+
+// expirySkew renews a token this long before it expires, so that it does not
+// expire on its way to the gateway
+const expirySkew = 30 * time.Second
+
+// OAuthToken is the token set issued for a gateway by an identity provider,
+// with what is needed to renew it
+type OAuthToken struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+
+	Grant        string `json:"grant"`
+	TokenURL     string `json:"token_url"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	Audience     string `json:"audience,omitempty"`
+}
+
+// Expired reports whether the token is about to expire, tokens without an
+// expiry never do
+func (token OAuthToken) Expired(now time.Time) bool {
+	return !token.Expiry.IsZero() && !now.Add(expirySkew).Before(token.Expiry)
+}
+
+// UpdateOAuthToken saves the token for a gateway in the named store, an empty
+// store keeps the store its credentials are already in or uses
+// credentials.store
+func UpdateOAuthToken(gateway string, token OAuthToken, store string) error {
+	if _, err := url.ParseRequestURI(gateway); err != nil || len(gateway) < 1 {
+		return fmt.Errorf("invalid gateway URL")
+	}
+	if len(token.AccessToken) == 0 {
+		return fmt.Errorf("access token can't be an empty string")
+	}
+
+	configPath, err := EnsureFile()
+	if err != nil {
+		return err
+	}
+
+	cfg, err := New(configPath)
+	if err != nil {
+		return err
+	}
+
+	if err := cfg.load(); err != nil {
+		return err
+	}
+
+	if len(store) == 0 {
+		store = cfg.defaultStore()
+		for _, auth := range cfg.AuthConfigs {
+			if auth.Gateway == gateway && len(auth.Store) > 0 {
+				store = auth.Store
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	return cfg.saveAuth(gateway, OAuth2Auth, string(encoded), store)
+}
+
+// LookupOAuthToken gives the token saved for a gateway, it is nil when the
+// gateway has no credentials or they are a username and password
+func LookupOAuthToken(gateway string) (*OAuthToken, error) {
+	cfg, err := ReadConfigFile()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, auth := range cfg.AuthConfigs {
+		if auth.Gateway != gateway || auth.Auth != OAuth2Auth {
+			continue
+		}
+
+		encoded, err := cfg.loadAuth(auth)
+		if err != nil {
+			return nil, err
+		}
+		token := &OAuthToken{}
+		if err := json.Unmarshal([]byte(encoded), token); err != nil {
+			return nil, fmt.Errorf("unable to read the oauth2 token for %s: %s", gateway, err)
+		}
+		return token, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package config
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_OAuthToken_Expired(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		expiry time.Time
+		want   bool
+	}{
+		"no expiry":         {time.Time{}, false},
+		"valid for an hour": {now.Add(time.Hour), false},
+		"about to expire":   {now.Add(10 * time.Second), true},
+		"expired":           {now.Add(-time.Minute), true},
+	}
+	for name, c := range cases {
+		if got := (OAuthToken{Expiry: c.expiry}).Expired(now); got != c.want {
+			t.Errorf("%s: want %v, got %v", name, c.want, got)
+		}
+	}
+}
+
+func Test_UpdateOAuthToken_Keychain(t *testing.T) {
+	keychain, _, undo := useFakeCredentialCommands()
+	defer undo()
+	path := writeTestConfig(t, "oauth1.yml", "")
+	gatewayURL := "http://openfaas.test"
+
+	token := OAuthToken{
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		Grant:        AuthorizationCodeGrant,
+		TokenURL:     "https://idp.example.com/token",
+		ClientID:     "faas-cli",
+	}
+	if err := UpdateOAuthToken(gatewayURL, token, KeychainStore); err != nil {
+		t.Fatal(err)
+	}
+
+	written, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(written), "access-1") {
+		t.Errorf("the token was written to the config file:\n%s", written)
+	}
+	if !strings.Contains(string(written), "auth: oauth2") {
+		t.Errorf("the entry does not record its auth type:\n%s", written)
+	}
+	if !strings.Contains(keychain[gatewayURL], "refresh-1") {
+		t.Errorf("the keychain was not updated, got %q", keychain[gatewayURL])
+	}
+
+	// A renewed token stays in the keychain
+	token.AccessToken = "access-2"
+	if err := UpdateOAuthToken(gatewayURL, token, ""); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := LookupOAuthToken(gatewayURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved == nil || saved.AccessToken != "access-2" || saved.ClientID != "faas-cli" {
+		t.Errorf("want the renewed token, got %+v", saved)
+	}
+	if !strings.Contains(keychain[gatewayURL], "access-2") {
+		t.Errorf("want the renewed token in the keychain, got %q", keychain[gatewayURL])
+	}
+
+	if _, _, err := LookupAuthConfig(gatewayURL); err == nil {
+		t.Errorf("want an error looking up a username and password for an oauth2 token")
+	}
+
+	statuses, err := AuthStatus()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Username != "faas-cli (oauth2)" || len(statuses[0].Error) > 0 {
+		t.Errorf("want the client of the token, got %+v", statuses)
+	}
+}
+
+func Test_LookupOAuthToken_BasicAuth(t *testing.T) {
+	writeTestConfig(t, "oauth2.yml", "")
+	gatewayURL := "http://openfaas.test"
+
+	if err := UpdateAuthConfig(gatewayURL, "admin", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	token, err := LookupOAuthToken(gatewayURL)
+	if err != nil || token != nil {
+		t.Errorf("want no token for basic auth, got %+v, %v", token, err)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultFile lists the hooks run by every command
+const DefaultFile = "~/.openfaas/hooks.yml"
+
+// DefaultTimeout stops a hook which has not finished
+const DefaultTimeout = 30 * time.Second
+
+// Lifecycle points hooks are run at
+const (
+	PreDeploy = "pre-deploy"
+	PostPush  = "post-push"
+	OnFailure = "on-failure"
+)
+
+var points = []string{PreDeploy, PostPush, OnFailure}
+
+// Hook is an executable run at a lifecycle point with the Event as JSON on
+// its stdin
+type Hook struct {
+	Command string   `yaml:"command"`
+	Args    []string `yaml:"args,omitempty"`
+
+	// Timeout such as 10s, defaults to DefaultTimeout
+	Timeout string `yaml:"timeout,omitempty"`
+
+	// Required pre-deploy hooks stop the deployment when they fail, other
+	// failures are reported as warnings
+	Required bool `yaml:"required,omitempty"`
+
+	timeout time.Duration
+}
+
+// Config maps each lifecycle point to the hooks run there, in order
+type Config map[string][]Hook
+
+// Event describes what happened, it is written to the hook's stdin
+type Event struct {
+	Hook   string    `json:"hook"`
+	Action string    `json:"action"`
+	Time   time.Time `json:"time"`
+	User   string    `json:"user,omitempty"`
+
+	Gateway  string `json:"gateway,omitempty"`
+	Function string `json:"function"`
+	Image    string `json:"image,omitempty"`
+
+	// StatusCode is the gateway's response, 0 when no response was received
+	StatusCode int    `json:"status_code,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Load reads a hooks file, returning nil when the file does not exist
+func Load(path string) (Config, error) {
+	expanded, err := homedir.Expand(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(expanded)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse reads hooks from YAML and checks each lifecycle point and timeout
+func Parse(data []byte) (Config, error) {
+	config := Config{}
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("unable to parse hooks: %s", err)
+	}
+
+	names := make([]string, 0, len(config))
+	for name := range config {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !knownPoint(name) {
+			return nil, fmt.Errorf("unknown hook %s, choose from: %s", name, strings.Join(points, ", "))
+		}
+
+		for i := range config[name] {
+			hook := &config[name][i]
+			if len(hook.Command) == 0 {
+				return nil, fmt.Errorf("%s hook %d has no command", name, i+1)
+			}
+
+			hook.timeout = DefaultTimeout
+			if len(hook.Timeout) > 0 {
+				timeout, err := time.ParseDuration(hook.Timeout)
+				if err != nil || timeout <= 0 {
+					return nil, fmt.Errorf("%s hook %s: timeout %q must be a duration such as 10s", name, hook.Command, hook.Timeout)
+				}
+				hook.timeout = timeout
+			}
+		}
+	}
+	return config, nil
+}
+
+func knownPoint(name string) bool {
+	for _, point := range points {
+		if point == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs the hook with the event on its stdin, the hook's output is
+// written to out
+func (h Hook) Run(event Event, out io.Writer) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	command, err := homedir.Expand(h.Command)
+	if err != nil {
+		return err
+	}
+
+	timeout := h.timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command, h.Args...)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.Env = append(os.Environ(), "FAAS_HOOK="+event.Hook)
+
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Parse(t *testing.T) {
+	config, err := Parse([]byte(`pre-deploy:
+  - command: /usr/local/bin/change-ticket
+    required: true
+post-push:
+  - command: notify
+    args: ["#deploys"]
+    timeout: 5s
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if hook := config[PreDeploy][0]; !hook.Required || hook.timeout != DefaultTimeout {
+		t.Errorf("unexpected pre-deploy hook: %+v", hook)
+	}
+	if hook := config[PostPush][0]; hook.Args[0] != "#deploys" || hook.timeout != 5*time.Second {
+		t.Errorf("unexpected post-push hook: %+v", hook)
+	}
+
+	invalid := map[string]string{
+		"unknown point":  "pre_deploy:\n  - command: notify\n",
+		"no command":     "on-failure:\n  - args: [a]\n",
+		"bad timeout":    "on-failure:\n  - command: notify\n    timeout: soon\n",
+		"not a list":     "on-failure: notify\n",
+		"negative delay": "on-failure:\n  - command: notify\n    timeout: -1s\n",
+	}
+	for name, data := range invalid {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func Test_Load_Missing(t *testing.T) {
+	config, err := Load(filepath.Join(os.TempDir(), "faas-cli-no-such-hooks.yml"))
+	if err != nil || config != nil {
+		t.Fatalf("want no hooks and no error, got: %v %v", config, err)
+	}
+}
+
+func Test_Hook_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-hooks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The hook copies the event from its stdin to a file
+	script := filepath.Join(dir, "hook.sh")
+	eventFile := filepath.Join(dir, "event.json")
+	if err := ioutil.WriteFile(script, []byte("#!/bin/sh\necho \"running $FAAS_HOOK\"\ncat > \"$1\"\n"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	event := Event{Hook: PostPush, Action: "push", Function: "api", Image: "api:0.1"}
+	var out bytes.Buffer
+	if err := (Hook{Command: script, Args: []string{eventFile}}).Run(event, &out); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !strings.Contains(out.String(), "running post-push") {
+		t.Errorf("want the hook's output, got: %q", out.String())
+	}
+
+	data, err := ioutil.ReadFile(eventFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Event
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("want JSON on stdin, got %q: %s", data, err)
+	}
+	if got.Function != "api" || got.Image != "api:0.1" || got.Hook != PostPush {
+		t.Errorf("unexpected event: %+v", got)
+	}
+}
+
+func Test_Hook_RunTimeout(t *testing.T) {
+	hook := Hook{Command: "sleep", Args: []string{"5"}, timeout: 50 * time.Millisecond}
+
+	err := hook.Run(Event{Hook: OnFailure}, ioutil.Discard)
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("want a timeout, got: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package jsonrpc serves JSON-RPC 2.0 over a stream framed as in the Language
+// Server Protocol: each message is preceded by a Content-Length header.
+package jsonrpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+)
+
+// Version is the JSON-RPC version of every message
+const Version = "2.0"
+
+// Error codes defined by JSON-RPC 2.0
+const (
+	ParseError     = -32700
+	InvalidRequest = -32600
+	MethodNotFound = -32601
+	InvalidParams  = -32602
+	InternalError  = -32603
+)
+
+// Request is a call, or a notification when it has no ID
+type Request struct {
+	Version string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+// Response answers a call with either a result or an error
+type Response struct {
+	Version string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result,omitempty"`
+	Error   *Error           `json:"error,omitempty"`
+}
+
+// Error is returned by a method to give the caller a code and data
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Handler runs a method with its params, the result is encoded as JSON
+type Handler func(params json.RawMessage) (interface{}, error)
+
+// Server dispatches requests to the handlers registered for their method
+type Server struct {
+	handlers map[string]Handler
+}
+
+// NewServer gives a server without any methods
+func NewServer() *Server {
+	return &Server{handlers: map[string]Handler{}}
+}
+
+// Handle registers the handler for a method
+func (s *Server) Handle(method string, handler Handler) {
+	s.handlers[method] = handler
+}
+
+// Serve accepts connections until the listener is closed
+func (s *Server) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			s.ServeConn(conn)
+		}()
+	}
+}
+
+// ServeConn answers the requests on a connection until it is closed. Calls
+// are answered in the order they were received.
+func (s *Server) ServeConn(conn io.ReadWriter) error {
+	reader := bufio.NewReader(conn)
+
+	for {
+		data, err := ReadMessage(reader)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		response := s.dispatch(data)
+		if response == nil {
+			continue
+		}
+
+		if err := WriteMessage(conn, response); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch runs a request, nil is returned for a notification
+func (s *Server) dispatch(data []byte) *Response {
+	var request Request
+	if err := json.Unmarshal(data, &request); err != nil {
+		return &Response{Version: Version, Error: &Error{Code: ParseError, Message: err.Error()}}
+	}
+	if request.Version != Version || len(request.Method) == 0 {
+		return &Response{Version: Version, ID: request.ID, Error: &Error{Code: InvalidRequest, Message: "not a JSON-RPC 2.0 request"}}
+	}
+
+	handler, ok := s.handlers[request.Method]
+	if !ok {
+		if request.ID == nil {
+			return nil
+		}
+		return &Response{Version: Version, ID: request.ID, Error: &Error{Code: MethodNotFound, Message: "method not found: " + request.Method}}
+	}
+
+	result, err := handler(request.Params)
+	if request.ID == nil {
+		return nil
+	}
+
+	response := &Response{Version: Version, ID: request.ID, Result: result}
+	if err != nil {
+		rpcErr, ok := err.(*Error)
+		if !ok {
+			rpcErr = &Error{Code: InternalError, Message: err.Error()}
+		}
+		response.Result = nil
+		response.Error = rpcErr
+	}
+	return response
+}
+
+// ReadMessage reads the body of the next message
+func ReadMessage(reader *bufio.Reader) ([]byte, error) {
+	headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header: %q", headers.Get("Content-Length"))
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// WriteMessage encodes a message as JSON behind its Content-Length header
+func WriteMessage(w io.Writer, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
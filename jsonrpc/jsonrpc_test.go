@@ -0,0 +1,87 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func call(t *testing.T, server *Server, requests ...string) []Response {
+	var in bytes.Buffer
+	for _, request := range requests {
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(request), request)
+	}
+
+	var out bytes.Buffer
+	if err := server.ServeConn(struct {
+		io.Reader
+		io.Writer
+	}{&in, &out}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var responses []Response
+	reader := bufio.NewReader(&out)
+	for {
+		data, err := ReadMessage(reader)
+		if err != nil {
+			break
+		}
+		var response Response
+		if err := json.Unmarshal(data, &response); err != nil {
+			t.Fatal(err)
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+func Test_Server(t *testing.T) {
+	server := NewServer()
+	server.Handle("echo", func(params json.RawMessage) (interface{}, error) {
+		var p struct{ Text string }
+		json.Unmarshal(params, &p)
+		return strings.ToUpper(p.Text), nil
+	})
+	server.Handle("fail", func(params json.RawMessage) (interface{}, error) {
+		return nil, fmt.Errorf("broken")
+	})
+
+	responses := call(t, server,
+		`{"jsonrpc":"2.0","id":1,"method":"echo","params":{"text":"hi"}}`,
+		`{"jsonrpc":"2.0","method":"echo","params":{"text":"notification"}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"fail"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"missing"}`,
+		`{"id":4,"method":"echo"}`,
+	)
+
+	if len(responses) != 4 {
+		t.Fatalf("want a response for each call but not the notification, got: %+v", responses)
+	}
+	if responses[0].Result != "HI" || string(*responses[0].ID) != "1" {
+		t.Errorf("unexpected echo response: %+v", responses[0])
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != InternalError || responses[1].Error.Message != "broken" {
+		t.Errorf("want the handler's error, got: %+v", responses[1])
+	}
+	if responses[2].Error == nil || responses[2].Error.Code != MethodNotFound {
+		t.Errorf("want method not found, got: %+v", responses[2])
+	}
+	if responses[3].Error == nil || responses[3].Error.Code != InvalidRequest {
+		t.Errorf("want an invalid request without jsonrpc 2.0, got: %+v", responses[3])
+	}
+}
+
+func Test_ReadMessage_InvalidLength(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader("Content-Length: many\r\n\r\n{}"))
+	if _, err := ReadMessage(reader); err == nil {
+		t.Fatalf("want an error for an invalid Content-Length")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package metrics
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_Counter(t *testing.T) {
+	r := &Registry{}
+	c := r.NewCounter("builds_total", "Builds by outcome", "function", "status")
+
+	c.Inc("url-ping", "success")
+	c.Inc("url-ping", "success")
+	c.Add(3, "figlet", "failure")
+
+	var out bytes.Buffer
+	r.Write(&out)
+
+	want := `# HELP builds_total Builds by outcome
+# TYPE builds_total counter
+builds_total{function="figlet",status="failure"} 3
+builds_total{function="url-ping",status="success"} 2
+`
+	if out.String() != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func Test_Histogram(t *testing.T) {
+	r := &Registry{}
+	h := r.NewHistogram("build_seconds", "Build time", []float64{1, 5}, "function")
+
+	h.Observe(0.5, "url-ping")
+	h.Observe(3, "url-ping")
+	h.Observe(10, "url-ping")
+
+	var out bytes.Buffer
+	r.Write(&out)
+
+	want := `# HELP build_seconds Build time
+# TYPE build_seconds histogram
+build_seconds_bucket{function="url-ping",le="1"} 1
+build_seconds_bucket{function="url-ping",le="5"} 2
+build_seconds_bucket{function="url-ping",le="+Inf"} 3
+build_seconds_sum{function="url-ping"} 13.5
+build_seconds_count{function="url-ping"} 3
+`
+	if out.String() != want {
+		t.Fatalf("want:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func Test_CounterWithoutLabels(t *testing.T) {
+	r := &Registry{}
+	r.NewCounter("runs_total", "Runs").Inc()
+
+	var out bytes.Buffer
+	r.Write(&out)
+
+	if !strings.HasSuffix(out.String(), "\nruns_total 1\n") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+}
+
+func Test_ServeHTTP(t *testing.T) {
+	r := &Registry{}
+	r.NewCounter("runs_total", "Runs").Inc()
+
+	s := httptest.NewServer(r)
+	defer s.Close()
+
+	res, err := http.Get(s.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := ioutil.ReadAll(res.Body)
+
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("want a text content type, got %s", res.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "runs_total 1") {
+		t.Errorf("unexpected body:\n%s", body)
+	}
+}
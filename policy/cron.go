@@ -0,0 +1,129 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five field cron expression, a time matches when
+// each of its fields is in the allowed set. As in cron, when both the day of
+// month and the day of week are restricted a time matches either of them.
+type cronSchedule struct {
+	minutes  map[int]bool
+	hours    map[int]bool
+	days     map[int]bool
+	months   map[int]bool
+	weekdays map[int]bool
+
+	// eitherDay is set when neither day field starts with *
+	eitherDay bool
+}
+
+var cronFieldBounds = [5][2]int{
+	{0, 59}, // minute
+	{0, 23}, // hour
+	{1, 31}, // day of month
+	{1, 12}, // month
+	{0, 6},  // day of week, Sunday is 0
+}
+
+// parseCron parses "minute hour day-of-month month day-of-week" where each
+// field is *, a value, a range a-b, a step */n or a-b/n, or a list of them
+func parseCron(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expression)
+	}
+
+	var sets [5]map[int]bool
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFieldBounds[i][0], cronFieldBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron expression %q: %s", expression, err)
+		}
+		sets[i] = set
+	}
+
+	// Allow 7 for Sunday as well as 0
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+
+	return &cronSchedule{
+		minutes:  sets[0],
+		hours:    sets[1],
+		days:     sets[2],
+		months:   sets[3],
+		weekdays: sets[4],
+
+		eitherDay: !strings.HasPrefix(fields[2], "*") && !strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i != -1 {
+			var err error
+			step, err = strconv.Atoi(part[i+1:])
+			if err != nil || step < 1 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			part = part[:i]
+		}
+
+		low, high := min, max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			low, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q", part)
+			}
+			high = low
+
+			if len(bounds) == 2 {
+				high, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid range %q", part)
+				}
+			}
+		}
+
+		// Day of week allows 7 as an alias for Sunday
+		upper := max
+		if max == 6 {
+			upper = 7
+		}
+		if low < min || high > upper || low > high {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			set[value] = true
+		}
+	}
+
+	return set, nil
+}
+
+// matches tells whether the minute of t is in the schedule
+func (c *cronSchedule) matches(t time.Time) bool {
+	day := c.days[t.Day()] && c.weekdays[int(t.Weekday())]
+	if c.eitherDay {
+		day = c.days[t.Day()] || c.weekdays[int(t.Weekday())]
+	}
+
+	return c.minutes[t.Minute()] &&
+		c.hours[t.Hour()] &&
+		day &&
+		c.months[int(t.Month())]
+}
@@ -0,0 +1,135 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ryanuber/go-glob"
+)
+
+// EnvironmentRule requires or forbids environment variables in the functions
+// it selects. A function is selected when it matches every selector given, a
+// rule without selectors selects every function.
+type EnvironmentRule struct {
+	Name string `yaml:"name"`
+
+	// Functions are glob patterns of function names
+	Functions []string `yaml:"functions,omitempty"`
+
+	// Labels must all be set on the function with these values
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// Groups are build groups, one of which the function must be in
+	Groups []string `yaml:"groups,omitempty"`
+
+	// Required are glob patterns of names, each must match a variable
+	Required []string `yaml:"required,omitempty"`
+
+	// Forbidden are glob patterns of names, or of NAME=VALUE to forbid only
+	// some values, which no variable may match
+	Forbidden []string `yaml:"forbidden,omitempty"`
+}
+
+// Workload is a function as it is about to be deployed
+type Workload struct {
+	Name        string
+	Labels      map[string]string
+	Group       string
+	Environment map[string]string
+}
+
+func (r *EnvironmentRule) validate(index int) error {
+	if len(r.Name) == 0 {
+		r.Name = fmt.Sprintf("environment rule %d", index+1)
+	}
+	if len(r.Required) == 0 && len(r.Forbidden) == 0 {
+		return fmt.Errorf("%s: give required or forbidden variables", r.Name)
+	}
+	for _, pattern := range append(append([]string{}, r.Required...), r.Forbidden...) {
+		if len(pattern) == 0 || strings.HasPrefix(pattern, "=") {
+			return fmt.Errorf("%s: %q is not a variable name pattern", r.Name, pattern)
+		}
+	}
+	return nil
+}
+
+// selects tells whether the rule applies to the workload
+func (r *EnvironmentRule) selects(workload Workload) bool {
+	if len(r.Functions) > 0 && !matchesAny(r.Functions, workload.Name) {
+		return false
+	}
+	for key, value := range r.Labels {
+		if workload.Labels[key] != value {
+			return false
+		}
+	}
+	if len(r.Groups) > 0 && !matchesAny(r.Groups, workload.Group) {
+		return false
+	}
+	return true
+}
+
+func matchesAny(patterns []string, value string) bool {
+	for _, pattern := range patterns {
+		if glob.Glob(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckEnvironment gives a line for each required variable the workload is
+// missing and each forbidden variable it sets, under the rules selecting it
+func (p *Policy) CheckEnvironment(workload Workload) []string {
+	if p == nil {
+		return nil
+	}
+
+	var names []string
+	for name := range workload.Environment {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations []string
+	for _, rule := range p.Environment {
+		if !rule.selects(workload) {
+			continue
+		}
+
+		for _, pattern := range rule.Required {
+			if !globAny(pattern, names) {
+				violations = append(violations, fmt.Sprintf("%s is required by %s", pattern, rule.Name))
+			}
+		}
+
+		for _, pattern := range rule.Forbidden {
+			parts := strings.SplitN(pattern, "=", 2)
+			for _, name := range names {
+				if !glob.Glob(parts[0], name) {
+					continue
+				}
+				if len(parts) == 1 {
+					violations = append(violations, fmt.Sprintf("%s is forbidden by %s", name, rule.Name))
+				} else if glob.Glob(parts[1], workload.Environment[name]) {
+					violations = append(violations, fmt.Sprintf("%s=%s is forbidden by %s", name, workload.Environment[name], rule.Name))
+				}
+			}
+		}
+	}
+	return violations
+}
+
+// globAny tells whether pattern matches one of the names
+func globAny(pattern string, names []string) bool {
+	for _, name := range names {
+		if glob.Glob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
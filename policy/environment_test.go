@@ -0,0 +1,76 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testEnvironmentPolicy = `environment:
+  - name: prod-logging
+    labels:
+      env: prod
+    required: [LOG_LEVEL]
+  - name: no-debug
+    forbidden: ["DEBUG=true", "AWS_SECRET_*"]
+  - name: batch-timeouts
+    groups: [batch]
+    functions: ["report-*"]
+    required: ["*_TIMEOUT"]
+`
+
+func Test_CheckEnvironment(t *testing.T) {
+	policy, err := Parse([]byte(testEnvironmentPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	cases := []struct {
+		workload Workload
+		want     []string
+	}{
+		{
+			workload: Workload{Name: "api", Labels: map[string]string{"env": "prod"}, Environment: map[string]string{"LOG_LEVEL": "info", "DEBUG": "false"}},
+		},
+		{
+			workload: Workload{Name: "api", Labels: map[string]string{"env": "prod"}, Environment: map[string]string{"DEBUG": "true", "AWS_SECRET_ACCESS_KEY": "x"}},
+			want: []string{
+				"LOG_LEVEL is required by prod-logging",
+				"DEBUG=true is forbidden by no-debug",
+				"AWS_SECRET_ACCESS_KEY is forbidden by no-debug",
+			},
+		},
+		{
+			workload: Workload{Name: "report-daily", Group: "batch", Environment: map[string]string{}},
+			want:     []string{"*_TIMEOUT is required by batch-timeouts"},
+		},
+		{
+			workload: Workload{Name: "report-daily", Group: "batch", Environment: map[string]string{"READ_TIMEOUT": "60s"}},
+		},
+		{
+			workload: Workload{Name: "ingest", Group: "batch", Environment: map[string]string{}},
+		},
+	}
+
+	for i, c := range cases {
+		if got := policy.CheckEnvironment(c.workload); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("case %d: want %q, got %q", i, c.want, got)
+		}
+	}
+}
+
+func Test_Parse_InvalidEnvironmentRule(t *testing.T) {
+	cases := []string{
+		`environment: [{name: empty, labels: {env: prod}}]`,
+		`environment: [{name: blank, required: [""]}]`,
+		`environment: [{forbidden: ["=true"]}]`,
+	}
+
+	for _, c := range cases {
+		if _, err := Parse([]byte(c)); err == nil {
+			t.Errorf("want error parsing %s", c)
+		}
+	}
+}
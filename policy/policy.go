@@ -0,0 +1,146 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package policy
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/ryanuber/go-glob"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// DefaultPolicyFile is read from the current folder when present
+const DefaultPolicyFile = ".faas-policy.yml"
+
+// OverrideAnnotation records why a deployment went ahead during a freeze
+const OverrideAnnotation = "com.openfaas.policy.override"
+
+// Policy holds change-management rules for deploying and removing functions
+type Policy struct {
+	// FreezeWindows during which protected functions must not change
+	FreezeWindows []FreezeWindow `yaml:"freeze_windows,omitempty"`
+
+	// ProtectedFunctions are glob patterns of function names covered by
+	// freeze windows, when empty every function is covered
+	ProtectedFunctions []string `yaml:"protected_functions,omitempty"`
+
+	// Environment rules are checked against each function's resolved
+	// environment before it is deployed
+	Environment []EnvironmentRule `yaml:"environment,omitempty"`
+}
+
+// FreezeWindow is a named cron range such as "* 17-23 * * 5" for Friday evenings
+type FreezeWindow struct {
+	Name string `yaml:"name"`
+	Cron string `yaml:"cron"`
+
+	// Timezone for the cron expression i.e. Europe/London, defaults to local time
+	Timezone string `yaml:"timezone"`
+
+	schedule *cronSchedule
+	location *time.Location
+}
+
+// Load reads a policy file, returning nil when the file does not exist
+func Load(path string) (*Policy, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse reads a policy from YAML and validates its freeze windows and rules
+func Parse(data []byte) (*Policy, error) {
+	var policy Policy
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("unable to parse policy: %s", err)
+	}
+
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &policy, nil
+}
+
+// Validate checks the freeze windows and compiles their cron expressions,
+// and checks the environment rules, it is needed for a policy read as part
+// of another file
+func (p *Policy) Validate() error {
+	for i := range p.Environment {
+		if err := p.Environment[i].validate(i); err != nil {
+			return err
+		}
+	}
+
+	for i := range p.FreezeWindows {
+		window := &p.FreezeWindows[i]
+		if len(window.Name) == 0 {
+			window.Name = window.Cron
+		}
+
+		schedule, err := parseCron(window.Cron)
+		if err != nil {
+			return fmt.Errorf("freeze window %s: %s", window.Name, err)
+		}
+		window.schedule = schedule
+
+		window.location = time.Local
+		if len(window.Timezone) > 0 {
+			location, err := time.LoadLocation(window.Timezone)
+			if err != nil {
+				return fmt.Errorf("freeze window %s: %s", window.Name, err)
+			}
+			window.location = location
+		}
+	}
+
+	return nil
+}
+
+// Protected tells whether functionName is covered by freeze windows
+func (p *Policy) Protected(functionName string) bool {
+	if len(p.ProtectedFunctions) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.ProtectedFunctions {
+		if glob.Glob(pattern, functionName) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActiveFreeze returns the first freeze window covering now, or nil
+func (p *Policy) ActiveFreeze(now time.Time) *FreezeWindow {
+	for i := range p.FreezeWindows {
+		window := &p.FreezeWindows[i]
+		if window.schedule.matches(now.In(window.location)) {
+			return window
+		}
+	}
+	return nil
+}
+
+// Check returns an error when functionName is protected and a freeze window
+// is active at now
+func (p *Policy) Check(functionName string, now time.Time) error {
+	if p == nil || !p.Protected(functionName) {
+		return nil
+	}
+
+	if window := p.ActiveFreeze(now); window != nil {
+		return fmt.Errorf("function %s is protected during freeze window %s (%s)", functionName, window.Name, window.Cron)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPolicy = `freeze_windows:
+  - name: friday-evening
+    cron: "* 17-23 * * 5"
+    timezone: UTC
+  - name: new-year
+    cron: "* * 1 1 *"
+    timezone: UTC
+protected_functions:
+  - "payments-*"
+  - checkout
+`
+
+func Test_Parse(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if len(policy.FreezeWindows) != 2 {
+		t.Fatalf("want 2 freeze windows, got %d", len(policy.FreezeWindows))
+	}
+	if len(policy.ProtectedFunctions) != 2 {
+		t.Fatalf("want 2 protected patterns, got %d", len(policy.ProtectedFunctions))
+	}
+}
+
+func Test_Parse_InvalidCron(t *testing.T) {
+	cases := []string{
+		`freeze_windows: [{name: short, cron: "* * *"}]`,
+		`freeze_windows: [{name: hour, cron: "* 24 * * *"}]`,
+		`freeze_windows: [{name: range, cron: "* 5-2 * * *"}]`,
+		`freeze_windows: [{name: step, cron: "*/0 * * * *"}]`,
+		`freeze_windows: [{name: tz, cron: "* * * * *", timezone: Nowhere/Place}]`,
+	}
+
+	for _, c := range cases {
+		if _, err := Parse([]byte(c)); err == nil {
+			t.Errorf("want error parsing %s", c)
+		}
+	}
+}
+
+func Test_Check(t *testing.T) {
+	policy, err := Parse([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fridayEvening := time.Date(2018, 6, 1, 18, 30, 0, 0, time.UTC)
+	fridayMorning := time.Date(2018, 6, 1, 9, 0, 0, 0, time.UTC)
+	newYear := time.Date(2019, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		title        string
+		functionName string
+		now          time.Time
+		wantErr      string
+	}{
+		{"protected function in freeze", "payments-api", fridayEvening, "freeze window friday-evening"},
+		{"exact protected name in freeze", "checkout", newYear, "freeze window new-year"},
+		{"unprotected function in freeze", "url-ping", fridayEvening, ""},
+		{"protected function outside freeze", "payments-api", fridayMorning, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.title, func(t *testing.T) {
+			err := policy.Check(c.functionName, c.now)
+			if len(c.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("want no error, got %s", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Fatalf("want error containing %q, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Check_NoProtectedFunctions(t *testing.T) {
+	policy, err := Parse([]byte(`freeze_windows: [{name: always, cron: "*/15 0-23 * 1,6-12 0-6"}]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	inWindow := time.Date(2018, 6, 1, 10, 45, 0, 0, time.Local)
+	if err := policy.Check("any-function", inWindow); err == nil {
+		t.Fatalf("want every function protected when no patterns are given")
+	}
+
+	offStep := time.Date(2018, 6, 1, 10, 46, 0, 0, time.Local)
+	if err := policy.Check("any-function", offStep); err != nil {
+		t.Fatalf("want no freeze off the step, got %s", err)
+	}
+
+	outsideMonths := time.Date(2018, 3, 1, 10, 45, 0, 0, time.Local)
+	if err := policy.Check("any-function", outsideMonths); err != nil {
+		t.Fatalf("want no freeze outside the months, got %s", err)
+	}
+}
+
+func Test_parseCron_DayOfMonthOrDayOfWeek(t *testing.T) {
+	cases := []struct {
+		expression string
+		day        time.Time
+		want       bool
+	}{
+		// The 1st of the month or any Friday
+		{"* * 1 * 5", time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{"* * 1 * 5", time.Date(2018, 3, 2, 12, 0, 0, 0, time.UTC), true},
+		{"* * 1 * 5", time.Date(2018, 3, 3, 12, 0, 0, 0, time.UTC), false},
+		// Every Friday, the day of month is not restricted
+		{"* * * * 5", time.Date(2018, 3, 1, 12, 0, 0, 0, time.UTC), false},
+		{"* * */2 * 5", time.Date(2018, 3, 2, 12, 0, 0, 0, time.UTC), false},
+		{"* * */2 * 5", time.Date(2018, 3, 9, 12, 0, 0, 0, time.UTC), true},
+	}
+	for _, c := range cases {
+		schedule, err := parseCron(c.expression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := schedule.matches(c.day); got != c.want {
+			t.Errorf("%q on %s: want %t, got %t", c.expression, c.day.Format("Mon Jan 2"), c.want, got)
+		}
+	}
+}
+
+func Test_Check_NilPolicy(t *testing.T) {
+	var policy *Policy
+	if err := policy.Check("payments-api", time.Now()); err != nil {
+		t.Fatalf("want nil policy to allow changes, got %s", err)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CallAPI sends a request to a path on the gateway with the credentials saved
+// for it, the caller must close the response's body
+func CallAPI(gateway string, method string, path string, body io.Reader, headers http.Header, timeout time.Duration) (*http.Response, error) {
+	gateway = strings.TrimRight(gateway, "/")
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(strings.ToUpper(method), gateway+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %s", err)
+	}
+	for name, values := range headers {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	return res, nil
+}
@@ -5,12 +5,18 @@ package proxy
 
 import (
 	"net/http"
+	"sync"
 
 	"github.com/openfaas/faas-cli/config"
 )
 
-//SetAuth sets basic auth for the given gateway
+//SetAuth sets basic auth, or the oauth2 token saved by auth login, for the
+//given gateway
 func SetAuth(req *http.Request, gateway string) {
+	if setBearerAuth(req, gateway) {
+		return
+	}
+
 	username, password, err := config.LookupAuthConfig(gateway)
 	if err != nil {
 		// no auth info found
@@ -19,3 +25,41 @@ func SetAuth(req *http.Request, gateway string) {
 
 	req.SetBasicAuth(username, password)
 }
+
+// Reauthenticate is called when the gateway rejects the saved credentials, it
+// returns true once they were renewed so that the request can be sent again
+var Reauthenticate func(gateway string) bool
+
+var reauthLock sync.Mutex
+
+// doWithAuth sends a request made with SetAuth and, when the gateway answers
+// 401 and Reauthenticate renews the credentials, sends it once more. Requests
+// whose body can't be read again are not retried.
+func doWithAuth(client http.Client, req *http.Request, gateway string) (*http.Response, error) {
+	res, err := doLimited(client, req, gateway)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || Reauthenticate == nil {
+		return res, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return res, nil
+	}
+
+	reauthLock.Lock()
+	renewed := Reauthenticate(gateway)
+	reauthLock.Unlock()
+	if !renewed {
+		return res, nil
+	}
+
+	if req.GetBody != nil {
+		body, bodyErr := req.GetBody()
+		if bodyErr != nil {
+			return res, nil
+		}
+		req.Body = body
+	}
+	res.Body.Close()
+
+	SetAuth(req, gateway)
+	return doLimited(client, req, gateway)
+}
@@ -4,7 +4,9 @@
 package proxy
 
 import (
+	"bytes"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -45,3 +47,71 @@ func Test_SetAuth_SkipAuthorization(t *testing.T) {
 		t.Errorf("got header %q, want none", header)
 	}
 }
+
+// rejectingGateway answers 401 unless the request has the password, and
+// records the body of each request
+func rejectingGateway(password string, bodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(body))
+		if _, pass, _ := r.BasicAuth(); pass != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func Test_doWithAuth_RetriesOnceRenewed(t *testing.T) {
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-auth-test")
+	config.DefaultFile = "authtest3.yml"
+
+	var bodies []string
+	s := rejectingGateway("rotated", &bodies)
+	defer s.Close()
+	config.UpdateAuthConfig(s.URL, "admin", "expired")
+
+	var asked []string
+	Reauthenticate = func(gateway string) bool {
+		asked = append(asked, gateway)
+		return config.UpdateAuthConfig(gateway, "admin", "rotated") == nil
+	}
+	defer func() { Reauthenticate = nil }()
+
+	req, _ := http.NewRequest(http.MethodPut, s.URL+"/system/functions", bytes.NewReader([]byte(`{"service":"fn"}`)))
+	SetAuth(req, s.URL)
+	res, err := doWithAuth(http.Client{}, req, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("want the retry to succeed, got %d", res.StatusCode)
+	}
+	if len(asked) != 1 || asked[0] != s.URL {
+		t.Errorf("want the credentials renewed once for %s, got %v", s.URL, asked)
+	}
+	if len(bodies) != 2 || bodies[1] != `{"service":"fn"}` {
+		t.Errorf("want the request sent again with its body, got %q", bodies)
+	}
+}
+
+func Test_doWithAuth_NotRenewed(t *testing.T) {
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-auth-test")
+	config.DefaultFile = "authtest4.yml"
+
+	var bodies []string
+	s := rejectingGateway("rotated", &bodies)
+	defer s.Close()
+
+	Reauthenticate = func(gateway string) bool { return false }
+	defer func() { Reauthenticate = nil }()
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/system/functions", nil)
+	res, err := doWithAuth(http.Client{}, req, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusUnauthorized || len(bodies) != 1 {
+		t.Errorf("want the 401 returned without a retry, got %d after %d request(s)", res.StatusCode, len(bodies))
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CallStatus is the outcome of an asynchronous invocation, reported by
+// providers whose queue keeps a record of each call
+type CallStatus struct {
+	ID       string `json:"id"`
+	Function string `json:"function,omitempty"`
+
+	// Status is queued, running, completed or failed
+	Status string `json:"status"`
+
+	// StatusCode is the function's response once the call has run
+	StatusCode int `json:"statusCode,omitempty"`
+
+	// Duration is in seconds
+	Duration float64   `json:"duration,omitempty"`
+	Started  time.Time `json:"started,omitempty"`
+	Finished time.Time `json:"finished,omitempty"`
+}
+
+// GetCallStatus reads the status of an asynchronous invocation by the id the
+// gateway gave in X-Call-Id, found is false when the gateway has no record
+// of it or does not keep records of calls
+func GetCallStatus(gateway string, callID string) (status CallStatus, found bool, err error) {
+	gateway = strings.TrimRight(gateway, "/")
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	getRequest, err := http.NewRequest(http.MethodGet, gateway+"/system/call/"+url.PathEscape(callID), nil)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	SetAuth(getRequest, gateway)
+
+	res, err := doWithAuth(client, getRequest, gateway)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	defer res.Body.Close()
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		if err := json.Unmarshal(bytesOut, &status); err != nil {
+			return status, false, fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, err.Error())
+		}
+		return status, true, nil
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return status, false, nil
+	case http.StatusUnauthorized:
+		return status, false, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		return status, false, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_GetCallStatus(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/call/call-1",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       CallStatus{ID: "call-1", Function: "resize", Status: "running"},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/call/call-2",
+			ResponseStatusCode: http.StatusNotImplemented,
+		},
+	})
+	defer s.Close()
+
+	status, found, err := GetCallStatus(s.URL, "call-1")
+	if err != nil || !found || status.Status != "running" || status.Function != "resize" {
+		t.Errorf("got %+v, %v, %v", status, found, err)
+	}
+
+	if _, found, err := GetCallStatus(s.URL, "call-2"); err != nil || found {
+		t.Errorf("want not found when the gateway keeps no record, got %v, %v", found, err)
+	}
+}
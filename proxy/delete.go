@@ -16,20 +16,26 @@ import (
 
 // DeleteFunction delete a function from the FaaS server
 func DeleteFunction(gateway string, functionName string) error {
+	return DeleteFunctionInNamespace(gateway, functionName, "")
+}
+
+// DeleteFunctionInNamespace deletes a function from a namespace, an empty
+// namespace is the provider's default
+func DeleteFunctionInNamespace(gateway string, functionName string, namespace string) error {
 	gateway = strings.TrimRight(gateway, "/")
 	delReq := requests.DeleteFunctionRequest{FunctionName: functionName}
 	reqBytes, _ := json.Marshal(&delReq)
 	reader := bytes.NewReader(reqBytes)
 
 	c := http.Client{}
-	req, err := http.NewRequest("DELETE", gateway+"/system/functions", reader)
+	req, err := http.NewRequest("DELETE", gateway+"/system/functions"+namespaceQuery(namespace), reader)
 	if err != nil {
 		fmt.Println(err)
 		return err
 	}
 	req.Header.Set("Content-Type", "application/json")
 	SetAuth(req, gateway)
-	delRes, delErr := c.Do(req)
+	delRes, delErr := doWithAuth(c, req, gateway)
 
 	if delErr != nil {
 		fmt.Printf("Error removing existing function: %s, gateway=%s, functionName=%s\n", delErr.Error(), gateway, functionName)
@@ -28,6 +28,25 @@ func Test_DeleteFunction(t *testing.T) {
 	}
 }
 
+func Test_DeleteFunctionInNamespace(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/functions?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+		},
+	})
+	defer s.Close()
+
+	var err error
+	test.CaptureStdout(func() {
+		err = DeleteFunctionInNamespace(s.URL, "function-to-delete", "staging")
+	})
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+}
+
 func Test_DeleteFunction_404(t *testing.T) {
 	s := test.MockHttpServerStatus(t, http.StatusNotFound)
 	defer s.Close()
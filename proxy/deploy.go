@@ -22,62 +22,98 @@ type FunctionResourceRequest struct {
 	Requests *stack.FunctionResources
 }
 
-func DeployFunction(fprocess string, gateway string, functionName string, image string,
-	language string, replace bool, envVars map[string]string, network string,
-	constraints []string, update bool, secrets []string, labels map[string]string,
-	functionResourceRequest1 FunctionResourceRequest) {
+// DeployFunctionSpec defines the spec used when deploying a function
+type DeployFunctionSpec struct {
+	FProcess                string
+	FunctionName            string
+	Image                   string
+	Language                string
+	Replace                 bool
+	EnvVars                 map[string]string
+	Network                 string
+	Constraints             []string
+	Update                  bool
+	Secrets                 []string
+	Labels                  map[string]string
+	Annotations             map[string]string
+	FunctionResourceRequest FunctionResourceRequest
+
+	// Namespace is the provider's default when empty
+	Namespace string
+}
+
+// createFunctionRequest extends the gateway's request with annotations, which
+// providers read for settings that are not used for scheduling or routing
+type createFunctionRequest struct {
+	requests.CreateFunctionRequest
+
+	Annotations *map[string]string `json:"annotations,omitempty"`
+
+	Namespace string `json:"namespace,omitempty"`
+}
 
-	rollingUpdateInfo := fmt.Sprintf("Function %s already exists, attempting rolling-update.", functionName)
-	statusCode, deployOutput := Deploy(fprocess, gateway, functionName, image, language, replace, envVars, network, constraints, update, secrets, labels, functionResourceRequest1)
+// DeployFunction deploys or updates a function and prints the outcome, the
+// status code of the final call to the gateway is returned
+func DeployFunction(gateway string, spec *DeployFunctionSpec) int {
 
-	if update == true && statusCode == http.StatusNotFound {
+	rollingUpdateInfo := fmt.Sprintf("Function %s already exists, attempting rolling-update.", spec.FunctionName)
+	statusCode, deployOutput := Deploy(gateway, spec, spec.Update)
+
+	if spec.Update == true && statusCode == http.StatusNotFound {
 		// Re-run the function with update=false
-		_, deployOutput = Deploy(fprocess, gateway, functionName, image, language, replace, envVars, network, constraints, false, secrets, labels, functionResourceRequest1)
+		statusCode, deployOutput = Deploy(gateway, spec, false)
 	} else if statusCode == http.StatusOK {
 		fmt.Println(rollingUpdateInfo)
 	}
 	fmt.Println()
 	fmt.Println(deployOutput)
+
+	return statusCode
 }
 
-func Deploy(fprocess string, gateway string, functionName string, image string,
-	language string, replace bool, envVars map[string]string, network string,
-	constraints []string, update bool, secrets []string, labels map[string]string,
-	functionResourceRequest1 FunctionResourceRequest) (int, string) {
+// Deploy calls the gateway to create the function, or to update it when update is true
+func Deploy(gateway string, spec *DeployFunctionSpec, update bool) (int, string) {
 
 	var deployOutput string
 	// Need to alter Gateway to allow nil/empty string as fprocess, to avoid this repetition.
 	var fprocessTemplate string
-	if len(fprocess) > 0 {
-		fprocessTemplate = fprocess
+	if len(spec.FProcess) > 0 {
+		fprocessTemplate = spec.FProcess
 	}
 
 	gateway = strings.TrimRight(gateway, "/")
 
-	if replace {
-		DeleteFunction(gateway, functionName)
+	if spec.Replace {
+		DeleteFunction(gateway, spec.FunctionName)
+	}
+
+	req := createFunctionRequest{
+		CreateFunctionRequest: requests.CreateFunctionRequest{
+			EnvProcess:  fprocessTemplate,
+			Image:       spec.Image,
+			Network:     spec.Network,
+			Service:     spec.FunctionName,
+			EnvVars:     spec.EnvVars,
+			Constraints: spec.Constraints,
+			Secrets:     spec.Secrets, // TODO: allow registry auth to be specified or read from local Docker credentials store
+			Labels:      &spec.Labels,
+		},
 	}
 
-	req := requests.CreateFunctionRequest{
-		EnvProcess:  fprocessTemplate,
-		Image:       image,
-		Network:     network,
-		Service:     functionName,
-		EnvVars:     envVars,
-		Constraints: constraints,
-		Secrets:     secrets, // TODO: allow registry auth to be specified or read from local Docker credentials store
-		Labels:      &labels,
+	if len(spec.Annotations) > 0 {
+		req.Annotations = &spec.Annotations
 	}
+	req.Namespace = spec.Namespace
 
 	hasLimits := false
 	req.Limits = &requests.FunctionResources{}
-	if functionResourceRequest1.Limits != nil && len(functionResourceRequest1.Limits.Memory) > 0 {
+	if spec.FunctionResourceRequest.Limits != nil && len(spec.FunctionResourceRequest.Limits.Memory) > 0 {
 		hasLimits = true
-		req.Limits.Memory = functionResourceRequest1.Limits.Memory
+		req.Limits.Memory = spec.FunctionResourceRequest.Limits.Memory
 	}
-	if functionResourceRequest1.Limits != nil && len(functionResourceRequest1.Limits.CPU) > 0 {
+	if spec.FunctionResourceRequest.Limits != nil && len(spec.FunctionResourceRequest.Limits.CPU) > 0 {
 		hasLimits = true
-		req.Limits.CPU = functionResourceRequest1.Limits.CPU
+		req.Limits.CPU = spec.FunctionResourceRequest.Limits.CPU
 	}
 	if !hasLimits {
 		req.Limits = nil
@@ -85,13 +121,13 @@ func Deploy(fprocess string, gateway string, functionName string, image string,
 
 	hasRequests := false
 	req.Requests = &requests.FunctionResources{}
-	if functionResourceRequest1.Requests != nil && len(functionResourceRequest1.Requests.Memory) > 0 {
+	if spec.FunctionResourceRequest.Requests != nil && len(spec.FunctionResourceRequest.Requests.Memory) > 0 {
 		hasRequests = true
-		req.Requests.Memory = functionResourceRequest1.Requests.Memory
+		req.Requests.Memory = spec.FunctionResourceRequest.Requests.Memory
 	}
-	if functionResourceRequest1.Requests != nil && len(functionResourceRequest1.Requests.CPU) > 0 {
+	if spec.FunctionResourceRequest.Requests != nil && len(spec.FunctionResourceRequest.Requests.CPU) > 0 {
 		hasRequests = true
-		req.Requests.CPU = functionResourceRequest1.Requests.CPU
+		req.Requests.CPU = spec.FunctionResourceRequest.Requests.CPU
 	}
 
 	if !hasRequests {
@@ -119,7 +155,7 @@ func Deploy(fprocess string, gateway string, functionName string, image string,
 		return http.StatusInternalServerError, deployOutput
 	}
 
-	res, err := client.Do(request)
+	res, err := doWithAuth(client, request, gateway)
 	if err != nil {
 		deployOutput += fmt.Sprintln("Is FaaS deployed? Do you need to specify the --gateway flag?")
 		deployOutput += fmt.Sprintln(err)
@@ -134,7 +170,7 @@ func Deploy(fprocess string, gateway string, functionName string, image string,
 	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
 		deployOutput += fmt.Sprintf("Deployed. %s.\n", res.Status)
 
-		deployedURL := fmt.Sprintf("URL: %s/function/%s", gateway, functionName)
+		deployedURL := fmt.Sprintf("URL: %s/function/%s", gateway, spec.FunctionName)
 		deployOutput += fmt.Sprintln(deployedURL)
 	case http.StatusUnauthorized:
 		deployOutput += fmt.Sprintln("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
@@ -30,21 +30,18 @@ func runDeployProxyTest(t *testing.T, deployTest deployProxyTest) {
 	defer s.Close()
 
 	stdout := test.CaptureStdout(func() {
-		DeployFunction(
-			"fproces",
-			s.URL,
-			"function",
-			"image",
-			"language",
-			deployTest.replace,
-			nil,
-			"network",
-			[]string{},
-			deployTest.update,
-			[]string{},
-			map[string]string{},
-			FunctionResourceRequest{},
-		)
+		DeployFunction(s.URL, &DeployFunctionSpec{
+			FProcess:     "fproces",
+			FunctionName: "function",
+			Image:        "image",
+			Language:     "language",
+			Replace:      deployTest.replace,
+			Network:      "network",
+			Constraints:  []string{},
+			Update:       deployTest.update,
+			Secrets:      []string{},
+			Labels:       map[string]string{},
+		})
 	})
 
 	r := regexp.MustCompile(deployTest.expectedOutput)
@@ -88,21 +85,16 @@ func Test_DeployFunction_MissingURLPrefix(t *testing.T) {
 	url := "127.0.0.1:8080"
 
 	stdout := test.CaptureStdout(func() {
-		DeployFunction(
-			"fprocess",
-			url,
-			"function",
-			"image",
-			"language",
-			false,
-			nil,
-			"network",
-			[]string{},
-			false,
-			[]string{},
-			map[string]string{},
-			FunctionResourceRequest{},
-		)
+		DeployFunction(url, &DeployFunctionSpec{
+			FProcess:     "fprocess",
+			FunctionName: "function",
+			Image:        "image",
+			Language:     "language",
+			Network:      "network",
+			Constraints:  []string{},
+			Secrets:      []string{},
+			Labels:       map[string]string{},
+		})
 	})
 
 	expectedErrMsg := "first path segment in URL cannot contain colon"
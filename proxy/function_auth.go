@@ -0,0 +1,104 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+// DefaultHMACHeader carries the signature of the body for hmac auth
+const DefaultHMACHeader = "X-Hub-Signature"
+
+// ValidateFunctionAuth checks the credentials needed by the auth type are present
+func ValidateFunctionAuth(auth *stack.FunctionAuth) error {
+	if auth == nil {
+		return nil
+	}
+
+	switch auth.Type {
+	case stack.AuthNone:
+	case stack.AuthBasic:
+		if len(auth.Username) == 0 {
+			return fmt.Errorf("basic auth needs a username")
+		}
+	case stack.AuthBearer:
+		if len(auth.Token) == 0 {
+			return fmt.Errorf("bearer auth needs a token")
+		}
+	case stack.AuthHMAC:
+		if len(auth.Key) == 0 {
+			return fmt.Errorf("hmac auth needs a key")
+		}
+		if _, err := hmacHash(auth.Algorithm); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("auth type %q must be one of basic, bearer, hmac or none", auth.Type)
+	}
+	return nil
+}
+
+// usesAuthorization reports whether the function's auth replaces the
+// gateway's credentials in the Authorization header
+func usesAuthorization(auth *stack.FunctionAuth) bool {
+	return auth != nil && (auth.Type == stack.AuthBasic || auth.Type == stack.AuthBearer)
+}
+
+// applyFunctionAuth adds the function's credentials to the request
+func applyFunctionAuth(req *http.Request, auth *stack.FunctionAuth, body []byte) error {
+	if auth == nil {
+		return nil
+	}
+	if err := ValidateFunctionAuth(auth); err != nil {
+		return err
+	}
+
+	switch auth.Type {
+	case stack.AuthBasic:
+		req.SetBasicAuth(auth.Username, auth.Password)
+	case stack.AuthBearer:
+		req.Header.Set("Authorization", "Bearer "+auth.Token)
+	case stack.AuthHMAC:
+		header := auth.Header
+		if len(header) == 0 {
+			header = DefaultHMACHeader
+		}
+		signature, _ := signBody(auth.Algorithm, auth.Key, body)
+		req.Header.Set(header, signature)
+	}
+	return nil
+}
+
+// signBody gives the hmac of the body prefixed by its algorithm, i.e. sha1=...
+func signBody(algorithm string, key string, body []byte) (string, error) {
+	newHash, err := hmacHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	if len(algorithm) == 0 {
+		algorithm = "sha1"
+	}
+
+	mac := hmac.New(newHash, []byte(key))
+	mac.Write(body)
+	return algorithm + "=" + hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func hmacHash(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "", "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	}
+	return nil, fmt.Errorf("hmac algorithm %q must be sha1 or sha256", algorithm)
+}
@@ -0,0 +1,103 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/stack"
+)
+
+func Test_InvokeFunctionWithAuth(t *testing.T) {
+	body := []byte(`{"q": 1}`)
+
+	cases := []struct {
+		name       string
+		auth       *stack.FunctionAuth
+		header     string
+		wantHeader string
+	}{
+		{
+			name:       "basic",
+			auth:       &stack.FunctionAuth{Type: stack.AuthBasic, Username: "admin", Password: "pass"},
+			header:     "Authorization",
+			wantHeader: "Basic YWRtaW46cGFzcw==",
+		},
+		{
+			name:       "bearer",
+			auth:       &stack.FunctionAuth{Type: stack.AuthBearer, Token: "t0ken"},
+			header:     "Authorization",
+			wantHeader: "Bearer t0ken",
+		},
+		{
+			name:       "hmac sha1",
+			auth:       &stack.FunctionAuth{Type: stack.AuthHMAC, Key: "secret"},
+			header:     DefaultHMACHeader,
+			wantHeader: "sha1=8fe1a2c76784046bc60970b1f135e4b1bdd2a0e2",
+		},
+		{
+			name:       "hmac sha256 custom header",
+			auth:       &stack.FunctionAuth{Type: stack.AuthHMAC, Key: "secret", Algorithm: "sha256", Header: "X-Signature"},
+			header:     "X-Signature",
+			wantHeader: "sha256=7502146eb676ed2cee293243b1d61090721dad11e225f0baa9ca0b4254e2e241",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get(c.header)
+			}))
+			defer s.Close()
+
+			if _, err := InvokeFunctionWithAuth(s.URL, "fn", &body, "application/json", nil, c.auth); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != c.wantHeader {
+				t.Errorf("want %s: %q, got %q", c.header, c.wantHeader, got)
+			}
+		})
+	}
+}
+
+func Test_InvokeFunctionWithAuth_Unauthorized(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer s.Close()
+
+	body := []byte("")
+	_, err := InvokeFunctionWithAuth(s.URL, "fn", &body, "text/plain", nil, &stack.FunctionAuth{Type: stack.AuthBearer, Token: "expired"})
+	if err == nil || !strings.Contains(err.Error(), "check the bearer credentials for function fn") {
+		t.Fatalf("want an error about the function's credentials, got: %v", err)
+	}
+}
+
+func Test_ValidateFunctionAuth(t *testing.T) {
+	cases := []struct {
+		auth    stack.FunctionAuth
+		wantErr string
+	}{
+		{stack.FunctionAuth{Type: stack.AuthNone}, ""},
+		{stack.FunctionAuth{Type: stack.AuthBasic}, "basic auth needs a username"},
+		{stack.FunctionAuth{Type: stack.AuthBearer}, "bearer auth needs a token"},
+		{stack.FunctionAuth{Type: stack.AuthHMAC}, "hmac auth needs a key"},
+		{stack.FunctionAuth{Type: stack.AuthHMAC, Key: "k", Algorithm: "md5"}, `hmac algorithm "md5" must be sha1 or sha256`},
+		{stack.FunctionAuth{Type: "digest"}, `auth type "digest" must be one of basic, bearer, hmac or none`},
+	}
+
+	for _, c := range cases {
+		err := ValidateFunctionAuth(&c.auth)
+		if len(c.wantErr) == 0 && err != nil {
+			t.Errorf("%+v: unexpected error: %s", c.auth, err)
+		}
+		if len(c.wantErr) > 0 && (err == nil || err.Error() != c.wantErr) {
+			t.Errorf("%+v: want error %q, got %v", c.auth, c.wantErr, err)
+		}
+	}
+}
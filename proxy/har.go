@@ -0,0 +1,370 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedHeaders are written to a HAR file without their values so that it
+// can be attached to a bug report
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+	"Set-Cookie":    true,
+}
+
+// HARRecorder is a http.RoundTripper which records each request and response
+// in HTTP Archive (HAR) 1.2 format
+type HARRecorder struct {
+	// Transport makes the requests, http.DefaultTransport when nil
+	Transport http.RoundTripper
+
+	// MaxBodySize caps the bytes of each body kept in the archive
+	MaxBodySize int
+
+	creatorVersion string
+	entries        []harEntry
+	lock           sync.Mutex
+}
+
+// NewHARRecorder creates a recorder keeping up to maxBodySize bytes of each body
+func NewHARRecorder(creatorVersion string, maxBodySize int) *HARRecorder {
+	return &HARRecorder{
+		MaxBodySize:    maxBodySize,
+		creatorVersion: creatorVersion,
+	}
+}
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// RoundTrip makes the request with Transport and records it
+func (r *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	return r.roundTrip(r.Transport, req)
+}
+
+// Wrap returns a http.RoundTripper which records each request made through
+// transport, so that a client keeps its own transport settings while the
+// invocation is recorded
+func (r *HARRecorder) Wrap(transport http.RoundTripper) http.RoundTripper {
+	return harTransport{recorder: r, transport: transport}
+}
+
+type harTransport struct {
+	recorder  *HARRecorder
+	transport http.RoundTripper
+}
+
+func (t harTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.recorder.roundTrip(t.transport, req)
+}
+
+// roundTrip makes the request and records it, the request body is buffered
+// so it can be sent as normal, the response body is recorded as the caller
+// reads it and the entry is added once it has been read or closed
+func (r *HARRecorder) roundTrip(transport http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	var requestBody []byte
+	if req.Body != nil {
+		var err error
+		requestBody, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	entry := harEntry{
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header),
+			QueryString: harQueryString(req),
+			HeadersSize: -1,
+			BodySize:    len(requestBody),
+		},
+		Response: harResponse{
+			Cookies:     []harNameValue{},
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+	}
+
+	if req.Body != nil {
+		text, comment := r.capBody(requestBody, len(requestBody))
+		entry.Request.PostData = &harPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     text,
+			Comment:  comment,
+		}
+	}
+
+	started := time.Now()
+	entry.StartedDateTime = started.Format(time.RFC3339Nano)
+
+	res, err := transport.RoundTrip(req)
+	waited := time.Since(started)
+
+	if err != nil {
+		entry.Error = err.Error()
+		entry.Timings.Wait = milliseconds(waited)
+		entry.Time = entry.Timings.Wait
+		r.add(entry)
+		return nil, err
+	}
+
+	entry.Response.Status = res.StatusCode
+	entry.Response.StatusText = strings.TrimPrefix(res.Status, strconv.Itoa(res.StatusCode)+" ")
+	entry.Response.HTTPVersion = res.Proto
+	entry.Response.Headers = harHeaders(res.Header)
+	entry.Response.RedirectURL = res.Header.Get("Location")
+	entry.Response.Content.MimeType = res.Header.Get("Content-Type")
+	entry.Timings.Wait = milliseconds(waited)
+
+	body := &harBody{
+		recorder: r,
+		entry:    entry,
+		started:  started,
+		waited:   waited,
+	}
+	if res.Body == nil {
+		body.finish(nil)
+		return res, nil
+	}
+
+	body.ReadCloser = res.Body
+	res.Body = body
+	return res, nil
+}
+
+// harBody passes a response body through to the caller, keeping up to
+// MaxBodySize bytes of it for the archive
+type harBody struct {
+	io.ReadCloser
+
+	recorder *HARRecorder
+	entry    harEntry
+	started  time.Time
+	waited   time.Duration
+	kept     bytes.Buffer
+	size     int
+	once     sync.Once
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.size += n
+
+	keep := n
+	if max := b.recorder.MaxBodySize; max >= 0 && b.kept.Len()+keep > max {
+		keep = max - b.kept.Len()
+	}
+	if keep > 0 {
+		b.kept.Write(p[:keep])
+	}
+
+	if err == io.EOF {
+		b.finish(nil)
+	} else if err != nil {
+		b.finish(err)
+	}
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.finish(nil)
+	return err
+}
+
+// finish adds the entry for the response once, with the part of the body
+// which was read
+func (b *harBody) finish(readErr error) {
+	b.once.Do(func() {
+		entry := b.entry
+		if readErr != nil {
+			entry.Error = readErr.Error()
+		}
+
+		text, comment := b.recorder.capBody(b.kept.Bytes(), b.size)
+		entry.Response.BodySize = b.size
+		entry.Response.Content.Size = b.size
+		entry.Response.Content.Text = text
+		entry.Response.Content.Comment = comment
+
+		entry.Timings.Receive = milliseconds(time.Since(b.started) - b.waited)
+		entry.Time = entry.Timings.Wait + entry.Timings.Receive
+
+		b.recorder.add(entry)
+	})
+}
+
+// Marshal renders the recorded entries as a HAR document
+func (r *HARRecorder) Marshal() ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	entries := r.entries
+	if entries == nil {
+		entries = []harEntry{}
+	}
+
+	return json.MarshalIndent(harFile{
+		Log: harLog{
+			Version: "1.2",
+			Creator: harCreator{Name: "faas-cli", Version: r.creatorVersion},
+			Entries: entries,
+		},
+	}, "", "  ")
+}
+
+// WriteFile saves the recorded entries to a HAR file
+func (r *HARRecorder) WriteFile(path string) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func (r *HARRecorder) add(entry harEntry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.entries = append(r.entries, entry)
+}
+
+// capBody gives the recorded text of a body of size bytes, of which body
+// holds at least the first MaxBodySize
+func (r *HARRecorder) capBody(body []byte, size int) (string, string) {
+	if r.MaxBodySize >= 0 && size > r.MaxBodySize {
+		if len(body) > r.MaxBodySize {
+			body = body[:r.MaxBodySize]
+		}
+		return string(body), "truncated, body was larger than the recorded size cap"
+	}
+	return string(body), ""
+}
+
+func harHeaders(header http.Header) []harNameValue {
+	headers := []harNameValue{}
+	for name, values := range header {
+		for _, value := range values {
+			if redactedHeaders[http.CanonicalHeaderKey(name)] {
+				value = "REDACTED"
+			}
+			headers = append(headers, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	sort.Slice(headers, func(i, j int) bool {
+		return headers[i].Name < headers[j].Name
+	})
+	return headers
+}
+
+func harQueryString(req *http.Request) []harNameValue {
+	query := []harNameValue{}
+	for name, values := range req.URL.Query() {
+		for _, value := range values {
+			query = append(query, harNameValue{Name: name, Value: value})
+		}
+	}
+
+	sort.Slice(query, func(i, j int) bool {
+		return query[i].Name < query[j].Name
+	})
+	return query
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,159 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_HARRecorder_RecordsInvocation(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/function/echo?a=1",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       "hello world",
+		},
+	})
+	defer s.Close()
+
+	recorder := NewHARRecorder("test", 5)
+	InvokeTransport = recorder.Wrap
+	defer func() { InvokeTransport = nil }()
+
+	bytesIn := []byte("request body")
+	response, err := InvokeFunction(s.URL, "echo", &bytesIn, "text/plain", []string{"a=1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(*response) != `"hello world"` {
+		t.Fatalf("want the full response to reach the caller, got %q", string(*response))
+	}
+
+	data, err := recorder.Marshal()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("unable to parse HAR: %s", err)
+	}
+
+	if har.Log.Version != "1.2" || har.Log.Creator.Version != "test" {
+		t.Fatalf("unexpected log header: %+v", har.Log)
+	}
+	if len(har.Log.Entries) != 1 {
+		t.Fatalf("want 1 entry, got %d", len(har.Log.Entries))
+	}
+
+	entry := har.Log.Entries[0]
+	if entry.Request.Method != http.MethodPost || entry.Request.BodySize != len(bytesIn) {
+		t.Errorf("unexpected request: %+v", entry.Request)
+	}
+	if entry.Request.PostData == nil || entry.Request.PostData.Text != "reque" || entry.Request.PostData.MimeType != "text/plain" {
+		t.Errorf("want truncated post data, got %+v", entry.Request.PostData)
+	}
+	if len(entry.Request.QueryString) != 1 || entry.Request.QueryString[0].Value != "1" {
+		t.Errorf("want query string a=1, got %+v", entry.Request.QueryString)
+	}
+	if entry.Response.Status != http.StatusOK || entry.Response.StatusText != "OK" {
+		t.Errorf("unexpected response status: %d %s", entry.Response.Status, entry.Response.StatusText)
+	}
+	if entry.Response.Content.Text != `"hell` || entry.Response.Content.Size != len(*response) || len(entry.Response.Content.Comment) == 0 {
+		t.Errorf("want truncated response content, got %+v", entry.Response.Content)
+	}
+}
+
+func Test_HARRecorder_RedactsAuthorization(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusOK)
+	defer s.Close()
+
+	recorder := NewHARRecorder("test", 1024)
+	client := http.Client{Transport: recorder}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL, nil)
+	req.SetBasicAuth("admin", "secret")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	headers := recorder.entries[0].Request.Headers
+	for _, header := range headers {
+		if header.Name == "Authorization" && header.Value != "REDACTED" {
+			t.Fatalf("want Authorization redacted, got %s", header.Value)
+		}
+	}
+	if recorder.entries[0].Request.PostData != nil {
+		t.Fatalf("want no post data for a GET")
+	}
+}
+
+func Test_HARRecorder_RecordsConnectionErrors(t *testing.T) {
+	recorder := NewHARRecorder("test", 1024)
+	client := http.Client{Transport: recorder}
+
+	if _, err := client.Get("http://127.0.0.1:1/"); err == nil {
+		t.Fatalf("want connection error")
+	}
+
+	if len(recorder.entries) != 1 || len(recorder.entries[0].Error) == 0 {
+		t.Fatalf("want the failed request recorded with its error, got %+v", recorder.entries)
+	}
+}
+
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func Test_HARRecorder_WrapKeepsClientTransport(t *testing.T) {
+	body := strings.Repeat("a", 4096)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer s.Close()
+
+	recorder := NewHARRecorder("test", 8)
+	transport := &countingTransport{}
+	client := http.Client{Transport: recorder.Wrap(transport)}
+
+	res, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(recorder.entries) != 0 {
+		t.Fatalf("want the entry added once the body has been read")
+	}
+	received, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	if transport.requests != 1 {
+		t.Fatalf("want the request made through the wrapped transport, got %d requests", transport.requests)
+	}
+	if string(received) != body {
+		t.Fatalf("want the full body to reach the caller, got %d bytes", len(received))
+	}
+	if len(recorder.entries) != 1 {
+		t.Fatalf("want 1 entry, got %d", len(recorder.entries))
+	}
+	content := recorder.entries[0].Response.Content
+	if content.Text != "aaaaaaaa" || content.Size != len(body) || len(content.Comment) == 0 {
+		t.Fatalf("want the recorded body capped, got %+v", content)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultHealthPath is served by the watchdog when a function is ready
+const DefaultHealthPath = "/_/health"
+
+// FunctionReady probes the health path of a function via the gateway and
+// reports whether it answered with a 2xx status
+func FunctionReady(gateway string, functionName string, healthPath string) (bool, error) {
+	gateway = strings.TrimRight(gateway, "/")
+
+	if len(healthPath) == 0 {
+		healthPath = DefaultHealthPath
+	}
+	if !strings.HasPrefix(healthPath, "/") {
+		healthPath = "/" + healthPath
+	}
+
+	timeout := 5 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	req, err := http.NewRequest(http.MethodGet, gateway+"/function/"+functionName+healthPath, nil)
+	if err != nil {
+		return false, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	SetAuth(req, gateway)
+
+	res, err := client.Do(req)
+	if err != nil {
+		return false, nil
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	return res.StatusCode >= http.StatusOK && res.StatusCode < http.StatusMultipleChoices, nil
+}
@@ -7,14 +7,70 @@ import (
 	"bytes"
 
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/openfaas/faas-cli/stack"
 )
 
+// InvokeTransport wraps the transport of the client used for invocations when
+// set, i.e. to record them with HARRecorder.Wrap
+var InvokeTransport func(http.RoundTripper) http.RoundTripper
+
+// InvokeHeaders are added to each invocation, i.e. to propagate a trace context
+var InvokeHeaders map[string]string
+
+// InvokeOptions control the HTTP request of an invocation beyond its body
+type InvokeOptions struct {
+	// Method defaults to POST
+	Method string
+
+	// Headers are set on the request after the content type and the
+	// InvokeHeaders, so they take precedence
+	Headers http.Header
+
+	// Async queues the invocation through /async-function/
+	Async bool
+
+	// AnyStatus gives the body of a response of any status rather than an
+	// error, for callers which check the status given to OnResponse
+	AnyStatus bool
+
+	// OnResponse is given the response before its body is read, i.e. to print
+	// its status line and headers
+	OnResponse func(res *http.Response)
+}
+
 // InvokeFunction a function
 func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType string, query []string) (*[]byte, error) {
+	return InvokeFunctionWithAuth(gateway, name, bytesIn, contentType, query, nil)
+}
+
+// InvokeFunctionWithAuth invokes a function which is behind its own
+// authentication, basic and bearer auth are sent instead of the gateway's
+// credentials
+func InvokeFunctionWithAuth(gateway string, name string, bytesIn *[]byte, contentType string, query []string, auth *stack.FunctionAuth) (*[]byte, error) {
+	return invokeFunction(gateway, name, bytesIn, contentType, query, auth, InvokeOptions{}, nil)
+}
+
+// InvokeFunctionWithOptions invokes a function with control over the method,
+// headers and path of the request. The response is copied to out while it is
+// being received when out is set, otherwise it is returned.
+func InvokeFunctionWithOptions(gateway string, name string, bytesIn *[]byte, contentType string, query []string, auth *stack.FunctionAuth, options InvokeOptions, out io.Writer) (*[]byte, error) {
+	return invokeFunction(gateway, name, bytesIn, contentType, query, auth, options, out)
+}
+
+// InvokeFunctionStream invokes a function and copies its response to out
+// while it is being received, for functions which stream their output
+func InvokeFunctionStream(gateway string, name string, bytesIn *[]byte, contentType string, query []string, auth *stack.FunctionAuth, out io.Writer) error {
+	_, err := invokeFunction(gateway, name, bytesIn, contentType, query, auth, InvokeOptions{}, out)
+	return err
+}
+
+func invokeFunction(gateway string, name string, bytesIn *[]byte, contentType string, query []string, auth *stack.FunctionAuth, options InvokeOptions, out io.Writer) (*[]byte, error) {
 	var resBytes []byte
 
 	gateway = strings.TrimRight(gateway, "/")
@@ -23,15 +79,27 @@ func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType st
 
 	var timeout *time.Duration
 	client := MakeHTTPClient(timeout)
+	if InvokeTransport != nil {
+		client.Transport = InvokeTransport(client.Transport)
+	}
 
 	qs, qsErr := buildQueryString(query)
 	if qsErr != nil {
 		return nil, qsErr
 	}
 
-	gatewayURL := gateway + "/function/" + name + qs
+	route := "/function/"
+	if options.Async {
+		route = "/async-function/"
+	}
+	gatewayURL := gateway + route + name + qs
+
+	method := http.MethodPost
+	if len(options.Method) > 0 {
+		method = strings.ToUpper(options.Method)
+	}
 
-	req, err := http.NewRequest(http.MethodPost, gatewayURL, reader)
+	req, err := http.NewRequest(method, gatewayURL, reader)
 	if err != nil {
 		fmt.Println()
 		fmt.Println(err)
@@ -39,9 +107,20 @@ func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType st
 	}
 
 	req.Header.Add("Content-Type", contentType)
-	SetAuth(req, gateway)
+	for name, value := range InvokeHeaders {
+		req.Header.Set(name, value)
+	}
+	for name, values := range options.Headers {
+		req.Header[http.CanonicalHeaderKey(name)] = values
+	}
+	if !usesAuthorization(auth) {
+		SetAuth(req, gateway)
+	}
+	if err := applyFunctionAuth(req, auth, *bytesIn); err != nil {
+		return nil, err
+	}
 
-	res, err := client.Do(req)
+	res, err := doLimited(client, req, gateway)
 
 	if err != nil {
 		fmt.Println()
@@ -52,15 +131,28 @@ func InvokeFunction(gateway string, name string, bytesIn *[]byte, contentType st
 	if res.Body != nil {
 		defer res.Body.Close()
 	}
+	if options.OnResponse != nil {
+		options.OnResponse(res)
+	}
+
+	switch {
+	case res.StatusCode == http.StatusOK || (options.Async && res.StatusCode == http.StatusAccepted) || options.AnyStatus:
+		if out != nil {
+			if _, copyErr := io.Copy(out, res.Body); copyErr != nil {
+				return nil, fmt.Errorf("cannot read result from OpenFaaS on URL: %s %s", gateway, copyErr)
+			}
+			return nil, nil
+		}
 
-	switch res.StatusCode {
-	case http.StatusOK:
 		var readErr error
 		resBytes, readErr = ioutil.ReadAll(res.Body)
 		if readErr != nil {
 			return nil, fmt.Errorf("cannot read result from OpenFaaS on URL: %s %s", gateway, readErr)
 		}
-	case http.StatusUnauthorized:
+	case res.StatusCode == http.StatusUnauthorized:
+		if auth != nil && auth.Type != stack.AuthNone {
+			return nil, fmt.Errorf("unauthorized access, check the %s credentials for function %s", auth.Type, name)
+		}
 		return nil, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
 	default:
 		bytesOut, err := ioutil.ReadAll(res.Body)
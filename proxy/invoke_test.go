@@ -4,8 +4,10 @@
 package proxy
 
 import (
+	"bytes"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 
 	"testing"
 
@@ -76,3 +78,42 @@ func Test_InvokeFunction_MissingURLPrefix(t *testing.T) {
 		t.Fatalf("Want: %s\nGot: %s", expectedErrMsg, err.Error())
 	}
 }
+
+func Test_InvokeFunctionStream(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("line 1\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("line 2\n"))
+	}))
+	defer s.Close()
+
+	var out bytes.Buffer
+	bytesIn := []byte("")
+	if err := InvokeFunctionStream(s.URL, "function", &bytesIn, "text/plain", nil, nil, &out); err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+
+	if out.String() != "line 1\nline 2\n" {
+		t.Fatalf("want both lines, got %q", out.String())
+	}
+}
+
+func Test_InvokeFunction_InvokeHeaders(t *testing.T) {
+	var traceparent string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer s.Close()
+
+	InvokeHeaders = map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}
+	defer func() { InvokeHeaders = nil }()
+
+	bytesIn := []byte("test data")
+	if _, err := InvokeFunction(s.URL, "function", &bytesIn, "text/plain", []string{}); err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if traceparent != InvokeHeaders["traceparent"] {
+		t.Errorf("want the traceparent header sent, got %q", traceparent)
+	}
+}
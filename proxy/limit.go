@@ -0,0 +1,204 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+// DefaultRateLimit applies to gateways without an entry in the rate_limits
+// of the config file, and fills in the values an entry leaves out
+var DefaultRateLimit = config.RateLimitConfig{
+	Concurrency:       8,
+	RequestsPerSecond: 20,
+	MaxRetries:        4,
+}
+
+// Bounds of the extra spacing added between requests once a gateway answers
+// with 429 or 503
+const (
+	minBackoff = 250 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// limiterSleep waits before a request is sent, it is swapped out in tests
+var limiterSleep = time.Sleep
+
+var (
+	limiters     = map[string]*gatewayLimiter{}
+	limitersLock sync.Mutex
+)
+
+// gatewayLimiter paces the requests sent to one gateway, which may be sent
+// from many goroutines during bulk operations
+type gatewayLimiter struct {
+	slots      chan struct{}
+	maxRetries int
+
+	lock     sync.Mutex
+	interval time.Duration
+	next     time.Time
+
+	// backoff is added to the interval after the gateway pushes back and
+	// halves with each request it accepts
+	backoff time.Duration
+}
+
+func newGatewayLimiter(limit config.RateLimitConfig) *gatewayLimiter {
+	if limit.Concurrency <= 0 {
+		limit.Concurrency = DefaultRateLimit.Concurrency
+	}
+	if limit.RequestsPerSecond <= 0 {
+		limit.RequestsPerSecond = DefaultRateLimit.RequestsPerSecond
+	}
+	if limit.MaxRetries < 0 {
+		limit.MaxRetries = 0
+	} else if limit.MaxRetries == 0 {
+		limit.MaxRetries = DefaultRateLimit.MaxRetries
+	}
+
+	return &gatewayLimiter{
+		slots:      make(chan struct{}, limit.Concurrency),
+		maxRetries: limit.MaxRetries,
+		interval:   time.Duration(float64(time.Second) / limit.RequestsPerSecond),
+	}
+}
+
+// limiterFor gives the limiter of a gateway, read from the config file the
+// first time the gateway is used
+func limiterFor(gateway string) *gatewayLimiter {
+	gateway = strings.TrimRight(gateway, "/")
+
+	limitersLock.Lock()
+	defer limitersLock.Unlock()
+
+	limiter, ok := limiters[gateway]
+	if !ok {
+		limit, _ := config.LookupRateLimit(gateway)
+		limiter = newGatewayLimiter(limit)
+		limiters[gateway] = limiter
+	}
+	return limiter
+}
+
+// acquire waits for a free slot and for the request's turn
+func (l *gatewayLimiter) acquire() {
+	l.slots <- struct{}{}
+
+	l.lock.Lock()
+	now := time.Now()
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.interval + l.backoff)
+	l.lock.Unlock()
+
+	if wait := start.Sub(now); wait > 0 {
+		limiterSleep(wait)
+	}
+}
+
+func (l *gatewayLimiter) release() {
+	<-l.slots
+}
+
+// throttled doubles the backoff after the gateway pushed back and gives how
+// long to wait before the request is sent again
+func (l *gatewayLimiter) throttled(retryAfter time.Duration) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.backoff *= 2
+	if l.backoff < minBackoff {
+		l.backoff = minBackoff
+	} else if l.backoff > maxBackoff {
+		l.backoff = maxBackoff
+	}
+
+	if retryAfter > l.backoff {
+		return retryAfter
+	}
+	return l.backoff
+}
+
+// accepted halves the backoff once the gateway keeps up again
+func (l *gatewayLimiter) accepted() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.backoff /= 2
+	if l.backoff < minBackoff/2 {
+		l.backoff = 0
+	}
+}
+
+// doLimited sends a request within the gateway's rate limit, backing off and
+// sending it again while the gateway answers 429 or 503. Only calls to the
+// gateway's /system/ API are retried, a function gives 429 and 503 itself and
+// invoking it again may repeat its side effects. Requests whose body can't be
+// read again are not retried.
+func doLimited(client http.Client, req *http.Request, gateway string) (*http.Response, error) {
+	limiter := limiterFor(gateway)
+	retry := isSystemRequest(req, gateway)
+
+	for attempt := 0; ; attempt++ {
+		limiter.acquire()
+		res, err := client.Do(req)
+		limiter.release()
+
+		if err != nil {
+			return res, err
+		}
+		if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+			limiter.accepted()
+			return res, nil
+		}
+
+		delay := limiter.throttled(retryAfter(res))
+		if !retry || attempt >= limiter.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return res, nil
+		}
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return res, nil
+			}
+			req.Body = body
+		}
+		res.Body.Close()
+
+		limiterSleep(delay)
+	}
+}
+
+// isSystemRequest is true for the gateway's API, under /system/ after the
+// gateway's URL, which may have a path of its own
+func isSystemRequest(req *http.Request, gateway string) bool {
+	path := strings.TrimPrefix(req.URL.String(), strings.TrimRight(gateway, "/"))
+	return strings.HasPrefix(path, "/system/")
+}
+
+// retryAfter reads the Retry-After header given as seconds or a date
+func retryAfter(res *http.Response) time.Duration {
+	value := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if len(value) == 0 {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+// stubLimiterSleep records the waits of the limiters instead of sleeping
+func stubLimiterSleep() (func() []time.Duration, func()) {
+	var lock sync.Mutex
+	var waits []time.Duration
+	limiterSleep = func(d time.Duration) {
+		lock.Lock()
+		defer lock.Unlock()
+		waits = append(waits, d)
+	}
+	return func() []time.Duration {
+			lock.Lock()
+			defer lock.Unlock()
+			return waits
+		}, func() {
+			limiterSleep = time.Sleep
+		}
+}
+
+func Test_doLimited_RetriesAfterBackoff(t *testing.T) {
+	waits, undo := stubLimiterSleep()
+	defer undo()
+
+	var bodies []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		switch len(bodies) {
+		case 1:
+			w.Header().Set("Retry-After", "2")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer s.Close()
+
+	limitersLock.Lock()
+	limiters[s.URL] = newGatewayLimiter(config.RateLimitConfig{RequestsPerSecond: 1000})
+	limitersLock.Unlock()
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/system/functions", bytes.NewReader([]byte("spec")))
+	res, err := doLimited(http.Client{}, req, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("want the third attempt to succeed, got %d", res.StatusCode)
+	}
+	if strings.Join(bodies, ",") != "spec,spec,spec" {
+		t.Errorf("want the body sent each time, got %v", bodies)
+	}
+
+	var backoffs []time.Duration
+	for _, wait := range waits() {
+		if wait >= minBackoff {
+			backoffs = append(backoffs, wait)
+		}
+	}
+	if len(backoffs) < 2 || backoffs[0] != 2*time.Second || backoffs[1] != 2*minBackoff {
+		t.Errorf("want Retry-After then a doubled backoff, got %v", backoffs)
+	}
+}
+
+func Test_doLimited_GivesUpAfterMaxRetries(t *testing.T) {
+	_, undo := stubLimiterSleep()
+	defer undo()
+
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	limitersLock.Lock()
+	limiters[s.URL] = newGatewayLimiter(config.RateLimitConfig{MaxRetries: 2})
+	limitersLock.Unlock()
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/system/functions", nil)
+	res, err := doLimited(http.Client{}, req, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusServiceUnavailable || requests != 3 {
+		t.Errorf("want the 503 returned after 3 requests, got %d after %d", res.StatusCode, requests)
+	}
+}
+
+func Test_doLimited_DoesNotRetryInvocations(t *testing.T) {
+	_, undo := stubLimiterSleep()
+	defer undo()
+
+	requests := 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer s.Close()
+
+	limitersLock.Lock()
+	limiters[s.URL] = newGatewayLimiter(config.RateLimitConfig{MaxRetries: 2})
+	limitersLock.Unlock()
+
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/function/charge-card", bytes.NewReader([]byte("order")))
+	res, err := doLimited(http.Client{}, req, s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusServiceUnavailable || requests != 1 {
+		t.Errorf("want the 503 returned after 1 request, got %d after %d", res.StatusCode, requests)
+	}
+}
+
+func Test_isSystemRequest(t *testing.T) {
+	cases := []struct {
+		url     string
+		gateway string
+		want    bool
+	}{
+		{"http://127.0.0.1:8080/system/functions", "http://127.0.0.1:8080", true},
+		{"http://127.0.0.1:8080/system/functions", "http://127.0.0.1:8080/", true},
+		{"https://example.com/openfaas/system/secrets", "https://example.com/openfaas", true},
+		{"http://127.0.0.1:8080/function/system", "http://127.0.0.1:8080", false},
+		{"http://127.0.0.1:8080/async-function/env", "http://127.0.0.1:8080", false},
+	}
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodGet, c.url, nil)
+		if got := isSystemRequest(req, c.gateway); got != c.want {
+			t.Errorf("%s of %s: want %t, got %t", c.url, c.gateway, c.want, got)
+		}
+	}
+}
+
+func Test_doLimited_BoundsConcurrency(t *testing.T) {
+	_, undo := stubLimiterSleep()
+	defer undo()
+
+	var lock sync.Mutex
+	inFlight, most := 0, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		inFlight++
+		if inFlight > most {
+			most = inFlight
+		}
+		lock.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		lock.Lock()
+		inFlight--
+		lock.Unlock()
+	}))
+	defer s.Close()
+
+	limitersLock.Lock()
+	limiters[s.URL] = newGatewayLimiter(config.RateLimitConfig{Concurrency: 2, RequestsPerSecond: 1000})
+	limitersLock.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/system/functions", nil)
+			if res, err := doLimited(http.Client{}, req, s.URL); err == nil {
+				res.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if most != 2 {
+		t.Errorf("want at most 2 requests in flight, got %d", most)
+	}
+}
+
+func Test_gatewayLimiter_BackoffRecovers(t *testing.T) {
+	limiter := newGatewayLimiter(config.RateLimitConfig{})
+	if limiter.interval != 50*time.Millisecond || limiter.maxRetries != DefaultRateLimit.MaxRetries || cap(limiter.slots) != DefaultRateLimit.Concurrency {
+		t.Fatalf("want the defaults, got %+v", limiter)
+	}
+
+	limiter.throttled(0)
+	limiter.throttled(0)
+	if limiter.backoff != 2*minBackoff {
+		t.Errorf("want the backoff doubled, got %s", limiter.backoff)
+	}
+
+	limiter.accepted()
+	limiter.accepted()
+	if limiter.backoff != minBackoff/2 {
+		t.Errorf("want the backoff halved twice, got %s", limiter.backoff)
+	}
+	limiter.accepted()
+	if limiter.backoff != 0 {
+		t.Errorf("want the backoff cleared, got %s", limiter.backoff)
+	}
+}
@@ -12,27 +12,109 @@ import (
 	"strings"
 	"time"
 
+	"github.com/openfaas/faas-cli/stack"
 	"github.com/openfaas/faas/gateway/requests"
 )
 
 // ListFunctions list deployed functions
 func ListFunctions(gateway string) ([]requests.Function, error) {
+	return ListFunctionsInNamespace(gateway, "")
+}
+
+// ListFunctionsInNamespace lists the functions deployed to a namespace, an
+// empty namespace is the provider's default
+func ListFunctionsInNamespace(gateway string, namespace string) ([]requests.Function, error) {
 	var results []requests.Function
 
+	if err := getFunctionListInNamespace(gateway, namespace, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// functionAnnotations is the part of a listed function holding its annotations
+type functionAnnotations struct {
+	Name        string            `json:"name"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+// ListFunctionAnnotations gives the annotations of each deployed function by name
+func ListFunctionAnnotations(gateway string) (map[string]map[string]string, error) {
+	return ListFunctionAnnotationsInNamespace(gateway, "")
+}
+
+// ListFunctionAnnotationsInNamespace gives the annotations of each function
+// deployed to a namespace by name
+func ListFunctionAnnotationsInNamespace(gateway string, namespace string) (map[string]map[string]string, error) {
+	var results []functionAnnotations
+
+	if err := getFunctionListInNamespace(gateway, namespace, &results); err != nil {
+		return nil, err
+	}
+
+	annotations := make(map[string]map[string]string, len(results))
+	for _, result := range results {
+		annotations[result.Name] = result.Annotations
+	}
+	return annotations, nil
+}
+
+// FunctionStatus is the full spec of a deployed function as reported by
+// providers which return more than the gateway's requests.Function
+type FunctionStatus struct {
+	Name        string                   `json:"name"`
+	Namespace   string                   `json:"namespace,omitempty"`
+	Image       string                   `json:"image"`
+	Replicas    uint64                   `json:"replicas,omitempty"`
+	EnvProcess  string                   `json:"envProcess"`
+	EnvVars     map[string]string        `json:"envVars"`
+	Constraints []string                 `json:"constraints"`
+	Secrets     []string                 `json:"secrets"`
+	Labels      map[string]string        `json:"labels"`
+	Annotations map[string]string        `json:"annotations"`
+	Limits      *stack.FunctionResources `json:"limits"`
+	Requests    *stack.FunctionResources `json:"requests"`
+
+	// InvocationCount, AvailableReplicas and CreatedAt are reported by the
+	// provider and are not part of the spec
+	InvocationCount   float64    `json:"invocationCount,omitempty"`
+	AvailableReplicas uint64     `json:"availableReplicas,omitempty"`
+	CreatedAt         *time.Time `json:"createdAt,omitempty"`
+}
+
+// ListFunctionStatus lists the spec of each deployed function
+func ListFunctionStatus(gateway string) ([]FunctionStatus, error) {
+	return ListFunctionStatusInNamespace(gateway, "")
+}
+
+// ListFunctionStatusInNamespace lists the spec of each function deployed to
+// a namespace, an empty namespace is the provider's default
+func ListFunctionStatusInNamespace(gateway string, namespace string) ([]FunctionStatus, error) {
+	var results []FunctionStatus
+
+	if err := getFunctionListInNamespace(gateway, namespace, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// getFunctionListInNamespace reads the functions deployed to a namespace into
+// results
+func getFunctionListInNamespace(gateway string, namespace string, results interface{}) error {
 	gateway = strings.TrimRight(gateway, "/")
 
 	timeout := 60 * time.Second
 	client := MakeHTTPClient(&timeout)
 
-	getRequest, err := http.NewRequest(http.MethodGet, gateway+"/system/functions", nil)
+	getRequest, err := http.NewRequest(http.MethodGet, gateway+"/system/functions"+namespaceQuery(namespace), nil)
 	SetAuth(getRequest, gateway)
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
 	}
 
-	res, err := client.Do(getRequest)
+	res, err := doWithAuth(client, getRequest, gateway)
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
 	}
 
 	if res.Body != nil {
@@ -44,19 +126,65 @@ func ListFunctions(gateway string) ([]requests.Function, error) {
 
 		bytesOut, err := ioutil.ReadAll(res.Body)
 		if err != nil {
-			return nil, fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
+			return fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
 		}
-		jsonErr := json.Unmarshal(bytesOut, &results)
+		jsonErr := json.Unmarshal(bytesOut, results)
 		if jsonErr != nil {
-			return nil, fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, jsonErr.Error())
+			return fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, jsonErr.Error())
 		}
 	case http.StatusUnauthorized:
-		return nil, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+		return fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
 	default:
 		bytesOut, err := ioutil.ReadAll(res.Body)
 		if err == nil {
-			return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+			return fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
 		}
 	}
-	return results, nil
+	return nil
+}
+
+// GetFunctionInfo reads the spec of one deployed function, found is false
+// when the gateway does not know it
+func GetFunctionInfo(gateway string, functionName string) (status FunctionStatus, found bool, err error) {
+	return GetFunctionInfoInNamespace(gateway, functionName, "")
+}
+
+// GetFunctionInfoInNamespace reads the spec of a function deployed to a
+// namespace, an empty namespace is the provider's default
+func GetFunctionInfoInNamespace(gateway string, functionName string, namespace string) (status FunctionStatus, found bool, err error) {
+	gateway = strings.TrimRight(gateway, "/")
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	getRequest, err := http.NewRequest(http.MethodGet, gateway+"/system/function/"+functionName+namespaceQuery(namespace), nil)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	SetAuth(getRequest, gateway)
+
+	res, err := doWithAuth(client, getRequest, gateway)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	defer res.Body.Close()
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		if err := json.Unmarshal(bytesOut, &status); err != nil {
+			return status, false, fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, err.Error())
+		}
+		return status, true, nil
+	case http.StatusNotFound:
+		return status, false, nil
+	case http.StatusUnauthorized:
+		return status, false, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		return status, false, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
 }
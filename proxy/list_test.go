@@ -35,6 +35,26 @@ func Test_ListFunctions(t *testing.T) {
 	}
 }
 
+func Test_ListFunctionsInNamespace(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/functions?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       expectedListFunctionsResponse,
+		},
+	})
+	defer s.Close()
+
+	result, err := ListFunctionsInNamespace(s.URL, "staging")
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if len(result) != len(expectedListFunctionsResponse) {
+		t.Fatalf("Want %d functions, got: %#v", len(expectedListFunctionsResponse), result)
+	}
+}
+
 func Test_ListFunctions_Not200(t *testing.T) {
 	s := test.MockHttpServerStatus(t, http.StatusBadRequest)
 
@@ -80,3 +100,55 @@ var expectedListFunctionsResponse = []requests.Function{
 		EnvProcess:      "env-process test2",
 	},
 }
+
+func Test_ListFunctionAnnotations(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: []map[string]interface{}{
+				{"name": "func-test1", "annotations": map[string]string{"openfaas.com/protect": "true"}},
+				{"name": "func-test2"},
+			},
+		},
+	})
+	defer s.Close()
+
+	result, err := ListFunctionAnnotations(s.URL)
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+
+	if got := result["func-test1"]["openfaas.com/protect"]; got != "true" {
+		t.Fatalf("Expected annotation for func-test1, got %q", got)
+	}
+	if _, ok := result["func-test2"]; !ok || len(result["func-test2"]) != 0 {
+		t.Fatalf("Expected func-test2 without annotations, got %v", result)
+	}
+}
+
+func Test_GetFunctionInfo(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/figlet",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       map[string]interface{}{"name": "figlet", "image": "functions/figlet:0.9"},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/function/missing",
+			ResponseStatusCode: http.StatusNotFound,
+		},
+	})
+	defer s.Close()
+
+	status, found, err := GetFunctionInfo(s.URL, "figlet")
+	if err != nil || !found || status.Image != "functions/figlet:0.9" {
+		t.Fatalf("got %+v, %v, %v", status, found, err)
+	}
+
+	_, found, err = GetFunctionInfo(s.URL, "missing")
+	if err != nil || found {
+		t.Fatalf("want a missing function to not be found, got %v, %v", found, err)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLogReconnects is how many times in a row a followed log stream is
+	// reopened without reading a line before giving up
+	maxLogReconnects = 5
+
+	// maxLogReconnectWait caps the wait between reconnects, which doubles
+	maxLogReconnectWait = 30 * time.Second
+)
+
+// logReconnectWait waits before a dropped log stream is reopened, it is
+// swapped out in tests
+var logReconnectWait = time.Sleep
+
+// LogMessage is a line written by a function, as returned by providers which
+// serve /system/logs
+type LogMessage struct {
+	Name      string    `json:"name"`
+	Instance  string    `json:"instance"`
+	Timestamp time.Time `json:"timestamp"`
+	Text      string    `json:"text"`
+}
+
+// LogRequest selects the lines read from /system/logs
+type LogRequest struct {
+	Name string
+
+	// Namespace of the function, the provider's default when empty
+	Namespace string
+
+	// Instance only reads the lines of one replica when set
+	Instance string
+
+	// Since skips the lines logged before it when set
+	Since time.Time
+
+	// Tail is how many of the most recent lines to read, all of them when
+	// it is negative
+	Tail int
+
+	// Follow keeps the stream open for lines as they are logged
+	Follow bool
+}
+
+func (r LogRequest) query() url.Values {
+	query := url.Values{}
+	query.Set("name", r.Name)
+	query.Set("follow", strconv.FormatBool(r.Follow))
+	if r.Tail >= 0 {
+		query.Set("tail", strconv.Itoa(r.Tail))
+	}
+	if len(r.Namespace) > 0 {
+		query.Set("namespace", r.Namespace)
+	}
+	if len(r.Instance) > 0 {
+		query.Set("instance", r.Instance)
+	}
+	if !r.Since.IsZero() {
+		query.Set("since", r.Since.UTC().Format(time.RFC3339Nano))
+	}
+	return query
+}
+
+// logStreamDropped is a log stream which could not be opened or was cut
+// off, a followed stream is reopened after it
+type logStreamDropped struct {
+	message string
+}
+
+func (e logStreamDropped) Error() string {
+	return e.message
+}
+
+// GetLogs reads the most recent lines logged by a function without following them
+func GetLogs(gateway string, functionName string, tail int) ([]LogMessage, error) {
+	return GetLogsInNamespace(gateway, functionName, "", tail)
+}
+
+// GetLogsInNamespace reads the most recent lines logged by a function in a
+// namespace, an empty namespace is the provider's default
+func GetLogsInNamespace(gateway string, functionName string, namespace string, tail int) ([]LogMessage, error) {
+	var messages []LogMessage
+	_, err := readLogs(strings.TrimRight(gateway, "/"), LogRequest{Name: functionName, Namespace: namespace, Tail: tail}, func(message LogMessage) {
+		messages = append(messages, message)
+	})
+	return messages, err
+}
+
+// StreamLogs passes each line logged by a function to handle as it is read.
+// A followed stream which the gateway drops is reopened from the time of the
+// last line read, so lines are neither lost nor repeated, until it has been
+// reopened maxLogReconnects times in a row without reading a line.
+func StreamLogs(gateway string, request LogRequest, handle func(LogMessage)) error {
+	gateway = strings.TrimRight(gateway, "/")
+
+	// last is the latest time read and seenAtLast the lines read at it, a
+	// reopened stream starts from resumed and skips the lines already read
+	var last, resumed time.Time
+	seenAtLast, seenAtResumed := map[string]bool{}, map[string]bool{}
+	deduplicate := func(message LogMessage) {
+		key := message.Instance + "\x00" + message.Text
+		if !resumed.IsZero() && (message.Timestamp.Before(resumed) || message.Timestamp.Equal(resumed) && seenAtResumed[key]) {
+			return
+		}
+		if message.Timestamp.After(last) {
+			last = message.Timestamp
+			seenAtLast = map[string]bool{}
+		}
+		if message.Timestamp.Equal(last) {
+			seenAtLast[key] = true
+		}
+		handle(message)
+	}
+
+	failures := 0
+	for {
+		received, err := readLogs(gateway, request, deduplicate)
+		if _, dropped := err.(logStreamDropped); !request.Follow || (err != nil && !dropped) {
+			return err
+		}
+		if err == nil {
+			err = fmt.Errorf("the gateway closed the stream")
+		}
+
+		if received > 0 {
+			failures = 0
+		}
+		failures++
+		if failures > maxLogReconnects {
+			return fmt.Errorf("unable to follow the logs of %s after %d attempts: %s", request.Name, maxLogReconnects, err)
+		}
+
+		wait := time.Second << uint(failures-1)
+		if wait > maxLogReconnectWait {
+			wait = maxLogReconnectWait
+		}
+		fmt.Fprintf(os.Stderr, "Reconnecting to the logs of %s in %s: %s\n", request.Name, wait, err)
+		logReconnectWait(wait)
+
+		if !last.IsZero() {
+			resumed, seenAtResumed = last, seenAtLast
+			request.Since = last
+			request.Tail = -1
+		}
+	}
+}
+
+// readLogs reads one stream of lines and gives how many were read
+func readLogs(gateway string, request LogRequest, handle func(LogMessage)) (int, error) {
+	// a followed stream stays open for as long as the function logs, so it
+	// has no timeout
+	timeout := time.Duration(0)
+	if !request.Follow {
+		timeout = 30 * time.Second
+	}
+	client := MakeHTTPClient(&timeout)
+
+	getRequest, err := http.NewRequest(http.MethodGet, gateway+"/system/logs?"+request.query().Encode(), nil)
+	if err != nil {
+		return 0, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	SetAuth(getRequest, gateway)
+
+	res, err := doWithAuth(client, getRequest, gateway)
+	if err != nil {
+		return 0, logStreamDropped{fmt.Sprintf("cannot connect to OpenFaaS on URL: %s", gateway)}
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return 0, fmt.Errorf("the gateway at %s does not provide function logs", gateway)
+	case http.StatusUnauthorized:
+		return 0, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return 0, logStreamDropped{fmt.Sprintf("server returned unexpected status code: %d", res.StatusCode)}
+	default:
+		bytesOut, _ := ioutil.ReadAll(res.Body)
+		return 0, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+
+	// Messages are streamed as one JSON object per line
+	received := 0
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 {
+			continue
+		}
+		var message LogMessage
+		if err := json.Unmarshal([]byte(line), &message); err != nil {
+			return received, fmt.Errorf("cannot parse the logs of %s: %s", request.Name, err)
+		}
+		received++
+		handle(message)
+	}
+	if err := scanner.Err(); err != nil {
+		return received, logStreamDropped{fmt.Sprintf("the log stream was cut off: %s", err)}
+	}
+	return received, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_GetLogs(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/logs?follow=false&name=figlet&tail=20",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       LogMessage{Name: "figlet", Instance: "figlet-1", Text: "Forked fprocess"},
+		},
+	})
+	defer s.Close()
+
+	messages, err := GetLogs(s.URL, "figlet", 20)
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if len(messages) != 1 || messages[0].Text != "Forked fprocess" || messages[0].Instance != "figlet-1" {
+		t.Fatalf("got messages %+v", messages)
+	}
+}
+
+func Test_StreamLogs_Namespace(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/logs?follow=false&name=figlet&namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       LogMessage{Name: "figlet", Text: "Forked fprocess"},
+		},
+	})
+	defer s.Close()
+
+	var messages []LogMessage
+	err := StreamLogs(s.URL, LogRequest{Name: "figlet", Namespace: "staging", Tail: -1}, func(message LogMessage) {
+		messages = append(messages, message)
+	})
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("got messages %+v", messages)
+	}
+}
+
+func Test_GetLogs_NotProvided(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusNotFound)
+	defer s.Close()
+
+	_, err := GetLogs(s.URL, "figlet", 20)
+	if err == nil || err.Error() != "the gateway at "+s.URL+" does not provide function logs" {
+		t.Fatalf("want an error about logs, got: %v", err)
+	}
+}
+
+func Test_StreamLogs_ReconnectsFromTheLastLine(t *testing.T) {
+	defer func() { logReconnectWait = time.Sleep }()
+	var waits []time.Duration
+	logReconnectWait = func(d time.Duration) { waits = append(waits, d) }
+
+	first := time.Date(2018, 6, 1, 10, 0, 0, 0, time.UTC)
+	line := func(w http.ResponseWriter, seconds int, text string) {
+		out, _ := json.Marshal(LogMessage{Name: "api", Instance: "api-1", Timestamp: first.Add(time.Duration(seconds) * time.Second), Text: text})
+		w.Write(append(out, '\n'))
+	}
+
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		switch len(queries) {
+		case 1:
+			line(w, 0, "one")
+			line(w, 1, "two")
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		case 3:
+			line(w, 1, "two")
+			line(w, 2, "three")
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer s.Close()
+
+	var texts []string
+	err := StreamLogs(s.URL, LogRequest{Name: "api", Tail: 10, Follow: true}, func(message LogMessage) {
+		texts = append(texts, message.Text)
+	})
+	if err == nil || !strings.HasPrefix(err.Error(), "unauthorized access") {
+		t.Fatalf("want the stream to end on the 401, got %v", err)
+	}
+
+	if strings.Join(texts, ",") != "one,two,three" {
+		t.Errorf("want each line once, got %v", texts)
+	}
+	if queries[0] != "follow=true&name=api&tail=10" || queries[2] != "follow=true&name=api&since=2018-06-01T10%3A00%3A01Z" {
+		t.Errorf("want the stream reopened from the last line, got %v", queries)
+	}
+	if len(waits) != 3 || waits[0] != time.Second || waits[1] != 2*time.Second || waits[2] != time.Second {
+		t.Errorf("want the wait doubled until a line is read, got %v", waits)
+	}
+}
+
+func Test_StreamLogs_GivesUp(t *testing.T) {
+	defer func() { logReconnectWait = time.Sleep }()
+	logReconnectWait = func(time.Duration) {}
+
+	s := test.MockHttpServerStatus(t, http.StatusServiceUnavailable)
+	defer s.Close()
+
+	err := StreamLogs(s.URL, LogRequest{Name: "api", Follow: true}, func(LogMessage) {})
+	if err == nil || !strings.HasPrefix(err.Error(), "unable to follow the logs of api after 5 attempts") {
+		t.Fatalf("want an error after the reconnects, got %v", err)
+	}
+}
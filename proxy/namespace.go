@@ -0,0 +1,65 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ListNamespaces lists the namespaces functions can be deployed to, it is
+// nil when the provider does not support namespaces
+func ListNamespaces(gateway string) ([]string, error) {
+	gateway = strings.TrimRight(gateway, "/")
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(http.MethodGet, gateway+"/system/namespaces", nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		var namespaces []string
+		if err := json.Unmarshal(bytesOut, &namespaces); err != nil {
+			return nil, fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, err.Error())
+		}
+		return namespaces, nil
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
+
+func namespaceQuery(namespace string) string {
+	if len(namespace) == 0 {
+		return ""
+	}
+	return "?namespace=" + url.QueryEscape(namespace)
+}
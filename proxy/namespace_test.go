@@ -0,0 +1,42 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_ListNamespaces(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/namespaces",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []string{"openfaas-fn", "staging"},
+		},
+	})
+	defer s.Close()
+
+	namespaces, err := ListNamespaces(s.URL)
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if !reflect.DeepEqual(namespaces, []string{"openfaas-fn", "staging"}) {
+		t.Fatalf("unexpected namespaces: %v", namespaces)
+	}
+}
+
+func Test_ListNamespaces_NotSupported(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusNotFound)
+	defer s.Close()
+
+	namespaces, err := ListNamespaces(s.URL)
+	if err != nil || namespaces != nil {
+		t.Fatalf("want no namespaces and no error, got: %v %v", namespaces, err)
+	}
+}
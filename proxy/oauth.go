@@ -0,0 +1,193 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+// OAuthEndpoints are read from an OpenID Connect issuer's discovery document
+type OAuthEndpoints struct {
+	AuthorizationURL string `json:"authorization_endpoint"`
+	TokenURL         string `json:"token_endpoint"`
+}
+
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// DiscoverOAuthEndpoints reads the authorization and token endpoints of an
+// OpenID Connect issuer from /.well-known/openid-configuration
+func DiscoverOAuthEndpoints(issuer string) (OAuthEndpoints, error) {
+	issuer = strings.TrimRight(issuer, "/")
+
+	timeout := 30 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	req, err := http.NewRequest(http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return OAuthEndpoints{}, fmt.Errorf("invalid issuer URL: %s", issuer)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return OAuthEndpoints{}, fmt.Errorf("cannot connect to the issuer on URL: %s", issuer)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return OAuthEndpoints{}, fmt.Errorf("cannot read result from the issuer on URL: %s", issuer)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return OAuthEndpoints{}, fmt.Errorf("the issuer returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+
+	var endpoints OAuthEndpoints
+	if err := json.Unmarshal(bytesOut, &endpoints); err != nil {
+		return OAuthEndpoints{}, fmt.Errorf("cannot parse the discovery document of %s\n%s", issuer, err.Error())
+	}
+	if len(endpoints.TokenURL) == 0 {
+		return OAuthEndpoints{}, fmt.Errorf("the discovery document of %s has no token_endpoint", issuer)
+	}
+	return endpoints, nil
+}
+
+// RequestOAuthToken posts a grant to the token endpoint, the token returned
+// only has its access token, refresh token and expiry set
+func RequestOAuthToken(tokenURL string, form url.Values) (config.OAuthToken, error) {
+	timeout := 30 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	req, err := http.NewRequest(http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return config.OAuthToken{}, fmt.Errorf("invalid token URL: %s", tokenURL)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return config.OAuthToken{}, fmt.Errorf("cannot connect to the token endpoint on URL: %s", tokenURL)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return config.OAuthToken{}, fmt.Errorf("cannot read result from the token endpoint on URL: %s", tokenURL)
+	}
+
+	var result tokenResponse
+	jsonErr := json.Unmarshal(bytesOut, &result)
+	if res.StatusCode != http.StatusOK {
+		if jsonErr == nil && len(result.Error) > 0 {
+			return config.OAuthToken{}, fmt.Errorf("the identity provider refused the %s grant: %s %s", form.Get("grant_type"), result.Error, result.ErrorDescription)
+		}
+		return config.OAuthToken{}, fmt.Errorf("the token endpoint returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+	if jsonErr != nil {
+		return config.OAuthToken{}, fmt.Errorf("cannot parse result from the token endpoint on URL: %s\n%s", tokenURL, jsonErr.Error())
+	}
+	if len(result.AccessToken) == 0 {
+		return config.OAuthToken{}, fmt.Errorf("the token endpoint on URL %s returned no access_token", tokenURL)
+	}
+
+	token := config.OAuthToken{
+		AccessToken:  result.AccessToken,
+		RefreshToken: result.RefreshToken,
+	}
+	if result.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// ClientCredentialsForm is the client credentials grant for a token
+func ClientCredentialsForm(token config.OAuthToken) url.Values {
+	form := url.Values{}
+	form.Set("grant_type", config.ClientCredentialsGrant)
+	form.Set("client_id", token.ClientID)
+	form.Set("client_secret", token.ClientSecret)
+	if len(token.Scope) > 0 {
+		form.Set("scope", token.Scope)
+	}
+	if len(token.Audience) > 0 {
+		form.Set("audience", token.Audience)
+	}
+	return form
+}
+
+// RenewOAuthToken gets a new access token for a gateway with the refresh
+// token, or by the client credentials grant again when there is none, and
+// saves it in place of the old one
+func RenewOAuthToken(gateway string, token config.OAuthToken) (*config.OAuthToken, error) {
+	var form url.Values
+	switch {
+	case len(token.RefreshToken) > 0:
+		form = url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("refresh_token", token.RefreshToken)
+		form.Set("client_id", token.ClientID)
+		if len(token.ClientSecret) > 0 {
+			form.Set("client_secret", token.ClientSecret)
+		}
+	case token.Grant == config.ClientCredentialsGrant && len(token.ClientSecret) > 0:
+		form = ClientCredentialsForm(token)
+	default:
+		return nil, fmt.Errorf("the oauth2 token for %s has expired, run \"faas-cli auth login\" to get a new one", gateway)
+	}
+
+	issued, err := RequestOAuthToken(token.TokenURL, form)
+	if err != nil {
+		return nil, err
+	}
+
+	renewed := token
+	renewed.AccessToken = issued.AccessToken
+	renewed.Expiry = issued.Expiry
+	if len(issued.RefreshToken) > 0 {
+		renewed.RefreshToken = issued.RefreshToken
+	}
+
+	if err := config.UpdateOAuthToken(gateway, renewed, ""); err != nil {
+		return nil, err
+	}
+	return &renewed, nil
+}
+
+// setBearerAuth sends the gateway's oauth2 token, renewing it first when it
+// has expired, it is false when the gateway has no token
+func setBearerAuth(req *http.Request, gateway string) bool {
+	token, err := config.LookupOAuthToken(gateway)
+	if err != nil || token == nil {
+		return false
+	}
+
+	if token.Expired(time.Now()) {
+		if renewed, err := RenewOAuthToken(gateway, *token); err == nil {
+			token = renewed
+		}
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	return true
+}
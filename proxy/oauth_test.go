@@ -0,0 +1,148 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/openfaas/faas-cli/config"
+)
+
+// identityProvider issues access-N for the Nth grant, and refuses refresh
+// tokens other than the one it issued last
+func identityProvider(grants *[]url.Values) *httptest.Server {
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{
+				"authorization_endpoint": s.URL + "/authorize",
+				"token_endpoint":         s.URL + "/token",
+			})
+		case "/token":
+			r.ParseForm()
+			*grants = append(*grants, r.PostForm)
+			if r.PostForm.Get("grant_type") == "refresh_token" && r.PostForm.Get("refresh_token") != "refresh-1" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "unknown refresh token"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "access-" + string(rune('0'+len(*grants))),
+				"expires_in":   3600,
+			})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return s
+}
+
+func Test_DiscoverOAuthEndpoints(t *testing.T) {
+	var grants []url.Values
+	s := identityProvider(&grants)
+	defer s.Close()
+
+	endpoints, err := DiscoverOAuthEndpoints(s.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoints.AuthorizationURL != s.URL+"/authorize" || endpoints.TokenURL != s.URL+"/token" {
+		t.Errorf("got %+v", endpoints)
+	}
+}
+
+func Test_SetAuth_RenewsExpiredOAuthToken(t *testing.T) {
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-auth-test")
+	config.DefaultFile = "oauthtest1.yml"
+
+	var grants []url.Values
+	s := identityProvider(&grants)
+	defer s.Close()
+
+	gatewayURL := "http://openfaas.test"
+	err := config.UpdateOAuthToken(gatewayURL, config.OAuthToken{
+		AccessToken:  "expired",
+		RefreshToken: "refresh-1",
+		Expiry:       time.Now().Add(-time.Minute),
+		Grant:        config.AuthorizationCodeGrant,
+		TokenURL:     s.URL + "/token",
+		ClientID:     "faas-cli",
+	}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, gatewayURL, nil)
+	SetAuth(req, gatewayURL)
+	if header := req.Header.Get("Authorization"); header != "Bearer access-1" {
+		t.Errorf("want the renewed token, got %q", header)
+	}
+	if len(grants) != 1 || grants[0].Get("grant_type") != "refresh_token" || grants[0].Get("client_id") != "faas-cli" {
+		t.Errorf("want one refresh_token grant, got %v", grants)
+	}
+
+	// The renewed token is saved, so it is sent as it is
+	req, _ = http.NewRequest(http.MethodGet, gatewayURL, nil)
+	SetAuth(req, gatewayURL)
+	if header := req.Header.Get("Authorization"); header != "Bearer access-1" || len(grants) != 1 {
+		t.Errorf("want the saved token without another grant, got %q after %d grants", header, len(grants))
+	}
+	saved, _ := config.LookupOAuthToken(gatewayURL)
+	if saved == nil || saved.RefreshToken != "refresh-1" || saved.Expired(time.Now()) {
+		t.Errorf("want the refresh token kept and a new expiry, got %+v", saved)
+	}
+}
+
+func Test_RenewOAuthToken_ClientCredentials(t *testing.T) {
+	config.DefaultDir, _ = ioutil.TempDir("", "faas-cli-auth-test")
+	config.DefaultFile = "oauthtest2.yml"
+
+	var grants []url.Values
+	s := identityProvider(&grants)
+	defer s.Close()
+
+	token := config.OAuthToken{
+		AccessToken:  "expired",
+		Grant:        config.ClientCredentialsGrant,
+		TokenURL:     s.URL + "/token",
+		ClientID:     "ci",
+		ClientSecret: "s3cr3t",
+		Audience:     "openfaas",
+	}
+	renewed, err := RenewOAuthToken("http://openfaas.test", token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.AccessToken != "access-1" || renewed.ClientSecret != "s3cr3t" {
+		t.Errorf("got %+v", renewed)
+	}
+	if grant := grants[0]; grant.Get("grant_type") != "client_credentials" || grant.Get("client_secret") != "s3cr3t" || grant.Get("audience") != "openfaas" {
+		t.Errorf("want a client_credentials grant, got %v", grant)
+	}
+
+	token.Grant, token.ClientSecret = config.AuthorizationCodeGrant, ""
+	if _, err := RenewOAuthToken("http://openfaas.test", token); err == nil {
+		t.Errorf("want an error renewing a token without a refresh token")
+	}
+}
+
+func Test_RequestOAuthToken_Refused(t *testing.T) {
+	var grants []url.Values
+	s := identityProvider(&grants)
+	defer s.Close()
+
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"stale"}}
+	_, err := RequestOAuthToken(s.URL+"/token", form)
+	want := "the identity provider refused the refresh_token grant: invalid_grant unknown refresh token"
+	if err == nil || err.Error() != want {
+		t.Errorf("want %q, got %v", want, err)
+	}
+}
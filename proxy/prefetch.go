@@ -0,0 +1,114 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// PrefetchRequest asks the provider to pull an image onto the nodes a function
+// can be scheduled on, before the function is switched over to it
+type PrefetchRequest struct {
+	Image string `json:"image"`
+
+	// Namespace the function is deployed to, the provider's default when empty
+	Namespace string `json:"namespace,omitempty"`
+
+	// Constraints narrow the nodes the image is pulled onto
+	Constraints []string `json:"constraints,omitempty"`
+}
+
+// PrefetchStatus is how far a prefetch has got. /system/prefetch is an
+// extension for providers to implement, neither the gateway nor faas-netes
+// serve it yet, so the CLI falls back to pulling at deploy time without it.
+type PrefetchStatus struct {
+	Image string `json:"image"`
+
+	// Nodes is how many nodes the image is pulled onto and Pulled how many
+	// of them have it
+	Nodes  int `json:"nodes"`
+	Pulled int `json:"pulled"`
+
+	// Error is why the image could not be pulled, the prefetch has failed
+	// when it is set
+	Error string `json:"error,omitempty"`
+}
+
+// Done reports whether every node has pulled the image
+func (s PrefetchStatus) Done() bool {
+	return len(s.Error) == 0 && s.Pulled >= s.Nodes
+}
+
+// StartPrefetch asks the provider to start pulling an image, supported is
+// false when the provider has no prefetch API
+func StartPrefetch(gateway string, request PrefetchRequest) (status PrefetchStatus, supported bool, err error) {
+	body, _ := json.Marshal(request)
+	return prefetch(gateway, http.MethodPost, "/system/prefetch", bytes.NewReader(body))
+}
+
+// GetPrefetchStatus reads how far the prefetch of an image has got
+func GetPrefetchStatus(gateway string, image string, namespace string) (PrefetchStatus, error) {
+	query := url.Values{}
+	query.Set("image", image)
+	if len(namespace) > 0 {
+		query.Set("namespace", namespace)
+	}
+
+	status, supported, err := prefetch(gateway, http.MethodGet, "/system/prefetch?"+query.Encode(), nil)
+	if err == nil && !supported {
+		err = fmt.Errorf("the gateway at %s has no record of the prefetch of %s", strings.TrimRight(gateway, "/"), image)
+	}
+	return status, err
+}
+
+func prefetch(gateway string, method string, path string, body *bytes.Reader) (status PrefetchStatus, supported bool, err error) {
+	gateway = strings.TrimRight(gateway, "/")
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	var request *http.Request
+	if body != nil {
+		request, err = http.NewRequest(method, gateway+path, body)
+	} else {
+		request, err = http.NewRequest(method, gateway+path, nil)
+	}
+	if err != nil {
+		return status, false, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	defer res.Body.Close()
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return status, false, fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		if err := json.Unmarshal(bytesOut, &status); err != nil {
+			return status, true, fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, err.Error())
+		}
+		return status, true, nil
+	case http.StatusNotFound, http.StatusNotImplemented:
+		return status, false, nil
+	case http.StatusUnauthorized:
+		return status, false, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		return status, false, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_StartPrefetch(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/system/prefetch",
+			ResponseStatusCode: http.StatusAccepted,
+			ResponseBody:       PrefetchStatus{Image: "model:2", Nodes: 3, Pulled: 1},
+		},
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/prefetch?image=model%3A2&namespace=ml",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       PrefetchStatus{Image: "model:2", Nodes: 3, Pulled: 3},
+		},
+	})
+	defer s.Close()
+
+	status, supported, err := StartPrefetch(s.URL, PrefetchRequest{Image: "model:2", Namespace: "ml"})
+	if err != nil || !supported {
+		t.Fatalf("want the prefetch started, got supported %t: %v", supported, err)
+	}
+	if status.Done() {
+		t.Errorf("want the prefetch in progress, got %+v", status)
+	}
+
+	status, err = GetPrefetchStatus(s.URL, "model:2", "ml")
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if !status.Done() {
+		t.Errorf("want the prefetch done, got %+v", status)
+	}
+}
+
+func Test_StartPrefetch_NotSupported(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusNotFound)
+	defer s.Close()
+
+	_, supported, err := StartPrefetch(s.URL, PrefetchRequest{Image: "model:2"})
+	if err != nil || supported {
+		t.Fatalf("want the prefetch unsupported without an error, got supported %t: %v", supported, err)
+	}
+}
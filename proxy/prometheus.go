@@ -0,0 +1,84 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Value []interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// QueryPrometheus runs an instant query and returns the first sample value,
+// found is false when the query matched no series.
+func QueryPrometheus(prometheusURL string, query string) (value float64, found bool, err error) {
+	prometheusURL = strings.TrimRight(prometheusURL, "/")
+
+	timeout := 30 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	queryURL := prometheusURL + "/api/v1/query?query=" + url.QueryEscape(query)
+	req, err := http.NewRequest(http.MethodGet, queryURL, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid Prometheus URL: %s", prometheusURL)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, false, fmt.Errorf("cannot connect to Prometheus on URL: %s", prometheusURL)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, false, fmt.Errorf("cannot read result from Prometheus on URL: %s", prometheusURL)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return 0, false, fmt.Errorf("Prometheus returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+
+	var result prometheusResponse
+	if err := json.Unmarshal(bytesOut, &result); err != nil {
+		return 0, false, fmt.Errorf("cannot parse result from Prometheus on URL: %s\n%s", prometheusURL, err.Error())
+	}
+
+	if result.Status != "success" {
+		return 0, false, fmt.Errorf("Prometheus query failed: %s", result.Error)
+	}
+
+	if len(result.Data.Result) == 0 || len(result.Data.Result[0].Value) != 2 {
+		return 0, false, nil
+	}
+
+	sample, ok := result.Data.Result[0].Value[1].(string)
+	if !ok {
+		return 0, false, fmt.Errorf("unexpected sample in Prometheus result")
+	}
+
+	value, err = strconv.ParseFloat(sample, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("unexpected sample in Prometheus result: %s", sample)
+	}
+
+	return value, true, nil
+}
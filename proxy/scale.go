@@ -0,0 +1,64 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type scaleServiceRequest struct {
+	ServiceName string `json:"serviceName"`
+	Namespace   string `json:"namespace,omitempty"`
+	Replicas    uint64 `json:"replicas"`
+}
+
+// ScaleFunction sets the number of replicas of a function
+func ScaleFunction(gateway string, functionName string, replicas uint64) error {
+	return ScaleFunctionInNamespace(gateway, functionName, "", replicas)
+}
+
+// ScaleFunctionInNamespace sets the number of replicas of a function in a
+// namespace, an empty namespace is the provider's default
+func ScaleFunctionInNamespace(gateway string, functionName string, namespace string, replicas uint64) error {
+	gateway = strings.TrimRight(gateway, "/")
+
+	reqBytes, _ := json.Marshal(&scaleServiceRequest{ServiceName: functionName, Namespace: namespace, Replicas: replicas})
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(http.MethodPost, gateway+"/system/scale-function/"+functionName+namespaceQuery(namespace), bytes.NewReader(reqBytes))
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("function %s not found", functionName)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		bytesOut, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
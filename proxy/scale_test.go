@@ -0,0 +1,51 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_ScaleFunction(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/system/scale-function/figlet",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+	})
+	defer s.Close()
+
+	if err := ScaleFunction(s.URL, "figlet", 3); err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+}
+
+func Test_ScaleFunction_NotFound(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusNotFound)
+	defer s.Close()
+
+	err := ScaleFunction(s.URL, "figlet", 3)
+	if err == nil || err.Error() != "function figlet not found" {
+		t.Fatalf("want a not found error, got: %v", err)
+	}
+}
+
+func Test_ScaleFunctionInNamespace(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPost,
+			Uri:                "/system/scale-function/figlet?namespace=staging",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+	})
+	defer s.Close()
+
+	if err := ScaleFunctionInNamespace(s.URL, "figlet", "staging", 3); err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+}
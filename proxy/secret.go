@@ -0,0 +1,178 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Secret is a named secret stored by the provider
+type Secret struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	Value     string `json:"value,omitempty"`
+}
+
+// UpdateSecret replaces the value of an existing secret
+func UpdateSecret(gateway string, secret Secret) error {
+	gateway = strings.TrimRight(gateway, "/")
+
+	reqBytes, _ := json.Marshal(&secret)
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(http.MethodPut, gateway+"/system/secrets", bytes.NewReader(reqBytes))
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("secret %s not found", secret.Name)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		bytesOut, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
+
+// ListSecrets lists the secrets in a namespace, an empty namespace is the
+// provider's default. Providers only give the names of secrets, not their values.
+func ListSecrets(gateway string, namespace string) ([]Secret, error) {
+	gateway = strings.TrimRight(gateway, "/")
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(http.MethodGet, gateway+"/system/secrets"+namespaceQuery(namespace), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		var secrets []Secret
+		if err := json.Unmarshal(bytesOut, &secrets); err != nil {
+			return nil, fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, err.Error())
+		}
+		return secrets, nil
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
+
+// CreateSecret creates a secret, an error is returned when it already exists
+func CreateSecret(gateway string, secret Secret) error {
+	gateway = strings.TrimRight(gateway, "/")
+
+	reqBytes, _ := json.Marshal(&secret)
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(http.MethodPost, gateway+"/system/secrets", bytes.NewReader(reqBytes))
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return nil
+	case http.StatusConflict:
+		return fmt.Errorf("secret %s already exists", secret.Name)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		bytesOut, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
+
+// RemoveSecret deletes a secret, functions which use it keep their mounted
+// value until they are restarted
+func RemoveSecret(gateway string, secret Secret) error {
+	gateway = strings.TrimRight(gateway, "/")
+
+	reqBytes, _ := json.Marshal(&Secret{Name: secret.Name, Namespace: secret.Namespace})
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(http.MethodDelete, gateway+"/system/secrets", bytes.NewReader(reqBytes))
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("secret %s not found", secret.Name)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		bytesOut, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
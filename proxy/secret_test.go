@@ -0,0 +1,81 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_UpdateSecret(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodPut,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+	})
+	defer s.Close()
+
+	if err := UpdateSecret(s.URL, Secret{Name: "db-password", Value: "s3cr3t"}); err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+}
+
+func Test_UpdateSecret_NotFound(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusNotFound)
+	defer s.Close()
+
+	err := UpdateSecret(s.URL, Secret{Name: "db-password", Value: "s3cr3t"})
+	if err == nil || err.Error() != "secret db-password not found" {
+		t.Fatalf("want a not found error, got: %v", err)
+	}
+}
+
+func Test_ListSecrets(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/secrets?namespace=staging",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody:       []Secret{{Name: "db-password"}},
+		},
+	})
+	defer s.Close()
+
+	secrets, err := ListSecrets(s.URL, "staging")
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if len(secrets) != 1 || secrets[0].Name != "db-password" {
+		t.Fatalf("unexpected secrets: %v", secrets)
+	}
+}
+
+func Test_CreateSecret_Conflict(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusConflict)
+	defer s.Close()
+
+	err := CreateSecret(s.URL, Secret{Name: "db-password", Value: "s3cr3t"})
+	if err == nil || err.Error() != "secret db-password already exists" {
+		t.Fatalf("want an already exists error, got: %v", err)
+	}
+}
+
+func Test_RemoveSecret(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodDelete,
+			Uri:                "/system/secrets",
+			ResponseStatusCode: http.StatusAccepted,
+		},
+	})
+	defer s.Close()
+
+	if err := RemoveSecret(s.URL, Secret{Name: "db-password"}); err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+}
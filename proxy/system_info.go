@@ -0,0 +1,81 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SystemInfo is the gateway's /system/info
+type SystemInfo struct {
+	Provider struct {
+		Name          string `json:"provider"`
+		Version       string `json:"version"`
+		Orchestration string `json:"orchestration"`
+	} `json:"provider"`
+
+	Version struct {
+		Release string `json:"release"`
+		SHA     string `json:"sha"`
+	} `json:"version"`
+}
+
+// GetSystemInfo reads the version of the gateway and its provider, it is nil
+// for gateways which do not serve /system/info
+func GetSystemInfo(gateway string) (*SystemInfo, error) {
+	gateway = strings.TrimRight(gateway, "/")
+
+	timeout := 60 * time.Second
+	client := MakeHTTPClient(&timeout)
+
+	request, err := http.NewRequest(http.MethodGet, gateway+"/system/info", nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+	SetAuth(request, gateway)
+
+	res, err := doWithAuth(client, request, gateway)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to OpenFaaS on URL: %s", gateway)
+	}
+
+	if res.Body != nil {
+		defer res.Body.Close()
+	}
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read result from OpenFaaS on URL: %s", gateway)
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		info := &SystemInfo{}
+		if err := json.Unmarshal(bytesOut, info); err != nil {
+			return nil, fmt.Errorf("cannot parse result from OpenFaaS on URL: %s\n%s", gateway, err.Error())
+		}
+		return info, nil
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusUnauthorized:
+		return nil, fmt.Errorf("unauthorized access, run \"faas-cli login\" to setup authentication for this server")
+	default:
+		return nil, fmt.Errorf("server returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+}
+
+// Faasd reports whether the provider is faasd or OpenFaaS Edge, which run
+// functions with containerd on a single host without namespaces or scaling
+func (info *SystemInfo) Faasd() bool {
+	if info == nil {
+		return false
+	}
+	name := strings.ToLower(info.Provider.Name)
+	return strings.HasPrefix(name, "faasd") || strings.Contains(name, "edge") || info.Provider.Orchestration == "containerd"
+}
@@ -0,0 +1,72 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package proxy
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/openfaas/faas-cli/test"
+)
+
+func Test_GetSystemInfo(t *testing.T) {
+	s := test.MockHttpServer(t, []test.Request{
+		{
+			Method:             http.MethodGet,
+			Uri:                "/system/info",
+			ResponseStatusCode: http.StatusOK,
+			ResponseBody: map[string]interface{}{
+				"provider": map[string]string{"provider": "faas-netes", "version": "0.7.5", "orchestration": "kubernetes"},
+				"version":  map[string]string{"release": "0.18.2", "sha": "abc123"},
+			},
+		},
+	})
+	defer s.Close()
+
+	info, err := GetSystemInfo(s.URL)
+	if err != nil {
+		t.Fatalf("Error returned: %s", err)
+	}
+	if info.Version.Release != "0.18.2" || info.Provider.Name != "faas-netes" || info.Provider.Orchestration != "kubernetes" {
+		t.Fatalf("unexpected system info: %+v", info)
+	}
+}
+
+func Test_GetSystemInfo_NotSupported(t *testing.T) {
+	s := test.MockHttpServerStatus(t, http.StatusNotFound)
+	defer s.Close()
+
+	info, err := GetSystemInfo(s.URL)
+	if err != nil || info != nil {
+		t.Fatalf("want no info and no error, got: %v %v", info, err)
+	}
+}
+
+func Test_SystemInfo_Faasd(t *testing.T) {
+	cases := []struct {
+		provider      string
+		orchestration string
+		want          bool
+	}{
+		{"faasd", "containerd", true},
+		{"faasd-ce", "", true},
+		{"openfaas-edge", "", true},
+		{"faas-netes", "kubernetes", false},
+		{"faas-swarm", "swarm", false},
+	}
+
+	for _, c := range cases {
+		info := &SystemInfo{}
+		info.Provider.Name = c.provider
+		info.Provider.Orchestration = c.orchestration
+		if got := info.Faasd(); got != c.want {
+			t.Errorf("%s/%s: want %v, got %v", c.provider, c.orchestration, c.want, got)
+		}
+	}
+
+	var missing *SystemInfo
+	if missing.Faasd() {
+		t.Errorf("want false for a gateway without /system/info")
+	}
+}
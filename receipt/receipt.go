@@ -0,0 +1,146 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package receipt
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SignatureHeader carries the receipt's signature when it is posted
+const SignatureHeader = "X-Receipt-Signature"
+
+const signaturePrefix = "sha256="
+
+// Receipt records who deployed which images and configuration to a gateway
+type Receipt struct {
+	User       string    `json:"user"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"`
+	Gateway    string    `json:"gateway"`
+	CLIVersion string    `json:"cli_version"`
+	CLICommit  string    `json:"cli_commit,omitempty"`
+
+	Functions []Function `json:"functions"`
+
+	// Signature is the hmac-sha256 of the receipt without its signature,
+	// given as sha256=HEX
+	Signature string `json:"signature,omitempty"`
+}
+
+// Function is the outcome of deploying one function
+type Function struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+
+	// Digest of the image's manifest, empty when it could not be read
+	Digest string `json:"digest,omitempty"`
+
+	// ConfigHash is the hash of the function's resolved configuration
+	ConfigHash string `json:"config_hash"`
+
+	StatusCode int  `json:"status_code,omitempty"`
+	Success    bool `json:"success"`
+}
+
+// Sign sets the receipt's signature using key
+func (r *Receipt) Sign(key []byte) error {
+	if len(key) == 0 {
+		return fmt.Errorf("a key is needed to sign the receipt")
+	}
+
+	payload, err := r.payload()
+	if err != nil {
+		return err
+	}
+	r.Signature = signaturePrefix + hex.EncodeToString(sign(key, payload))
+	return nil
+}
+
+// Verify checks a signed receipt read from JSON against key
+func Verify(data []byte, key []byte) (*Receipt, error) {
+	var r Receipt
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("unable to parse receipt: %s", err)
+	}
+
+	if !strings.HasPrefix(r.Signature, signaturePrefix) {
+		return nil, fmt.Errorf("the receipt is not signed")
+	}
+	signature, err := hex.DecodeString(strings.TrimPrefix(r.Signature, signaturePrefix))
+	if err != nil {
+		return nil, fmt.Errorf("the receipt's signature is not valid hex: %s", err)
+	}
+
+	payload, err := r.payload()
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(signature, sign(key, payload)) {
+		return nil, fmt.Errorf("the receipt's signature does not match, it was changed or signed with another key")
+	}
+	return &r, nil
+}
+
+// JSON gives the receipt as indented JSON
+func (r *Receipt) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Write saves the receipt to a file
+func (r *Receipt) Write(path string) error {
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
+
+// Post sends the receipt to a webhook, its signature is also sent in the
+// SignatureHeader
+func (r *Receipt) Post(url string, timeout time.Duration) error {
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, r.Signature)
+
+	client := http.Client{Timeout: timeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d from %s", res.StatusCode, url)
+	}
+	return nil
+}
+
+// payload is what is signed: the receipt without its signature
+func (r Receipt) payload() ([]byte, error) {
+	r.Signature = ""
+	return json.Marshal(r)
+}
+
+func sign(key []byte, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package receipt
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReceipt() *Receipt {
+	return &Receipt{
+		User:       "alex",
+		Started:    time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC),
+		Finished:   time.Date(2018, 6, 1, 12, 1, 0, 0, time.UTC),
+		Gateway:    "https://openfaas.example.com",
+		CLIVersion: "0.7.0",
+		Functions: []Function{
+			{Name: "api", Image: "api:0.1", Digest: "sha256:abc", ConfigHash: "123", StatusCode: 200, Success: true},
+		},
+	}
+}
+
+func Test_SignAndVerify(t *testing.T) {
+	r := testReceipt()
+	if err := r.Sign([]byte("secret")); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.HasPrefix(r.Signature, "sha256=") {
+		t.Fatalf("want a sha256 signature, got: %s", r.Signature)
+	}
+
+	data, _ := r.JSON()
+	verified, err := Verify(data, []byte("secret"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if verified.Functions[0].Digest != "sha256:abc" {
+		t.Errorf("unexpected receipt: %+v", verified)
+	}
+
+	if _, err := Verify(data, []byte("other")); err == nil {
+		t.Errorf("want an error for the wrong key")
+	}
+
+	tampered := strings.Replace(string(data), "sha256:abc", "sha256:def", 1)
+	if _, err := Verify([]byte(tampered), []byte("secret")); err == nil {
+		t.Errorf("want an error for a changed receipt")
+	}
+
+	if err := testReceipt().Sign(nil); err == nil {
+		t.Errorf("want an error without a key")
+	}
+}
+
+func Test_Post(t *testing.T) {
+	var got Receipt
+	var header string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(SignatureHeader)
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer s.Close()
+
+	r := testReceipt()
+	r.Sign([]byte("secret"))
+	if err := r.Post(s.URL, time.Second); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if header != r.Signature || got.Signature != r.Signature || got.Gateway != r.Gateway {
+		t.Errorf("want the signed receipt posted, got %+v with header %q", got, header)
+	}
+}
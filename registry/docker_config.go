@@ -0,0 +1,111 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+// Package registry gets credentials for container registries and writes
+// them to a docker config which docker push can be pointed at
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DockerHubServer is the server docker login saves Docker Hub credentials for
+const DockerHubServer = "https://index.docker.io/v1/"
+
+// Credentials log in to a registry
+type Credentials struct {
+	Server   string
+	Username string
+	Password string
+
+	// Expiry is when a token given as the password stops working, zero when
+	// it is not known
+	Expiry time.Time
+}
+
+// WriteDockerConfig saves the credentials to config.json in dir, keeping the
+// other registries and settings already in it, and returns its path
+func WriteDockerConfig(dir string, credentials Credentials) (string, error) {
+	if len(credentials.Server) == 0 {
+		return "", fmt.Errorf("registry server can't be an empty string")
+	}
+	if len(credentials.Username) == 0 || len(credentials.Password) == 0 {
+		return "", fmt.Errorf("username and password can't be empty strings")
+	}
+
+	dockerConfig, path, err := readDockerConfig(dir)
+	if err != nil {
+		return "", err
+	}
+
+	auths, _ := dockerConfig["auths"].(map[string]interface{})
+	if auths == nil {
+		auths = map[string]interface{}{}
+	}
+	auths[credentials.Server] = map[string]string{
+		"auth": base64.StdEncoding.EncodeToString([]byte(credentials.Username + ":" + credentials.Password)),
+	}
+	dockerConfig["auths"] = auths
+
+	return path, writeDockerConfig(path, dockerConfig)
+}
+
+// WriteCredentialHelper sets the docker-credential-* helper which gives the
+// credentials for server in config.json in dir, i.e. ecr-login, and returns
+// its path
+func WriteCredentialHelper(dir string, server string, helper string) (string, error) {
+	if len(server) == 0 {
+		return "", fmt.Errorf("registry server can't be an empty string")
+	}
+	if len(helper) == 0 {
+		return "", fmt.Errorf("credential helper can't be an empty string")
+	}
+
+	dockerConfig, path, err := readDockerConfig(dir)
+	if err != nil {
+		return "", err
+	}
+
+	credHelpers, _ := dockerConfig["credHelpers"].(map[string]interface{})
+	if credHelpers == nil {
+		credHelpers = map[string]interface{}{}
+	}
+	credHelpers[server] = helper
+	dockerConfig["credHelpers"] = credHelpers
+
+	return path, writeDockerConfig(path, dockerConfig)
+}
+
+// readDockerConfig creates dir and reads the config.json in it, which is
+// empty when it doesn't exist yet
+func readDockerConfig(dir string) (map[string]interface{}, string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, "", err
+	}
+	path := filepath.Join(dir, "config.json")
+
+	dockerConfig := map[string]interface{}{}
+	if data, err := ioutil.ReadFile(path); err == nil && len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &dockerConfig); err != nil {
+			return nil, "", fmt.Errorf("unable to read %s: %s", path, err)
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, "", err
+	}
+	return dockerConfig, path, nil
+}
+
+func writeDockerConfig(path string, dockerConfig map[string]interface{}) error {
+	data, err := json.MarshalIndent(dockerConfig, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(data, '\n'), 0600)
+}
@@ -0,0 +1,86 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package registry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_WriteDockerConfig_KeepsOtherRegistries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := `{"auths":{"ghcr.io":{"auth":"b3RoZXI6dG9rZW4="}},"credsStore":"desktop"}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "config.json"), []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := WriteDockerConfig(dir, Credentials{Server: "gcr.io", Username: "oauth2accesstoken", Password: "token"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	var written struct {
+		Auths      map[string]map[string]string `json:"auths"`
+		CredsStore string                       `json:"credsStore"`
+	}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("unable to parse %s: %s", data, err)
+	}
+
+	if got := written.Auths["gcr.io"]["auth"]; got != "b2F1dGgyYWNjZXNzdG9rZW46dG9rZW4=" {
+		t.Errorf("want the base64 of username:password for gcr.io, got %q", got)
+	}
+	if _, ok := written.Auths["ghcr.io"]; !ok || written.CredsStore != "desktop" {
+		t.Errorf("want the existing settings kept, got %s", data)
+	}
+
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("want config.json to be readable only by the user, got %v", info.Mode().Perm())
+	}
+
+	if _, err := WriteDockerConfig(dir, Credentials{Server: "gcr.io"}); err == nil {
+		t.Errorf("want an error for empty credentials")
+	}
+}
+
+func Test_WriteCredentialHelper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := WriteDockerConfig(dir, Credentials{Server: "ghcr.io", Username: "user", Password: "token"}); err != nil {
+		t.Fatal(err)
+	}
+	path, err := WriteCredentialHelper(dir, "123456789012.dkr.ecr.eu-west-1.amazonaws.com", "ecr-login")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	var written struct {
+		Auths       map[string]map[string]string `json:"auths"`
+		CredHelpers map[string]string            `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &written); err != nil {
+		t.Fatalf("unable to parse %s: %s", data, err)
+	}
+
+	if got := written.CredHelpers["123456789012.dkr.ecr.eu-west-1.amazonaws.com"]; got != "ecr-login" {
+		t.Errorf("want the ecr-login helper for the registry, got %q", got)
+	}
+	if _, ok := written.Auths["ghcr.io"]; !ok {
+		t.Errorf("want the existing auths kept, got %s", data)
+	}
+}
@@ -0,0 +1,228 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package registry
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ecrEndpoint is the ECR API of a region, it is swapped out in tests
+var ecrEndpoint = func(region string) string {
+	return "https://api.ecr." + region + ".amazonaws.com/"
+}
+
+// runCommand runs a cloud CLI and gives its trimmed output, it is swapped out
+// in tests
+var runCommand = func(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if _, lookErr := exec.LookPath(name); lookErr != nil {
+			return "", fmt.Errorf("%s was not found in the PATH", name)
+		}
+		return "", fmt.Errorf("%s failed: %s", name, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// awsCredentials sign requests to AWS APIs
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// ECRLogin exchanges the IAM credentials in AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY for a token for the account's ECR registry in the
+// region, the registry of the credentials' account is used when accountID is
+// empty. Without those variables "aws ecr get-login-password" is run, so that
+// profiles and instance roles work, which needs the accountID.
+func ECRLogin(region string, accountID string) (Credentials, error) {
+	if len(region) == 0 {
+		region = os.Getenv("AWS_REGION")
+	}
+	if len(region) == 0 {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if len(region) == 0 {
+		return Credentials{}, fmt.Errorf("give the region of the ECR registry with --region or AWS_REGION")
+	}
+
+	credentials := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if len(credentials.AccessKeyID) == 0 || len(credentials.SecretAccessKey) == 0 {
+		if len(accountID) == 0 {
+			return Credentials{}, fmt.Errorf("give --account-id, or set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+		}
+		password, err := runCommand("aws", "ecr", "get-login-password", "--region", region)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("unable to get an ECR token: %s", err)
+		}
+		return Credentials{
+			Server:   accountID + ".dkr.ecr." + region + ".amazonaws.com",
+			Username: "AWS",
+			Password: password,
+		}, nil
+	}
+
+	return getECRAuthorizationToken(credentials, region, accountID, time.Now())
+}
+
+type ecrAuthorizationResponse struct {
+	AuthorizationData []struct {
+		AuthorizationToken string  `json:"authorizationToken"`
+		ProxyEndpoint      string  `json:"proxyEndpoint"`
+		ExpiresAt          float64 `json:"expiresAt"`
+	} `json:"authorizationData"`
+	Message string `json:"message"`
+}
+
+// getECRAuthorizationToken calls ecr:GetAuthorizationToken
+func getECRAuthorizationToken(credentials awsCredentials, region string, accountID string, now time.Time) (Credentials, error) {
+	body := []byte("{}")
+	if len(accountID) > 0 {
+		body, _ = json.Marshal(map[string][]string{"registryIds": {accountID}})
+	}
+
+	endpoint := ecrEndpoint(region)
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Credentials{}, fmt.Errorf("invalid ECR endpoint: %s", endpoint)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken")
+	signV4(req, body, credentials, region, "ecr", now)
+
+	client := http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("cannot connect to ECR on URL: %s", endpoint)
+	}
+	defer res.Body.Close()
+
+	bytesOut, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("cannot read result from ECR on URL: %s", endpoint)
+	}
+
+	var result ecrAuthorizationResponse
+	jsonErr := json.Unmarshal(bytesOut, &result)
+	if res.StatusCode != http.StatusOK {
+		if jsonErr == nil && len(result.Message) > 0 {
+			return Credentials{}, fmt.Errorf("ECR refused the token exchange: %s", result.Message)
+		}
+		return Credentials{}, fmt.Errorf("ECR returned unexpected status code: %d - %s", res.StatusCode, string(bytesOut))
+	}
+	if jsonErr != nil || len(result.AuthorizationData) == 0 {
+		return Credentials{}, fmt.Errorf("cannot parse result from ECR on URL: %s", endpoint)
+	}
+
+	data := result.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(data.AuthorizationToken)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("cannot decode the ECR authorization token: %s", err)
+	}
+	parts := strings.SplitN(string(decoded), ":", 2)
+	if len(parts) != 2 {
+		return Credentials{}, fmt.Errorf("the ECR authorization token is not a username and password")
+	}
+
+	login := Credentials{
+		Server:   strings.TrimPrefix(strings.TrimPrefix(data.ProxyEndpoint, "https://"), "http://"),
+		Username: parts[0],
+		Password: parts[1],
+	}
+	if data.ExpiresAt > 0 {
+		login.Expiry = time.Unix(int64(data.ExpiresAt), 0)
+	}
+	return login, nil
+}
+
+// signV4 signs a request to an AWS API with Signature Version 4, signing the
+// host, X-Amz-* and Content-Type headers
+func signV4(req *http.Request, body []byte, credentials awsCredentials, region string, service string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if len(credentials.SessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	host := req.Host
+	if len(host) == 0 {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(req.Header.Get(name))
+		}
+	}
+
+	var names []string
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders bytes.Buffer
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if len(path) == 0 {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
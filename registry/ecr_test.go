@@ -0,0 +1,110 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package registry
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_signV4_MatchesAWSTestSuite(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	credentials := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+
+	signV4(req, []byte{}, credentials, "us-east-1", "service", now)
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("want Authorization:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+func Test_getECRAuthorizationToken(t *testing.T) {
+	var target string
+	var body map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target = r.Header.Get("X-Amz-Target")
+		data, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(data, &body)
+
+		if !strings.Contains(r.Header.Get("Authorization"), "Credential=AKID/") || r.Header.Get("X-Amz-Security-Token") != "session" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"The security token included in the request is invalid."}`))
+			return
+		}
+		w.Write([]byte(`{"authorizationData":[{"authorizationToken":"` + base64.StdEncoding.EncodeToString([]byte("AWS:ecr-token")) +
+			`","proxyEndpoint":"https://123456789012.dkr.ecr.eu-west-1.amazonaws.com","expiresAt":1500000000}]}`))
+	}))
+	defer server.Close()
+
+	original := ecrEndpoint
+	defer func() { ecrEndpoint = original }()
+	ecrEndpoint = func(region string) string { return server.URL + "/" }
+
+	login, err := getECRAuthorizationToken(awsCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"}, "eu-west-1", "123456789012", time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if target != "AmazonEC2ContainerRegistry_V20150921.GetAuthorizationToken" {
+		t.Errorf("want the GetAuthorizationToken target, got %q", target)
+	}
+	if len(body["registryIds"]) != 1 || body["registryIds"][0] != "123456789012" {
+		t.Errorf("want the account ID in registryIds, got %v", body)
+	}
+
+	want := Credentials{Server: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", Username: "AWS", Password: "ecr-token", Expiry: time.Unix(1500000000, 0)}
+	if login != want {
+		t.Errorf("want %+v, got %+v", want, login)
+	}
+
+	_, err = getECRAuthorizationToken(awsCredentials{AccessKeyID: "other", SecretAccessKey: "secret"}, "eu-west-1", "", time.Now())
+	if err == nil || !strings.Contains(err.Error(), "security token included in the request is invalid") {
+		t.Errorf("want ECR's message in the error, got %v", err)
+	}
+}
+
+func Test_ECRLogin_UsesAWSCLIWithoutKeys(t *testing.T) {
+	for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if value, exists := os.LookupEnv(name); exists {
+			defer os.Setenv(name, value)
+		}
+		os.Unsetenv(name)
+	}
+
+	var ran []string
+	original := runCommand
+	defer func() { runCommand = original }()
+	runCommand = func(name string, args ...string) (string, error) {
+		ran = append([]string{name}, args...)
+		return "cli-token", nil
+	}
+
+	if _, err := ECRLogin("", "123456789012"); err == nil || !strings.Contains(err.Error(), "--region") {
+		t.Errorf("want an error asking for the region, got %v", err)
+	}
+	if _, err := ECRLogin("eu-west-1", ""); err == nil || !strings.Contains(err.Error(), "--account-id") {
+		t.Errorf("want an error asking for the account ID, got %v", err)
+	}
+
+	login, err := ECRLogin("eu-west-1", "123456789012")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if strings.Join(ran, " ") != "aws ecr get-login-password --region eu-west-1" {
+		t.Errorf("want aws ecr get-login-password to be run, got %v", ran)
+	}
+	want := Credentials{Server: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", Username: "AWS", Password: "cli-token"}
+	if login != want {
+		t.Errorf("want %+v, got %+v", want, login)
+	}
+}
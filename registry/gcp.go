@@ -0,0 +1,82 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// gcpTokenUsername is the username Google registries take an access token for
+const gcpTokenUsername = "oauth2accesstoken"
+
+// gcpMetadataTokenURL gives the access token of the service account of a
+// GCE VM, GKE pod or Cloud Build step, it is swapped out in tests
+var gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// GCPLogin gets an access token for Artifact Registry in the location, i.e.
+// europe-west1, or for server when it is given such as gcr.io. The token is
+// read from GOOGLE_OAUTH_ACCESS_TOKEN, then from "gcloud auth
+// print-access-token" and then from the metadata server.
+func GCPLogin(location string, server string) (Credentials, error) {
+	if len(server) == 0 {
+		if len(location) == 0 {
+			return Credentials{}, fmt.Errorf("give the location of the Artifact Registry with --location, or its --server")
+		}
+		server = location + "-docker.pkg.dev"
+	}
+
+	login := Credentials{Server: server, Username: gcpTokenUsername}
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); len(token) > 0 {
+		login.Password = token
+		return login, nil
+	}
+
+	token, gcloudErr := runCommand("gcloud", "auth", "print-access-token")
+	if gcloudErr == nil && len(token) > 0 {
+		login.Password = token
+		return login, nil
+	}
+
+	token, expiry, metadataErr := gcpMetadataToken()
+	if metadataErr != nil {
+		return Credentials{}, fmt.Errorf("unable to get a Google access token, set GOOGLE_OAUTH_ACCESS_TOKEN or log in with gcloud: %s", gcloudErr)
+	}
+	login.Password = token
+	login.Expiry = expiry
+	return login, nil
+}
+
+// gcpMetadataToken asks the metadata server for the default service
+// account's token, it fails quickly away from Google Cloud
+func gcpMetadataToken() (string, time.Time, error) {
+	req, err := http.NewRequest(http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	client := http.Client{Timeout: 2 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("the metadata server returned unexpected status code: %d", res.StatusCode)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&result); err != nil || len(result.AccessToken) == 0 {
+		return "", time.Time{}, fmt.Errorf("cannot parse the token from the metadata server")
+	}
+	return result.AccessToken, time.Now().Add(time.Duration(result.ExpiresIn) * time.Second), nil
+}
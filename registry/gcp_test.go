@@ -0,0 +1,67 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_GCPLogin(t *testing.T) {
+	if value, exists := os.LookupEnv("GOOGLE_OAUTH_ACCESS_TOKEN"); exists {
+		defer os.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", value)
+	}
+	os.Unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+
+	originalRun, originalURL := runCommand, gcpMetadataTokenURL
+	defer func() { runCommand, gcpMetadataTokenURL = originalRun, originalURL }()
+
+	metadata := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"access_token":"metadata-token","expires_in":3599}`))
+	}))
+	defer metadata.Close()
+	gcpMetadataTokenURL = metadata.URL
+
+	gcloudToken := "gcloud-token"
+	runCommand = func(name string, args ...string) (string, error) {
+		if len(gcloudToken) == 0 {
+			return "", fmt.Errorf("gcloud was not found in the PATH")
+		}
+		return gcloudToken, nil
+	}
+
+	if _, err := GCPLogin("", ""); err == nil {
+		t.Errorf("want an error without a location or server")
+	}
+
+	login, err := GCPLogin("europe-west1", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if login.Server != "europe-west1-docker.pkg.dev" || login.Username != "oauth2accesstoken" || login.Password != "gcloud-token" {
+		t.Errorf("want the gcloud token for europe-west1-docker.pkg.dev, got %+v", login)
+	}
+
+	gcloudToken = ""
+	login, err = GCPLogin("", "gcr.io")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if login.Server != "gcr.io" || login.Password != "metadata-token" || login.Expiry.IsZero() {
+		t.Errorf("want the metadata server's token for gcr.io, got %+v", login)
+	}
+
+	os.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "env-token")
+	defer os.Unsetenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if login, _ = GCPLogin("us", ""); login.Password != "env-token" {
+		t.Errorf("want GOOGLE_OAUTH_ACCESS_TOKEN to be used first, got %q", login.Password)
+	}
+}
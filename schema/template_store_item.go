@@ -0,0 +1,12 @@
+package schema
+
+// TemplateStoreItem is a template listed in a template store
+type TemplateStoreItem struct {
+	Template    string `json:"template"`
+	Platform    string `json:"platform"`
+	Language    string `json:"language"`
+	Source      string `json:"source"`
+	Description string `json:"description"`
+	Repository  string `json:"repo"`
+	Official    string `json:"official"`
+}
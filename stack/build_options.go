@@ -0,0 +1,130 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// AdditionalPackageBuildArg is the build-arg the packages of the build
+	// options chosen are given to the template's Dockerfile in
+	AdditionalPackageBuildArg = "ADDITIONAL_PACKAGE"
+
+	// AllBuildOptions chooses every option of the template and the stack
+	AllBuildOptions = "all"
+)
+
+// ResolveBuildOptions gives the packages and build-args of the options named,
+// which are defined by the template and extended by the stack. A group applies
+// the options it names first, and a build-arg set by a later option wins.
+func ResolveBuildOptions(templateOptions []BuildOption, stackOptions []BuildOption, names []string) ([]string, map[string]string, error) {
+	defined := mergeBuildOptions(templateOptions, stackOptions)
+
+	var selected []string
+	for _, name := range names {
+		if name == AllBuildOptions {
+			for _, option := range defined {
+				selected = append(selected, option.Name)
+			}
+			continue
+		}
+		selected = append(selected, name)
+	}
+
+	packages := []string{}
+	buildArgs := map[string]string{}
+	applied := map[string]bool{}
+
+	var apply func(name string, path []string) error
+	apply = func(name string, path []string) error {
+		for _, parent := range path {
+			if parent == name {
+				return fmt.Errorf("build option %s includes itself: %s", name, strings.Join(append(path, name), " -> "))
+			}
+		}
+		if applied[name] {
+			return nil
+		}
+
+		option, found := findBuildOption(defined, name)
+		if !found {
+			return fmt.Errorf("unknown build option %s, %s", name, describeBuildOptions(defined))
+		}
+		for _, included := range option.Options {
+			if err := apply(included, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		for _, pkg := range option.Packages {
+			if !contains(packages, pkg) {
+				packages = append(packages, pkg)
+			}
+		}
+		for key, value := range option.BuildArgs {
+			buildArgs[key] = value
+		}
+		applied[name] = true
+		return nil
+	}
+
+	for _, name := range selected {
+		if err := apply(name, nil); err != nil {
+			return nil, nil, err
+		}
+	}
+	return packages, buildArgs, nil
+}
+
+// mergeBuildOptions extends the template's options with the stack's, sorted by
+// name. An option defined by both has the packages, build-args and options of
+// both, the stack's build-args win.
+func mergeBuildOptions(templateOptions []BuildOption, stackOptions []BuildOption) []BuildOption {
+	merged := map[string]BuildOption{}
+	for _, options := range [][]BuildOption{templateOptions, stackOptions} {
+		for _, option := range options {
+			existing := merged[option.Name]
+			existing.Name = option.Name
+			existing.Packages = append(append([]string{}, existing.Packages...), option.Packages...)
+			existing.Options = append(append([]string{}, existing.Options...), option.Options...)
+			existing.BuildArgs = mergeStrings(existing.BuildArgs, option.BuildArgs)
+			merged[option.Name] = existing
+		}
+	}
+
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	options := make([]BuildOption, 0, len(names))
+	for _, name := range names {
+		options = append(options, merged[name])
+	}
+	return options
+}
+
+func findBuildOption(options []BuildOption, name string) (BuildOption, bool) {
+	for _, option := range options {
+		if option.Name == name {
+			return option, true
+		}
+	}
+	return BuildOption{}, false
+}
+
+func describeBuildOptions(options []BuildOption) string {
+	if len(options) == 0 {
+		return "the template and stack define none"
+	}
+	names := make([]string, 0, len(options))
+	for _, option := range options {
+		names = append(names, option.Name)
+	}
+	return "choose from: " + strings.Join(names, ", ")
+}
@@ -0,0 +1,66 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var templateBuildOptions = []BuildOption{
+	{Name: "dev", Packages: []string{"make", "gcc"}},
+	{Name: "git", Packages: []string{"git"}},
+}
+
+func Test_ResolveBuildOptions_StackExtendsTemplate(t *testing.T) {
+	stackOptions := []BuildOption{
+		{Name: "dev", Packages: []string{"gcc", "cmake"}, BuildArgs: map[string]string{"CGO_ENABLED": "1"}},
+		{Name: "toolchain", Options: []string{"dev", "git"}, BuildArgs: map[string]string{"CGO_ENABLED": "0"}},
+	}
+
+	packages, buildArgs, err := ResolveBuildOptions(templateBuildOptions, stackOptions, []string{"toolchain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"make", "gcc", "cmake", "git"}; !reflect.DeepEqual(packages, want) {
+		t.Errorf("want packages %v, got %v", want, packages)
+	}
+	if buildArgs["CGO_ENABLED"] != "0" {
+		t.Errorf("want the group's own build-arg applied last, got %v", buildArgs)
+	}
+}
+
+func Test_ResolveBuildOptions_All(t *testing.T) {
+	packages, _, err := ResolveBuildOptions(templateBuildOptions, []BuildOption{{Name: "curl", Packages: []string{"curl"}}}, []string{AllBuildOptions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"curl", "make", "gcc", "git"}; !reflect.DeepEqual(packages, want) {
+		t.Errorf("want every option by name, got %v", packages)
+	}
+}
+
+func Test_ResolveBuildOptions_Errors(t *testing.T) {
+	_, _, err := ResolveBuildOptions(templateBuildOptions, nil, []string{"java"})
+	if err == nil || err.Error() != "unknown build option java, choose from: dev, git" {
+		t.Errorf("want an error for an unknown option, got %v", err)
+	}
+
+	cycle := []BuildOption{{Name: "a", Options: []string{"b"}}, {Name: "b", Options: []string{"a"}}}
+	_, _, err = ResolveBuildOptions(nil, cycle, []string{"a"})
+	if err == nil || !strings.HasPrefix(err.Error(), "build option a includes itself: a -> b -> a") {
+		t.Errorf("want an error for a cycle, got %v", err)
+	}
+}
+
+func Test_inherit_BuildOptions(t *testing.T) {
+	services := &Services{BuildOptions: []BuildOption{{Name: "dev", Packages: []string{"gcc"}}}}
+	base := &Services{BuildOptions: []BuildOption{{Name: "dev", Packages: []string{"make"}}, {Name: "git", Packages: []string{"git"}}}}
+
+	inherit(services, base)
+	if len(services.BuildOptions) != 2 || services.BuildOptions[0].Packages[0] != "gcc" || services.BuildOptions[1].Name != "git" {
+		t.Errorf("want the stack's own dev option kept and git inherited, got %+v", services.BuildOptions)
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// IsDotEnv reports whether an environment_file is a dotenv file, i.e. .env,
+// prod.env or .env.staging, rather than YAML
+func IsDotEnv(file string) bool {
+	name := filepath.Base(file)
+	return name == ".env" || strings.HasSuffix(name, ".env") || strings.HasPrefix(name, ".env.")
+}
+
+// ParseDotEnv reads KEY=VALUE lines. Blank lines and lines starting with #
+// are skipped, an export before the key is allowed and a value in matching
+// single or double quotes is unquoted.
+func ParseDotEnv(data []byte) (map[string]string, error) {
+	environment := map[string]string{}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || len(key) == 0 || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d must be KEY=VALUE", i+1)
+		}
+
+		value := strings.TrimSpace(parts[1])
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		environment[key] = value
+	}
+	return environment, nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_IsDotEnv(t *testing.T) {
+	cases := map[string]bool{
+		".env":             true,
+		"config/prod.env":  true,
+		".env.staging":     true,
+		"env.yml":          false,
+		"environment.yaml": false,
+	}
+	for file, want := range cases {
+		if got := IsDotEnv(file); got != want {
+			t.Errorf("%s: want %v, got %v", file, want, got)
+		}
+	}
+}
+
+func Test_ParseDotEnv(t *testing.T) {
+	data := []byte(`# Staging
+REGION=eu-west-1
+export LOG_LEVEL=debug
+
+GREETING="hello world"
+PATTERN='a=b'
+EMPTY=
+`)
+
+	got, err := ParseDotEnv(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]string{
+		"REGION":    "eu-west-1",
+		"LOG_LEVEL": "debug",
+		"GREETING":  "hello world",
+		"PATTERN":   "a=b",
+		"EMPTY":     "",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	if _, err := ParseDotEnv([]byte("REGION=eu\nnot a variable\n")); err == nil || err.Error() != "line 2 must be KEY=VALUE" {
+		t.Errorf("want an error for line 2, got %v", err)
+	}
+}
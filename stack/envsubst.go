@@ -0,0 +1,65 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// EnvSubst expands ${VAR} and ${VAR:-default} in stack files before they are
+// parsed, it is set by --envsubst
+var EnvSubst bool
+
+// envReference matches $${ which escapes a literal ${, or ${VAR} with an
+// optional default given by :- when VAR is unset or empty, or by - when it
+// is unset
+var envReference = regexp.MustCompile(`\$(\$\{)|\$\{([A-Za-z_][A-Za-z0-9_]*)(?:(:?-)([^}]*))?\}`)
+
+// SubstituteEnvironment expands the environment variables referenced in the
+// stack. Comment lines are left as they are, and a variable which is not set
+// and has no default is an error, so that an image or gateway isn't quietly
+// left empty.
+func SubstituteEnvironment(data []byte) ([]byte, error) {
+	missing := map[string]bool{}
+
+	lines := strings.Split(string(data), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+
+		lines[i] = envReference.ReplaceAllStringFunc(line, func(reference string) string {
+			match := envReference.FindStringSubmatch(reference)
+			if len(match[1]) > 0 {
+				return match[1]
+			}
+
+			name, operator, fallback := match[2], match[3], match[4]
+			value, set := os.LookupEnv(name)
+			switch {
+			case operator == ":-" && len(value) == 0:
+				return fallback
+			case operator == "-" && !set:
+				return fallback
+			case !set:
+				missing[name] = true
+			}
+			return value
+		})
+	}
+
+	if len(missing) > 0 {
+		var names []string
+		for name := range missing {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("environment variable(s) %s are not set, set them or give a default with ${%s:-default}", strings.Join(names, ", "), names[0])
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
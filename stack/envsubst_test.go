@@ -0,0 +1,85 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_SubstituteEnvironment(t *testing.T) {
+	os.Setenv("FAAS_TEST_TAG", "0.2")
+	os.Setenv("FAAS_TEST_EMPTY", "")
+	defer os.Unsetenv("FAAS_TEST_TAG")
+	defer os.Unsetenv("FAAS_TEST_EMPTY")
+
+	in := `# ${FAAS_TEST_UNSET} in a comment is left alone
+provider:
+  gateway: ${FAAS_TEST_GATEWAY:-http://127.0.0.1:8080}
+functions:
+  api:
+    image: acme/api:${FAAS_TEST_TAG}
+    fprocess: sh -c 'echo $$HOME $${FAAS_TEST_TAG}'
+    environment:
+      empty: "${FAAS_TEST_EMPTY:-fallback}"
+      kept: "${FAAS_TEST_EMPTY-fallback}"
+`
+	want := `# ${FAAS_TEST_UNSET} in a comment is left alone
+provider:
+  gateway: http://127.0.0.1:8080
+functions:
+  api:
+    image: acme/api:0.2
+    fprocess: sh -c 'echo $$HOME ${FAAS_TEST_TAG}'
+    environment:
+      empty: "fallback"
+      kept: ""
+`
+
+	out, err := SubstituteEnvironment([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want {
+		t.Errorf("want:\n%s\ngot:\n%s", want, out)
+	}
+}
+
+func Test_SubstituteEnvironment_Unset(t *testing.T) {
+	_, err := SubstituteEnvironment([]byte("image: ${FAAS_TEST_UNSET_B}:${FAAS_TEST_UNSET_A}\n"))
+	if err == nil || !strings.Contains(err.Error(), "FAAS_TEST_UNSET_A, FAAS_TEST_UNSET_B are not set") {
+		t.Errorf("want the unset variables named, got %v", err)
+	}
+}
+
+func Test_ParseYAMLData_EnvSubst(t *testing.T) {
+	os.Setenv("FAAS_TEST_TAG", "0.3")
+	defer os.Unsetenv("FAAS_TEST_TAG")
+
+	stack := []byte(`provider:
+  name: faas
+functions:
+  api:
+    image: acme/api:${FAAS_TEST_TAG}
+`)
+
+	services, err := ParseYAMLData(stack, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := services.Functions["api"].Image; image != "acme/api:${FAAS_TEST_TAG}" {
+		t.Errorf("want no substitution without EnvSubst, got %s", image)
+	}
+
+	EnvSubst = true
+	defer func() { EnvSubst = false }()
+	services, err = ParseYAMLData(stack, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image := services.Functions["api"].Image; image != "acme/api:0.3" {
+		t.Errorf("want the tag substituted, got %s", image)
+	}
+}
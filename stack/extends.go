@@ -0,0 +1,278 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/openfaas/faas-cli/versioncontrol"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// maxExtendsDepth limits how many stacks can be chained with extends
+const maxExtendsDepth = 10
+
+// defaultGitStackFile is read from a git repository given without a path
+const defaultGitStackFile = "stack.yml"
+
+// resolveExtends merges the stack named by services.Extends, and those it
+// extends in turn, into services. location is where services was read from,
+// relative extends are resolved from it.
+func resolveExtends(services *Services, location string, seen []string) error {
+	if len(services.Extends) == 0 {
+		return nil
+	}
+
+	baseLocation := extendsLocation(location, services.Extends)
+	for _, previous := range seen {
+		if previous == baseLocation {
+			return fmt.Errorf("extends cycle: %s", strings.Join(append(seen, baseLocation), " -> "))
+		}
+	}
+	if len(seen) >= maxExtendsDepth {
+		return fmt.Errorf("extends is nested more than %d deep at %s", maxExtendsDepth, baseLocation)
+	}
+
+	data, err := readExtends(baseLocation)
+	if err != nil {
+		return fmt.Errorf("unable to read %s which the stack extends: %s", baseLocation, err)
+	}
+	if EnvSubst {
+		if data, err = SubstituteEnvironment(data); err != nil {
+			return fmt.Errorf("%s which the stack extends: %s", baseLocation, err)
+		}
+	}
+
+	var base Services
+	if err := yaml.Unmarshal(data, &base); err != nil {
+		return fmt.Errorf("unable to parse %s which the stack extends: %s", baseLocation, err)
+	}
+	if err := resolveExtends(&base, baseLocation, append(seen, baseLocation)); err != nil {
+		return err
+	}
+
+	inherit(services, &base)
+	services.Extends = ""
+	return nil
+}
+
+// inherit fills in the provider, defaults, policy, build options and build
+// groups of services from base, the settings of services win. Functions and base images
+// are not inherited.
+func inherit(services *Services, base *Services) {
+	if len(services.Provider.Name) == 0 {
+		services.Provider.Name = base.Provider.Name
+	}
+	if len(services.Provider.GatewayURL) == 0 {
+		services.Provider.GatewayURL = base.Provider.GatewayURL
+	}
+	if len(services.Provider.Network) == 0 {
+		services.Provider.Network = base.Provider.Network
+	}
+
+	if services.Policy == nil {
+		services.Policy = base.Policy
+	}
+
+	for _, option := range base.BuildOptions {
+		if _, defined := findBuildOption(services.BuildOptions, option.Name); !defined {
+			services.BuildOptions = append(services.BuildOptions, option)
+		}
+	}
+
+	for name, group := range base.BuildGroups {
+		if _, defined := services.BuildGroups[name]; defined {
+			continue
+		}
+		if services.BuildGroups == nil {
+			services.BuildGroups = map[string]BuildGroup{}
+		}
+		services.BuildGroups[name] = group
+	}
+
+	if base.Defaults == nil {
+		return
+	}
+	if services.Defaults == nil {
+		services.Defaults = base.Defaults
+		return
+	}
+
+	defaults := services.Defaults
+	defaults.Environment = mergeStrings(base.Defaults.Environment, defaults.Environment)
+	defaults.Secrets = appendMissing(base.Defaults.Secrets, defaults.Secrets)
+	defaults.Labels = mergeStringsPtr(base.Defaults.Labels, defaults.Labels)
+	defaults.Annotations = mergeStringsPtr(base.Defaults.Annotations, defaults.Annotations)
+	if defaults.Constraints == nil {
+		defaults.Constraints = base.Defaults.Constraints
+	}
+	if defaults.Limits == nil {
+		defaults.Limits = base.Defaults.Limits
+	}
+	if defaults.Requests == nil {
+		defaults.Requests = base.Defaults.Requests
+	}
+	if defaults.HealthCheck == nil {
+		defaults.HealthCheck = base.Defaults.HealthCheck
+	}
+	if defaults.Logging == nil {
+		defaults.Logging = base.Defaults.Logging
+	}
+}
+
+// applyDefaults sets the defaults on each function which doesn't set them itself
+func applyDefaults(services *Services) {
+	defaults := services.Defaults
+	if defaults == nil {
+		return
+	}
+
+	for name, function := range services.Functions {
+		function.Environment = mergeStrings(defaults.Environment, function.Environment)
+		function.Secrets = appendMissing(defaults.Secrets, function.Secrets)
+		function.Labels = mergeStringsPtr(defaults.Labels, function.Labels)
+		function.Annotations = mergeStringsPtr(defaults.Annotations, function.Annotations)
+		if function.Constraints == nil {
+			function.Constraints = defaults.Constraints
+		}
+		if function.Limits == nil {
+			function.Limits = defaults.Limits
+		}
+		if function.Requests == nil {
+			function.Requests = defaults.Requests
+		}
+		if function.HealthCheck == nil {
+			function.HealthCheck = defaults.HealthCheck
+		}
+		if function.Logging == nil {
+			function.Logging = defaults.Logging
+		}
+		services.Functions[name] = function
+	}
+}
+
+// extendsLocation resolves extends relative to the location of the stack
+// which gives it
+func extendsLocation(location string, extends string) string {
+	if isRemoteStack(extends) || filepath.IsAbs(extends) {
+		return extends
+	}
+
+	if repo, file, ok := splitGitStack(location); ok {
+		return repo + "//" + path.Join(path.Dir(file), extends)
+	}
+
+	if locationURL, err := url.Parse(location); err == nil && isHTTPURL(locationURL) {
+		if extendsURL, err := url.Parse(extends); err == nil {
+			return locationURL.ResolveReference(extendsURL).String()
+		}
+	}
+
+	if len(location) == 0 || location == StdinFile {
+		return extends
+	}
+	return filepath.Join(filepath.Dir(location), extends)
+}
+
+// readExtends reads a stack from a git repository, an http(s) URL or a file
+func readExtends(location string) ([]byte, error) {
+	if repo, file, ok := splitGitStack(location); ok {
+		return readGitStack(repo, file)
+	}
+
+	if locationURL, err := url.Parse(location); err == nil && isHTTPURL(locationURL) {
+		return fetchYAML(locationURL)
+	}
+
+	return ioutil.ReadFile(location)
+}
+
+// readGitStack clones the repository to read one file from it
+func readGitStack(repo string, file string) ([]byte, error) {
+	dir, err := ioutil.TempDir("", "openFaasStack")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := versioncontrol.GitClone.Invoke(".", map[string]string{"repo": repo, "dir": dir}); err != nil {
+		return nil, fmt.Errorf("unable to clone %s: %s", repo, err)
+	}
+
+	return ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+}
+
+// splitGitStack splits a stack in a git repository, given as REPOSITORY.git//PATH,
+// into the repository and the path. A repository given without a path is
+// read from its stack.yml.
+func splitGitStack(location string) (string, string, bool) {
+	if i := strings.Index(location, ".git//"); i >= 0 {
+		return location[:i+len(".git")], location[i+len(".git//"):], true
+	}
+
+	if versioncontrol.IsSSHURL(location) || strings.HasSuffix(location, ".git") {
+		return location, defaultGitStackFile, true
+	}
+	return "", "", false
+}
+
+func isRemoteStack(location string) bool {
+	if _, _, ok := splitGitStack(location); ok {
+		return true
+	}
+	locationURL, err := url.Parse(location)
+	return err == nil && isHTTPURL(locationURL)
+}
+
+func isHTTPURL(address *url.URL) bool {
+	return address.Scheme == "http" || address.Scheme == "https"
+}
+
+// mergeStrings returns the values of base overridden by those of overrides
+func mergeStrings(base map[string]string, overrides map[string]string) map[string]string {
+	if len(base) == 0 {
+		return overrides
+	}
+
+	merged := map[string]string{}
+	for key, value := range base {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
+
+func mergeStringsPtr(base *map[string]string, overrides *map[string]string) *map[string]string {
+	if base == nil {
+		return overrides
+	}
+
+	var values map[string]string
+	if overrides != nil {
+		values = *overrides
+	}
+	merged := mergeStrings(*base, values)
+	if merged == nil {
+		return overrides
+	}
+	return &merged
+}
+
+// appendMissing adds the values of base which are not in values
+func appendMissing(base []string, values []string) []string {
+	for _, value := range base {
+		if !contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
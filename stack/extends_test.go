@@ -0,0 +1,184 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openfaas/faas-cli/versioncontrol"
+)
+
+const goldenStack = `provider:
+  name: faas
+  gateway: https://openfaas.example.com
+defaults:
+  environment:
+    TEAM: platform
+    write_debug: "false"
+  secrets: [registry-token]
+  labels:
+    com.example.owner: platform
+  limits:
+    memory: 128Mi
+policy:
+  freeze_windows:
+    - name: friday
+      cron: "* 17-23 * * 5"
+      timezone: UTC
+build_groups:
+  batch:
+    concurrency: 1
+`
+
+const teamStack = `extends: %s
+defaults:
+  environment:
+    write_debug: "true"
+functions:
+  api:
+    lang: python3
+    handler: ./api
+    image: api:0.1
+    environment:
+      PORT: "8080"
+    limits:
+      memory: 256Mi
+`
+
+func writeStackFile(t *testing.T, dir string, name string, data string) string {
+	file := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(file, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func assertInherited(t *testing.T, services *Services) {
+	if services.Provider.GatewayURL != "https://openfaas.example.com" {
+		t.Errorf("want the gateway inherited, got: %q", services.Provider.GatewayURL)
+	}
+	if services.Policy == nil || services.Policy.FreezeWindows[0].Name != "friday" {
+		t.Errorf("want the policy inherited, got: %+v", services.Policy)
+	}
+	if group, ok := services.BuildGroups["batch"]; !ok || group.Concurrency != 1 {
+		t.Errorf("want the build groups inherited, got: %+v", services.BuildGroups)
+	}
+
+	api := services.Functions["api"]
+	wantEnvironment := map[string]string{"TEAM": "platform", "write_debug": "true", "PORT": "8080"}
+	if !reflect.DeepEqual(api.Environment, wantEnvironment) {
+		t.Errorf("want environment %v, got: %v", wantEnvironment, api.Environment)
+	}
+	if !reflect.DeepEqual(api.Secrets, []string{"registry-token"}) {
+		t.Errorf("want the default secret, got: %v", api.Secrets)
+	}
+	if api.Labels == nil || (*api.Labels)["com.example.owner"] != "platform" {
+		t.Errorf("want the default label, got: %v", api.Labels)
+	}
+	if api.Limits == nil || api.Limits.Memory != "256Mi" {
+		t.Errorf("want the function's own limits to win, got: %+v", api.Limits)
+	}
+}
+
+func Test_ParseYAMLFile_ExtendsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	os.Mkdir(filepath.Join(dir, "platform"), 0700)
+	writeStackFile(t, filepath.Join(dir, "platform"), "golden.yml", goldenStack)
+	stackFile := writeStackFile(t, dir, "stack.yml", strings.Replace(teamStack, "%s", "./platform/golden.yml", 1))
+
+	services, err := ParseYAMLFile(stackFile, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertInherited(t, services)
+}
+
+func Test_ParseYAMLFile_ExtendsURL(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/platform/golden.yml" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(goldenStack))
+	}))
+	defer s.Close()
+
+	services, err := ParseYAMLData([]byte(strings.Replace(teamStack, "%s", s.URL+"/platform/golden.yml", 1)), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertInherited(t, services)
+}
+
+func Test_ParseYAMLFile_ExtendsGit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repo := filepath.Join(dir, "golden-stack.git")
+	os.Mkdir(repo, 0700)
+	os.Mkdir(filepath.Join(repo, "stacks"), 0700)
+	writeStackFile(t, filepath.Join(repo, "stacks"), "golden.yml", goldenStack)
+	if err := versioncontrol.GitInitRepo.Invoke(repo, map[string]string{"dir": "."}); err != nil {
+		t.Fatal(err)
+	}
+
+	services, err := ParseYAMLData([]byte(strings.Replace(teamStack, "%s", repo+"//stacks/golden.yml", 1)), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	assertInherited(t, services)
+}
+
+func Test_ParseYAMLFile_ExtendsCycle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeStackFile(t, dir, "a.yml", "extends: ./b.yml\n")
+	writeStackFile(t, dir, "b.yml", "extends: ./a.yml\n")
+	stackFile := writeStackFile(t, dir, "stack.yml", "extends: ./a.yml\nprovider:\n  name: faas\n")
+
+	_, err = ParseYAMLFile(stackFile, "", "")
+	if err == nil || !strings.Contains(err.Error(), "extends cycle") {
+		t.Fatalf("want an extends cycle error, got: %v", err)
+	}
+}
+
+func Test_extendsLocation(t *testing.T) {
+	cases := []struct {
+		location string
+		extends  string
+		want     string
+	}{
+		{"stacks/stack.yml", "golden.yml", filepath.Join("stacks", "golden.yml")},
+		{"", "golden.yml", "golden.yml"},
+		{"stacks/stack.yml", "/etc/golden.yml", "/etc/golden.yml"},
+		{"https://example.com/stacks/stack.yml", "../golden.yml", "https://example.com/golden.yml"},
+		{"git@github.com:platform/stacks.git//teams/stack.yml", "base.yml", "git@github.com:platform/stacks.git//teams/base.yml"},
+		{"stack.yml", "git@github.com:platform/golden-stack.git", "git@github.com:platform/golden-stack.git"},
+	}
+
+	for _, c := range cases {
+		if got := extendsLocation(c.location, c.extends); got != c.want {
+			t.Errorf("extends %s from %s: want %s, got %s", c.extends, c.location, c.want, got)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// dockerfileFProcessArg matches the ARG a template's Dockerfile sets fprocess from
+var dockerfileFProcessArg = regexp.MustCompile(`(?m)^\s*ARG\s+(fprocess|FPROCESS)\b`)
+
+// FProcessOverride is how a template lets a function replace its fprocess
+type FProcessOverride struct {
+	// BuildArg gives the function's fprocess to the template's Dockerfile,
+	// it is empty when the template takes none
+	BuildArg string
+
+	// Pattern must match the function's fprocess when it is set
+	Pattern *regexp.Regexp
+}
+
+// TemplateFProcessOverride reads how a template lets a function replace its
+// fprocess from its template.yml, falling back to the ARG in its Dockerfile
+func TemplateFProcessOverride(language string) (*FProcessOverride, error) {
+	templatePath := filepath.Join(TemplateDirectory, language)
+
+	langTemplate, err := ParseYAMLForLanguageTemplate(filepath.Join(templatePath, "template.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	dockerfile, err := ioutil.ReadFile(filepath.Join(templatePath, "Dockerfile"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return detectFProcessOverride(langTemplate, string(dockerfile))
+}
+
+func detectFProcessOverride(langTemplate *LanguageTemplate, dockerfile string) (*FProcessOverride, error) {
+	override := &FProcessOverride{BuildArg: langTemplate.FProcessBuildArg}
+	if len(override.BuildArg) == 0 {
+		if match := dockerfileFProcessArg.FindStringSubmatch(dockerfile); match != nil {
+			override.BuildArg = match[1]
+		}
+	}
+
+	if len(langTemplate.FProcessPattern) > 0 {
+		pattern, err := regexp.Compile(langTemplate.FProcessPattern)
+		if err != nil {
+			return nil, fmt.Errorf("fprocess_pattern %q is not a valid regular expression: %s", langTemplate.FProcessPattern, err)
+		}
+		override.Pattern = pattern
+	}
+
+	return override, nil
+}
+
+// Check makes sure a function's fprocess is one the template expects
+func (o *FProcessOverride) Check(fprocess string) error {
+	if o.Pattern != nil && !o.Pattern.MatchString(fprocess) {
+		return fmt.Errorf("fprocess %q does not match the template's fprocess_pattern %s", fprocess, o.Pattern)
+	}
+	return nil
+}
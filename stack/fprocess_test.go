@@ -0,0 +1,71 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"testing"
+)
+
+func Test_detectFProcessOverride(t *testing.T) {
+	cases := []struct {
+		name       string
+		template   LanguageTemplate
+		dockerfile string
+		buildArg   string
+		wantErr    bool
+	}{
+		{
+			name:       "no build-arg",
+			dockerfile: classicDockerfile,
+		},
+		{
+			name:       "ARG in the Dockerfile",
+			dockerfile: "FROM node:8-alpine\nARG FPROCESS=\"node index.js\"\nENV fprocess=\"${FPROCESS}\"\n",
+			buildArg:   "FPROCESS",
+		},
+		{
+			name:       "build-arg in template.yml",
+			template:   LanguageTemplate{FProcessBuildArg: "NODE_FPROCESS"},
+			dockerfile: "FROM node:8-alpine\nARG FPROCESS\n",
+			buildArg:   "NODE_FPROCESS",
+		},
+		{
+			name:     "invalid pattern",
+			template: LanguageTemplate{FProcessPattern: "^node ("},
+			wantErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			override, err := detectFProcessOverride(&c.template, c.dockerfile)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want an error, got: %+v", override)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if override.BuildArg != c.buildArg {
+				t.Errorf("want build-arg %q, got %q", c.buildArg, override.BuildArg)
+			}
+		})
+	}
+}
+
+func Test_FProcessOverride_Check(t *testing.T) {
+	override, err := detectFProcessOverride(&LanguageTemplate{FProcessPattern: "^node "}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := override.Check("node --max-old-space-size=256 index.js"); err != nil {
+		t.Errorf("want a matching fprocess accepted, got: %s", err)
+	}
+	if err := override.Check("python index.py"); err == nil {
+		t.Errorf("want an fprocess which does not match rejected")
+	}
+}
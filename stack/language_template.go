@@ -8,10 +8,15 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os"
+	"path/filepath"
 
 	yaml "gopkg.in/yaml.v2"
 )
 
+// TemplateDirectory is the root folder language templates are read from and
+// pulled into, it can be moved with --template-dir or FAAS_TEMPLATE_DIR
+var TemplateDirectory = "./template"
+
 func ParseYAMLForLanguageTemplate(file string) (*LanguageTemplate, error) {
 	var err error
 	var fileData []byte
@@ -50,8 +55,8 @@ func ParseYAMLDataForLanguageTemplate(fileData []byte) (*LanguageTemplate, error
 func IsValidTemplate(lang string) bool {
 	var found bool
 
-	if _, err := os.Stat("./template/" + lang); err == nil {
-		templateYAMLPath := "./template/" + lang + "/template.yml"
+	if _, err := os.Stat(filepath.Join(TemplateDirectory, lang)); err == nil {
+		templateYAMLPath := filepath.Join(TemplateDirectory, lang, "template.yml")
 
 		if _, err := ParseYAMLForLanguageTemplate(templateYAMLPath); err == nil {
 			found = true
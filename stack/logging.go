@@ -0,0 +1,62 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import "fmt"
+
+// Environment variables templates read a function's logging settings from
+const (
+	LogLevelEnv  = "LOG_LEVEL"
+	LogFormatEnv = "LOG_FORMAT"
+)
+
+var (
+	logLevels  = []string{"debug", "info", "warn", "error"}
+	logFormats = []string{"text", "json"}
+)
+
+// Logging is how a function logs, it is given to the function as LOG_LEVEL
+// and LOG_FORMAT
+type Logging struct {
+	// Level is debug, info, warn or error
+	Level string `yaml:"level,omitempty"`
+
+	// Format is text or json
+	Format string `yaml:"format,omitempty"`
+}
+
+// Environment gives the variables for the settings which are set, it is
+// empty for a nil Logging
+func (l *Logging) Environment() map[string]string {
+	environment := map[string]string{}
+	if l == nil {
+		return environment
+	}
+	if len(l.Level) > 0 {
+		environment[LogLevelEnv] = l.Level
+	}
+	if len(l.Format) > 0 {
+		environment[LogFormatEnv] = l.Format
+	}
+	return environment
+}
+
+func (l *Logging) validate() error {
+	if len(l.Level) > 0 && !contains(logLevels, l.Level) {
+		return fmt.Errorf("logging level %q must be one of debug, info, warn or error", l.Level)
+	}
+	if len(l.Format) > 0 && !contains(logFormats, l.Format) {
+		return fmt.Errorf("logging format %q must be text or json", l.Format)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_ParseYAMLData_Logging(t *testing.T) {
+	stackYAML := `provider:
+  name: faas
+
+functions:
+  api:
+    lang: go
+    handler: ./api
+    image: api
+    logging:
+      level: debug
+      format: json
+`
+
+	parsedYAML, err := ParseYAMLData([]byte(stackYAML), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := map[string]string{LogLevelEnv: "debug", LogFormatEnv: "json"}
+	if got := parsedYAML.Functions["api"].Logging.Environment(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+
+	for _, invalid := range []string{
+		strings.Replace(stackYAML, "level: debug", "level: verbose", 1),
+		strings.Replace(stackYAML, "format: json", "format: logfmt", 1),
+	} {
+		if _, err := ParseYAMLData([]byte(invalid), "", ""); err == nil {
+			t.Errorf("expected an error for:\n%s", invalid)
+		}
+	}
+}
+
+func Test_Logging_Environment(t *testing.T) {
+	var unset *Logging
+	if got := unset.Environment(); len(got) != 0 {
+		t.Errorf("want no variables without a logging block, got: %v", got)
+	}
+
+	levelOnly := &Logging{Level: "warn"}
+	if got := levelOnly.Environment(); !reflect.DeepEqual(got, map[string]string{LogLevelEnv: "warn"}) {
+		t.Errorf("want only LOG_LEVEL, got: %v", got)
+	}
+}
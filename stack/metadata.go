@@ -0,0 +1,97 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+// Annotations which hold a function's metadata once it is deployed
+const (
+	OwnerAnnotation      = "com.openfaas.metadata.owner"
+	SlackAnnotation      = "com.openfaas.metadata.slack"
+	RepositoryAnnotation = "com.openfaas.metadata.repository"
+	RunbookAnnotation    = "com.openfaas.metadata.runbook"
+	ReadmeAnnotation     = "com.openfaas.metadata.readme"
+)
+
+// Metadata tells on-call engineers who owns a function and where to find out
+// more about it, it is deployed as annotations so that list and describe can
+// show it
+type Metadata struct {
+	// Owner is the team or person who owns the function
+	Owner string `yaml:"owner,omitempty" json:"owner,omitempty"`
+
+	// Slack is the channel to ask about the function, i.e. #payments-oncall
+	Slack string `yaml:"slack,omitempty" json:"slack,omitempty"`
+
+	// Repository is the URL of the function's source
+	Repository string `yaml:"repository,omitempty" json:"repository,omitempty"`
+
+	// Runbook is the URL of the steps to follow when the function fails
+	Runbook string `yaml:"runbook,omitempty" json:"runbook,omitempty"`
+
+	// Readme is the URL of the function's or the stack's README
+	Readme string `yaml:"readme,omitempty" json:"readme,omitempty"`
+}
+
+// fields pairs each field of the metadata with its annotation
+func (m *Metadata) fields() map[string]*string {
+	return map[string]*string{
+		OwnerAnnotation:      &m.Owner,
+		SlackAnnotation:      &m.Slack,
+		RepositoryAnnotation: &m.Repository,
+		RunbookAnnotation:    &m.Runbook,
+		ReadmeAnnotation:     &m.Readme,
+	}
+}
+
+// Annotations gives the annotation of each field which is set
+func (m *Metadata) Annotations() map[string]string {
+	annotations := map[string]string{}
+	if m == nil {
+		return annotations
+	}
+	for annotation, value := range m.fields() {
+		if len(*value) > 0 {
+			annotations[annotation] = *value
+		}
+	}
+	return annotations
+}
+
+// MetadataFromAnnotations reads the metadata of a deployed function, it is
+// nil when none was deployed
+func MetadataFromAnnotations(annotations map[string]string) *Metadata {
+	metadata := &Metadata{}
+	found := false
+	for annotation, value := range metadata.fields() {
+		if annotationValue, ok := annotations[annotation]; ok && len(annotationValue) > 0 {
+			*value = annotationValue
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return metadata
+}
+
+// applyMetadata fills in the metadata of each function from the stack's, the
+// fields a function sets win
+func applyMetadata(services *Services) {
+	if services.Metadata == nil {
+		return
+	}
+
+	for name, function := range services.Functions {
+		metadata := *services.Metadata
+		if function.Metadata != nil {
+			own := function.Metadata.fields()
+			for annotation, value := range metadata.fields() {
+				if len(*own[annotation]) > 0 {
+					*value = *own[annotation]
+				}
+			}
+		}
+		function.Metadata = &metadata
+		services.Functions[name] = function
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_ParseYAMLData_Metadata(t *testing.T) {
+	services, err := ParseYAMLData([]byte(`provider:
+  name: faas
+metadata:
+  owner: payments
+  slack: "#payments-oncall"
+  readme: https://github.com/acme/payments#readme
+functions:
+  api:
+    image: acme/api:0.1
+    metadata:
+      owner: checkout
+      runbook: https://wiki.example.com/api
+  worker:
+    image: acme/worker:0.1
+`), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := map[string]string{
+		OwnerAnnotation:   "checkout",
+		SlackAnnotation:   "#payments-oncall",
+		RunbookAnnotation: "https://wiki.example.com/api",
+		ReadmeAnnotation:  "https://github.com/acme/payments#readme",
+	}
+	if got := services.Functions["api"].Metadata.Annotations(); !reflect.DeepEqual(got, want) {
+		t.Errorf("want the function's metadata over the stack's, got %v", got)
+	}
+	if got := services.Functions["worker"].Metadata; got == nil || got.Owner != "payments" {
+		t.Errorf("want the stack's metadata for worker, got %+v", got)
+	}
+}
+
+func Test_MetadataFromAnnotations(t *testing.T) {
+	if metadata := MetadataFromAnnotations(map[string]string{"topic": "banners"}); metadata != nil {
+		t.Errorf("want no metadata, got %+v", metadata)
+	}
+
+	metadata := MetadataFromAnnotations(map[string]string{OwnerAnnotation: "payments", RepositoryAnnotation: "https://github.com/acme/api"})
+	if want := (&Metadata{Owner: "payments", Repository: "https://github.com/acme/api"}); !reflect.DeepEqual(metadata, want) {
+		t.Errorf("want %+v, got %+v", want, metadata)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DeploymentOrder sorts the functions so that each one comes after those in
+// its depends_on, names are sorted otherwise. Dependencies which are not in
+// functions, such as those left out by --filter, are ignored.
+func DeploymentOrder(functions map[string]Function) ([]string, error) {
+	names := make([]string, 0, len(functions))
+	for name := range functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[string]int{}
+	var order []string
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("depends_on forms a cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+
+		dependencies := append([]string{}, functions[name].DependsOn...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			if _, ok := functions[dependency]; !ok {
+				continue
+			}
+			if err := visit(dependency, append(path, name)); err != nil {
+				return err
+			}
+		}
+
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,58 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_DeploymentOrder(t *testing.T) {
+	functions := map[string]Function{
+		"api":     {DependsOn: []string{"db", "cache"}},
+		"cache":   {},
+		"db":      {DependsOn: []string{"secrets"}},
+		"web":     {DependsOn: []string{"api"}},
+		"cleanup": {},
+	}
+
+	got, err := DeploymentOrder(functions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// secrets was left out by a filter so it is ignored
+	want := []string{"cache", "db", "api", "cleanup", "web"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func Test_DeploymentOrder_Cycle(t *testing.T) {
+	functions := map[string]Function{
+		"a": {DependsOn: []string{"b"}},
+		"b": {DependsOn: []string{"c"}},
+		"c": {DependsOn: []string{"a"}},
+	}
+
+	_, err := DeploymentOrder(functions)
+	if err == nil || err.Error() != "depends_on forms a cycle: a -> b -> c -> a" {
+		t.Errorf("want the cycle in the error, got %v", err)
+	}
+}
+
+func Test_ParseYAMLData_UnknownDependency(t *testing.T) {
+	yaml := `provider:
+  name: faas
+functions:
+  api:
+    image: api
+    depends_on:
+      - db
+`
+	_, err := ParseYAMLData([]byte(yaml), "", "")
+	if err == nil || !strings.Contains(err.Error(), "function api: depends_on db, which is not a function in the YAML file") {
+		t.Errorf("want an error for the unknown dependency, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// OverlayFiles are merged over the YAML file in order, they are given by
+// repeating -f, i.e. -f stack.yml -f prod.yml
+var OverlayFiles []string
+
+// mergeOverlays reads each of the OverlayFiles and merges it over data, the
+// stack read from location. Environment variables are expanded in the merged
+// stack, so they are only expanded once.
+func mergeOverlays(data []byte, location string) ([]byte, error) {
+	var merged interface{}
+	if err := yaml.Unmarshal(data, &merged); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", location, err)
+	}
+
+	for _, overlayFile := range OverlayFiles {
+		overlayData, err := readExtends(overlayFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %s", overlayFile, err)
+		}
+
+		var overlay interface{}
+		if err := yaml.Unmarshal(overlayData, &overlay); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %s", overlayFile, err)
+		}
+		merged = mergeYAML(merged, overlay)
+	}
+	return yaml.Marshal(merged)
+}
+
+// mergeYAML merges overlay over base as docker-compose does, maps such as
+// functions, environment and labels are merged key by key and any other value
+// in the overlay, including a list, replaces the one in base
+func mergeYAML(base interface{}, overlay interface{}) interface{} {
+	baseMap, baseIsMap := base.(map[interface{}]interface{})
+	overlayMap, overlayIsMap := overlay.(map[interface{}]interface{})
+	if !baseIsMap || !overlayIsMap {
+		if overlay == nil {
+			return base
+		}
+		return overlay
+	}
+
+	merged := make(map[interface{}]interface{}, len(baseMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overlayMap {
+		merged[key] = mergeYAML(baseMap[key], value)
+	}
+	return merged
+}
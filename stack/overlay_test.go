@@ -0,0 +1,105 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_ParseYAMLFile_OverlayFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(base, []byte(`provider:
+  name: faas
+  gateway: http://127.0.0.1:8080
+functions:
+  api:
+    lang: node18
+    image: acme/api:0.1
+    environment:
+      REGION: eu
+      LOG_LEVEL: info
+    labels:
+      team: payments
+    secrets:
+      - db-password
+  worker:
+    image: acme/worker:0.1
+`), 0600)
+	staging := filepath.Join(dir, "staging.yml")
+	ioutil.WriteFile(staging, []byte(`provider:
+  gateway: https://staging.example.com
+functions:
+  api:
+    environment:
+      LOG_LEVEL: debug
+    secrets:
+      - staging-db-password
+`), 0600)
+	canary := filepath.Join(dir, "canary.yml")
+	ioutil.WriteFile(canary, []byte(`functions:
+  api:
+    image: acme/api:0.2
+    labels:
+      canary: "true"
+  reporter:
+    image: acme/reporter:0.1
+`), 0600)
+
+	OverlayFiles = []string{staging, canary}
+	defer func() { OverlayFiles = nil }()
+
+	services, err := ParseYAMLFile(base, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if services.Provider.GatewayURL != "https://staging.example.com" || services.Provider.Name != "faas" {
+		t.Errorf("want the provider merged, got %+v", services.Provider)
+	}
+
+	api := services.Functions["api"]
+	if api.Image != "acme/api:0.2" || api.Language != "node18" {
+		t.Errorf("want the later image and the base lang, got %s and %s", api.Image, api.Language)
+	}
+	if want := map[string]string{"REGION": "eu", "LOG_LEVEL": "debug"}; !reflect.DeepEqual(api.Environment, want) {
+		t.Errorf("want the environment merged, got %v", api.Environment)
+	}
+	if want := map[string]string{"team": "payments", "canary": "true"}; !reflect.DeepEqual(*api.Labels, want) {
+		t.Errorf("want the labels merged, got %v", *api.Labels)
+	}
+	if want := []string{"staging-db-password"}; !reflect.DeepEqual(api.Secrets, want) {
+		t.Errorf("want a list replaced, got %v", api.Secrets)
+	}
+	if len(services.Functions) != 3 {
+		t.Errorf("want the functions of every file, got %d", len(services.Functions))
+	}
+}
+
+func Test_ParseYAMLFile_MissingOverlay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-overlay")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	base := filepath.Join(dir, "stack.yml")
+	ioutil.WriteFile(base, []byte("provider:\n  name: faas\n"), 0600)
+
+	OverlayFiles = []string{filepath.Join(dir, "prod.yml")}
+	defer func() { OverlayFiles = nil }()
+
+	if _, err := ParseYAMLFile(base, "", ""); err == nil {
+		t.Errorf("want an error for a missing overlay")
+	}
+}
@@ -0,0 +1,34 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// validatePipelines checks each step of a pipeline invokes a function in the
+// stack and gives its query as KEY=VALUE
+func validatePipelines(services Services) error {
+	for name, pipeline := range services.Pipelines {
+		if len(pipeline.Steps) == 0 {
+			return fmt.Errorf("pipeline %s has no steps", name)
+		}
+
+		for i, step := range pipeline.Steps {
+			if len(step.Function) == 0 {
+				return fmt.Errorf("pipeline %s: step %d needs a function", name, i+1)
+			}
+			if _, ok := services.Functions[step.Function]; !ok {
+				return fmt.Errorf("pipeline %s: step %d invokes %s, which is not a function in the YAML file", name, i+1, step.Function)
+			}
+			for _, query := range step.Query {
+				if !strings.Contains(query, "=") {
+					return fmt.Errorf("pipeline %s: step %d query %q must be KEY=VALUE", name, i+1, query)
+				}
+			}
+		}
+	}
+	return nil
+}
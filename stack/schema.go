@@ -3,68 +3,345 @@
 
 package stack
 
+import "github.com/openfaas/faas-cli/policy"
+
 // Provider for the FaaS set of functions.
 type Provider struct {
-	Name       string `yaml:"name"`
-	GatewayURL string `yaml:"gateway"`
-	Network    string `yaml:"network"`
+	Name       string `yaml:"name,omitempty"`
+	GatewayURL string `yaml:"gateway,omitempty"`
+	Network    string `yaml:"network,omitempty"`
 }
 
 // Function as deployed or built on FaaS
 type Function struct {
 	// Name of deployed function
 	Name     string `yaml:"-"`
-	Language string `yaml:"lang"`
+	Language string `yaml:"lang,omitempty"`
 
 	// Handler Local folder to use for function
-	Handler string `yaml:"handler"`
+	Handler string `yaml:"handler,omitempty"`
 
 	// Image Docker image name
-	Image string `yaml:"image"`
+	Image string `yaml:"image,omitempty"`
 
-	FProcess string `yaml:"fprocess"`
+	FProcess string `yaml:"fprocess,omitempty"`
+
+	// Namespace the function is deployed to, overriding --namespace
+	Namespace string `yaml:"namespace,omitempty"`
 
-	Environment map[string]string `yaml:"environment"`
+	Environment map[string]string `yaml:"environment,omitempty"`
 
 	// Secrets list of secrets to be made available to function
-	Secrets []string `yaml:"secrets"`
+	Secrets []string `yaml:"secrets,omitempty"`
+
+	SkipBuild bool `yaml:"skip_build,omitempty"`
+
+	// BuildArgs are passed to the Docker build with --build-arg
+	BuildArgs map[string]string `yaml:"build_args,omitempty"`
+
+	// BuildOptions are the names of options or groups of options, defined by
+	// the template or the stack, which add packages and build-args to the build
+	BuildOptions []string `yaml:"build_options,omitempty"`
 
-	SkipBuild bool `yaml:"skip_build"`
+	// Matrix of build-arg values, one image is built and tagged for each combination
+	Matrix map[string][]string `yaml:"matrix,omitempty"`
 
-	Constraints *[]string `yaml:"constraints"`
+	// Platforms to build the image for i.e. linux/amd64 and linux/arm/v7, the
+	// images are pushed under a single multi-arch manifest
+	Platforms []string `yaml:"platforms,omitempty"`
+
+	Constraints *[]string `yaml:"constraints,omitempty"`
 
 	// EnvironmentFile is a list of files to import and override environmental variables.
 	// These are overriden in order.
-	EnvironmentFile []string `yaml:"environment_file"`
+	EnvironmentFile []string `yaml:"environment_file,omitempty"`
+
+	Labels *map[string]string `yaml:"labels,omitempty"`
 
-	Labels *map[string]string `yaml:"labels"`
+	// Annotations are metadata for functions which are read by the provider
+	// but are not used for scheduling or routing
+	Annotations *map[string]string `yaml:"annotations,omitempty"`
 
 	// Limits for function
-	Limits *FunctionResources `yaml:"limits"`
+	Limits *FunctionResources `yaml:"limits,omitempty"`
 
 	// Requests of resources requested by function
-	Requests *FunctionResources `yaml:"requests"`
+	Requests *FunctionResources `yaml:"requests,omitempty"`
+
+	// Canary settings used when promoting a canary of the function
+	Canary *Canary `yaml:"canary,omitempty"`
+
+	// HealthCheck overrides how the function's readiness is probed
+	HealthCheck *HealthCheck `yaml:"healthcheck,omitempty"`
+
+	// Auth is used by invoke for a function behind its own authentication,
+	// it is separate from the gateway's credentials
+	Auth *FunctionAuth `yaml:"auth,omitempty"`
+
+	// DependsOn names the functions which deploy --ordered makes ready
+	// before deploying this one
+	DependsOn []string `yaml:"depends_on,omitempty"`
+
+	// Logging sets the function's LOG_LEVEL and LOG_FORMAT
+	Logging *Logging `yaml:"logging,omitempty"`
+
+	// Build options for the function's image
+	Build *FunctionBuild `yaml:"build,omitempty"`
+
+	// RegistryCredential names one of the registries of the CLI's config
+	// file, used to push the image and to pull it with the provider's secret
+	RegistryCredential string `yaml:"registry_credential,omitempty"`
+
+	// Metadata about who owns the function, fields it doesn't set are taken
+	// from the stack's metadata
+	Metadata *Metadata `yaml:"metadata,omitempty"`
+}
+
+// FunctionBuild holds the options for building a function's image
+type FunctionBuild struct {
+	// CacheFrom are images whose layers are used as a build cache, i.e. the
+	// image last pushed by CI
+	CacheFrom []string `yaml:"cache_from,omitempty"`
+
+	// Profile names a build profile from the CLI's config file
+	Profile string `yaml:"profile,omitempty"`
+
+	// Group names one of the stack's build_groups, which orders the build and
+	// caps how many of its functions are built at once
+	Group string `yaml:"group,omitempty"`
+}
+
+// BuildGroup schedules the builds of the functions in it
+type BuildGroup struct {
+	// Priority orders the groups, the functions of higher priority groups are
+	// built first
+	Priority int `yaml:"priority,omitempty"`
+
+	// Concurrency caps how many of the group's functions are built at once,
+	// --parallel is the only cap when it is 0
+	Concurrency int `yaml:"concurrency,omitempty"`
+
+	// RegistryCredential is used by the group's functions which don't name
+	// their own
+	RegistryCredential string `yaml:"registry_credential,omitempty"`
+}
+
+// Authentication types for invoking a function
+const (
+	AuthNone   = "none"
+	AuthBasic  = "basic"
+	AuthBearer = "bearer"
+	AuthHMAC   = "hmac"
+)
+
+// FunctionAuth holds the credentials for invoking a function, values may
+// reference environment variables such as ${API_TOKEN}
+type FunctionAuth struct {
+	// Type is basic, bearer, hmac or none
+	Type string `yaml:"type,omitempty"`
+
+	// Username and Password for basic auth
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+
+	// Token sent as a bearer token
+	Token string `yaml:"token,omitempty"`
+
+	// Key used to sign the request body for hmac
+	Key string `yaml:"key,omitempty"`
+
+	// Header carrying the hmac signature, defaults to X-Hub-Signature
+	Header string `yaml:"header,omitempty"`
+
+	// Algorithm for hmac, sha1 or sha256, defaults to sha1
+	Algorithm string `yaml:"algorithm,omitempty"`
+}
+
+// HealthCheck for a function, durations are given as i.e. 2s or 1m
+type HealthCheck struct {
+	// Path of the health endpoint, defaults to /_/health
+	Path string `yaml:"path,omitempty"`
+
+	// Interval between probes
+	Interval string `yaml:"interval,omitempty"`
+
+	// InitialDelay before the first probe
+	InitialDelay string `yaml:"initial_delay,omitempty"`
+}
+
+// Canary settings of a function: its share of traffic when deployed with
+// deploy --canary, and the thresholds checked by analysis before a canary
+// is promoted
+type Canary struct {
+	// Weight is the percentage of the function's traffic routed to its canary,
+	// it takes the place of the value given to deploy --canary
+	Weight int `yaml:"weight,omitempty"`
+
+	// MaxErrorRate is the highest ratio of 5xx responses allowed, i.e. 0.05
+	MaxErrorRate float64 `yaml:"max_error_rate,omitempty"`
+
+	// MaxP95Latency is the highest 95th percentile latency allowed, i.e. 500ms
+	MaxP95Latency string `yaml:"max_p95_latency,omitempty"`
 }
 
 // FunctionResources Memory and CPU
 type FunctionResources struct {
-	Memory string `yaml:"memory"`
-	CPU    string `yaml:"cpu"`
+	Memory string `yaml:"memory,omitempty"`
+	CPU    string `yaml:"cpu,omitempty"`
 }
 
 // EnvironmentFile represents external file for environment data
 type EnvironmentFile struct {
-	Environment map[string]string `yaml:"environment"`
+	Environment map[string]string `yaml:"environment,omitempty"`
+}
+
+// BaseImage is a shared image built ahead of the functions in the stack and
+// made available to their Dockerfiles as a build-arg
+type BaseImage struct {
+	// Name of the base image, exposed to functions as an upper-case build-arg
+	Name string `yaml:"name,omitempty"`
+
+	// Image Docker image name to tag the base image with, defaults to Name
+	Image string `yaml:"image,omitempty"`
+
+	// Dockerfile relative to Context, defaults to Dockerfile
+	Dockerfile string `yaml:"dockerfile,omitempty"`
+
+	// Context folder sent to the Docker build, defaults to the current folder
+	Context string `yaml:"context,omitempty"`
 }
 
 // Services root level YAML file to define FaaS function-set
 type Services struct {
 	Functions map[string]Function `yaml:"functions,omitempty"`
 	Provider  Provider            `yaml:"provider,omitempty"`
+
+	// BaseImages are built in order before any function
+	BaseImages []BaseImage `yaml:"base_images,omitempty"`
+
+	// Extends is a stack whose provider, defaults and policy are inherited,
+	// given as a path, an http(s) URL or a git repository
+	Extends string `yaml:"extends,omitempty"`
+
+	// Defaults are applied to every function in the stack
+	Defaults *Defaults `yaml:"defaults,omitempty"`
+
+	// Policy is used when there is no .faas-policy.yml in the current folder
+	Policy *policy.Policy `yaml:"policy,omitempty"`
+
+	// Pipelines chain the functions in the stack, see faas-cli pipeline run
+	Pipelines map[string]Pipeline `yaml:"pipelines,omitempty"`
+
+	// BuildOptions extend those of each template, an option defined by both
+	// has the packages and build-args of both
+	BuildOptions []BuildOption `yaml:"build_options,omitempty"`
+
+	// BuildGroups order and throttle the build, see FunctionBuild.Group
+	BuildGroups map[string]BuildGroup `yaml:"build_groups,omitempty"`
+
+	// Configuration of the tools which work on the stack
+	Configuration *Configuration `yaml:"configuration,omitempty"`
+
+	// Metadata about who owns the functions of the stack
+	Metadata *Metadata `yaml:"metadata,omitempty"`
+}
+
+// Configuration of the stack which isn't about any one function
+type Configuration struct {
+	// Templates are pulled from their sources by build when they are missing
+	Templates []TemplateSource `yaml:"templates,omitempty"`
+}
+
+// TemplateSource is where a template is pulled from, recorded by template
+// pull -f so that builds use the same version of the template
+type TemplateSource struct {
+	Name string `yaml:"name"`
+
+	// Source is a git repository with the branch, tag or commit to check out
+	// after a #, i.e. https://github.com/acme/templates#0a1b2c3
+	Source string `yaml:"source"`
+
+	// Path of the folder holding the templates in the repository, defaults
+	// to template
+	Path string `yaml:"path,omitempty"`
+}
+
+// Pipeline invokes its steps in order, each step is given the response of
+// the one before it
+type Pipeline struct {
+	Steps []PipelineStep `yaml:"steps"`
+}
+
+// PipelineStep is one invocation in a pipeline
+type PipelineStep struct {
+	Function string `yaml:"function"`
+
+	// ContentType of the request, defaults to text/plain
+	ContentType string `yaml:"content_type,omitempty"`
+
+	// Query string parameters as KEY=VALUE
+	Query []string `yaml:"query,omitempty"`
+}
+
+// Defaults for the functions in a stack, a function's own settings win and
+// its maps are merged over those of the defaults
+type Defaults struct {
+	Environment map[string]string `yaml:"environment,omitempty"`
+
+	// Secrets are added to those of each function
+	Secrets []string `yaml:"secrets,omitempty"`
+
+	Constraints *[]string          `yaml:"constraints,omitempty"`
+	Labels      *map[string]string `yaml:"labels,omitempty"`
+	Annotations *map[string]string `yaml:"annotations,omitempty"`
+
+	Limits      *FunctionResources `yaml:"limits,omitempty"`
+	Requests    *FunctionResources `yaml:"requests,omitempty"`
+	HealthCheck *HealthCheck       `yaml:"healthcheck,omitempty"`
+	Logging     *Logging           `yaml:"logging,omitempty"`
 }
 
 // LanguageTemplate read from template.yml within root of a language template folder
 type LanguageTemplate struct {
 	Language string `yaml:"language"`
 	FProcess string `yaml:"fprocess"`
+
+	// Watchdog is classic or of-watchdog, when it is not given it is
+	// detected from the template's Dockerfile
+	Watchdog string `yaml:"watchdog"`
+
+	// WatchdogMode is the of-watchdog's mode: http, streaming, serializing or static
+	WatchdogMode string `yaml:"watchdog_mode"`
+
+	// BuildInfo is the path in the build context that build --build-info
+	// writes its JSON to, defaults to function/version.json
+	BuildInfo string `yaml:"build_info"`
+
+	// BuildOptions can be chosen by functions to add packages to the build
+	BuildOptions []BuildOption `yaml:"build_options"`
+
+	// FProcessBuildArg is the build-arg the template's Dockerfile sets
+	// fprocess from, which a function's own fprocess is given in. When it is
+	// not given an ARG named fprocess or FPROCESS in the Dockerfile is used
+	FProcessBuildArg string `yaml:"fprocess_build_arg"`
+
+	// FProcessPattern is a regular expression a function's own fprocess must
+	// match, such as ^node  for a template which only runs Node.js
+	FProcessPattern string `yaml:"fprocess_pattern"`
+}
+
+// BuildOption adds packages and build-args to a build, it is chosen by name
+// with a function's build_options or --build-option
+type BuildOption struct {
+	Name string `yaml:"name"`
+
+	// Packages are given to the template's Dockerfile, space-separated, in the
+	// ADDITIONAL_PACKAGE build-arg
+	Packages []string `yaml:"packages,omitempty"`
+
+	BuildArgs map[string]string `yaml:"build_args,omitempty"`
+
+	// Options makes this a group, the options named are applied before its
+	// own packages and build-args
+	Options []string `yaml:"options,omitempty"`
 }
@@ -5,10 +5,12 @@ package stack
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
 	"regexp"
 	"time"
 
@@ -18,10 +20,35 @@ import (
 
 const providerName = "faas"
 
+var validPlatform = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9_]+(/[a-z0-9]+)?$`)
+
+// StdinFile is given as the YAML file to read the stack from stdin
+const StdinFile = "-"
+
+// Stdin is read when the YAML file is StdinFile
+var Stdin io.Reader = os.Stdin
+
+// stdinData keeps the stack read from Stdin, which can only be read once, for
+// commands which parse the stack more than once
+var stdinData []byte
+
 // ParseYAMLData parse YAML file into a stack of "services".
 func ParseYAMLFile(yamlFile, regex, filter string) (*Services, error) {
 	var err error
 	var fileData []byte
+	if yamlFile == StdinFile {
+		fileData, err = readStdin()
+		if err != nil {
+			return nil, err
+		}
+		if len(OverlayFiles) > 0 {
+			if fileData, err = mergeOverlays(fileData, yamlFile); err != nil {
+				return nil, err
+			}
+		}
+		return parseYAMLData(fileData, yamlFile, regex, filter)
+	}
+
 	urlParsed, err := url.Parse(yamlFile)
 	if err == nil && len(urlParsed.Scheme) > 0 {
 		fmt.Println("Parsed: " + urlParsed.String())
@@ -35,21 +62,45 @@ func ParseYAMLFile(yamlFile, regex, filter string) (*Services, error) {
 			return nil, err
 		}
 	}
-	return ParseYAMLData(fileData, regex, filter)
+
+	if len(OverlayFiles) > 0 {
+		if fileData, err = mergeOverlays(fileData, yamlFile); err != nil {
+			return nil, err
+		}
+	}
+	return parseYAMLData(fileData, yamlFile, regex, filter)
 }
 
 // ParseYAMLData parse YAML data into a stack of "services".
 func ParseYAMLData(fileData []byte, regex string, filter string) (*Services, error) {
+	return parseYAMLData(fileData, "", regex, filter)
+}
+
+// parseYAMLData parses a stack read from location, which a relative extends
+// is resolved from
+func parseYAMLData(fileData []byte, location string, regex string, filter string) (*Services, error) {
 	var services Services
 	regexExists := len(regex) > 0
 	filterExists := len(filter) > 0
 
+	if EnvSubst {
+		substituted, err := SubstituteEnvironment(fileData)
+		if err != nil {
+			return nil, err
+		}
+		fileData = substituted
+	}
+
 	err := yaml.Unmarshal(fileData, &services)
 	if err != nil {
 		fmt.Printf("Error with YAML file\n")
 		return nil, err
 	}
 
+	if err := resolveExtends(&services, location, nil); err != nil {
+		return nil, err
+	}
+
 	for _, f := range services.Functions {
 		if f.Language == "Dockerfile" {
 			f.Language = "dockerfile"
@@ -60,6 +111,67 @@ func ParseYAMLData(fileData []byte, regex string, filter string) (*Services, err
 		return nil, fmt.Errorf("'%s' is the only valid provider for this tool - found: %s", providerName, services.Provider.Name)
 	}
 
+	if services.Policy != nil {
+		if err := services.Policy.Validate(); err != nil {
+			return nil, fmt.Errorf("policy: %s", err)
+		}
+	}
+
+	if services.Defaults != nil && services.Defaults.Logging != nil {
+		if err := services.Defaults.Logging.validate(); err != nil {
+			return nil, fmt.Errorf("defaults: %s", err)
+		}
+	}
+	applyDefaults(&services)
+	applyMetadata(&services)
+
+	baseImageNames := make(map[string]bool)
+	for _, baseImage := range services.BaseImages {
+		if len(baseImage.Name) == 0 {
+			return nil, fmt.Errorf("each entry in base_images needs a name")
+		}
+		if baseImageNames[baseImage.Name] {
+			return nil, fmt.Errorf("base image %s is defined more than once", baseImage.Name)
+		}
+		baseImageNames[baseImage.Name] = true
+	}
+
+	for name, function := range services.Functions {
+		for _, platform := range function.Platforms {
+			if !validPlatform.MatchString(platform) {
+				return nil, fmt.Errorf("function %s: platform %q must be given as os/arch or os/arch/variant", name, platform)
+			}
+		}
+
+		if function.Auth != nil {
+			switch function.Auth.Type {
+			case AuthNone, AuthBasic, AuthBearer, AuthHMAC:
+			default:
+				return nil, fmt.Errorf("function %s: auth type %q must be one of basic, bearer, hmac or none", name, function.Auth.Type)
+			}
+		}
+
+		if function.Logging != nil {
+			if err := function.Logging.validate(); err != nil {
+				return nil, fmt.Errorf("function %s: %s", name, err)
+			}
+		}
+
+		for _, dependency := range function.DependsOn {
+			if _, ok := services.Functions[dependency]; !ok {
+				return nil, fmt.Errorf("function %s: depends_on %s, which is not a function in the YAML file", name, dependency)
+			}
+		}
+	}
+
+	if _, err := DeploymentOrder(services.Functions); err != nil {
+		return nil, err
+	}
+
+	if err := validatePipelines(services); err != nil {
+		return nil, err
+	}
+
 	if regexExists && filterExists {
 		return nil, fmt.Errorf("pass in a regex or a filter, not both")
 	}
@@ -115,6 +227,22 @@ func makeHTTPClient(timeout *time.Duration) http.Client {
 	return http.Client{}
 }
 
+func readStdin() ([]byte, error) {
+	if stdinData != nil {
+		return stdinData, nil
+	}
+
+	data, err := ioutil.ReadAll(Stdin)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the stack from stdin: %s", err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("no stack was given on stdin")
+	}
+	stdinData = data
+	return stdinData, nil
+}
+
 // fetchYAML pulls in file from remote location such as GitHub raw file-view
 func fetchYAML(address *url.URL) ([]byte, error) {
 	req, err := http.NewRequest(http.MethodGet, address.String(), nil)
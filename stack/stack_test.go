@@ -4,6 +4,7 @@
 package stack
 
 import (
+	"os"
 	"reflect"
 	"sort"
 	"strings"
@@ -322,3 +323,221 @@ func Test_ParseYAMLDataFilterAndRegex(t *testing.T) {
 		t.Errorf("Test_ParseYAMLDataFilterAndRegex test failed, expected error not thrown")
 	}
 }
+
+func Test_ParseYAMLData_BaseImages(t *testing.T) {
+	stackYAML := `provider:
+  name: faas
+
+base_images:
+  - name: python-base
+    image: acme/python-base:1.0
+    dockerfile: Dockerfile.python
+    context: ./base
+  - name: node-base
+
+functions:
+  url-ping:
+    lang: python
+    handler: ./sample/url-ping
+    image: alexellis/faas-url-ping
+`
+
+	parsedYAML, err := ParseYAMLData([]byte(stackYAML), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []BaseImage{
+		{Name: "python-base", Image: "acme/python-base:1.0", Dockerfile: "Dockerfile.python", Context: "./base"},
+		{Name: "node-base"},
+	}
+
+	if !reflect.DeepEqual(parsedYAML.BaseImages, expected) {
+		t.Errorf("want: %+v, got: %+v", expected, parsedYAML.BaseImages)
+	}
+
+	duplicateYAML := `provider:
+  name: faas
+
+base_images:
+  - name: python-base
+  - name: python-base
+`
+	if _, err := ParseYAMLData([]byte(duplicateYAML), "", ""); err == nil {
+		t.Errorf("expected an error for a duplicate base image")
+	}
+}
+
+func Test_ParseYAMLData_Platforms(t *testing.T) {
+	stackYAML := `provider:
+  name: faas
+
+functions:
+  url-ping:
+    lang: python
+    handler: ./sample/url-ping
+    image: alexellis/faas-url-ping
+    platforms:
+      - linux/amd64
+      - linux/arm/v7
+`
+
+	parsedYAML, err := ParseYAMLData([]byte(stackYAML), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"linux/amd64", "linux/arm/v7"}
+	if !reflect.DeepEqual(parsedYAML.Functions["url-ping"].Platforms, expected) {
+		t.Errorf("want: %v, got: %v", expected, parsedYAML.Functions["url-ping"].Platforms)
+	}
+
+	invalidYAML := strings.Replace(stackYAML, "linux/arm/v7", "armhf", 1)
+	if _, err := ParseYAMLData([]byte(invalidYAML), "", ""); err == nil {
+		t.Errorf("expected an error for a platform without an os")
+	}
+}
+
+func Test_ParseYAMLData_BuildCacheFrom(t *testing.T) {
+	stackYAML := `provider:
+  name: faas
+
+functions:
+  url-ping:
+    lang: python
+    handler: ./sample/url-ping
+    image: alexellis/faas-url-ping:0.2
+    build:
+      cache_from:
+        - alexellis/faas-url-ping:latest
+`
+
+	parsedYAML, err := ParseYAMLData([]byte(stackYAML), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"alexellis/faas-url-ping:latest"}
+	if got := parsedYAML.Functions["url-ping"].Build; got == nil || !reflect.DeepEqual(got.CacheFrom, expected) {
+		t.Errorf("want cache_from: %v, got: %+v", expected, got)
+	}
+}
+
+func Test_ParseYAMLData_Auth(t *testing.T) {
+	stackYAML := `provider:
+  name: faas
+
+functions:
+  webhook:
+    lang: go
+    handler: ./webhook
+    image: alexellis/webhook
+    auth:
+      type: hmac
+      key: ${WEBHOOK_KEY}
+      algorithm: sha256
+`
+
+	parsedYAML, err := ParseYAMLData([]byte(stackYAML), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := &FunctionAuth{Type: AuthHMAC, Key: "${WEBHOOK_KEY}", Algorithm: "sha256"}
+	if !reflect.DeepEqual(parsedYAML.Functions["webhook"].Auth, expected) {
+		t.Errorf("want: %+v, got: %+v", expected, parsedYAML.Functions["webhook"].Auth)
+	}
+
+	invalidYAML := strings.Replace(stackYAML, "type: hmac", "type: digest", 1)
+	if _, err := ParseYAMLData([]byte(invalidYAML), "", ""); err == nil {
+		t.Errorf("expected an error for an unknown auth type")
+	}
+}
+
+func Test_ParseYAMLData_Pipelines(t *testing.T) {
+	stackYAML := `provider:
+  name: faas
+
+functions:
+  fetch:
+    lang: go
+    handler: ./fetch
+    image: alexellis/fetch
+  resize:
+    lang: go
+    handler: ./resize
+    image: alexellis/resize
+
+pipelines:
+  thumbnail:
+    steps:
+      - function: fetch
+      - function: resize
+        content_type: image/png
+        query:
+          - width=200
+`
+
+	parsedYAML, err := ParseYAMLData([]byte(stackYAML), "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := Pipeline{Steps: []PipelineStep{
+		{Function: "fetch"},
+		{Function: "resize", ContentType: "image/png", Query: []string{"width=200"}},
+	}}
+	if !reflect.DeepEqual(parsedYAML.Pipelines["thumbnail"], expected) {
+		t.Errorf("want: %+v, got: %+v", expected, parsedYAML.Pipelines["thumbnail"])
+	}
+
+	cases := map[string]string{
+		"function: resize":    "invokes crop, which is not a function",
+		"- width=200":         "must be KEY=VALUE",
+		"- function: fetch\n": "step 1 needs a function",
+	}
+	replacements := map[string]string{
+		"function: resize":    "function: crop",
+		"- width=200":         "- width",
+		"- function: fetch\n": "- content_type: text/plain\n",
+	}
+	for old, want := range cases {
+		invalidYAML := strings.Replace(stackYAML, old, replacements[old], 1)
+		if _, err := ParseYAMLData([]byte(invalidYAML), "", ""); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want an error containing %q, got: %v", want, err)
+		}
+	}
+}
+
+func Test_ParseYAMLFile_Stdin(t *testing.T) {
+	defer func() {
+		Stdin = os.Stdin
+		stdinData = nil
+	}()
+
+	Stdin = strings.NewReader(TestData_1)
+	stdinData = nil
+
+	first, err := ParseYAMLFile(StdinFile, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// The stack is kept for commands which parse it again
+	second, err := ParseYAMLFile(StdinFile, "", "")
+	if err != nil {
+		t.Fatalf("unexpected error parsing stdin again: %s", err)
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("want the same stack from both reads, got: %+v and %+v", first, second)
+	}
+	if _, ok := first.Functions["url-ping"]; !ok {
+		t.Errorf("want url-ping in the stack, got: %v", first.Functions)
+	}
+
+	Stdin = strings.NewReader("")
+	stdinData = nil
+	if _, err := ParseYAMLFile(StdinFile, "", ""); err == nil {
+		t.Errorf("expected an error for an empty stdin")
+	}
+}
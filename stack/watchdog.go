@@ -0,0 +1,178 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Watchdogs which serve a template's functions
+const (
+	ClassicWatchdog = "classic"
+	OfWatchdog      = "of-watchdog"
+)
+
+// Modes of the of-watchdog
+const (
+	HTTPMode        = "http"
+	StreamingMode   = "streaming"
+	SerializingMode = "serializing"
+	StaticMode      = "static"
+)
+
+// Watchdog is the watchdog a template uses and the mode it runs in
+type Watchdog struct {
+	// Type is classic or of-watchdog
+	Type string
+
+	// Mode of the of-watchdog, empty for the classic watchdog
+	Mode string
+
+	// FProcessInImage is true when the template's Dockerfile sets fprocess
+	FProcessInImage bool
+
+	// Version of the watchdog the template's Dockerfile downloads, empty when
+	// it cannot be read from the Dockerfile
+	Version string
+}
+
+// Labels written to images built from a template, so that the watchdog can be
+// checked at deploy time without the template
+const (
+	TemplateLabel        = "com.openfaas.template"
+	WatchdogLabel        = "com.openfaas.watchdog"
+	WatchdogModeLabel    = "com.openfaas.watchdog.mode"
+	WatchdogVersionLabel = "com.openfaas.watchdog.version"
+)
+
+var (
+	dockerfileMode     = regexp.MustCompile(`(?m)^\s*ENV\s+.*\bmode="?([a-z]+)"?`)
+	dockerfileFProcess = regexp.MustCompile(`(?m)^\s*ENV\s+.*\bfprocess[= ]`)
+
+	// dockerfileWatchdogVersion matches the watchdog image, i.e.
+	// openfaas/of-watchdog:0.7.7, or a watchdog downloaded from a release
+	dockerfileWatchdogVersion = regexp.MustCompile(`(?:of-watchdog:|classic-watchdog:|/releases/download/)v?([0-9]+(?:\.[0-9]+)+)`)
+)
+
+// TemplateWatchdog reads the watchdog of a template from its template.yml,
+// falling back to the watchdog its Dockerfile downloads
+func TemplateWatchdog(language string) (*Watchdog, error) {
+	templatePath := filepath.Join(TemplateDirectory, language)
+
+	langTemplate, err := ParseYAMLForLanguageTemplate(filepath.Join(templatePath, "template.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	dockerfile, err := ioutil.ReadFile(filepath.Join(templatePath, "Dockerfile"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return detectWatchdog(langTemplate, string(dockerfile))
+}
+
+func detectWatchdog(langTemplate *LanguageTemplate, dockerfile string) (*Watchdog, error) {
+	watchdog := &Watchdog{
+		Type:            langTemplate.Watchdog,
+		Mode:            langTemplate.WatchdogMode,
+		FProcessInImage: dockerfileFProcess.MatchString(dockerfile),
+	}
+
+	if match := dockerfileWatchdogVersion.FindStringSubmatch(dockerfile); match != nil {
+		watchdog.Version = match[1]
+	}
+
+	if len(watchdog.Type) == 0 {
+		watchdog.Type = ClassicWatchdog
+		if strings.Contains(dockerfile, "of-watchdog") {
+			watchdog.Type = OfWatchdog
+		}
+	}
+
+	if watchdog.Type == OfWatchdog && len(watchdog.Mode) == 0 {
+		watchdog.Mode = HTTPMode
+		if match := dockerfileMode.FindStringSubmatch(dockerfile); match != nil {
+			watchdog.Mode = match[1]
+		}
+	}
+
+	return watchdog, watchdog.validate()
+}
+
+func (w *Watchdog) validate() error {
+	switch w.Type {
+	case ClassicWatchdog:
+		if len(w.Mode) > 0 {
+			return fmt.Errorf("watchdog_mode %q is only used by the of-watchdog", w.Mode)
+		}
+	case OfWatchdog:
+		switch w.Mode {
+		case HTTPMode, StreamingMode, SerializingMode, StaticMode:
+		default:
+			return fmt.Errorf("watchdog_mode %q must be one of http, streaming, serializing or static", w.Mode)
+		}
+	default:
+		return fmt.Errorf("watchdog %q must be classic or of-watchdog", w.Type)
+	}
+	return nil
+}
+
+// NeedsFProcess reports whether the watchdog starts a process for each
+// request or as its upstream, which is every mode except static
+func (w *Watchdog) NeedsFProcess() bool {
+	return w.Mode != StaticMode
+}
+
+// Streams reports whether responses are sent while the function is writing them
+func (w *Watchdog) Streams() bool {
+	return w.Type == OfWatchdog && w.Mode == StreamingMode
+}
+
+// CustomHealthPath reports whether the function itself can answer a
+// health check on a path other than the watchdog's own /_/health
+func (w *Watchdog) CustomHealthPath() bool {
+	return w.Type == OfWatchdog && w.Mode == HTTPMode
+}
+
+// Labels gives the image labels which record the watchdog of a template
+func (w *Watchdog) Labels(language string) map[string]string {
+	labels := map[string]string{
+		TemplateLabel: language,
+		WatchdogLabel: w.Type,
+	}
+	if len(w.Mode) > 0 {
+		labels[WatchdogModeLabel] = w.Mode
+	}
+	if len(w.Version) > 0 {
+		labels[WatchdogVersionLabel] = w.Version
+	}
+	return labels
+}
+
+// LabelledWatchdog reads the watchdog recorded in an image's labels, it is nil
+// for images not built from a template
+func LabelledWatchdog(labels map[string]string) *Watchdog {
+	watchdogType, ok := labels[WatchdogLabel]
+	if !ok || len(watchdogType) == 0 {
+		return nil
+	}
+	return &Watchdog{
+		Type:    watchdogType,
+		Mode:    labels[WatchdogModeLabel],
+		Version: labels[WatchdogVersionLabel],
+	}
+}
+
+func (w *Watchdog) String() string {
+	if len(w.Mode) == 0 {
+		return w.Type
+	}
+	return fmt.Sprintf("%s (%s)", w.Type, w.Mode)
+}
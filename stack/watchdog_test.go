@@ -0,0 +1,161 @@
+// Copyright (c) OpenFaaS Project 2018. All rights reserved.
+// Licensed under the MIT license. See LICENSE file in the project root for full license information.
+
+package stack
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const classicDockerfile = `FROM python:2.7-alpine
+RUN curl -sSL https://github.com/openfaas/faas/releases/download/0.6.9/fwatchdog > /usr/bin/fwatchdog
+ENV fprocess="python index.py"
+CMD ["fwatchdog"]
+`
+
+const ofWatchdogDockerfile = `FROM openfaas/of-watchdog:0.2.1 as watchdog
+FROM node:8-alpine
+COPY --from=watchdog /fwatchdog /usr/bin/fwatchdog
+ENV cgi_headers="true"
+ENV fprocess="node index.js"
+ENV mode="streaming"
+CMD ["fwatchdog"]
+`
+
+func Test_detectWatchdog(t *testing.T) {
+	cases := []struct {
+		name       string
+		template   LanguageTemplate
+		dockerfile string
+		want       Watchdog
+		wantErr    bool
+	}{
+		{
+			name:       "classic from the Dockerfile",
+			dockerfile: classicDockerfile,
+			want:       Watchdog{Type: ClassicWatchdog, FProcessInImage: true, Version: "0.6.9"},
+		},
+		{
+			name:       "of-watchdog with its mode from the Dockerfile",
+			dockerfile: ofWatchdogDockerfile,
+			want:       Watchdog{Type: OfWatchdog, Mode: StreamingMode, FProcessInImage: true, Version: "0.2.1"},
+		},
+		{
+			name:     "of-watchdog defaults to http",
+			template: LanguageTemplate{Watchdog: OfWatchdog},
+			want:     Watchdog{Type: OfWatchdog, Mode: HTTPMode},
+		},
+		{
+			name:       "template.yml takes precedence",
+			template:   LanguageTemplate{Watchdog: OfWatchdog, WatchdogMode: StaticMode},
+			dockerfile: ofWatchdogDockerfile,
+			want:       Watchdog{Type: OfWatchdog, Mode: StaticMode, FProcessInImage: true, Version: "0.2.1"},
+		},
+		{
+			name:     "unknown watchdog",
+			template: LanguageTemplate{Watchdog: "fwatchdog"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown mode",
+			template: LanguageTemplate{Watchdog: OfWatchdog, WatchdogMode: "afterburn"},
+			wantErr:  true,
+		},
+		{
+			name:     "mode for the classic watchdog",
+			template: LanguageTemplate{Watchdog: ClassicWatchdog, WatchdogMode: HTTPMode},
+			wantErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			watchdog, err := detectWatchdog(&c.template, c.dockerfile)
+			if c.wantErr {
+				if err == nil {
+					t.Fatalf("want an error, got %+v", watchdog)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(*watchdog, c.want) {
+				t.Errorf("want %+v, got %+v", c.want, *watchdog)
+			}
+		})
+	}
+}
+
+func Test_Watchdog_Defaults(t *testing.T) {
+	classic := Watchdog{Type: ClassicWatchdog}
+	streaming := Watchdog{Type: OfWatchdog, Mode: StreamingMode}
+	httpMode := Watchdog{Type: OfWatchdog, Mode: HTTPMode}
+	static := Watchdog{Type: OfWatchdog, Mode: StaticMode}
+
+	if classic.Streams() || !streaming.Streams() || httpMode.Streams() {
+		t.Errorf("only the of-watchdog in streaming mode streams responses")
+	}
+	if !classic.NeedsFProcess() || static.NeedsFProcess() {
+		t.Errorf("every mode but static needs an fprocess")
+	}
+	if classic.CustomHealthPath() || !httpMode.CustomHealthPath() {
+		t.Errorf("only the of-watchdog in http mode passes health checks to the function")
+	}
+	if streaming.String() != "of-watchdog (streaming)" || classic.String() != "classic" {
+		t.Errorf("unexpected names: %s, %s", streaming.String(), classic.String())
+	}
+}
+
+func Test_TemplateWatchdog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "faas-cli-watchdog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(templateDirectory string) { TemplateDirectory = templateDirectory }(TemplateDirectory)
+	TemplateDirectory = dir
+
+	os.MkdirAll(filepath.Join(dir, "node-streaming"), 0700)
+	ioutil.WriteFile(filepath.Join(dir, "node-streaming", "template.yml"), []byte("language: node-streaming\nfprocess: node index.js\n"), 0600)
+	ioutil.WriteFile(filepath.Join(dir, "node-streaming", "Dockerfile"), []byte(ofWatchdogDockerfile), 0600)
+
+	watchdog, err := TemplateWatchdog("node-streaming")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !watchdog.Streams() {
+		t.Errorf("want a streaming watchdog, got %s", watchdog)
+	}
+
+	if _, err := TemplateWatchdog("missing"); err == nil {
+		t.Errorf("want an error for a missing template")
+	}
+}
+
+func Test_Watchdog_Labels(t *testing.T) {
+	watchdog := &Watchdog{Type: OfWatchdog, Mode: HTTPMode, Version: "0.7.7"}
+
+	labels := watchdog.Labels("golang-http")
+	want := map[string]string{
+		TemplateLabel:        "golang-http",
+		WatchdogLabel:        OfWatchdog,
+		WatchdogModeLabel:    HTTPMode,
+		WatchdogVersionLabel: "0.7.7",
+	}
+	if !reflect.DeepEqual(labels, want) {
+		t.Fatalf("want labels %v, got %v", want, labels)
+	}
+
+	if got := LabelledWatchdog(labels); !reflect.DeepEqual(got, watchdog) {
+		t.Errorf("want %+v read back from the labels, got %+v", watchdog, got)
+	}
+	if got := LabelledWatchdog(map[string]string{"maintainer": "someone"}); got != nil {
+		t.Errorf("want no watchdog for an image without the labels, got %+v", got)
+	}
+}
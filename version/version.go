@@ -4,6 +4,9 @@ const UserAgent = "OpenFaaS CLI"
 
 var (
 	Version, GitCommit string
+
+	// BuildDate is injected at build-time, i.e. 2018-06-01T12:00:00Z
+	BuildDate string
 )
 
 func BuildVersion() string {
@@ -26,16 +26,35 @@ type vcsCmd struct {
 // Invoke executes the vcsCmd replacing varibables in the cmds with the keyval
 // variables passed.
 func (v *vcsCmd) Invoke(dir string, args map[string]string) error {
+	return v.InvokeWithEnv(dir, args, nil)
+}
+
+// InvokeWithEnv is Invoke with extra environment variables, such as
+// GIT_SSH_COMMAND, added for the commands.
+func (v *vcsCmd) InvokeWithEnv(dir string, args map[string]string, env []string) error {
 	for _, cmd := range v.cmds {
-		if _, err := v.run(dir, cmd, args, true); err != nil {
+		if _, err := v.run(dir, cmd, args, env, true); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
+// Output runs the commands like InvokeWithEnv and returns the trimmed output
+// of the last one
+func (v *vcsCmd) Output(dir string, args map[string]string, env []string) (string, error) {
+	var out []byte
+	for _, cmd := range v.cmds {
+		var err error
+		if out, err = v.run(dir, cmd, args, env, true); err != nil {
+			return "", err
+		}
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
 // run is the generalized implementation of executing our commands.
-func (v *vcsCmd) run(dir string, cmdline string, keyval map[string]string, verbose bool) ([]byte, error) {
+func (v *vcsCmd) run(dir string, cmdline string, keyval map[string]string, env []string, verbose bool) ([]byte, error) {
 	args := strings.Fields(cmdline)
 	for i, arg := range args {
 		args[i] = replaceVars(keyval, arg)
@@ -50,7 +69,7 @@ func (v *vcsCmd) run(dir string, cmdline string, keyval map[string]string, verbo
 
 	cmd := exec.Command(v.cmd, args...)
 	cmd.Dir = dir
-	cmd.Env = envWithPWD(cmd.Dir)
+	cmd.Env = append(envWithPWD(cmd.Dir), env...)
 
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
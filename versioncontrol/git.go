@@ -21,3 +21,56 @@ var GitInitRepo = &vcsCmd{
 	},
 	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
 }
+
+// GitSparseClone clones a repo into a directory without checking out its
+// files or fetching their contents, see GitSparseCheckoutAdd
+var GitSparseClone = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"clone {repo} {dir} --depth=1 --filter=blob:none --sparse"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
+// GitSparseCheckoutAdd checks out a path of a sparsely cloned repo
+var GitSparseCheckoutAdd = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"-C {dir} sparse-checkout add {path}"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
+// GitCloneRef clones a branch or tag of a repo into a directory
+var GitCloneRef = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"clone {repo} {dir} --depth=1 --branch {ref}"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
+// GitSparseCloneRef is GitSparseClone for a branch or tag
+var GitSparseCloneRef = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"clone {repo} {dir} --depth=1 --filter=blob:none --sparse --branch {ref}"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
+// GitCloneCommit clones the whole history of a repo into a directory and
+// checks out a commit, which can't be cloned by name
+var GitCloneCommit = &vcsCmd{
+	name: "Git",
+	cmd:  "git",
+	cmds: []string{
+		"clone {repo} {dir}",
+		"-C {dir} checkout --quiet {ref}",
+	},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
+
+// GitHeadCommit prints the commit checked out in a directory
+var GitHeadCommit = &vcsCmd{
+	name:   "Git",
+	cmd:    "git",
+	cmds:   []string{"-C {dir} rev-parse HEAD"},
+	scheme: []string{"git", "https", "http", "git+ssh", "ssh"},
+}
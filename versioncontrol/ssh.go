@@ -0,0 +1,112 @@
+package versioncontrol
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/crypto/ssh/agent"
+)
+
+var scpLikeURL = regexp.MustCompile(`^[-\w.]+@[-\w.]+:`)
+
+// SSHOptions control how git authenticates and verifies hosts when cloning
+// over SSH. Keys held by a running ssh-agent are always offered by ssh.
+type SSHOptions struct {
+	// KeyFile is a private key such as a deploy key
+	KeyFile string
+
+	// KnownHostsFile replaces ~/.ssh/known_hosts for verifying the host key
+	KnownHostsFile string
+
+	// HostKeyChecking is yes, no or accept-new, ssh's default is used when empty
+	HostKeyChecking string
+}
+
+// IsSSHURL tells whether git will use SSH for the repository
+func IsSSHURL(repo string) bool {
+	return strings.HasPrefix(repo, "ssh://") ||
+		strings.HasPrefix(repo, "git+ssh://") ||
+		scpLikeURL.MatchString(repo)
+}
+
+// Validate checks the host key checking mode and that the files exist
+func (o SSHOptions) Validate() error {
+	switch o.HostKeyChecking {
+	case "", "yes", "no", "accept-new":
+	default:
+		return fmt.Errorf("host key checking must be one of yes, no or accept-new, got: %s", o.HostKeyChecking)
+	}
+
+	if len(o.KeyFile) > 0 {
+		if _, err := os.Stat(o.KeyFile); err != nil {
+			return fmt.Errorf("unable to read SSH key: %s", err)
+		}
+	}
+
+	if len(o.KnownHostsFile) > 0 {
+		if _, err := os.Stat(o.KnownHostsFile); err != nil {
+			return fmt.Errorf("unable to read known hosts: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// GitSSHCommand gives the value for GIT_SSH_COMMAND, BatchMode stops ssh
+// from prompting since the clone is not attached to a terminal
+func (o SSHOptions) GitSSHCommand() string {
+	command := []string{"ssh", "-o", "BatchMode=yes"}
+
+	if len(o.KeyFile) > 0 {
+		command = append(command, "-i", shellQuote(o.KeyFile), "-o", "IdentitiesOnly=yes")
+	}
+
+	switch {
+	case o.HostKeyChecking == "no":
+		command = append(command, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null")
+	case len(o.HostKeyChecking) > 0:
+		command = append(command, "-o", "StrictHostKeyChecking="+o.HostKeyChecking)
+	}
+
+	if len(o.KnownHostsFile) > 0 && o.HostKeyChecking != "no" {
+		command = append(command, "-o", "UserKnownHostsFile="+shellQuote(o.KnownHostsFile))
+	}
+
+	return strings.Join(command, " ")
+}
+
+// Env gives the environment for running git with the options
+func (o SSHOptions) Env() []string {
+	return []string{"GIT_SSH_COMMAND=" + o.GitSSHCommand()}
+}
+
+// CheckAgent reports whether an ssh-agent is running and holds any keys
+func CheckAgent() error {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if len(socket) == 0 {
+		return fmt.Errorf("SSH_AUTH_SOCK is not set, start ssh-agent or pass an SSH key")
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return fmt.Errorf("unable to connect to ssh-agent: %s", err)
+	}
+	defer conn.Close()
+
+	keys, err := agent.NewClient(conn).List()
+	if err != nil {
+		return fmt.Errorf("unable to list ssh-agent keys: %s", err)
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("ssh-agent has no keys, add one with ssh-add")
+	}
+
+	return nil
+}
+
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,69 @@
+package versioncontrol
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func Test_IsSSHURL(t *testing.T) {
+	cases := map[string]bool{
+		"git@github.com:acme/templates.git":         true,
+		"ssh://git@github.com/acme/templates.git":   true,
+		"git+ssh://git@github.com/acme/templates":   true,
+		"https://github.com/openfaas/templates.git": false,
+		"git://github.com/openfaas/templates.git":   false,
+		"./local/templates":                         false,
+	}
+
+	for repo, want := range cases {
+		if got := IsSSHURL(repo); got != want {
+			t.Errorf("%s: want %v, got %v", repo, want, got)
+		}
+	}
+}
+
+func Test_GitSSHCommand(t *testing.T) {
+	cases := []struct {
+		title   string
+		options SSHOptions
+		want    string
+	}{
+		{"agent only", SSHOptions{}, "ssh -o BatchMode=yes"},
+		{
+			"deploy key with known hosts",
+			SSHOptions{KeyFile: "/keys/deploy key", KnownHostsFile: "/etc/known_hosts", HostKeyChecking: "yes"},
+			"ssh -o BatchMode=yes -i '/keys/deploy key' -o IdentitiesOnly=yes -o StrictHostKeyChecking=yes -o UserKnownHostsFile='/etc/known_hosts'",
+		},
+		{
+			"host key checking disabled",
+			SSHOptions{KnownHostsFile: "/etc/known_hosts", HostKeyChecking: "no"},
+			"ssh -o BatchMode=yes -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null",
+		},
+		{"quotes in paths", SSHOptions{KeyFile: "/keys/it's"}, `ssh -o BatchMode=yes -i '/keys/it'\''s' -o IdentitiesOnly=yes`},
+	}
+
+	for _, c := range cases {
+		if got := c.options.GitSSHCommand(); got != c.want {
+			t.Errorf("%s:\nwant: %s\ngot:  %s", c.title, c.want, got)
+		}
+	}
+}
+
+func Test_SSHOptions_Validate(t *testing.T) {
+	key, err := ioutil.TempFile("", "deploy-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(key.Name())
+
+	if err := (SSHOptions{KeyFile: key.Name(), HostKeyChecking: "accept-new"}).Validate(); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := (SSHOptions{HostKeyChecking: "sometimes"}).Validate(); err == nil {
+		t.Errorf("want error for an unknown host key checking mode")
+	}
+	if err := (SSHOptions{KeyFile: key.Name() + "-missing"}).Validate(); err == nil {
+		t.Errorf("want error for a missing key")
+	}
+}